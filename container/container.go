@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"github.com/johnny1110/crypto-exchange/engine-v2/core"
 	"github.com/johnny1110/crypto-exchange/external"
+	"github.com/johnny1110/crypto-exchange/middleware"
+	"github.com/johnny1110/crypto-exchange/notifier"
 	"github.com/johnny1110/crypto-exchange/ohlcv"
 	"github.com/johnny1110/crypto-exchange/repository"
 	repositoryImpl "github.com/johnny1110/crypto-exchange/repository/impl"
@@ -14,6 +16,7 @@ import (
 	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
 	"github.com/johnny1110/crypto-exchange/service/impl/amm"
 	"github.com/johnny1110/crypto-exchange/service/impl/metrics"
+	"github.com/johnny1110/crypto-exchange/service/impl/twofa"
 	"github.com/johnny1110/crypto-exchange/settings"
 	"github.com/johnny1110/crypto-exchange/ws"
 	"log"
@@ -27,24 +30,69 @@ type Container struct {
 	DB *sql.DB
 
 	// Repositories
-	UserRepo    repository.IUserRepository
-	BalanceRepo repository.IBalanceRepository
-	OrderRepo   repository.IOrderRepository
-	TradeRepo   repository.ITradeRepository
-	OHLCVRepo   ohlcv.OHLCVRepository
+	UserRepo              repository.IUserRepository
+	BalanceRepo           repository.IBalanceRepository
+	OrderRepo             repository.IOrderRepository
+	TradeRepo             repository.ITradeRepository
+	WithdrawalRepo        repository.IWithdrawalRepository
+	WithdrawalAddressRepo repository.IWithdrawalAddressRepository
+	TransferRepo          repository.ITransferRepository
+	SubAccountRepo        repository.ISubAccountRepository
+	FeeScheduleRepo       repository.IFeeScheduleRepository
+	FeeRevenueRepo        repository.IFeeRevenueRepository
+	ReferralRepo          repository.IReferralRepository
+	LedgerRepo            repository.ILedgerRepository
+	PnlRepo               repository.IPnlRepository
+	EquitySnapshotRepo    repository.IEquitySnapshotRepository
+	MarginRepo            repository.IMarginRepository
+	LiquidationRepo       repository.ILiquidationRepository
+	PerpetualRepo         repository.IPerpetualRepository
+	ConvertRepo           repository.IConvertRepository
+	RfqRepo               repository.IRfqRepository
+	ApiKeyRepo            repository.IApiKeyRepository
+	SessionRepo           repository.ISessionRepository
+	AdminRepo             repository.IAdminRepository
+	AuditLogRepo          repository.IAuditLogRepository
+	PasswordResetRepo     repository.IPasswordResetRepository
+	OHLCVRepo             ohlcv.OHLCVRepository
 
 	// Services
-	UserService       service.IUserService
-	BalanceService    service.IBalanceService
-	OrderService      service.IOrderService
-	OrderBookService  service.IOrderBookService
-	AdminService      service.IAdminService
-	CacheService      service.ICacheService
-	MarketDataService service.IMarketDataService
+	UserService         service.IUserService
+	BalanceService      service.IBalanceService
+	OrderService        service.IOrderService
+	OrderBookService    service.IOrderBookService
+	AdminService        service.IAdminService
+	CacheService        service.ICacheService
+	MarketDataService   service.IMarketDataService
+	WithdrawalService   service.IWithdrawalService
+	TransferService     service.ITransferService
+	FeeTierService      service.IFeeTierService
+	ReferralService     service.IReferralService
+	StatementService    service.IStatementService
+	PortfolioService    service.IPortfolioService
+	PriceIndexService   service.IPriceIndexService
+	MarginService       service.IMarginService
+	LiquidationService  service.ILiquidationService
+	PerpetualService    service.IPerpetualService
+	ConvertService      service.IConvertService
+	RfqService          service.IRfqService
+	ApiKeyService       service.IApiKeyService
+	AdminAccountService service.IAdminAccountService
+	TwoFactorService    service.ITwoFactorService
+	AuditService        service.IAuditService
 
 	// Cache and Security
-	CredentialCache *security.CredentialCache
-	MatchingEngine  *core.MatchingEngine
+	SessionStore      security.SessionStore
+	AdminSessionCache *security.AdminSessionCache
+	AdminLoginLock    *security.LoginLock
+	TwoFactorLock     *security.LoginLock
+	IPRateLimiter     *security.RateLimiter
+	UserRateLimiter   *security.RateLimiter
+	OrderRateLimiter  *security.RateLimiter
+	MatchingEngine    *core.MatchingEngine
+
+	// Notifier
+	Notifier notifier.Notifier
 
 	// Scheduler
 	SchedulerReporter          *scheduler.SchedulerReporter
@@ -52,6 +100,13 @@ type Container struct {
 	OrderBookSnapshotScheduler scheduler.Scheduler
 	LQDTScheduler              scheduler.Scheduler
 	WSDataFeederScheduler      scheduler.Scheduler
+	FeeTierScheduler           scheduler.Scheduler
+	EquitySnapshotScheduler    scheduler.Scheduler
+	MarginInterestScheduler    scheduler.Scheduler
+	LiquidationScheduler       scheduler.Scheduler
+	FundingScheduler           scheduler.Scheduler
+	RfqExpireScheduler         scheduler.Scheduler
+	SessionCleanupScheduler    scheduler.Scheduler
 
 	// Metrics
 	MetricsService *metrics.MetricService
@@ -65,7 +120,8 @@ type Container struct {
 	OHLCVAggregatorConfig *ohlcv.AggregatorConfig
 
 	// Websocket
-	WSHub *ws.Hub
+	WSHub           *ws.Hub
+	WSAuthenticator ws.Authenticator
 }
 
 // NewContainer do DI
@@ -75,15 +131,24 @@ func NewContainer(db *sql.DB, engine *core.MatchingEngine) *Container {
 		MatchingEngine: engine,
 	}
 
-	// init cache
-	c.CredentialCache = security.NewCredentialCache()
-
 	// init repositories
 	c.initRepositories()
 
+	// init session store
+	c.initSessionStore()
+
+	// init rate limiters
+	c.initRateLimiters()
+
+	// init notifier
+	c.initNotifier()
+
 	// init kline module
 	c.initOHLCVAgg()
 
+	// init websocket hub, services push user data to it
+	c.initWSHub()
+
 	// init services
 	c.initServices()
 
@@ -107,16 +172,74 @@ func (c *Container) initRepositories() {
 	c.BalanceRepo = repositoryImpl.NewBalanceRepository()
 	c.OrderRepo = repositoryImpl.NewOrderRepository()
 	c.TradeRepo = repositoryImpl.NewTradeRepository()
+	c.WithdrawalRepo = repositoryImpl.NewWithdrawalRepository()
+	c.WithdrawalAddressRepo = repositoryImpl.NewWithdrawalAddressRepository()
+	c.TransferRepo = repositoryImpl.NewTransferRepository()
+	c.SubAccountRepo = repositoryImpl.NewSubAccountRepository()
+	c.FeeScheduleRepo = repositoryImpl.NewFeeScheduleRepository()
+	c.FeeRevenueRepo = repositoryImpl.NewFeeRevenueRepository()
+	c.ReferralRepo = repositoryImpl.NewReferralRepository()
+	c.LedgerRepo = repositoryImpl.NewLedgerRepository()
+	c.PnlRepo = repositoryImpl.NewPnlRepository()
+	c.EquitySnapshotRepo = repositoryImpl.NewEquitySnapshotRepository()
+	c.MarginRepo = repositoryImpl.NewMarginRepository()
+	c.LiquidationRepo = repositoryImpl.NewLiquidationRepository()
+	c.PerpetualRepo = repositoryImpl.NewPerpetualRepository()
+	c.ConvertRepo = repositoryImpl.NewConvertRepository()
+	c.RfqRepo = repositoryImpl.NewRfqRepository()
+	c.ApiKeyRepo = repositoryImpl.NewApiKeyRepository()
+	c.SessionRepo = repositoryImpl.NewSessionRepository()
+	c.AdminRepo = repositoryImpl.NewAdminRepository()
+	c.AuditLogRepo = repositoryImpl.NewAuditLogRepository()
+	c.PasswordResetRepo = repositoryImpl.NewPasswordResetRepository()
 	c.OHLCVRepo = ohlcv.NewSQLiteOHLCVRepository(c.DB)
 }
 
+func (c *Container) initSessionStore() {
+	switch settings.SESSION_STORE {
+	case "memory":
+		c.SessionStore = security.NewMemorySessionStore()
+	default:
+		c.SessionStore = security.NewSQLiteSessionStore(c.DB, c.SessionRepo, c.UserRepo)
+	}
+	c.AdminSessionCache = security.NewAdminSessionCache(settings.ADMIN_SESSION_TTL)
+	c.AdminLoginLock = security.NewLoginLock(settings.ADMIN_LOGIN_MAX_FAILURES, settings.ADMIN_LOGIN_LOCK_DURATION)
+	c.TwoFactorLock = security.NewLoginLock(settings.TOTP_MAX_FAILURES, settings.TOTP_LOCK_DURATION)
+}
+
+func (c *Container) initRateLimiters() {
+	c.IPRateLimiter = security.NewRateLimiter(settings.RATE_LIMIT_IP_CAPACITY, settings.RATE_LIMIT_WINDOW)
+	c.UserRateLimiter = security.NewRateLimiter(settings.RATE_LIMIT_USER_CAPACITY, settings.RATE_LIMIT_WINDOW)
+	c.OrderRateLimiter = security.NewRateLimiter(settings.ORDER_RATE_LIMIT_PER_SECOND, time.Second)
+}
+
+func (c *Container) initNotifier() {
+	c.Notifier = notifier.NewLocalNotifier(settings.NOTIFIER_LOCAL_FILE)
+}
+
 func (c *Container) initServices() {
-	c.UserService = serviceImpl.NewIUserService(c.DB, c.UserRepo, c.BalanceRepo, c.CredentialCache)
-	c.OrderService = serviceImpl.NewIOrderService(c.DB, c.MatchingEngine, c.OrderRepo, c.TradeRepo, c.BalanceRepo, c.OHLCVTradeStream)
+	c.FeeTierService = serviceImpl.NewIFeeTierService(c.DB, c.UserRepo, c.TradeRepo, c.FeeScheduleRepo, c.SessionStore, c.AuditLogRepo)
+	c.AuditService = serviceImpl.NewIAuditService(c.DB, c.AuditLogRepo)
+	c.TwoFactorService = serviceImpl.NewITwoFactorService(c.DB, c.UserRepo, c.SessionStore, twofa.NewAuthenticator(time.Now), c.TwoFactorLock, c.AuditLogRepo)
+	c.UserService = serviceImpl.NewIUserService(c.DB, c.UserRepo, c.BalanceRepo, c.ReferralRepo, c.SessionStore, c.FeeTierService, c.TwoFactorService, c.AuditLogRepo, c.PasswordResetRepo, c.Notifier)
 	c.OrderBookService = serviceImpl.NewIOrderBookService(c.MatchingEngine)
-	c.AdminService = serviceImpl.NewIAdminService(c.DB, c.UserRepo, c.BalanceRepo, c.OrderService)
+	c.OrderService = serviceImpl.NewIOrderService(c.DB, c.MatchingEngine, c.OrderRepo, c.TradeRepo, c.BalanceRepo, c.FeeRevenueRepo, c.ReferralRepo, c.PnlRepo, c.MarginRepo, c.PerpetualRepo, c.OrderBookService, c.OHLCVTradeStream, c.WSHub, c.WSHub)
+	c.ApiKeyService = serviceImpl.NewIApiKeyService(c.DB, c.UserRepo, c.ApiKeyRepo, c.TwoFactorService, c.AuditLogRepo)
 	c.CacheService = serviceImpl.NewCacheService()
 	c.MarketDataService = serviceImpl.NewMarketDataService(c.DB, c.TradeRepo, c.CacheService, c.OHLCVAggregator)
+	c.WithdrawalService = serviceImpl.NewIWithdrawalService(c.DB, c.UserRepo, c.WithdrawalRepo, c.WithdrawalAddressRepo, c.BalanceRepo, c.TransferRepo, c.SubAccountRepo, c.MarketDataService, c.TwoFactorService, c.AuditLogRepo)
+	c.TransferService = serviceImpl.NewITransferService(c.DB, c.UserRepo, c.BalanceRepo, c.TransferRepo, c.SubAccountRepo, c.WithdrawalRepo, c.MarketDataService, c.TwoFactorService)
+	c.ReferralService = serviceImpl.NewIReferralService(c.DB, c.ReferralRepo)
+	c.StatementService = serviceImpl.NewIStatementService(c.DB, c.OrderRepo, c.LedgerRepo, c.OrderService)
+	c.PriceIndexService = serviceImpl.NewIPriceIndexService()
+	c.PortfolioService = serviceImpl.NewIPortfolioService(c.DB, c.UserRepo, c.BalanceRepo, c.PnlRepo, c.EquitySnapshotRepo, c.OrderBookService, c.PriceIndexService)
+	c.ConvertService = serviceImpl.NewIConvertService(c.DB, c.BalanceRepo, c.ConvertRepo, c.OrderService, c.OrderBookService)
+	c.RfqService = serviceImpl.NewIRfqService(c.DB, c.BalanceRepo, c.TradeRepo, c.FeeRevenueRepo, c.PnlRepo, c.RfqRepo, c.OrderBookService, c.OHLCVTradeStream, c.AuditLogRepo, c.WSHub)
+	c.MarginService = serviceImpl.NewIMarginService(c.DB, c.UserRepo, c.BalanceRepo, c.MarginRepo, c.TransferRepo, c.PortfolioService)
+	c.LiquidationService = serviceImpl.NewILiquidationService(c.DB, c.UserRepo, c.BalanceRepo, c.MarginRepo, c.LiquidationRepo, c.OrderService, c.MarginService, c.PortfolioService)
+	c.PerpetualService = serviceImpl.NewIPerpetualService(c.DB, c.BalanceRepo, c.PerpetualRepo, c.PnlRepo, c.OrderBookService, c.PriceIndexService, c.AuditLogRepo)
+	c.AdminService = serviceImpl.NewIAdminService(c.DB, c.UserRepo, c.BalanceRepo, c.OrderService, c.WithdrawalService, c.FeeTierService, c.FeeRevenueRepo, c.LiquidationService, c.AdminRepo, c.AuditLogRepo, c.SessionStore, c.SubAccountRepo)
+	c.AdminAccountService = serviceImpl.NewIAdminAccountService(c.DB, c.AdminRepo, c.AdminSessionCache, c.AdminLoginLock, c.AuditLogRepo)
 	c.BalanceService = serviceImpl.NewIBalanceService(c.DB, c.UserRepo, c.BalanceRepo, c.MarketDataService)
 }
 
@@ -134,11 +257,25 @@ func (c *Container) initScheduler() {
 	c.OrderBookSnapshotScheduler = scheduler.NewOrderBookSnapshotScheduler(c.MatchingEngine, 300*time.Millisecond)
 	c.LQDTScheduler = scheduler.NewLQDTScheduler(c.AmmExFuncProxy, c.UserService, 5*time.Minute)
 	c.WSDataFeederScheduler = scheduler.NewWSDataFeederJob(c.WSHub, c.OHLCVAggregator, c.OrderBookService, c.MarketDataService)
-
-	schedulers := make([]scheduler.Scheduler, 0, 3)
+	c.FeeTierScheduler = scheduler.NewFeeTierScheduler(c.FeeTierService)
+	c.EquitySnapshotScheduler = scheduler.NewEquitySnapshotScheduler(c.PortfolioService)
+	c.MarginInterestScheduler = scheduler.NewMarginInterestScheduler(c.MarginService, settings.MARGIN_INTEREST_INTERVAL)
+	c.LiquidationScheduler = scheduler.NewLiquidationScheduler(c.LiquidationService, c.PerpetualService, settings.MARGIN_RISK_CHECK_INTERVAL)
+	c.FundingScheduler = scheduler.NewFundingScheduler(c.PerpetualService)
+	c.RfqExpireScheduler = scheduler.NewRfqExpireScheduler(c.RfqService, settings.RFQ_EXPIRE_CHECK_INTERVAL)
+	c.SessionCleanupScheduler = scheduler.NewSessionCleanupScheduler(c.SessionStore, settings.SESSION_CLEANUP_INTERVAL)
+
+	schedulers := make([]scheduler.Scheduler, 0, 4)
 	schedulers = append(schedulers, c.MarketDataScheduler)
 	schedulers = append(schedulers, c.OrderBookSnapshotScheduler)
 	schedulers = append(schedulers, c.LQDTScheduler)
+	schedulers = append(schedulers, c.FeeTierScheduler)
+	schedulers = append(schedulers, c.EquitySnapshotScheduler)
+	schedulers = append(schedulers, c.MarginInterestScheduler)
+	schedulers = append(schedulers, c.LiquidationScheduler)
+	schedulers = append(schedulers, c.FundingScheduler)
+	schedulers = append(schedulers, c.RfqExpireScheduler)
+	schedulers = append(schedulers, c.SessionCleanupScheduler)
 
 	c.SchedulerReporter = scheduler.NewSchedulerReporter(schedulers)
 }
@@ -175,7 +312,7 @@ func (c *Container) initOHLCVAgg() {
 
 	allSymbolNames := make([]string, 0, len(settings.ALL_MARKETS))
 	for _, symbol := range settings.ALL_MARKETS {
-		initPrice, err := external.GetIndexPrice(ctx, symbol.Name)
+		initPrice, err := external.GetIndexPrice(ctx, symbol.IndexSymbol())
 		if err != nil {
 			log.Printf("[OHLCVAggregator] initOHLCVAgg GetIndexPrice err: %v", err)
 			initPrice = 0.01
@@ -201,9 +338,13 @@ func (c *Container) initOHLCVAgg() {
 	}
 }
 
-func (c *Container) initWS() {
+func (c *Container) initWSHub() {
 	hub := ws.NewHub()
 	c.WSHub = hub
 	ctx := context.Background()
 	go c.WSHub.Run(ctx)
 }
+
+func (c *Container) initWS() {
+	c.WSAuthenticator = middleware.NewWSAuthenticator(c.SessionStore, c.ApiKeyService)
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+)
+
+type AdminAccountController struct {
+	adminAccountService service.IAdminAccountService
+}
+
+func NewAdminAccountController(adminAccountService service.IAdminAccountService) *AdminAccountController {
+	return &AdminAccountController{
+		adminAccountService: adminAccountService,
+	}
+}
+
+func (c AdminAccountController) Login(context *gin.Context) {
+	var req dto.AdminLoginReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	session, err := c.adminAccountService.Login(context.Request.Context(), &req)
+	if err != nil {
+		context.JSON(http.StatusUnauthorized, HandleCodeError(ADMIN_LOGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(session))
+}
+
+func (c AdminAccountController) Logout(context *gin.Context) {
+	token := context.MustGet("adminToken").(string)
+	if err := c.adminAccountService.Logout(context.Request.Context(), token); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(ADMIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
+
+func (c AdminAccountController) GetMe(context *gin.Context) {
+	admin := context.MustGet("admin").(*dto.Admin)
+	context.JSON(http.StatusOK, HandleSuccess(admin))
+}
+
+func (c AdminAccountController) GetAdmins(context *gin.Context) {
+	admins, err := c.adminAccountService.GetAdmins(context.Request.Context())
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_ADMIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(admins))
+}
+
+func (c AdminAccountController) CreateAdmin(context *gin.Context) {
+	var req dto.CreateAdminReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	operator := context.MustGet("admin").(*dto.Admin)
+	admin, err := c.adminAccountService.CreateAdmin(context.Request.Context(), operator, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(ADMIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(admin))
+}
+
+func (c AdminAccountController) UpdateAdmin(context *gin.Context) {
+	var req dto.UpdateAdminReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	operator := context.MustGet("admin").(*dto.Admin)
+	admin, err := c.adminAccountService.UpdateAdmin(context.Request.Context(), operator, context.Param("adminId"), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(ADMIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(admin))
+}
+
+func (c AdminAccountController) ChangePassword(context *gin.Context) {
+	var req dto.AdminChangePasswordReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleBindError(err))
+		return
+	}
+
+	admin := context.MustGet("admin").(*dto.Admin)
+	token := context.MustGet("adminToken").(string)
+	if err := c.adminAccountService.ChangePassword(context.Request.Context(), admin, token, &req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(ADMIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
+
+func (c AdminAccountController) RotateTotp(context *gin.Context) {
+	var req dto.AdminRotateTotpReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	admin, err := c.adminAccountService.RotateTotp(context.Request.Context(), context.MustGet("admin").(*dto.Admin), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(ADMIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(admin))
+}
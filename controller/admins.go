@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/service"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type AdminController struct {
@@ -18,7 +21,8 @@ func (c AdminController) ManualAdjustment(context *gin.Context) {
 		return
 	}
 
-	err := c.adminService.Settlement(context.Request.Context(), req)
+	admin := context.MustGet("admin").(*dto.Admin)
+	err := c.adminService.Settlement(context.Request.Context(), admin, req)
 	if err != nil {
 		context.JSON(http.StatusBadRequest, HandleCodeError(INVALID_PARAMS, err))
 		return
@@ -27,12 +31,155 @@ func (c AdminController) ManualAdjustment(context *gin.Context) {
 	context.JSON(http.StatusOK, HandleSuccess(nil))
 }
 
+func (c AdminController) GetManualAdjustments(context *gin.Context) {
+	limit, _ := strconv.Atoi(context.Query("limit"))
+	adjustments, err := c.adminService.GetManualAdjustments(context.Request.Context(), context.Query("user_id"), limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_ADMIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(adjustments))
+}
+
+func (c AdminController) AssignMarketMakerTier(context *gin.Context) {
+	var req dto.AssignMarketMakerTierReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	admin := context.MustGet("admin").(*dto.Admin)
+	user, err := c.adminService.AssignMarketMakerTier(context.Request.Context(), admin, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(FEE_TIER_ERROR, err))
+		return
+	}
+
+	context.JSON(http.StatusOK, HandleSuccess(user))
+}
+
+func (c AdminController) GetAccountStatus(context *gin.Context) {
+	info, err := c.adminService.GetAccountStatus(context.Request.Context(), context.Param("userId"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(ACCOUNT_STATUS_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(info))
+}
+
+func (c AdminController) UpdateAccountStatus(context *gin.Context) {
+	var req dto.UpdateAccountStatusReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	admin := context.MustGet("admin").(*dto.Admin)
+	info, err := c.adminService.UpdateAccountStatus(context.Request.Context(), admin, context.Param("userId"), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(ACCOUNT_STATUS_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(info))
+}
+
+func (c AdminController) GetFeeRevenues(context *gin.Context) {
+	var req dto.FeeRevenueQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	to := time.Now()
+	if req.To > 0 {
+		to = time.UnixMilli(req.To)
+	}
+	from := to.Add(-24 * time.Hour)
+	if req.From > 0 {
+		from = time.UnixMilli(req.From)
+	}
+
+	revenues, err := c.adminService.GetFeeRevenues(context.Request.Context(), from, to)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_FEE_REVENUE_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(revenues))
+}
+
+func (c AdminController) GetLiquidations(context *gin.Context) {
+	limit, _ := strconv.Atoi(context.Query("limit"))
+	liquidations, err := c.adminService.GetLiquidations(context.Request.Context(), context.Query("user_id"), limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(liquidations))
+}
+
 func (c AdminController) TestMakeMarket(context *gin.Context) {
 	// TODO: implement auto market maker logic.
 	context.JSON(http.StatusBadRequest, HandleCodeErrorAndMsg(FUNC_NOT_IMPLEMENT, "func not support yet"))
 	return
 }
 
+func (c AdminController) GetWithdrawals(context *gin.Context) {
+	status := dto.WithdrawalStatus(context.DefaultQuery("status", string(dto.WITHDRAWAL_STATUS_PENDING)))
+	withdrawals, err := c.adminService.GetWithdrawals(context.Request.Context(), status)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_WITHDRAWAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(withdrawals))
+}
+
+func (c AdminController) GetWithdrawalAudits(context *gin.Context) {
+	withdrawalId := context.Param("withdrawalId")
+	audits, err := c.adminService.GetWithdrawalAudits(context.Request.Context(), withdrawalId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_WITHDRAWAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(audits))
+}
+
+func (c AdminController) ApproveWithdrawal(context *gin.Context) {
+	c.reviewWithdrawal(context, c.adminService.ApproveWithdrawal)
+}
+
+func (c AdminController) RejectWithdrawal(context *gin.Context) {
+	c.reviewWithdrawal(context, c.adminService.RejectWithdrawal)
+}
+
+func (c AdminController) BroadcastWithdrawal(context *gin.Context) {
+	c.reviewWithdrawal(context, c.adminService.BroadcastWithdrawal)
+}
+
+func (c AdminController) ConfirmWithdrawal(context *gin.Context) {
+	c.reviewWithdrawal(context, c.adminService.ConfirmWithdrawal)
+}
+
+type reviewWithdrawalFunc func(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error)
+
+func (c AdminController) reviewWithdrawal(context *gin.Context, review reviewWithdrawalFunc) {
+	admin := context.MustGet("admin").(*dto.Admin)
+	withdrawalId := context.Param("withdrawalId")
+
+	var req dto.ReviewWithdrawalReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	withdrawal, err := review(context.Request.Context(), admin, withdrawalId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(REVIEW_WITHDRAWAL_ERROR, err))
+		return
+	}
+
+	context.JSON(http.StatusOK, HandleSuccess(withdrawal))
+}
+
 func NewAdminController(adminService service.IAdminService) *AdminController {
 	return &AdminController{
 		adminService: adminService,
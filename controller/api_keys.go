@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+)
+
+type ApiKeyController struct {
+	apiKeyService service.IApiKeyService
+}
+
+func NewApiKeyController(apiKeyService service.IApiKeyService) *ApiKeyController {
+	return &ApiKeyController{
+		apiKeyService: apiKeyService,
+	}
+}
+
+func (c ApiKeyController) CreateApiKey(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.CreateApiKeyReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	apiKey, err := c.apiKeyService.CreateApiKey(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(API_KEY_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(apiKey))
+}
+
+func (c ApiKeyController) GetApiKeys(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+
+	apiKeys, err := c.apiKeyService.GetApiKeys(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_API_KEY_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(apiKeys))
+}
+
+func (c ApiKeyController) UpdateLabel(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.UpdateApiKeyReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	apiKey, err := c.apiKeyService.UpdateLabel(context.Request.Context(), userId, context.Param("apiKey"), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(API_KEY_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(apiKey))
+}
+
+func (c ApiKeyController) RevokeApiKey(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+
+	if err := c.apiKeyService.RevokeApiKey(context.Request.Context(), userId, context.Param("apiKey")); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(API_KEY_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
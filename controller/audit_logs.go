@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+)
+
+type AuditLogController struct {
+	auditService service.IAuditService
+}
+
+func NewAuditLogController(auditService service.IAuditService) *AuditLogController {
+	return &AuditLogController{
+		auditService: auditService,
+	}
+}
+
+func (c AuditLogController) GetAuditLogs(context *gin.Context) {
+	var req dto.AuditLogQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	auditLogs, err := c.auditService.GetAuditLogs(context.Request.Context(), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_AUDIT_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(auditLogs))
+}
+
+func (c AuditLogController) VerifyChain(context *gin.Context) {
+	result, err := c.auditService.VerifyChain(context.Request.Context())
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, HandleCodeError(QUERY_AUDIT_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(result))
+}
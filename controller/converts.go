@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"net/http"
+	"strconv"
+)
+
+type ConvertController struct {
+	convertService service.IConvertService
+}
+
+func NewConvertController(convertService service.IConvertService) *ConvertController {
+	return &ConvertController{
+		convertService: convertService,
+	}
+}
+
+func (c ConvertController) Quote(context *gin.Context) {
+	user := context.MustGet("user").(*dto.User)
+	var req dto.ConvertQuoteReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	quote, err := c.convertService.Quote(context.Request.Context(), user, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(CONVERT_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(quote))
+}
+
+func (c ConvertController) Accept(context *gin.Context) {
+	user := context.MustGet("user").(*dto.User)
+	var req dto.ConvertAcceptReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	convert, err := c.convertService.Accept(context.Request.Context(), user, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(CONVERT_ERROR, err))
+		return
+	}
+	if convert.Status == dto.CONVERT_STATUS_PARTIAL {
+		// legs are executed and can not be undone, response must not look like a completed convert.
+		context.JSON(http.StatusOK, HandleCodeErrorAndData(CONVERT_PARTIAL, "convert did not complete, unconverted amount stays in from asset or "+settings.CONVERT_ROUTE_ASSET, convert))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(convert))
+}
+
+func (c ConvertController) GetHistory(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	limit, _ := strconv.Atoi(context.Query("limit"))
+
+	converts, err := c.convertService.GetConverts(context.Request.Context(), userId, limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_CONVERT_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(converts))
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"strconv"
+)
+
+type MarginController struct {
+	marginService      service.IMarginService
+	liquidationService service.ILiquidationService
+}
+
+func NewMarginController(marginService service.IMarginService, liquidationService service.ILiquidationService) *MarginController {
+	return &MarginController{
+		marginService:      marginService,
+		liquidationService: liquidationService,
+	}
+}
+
+func (c MarginController) GetAccount(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	account, err := c.marginService.GetAccount(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(account))
+}
+
+func (c MarginController) Transfer(context *gin.Context) {
+	user := context.MustGet("user").(*dto.User)
+	var req dto.MarginTransferReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	log.Infof("[MarginController] transfer, user:[%s], req: %v", user.Username, req)
+
+	transfer, err := c.marginService.Transfer(context.Request.Context(), user, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(transfer))
+}
+
+func (c MarginController) Borrow(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.MarginLoanReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	record, err := c.marginService.Borrow(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(record))
+}
+
+func (c MarginController) Repay(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.MarginLoanReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	record, err := c.marginService.Repay(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(record))
+}
+
+func (c MarginController) GetLoanRecords(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	limit, _ := strconv.Atoi(context.Query("limit"))
+
+	records, err := c.marginService.GetLoanRecords(context.Request.Context(), userId, limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(records))
+}
+
+func (c MarginController) GetMarginCalls(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	limit, _ := strconv.Atoi(context.Query("limit"))
+
+	calls, err := c.liquidationService.GetMarginCalls(context.Request.Context(), userId, limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(calls))
+}
+
+func (c MarginController) GetLiquidations(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	limit, _ := strconv.Atoi(context.Query("limit"))
+
+	liquidations, err := c.liquidationService.GetLiquidations(context.Request.Context(), userId, limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_MARGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(liquidations))
+}
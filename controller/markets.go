@@ -130,3 +130,30 @@ func (mc MarketDataController) GetOHLCVHistory(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, HandleSuccess(data))
 }
+
+func (mc MarketDataController) GetRecentTrades(ctx *gin.Context) {
+	market := ctx.Param("market")
+	if market == "" {
+		ctx.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	// if limit not input, default is 100
+	var limit int
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			ctx.JSON(http.StatusBadRequest, HandleInvalidInput())
+			return
+		}
+		limit = parsed
+	}
+
+	trades, err := mc.marketDataService.GetRecentTrades(ctx.Request.Context(), market, limit)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, HandleSuccess(trades))
+}
@@ -83,3 +83,21 @@ func (c OrderController) GetOrders(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, HandleSuccess(resp))
 }
+
+func (c OrderController) GetFills(ctx *gin.Context) {
+	userID := ctx.MustGet("userId").(string)
+	var query dto.GetFillsQueryReq
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	resp, err := c.orderService.GetFills(ctx.Request.Context(), userID, &query)
+	if err != nil {
+		log.Errorf("[OrderController] failed to GetFills, error: %v", err)
+		ctx.JSON(http.StatusBadRequest, HandleCodeError(QUERY_FILLS_ERROR, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, HandleSuccess(resp))
+}
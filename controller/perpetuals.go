@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+)
+
+type PerpetualController struct {
+	perpetualService service.IPerpetualService
+}
+
+func NewPerpetualController(perpetualService service.IPerpetualService) *PerpetualController {
+	return &PerpetualController{
+		perpetualService: perpetualService,
+	}
+}
+
+func (c PerpetualController) GetMarkets(context *gin.Context) {
+	markets, err := c.perpetualService.GetMarkets(context.Request.Context())
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PERPETUAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(markets))
+}
+
+func (c PerpetualController) GetFundingRates(context *gin.Context) {
+	var req dto.FundingQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	rates, err := c.perpetualService.GetFundingRates(context.Request.Context(), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PERPETUAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(rates))
+}
+
+func (c PerpetualController) GetPositions(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	positions, err := c.perpetualService.GetPositions(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PERPETUAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(positions))
+}
+
+func (c PerpetualController) GetFundingPayments(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.FundingQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	payments, err := c.perpetualService.GetFundingPayments(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PERPETUAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(payments))
+}
+
+func (c PerpetualController) GetLiquidations(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.FundingQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	liquidations, err := c.perpetualService.GetLiquidations(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PERPETUAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(liquidations))
+}
+
+func (c PerpetualController) ResumeMarket(context *gin.Context) {
+	admin := context.MustGet("admin").(*dto.Admin)
+	if err := c.perpetualService.ResumeMarket(context.Request.Context(), admin, context.Param("market")); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(PERPETUAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+)
+
+type PortfolioController struct {
+	portfolioService service.IPortfolioService
+}
+
+func NewPortfolioController(portfolioService service.IPortfolioService) *PortfolioController {
+	return &PortfolioController{
+		portfolioService: portfolioService,
+	}
+}
+
+func (c PortfolioController) GetPnl(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.PnlQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	pnl, err := c.portfolioService.GetPnl(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PORTFOLIO_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(pnl))
+}
+
+func (c PortfolioController) GetPortfolio(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.PortfolioQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	portfolio, err := c.portfolioService.GetPortfolio(context.Request.Context(), userId, req.Quote)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PORTFOLIO_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(portfolio))
+}
+
+func (c PortfolioController) GetEquityHistory(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.EquityHistoryQueryReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	snapshots, err := c.portfolioService.GetEquityHistory(context.Request.Context(), userId, req.Days)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_PORTFOLIO_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(snapshots))
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+	"strconv"
+)
+
+type ReferralController struct {
+	referralService service.IReferralService
+}
+
+func NewReferralController(referralService service.IReferralService) *ReferralController {
+	return &ReferralController{
+		referralService: referralService,
+	}
+}
+
+func (c ReferralController) GetReferralInfo(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	info, err := c.referralService.GetReferralInfo(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_REFERRAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(info))
+}
+
+func (c ReferralController) GetCommissions(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	limit, err := strconv.Atoi(context.DefaultQuery("limit", "50"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	commissions, err := c.referralService.GetCommissions(context.Request.Context(), userId, limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_REFERRAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(commissions))
+}
+
+func (c ReferralController) GetCommissionTotals(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	totals, err := c.referralService.GetCommissionTotals(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_REFERRAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(totals))
+}
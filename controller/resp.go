@@ -35,6 +35,16 @@ func HandleCodeErrorAndMsg(code MessageCode, msg string) any {
 	}
 }
 
+// HandleCodeErrorAndData error with data of what was done before failing.
+func HandleCodeErrorAndData(code MessageCode, msg string, data any) any {
+	return &Resp{
+		Code:      code,
+		Msg:       msg,
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
 func HandleSuccess(data any) any {
 	return &Resp{
 		Code:      SUCCESS,
@@ -58,21 +68,61 @@ const (
 	FUNC_NOT_IMPLEMENT = "1000009"
 
 	// users : 2000000 ~ 2999999
-	REGISTER_ERROR      = "2000001"
-	LOGIN_ERROR         = "2000002"
-	USER_DATA_NOT_FOUND = "2000003"
+	REGISTER_ERROR       = "2000001"
+	LOGIN_ERROR          = "2000002"
+	USER_DATA_NOT_FOUND  = "2000003"
+	UPDATE_USER_ERROR    = "2000004"
+	API_KEY_ERROR        = "2000005"
+	QUERY_API_KEY_ERROR  = "2000006"
+	ADMIN_LOGIN_ERROR    = "2000007"
+	ADMIN_ERROR          = "2000008"
+	QUERY_ADMIN_ERROR    = "2000009"
+	TWO_FACTOR_ERROR     = "2000010"
+	QUERY_AUDIT_ERROR    = "2000011"
+	ACCOUNT_STATUS_ERROR = "2000012"
+	PASSWORD_ERROR       = "2000013"
 
 	// orders : 3000000 ~ 3999999
-	PLACE_ORDER_ERROR  = "3000001"
-	CANCEL_ORDER_ERROR = "3000002"
+	PLACE_ORDER_ERROR     = "3000001"
+	CANCEL_ORDER_ERROR    = "3000002"
+	QUERY_FILLS_ERROR     = "3000003"
+	QUERY_STATEMENT_ERROR = "3000004"
+	QUERY_PERPETUAL_ERROR = "3000005"
+	CONVERT_ERROR         = "3000006"
+	QUERY_CONVERT_ERROR   = "3000007"
+	RFQ_ERROR             = "3000008"
+	QUERY_RFQ_ERROR       = "3000009"
+	CONVERT_PARTIAL       = "3000010"
+	PERPETUAL_ERROR       = "3000011"
 
 	// balances : 4000000 ~ 4999999
-	QUERY_BALANCE_ERROR = "4000001"
+	QUERY_BALANCE_ERROR   = "4000001"
+	QUERY_PORTFOLIO_ERROR = "4000002"
+	MARGIN_ERROR          = "4000003"
+	QUERY_MARGIN_ERROR    = "4000004"
 
 	// orderBooks: 5000000 ~ 5999999
 	SNAPSHOT_ERROR = "5000001"
 
-	BAD_REQUEST   MessageCode = "9000001"
-	ACCESS_DENIED MessageCode = "9900001"
-	SYSTEM_ERROR  MessageCode = "9999999"
+	// withdrawals: 6000000 ~ 6999999
+	WITHDRAW_ERROR           = "6000001"
+	QUERY_WITHDRAWAL_ERROR   = "6000002"
+	WITHDRAWAL_ADDRESS_ERROR = "6000003"
+	REVIEW_WITHDRAWAL_ERROR  = "6000004"
+
+	// transfers: 7000000 ~ 7999999
+	TRANSFER_ERROR       = "7000001"
+	QUERY_TRANSFER_ERROR = "7000002"
+	SUB_ACCOUNT_ERROR    = "7000003"
+
+	// fees: 8000000 ~ 8999999
+	FEE_TIER_ERROR          = "8000001"
+	QUERY_FEE_REVENUE_ERROR = "8000002"
+	QUERY_REFERRAL_ERROR    = "8000003"
+
+	BAD_REQUEST      MessageCode = "9000001"
+	ACCESS_DENIED    MessageCode = "9900001"
+	RATE_LIMITED     MessageCode = "9900002"
+	ACCOUNT_DISABLED MessageCode = "9900003"
+	SYSTEM_ERROR     MessageCode = "9999999"
 )
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+	"strconv"
+)
+
+type RfqController struct {
+	rfqService service.IRfqService
+}
+
+func NewRfqController(rfqService service.IRfqService) *RfqController {
+	return &RfqController{
+		rfqService: rfqService,
+	}
+}
+
+func (c RfqController) CreateRequest(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.RfqRequestReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	request, err := c.rfqService.CreateRequest(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(request))
+}
+
+func (c RfqController) GetRequests(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	limit, _ := strconv.Atoi(context.Query("limit"))
+
+	requests, err := c.rfqService.GetRequests(context.Request.Context(), userId, limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(requests))
+}
+
+func (c RfqController) GetRequest(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	request, err := c.rfqService.GetRequest(context.Request.Context(), userId, context.Param("requestId"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(request))
+}
+
+func (c RfqController) CancelRequest(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	request, err := c.rfqService.CancelRequest(context.Request.Context(), userId, context.Param("requestId"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(request))
+}
+
+func (c RfqController) Accept(context *gin.Context) {
+	user := context.MustGet("user").(*dto.User)
+	var req dto.RfqAcceptReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	request, err := c.rfqService.Accept(context.Request.Context(), user, context.Param("requestId"), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(request))
+}
+
+func (c RfqController) GetOpenRequests(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	requests, err := c.rfqService.GetOpenRequests(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(requests))
+}
+
+func (c RfqController) SubmitQuote(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.RfqQuoteReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	quote, err := c.rfqService.SubmitQuote(context.Request.Context(), userId, context.Param("requestId"), &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(quote))
+}
+
+func (c RfqController) GetQuotes(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	limit, _ := strconv.Atoi(context.Query("limit"))
+
+	quotes, err := c.rfqService.GetQuotes(context.Request.Context(), userId, limit)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(quotes))
+}
+
+func (c RfqController) CancelQuote(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	quote, err := c.rfqService.CancelQuote(context.Request.Context(), userId, context.Param("quoteId"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(quote))
+}
+
+func (c RfqController) GetProviders(context *gin.Context) {
+	providers, err := c.rfqService.GetProviders(context.Request.Context())
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(providers))
+}
+
+func (c RfqController) AddProvider(context *gin.Context) {
+	var req dto.AddRfqProviderReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	admin := context.MustGet("admin").(*dto.Admin)
+	provider, err := c.rfqService.AddProvider(context.Request.Context(), admin, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(provider))
+}
+
+func (c RfqController) RemoveProvider(context *gin.Context) {
+	admin := context.MustGet("admin").(*dto.Admin)
+	if err := c.rfqService.RemoveProvider(context.Request.Context(), admin, context.Param("userId")); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(RFQ_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+type StatementController struct {
+	statementService service.IStatementService
+}
+
+func NewStatementController(statementService service.IStatementService) *StatementController {
+	return &StatementController{
+		statementService: statementService,
+	}
+}
+
+func (c StatementController) ExportStatement(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.StatementReq
+	if err := context.ShouldBindQuery(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	// errors after streaming started can not be responded as json.
+	if err := c.statementService.Validate(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_STATEMENT_ERROR, err))
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if req.Format == dto.STATEMENT_FORMAT_JSONL {
+		contentType = "application/x-ndjson"
+	}
+	context.Header("Content-Type", contentType)
+	context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"statement-%s-%d.%s\"", req.Type, req.From, req.Format))
+	context.Status(http.StatusOK)
+
+	if err := c.statementService.Export(context.Request.Context(), userId, &req, context.Writer); err != nil {
+		log.Errorf("[StatementController] failed to export statement, userId: %s, error: %v", userId, err)
+		_ = context.Error(err)
+	}
+}
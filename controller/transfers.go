@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+type TransferController struct {
+	transferService service.ITransferService
+}
+
+func NewTransferController(transferService service.ITransferService) *TransferController {
+	return &TransferController{
+		transferService: transferService,
+	}
+}
+
+func (c TransferController) Transfer(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+
+	var req dto.TransferReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	log.Infof("[TransferController] Transfer: userId:[%s], asset: %s, amount: %v", userId, req.Asset, req.Amount)
+
+	transfer, err := c.transferService.Transfer(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(TRANSFER_ERROR, err))
+		return
+	}
+
+	context.JSON(http.StatusOK, HandleSuccess(transfer))
+}
+
+func (c TransferController) GetTransfers(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	transfers, err := c.transferService.GetTransfers(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_TRANSFER_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(transfers))
+}
+
+func (c TransferController) CreateSubAccount(context *gin.Context) {
+	user := context.MustGet("user").(*dto.User)
+
+	var req dto.CreateSubAccountReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleBindError(err))
+		return
+	}
+
+	subAccount, err := c.transferService.CreateSubAccount(context.Request.Context(), user, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(SUB_ACCOUNT_ERROR, err))
+		return
+	}
+
+	context.JSON(http.StatusOK, HandleSuccess(subAccount))
+}
+
+func (c TransferController) GetSubAccounts(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	subAccounts, err := c.transferService.GetSubAccounts(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(SUB_ACCOUNT_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(subAccounts))
+}
+
+func (c TransferController) SubAccountTransfer(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+
+	var req dto.SubAccountTransferReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	log.Infof("[TransferController] SubAccountTransfer: userId:[%s], req: %v", userId, req)
+
+	transfer, err := c.transferService.SubAccountTransfer(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(TRANSFER_ERROR, err))
+		return
+	}
+
+	context.JSON(http.StatusOK, HandleSuccess(transfer))
+}
+
+func (c TransferController) GetConsolidatedBalances(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	balances, err := c.transferService.GetConsolidatedBalances(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_BALANCE_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(balances))
+}
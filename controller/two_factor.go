@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"net/http"
+)
+
+type TwoFactorController struct {
+	twoFactorService service.ITwoFactorService
+}
+
+func NewTwoFactorController(twoFactorService service.ITwoFactorService) *TwoFactorController {
+	return &TwoFactorController{
+		twoFactorService: twoFactorService,
+	}
+}
+
+func (c TwoFactorController) Enroll(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+
+	enrollment, err := c.twoFactorService.Enroll(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(TWO_FACTOR_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(enrollment))
+}
+
+func (c TwoFactorController) Enable(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.TwoFactorCodeReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	backupCodes, err := c.twoFactorService.Enable(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(TWO_FACTOR_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(backupCodes))
+}
+
+func (c TwoFactorController) Disable(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.TwoFactorCodeReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	if err := c.twoFactorService.Disable(context.Request.Context(), userId, &req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(TWO_FACTOR_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
+
+func (c TwoFactorController) RegenerateBackupCodes(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	var req dto.TwoFactorCodeReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	backupCodes, err := c.twoFactorService.RegenerateBackupCodes(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(TWO_FACTOR_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(backupCodes))
+}
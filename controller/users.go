@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/security"
 	"github.com/johnny1110/crypto-exchange/service"
 	"net/http"
 )
@@ -14,7 +15,7 @@ type UserController struct {
 func (c UserController) Register(context *gin.Context) {
 	var req dto.RegisterReq
 	if err := context.ShouldBindJSON(&req); err != nil {
-		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		context.JSON(http.StatusBadRequest, HandleBindError(err))
 		return
 	}
 
@@ -34,18 +35,32 @@ func (c UserController) Login(context *gin.Context) {
 		context.JSON(http.StatusBadRequest, HandleInvalidInput())
 		return
 	}
-	token, err := c.userService.Login(context.Request.Context(), &req)
+	session, err := c.userService.Login(context.Request.Context(), &req, security.ClientIP(context.Request), context.Request.UserAgent())
 	if err != nil {
 		context.JSON(http.StatusBadRequest, HandleCodeError(LOGIN_ERROR, err))
 		return
 	}
-	context.JSON(http.StatusOK, HandleSuccess(map[string]any{"token": token}))
+	context.JSON(http.StatusOK, HandleSuccess(session))
 	return
 }
 
+func (c UserController) RefreshToken(context *gin.Context) {
+	var req dto.RefreshTokenReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+	session, err := c.userService.RefreshSession(context.Request.Context(), &req, security.ClientIP(context.Request), context.Request.UserAgent())
+	if err != nil {
+		context.JSON(http.StatusUnauthorized, HandleCodeError(LOGIN_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(session))
+}
+
 func (c UserController) GetProfile(context *gin.Context) {
 	userId := context.MustGet("userId").(string)
-	user, err := c.userService.GetUser(context.Request.Context(), userId)
+	user, err := c.userService.GetProfile(context.Request.Context(), userId)
 	if err != nil {
 		context.JSON(http.StatusBadRequest, HandleCodeError(USER_DATA_NOT_FOUND, err))
 		return
@@ -54,6 +69,23 @@ func (c UserController) GetProfile(context *gin.Context) {
 	return
 }
 
+func (c UserController) UpdateFeeSettings(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+
+	var req dto.UpdateFeeSettingsReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	user, err := c.userService.UpdateFeeSettings(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(UPDATE_USER_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(user))
+}
+
 func (c UserController) Logout(context *gin.Context) {
 	token := context.MustGet("token").(string)
 	err := c.userService.Logout(context.Request.Context(), token)
@@ -65,6 +97,71 @@ func (c UserController) Logout(context *gin.Context) {
 	return
 }
 
+func (c UserController) LogoutAll(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	if err := c.userService.LogoutAll(context.Request.Context(), userId); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(USER_DATA_NOT_FOUND, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
+
+func (c UserController) GetSessions(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	token := context.MustGet("token").(string)
+	sessions, err := c.userService.GetSessions(context.Request.Context(), userId, token)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(USER_DATA_NOT_FOUND, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(sessions))
+}
+
+func (c UserController) ChangePassword(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	token := context.MustGet("token").(string)
+
+	var req dto.ChangePasswordReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleBindError(err))
+		return
+	}
+
+	if err := c.userService.ChangePassword(context.Request.Context(), userId, token, &req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(PASSWORD_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
+
+func (c UserController) RequestPasswordReset(context *gin.Context) {
+	var req dto.PasswordResetRequestReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	if err := c.userService.RequestPasswordReset(context.Request.Context(), &req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(PASSWORD_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
+
+func (c UserController) ResetPassword(context *gin.Context) {
+	var req dto.PasswordResetReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleBindError(err))
+		return
+	}
+
+	if err := c.userService.ResetPassword(context.Request.Context(), &req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(PASSWORD_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
+
 func NewUserController(userService service.IUserService) *UserController {
 	return &UserController{
 		userService: userService,
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/johnny1110/crypto-exchange/security"
+)
+
+// RegisterValidators custom binding tags:
+//   - password: new password strength, see security.PasswordRule.
+func RegisterValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected binding validator engine")
+	}
+	return v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return security.IsStrongPassword(fl.Field().String())
+	})
+}
+
+// HandleBindError invalid input, tell password rule if password is too weak.
+func HandleBindError(err error) any {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fieldError := range validationErrors {
+			if fieldError.Tag() == "password" {
+				return HandleCodeErrorAndMsg(INVALID_PARAMS, security.PasswordRule)
+			}
+		}
+	}
+	return HandleInvalidInput()
+}
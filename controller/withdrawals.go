@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"strconv"
+)
+
+type WithdrawalController struct {
+	withdrawalService service.IWithdrawalService
+}
+
+func NewWithdrawalController(withdrawalService service.IWithdrawalService) *WithdrawalController {
+	return &WithdrawalController{
+		withdrawalService: withdrawalService,
+	}
+}
+
+func (c WithdrawalController) Withdraw(context *gin.Context) {
+	user := context.MustGet("user").(*dto.User)
+
+	var req dto.WithdrawReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	log.Infof("[WithdrawalController] Withdraw: user:[%s], req: %v", user.Username, req)
+
+	withdrawal, err := c.withdrawalService.Withdraw(context.Request.Context(), user, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(WITHDRAW_ERROR, err))
+		return
+	}
+
+	context.JSON(http.StatusOK, HandleSuccess(withdrawal))
+}
+
+func (c WithdrawalController) GetWithdrawals(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	withdrawals, err := c.withdrawalService.GetWithdrawals(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(QUERY_WITHDRAWAL_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(withdrawals))
+}
+
+func (c WithdrawalController) AddAddress(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+
+	var req dto.AddWithdrawalAddressReq
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	address, err := c.withdrawalService.AddAddress(context.Request.Context(), userId, &req)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(WITHDRAWAL_ADDRESS_ERROR, err))
+		return
+	}
+
+	context.JSON(http.StatusOK, HandleSuccess(address))
+}
+
+func (c WithdrawalController) GetAddresses(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	addresses, err := c.withdrawalService.GetAddresses(context.Request.Context(), userId)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(WITHDRAWAL_ADDRESS_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(addresses))
+}
+
+func (c WithdrawalController) RemoveAddress(context *gin.Context) {
+	userId := context.MustGet("userId").(string)
+	addressId, err := strconv.ParseInt(context.Param("addressId"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, HandleInvalidInput())
+		return
+	}
+
+	if err = c.withdrawalService.RemoveAddress(context.Request.Context(), userId, addressId); err != nil {
+		context.JSON(http.StatusBadRequest, HandleCodeError(WITHDRAWAL_ADDRESS_ERROR, err))
+		return
+	}
+	context.JSON(http.StatusOK, HandleSuccess(nil))
+}
@@ -7,7 +7,16 @@ CREATE TABLE users
     vip_level     INTEGER DEFAULT 1,
     maker_fee      REAL NOT NULL,
     taker_fee      REAL NOT NULL,
-    created_at     DATETIME DEFAULT CURRENT_TIMESTAMP
+    pay_fee_in_btse INTEGER DEFAULT 0, -- 1: pay trading fees in BTSE with discount
+    created_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    totp_secret       TEXT    NOT NULL DEFAULT '', -- base32, set on enrolment
+    totp_enabled      INTEGER NOT NULL DEFAULT 0,
+    totp_backup_codes TEXT    NOT NULL DEFAULT '', -- comma separated sha256 of unused backup codes
+    totp_last_step    INTEGER NOT NULL DEFAULT 0,  -- last accepted TOTP time step, prevents code replay
+    status            TEXT    NOT NULL DEFAULT 'ACTIVE', -- ACTIVE, FROZEN, CLOSED
+    trade_disabled    INTEGER NOT NULL DEFAULT 0,
+    withdraw_disabled INTEGER NOT NULL DEFAULT 0,
+    status_reason     TEXT    NOT NULL DEFAULT ''  -- reason of last status change, internal only
 );
 
 DROP TABLE IF EXISTS balances;
@@ -64,6 +73,14 @@ create table trades
     size         REAL     NOT NULL,
     bid_fee_rate REAL,
     ask_fee_rate REAL,
+    bid_user_id   TEXT,
+    ask_user_id   TEXT,
+    taker_side    INTEGER,      -- 0=Bid,1=Ask
+    bid_fee       REAL DEFAULT 0, -- negative means rebate
+    bid_fee_asset TEXT,
+    ask_fee       REAL DEFAULT 0, -- negative means rebate
+    ask_fee_asset TEXT,
+    block         INTEGER DEFAULT 0, -- 1: RFQ block trade, settled off order book
     timestamp    DATETIME NOT NULL
 );
 
@@ -73,4 +90,531 @@ CREATE INDEX idx_trades_bid_order_id ON trades(bid_order_id);
 -- Time-based trade queries
 CREATE INDEX idx_trades_timestamp ON trades(market, timestamp);
 -- Price-based queries (for analytics)
-CREATE INDEX idx_trades_price ON trades(market, price);
\ No newline at end of file
+CREATE INDEX idx_trades_price ON trades(market, price);
+-- User fills queries
+CREATE INDEX idx_trades_bid_user_id ON trades(bid_user_id, timestamp);
+CREATE INDEX idx_trades_ask_user_id ON trades(ask_user_id, timestamp);
+
+DROP TABLE IF EXISTS withdrawals;
+CREATE TABLE withdrawals
+(
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT     NOT NULL,
+    asset      TEXT     NOT NULL,
+    amount     REAL     NOT NULL,
+    address    TEXT     NOT NULL,
+    valuation  REAL DEFAULT 0, -- USDT value when requested
+    status     TEXT     NOT NULL, -- PENDING, APPROVED, BROADCAST, CONFIRMED, REJECTED
+    tx_hash    TEXT DEFAULT '',
+    remark     TEXT DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_withdrawals_user_id ON withdrawals(user_id, created_at);
+CREATE INDEX idx_withdrawals_status ON withdrawals(status, created_at);
+
+
+DROP TABLE IF EXISTS withdrawal_addresses;
+CREATE TABLE withdrawal_addresses
+(
+    id           INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id      TEXT     NOT NULL,
+    asset        TEXT     NOT NULL,
+    address      TEXT     NOT NULL,
+    label        TEXT DEFAULT '',
+    created_at   DATETIME NOT NULL,
+    available_at DATETIME NOT NULL, -- created_at + cooling-off period
+    UNIQUE (user_id, asset, address)
+);
+
+
+DROP TABLE IF EXISTS withdrawal_audits;
+CREATE TABLE withdrawal_audits
+(
+    id            INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    withdrawal_id TEXT     NOT NULL,
+    from_status   TEXT DEFAULT '',
+    to_status     TEXT     NOT NULL,
+    operator      TEXT     NOT NULL, -- userId, SYSTEM or ADMIN
+    remark        TEXT DEFAULT '',
+    created_at    DATETIME NOT NULL
+);
+
+CREATE INDEX idx_withdrawal_audits_withdrawal_id ON withdrawal_audits(withdrawal_id);
+
+
+DROP TABLE IF EXISTS transfers;
+CREATE TABLE transfers
+(
+    id              TEXT PRIMARY KEY,
+    idempotency_key TEXT     NOT NULL,
+    from_user_id    TEXT     NOT NULL,
+    to_user_id      TEXT     NOT NULL,
+    asset           TEXT     NOT NULL,
+    amount          REAL     NOT NULL,
+    type            TEXT     NOT NULL, -- INTERNAL, SUB_ACCOUNT, MARGIN
+    remark          TEXT DEFAULT '',
+    valuation       REAL DEFAULT 0, -- USDT value of transfer to other user, counted by withdrawal daily limit
+    created_at      DATETIME NOT NULL,
+    UNIQUE (from_user_id, idempotency_key)
+);
+
+CREATE INDEX idx_transfers_from_user_id ON transfers(from_user_id, created_at);
+CREATE INDEX idx_transfers_to_user_id ON transfers(to_user_id, created_at);
+
+
+DROP TABLE IF EXISTS sub_accounts;
+CREATE TABLE sub_accounts
+(
+    sub_user_id    TEXT PRIMARY KEY,
+    master_user_id TEXT     NOT NULL,
+    label          TEXT DEFAULT '',
+    created_at     DATETIME NOT NULL
+);
+
+CREATE INDEX idx_sub_accounts_master_user_id ON sub_accounts(master_user_id);
+
+
+DROP TABLE IF EXISTS fee_schedules;
+CREATE TABLE fee_schedules
+(
+    vip_level  INTEGER PRIMARY KEY,
+    min_volume REAL NOT NULL, -- rolling 30-day quote volume (USDT) threshold
+    maker_fee  REAL NOT NULL,
+    taker_fee  REAL NOT NULL
+);
+
+INSERT INTO fee_schedules(vip_level, min_volume, maker_fee, taker_fee)
+VALUES (1, 0, 0.001, 0.002),
+       (2, 50000, 0.0009, 0.0018),
+       (3, 250000, 0.0008, 0.0016),
+       (4, 1000000, 0.0006, 0.0014),
+       (5, 5000000, 0.0004, 0.0012),
+       (6, 20000000, 0.0002, 0.001);
+
+
+DROP TABLE IF EXISTS vip_level_histories;
+CREATE TABLE vip_level_histories
+(
+    id             INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id        TEXT     NOT NULL,
+    from_vip_level INTEGER  NOT NULL,
+    to_vip_level   INTEGER  NOT NULL,
+    maker_fee      REAL     NOT NULL,
+    taker_fee      REAL     NOT NULL,
+    volume_30d     REAL     NOT NULL,
+    created_at     DATETIME NOT NULL
+);
+
+CREATE INDEX idx_vip_level_histories_user_id ON vip_level_histories(user_id, created_at);
+
+
+DROP TABLE IF EXISTS fee_revenues;
+CREATE TABLE fee_revenues
+(
+    id         INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    market     TEXT     NOT NULL,
+    asset      TEXT     NOT NULL,
+    fee_income REAL     NOT NULL DEFAULT 0, -- fees collected to margin account
+    rebate     REAL     NOT NULL DEFAULT 0, -- maker rebates paid by margin account
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_fee_revenues_created_at ON fee_revenues(created_at, asset);
+
+
+DROP TABLE IF EXISTS referral_codes;
+CREATE TABLE referral_codes
+(
+    user_id    TEXT PRIMARY KEY,
+    code       TEXT UNIQUE NOT NULL,
+    created_at DATETIME    NOT NULL
+);
+
+
+DROP TABLE IF EXISTS referrals;
+CREATE TABLE referrals
+(
+    referee_id  TEXT PRIMARY KEY, -- one user can only be referred once
+    referrer_id TEXT     NOT NULL,
+    created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX idx_referrals_referrer_id ON referrals(referrer_id);
+
+
+DROP TABLE IF EXISTS referral_commissions;
+CREATE TABLE referral_commissions
+(
+    id          INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    referrer_id TEXT     NOT NULL,
+    referee_id  TEXT     NOT NULL,
+    order_id    TEXT     NOT NULL,
+    market      TEXT     NOT NULL,
+    asset       TEXT     NOT NULL,
+    fees        REAL     NOT NULL, -- referee paid fees
+    commission  REAL     NOT NULL, -- paid to referrer by margin account
+    created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX idx_referral_commissions_referrer_id ON referral_commissions(referrer_id, created_at);
+
+
+DROP TABLE IF EXISTS cost_bases;
+CREATE TABLE cost_bases
+(
+    user_id    TEXT     NOT NULL,
+    asset      TEXT     NOT NULL,
+    quantity   REAL     NOT NULL DEFAULT 0, -- quantity tracked by trades
+    avg_cost   REAL     NOT NULL DEFAULT 0, -- average cost in quote asset (USDT)
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, asset)
+);
+
+
+DROP TABLE IF EXISTS realized_pnls;
+CREATE TABLE realized_pnls
+(
+    id         INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id    TEXT     NOT NULL,
+    market     TEXT     NOT NULL,
+    asset      TEXT     NOT NULL,
+    size       REAL     NOT NULL,
+    proceeds   REAL     NOT NULL, -- quote asset received (after fees)
+    cost       REAL     NOT NULL, -- size * avg_cost
+    pnl        REAL     NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_realized_pnls_user_id ON realized_pnls(user_id, created_at);
+
+
+DROP TABLE IF EXISTS equity_snapshots;
+CREATE TABLE equity_snapshots
+(
+    user_id       TEXT     NOT NULL,
+    snapshot_date TEXT     NOT NULL, -- 2006-01-02, equity at 00:00 UTC
+    equity        REAL     NOT NULL,
+    quote_asset   TEXT     NOT NULL DEFAULT 'USDT',
+    created_at    DATETIME NOT NULL,
+    PRIMARY KEY (user_id, snapshot_date)
+);
+
+
+DROP TABLE IF EXISTS margin_accounts;
+CREATE TABLE margin_accounts
+(
+    user_id        TEXT PRIMARY KEY,
+    margin_user_id TEXT UNIQUE NOT NULL, -- internal user holds margin wallet balances and margin orders
+    created_at     DATETIME    NOT NULL
+);
+
+
+DROP TABLE IF EXISTS margin_loans;
+CREATE TABLE margin_loans
+(
+    user_id    TEXT     NOT NULL,
+    asset      TEXT     NOT NULL,
+    principal  REAL     NOT NULL DEFAULT 0,
+    interest   REAL     NOT NULL DEFAULT 0, -- accrued unpaid interest
+    accrued_at DATETIME NOT NULL,           -- interest is accrued up to this time
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, asset)
+);
+
+
+DROP TABLE IF EXISTS margin_loan_records;
+CREATE TABLE margin_loan_records
+(
+    id         INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id    TEXT     NOT NULL,
+    asset      TEXT     NOT NULL,
+    type       TEXT     NOT NULL, -- BORROW, REPAY, INSURANCE
+    principal  REAL     NOT NULL DEFAULT 0,
+    interest   REAL     NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_margin_loan_records_user_id ON margin_loan_records(user_id, created_at);
+
+
+DROP TABLE IF EXISTS margin_calls;
+CREATE TABLE margin_calls
+(
+    id           INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id      TEXT     NOT NULL,
+    margin_ratio REAL     NOT NULL,
+    asset_value  REAL     NOT NULL, -- USDT
+    debt_value   REAL     NOT NULL, -- USDT
+    created_at   DATETIME NOT NULL
+);
+
+CREATE INDEX idx_margin_calls_user_id ON margin_calls(user_id, created_at);
+
+
+DROP TABLE IF EXISTS liquidations;
+CREATE TABLE liquidations
+(
+    id             INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id        TEXT     NOT NULL,
+    margin_user_id TEXT     NOT NULL,
+    margin_ratio   REAL     NOT NULL, -- margin ratio when liquidation triggered
+    asset_value    REAL     NOT NULL, -- USDT
+    debt_value     REAL     NOT NULL, -- USDT
+    status         TEXT     NOT NULL, -- COMPLETED, BAD_DEBT, FAILED
+    details        TEXT     NOT NULL, -- JSON: canceled orders, liquidation orders, repayments, shortfalls
+    error          TEXT     NOT NULL DEFAULT '',
+    created_at     DATETIME NOT NULL
+);
+
+CREATE INDEX idx_liquidations_user_id ON liquidations(user_id, created_at);
+
+
+DROP TABLE IF EXISTS perpetual_positions;
+CREATE TABLE perpetual_positions
+(
+    user_id      TEXT     NOT NULL,
+    market       TEXT     NOT NULL,
+    size         REAL     NOT NULL DEFAULT 0, -- contracts, positive: long, negative: short
+    entry_price  REAL     NOT NULL DEFAULT 0,
+    margin       REAL     NOT NULL DEFAULT 0, -- quote asset, kept in locked balance
+    realized_pnl REAL     NOT NULL DEFAULT 0, -- accumulated, funding payments excluded
+    updated_at   DATETIME NOT NULL,
+    PRIMARY KEY (user_id, market)
+);
+
+CREATE INDEX idx_perpetual_positions_market ON perpetual_positions(market);
+
+
+DROP TABLE IF EXISTS funding_rates;
+CREATE TABLE funding_rates
+(
+    id           INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    market       TEXT     NOT NULL,
+    funding_rate REAL     NOT NULL,
+    mark_price   REAL     NOT NULL,
+    index_price  REAL     NOT NULL,
+    created_at   DATETIME NOT NULL
+);
+
+CREATE INDEX idx_funding_rates_market ON funding_rates(market, created_at);
+
+
+DROP TABLE IF EXISTS funding_payments;
+CREATE TABLE funding_payments
+(
+    id            INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id       TEXT     NOT NULL,
+    market        TEXT     NOT NULL,
+    asset         TEXT     NOT NULL,
+    position_size REAL     NOT NULL,
+    mark_price    REAL     NOT NULL,
+    funding_rate  REAL     NOT NULL,
+    amount        REAL     NOT NULL, -- positive: received, negative: paid
+    created_at    DATETIME NOT NULL
+);
+
+CREATE INDEX idx_funding_payments_user_id ON funding_payments(user_id, created_at);
+
+
+DROP TABLE IF EXISTS perpetual_liquidations;
+CREATE TABLE perpetual_liquidations
+(
+    id          INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id     TEXT     NOT NULL,
+    market      TEXT     NOT NULL,
+    size        REAL     NOT NULL, -- contracts closed, positive: long, negative: short
+    entry_price REAL     NOT NULL,
+    mark_price  REAL     NOT NULL,
+    margin      REAL     NOT NULL, -- released position margin
+    pnl         REAL     NOT NULL, -- realized pnl, loss capped at margin
+    bad_debt    REAL     NOT NULL, -- loss exceeding margin, absorbed by insurance fund
+    created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX idx_perpetual_liquidations_user_id ON perpetual_liquidations(user_id, created_at);
+
+
+DROP TABLE IF EXISTS perpetual_market_halts;
+CREATE TABLE perpetual_market_halts
+(
+    market     TEXT     NOT NULL
+        PRIMARY KEY,
+    reason     TEXT     NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+
+DROP TABLE IF EXISTS converts;
+CREATE TABLE converts
+(
+    id               INTEGER
+        PRIMARY KEY AUTOINCREMENT,
+    user_id          TEXT     NOT NULL,
+    quote_id         TEXT     NOT NULL UNIQUE,
+    from_asset       TEXT     NOT NULL,
+    to_asset         TEXT     NOT NULL,
+    from_amount      REAL     NOT NULL, -- actually spent
+    to_amount        REAL     NOT NULL, -- actually received
+    quoted_to_amount REAL     NOT NULL,
+    order_ids        TEXT     NOT NULL, -- json array of IOC order ids
+    status           TEXT     NOT NULL, -- COMPLETED, PARTIAL
+    created_at       DATETIME NOT NULL
+);
+
+CREATE INDEX idx_converts_user_id ON converts(user_id, created_at);
+
+
+DROP TABLE IF EXISTS rfq_providers;
+CREATE TABLE rfq_providers
+(
+    user_id    TEXT PRIMARY KEY,
+    created_at DATETIME NOT NULL
+);
+
+
+DROP TABLE IF EXISTS rfq_requests;
+CREATE TABLE rfq_requests
+(
+    id                TEXT PRIMARY KEY,
+    user_id           TEXT     NOT NULL,
+    market            TEXT     NOT NULL,
+    side              INTEGER  NOT NULL, -- taker side, 0=Bid,1=Ask
+    size              REAL     NOT NULL,
+    status            TEXT     NOT NULL, -- OPEN, FILLED, CANCELED, EXPIRED
+    accepted_quote_id TEXT DEFAULT '',
+    expires_at        DATETIME NOT NULL,
+    created_at        DATETIME NOT NULL,
+    updated_at        DATETIME NOT NULL
+);
+
+CREATE INDEX idx_rfq_requests_user_id ON rfq_requests(user_id, created_at);
+CREATE INDEX idx_rfq_requests_status ON rfq_requests(status, expires_at);
+
+
+DROP TABLE IF EXISTS rfq_quotes;
+CREATE TABLE rfq_quotes
+(
+    id          TEXT PRIMARY KEY,
+    request_id  TEXT     NOT NULL,
+    provider_id TEXT     NOT NULL,
+    price       REAL     NOT NULL,
+    status      TEXT     NOT NULL, -- OPEN, ACCEPTED, REJECTED, CANCELED
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+
+CREATE INDEX idx_rfq_quotes_request_id ON rfq_quotes(request_id);
+CREATE INDEX idx_rfq_quotes_provider_id ON rfq_quotes(provider_id, created_at);
+
+
+DROP TABLE IF EXISTS api_keys;
+CREATE TABLE api_keys
+(
+    api_key      TEXT PRIMARY KEY,
+    user_id      TEXT     NOT NULL,
+    label        TEXT     NOT NULL DEFAULT '',
+    secret       TEXT     NOT NULL, -- HMAC-SHA256 signing secret, only returned when created
+    scopes       TEXT     NOT NULL, -- comma separated: read, trade, withdraw
+    ip_allowlist TEXT     NOT NULL DEFAULT '', -- comma separated IPs or CIDRs, empty: any IP
+    expires_at   DATETIME, -- NULL: never expires
+    revoked      INTEGER  NOT NULL DEFAULT 0,
+    last_used_at DATETIME,
+    created_at   DATETIME NOT NULL
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+
+DROP TABLE IF EXISTS sessions;
+CREATE TABLE sessions
+(
+    id                 TEXT PRIMARY KEY,
+    user_id            TEXT     NOT NULL,
+    token_hash         TEXT     NOT NULL UNIQUE, -- sha256 of access token
+    refresh_token_hash TEXT     NOT NULL UNIQUE, -- sha256 of refresh token
+    client_ip          TEXT     NOT NULL DEFAULT '',
+    user_agent         TEXT     NOT NULL DEFAULT '',
+    created_at         DATETIME NOT NULL,
+    last_active_at     DATETIME NOT NULL,
+    expires_at         DATETIME NOT NULL, -- access token absolute expiry
+    refresh_expires_at DATETIME NOT NULL  -- session absolute expiry
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_refresh_expires_at ON sessions(refresh_expires_at);
+
+DROP TABLE IF EXISTS password_reset_tokens;
+-- single-use password reset tokens, at most one per user, row is deleted when used.
+CREATE TABLE password_reset_tokens
+(
+    token_hash TEXT PRIMARY KEY, -- sha256 of reset token
+    user_id    TEXT     NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+
+DROP TABLE IF EXISTS admins;
+CREATE TABLE admins
+(
+    id            TEXT PRIMARY KEY,
+    username      TEXT     NOT NULL UNIQUE,
+    password_hash TEXT     NOT NULL,
+    role          TEXT     NOT NULL, -- support, finance, ops, superadmin
+    totp_secret   TEXT     NOT NULL, -- second factor, base32
+    disabled      INTEGER  NOT NULL DEFAULT 0,
+    created_at    DATETIME NOT NULL,
+    updated_at    DATETIME NOT NULL
+);
+
+DROP TABLE IF EXISTS manual_adjustments;
+CREATE TABLE manual_adjustments
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_id   TEXT     NOT NULL,
+    user_id    TEXT     NOT NULL,
+    asset      TEXT     NOT NULL,
+    amount     REAL     NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_manual_adjustments_user_id ON manual_adjustments(user_id);
+
+DROP TABLE IF EXISTS audit_logs;
+
+-- append-only, hash = sha256(prev_hash and fields), rows are never updated or deleted.
+CREATE TABLE audit_logs
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_type   TEXT     NOT NULL, -- ADMIN, USER, SYSTEM
+    actor_id     TEXT     NOT NULL DEFAULT '',
+    actor_name   TEXT     NOT NULL DEFAULT '',
+    action       TEXT     NOT NULL,
+    target       TEXT     NOT NULL DEFAULT '',
+    client_ip    TEXT     NOT NULL DEFAULT '',
+    user_agent   TEXT     NOT NULL DEFAULT '',
+    request_id   TEXT     NOT NULL DEFAULT '',
+    before_value TEXT     NOT NULL DEFAULT '', -- JSON
+    after_value  TEXT     NOT NULL DEFAULT '', -- JSON
+    prev_hash    TEXT     NOT NULL DEFAULT '',
+    hash         TEXT     NOT NULL,
+    created_at   DATETIME NOT NULL
+);
+
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
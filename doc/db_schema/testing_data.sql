@@ -6,6 +6,14 @@ update balances set available = 0, locked = 0 where TRUE;
 INSERT INTO users(id,username,password_hash,vip_level,maker_fee, taker_fee)
 values ('0', 'margin_account', '$2a$10$z.kl4/Zazgme18gFCqwozOk5WoqMbhqAeZk5.zk55gwVgurQCwqpq', 0, 0, 0);
 
+-- Create Insurance Fund Account (reserved id of settings.INSURANCE_FUND_ACCOUNT_ID, password hash is not bcrypt so login is impossible)
+INSERT INTO users(id,username,password_hash,vip_level,maker_fee, taker_fee)
+values ('SYS_INSURANCE_FUND', 'insurance_fund', '!', 0, 0, 0);
+
+-- Create Margin Lending Pool Account (reserved id of settings.MARGIN_LENDING_POOL_ACCOUNT_ID, login is impossible)
+INSERT INTO users(id,username,password_hash,vip_level,maker_fee, taker_fee)
+values ('SYS_MARGIN_LENDING_POOL', 'margin_lending_pool', '!', 0, 0, 0);
+
 -- Create Testing Maker Account
 INSERT INTO users(id,username,password_hash,vip_level,maker_fee, taker_fee)
 values ('MID250606CXAZ1199', 'market_maker', '$2a$10$z.kl4/Zazgme18gFCqwozOk5WoqMbhqAeZk5.zk55gwVgurQCwqpq', 7, 0.0001, 0.002);
@@ -38,6 +46,38 @@ VALUES ('0', 'USDT', 0, 0),
        ('0', 'DOGE', 0, 0),
        ('0', 'BTSE', 0, 0);
 
+-- Create Balances for Insurance Fund Account
+INSERT INTO balances(user_id,asset,available,locked)
+VALUES ('SYS_INSURANCE_FUND', 'USDT', 1000000, 0),
+       ('SYS_INSURANCE_FUND', 'BTC', 10, 0),
+       ('SYS_INSURANCE_FUND', 'ETH', 100, 0),
+       ('SYS_INSURANCE_FUND', 'DOT', 0, 0),
+       ('SYS_INSURANCE_FUND', 'ASTR', 0, 0),
+       ('SYS_INSURANCE_FUND', 'HDX', 0, 0),
+       ('SYS_INSURANCE_FUND', 'SOL', 0, 0),
+       ('SYS_INSURANCE_FUND', 'LINK', 0, 0),
+       ('SYS_INSURANCE_FUND', 'ADA', 0, 0),
+       ('SYS_INSURANCE_FUND', 'BNB', 0, 0),
+       ('SYS_INSURANCE_FUND', 'AVAX', 0, 0),
+       ('SYS_INSURANCE_FUND', 'DOGE', 0, 0),
+       ('SYS_INSURANCE_FUND', 'BTSE', 0, 0);
+
+-- Create Balances for Margin Lending Pool Account
+INSERT INTO balances(user_id,asset,available,locked)
+VALUES ('SYS_MARGIN_LENDING_POOL', 'USDT', 1000000, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'BTC', 10, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'ETH', 100, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'DOT', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'ASTR', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'HDX', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'SOL', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'LINK', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'ADA', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'BNB', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'AVAX', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'DOGE', 0, 0),
+       ('SYS_MARGIN_LENDING_POOL', 'BTSE', 0, 0);
+
 -- Create Balances for Maker Account
 INSERT INTO balances(user_id,asset,available,locked)
 VALUES ('MID250606CXAZ1199', 'USDT', 88150000, 0),
@@ -133,3 +173,7 @@ VALUES ('UID25060650QA0003', 'USDT', 1000000, 0),
        ('UID25060650QA0003', 'DOGE', 0, 0),
        ('UID25060650QA0003', 'BTSE', 500, 0);
 
+
+-- Create Testing Superadmin (password: 1234, TOTP secret: JBSWY3DPEHPK3PXP)
+INSERT INTO admins(id,username,password_hash,role,totp_secret,disabled,created_at,updated_at)
+values ('ADM000000000001', 'frizo', '$2a$10$z.kl4/Zazgme18gFCqwozOk5WoqMbhqAeZk5.zk55gwVgurQCwqpq', 'superadmin', 'JBSWY3DPEHPK3PXP', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
@@ -0,0 +1,145 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AdminRole string
+
+const (
+	// ADMIN_ROLE_SUPPORT read-only.
+	ADMIN_ROLE_SUPPORT AdminRole = "support"
+	// ADMIN_ROLE_FINANCE balance adjustments, withdrawal reviews and fee tiers.
+	ADMIN_ROLE_FINANCE AdminRole = "finance"
+	// ADMIN_ROLE_OPS market halts, AMM control and RFQ providers.
+	ADMIN_ROLE_OPS AdminRole = "ops"
+	// ADMIN_ROLE_SUPERADMIN all permissions, including managing admin accounts.
+	ADMIN_ROLE_SUPERADMIN AdminRole = "superadmin"
+)
+
+type AdminPermission string
+
+const (
+	ADMIN_PERMISSION_READ    AdminPermission = "read"
+	ADMIN_PERMISSION_FUNDS   AdminPermission = "funds"
+	ADMIN_PERMISSION_MARKETS AdminPermission = "markets"
+	ADMIN_PERMISSION_ADMINS  AdminPermission = "admins"
+)
+
+var adminRolePermissions = map[AdminRole][]AdminPermission{
+	ADMIN_ROLE_SUPPORT:    {ADMIN_PERMISSION_READ},
+	ADMIN_ROLE_FINANCE:    {ADMIN_PERMISSION_READ, ADMIN_PERMISSION_FUNDS},
+	ADMIN_ROLE_OPS:        {ADMIN_PERMISSION_READ, ADMIN_PERMISSION_MARKETS},
+	ADMIN_ROLE_SUPERADMIN: {ADMIN_PERMISSION_READ, ADMIN_PERMISSION_FUNDS, ADMIN_PERMISSION_MARKETS, ADMIN_PERMISSION_ADMINS},
+}
+
+func (r AdminRole) Valid() bool {
+	_, ok := adminRolePermissions[r]
+	return ok
+}
+
+func (r AdminRole) HasPermission(permission AdminPermission) bool {
+	for _, p := range adminRolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Admin back office account, TotpSecret and TotpURI are only returned when created or rotated.
+type Admin struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         AdminRole `json:"role"`
+	TotpSecret   string    `json:"totp_secret,omitempty"`
+	TotpURI      string    `json:"totp_uri,omitempty"`
+	Disabled     bool      `json:"disabled"`
+	CreatedAt    time.Time `json:"-"`
+	UpdatedAt    time.Time `json:"-"`
+}
+
+func (a Admin) MarshalJSON() ([]byte, error) {
+	type Alias Admin
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+		UpdatedAt int64 `json:"updated_at"`
+	}{
+		Alias:     (*Alias)(&a),
+		CreatedAt: a.CreatedAt.UnixMilli(),
+		UpdatedAt: a.UpdatedAt.UnixMilli(),
+	})
+}
+
+// ManualAdjustment balance credited by admin through manual adjustment.
+type ManualAdjustment struct {
+	ID            int64     `json:"id"`
+	AdminID       string    `json:"admin_id"`
+	AdminUsername string    `json:"admin_username"`
+	UserID        string    `json:"user_id"`
+	Asset         string    `json:"asset"`
+	Amount        float64   `json:"amount"`
+	CreatedAt     time.Time `json:"-"`
+}
+
+func (m ManualAdjustment) MarshalJSON() ([]byte, error) {
+	type Alias ManualAdjustment
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&m),
+		CreatedAt: m.CreatedAt.UnixMilli(),
+	})
+}
+
+// AdminSession admin login token.
+type AdminSession struct {
+	Token     string    `json:"token"`
+	Admin     *Admin    `json:"admin"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+func (s AdminSession) MarshalJSON() ([]byte, error) {
+	type Alias AdminSession
+	return json.Marshal(&struct {
+		*Alias
+		ExpiresAt int64 `json:"expires_at"`
+	}{
+		Alias:     (*Alias)(&s),
+		ExpiresAt: s.ExpiresAt.UnixMilli(),
+	})
+}
+
+type AdminLoginReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	TotpCode string `json:"totp_code" binding:"required"`
+}
+
+type CreateAdminReq struct {
+	Username string    `json:"username" binding:"required"`
+	Password string    `json:"password" binding:"required,min=8"`
+	Role     AdminRole `json:"role" binding:"required"`
+}
+
+// UpdateAdminReq nil fields are not updated.
+type UpdateAdminReq struct {
+	Role     *AdminRole `json:"role"`
+	Disabled *bool      `json:"disabled"`
+}
+
+type AdminChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,password"`
+	TotpCode    string `json:"totp_code" binding:"required"`
+}
+
+// AdminRotateTotpReq TotpCode is current code of old secret.
+type AdminRotateTotpReq struct {
+	Password string `json:"password" binding:"required"`
+	TotpCode string `json:"totp_code" binding:"required"`
+}
@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ApiKeyScope string
+
+const (
+	// API_KEY_SCOPE_READ query endpoints (GET).
+	API_KEY_SCOPE_READ ApiKeyScope = "read"
+	// API_KEY_SCOPE_TRADE place and cancel orders and other trading actions.
+	API_KEY_SCOPE_TRADE ApiKeyScope = "trade"
+	// API_KEY_SCOPE_WITHDRAW withdrawals, transfers and sub-account transfers.
+	API_KEY_SCOPE_WITHDRAW ApiKeyScope = "withdraw"
+)
+
+// ApiKey user managed key of signed requests, Secret is only returned when created.
+type ApiKey struct {
+	ApiKey      string        `json:"api_key"`
+	UserID      string        `json:"-"`
+	Label       string        `json:"label"`
+	Secret      string        `json:"secret,omitempty"`
+	Scopes      []ApiKeyScope `json:"scopes"`
+	IPAllowlist []string      `json:"ip_allowlist"` // empty: any IP
+	ExpiresAt   *time.Time    `json:"-"`            // nil: never expires
+	Revoked     bool          `json:"revoked"`
+	LastUsedAt  *time.Time    `json:"-"`
+	CreatedAt   time.Time     `json:"-"`
+}
+
+func (k ApiKey) MarshalJSON() ([]byte, error) {
+	type Alias ApiKey
+	return json.Marshal(&struct {
+		*Alias
+		ExpiresAt  int64 `json:"expires_at"`   // 0: never expires
+		LastUsedAt int64 `json:"last_used_at"` // 0: never used
+		CreatedAt  int64 `json:"created_at"`
+	}{
+		Alias:      (*Alias)(&k),
+		ExpiresAt:  unixMilliOrZero(k.ExpiresAt),
+		LastUsedAt: unixMilliOrZero(k.LastUsedAt),
+		CreatedAt:  k.CreatedAt.UnixMilli(),
+	})
+}
+
+func (k *ApiKey) HasScope(scope ApiKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ApiKeyAuth signed request to verify, Signature is hex HMAC-SHA256 of Timestamp + Method + Path + Body.
+type ApiKeyAuth struct {
+	ApiKey    string
+	Timestamp string // unix milliseconds
+	Signature string
+	Method    string
+	Path      string // request uri with query string
+	Body      string
+	ClientIP  string
+}
+
+func unixMilliOrZero(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// CreateApiKeyReq IPAllowlist items are IPs or CIDRs, ExpiresAt is unix milliseconds, 0 never expires.
+type CreateApiKeyReq struct {
+	Label       string        `json:"label" binding:"max=64"`
+	Scopes      []ApiKeyScope `json:"scopes" binding:"required,min=1"`
+	IPAllowlist []string      `json:"ip_allowlist"`
+	ExpiresAt   int64         `json:"expires_at" binding:"gte=0"`
+	// TotpCode required if user enabled 2FA.
+	TotpCode string `json:"totp_code"`
+}
+
+type UpdateApiKeyReq struct {
+	Label string `json:"label" binding:"max=64"`
+}
@@ -0,0 +1,127 @@
+package dto
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type AuditActorType string
+
+const (
+	AUDIT_ACTOR_ADMIN  AuditActorType = "ADMIN"
+	AUDIT_ACTOR_USER   AuditActorType = "USER"
+	AUDIT_ACTOR_SYSTEM AuditActorType = "SYSTEM"
+)
+
+type AuditAction string
+
+const (
+	// admin actions
+	AUDIT_ADMIN_LOGIN         AuditAction = "ADMIN_LOGIN"
+	AUDIT_ADMIN_LOGIN_FAILED  AuditAction = "ADMIN_LOGIN_FAILED"
+	AUDIT_ADMIN_LOGOUT        AuditAction = "ADMIN_LOGOUT"
+	AUDIT_ADMIN_CREATE        AuditAction = "ADMIN_CREATE"
+	AUDIT_ADMIN_UPDATE        AuditAction = "ADMIN_UPDATE"
+	AUDIT_ADMIN_PASSWORD      AuditAction = "ADMIN_PASSWORD_CHANGE"
+	AUDIT_ADMIN_TOTP_ROTATE   AuditAction = "ADMIN_TOTP_ROTATE"
+	AUDIT_MANUAL_ADJUSTMENT   AuditAction = "MANUAL_ADJUSTMENT"
+	AUDIT_TEST_MAKE_MARKET    AuditAction = "TEST_MAKE_MARKET"
+	AUDIT_WITHDRAWAL_REVIEW   AuditAction = "WITHDRAWAL_REVIEW"
+	AUDIT_MARKET_MAKER_TIER   AuditAction = "MARKET_MAKER_TIER"
+	AUDIT_ACCOUNT_STATUS      AuditAction = "ACCOUNT_STATUS"
+	AUDIT_RFQ_PROVIDER_ADD    AuditAction = "RFQ_PROVIDER_ADD"
+	AUDIT_RFQ_PROVIDER_REMOVE AuditAction = "RFQ_PROVIDER_REMOVE"
+	AUDIT_PERPETUAL_RESUME    AuditAction = "PERPETUAL_RESUME"
+
+	// user security actions
+	AUDIT_USER_LOGIN             AuditAction = "USER_LOGIN"
+	AUDIT_USER_LOGIN_FAILED      AuditAction = "USER_LOGIN_FAILED"
+	AUDIT_PASSWORD_CHANGE        AuditAction = "PASSWORD_CHANGE"
+	AUDIT_PASSWORD_RESET_REQUEST AuditAction = "PASSWORD_RESET_REQUEST"
+	AUDIT_PASSWORD_RESET         AuditAction = "PASSWORD_RESET"
+	AUDIT_TWO_FACTOR_ENABLE      AuditAction = "TWO_FACTOR_ENABLE"
+	AUDIT_TWO_FACTOR_DISABLE     AuditAction = "TWO_FACTOR_DISABLE"
+	AUDIT_BACKUP_CODES_RESET     AuditAction = "BACKUP_CODES_RESET"
+	AUDIT_API_KEY_CREATE         AuditAction = "API_KEY_CREATE"
+	AUDIT_API_KEY_UPDATE         AuditAction = "API_KEY_UPDATE"
+	AUDIT_API_KEY_REVOKE         AuditAction = "API_KEY_REVOKE"
+)
+
+// AuditLog append-only, Hash = sha256 of PrevHash and fields, so changing or deleting a row breaks the chain.
+// Before and After are JSON text, empty if not applicable.
+type AuditLog struct {
+	ID        int64          `json:"id"`
+	ActorType AuditActorType `json:"actor_type"`
+	ActorID   string         `json:"actor_id"`
+	ActorName string         `json:"actor_name"`
+	Action    AuditAction    `json:"action"`
+	Target    string         `json:"target"`
+	ClientIP  string         `json:"client_ip"`
+	UserAgent string         `json:"user_agent"`
+	RequestID string         `json:"request_id"`
+	Before    string         `json:"-"`
+	After     string         `json:"-"`
+	PrevHash  string         `json:"prev_hash"`
+	Hash      string         `json:"hash"`
+	CreatedAt time.Time      `json:"-"`
+}
+
+func (a AuditLog) MarshalJSON() ([]byte, error) {
+	type Alias AuditLog
+	return json.Marshal(&struct {
+		*Alias
+		Before    json.RawMessage `json:"before"`
+		After     json.RawMessage `json:"after"`
+		CreatedAt int64           `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&a),
+		Before:    rawJSONOrNull(a.Before),
+		After:     rawJSONOrNull(a.After),
+		CreatedAt: a.CreatedAt.UnixMilli(),
+	})
+}
+
+func rawJSONOrNull(s string) json.RawMessage {
+	if s == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(s)
+}
+
+// AuditVerifyResult BrokenID is the first row breaking hash chain, 0 if chain is valid.
+type AuditVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenID int64  `json:"broken_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// RequestMeta of http request, carried in request context for audit logs.
+type RequestMeta struct {
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+type requestMetaKey struct{}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFrom empty meta if ctx is not from http request (e.g. schedulers).
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
+
+// AuditLogQueryReq From, To in unix milliseconds, all filters are optional.
+type AuditLogQueryReq struct {
+	ActorID string      `form:"actor_id"`
+	Action  AuditAction `form:"action"`
+	Target  string      `form:"target"`
+	From    int64       `form:"from"`
+	To      int64       `form:"to"`
+	Limit   int         `form:"limit,default=50"`
+}
@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"time"
+)
+
+// ConvertLeg one IOC order of convert route, Price is the worst price level reached (limit price of the order).
+type ConvertLeg struct {
+	Market     string     `json:"market"`
+	Side       model.Side `json:"side"`
+	FromAsset  string     `json:"from_asset"`
+	ToAsset    string     `json:"to_asset"`
+	Price      float64    `json:"price"`
+	Size       float64    `json:"size"`
+	FromAmount float64    `json:"from_amount"`
+	ToAmount   float64    `json:"to_amount"` // after taker fees
+}
+
+// ConvertQuote priced off current order book depth, ToAmount is guaranteed if accepted before ExpiresAt.
+type ConvertQuote struct {
+	QuoteID    string        `json:"quote_id"`
+	UserID     string        `json:"-"`
+	FromAsset  string        `json:"from_asset"`
+	ToAsset    string        `json:"to_asset"`
+	FromAmount float64       `json:"from_amount"`
+	ToAmount   float64       `json:"to_amount"`
+	Price      float64       `json:"price"` // to asset per from asset
+	Legs       []*ConvertLeg `json:"legs"`
+	ExpiresAt  time.Time     `json:"-"`
+}
+
+func (q ConvertQuote) MarshalJSON() ([]byte, error) {
+	type Alias ConvertQuote
+	return json.Marshal(&struct {
+		*Alias
+		ExpiresAt int64 `json:"expires_at"`
+	}{
+		Alias:     (*Alias)(&q),
+		ExpiresAt: q.ExpiresAt.UnixMilli(),
+	})
+}
+
+type ConvertStatus string
+
+const (
+	CONVERT_STATUS_COMPLETED ConvertStatus = "COMPLETED"
+	// CONVERT_STATUS_PARTIAL order book changed between legs, unconverted amount stays in from asset or intermediate asset.
+	CONVERT_STATUS_PARTIAL ConvertStatus = "PARTIAL"
+)
+
+// Convert executed convert, ToAmount is actually received.
+type Convert struct {
+	ID             int64         `json:"id"`
+	UserID         string        `json:"-"`
+	QuoteID        string        `json:"quote_id"`
+	FromAsset      string        `json:"from_asset"`
+	ToAsset        string        `json:"to_asset"`
+	FromAmount     float64       `json:"from_amount"`
+	ToAmount       float64       `json:"to_amount"`
+	QuotedToAmount float64       `json:"quoted_to_amount"`
+	OrderIDs       []string      `json:"order_ids"`
+	Status         ConvertStatus `json:"status"`
+	CreatedAt      time.Time     `json:"-"`
+}
+
+func (c Convert) MarshalJSON() ([]byte, error) {
+	type Alias Convert
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&c),
+		CreatedAt: c.CreatedAt.UnixMilli(),
+	})
+}
+
+type ConvertQuoteReq struct {
+	FromAsset  string  `json:"from_asset" binding:"required"`
+	ToAsset    string  `json:"to_asset" binding:"required"`
+	FromAmount float64 `json:"from_amount" binding:"required,gt=0"`
+}
+
+type ConvertAcceptReq struct {
+	QuoteID string `json:"quote_id" binding:"required"`
+}
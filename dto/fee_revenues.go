@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FeeRevenue fee income and maker rebates of one settlement by asset.
+type FeeRevenue struct {
+	ID        int64     `json:"id"`
+	Market    string    `json:"market"`
+	Asset     string    `json:"asset"`
+	FeeIncome float64   `json:"fee_income"`
+	Rebate    float64   `json:"rebate"`
+	CreatedAt time.Time `json:"-"`
+}
+
+func (r FeeRevenue) MarshalJSON() ([]byte, error) {
+	type Alias FeeRevenue
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&r),
+		CreatedAt: r.CreatedAt.UnixMilli(),
+	})
+}
+
+// FeeRevenueSummary fee income and rebates reported separately, NetRevenue = FeeIncome - Rebate.
+type FeeRevenueSummary struct {
+	Asset      string  `json:"asset"`
+	FeeIncome  float64 `json:"fee_income"`
+	Rebate     float64 `json:"rebate"`
+	NetRevenue float64 `json:"net_revenue"`
+}
+
+// FeeRevenueQueryReq from, to in unix milliseconds, default latest 24 hours.
+type FeeRevenueQueryReq struct {
+	From int64 `form:"from"`
+	To   int64 `form:"to"`
+}
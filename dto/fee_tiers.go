@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FeeSchedule user reach MinVolume (30-day quote volume) get VipLevel fee rates.
+type FeeSchedule struct {
+	VipLevel  int     `json:"vip_level"`
+	MinVolume float64 `json:"min_volume"`
+	MakerFee  float64 `json:"maker_fee"`
+	TakerFee  float64 `json:"taker_fee"`
+}
+
+// MatchFeeSchedule return the highest tier which minVolume <= volume, schedules must sorted by MinVolume asc.
+func MatchFeeSchedule(schedules []*FeeSchedule, volume float64) (current *FeeSchedule, next *FeeSchedule) {
+	for _, schedule := range schedules {
+		if schedule.MinVolume <= volume {
+			current = schedule
+			continue
+		}
+		next = schedule
+		break
+	}
+	return current, next
+}
+
+// FeeTierProgress user current tier and progress to next tier.
+type FeeTierProgress struct {
+	VipLevel          int     `json:"vip_level"`
+	Volume30D         float64 `json:"volume_30d"`
+	NextVipLevel      int     `json:"next_vip_level"`       // 0 if already top tier
+	NextLevelVolume   float64 `json:"next_level_volume"`    // 30-day volume required by next tier
+	VolumeToNextLevel float64 `json:"volume_to_next_level"` // 0 if already top tier
+}
+
+type VipLevelHistory struct {
+	ID           int64     `json:"id"`
+	UserID       string    `json:"user_id"`
+	FromVipLevel int       `json:"from_vip_level"`
+	ToVipLevel   int       `json:"to_vip_level"`
+	MakerFee     float64   `json:"maker_fee"`
+	TakerFee     float64   `json:"taker_fee"`
+	Volume30D    float64   `json:"volume_30d"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+func (h VipLevelHistory) MarshalJSON() ([]byte, error) {
+	type Alias VipLevelHistory
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&h),
+		CreatedAt: h.CreatedAt.UnixMilli(),
+	})
+}
+
+type AssignMarketMakerTierReq struct {
+	Username string `json:"username" binding:"required"`
+	VipLevel int    `json:"vip_level" binding:"required"`
+}
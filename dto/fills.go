@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"time"
+)
+
+// Fill one side of a trade from user's view.
+type Fill struct {
+	TradeID     int64      `json:"trade_id"`
+	OrderID     string     `json:"order_id"`
+	Market      string     `json:"market"`
+	Side        model.Side `json:"side"`
+	Role        model.Mode `json:"role"` // 0=MAKER, 1=TAKER
+	Price       float64    `json:"price"`
+	Size        float64    `json:"size"`
+	QuoteAmount float64    `json:"quote_amount"`
+	Fee         float64    `json:"fee"` // negative means rebate
+	FeeAsset    string     `json:"fee_asset"`
+	Block       bool       `json:"block"` // RFQ block trade
+	Timestamp   time.Time  `json:"-"`
+}
+
+func (f Fill) MarshalJSON() ([]byte, error) {
+	type Alias Fill
+	return json.Marshal(&struct {
+		*Alias
+		Timestamp int64 `json:"timestamp"`
+	}{
+		Alias:     (*Alias)(&f),
+		Timestamp: f.Timestamp.UnixMilli(),
+	})
+}
+
+// FillsResp NextCursor is 0 if no more fills, otherwise pass it as cursor to query next page.
+type FillsResp struct {
+	Fills      []*Fill `json:"fills"`
+	NextCursor int64   `json:"next_cursor"`
+}
+
+// GetFillsQueryReq StartTime, EndTime in unix milliseconds, Cursor is trade id (exclusive) from previous page.
+type GetFillsQueryReq struct {
+	Market    string `form:"market"`
+	StartTime int64  `form:"start_time"`
+	EndTime   int64  `form:"end_time"`
+	Cursor    int64  `form:"cursor"`
+	Limit     int    `form:"limit,default=50"`
+}
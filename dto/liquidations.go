@@ -0,0 +1,95 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"time"
+)
+
+// MarginCall warning of margin ratio lower than settings.MARGIN_CALL_RATIO, values in USDT.
+type MarginCall struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"-"`
+	MarginRatio float64   `json:"margin_ratio"`
+	AssetValue  float64   `json:"asset_value"`
+	DebtValue   float64   `json:"debt_value"`
+	CreatedAt   time.Time `json:"-"`
+}
+
+func (c MarginCall) MarshalJSON() ([]byte, error) {
+	type Alias MarginCall
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&c),
+		CreatedAt: c.CreatedAt.UnixMilli(),
+	})
+}
+
+type LiquidationStatus string
+
+const (
+	LIQUIDATION_STATUS_COMPLETED LiquidationStatus = "COMPLETED"
+	// LIQUIDATION_STATUS_BAD_DEBT shortfall not fully covered by insurance fund, loan remains.
+	LIQUIDATION_STATUS_BAD_DEBT LiquidationStatus = "BAD_DEBT"
+	LIQUIDATION_STATUS_FAILED   LiquidationStatus = "FAILED"
+)
+
+// LiquidationOrder market order placed by liquidation in margin wallet.
+type LiquidationOrder struct {
+	Market      string     `json:"market"`
+	Side        model.Side `json:"side"`
+	Size        float64    `json:"size"`         // ask order
+	QuoteAmount float64    `json:"quote_amount"` // bid order
+	OrderID     string     `json:"order_id"`
+	FilledSize  float64    `json:"filled_size"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type LiquidationRepayment struct {
+	Asset     string  `json:"asset"`
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+}
+
+// LiquidationShortfall loan left after repaying with all margin wallet balances.
+type LiquidationShortfall struct {
+	Asset     string  `json:"asset"`
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	Value     float64 `json:"value"`   // USDT
+	Covered   bool    `json:"covered"` // covered by insurance fund
+}
+
+type LiquidationDetails struct {
+	CanceledOrderIDs []string                `json:"canceled_order_ids"`
+	Orders           []*LiquidationOrder     `json:"orders"`
+	Repayments       []*LiquidationRepayment `json:"repayments"`
+	Shortfalls       []*LiquidationShortfall `json:"shortfalls"`
+}
+
+// Liquidation record of margin account liquidated, risk values (USDT) are taken when liquidation triggered.
+type Liquidation struct {
+	ID           int64              `json:"id"`
+	UserID       string             `json:"user_id"`
+	MarginUserID string             `json:"margin_user_id"`
+	MarginRatio  float64            `json:"margin_ratio"`
+	AssetValue   float64            `json:"asset_value"`
+	DebtValue    float64            `json:"debt_value"`
+	Status       LiquidationStatus  `json:"status"`
+	Details      LiquidationDetails `json:"details"`
+	Error        string             `json:"error,omitempty"`
+	CreatedAt    time.Time          `json:"-"`
+}
+
+func (l Liquidation) MarshalJSON() ([]byte, error) {
+	type Alias Liquidation
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&l),
+		CreatedAt: l.CreatedAt.UnixMilli(),
+	})
+}
@@ -0,0 +1,101 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MarginAccount user margin wallet, balances and margin orders belong to internal MarginUserID.
+type MarginAccount struct {
+	UserID       string    `json:"-"`
+	MarginUserID string    `json:"-"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+type MarginLoan struct {
+	UserID    string    `json:"-"`
+	Asset     string    `json:"asset"`
+	Principal float64   `json:"principal"`
+	Interest  float64   `json:"interest"` // accrued unpaid interest
+	AccruedAt time.Time `json:"-"`        // interest is accrued up to this time
+	UpdatedAt time.Time `json:"-"`
+}
+
+func (l MarginLoan) MarshalJSON() ([]byte, error) {
+	type Alias MarginLoan
+	return json.Marshal(&struct {
+		*Alias
+		UpdatedAt int64 `json:"updated_at"`
+	}{
+		Alias:     (*Alias)(&l),
+		UpdatedAt: l.UpdatedAt.UnixMilli(),
+	})
+}
+
+type MarginLoanType string
+
+const (
+	MARGIN_LOAN_BORROW MarginLoanType = "BORROW"
+	MARGIN_LOAN_REPAY  MarginLoanType = "REPAY"
+	// MARGIN_LOAN_INSURANCE liquidation shortfall covered by insurance fund.
+	MARGIN_LOAN_INSURANCE MarginLoanType = "INSURANCE"
+)
+
+type MarginLoanRecord struct {
+	ID        int64          `json:"id"`
+	UserID    string         `json:"-"`
+	Asset     string         `json:"asset"`
+	Type      MarginLoanType `json:"type"`
+	Principal float64        `json:"principal"`
+	Interest  float64        `json:"interest"`
+	CreatedAt time.Time      `json:"-"`
+}
+
+func (r MarginLoanRecord) MarshalJSON() ([]byte, error) {
+	type Alias MarginLoanRecord
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&r),
+		CreatedAt: r.CreatedAt.UnixMilli(),
+	})
+}
+
+// MarginRisk margin wallet risk valued in USDT, MarginRatio = Equity / AssetValue, Leverage = AssetValue / Equity.
+type MarginRisk struct {
+	AssetValue  float64 `json:"asset_value"`
+	DebtValue   float64 `json:"debt_value"`
+	Equity      float64 `json:"equity"`
+	MarginRatio float64 `json:"margin_ratio"`
+	Leverage    float64 `json:"leverage"`
+}
+
+type MarginAccountInfo struct {
+	Balances          []*Balance    `json:"balances"`
+	Loans             []*MarginLoan `json:"loans"`
+	Risk              *MarginRisk   `json:"risk"`
+	MaintenanceRatio  float64       `json:"maintenance_ratio"`
+	InitialRatio      float64       `json:"initial_ratio"`
+	MaxLeverage       float64       `json:"max_leverage"`
+	MaxBorrowableUSDT float64       `json:"max_borrowable_usdt"` // max borrowable value in USDT keeping initial ratio
+}
+
+type MarginTransferDirection string
+
+const (
+	MARGIN_TRANSFER_IN  MarginTransferDirection = "IN"  // spot wallet -> margin wallet
+	MARGIN_TRANSFER_OUT MarginTransferDirection = "OUT" // margin wallet -> spot wallet
+)
+
+type MarginTransferReq struct {
+	Asset          string                  `json:"asset" binding:"required"`
+	Amount         float64                 `json:"amount" binding:"required,gt=0"`
+	Direction      MarginTransferDirection `json:"direction" binding:"required,oneof=IN OUT"`
+	IdempotencyKey string                  `json:"idempotency_key" binding:"required"`
+}
+
+type MarginLoanReq struct {
+	Asset  string  `json:"asset" binding:"required"`
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
 	"github.com/johnny1110/crypto-exchange/engine-v2/model"
 	"time"
 )
@@ -146,6 +147,8 @@ type PlaceOrderContext struct {
 	OrderDTO *Order
 	Assets   *AssetDetails
 	Trades   []book.Trade
+	// Perpetual market info if placing on perpetual market, nil for spot market.
+	Perpetual *market.MarketInfo
 }
 
 func (c *PlaceOrderContext) SyncTradeResult(engineOrder *model.Order, trades []book.Trade) {
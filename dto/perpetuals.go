@@ -0,0 +1,135 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PerpetualPosition user position of perpetual market, Size in contracts (positive: long, negative: short),
+// Margin in quote asset is kept in user's locked balance.
+type PerpetualPosition struct {
+	UserID      string    `json:"-"`
+	Market      string    `json:"market"`
+	Size        float64   `json:"size"`
+	EntryPrice  float64   `json:"entry_price"`
+	Margin      float64   `json:"margin"`
+	RealizedPnl float64   `json:"realized_pnl"`
+	UpdatedAt   time.Time `json:"-"`
+
+	// valued by mark price when querying
+	MarkPrice     float64 `json:"mark_price"`
+	Notional      float64 `json:"notional"`
+	UnrealizedPnl float64 `json:"unrealized_pnl"`
+	MarginRatio   float64 `json:"margin_ratio"` // (margin + unrealized pnl) / notional
+}
+
+func (p PerpetualPosition) MarshalJSON() ([]byte, error) {
+	type Alias PerpetualPosition
+	return json.Marshal(&struct {
+		*Alias
+		UpdatedAt int64 `json:"updated_at"`
+	}{
+		Alias:     (*Alias)(&p),
+		UpdatedAt: p.UpdatedAt.UnixMilli(),
+	})
+}
+
+// PerpetualMarket market summary of perpetual market, FundingRate is predicted rate of next funding.
+type PerpetualMarket struct {
+	Market          string  `json:"market"`
+	BaseAsset       string  `json:"base_asset"`
+	QuoteAsset      string  `json:"quote_asset"`
+	ContractSize    float64 `json:"contract_size"`
+	LastPrice       float64 `json:"last_price"`
+	MarkPrice       float64 `json:"mark_price"`
+	IndexPrice      float64 `json:"index_price"`
+	FundingRate     float64 `json:"funding_rate"`
+	NextFundingTime int64   `json:"next_funding_time"`
+	OpenInterest    float64 `json:"open_interest"` // contracts of long positions
+	Halted          bool    `json:"halted"`
+}
+
+// PerpetualMarketHalt market is halted when insurance fund can not cover a payment, orders are refused until
+// admin resumes it.
+type PerpetualMarketHalt struct {
+	Market    string    `json:"market"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"-"`
+}
+
+type FundingRate struct {
+	ID          int64     `json:"-"`
+	Market      string    `json:"market"`
+	FundingRate float64   `json:"funding_rate"`
+	MarkPrice   float64   `json:"mark_price"`
+	IndexPrice  float64   `json:"index_price"`
+	CreatedAt   time.Time `json:"-"`
+}
+
+func (r FundingRate) MarshalJSON() ([]byte, error) {
+	type Alias FundingRate
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&r),
+		CreatedAt: r.CreatedAt.UnixMilli(),
+	})
+}
+
+// FundingPayment Amount positive is received, negative is paid.
+type FundingPayment struct {
+	ID           int64     `json:"id"`
+	UserID       string    `json:"-"`
+	Market       string    `json:"market"`
+	Asset        string    `json:"asset"`
+	PositionSize float64   `json:"position_size"`
+	MarkPrice    float64   `json:"mark_price"`
+	FundingRate  float64   `json:"funding_rate"`
+	Amount       float64   `json:"amount"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+func (p FundingPayment) MarshalJSON() ([]byte, error) {
+	type Alias FundingPayment
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&p),
+		CreatedAt: p.CreatedAt.UnixMilli(),
+	})
+}
+
+// PerpetualLiquidation position under maintenance margin rate closed at mark price against insurance fund,
+// Margin is released position margin, Pnl is realized pnl charged from it, loss exceeding margin is BadDebt
+// absorbed by insurance fund.
+type PerpetualLiquidation struct {
+	ID         int64     `json:"id"`
+	UserID     string    `json:"-"`
+	Market     string    `json:"market"`
+	Size       float64   `json:"size"`
+	EntryPrice float64   `json:"entry_price"`
+	MarkPrice  float64   `json:"mark_price"`
+	Margin     float64   `json:"margin"`
+	Pnl        float64   `json:"pnl"`
+	BadDebt    float64   `json:"bad_debt"`
+	CreatedAt  time.Time `json:"-"`
+}
+
+func (l PerpetualLiquidation) MarshalJSON() ([]byte, error) {
+	type Alias PerpetualLiquidation
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&l),
+		CreatedAt: l.CreatedAt.UnixMilli(),
+	})
+}
+
+// FundingQueryReq Market is optional for funding payments, required for funding rates.
+type FundingQueryReq struct {
+	Market string `form:"market"`
+	Limit  int    `form:"limit,default=50"`
+}
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CostBasis user average cost of an asset in quote asset, Quantity is only changed by trades.
+type CostBasis struct {
+	UserID    string    `json:"-"`
+	Asset     string    `json:"asset"`
+	Quantity  float64   `json:"quantity"`
+	AvgCost   float64   `json:"avg_cost"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// RealizedPnl realized by selling base asset in one settlement, Pnl = Proceeds - Cost.
+type RealizedPnl struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"-"`
+	Market    string    `json:"market"`
+	Asset     string    `json:"asset"`
+	Size      float64   `json:"size"`
+	Proceeds  float64   `json:"proceeds"`
+	Cost      float64   `json:"cost"`
+	Pnl       float64   `json:"pnl"`
+	CreatedAt time.Time `json:"-"`
+}
+
+func (r RealizedPnl) MarshalJSON() ([]byte, error) {
+	type Alias RealizedPnl
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&r),
+		CreatedAt: r.CreatedAt.UnixMilli(),
+	})
+}
+
+// MarketRealizedPnl realized pnl sum of a market in period.
+type MarketRealizedPnl struct {
+	Market   string  `json:"market"`
+	Size     float64 `json:"size"`
+	Proceeds float64 `json:"proceeds"`
+	Cost     float64 `json:"cost"`
+	Pnl      float64 `json:"pnl"`
+}
+
+// AssetUnrealizedPnl Quantity is current balance (available + locked), valued at market latest price.
+type AssetUnrealizedPnl struct {
+	Asset       string  `json:"asset"`
+	Market      string  `json:"market"`
+	Quantity    float64 `json:"quantity"`
+	AvgCost     float64 `json:"avg_cost"`
+	LatestPrice float64 `json:"latest_price"`
+	MarketValue float64 `json:"market_value"`
+	CostValue   float64 `json:"cost_value"`
+	Pnl         float64 `json:"pnl"`
+}
+
+type PnlResp struct {
+	QuoteAsset         string                `json:"quote_asset"`
+	From               int64                 `json:"from"`
+	To                 int64                 `json:"to"`
+	Realized           []*MarketRealizedPnl  `json:"realized"`
+	TotalRealizedPnl   float64               `json:"total_realized_pnl"`
+	Unrealized         []*AssetUnrealizedPnl `json:"unrealized"`
+	TotalUnrealizedPnl float64               `json:"total_unrealized_pnl"`
+}
+
+// PnlQueryReq From (inclusive), To (exclusive) in unix milliseconds for realized pnl, default last 30 days.
+type PnlQueryReq struct {
+	From int64 `form:"from"`
+	To   int64 `form:"to"`
+}
@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type PriceSource string
+
+const (
+	PRICE_SOURCE_LATEST PriceSource = "LATEST" // matching engine latest price
+	PRICE_SOURCE_INDEX  PriceSource = "INDEX"  // external index price, used if market has no trade yet
+	PRICE_SOURCE_NONE   PriceSource = "NONE"   // no price available, valued as 0
+)
+
+// PortfolioAsset asset balance valued in portfolio quote asset.
+type PortfolioAsset struct {
+	Asset          string      `json:"asset"`
+	Available      float64     `json:"available"`
+	Locked         float64     `json:"locked"`
+	Total          float64     `json:"total"`
+	Price          float64     `json:"price"`
+	PriceSource    PriceSource `json:"price_source"`
+	AvailableValue float64     `json:"available_value"`
+	LockedValue    float64     `json:"locked_value"`
+	Value          float64     `json:"value"`
+	Allocation     float64     `json:"allocation"` // percentage of total equity, e.g. 12.5 means 12.5%
+}
+
+type Portfolio struct {
+	QuoteAsset      string            `json:"quote_asset"`
+	TotalEquity     float64           `json:"total_equity"`
+	AvailableEquity float64           `json:"available_equity"`
+	LockedEquity    float64           `json:"locked_equity"`
+	Assets          []*PortfolioAsset `json:"assets"`
+	Timestamp       time.Time         `json:"-"`
+}
+
+func (p Portfolio) MarshalJSON() ([]byte, error) {
+	type Alias Portfolio
+	return json.Marshal(&struct {
+		*Alias
+		Timestamp int64 `json:"timestamp"`
+	}{
+		Alias:     (*Alias)(&p),
+		Timestamp: p.Timestamp.UnixMilli(),
+	})
+}
+
+// EquitySnapshot user total equity (USDT) at 00:00 UTC of SnapshotDate.
+type EquitySnapshot struct {
+	UserID       string    `json:"-"`
+	SnapshotDate string    `json:"snapshot_date"` // 2006-01-02
+	Equity       float64   `json:"equity"`
+	QuoteAsset   string    `json:"quote_asset"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+func (e EquitySnapshot) MarshalJSON() ([]byte, error) {
+	type Alias EquitySnapshot
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&e),
+		CreatedAt: e.CreatedAt.UnixMilli(),
+	})
+}
+
+// PortfolioQueryReq Quote is valuation asset, USDT or any base asset of markets.
+type PortfolioQueryReq struct {
+	Quote string `form:"quote,default=USDT"`
+}
+
+type EquityHistoryQueryReq struct {
+	Days int `form:"days,default=30"`
+}
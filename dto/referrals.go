@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ReferralInfo user own referral code and referees summary.
+type ReferralInfo struct {
+	Code           string  `json:"code"`
+	RefereeCount   int64   `json:"referee_count"`
+	CommissionRate float64 `json:"commission_rate"`
+}
+
+// ReferralCommission share of referee's trading fee paid to referrer by margin account.
+type ReferralCommission struct {
+	ID         int64     `json:"id"`
+	ReferrerID string    `json:"-"`
+	RefereeID  string    `json:"referee_id"`
+	OrderID    string    `json:"order_id"`
+	Market     string    `json:"market"`
+	Asset      string    `json:"asset"`
+	Fees       float64   `json:"fees"`
+	Commission float64   `json:"commission"`
+	CreatedAt  time.Time `json:"-"`
+}
+
+func (c ReferralCommission) MarshalJSON() ([]byte, error) {
+	type Alias ReferralCommission
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&c),
+		CreatedAt: c.CreatedAt.UnixMilli(),
+	})
+}
+
+type ReferralCommissionTotal struct {
+	Asset string  `json:"asset"`
+	Total float64 `json:"total"`
+}
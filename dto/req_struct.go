@@ -2,16 +2,20 @@ package dto
 
 import (
 	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"time"
 )
 
 type RegisterReq struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required,password"`
+	ReferralCode string `json:"referral_code"` // optional
 }
 
+// LoginReq TotpCode is required if user enabled 2FA, TOTP code or backup code.
 type LoginReq struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	TotpCode string `json:"totp_code"`
 }
 
 type SettlementReq struct {
@@ -27,6 +31,7 @@ type OrderReq struct {
 	Price       float64         `json:"price"`                                             // only LIMIT order, and > 0
 	Size        float64         `json:"size"`                                              // only market bid no need
 	QuoteAmount float64         `json:"quote_amount"`                                      // only for taker bid order
+	Margin      bool            `json:"margin"`                                            // place in margin wallet
 }
 
 type OrdersQueryType = string
@@ -43,4 +48,8 @@ type GetOrdersQueryReq struct {
 	Type        OrdersQueryType `form:"type" binding:"required"`
 	PageSize    int64           `form:"page_size,default=10"`
 	CurrentPage int64           `form:"current_page,default=1"`
+	Margin      bool            `form:"margin"` // query margin wallet orders
+	// CreatedFrom (inclusive), CreatedTo (exclusive) optional created_at range, not bind from query.
+	CreatedFrom time.Time `form:"-"`
+	CreatedTo   time.Time `form:"-"`
 }
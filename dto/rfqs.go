@@ -0,0 +1,123 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"time"
+)
+
+type RfqRequestStatus string
+
+const (
+	// RFQ_REQUEST_STATUS_OPEN waiting for quotes and taker's acceptance.
+	RFQ_REQUEST_STATUS_OPEN RfqRequestStatus = "OPEN"
+	// RFQ_REQUEST_STATUS_FILLED taker accepted one quote and block trade settled.
+	RFQ_REQUEST_STATUS_FILLED RfqRequestStatus = "FILLED"
+	// RFQ_REQUEST_STATUS_CANCELED canceled by taker.
+	RFQ_REQUEST_STATUS_CANCELED RfqRequestStatus = "CANCELED"
+	// RFQ_REQUEST_STATUS_EXPIRED no quote accepted within settings.RFQ_REQUEST_TTL.
+	RFQ_REQUEST_STATUS_EXPIRED RfqRequestStatus = "EXPIRED"
+)
+
+type RfqQuoteStatus string
+
+const (
+	// RFQ_QUOTE_STATUS_OPEN firm quote, provider's funds are locked.
+	RFQ_QUOTE_STATUS_OPEN RfqQuoteStatus = "OPEN"
+	// RFQ_QUOTE_STATUS_ACCEPTED accepted by taker, locked funds settled.
+	RFQ_QUOTE_STATUS_ACCEPTED RfqQuoteStatus = "ACCEPTED"
+	// RFQ_QUOTE_STATUS_REJECTED request closed without accepting it, locked funds returned to available.
+	RFQ_QUOTE_STATUS_REJECTED RfqQuoteStatus = "REJECTED"
+	// RFQ_QUOTE_STATUS_CANCELED withdrawn by provider, locked funds returned to available.
+	RFQ_QUOTE_STATUS_CANCELED RfqQuoteStatus = "CANCELED"
+)
+
+// RfqRequest taker request for quotes of Size base asset, Side is taker side.
+type RfqRequest struct {
+	ID              string           `json:"id"`
+	UserID          string           `json:"-"`
+	Market          string           `json:"market"`
+	Side            model.Side       `json:"side"`
+	Size            float64          `json:"size"`
+	Status          RfqRequestStatus `json:"status"`
+	AcceptedQuoteID string           `json:"accepted_quote_id"`
+	ExpiresAt       time.Time        `json:"-"`
+	CreatedAt       time.Time        `json:"-"`
+	UpdatedAt       time.Time        `json:"-"`
+
+	Quotes []*RfqQuote `json:"quotes,omitempty"`
+}
+
+func (r RfqRequest) MarshalJSON() ([]byte, error) {
+	type Alias RfqRequest
+	return json.Marshal(&struct {
+		*Alias
+		ExpiresAt int64 `json:"expires_at"`
+		CreatedAt int64 `json:"created_at"`
+		UpdatedAt int64 `json:"updated_at"`
+	}{
+		Alias:     (*Alias)(&r),
+		ExpiresAt: r.ExpiresAt.UnixMilli(),
+		CreatedAt: r.CreatedAt.UnixMilli(),
+		UpdatedAt: r.UpdatedAt.UnixMilli(),
+	})
+}
+
+// RfqQuote firm quote of liquidity provider, providers are anonymous to taker.
+type RfqQuote struct {
+	ID         string         `json:"id"`
+	RequestID  string         `json:"request_id"`
+	ProviderID string         `json:"-"`
+	Price      float64        `json:"price"`
+	Status     RfqQuoteStatus `json:"status"`
+	CreatedAt  time.Time      `json:"-"`
+	UpdatedAt  time.Time      `json:"-"`
+}
+
+func (q RfqQuote) MarshalJSON() ([]byte, error) {
+	type Alias RfqQuote
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+		UpdatedAt int64 `json:"updated_at"`
+	}{
+		Alias:     (*Alias)(&q),
+		CreatedAt: q.CreatedAt.UnixMilli(),
+		UpdatedAt: q.UpdatedAt.UnixMilli(),
+	})
+}
+
+// RfqProvider user registered as RFQ liquidity provider, internal AMM account always provides quotes.
+type RfqProvider struct {
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"-"`
+}
+
+func (p RfqProvider) MarshalJSON() ([]byte, error) {
+	type Alias RfqProvider
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&p),
+		CreatedAt: p.CreatedAt.UnixMilli(),
+	})
+}
+
+type RfqRequestReq struct {
+	Market string     `json:"market" binding:"required"`
+	Side   model.Side `json:"side" binding:"oneof=0 1"` // taker side, 0=Bid,1=Ask
+	Size   float64    `json:"size" binding:"required,gt=0"`
+}
+
+type RfqQuoteReq struct {
+	Price float64 `json:"price" binding:"required,gt=0"`
+}
+
+type RfqAcceptReq struct {
+	QuoteID string `json:"quote_id" binding:"required"`
+}
+
+type AddRfqProviderReq struct {
+	UserID string `json:"user_id" binding:"required"`
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Session login session, Token and RefreshToken are only returned when issued (login or refresh),
+// stores keep their hashes only.
+type Session struct {
+	ID               string    `json:"session_id"`
+	UserID           string    `json:"-"`
+	Token            string    `json:"token,omitempty"`
+	RefreshToken     string    `json:"refresh_token,omitempty"`
+	TokenHash        string    `json:"-"`
+	RefreshTokenHash string    `json:"-"`
+	ClientIP         string    `json:"client_ip"`
+	UserAgent        string    `json:"user_agent"`
+	Current          bool      `json:"current"` // session of the requesting token
+	CreatedAt        time.Time `json:"-"`
+	LastActiveAt     time.Time `json:"-"`
+	ExpiresAt        time.Time `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
+}
+
+func (s Session) MarshalJSON() ([]byte, error) {
+	type Alias Session
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt        int64 `json:"created_at"`
+		LastActiveAt     int64 `json:"last_active_at"`
+		ExpiresAt        int64 `json:"expires_at"`
+		RefreshExpiresAt int64 `json:"refresh_expires_at"`
+	}{
+		Alias:            (*Alias)(&s),
+		CreatedAt:        s.CreatedAt.UnixMilli(),
+		LastActiveAt:     s.LastActiveAt.UnixMilli(),
+		ExpiresAt:        s.ExpiresAt.UnixMilli(),
+		RefreshExpiresAt: s.RefreshExpiresAt.UnixMilli(),
+	})
+}
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+)
+
+type StatementType string
+
+const (
+	STATEMENT_TYPE_ORDERS StatementType = "orders"
+	STATEMENT_TYPE_FILLS  StatementType = "fills"
+	STATEMENT_TYPE_LEDGER StatementType = "ledger"
+)
+
+type StatementFormat string
+
+const (
+	STATEMENT_FORMAT_CSV   StatementFormat = "csv"
+	STATEMENT_FORMAT_JSONL StatementFormat = "jsonl"
+)
+
+type LedgerEntryType string
+
+const (
+	// LEDGER_ENTRY_TRADE base or quote asset moved by a fill.
+	LEDGER_ENTRY_TRADE LedgerEntryType = "TRADE"
+	// LEDGER_ENTRY_FEE fees charged by a fill, positive amount if it's a maker rebate.
+	LEDGER_ENTRY_FEE                 LedgerEntryType = "FEE"
+	LEDGER_ENTRY_WITHDRAWAL          LedgerEntryType = "WITHDRAWAL"
+	LEDGER_ENTRY_WITHDRAWAL_REFUND   LedgerEntryType = "WITHDRAWAL_REFUND"
+	LEDGER_ENTRY_TRANSFER_IN         LedgerEntryType = "TRANSFER_IN"
+	LEDGER_ENTRY_TRANSFER_OUT        LedgerEntryType = "TRANSFER_OUT"
+	LEDGER_ENTRY_REFERRAL_COMMISSION LedgerEntryType = "REFERRAL_COMMISSION"
+	// LEDGER_ENTRY_REALIZED_PNL perpetual position closed, settled in quote asset.
+	LEDGER_ENTRY_REALIZED_PNL LedgerEntryType = "REALIZED_PNL"
+	LEDGER_ENTRY_FUNDING      LedgerEntryType = "FUNDING"
+)
+
+// LedgerEntry one balance movement of user, Amount is signed (negative means decreasing).
+type LedgerEntry struct {
+	Type      LedgerEntryType
+	Asset     string
+	Amount    float64
+	RefID     string // trade id, withdrawal id, transfer id or referral commission id
+	Market    string
+	Timestamp time.Time
+}
+
+// StatementReq From, To in unix milliseconds, TimeZone is IANA name (e.g. "Asia/Taipei") default UTC.
+type StatementReq struct {
+	Type     StatementType   `form:"type" binding:"required"`
+	Format   StatementFormat `form:"format,default=csv"`
+	From     int64           `form:"from" binding:"required"`
+	To       int64           `form:"to"`
+	TimeZone string          `form:"tz"`
+}
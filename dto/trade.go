@@ -2,6 +2,7 @@ package dto
 
 import (
 	"encoding/json"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
 	"time"
 )
 
@@ -26,3 +27,26 @@ func (m Match) MarshalJSON() ([]byte, error) {
 		Timestamp: m.Timestamp.UnixMilli(),
 	})
 }
+
+// MarketTrade public trade of market, TakerSide 0=Bid means taker bought, 1=Ask means taker sold.
+type MarketTrade struct {
+	ID          int64      `json:"id"`
+	Market      string     `json:"market"`
+	Price       float64    `json:"price"`
+	Size        float64    `json:"size"`
+	QuoteAmount float64    `json:"quote_amount"`
+	TakerSide   model.Side `json:"taker_side"`
+	Block       bool       `json:"block"` // RFQ block trade
+	Timestamp   time.Time  `json:"-"`
+}
+
+func (t MarketTrade) MarshalJSON() ([]byte, error) {
+	type Alias MarketTrade
+	return json.Marshal(&struct {
+		*Alias
+		Timestamp int64 `json:"timestamp"`
+	}{
+		Alias:     (*Alias)(&t),
+		Timestamp: t.Timestamp.UnixMilli(),
+	})
+}
@@ -0,0 +1,101 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TransferType string
+
+const (
+	// TRANSFER_TYPE_INTERNAL transfer between any two users.
+	TRANSFER_TYPE_INTERNAL TransferType = "INTERNAL"
+	// TRANSFER_TYPE_SUB_ACCOUNT transfer between master user and its sub-accounts.
+	TRANSFER_TYPE_SUB_ACCOUNT TransferType = "SUB_ACCOUNT"
+	// TRANSFER_TYPE_MARGIN transfer between user spot wallet and margin wallet.
+	TRANSFER_TYPE_MARGIN TransferType = "MARGIN"
+)
+
+type Transfer struct {
+	ID             string       `json:"id"`
+	IdempotencyKey string       `json:"idempotency_key"`
+	FromUserID     string       `json:"from_user_id"`
+	ToUserID       string       `json:"to_user_id"`
+	Asset          string       `json:"asset"`
+	Amount         float64      `json:"amount"`
+	Type           TransferType `json:"type"`
+	Remark         string       `json:"remark"`
+	Valuation      float64      `json:"-"` // USDT value of transfer to other user, 0 if between accounts of same master.
+	CreatedAt      time.Time    `json:"-"`
+}
+
+func (t Transfer) MarshalJSON() ([]byte, error) {
+	type Alias Transfer
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&t),
+		CreatedAt: t.CreatedAt.UnixMilli(),
+	})
+}
+
+// SubAccount a user owned by master user, master can move funds between them.
+type SubAccount struct {
+	SubUserID    string    `json:"sub_user_id"`
+	MasterUserID string    `json:"master_user_id"`
+	Username     string    `json:"username"`
+	Label        string    `json:"label"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+func (s SubAccount) MarshalJSON() ([]byte, error) {
+	type Alias SubAccount
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&s),
+		CreatedAt: s.CreatedAt.UnixMilli(),
+	})
+}
+
+type AccountBalances struct {
+	UserID   string     `json:"user_id"`
+	Username string     `json:"username"`
+	Label    string     `json:"label"`
+	Balances []*Balance `json:"balances"`
+}
+
+// ConsolidatedBalances master account and all sub-accounts balances.
+type ConsolidatedBalances struct {
+	Total    []*Balance         `json:"total"`
+	Accounts []*AccountBalances `json:"accounts"`
+}
+
+type TransferReq struct {
+	ToUserID       string  `json:"to_user_id"`  // to_user_id or to_username is required
+	ToUsername     string  `json:"to_username"` // to_user_id or to_username is required
+	Asset          string  `json:"asset" binding:"required"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	IdempotencyKey string  `json:"idempotency_key" binding:"required"`
+	Remark         string  `json:"remark"`
+	// TotpCode required if user enabled 2FA and target is not master or sub-account of user.
+	TotpCode string `json:"totp_code"`
+}
+
+// CreateSubAccountReq TotpCode is required if master enabled 2FA.
+type CreateSubAccountReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required,password"`
+	Label    string `json:"label"`
+	TotpCode string `json:"totp_code"`
+}
+
+type SubAccountTransferReq struct {
+	FromUserID     string  `json:"from_user_id" binding:"required"` // master or sub-account user id
+	ToUserID       string  `json:"to_user_id" binding:"required"`   // master or sub-account user id
+	Asset          string  `json:"asset" binding:"required"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	IdempotencyKey string  `json:"idempotency_key" binding:"required"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+// OrdersUpdate data of private websocket channel "orders", Margin is true if orders are of user's margin wallet.
+type OrdersUpdate struct {
+	Margin bool     `json:"margin"`
+	Orders []*Order `json:"orders"`
+}
+
+// FillsUpdate data of private websocket channel "fills", Margin is true if fills are of user's margin wallet.
+type FillsUpdate struct {
+	Margin bool    `json:"margin"`
+	Fills  []*Fill `json:"fills"`
+}
+
+// BalancesUpdate data of private websocket channel "balances", all balances of wallet after change.
+type BalancesUpdate struct {
+	Margin   bool       `json:"margin"`
+	Balances []*Balance `json:"balances"`
+}
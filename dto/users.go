@@ -5,6 +5,23 @@ import (
 	"time"
 )
 
+// AccountStatus FROZEN and CLOSED accounts can not log in or call private APIs, CLOSED is final.
+type AccountStatus string
+
+const (
+	ACCOUNT_STATUS_ACTIVE AccountStatus = "ACTIVE"
+	ACCOUNT_STATUS_FROZEN AccountStatus = "FROZEN"
+	ACCOUNT_STATUS_CLOSED AccountStatus = "CLOSED"
+)
+
+func (s AccountStatus) IsValid() bool {
+	switch s {
+	case ACCOUNT_STATUS_ACTIVE, ACCOUNT_STATUS_FROZEN, ACCOUNT_STATUS_CLOSED:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	ID           string    `json:"id"`
 	Username     string    `json:"username"`
@@ -12,7 +29,23 @@ type User struct {
 	VipLevel     int       `json:"vip_level"`
 	MakerFee     float64   `json:"maker_fee"`
 	TakerFee     float64   `json:"taker_fee"`
+	PayFeeInBTSE bool      `json:"pay_fee_in_btse"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// TOTP two-factor authentication
+	TotpSecret      string   `json:"-"`
+	TotpEnabled     bool     `json:"totp_enabled"`
+	TotpBackupCodes []string `json:"-"` // sha256 hashes of unused backup codes
+	TotpLastStep    int64    `json:"-"` // last accepted TOTP time step, codes of it and earlier steps are rejected
+
+	// account lifecycle, changed by admins
+	Status           AccountStatus `json:"status"`
+	TradeDisabled    bool          `json:"trade_disabled"`
+	WithdrawDisabled bool          `json:"withdraw_disabled"`
+	StatusReason     string        `json:"-"` // internal, only shown to admins
+
+	// for API
+	FeeTier *FeeTierProgress `json:"fee_tier,omitempty"`
 }
 
 func (u User) MarshalJSON() ([]byte, error) {
@@ -25,3 +58,85 @@ func (u User) MarshalJSON() ([]byte, error) {
 		CreatedAt: u.CreatedAt.UnixMilli(),
 	})
 }
+
+// IsActive zero value status is treated as active, users built in memory have no status.
+func (u User) IsActive() bool {
+	return u.Status != ACCOUNT_STATUS_FROZEN && u.Status != ACCOUNT_STATUS_CLOSED
+}
+
+// AccountStatusInfo account status of user for admins, CanceledOrderIDs is set if open orders are canceled on change.
+type AccountStatusInfo struct {
+	UserID           string        `json:"user_id"`
+	Username         string        `json:"username"`
+	Status           AccountStatus `json:"status"`
+	TradeDisabled    bool          `json:"trade_disabled"`
+	WithdrawDisabled bool          `json:"withdraw_disabled"`
+	Reason           string        `json:"reason"`
+	CanceledOrderIDs []string      `json:"canceled_order_ids,omitempty"`
+}
+
+func NewAccountStatusInfo(user *User) *AccountStatusInfo {
+	return &AccountStatusInfo{
+		UserID:           user.ID,
+		Username:         user.Username,
+		Status:           user.Status,
+		TradeDisabled:    user.TradeDisabled,
+		WithdrawDisabled: user.WithdrawDisabled,
+		Reason:           user.StatusReason,
+	}
+}
+
+// PasswordResetToken only hash of token is stored, plain token is sent to user by notifier.
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// TwoFactorEnrollment Secret and URI (render as QR code) for authenticator apps, 2FA takes effect after enabling.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorBackupCodes plain backup codes, only returned when generated.
+type TwoFactorBackupCodes struct {
+	BackupCodes []string `json:"backup_codes"`
+}
+
+// ChangePasswordReq TotpCode is required if user enabled 2FA.
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,password"`
+	TotpCode    string `json:"totp_code"`
+}
+
+type PasswordResetRequestReq struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// PasswordResetReq Token is sent by notifier, TotpCode is required if user enabled 2FA.
+type PasswordResetReq struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,password"`
+	TotpCode    string `json:"totp_code"`
+}
+
+// TwoFactorCodeReq Code is TOTP code, backup code is also accepted except enabling.
+type TwoFactorCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type UpdateFeeSettingsReq struct {
+	PayFeeInBTSE *bool `json:"pay_fee_in_btse" binding:"required"`
+}
+
+// UpdateAccountStatusReq replace status and flags of account, CancelOrders cancel open orders of user and its margin wallet.
+type UpdateAccountStatusReq struct {
+	Status           AccountStatus `json:"status" binding:"required"`
+	TradeDisabled    bool          `json:"trade_disabled"`
+	WithdrawDisabled bool          `json:"withdraw_disabled"`
+	Reason           string        `json:"reason" binding:"required"`
+	CancelOrders     bool          `json:"cancel_orders"`
+}
@@ -0,0 +1,138 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WithdrawalStatus string
+
+const (
+	// WITHDRAWAL_STATUS_PENDING waiting for admin review, funds are locked.
+	WITHDRAWAL_STATUS_PENDING WithdrawalStatus = "PENDING"
+	// WITHDRAWAL_STATUS_APPROVED passed all checks, ready to broadcast on chain.
+	WITHDRAWAL_STATUS_APPROVED WithdrawalStatus = "APPROVED"
+	// WITHDRAWAL_STATUS_BROADCAST tx has been sent on chain, funds still locked.
+	WITHDRAWAL_STATUS_BROADCAST WithdrawalStatus = "BROADCAST"
+	// WITHDRAWAL_STATUS_CONFIRMED tx confirmed on chain, locked funds deducted.
+	WITHDRAWAL_STATUS_CONFIRMED WithdrawalStatus = "CONFIRMED"
+	// WITHDRAWAL_STATUS_REJECTED rejected by admin, locked funds returned to available.
+	WITHDRAWAL_STATUS_REJECTED WithdrawalStatus = "REJECTED"
+)
+
+const (
+	WITHDRAWAL_OPERATOR_SYSTEM = "SYSTEM"
+	WITHDRAWAL_OPERATOR_ADMIN  = "ADMIN"
+)
+
+// AdminOperator audit operator of admin, e.g. ADMIN:alice.
+func AdminOperator(admin *Admin) string {
+	return WITHDRAWAL_OPERATOR_ADMIN + ":" + admin.Username
+}
+
+// CanTransitTo check withdrawal state machine:
+// PENDING -> APPROVED -> BROADCAST -> CONFIRMED, PENDING/APPROVED -> REJECTED
+func (s WithdrawalStatus) CanTransitTo(next WithdrawalStatus) bool {
+	switch s {
+	case WITHDRAWAL_STATUS_PENDING:
+		return next == WITHDRAWAL_STATUS_APPROVED || next == WITHDRAWAL_STATUS_REJECTED
+	case WITHDRAWAL_STATUS_APPROVED:
+		return next == WITHDRAWAL_STATUS_BROADCAST || next == WITHDRAWAL_STATUS_REJECTED
+	case WITHDRAWAL_STATUS_BROADCAST:
+		return next == WITHDRAWAL_STATUS_CONFIRMED
+	default:
+		return false
+	}
+}
+
+type Withdrawal struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	Asset     string           `json:"asset"`
+	Amount    float64          `json:"amount"`
+	Address   string           `json:"address"`
+	Valuation float64          `json:"valuation"` // USDT value when requested, used by daily limit.
+	Status    WithdrawalStatus `json:"status"`
+	TxHash    string           `json:"tx_hash"`
+	Remark    string           `json:"remark"`
+	CreatedAt time.Time        `json:"-"`
+	UpdatedAt time.Time        `json:"-"`
+}
+
+func (w Withdrawal) MarshalJSON() ([]byte, error) {
+	type Alias Withdrawal
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+		UpdatedAt int64 `json:"updated_at"`
+	}{
+		Alias:     (*Alias)(&w),
+		CreatedAt: w.CreatedAt.UnixMilli(),
+		UpdatedAt: w.UpdatedAt.UnixMilli(),
+	})
+}
+
+// WithdrawalAddress whitelisted address, only usable after cooling-off period.
+type WithdrawalAddress struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"-"`
+	Asset       string    `json:"asset"`
+	Address     string    `json:"address"`
+	Label       string    `json:"label"`
+	CreatedAt   time.Time `json:"-"`
+	AvailableAt time.Time `json:"-"`
+}
+
+func (a WithdrawalAddress) MarshalJSON() ([]byte, error) {
+	type Alias WithdrawalAddress
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt   int64 `json:"created_at"`
+		AvailableAt int64 `json:"available_at"`
+	}{
+		Alias:       (*Alias)(&a),
+		CreatedAt:   a.CreatedAt.UnixMilli(),
+		AvailableAt: a.AvailableAt.UnixMilli(),
+	})
+}
+
+// WithdrawalAudit record every withdrawal status transition.
+type WithdrawalAudit struct {
+	ID           int64            `json:"id"`
+	WithdrawalID string           `json:"withdrawal_id"`
+	FromStatus   WithdrawalStatus `json:"from_status"`
+	ToStatus     WithdrawalStatus `json:"to_status"`
+	Operator     string           `json:"operator"`
+	Remark       string           `json:"remark"`
+	CreatedAt    time.Time        `json:"-"`
+}
+
+func (a WithdrawalAudit) MarshalJSON() ([]byte, error) {
+	type Alias WithdrawalAudit
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt int64 `json:"created_at"`
+	}{
+		Alias:     (*Alias)(&a),
+		CreatedAt: a.CreatedAt.UnixMilli(),
+	})
+}
+
+type WithdrawReq struct {
+	Asset   string  `json:"asset" binding:"required"`
+	Amount  float64 `json:"amount" binding:"required,gt=0"`
+	Address string  `json:"address" binding:"required"`
+	// TotpCode required if user enabled 2FA.
+	TotpCode string `json:"totp_code"`
+}
+
+type AddWithdrawalAddressReq struct {
+	Asset   string `json:"asset" binding:"required"`
+	Address string `json:"address" binding:"required"`
+	Label   string `json:"label"`
+}
+
+type ReviewWithdrawalReq struct {
+	Remark string `json:"remark"`
+	TxHash string `json:"tx_hash"` // only for broadcast
+}
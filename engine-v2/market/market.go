@@ -4,10 +4,30 @@ import (
 	"fmt"
 )
 
+type MarketType int
+
+const (
+	SPOT      MarketType = iota
+	PERPETUAL            // settled in quote asset, base asset is never delivered
+)
+
+func (t MarketType) String() string {
+	switch t {
+	case SPOT:
+		return "SPOT"
+	case PERPETUAL:
+		return "PERPETUAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 type MarketInfo struct {
-	Name       string // e.g."BTC/USDT"
-	BaseAsset  string // e.g. "BTC"
-	QuoteAsset string // e.g. "USDT"
+	Name         string     // e.g."BTC/USDT"
+	BaseAsset    string     // e.g. "BTC"
+	QuoteAsset   string     // e.g. "USDT"
+	Type         MarketType // default SPOT
+	ContractSize float64    // base asset per contract, only PERPETUAL
 }
 
 func NewMarketInfo(name string, baseAsset, quoteAsset string) *MarketInfo {
@@ -18,6 +38,25 @@ func NewMarketInfo(name string, baseAsset, quoteAsset string) *MarketInfo {
 	}
 }
 
+func NewPerpetualMarketInfo(name string, baseAsset, quoteAsset string, contractSize float64) *MarketInfo {
+	return &MarketInfo{
+		Name:         name,
+		BaseAsset:    baseAsset,
+		QuoteAsset:   quoteAsset,
+		Type:         PERPETUAL,
+		ContractSize: contractSize,
+	}
+}
+
+func (mi *MarketInfo) IsPerpetual() bool {
+	return mi.Type == PERPETUAL
+}
+
+// IndexSymbol spot symbol of underlying index price, e.g. "BTC-USDT" of "BTC-USDT-PERP".
+func (mi *MarketInfo) IndexSymbol() string {
+	return fmt.Sprintf("%s-%s", mi.BaseAsset, mi.QuoteAsset)
+}
+
 type MarketManager struct {
 	markets map[string]*MarketInfo
 }
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"github.com/johnny1110/crypto-exchange/container"
+	"github.com/johnny1110/crypto-exchange/controller"
 	"github.com/johnny1110/crypto-exchange/engine-v2/core"
 	"github.com/johnny1110/crypto-exchange/settings"
 	"github.com/labstack/gommon/log"
+	"os"
 )
 
 func main() {
@@ -18,6 +21,11 @@ func main() {
 		log.Fatalf("failed to open database: %v", err)
 	}
 
+	// Verify audit log hash chain and exit, instead of starting server.
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit-log" {
+		os.Exit(verifyAuditLog(db))
+	}
+
 	engine, err := core.NewMatchingEngine(settings.ALL_MARKETS)
 
 	if err != nil {
@@ -27,6 +35,9 @@ func main() {
 	c := container.NewContainer(db, engine)
 	defer c.Cleanup()
 
+	if err := controller.RegisterValidators(); err != nil {
+		log.Fatalf("failed to register validators: %v", err)
+	}
 	router := setupRouter(c)
 	setupWebSocket(c)
 
@@ -36,6 +47,12 @@ func main() {
 		log.Fatalf("failed to recover orderbook: %v", err)
 	}
 
+	// Create superadmin if there is no admin.
+	err = c.AdminAccountService.BootstrapSuperAdmin(context.Background(), settings.ADMIN_BOOTSTRAP_SECRET_FILE)
+	if err != nil {
+		log.Fatalf("failed to bootstrap superadmin: %v", err)
+	}
+
 	startUpAllScheduler(c)
 
 	log.Infof("Exchange Server starting on :8080")
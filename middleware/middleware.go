@@ -1,22 +1,45 @@
 package middleware
 
 import (
+	"bytes"
 	"errors"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/johnny1110/crypto-exchange/controller"
+	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
 	"github.com/labstack/gommon/log"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
+const (
+	API_KEY_HEADER           = "X-API-KEY"
+	API_KEY_TIMESTAMP_HEADER = "X-API-TIMESTAMP"
+	API_KEY_SIGNATURE_HEADER = "X-API-SIGNATURE"
+	REQUEST_ID_HEADER        = "X-Request-ID"
+)
+
+// apiKeySessionOnlyPaths api key management, logout, sessions, password and 2FA settings require login token.
+var apiKeySessionOnlyPaths = []string{"/api/v1/api-keys", "/api/v1/users/logout", "/api/v1/users/sessions", "/api/v1/users/password", "/api/v1/users/2fa"}
+
+// apiKeyWithdrawPaths moving funds out of account requires withdraw scope.
+var apiKeyWithdrawPaths = []string{"/api/v1/withdrawals", "/api/v1/transfers", "/api/v1/sub-accounts"}
+
 // CORS middleware
 func CORS() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowAllOrigins:  true, // allow all
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Admin-Token"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Admin-Token", API_KEY_HEADER, API_KEY_TIMESTAMP_HEADER, API_KEY_SIGNATURE_HEADER, REQUEST_ID_HEADER},
+		ExposeHeaders:    []string{REQUEST_ID_HEADER, "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-RateLimit-Weight", "Retry-After"},
 		AllowWebSockets:  true,
 		AllowCredentials: true,
 	})
@@ -42,9 +65,112 @@ func ErrorHandler() gin.HandlerFunc {
 	}
 }
 
-// AuthMiddleware middleware
-func AuthMiddleware(credentialCache *security.CredentialCache) gin.HandlerFunc {
+// RequestMeta put request id, client IP and user agent into request context for audit logs.
+// X-Request-ID from client is kept, otherwise a new one is generated, it is echoed in response header.
+func RequestMeta() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(REQUEST_ID_HEADER)
+		if requestId == "" || len(requestId) > 64 {
+			requestId = uuid.NewString()
+		}
+		c.Header(REQUEST_ID_HEADER, requestId)
+		c.Request = c.Request.WithContext(dto.WithRequestMeta(c.Request.Context(), dto.RequestMeta{
+			RequestID: requestId,
+			ClientIP:  security.ClientIP(c.Request),
+			UserAgent: c.Request.UserAgent(),
+		}))
+
+		c.Next()
+	}
+}
+
+// ApiKeyMiddleware verify signed request if X-API-KEY header is present, otherwise leave it to AuthMiddleware.
+// GET requires read scope, withdrawals, transfers and sub-accounts require withdraw scope, others require trade scope.
+func ApiKeyMiddleware(apiKeyService service.IApiKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(API_KEY_HEADER) == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, controller.HandleCodeError(controller.BAD_REQUEST, err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		user, apiKey, err := apiKeyService.Authenticate(c.Request.Context(), &dto.ApiKeyAuth{
+			ApiKey:    c.GetHeader(API_KEY_HEADER),
+			Timestamp: c.GetHeader(API_KEY_TIMESTAMP_HEADER),
+			Signature: c.GetHeader(API_KEY_SIGNATURE_HEADER),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.RequestURI(),
+			Body:      string(body),
+			ClientIP:  security.ClientIP(c.Request),
+		})
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, controller.HandleCodeError(controller.ACCESS_DENIED, err))
+			c.Abort()
+			return
+		}
+
+		if hasPathPrefix(c.FullPath(), apiKeySessionOnlyPaths) {
+			c.JSON(http.StatusForbidden, controller.HandleCodeError(controller.ACCESS_DENIED, errors.New("api key is not allowed, login required")))
+			c.Abort()
+			return
+		}
+		scope := requiredApiKeyScope(c.Request.Method, c.FullPath())
+		if !apiKey.HasScope(scope) {
+			c.JSON(http.StatusForbidden, controller.HandleCodeError(controller.ACCESS_DENIED, errors.New("api key scope required: "+string(scope))))
+			c.Abort()
+			return
+		}
+
+		// store user info and api key into context
+		c.Set("user", user)
+		c.Set("userId", user.ID)
+		c.Set("username", user.Username)
+		c.Set("apiKey", apiKey)
+
+		log.Infof("Api key user: %s, api key: %s", user.Username, apiKey.ApiKey)
+
+		c.Next()
+	}
+}
+
+func requiredApiKeyScope(method, path string) dto.ApiKeyScope {
+	if method == http.MethodGet {
+		return dto.API_KEY_SCOPE_READ
+	}
+	if hasPathPrefix(path, apiKeyWithdrawPaths) {
+		return dto.API_KEY_SCOPE_WITHDRAW
+	}
+	return dto.API_KEY_SCOPE_TRADE
+}
+
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthMiddleware middleware, skipped if user is already authenticated by ApiKeyMiddleware.
+// Frozen and closed accounts are refused for both login token and API key.
+func AuthMiddleware(sessionStore security.SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if user, exists := c.Get("user"); exists {
+			if rejectInactiveAccount(c, user.(*dto.User)) {
+				return
+			}
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			//Authorization header required
@@ -59,13 +185,16 @@ func AuthMiddleware(credentialCache *security.CredentialCache) gin.HandlerFunc {
 			token = strings.TrimPrefix(authHeader, "Bearer ")
 		}
 
-		user, err := credentialCache.Get(token)
+		user, err := sessionStore.Get(c.Request.Context(), token)
 		if err != nil || user == nil {
 			// Invalid or expired token
 			c.JSON(http.StatusUnauthorized, controller.HandleCodeError(controller.ACCESS_DENIED, errors.New("invalid or expired token")))
 			c.Abort()
 			return
 		}
+		if rejectInactiveAccount(c, user) {
+			return
+		}
 
 		// store user info and token into context
 		c.Set("user", user)
@@ -79,8 +208,19 @@ func AuthMiddleware(credentialCache *security.CredentialCache) gin.HandlerFunc {
 	}
 }
 
-// AdminMiddleware middleware
-func AdminMiddleware() gin.HandlerFunc {
+// rejectInactiveAccount abort with 403 if account is frozen or closed.
+func rejectInactiveAccount(c *gin.Context, user *dto.User) bool {
+	if user.IsActive() {
+		return false
+	}
+	log.Warnf("[AuthMiddleware] %s account %s refused, path: %s", user.Status, user.ID, c.FullPath())
+	c.JSON(http.StatusForbidden, controller.HandleCodeError(controller.ACCOUNT_DISABLED, errors.New("account is "+strings.ToLower(string(user.Status)))))
+	c.Abort()
+	return true
+}
+
+// AdminMiddleware authenticate admin by Admin-Token header (admin login token).
+func AdminMiddleware(adminSessionCache *security.AdminSessionCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		adminToken := c.GetHeader("Admin-Token")
 		if adminToken == "" {
@@ -90,10 +230,28 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// TODO: revamp this, make it better.
-		if adminToken != "frizo" {
+		admin, err := adminSessionCache.Get(adminToken)
+		if err != nil {
 			// Invalid or expired token
-			c.JSON(http.StatusUnauthorized, controller.HandleCodeError(controller.ACCESS_DENIED, errors.New("invalid or expired token")))
+			c.JSON(http.StatusUnauthorized, controller.HandleCodeError(controller.ACCESS_DENIED, err))
+			c.Abort()
+			return
+		}
+
+		c.Set("admin", admin)
+		c.Set("adminToken", adminToken)
+
+		c.Next()
+	}
+}
+
+// AdminPermission per endpoint permission check, must be used after AdminMiddleware.
+func AdminPermission(permission dto.AdminPermission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin := c.MustGet("admin").(*dto.Admin)
+		if !admin.Role.HasPermission(permission) {
+			log.Warnf("[AdminPermission] admin %s (%s) denied, permission required: %s, path: %s", admin.Username, admin.Role, permission, c.FullPath())
+			c.JSON(http.StatusForbidden, controller.HandleCodeError(controller.ACCESS_DENIED, errors.New("admin permission required: "+string(permission))))
 			c.Abort()
 			return
 		}
@@ -102,10 +260,68 @@ func AdminMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware middleware
-func RateLimitMiddleware() gin.HandlerFunc {
-	return gin.HandlerFunc(func(c *gin.Context) {
-		// TODO: IP check
+// RateLimitMiddleware weighted rate limit of public routes by client IP.
+func RateLimitMiddleware(limiter *security.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rateLimit(c, limiter, "ip:"+security.ClientIP(c.Request)) {
+			return
+		}
 		c.Next()
-	})
+	}
+}
+
+// UserRateLimitMiddleware weighted rate limit of private routes by API key or user, must be used after auth middlewares.
+// API keys have own buckets, so bots of one user do not exhaust limit of its login sessions.
+func UserRateLimitMiddleware(limiter *security.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "user:" + c.GetString("userId")
+		if apiKey, exists := c.Get("apiKey"); exists {
+			key = "apikey:" + apiKey.(*dto.ApiKey).ApiKey
+		}
+		if !rateLimit(c, limiter, key) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// OrderRateLimitMiddleware orders per second of user in market, apart from request weight, must be used after auth middlewares.
+func OrderRateLimitMiddleware(limiter *security.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := limiter.Allow(c.GetString("userId")+":"+c.Param("market"), 1, time.Now())
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, controller.HandleCodeError(controller.RATE_LIMITED, errors.New("too many orders in market "+c.Param("market"))))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimit take route weight from bucket of key and write X-RateLimit-* headers, abort with 429 if exceeded.
+func rateLimit(c *gin.Context, limiter *security.RateLimiter, key string) bool {
+	weight, ok := settings.RATE_LIMIT_WEIGHTS[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		weight = settings.RATE_LIMIT_DEFAULT_WEIGHT
+	}
+
+	result := limiter.Allow(key, weight, time.Now())
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	c.Header("X-RateLimit-Weight", strconv.Itoa(weight))
+	if result.Allowed {
+		return true
+	}
+
+	log.Warnf("[RateLimit] rate limit exceeded, key: %s, path: %s %s", key, c.Request.Method, c.FullPath())
+	c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	c.JSON(http.StatusTooManyRequests, controller.HandleCodeError(controller.RATE_LIMITED, errors.New("rate limit exceeded")))
+	c.Abort()
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
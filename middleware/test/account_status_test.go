@@ -0,0 +1,48 @@
+package test
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/middleware"
+	"github.com/johnny1110/crypto-exchange/security"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthAccountStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sessionStore := security.NewMemorySessionStore()
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(sessionStore))
+	router.GET("/api/v1/balances", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("userId"))
+	})
+
+	tests := []struct {
+		name     string
+		user     *dto.User
+		expected int
+	}{
+		{"active", &dto.User{ID: "U1", Status: dto.ACCOUNT_STATUS_ACTIVE}, http.StatusOK},
+		{"trade disabled", &dto.User{ID: "U2", Status: dto.ACCOUNT_STATUS_ACTIVE, TradeDisabled: true}, http.StatusOK},
+		{"frozen", &dto.User{ID: "U3", Status: dto.ACCOUNT_STATUS_FROZEN}, http.StatusForbidden},
+		{"closed", &dto.User{ID: "U4", Status: dto.ACCOUNT_STATUS_CLOSED}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := sessionStore.Create(context.Background(), tt.user, "10.0.0.1", "phone")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/balances", nil)
+			req.Header.Set("Authorization", "Bearer "+session.Token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.expected {
+				t.Errorf("Expected %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApiKeyScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// allowlistApiKeyService grants read scope only.
+	router.Use(middleware.ApiKeyMiddleware(&allowlistApiKeyService{}))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString("userId")) }
+	router.GET("/api/v1/orders", ok)
+	router.POST("/api/v1/orders/:market", ok)
+	router.POST("/api/v1/withdrawals", ok)
+	router.GET("/api/v1/api-keys", ok)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		expected int
+	}{
+		{"read", http.MethodGet, "/api/v1/orders", http.StatusOK},
+		{"trade", http.MethodPost, "/api/v1/orders/HDX-USDT", http.StatusForbidden},
+		{"withdraw", http.MethodPost, "/api/v1/withdrawals", http.StatusForbidden},
+		{"session only", http.MethodGet, "/api/v1/api-keys", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = allowedIP + ":40000"
+			req.Header.Set(middleware.API_KEY_HEADER, "key")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.expected {
+				t.Errorf("Expected %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
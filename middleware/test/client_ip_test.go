@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/middleware"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const allowedIP = "10.0.0.1"
+
+// allowlistApiKeyService accepts any api key, only from allowedIP.
+type allowlistApiKeyService struct {
+	service.IApiKeyService
+}
+
+func (s *allowlistApiKeyService) Authenticate(ctx context.Context, auth *dto.ApiKeyAuth) (*dto.User, *dto.ApiKey, error) {
+	if auth.ClientIP != allowedIP {
+		return nil, nil, errors.New("ip not allowed: " + auth.ClientIP)
+	}
+	return &dto.User{ID: "U1", Username: "bot"}, &dto.ApiKey{ApiKey: auth.ApiKey, Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_READ}}, nil
+}
+
+func newRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(settings.TRUSTED_PROXIES); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(middleware.ApiKeyMiddleware(&allowlistApiKeyService{}))
+	router.GET("/api/v1/balances", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("userId"))
+	})
+	return router
+}
+
+func TestApiKeyIPAllowlist(t *testing.T) {
+	defer func(proxies []string) { settings.TRUSTED_PROXIES = proxies }(settings.TRUSTED_PROXIES)
+
+	tests := []struct {
+		name          string
+		proxies       []string
+		remoteAddr    string
+		xForwardedFor string
+		expected      int
+	}{
+		{"direct allowed ip", nil, allowedIP + ":40000", "", http.StatusOK},
+		{"direct spoofed X-Forwarded-For", nil, "203.0.113.9:40000", allowedIP, http.StatusUnauthorized},
+		{"untrusted proxy", []string{"192.168.0.0/16"}, "203.0.113.9:40000", allowedIP, http.StatusUnauthorized},
+		{"trusted proxy", []string{"192.168.0.0/16"}, "192.168.1.1:40000", allowedIP, http.StatusOK},
+		{"trusted proxy spoofed X-Forwarded-For", []string{"192.168.0.0/16"}, "192.168.1.1:40000", allowedIP + ", 203.0.113.9", http.StatusUnauthorized},
+		{"trusted proxy chain", []string{"192.168.0.0/16"}, "192.168.1.1:40000", "203.0.113.9, " + allowedIP + ", 192.168.1.2", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings.TRUSTED_PROXIES = tt.proxies
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/balances", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(middleware.API_KEY_HEADER, "key")
+			if tt.xForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.xForwardedFor)
+			}
+			w := httptest.NewRecorder()
+			newRouter(t).ServeHTTP(w, req)
+			if w.Code != tt.expected {
+				t.Errorf("Expected %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
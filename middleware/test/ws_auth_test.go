@@ -0,0 +1,130 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/middleware"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/ws"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loginWS connect websocket, login by token and subscribe orders channel.
+func loginWS(t *testing.T, hub *ws.Hub, sessionStore security.SessionStore, token string) *websocket.Conn {
+	t.Helper()
+	auth := middleware.NewWSAuthenticator(sessionStore, &allowlistApiKeyService{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws.HandleWebSocket(hub, auth, w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	writeWS(t, conn, ws.WSReq{Action: ws.LOGIN, Params: ws.LoginReqParams{Token: token}})
+	if resp := readWS(t, conn); resp.Channel != ws.AUTH {
+		t.Fatalf("login failed: %v", resp.Data)
+	}
+	writeWS(t, conn, ws.WSReq{Action: ws.SUBSCRIBE, Channel: ws.ORDERS})
+	return conn
+}
+
+func writeWS(t *testing.T, conn *websocket.Conn, req ws.WSReq) {
+	t.Helper()
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) ws.WSResp {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp ws.WSResp
+	if err := json.Unmarshal(message, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// waitSubscribed wait until subscription of orders channel is registered or dropped.
+func waitSubscribed(t *testing.T, hub *ws.Hub, userId string, subscribed bool) {
+	t.Helper()
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if hub.Subscribed(ws.ORDERS, userId) == subscribed {
+			return
+		}
+	}
+	t.Fatalf("Expected subscribed %v", subscribed)
+}
+
+func Test_WSLogin_RevokedStopsPrivateChannels(t *testing.T) {
+	interval := settings.WS_LOGIN_CHECK_INTERVAL
+	settings.WS_LOGIN_CHECK_INTERVAL = 50 * time.Millisecond
+	defer func() { settings.WS_LOGIN_CHECK_INTERVAL = interval }()
+
+	tests := []struct {
+		name   string
+		revoke func(store security.SessionStore, user *dto.User, token string)
+	}{
+		{"logout", func(store security.SessionStore, user *dto.User, token string) {
+			store.Delete(context.Background(), token)
+		}},
+		{"logout others", func(store security.SessionStore, user *dto.User, token string) {
+			store.DeleteOthers(context.Background(), user.ID, "")
+		}},
+		{"frozen", func(store security.SessionStore, user *dto.User, token string) {
+			frozen := *user
+			frozen.Status = dto.ACCOUNT_STATUS_FROZEN
+			store.RefreshUser(&frozen)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			hub := ws.NewHub()
+			go hub.Run(ctx)
+
+			user := &dto.User{ID: "U1", Username: "alice", Status: dto.ACCOUNT_STATUS_ACTIVE}
+			sessionStore := security.NewMemorySessionStore()
+			session, err := sessionStore.Create(ctx, user, allowedIP, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn := loginWS(t, hub, sessionStore, session.Token)
+			waitSubscribed(t, hub, user.ID, true)
+
+			tt.revoke(sessionStore, user, session.Token)
+			resp := readWS(t, conn)
+			assert(t, resp.Channel, ws.ERROR)
+			assert(t, resp.Data, ws.ErrLoginRevoked.Error())
+			waitSubscribed(t, hub, user.ID, false)
+
+			// private channels need login again.
+			writeWS(t, conn, ws.WSReq{Action: ws.SUBSCRIBE, Channel: ws.ORDERS})
+			assert(t, readWS(t, conn).Data, ws.ErrLoginRequired.Error())
+		})
+	}
+}
+
+func assert(t *testing.T, a, b any) {
+	t.Helper()
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/ws"
+	"net/http"
+	"strings"
+)
+
+// wsAuthenticator websocket login by login token like AuthMiddleware, or by API key with read scope like ApiKeyMiddleware.
+type wsAuthenticator struct {
+	sessionStore  security.SessionStore
+	apiKeyService service.IApiKeyService
+}
+
+func NewWSAuthenticator(sessionStore security.SessionStore, apiKeyService service.IApiKeyService) ws.Authenticator {
+	return &wsAuthenticator{
+		sessionStore:  sessionStore,
+		apiKeyService: apiKeyService,
+	}
+}
+
+func (a *wsAuthenticator) Authenticate(ctx context.Context, params *ws.LoginReqParams, clientIP string) (string, error) {
+	var user *dto.User
+	switch {
+	case params.Token != "":
+		sessionUser, err := a.sessionStore.Get(ctx, params.Token)
+		if err != nil || sessionUser == nil {
+			return "", errors.New("invalid or expired token")
+		}
+		user = sessionUser
+
+	case params.ApiKey != "":
+		apiKeyUser, apiKey, err := a.apiKeyService.Authenticate(ctx, &dto.ApiKeyAuth{
+			ApiKey:    params.ApiKey,
+			Timestamp: params.Timestamp,
+			Signature: params.Signature,
+			Method:    http.MethodGet,
+			Path:      ws.ENDPOINT,
+			ClientIP:  clientIP,
+		})
+		if err != nil {
+			return "", err
+		}
+		if !apiKey.HasScope(dto.API_KEY_SCOPE_READ) {
+			return "", errors.New("api key scope required: " + string(dto.API_KEY_SCOPE_READ))
+		}
+		user = apiKeyUser
+
+	default:
+		return "", errors.New("token or api key required")
+	}
+
+	if !user.IsActive() {
+		return "", errors.New("account is " + strings.ToLower(string(user.Status)))
+	}
+	return user.ID, nil
+}
+
+func (a *wsAuthenticator) Revalidate(ctx context.Context, params *ws.LoginReqParams) error {
+	var user *dto.User
+	var err error
+	if params.Token != "" {
+		user, err = a.sessionStore.Get(ctx, params.Token)
+		if err == nil && user == nil {
+			err = errors.New("invalid or expired token")
+		}
+	} else {
+		user, err = a.apiKeyService.GetActiveApiKeyOwner(ctx, params.ApiKey)
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsActive() {
+		return errors.New("account is " + strings.ToLower(string(user.Status)))
+	}
+	return nil
+}
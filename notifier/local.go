@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// localNotifier for development, notifications are appended to local file instead of being sent.
+// Body may carry secrets (e.g. reset tokens), so only subject goes to server log.
+type localNotifier struct {
+	mu       sync.Mutex
+	filePath string
+}
+
+func NewLocalNotifier(filePath string) Notifier {
+	return &localNotifier{filePath: filePath}
+}
+
+func (n *localNotifier) Notify(ctx context.Context, notification *Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create notification dir: %w", err)
+	}
+	file, err := os.OpenFile(n.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s to: %s (%s) subject: %s\n%s\n\n",
+		time.Now().Format(time.RFC3339), notification.Username, notification.UserID, notification.Subject, notification.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+
+	log.Infof("[LocalNotifier] notification to %s: %s, see %s", notification.Username, notification.Subject, n.filePath)
+	return nil
+}
@@ -0,0 +1,16 @@
+package notifier
+
+import "context"
+
+// Notification message to user, delivered through channel of Notifier implementation (email, sms...).
+type Notification struct {
+	UserID   string
+	Username string
+	Subject  string
+	Body     string
+}
+
+// Notifier implementations must be safe for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, notification *Notification) error
+}
@@ -21,6 +21,7 @@ type Trade struct {
 	Price     float64 // price limit
 	Volume    float64 // dealt qty
 	Timestamp time.Time
+	Block     bool // RFQ block trade, counted in volume but does not move prices
 }
 
 type GetOhlcvDataReq struct {
@@ -105,6 +106,12 @@ func (b *OHLCVBar) Update(trade *Trade) error {
 	if trade.Volume <= 0 {
 		return fmt.Errorf("invalid volume: %f", trade.Volume)
 	}
+	if trade.Block {
+		b.Volume += trade.Volume
+		b.QuoteVolume += trade.Volume * trade.Price
+		b.TradeCount++
+		return nil
+	}
 	// Update high (h)
 	b.HighPrice = max(b.HighPrice, trade.Price)
 	// update low (l)
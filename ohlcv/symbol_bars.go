@@ -121,8 +121,7 @@ func (s *RealtimeSymbolBars) UpdateByTrades(ctx context.Context, trades []*Trade
 		if bar, exists := intervalBars[key.bucketTime]; exists {
 			bar.BatchUpdate(tradeList)
 		} else {
-			firstTrade := tradeList[0]
-			newBar := NewOhlcvBar(s.symbol, firstTrade.Price, key.bucketTime, SupportedIntervals[key.interval].Duration)
+			newBar := NewOhlcvBar(s.symbol, s.openPrice(intervalBars, tradeList), key.bucketTime, SupportedIntervals[key.interval].Duration)
 			intervalBars[key.bucketTime] = newBar
 			newBar.BatchUpdate(tradeList)
 		}
@@ -131,6 +130,25 @@ func (s *RealtimeSymbolBars) UpdateByTrades(ctx context.Context, trades []*Trade
 	s.mu.Unlock()
 }
 
+// openPrice price of first non-block trade, block trades do not move prices so bar opened by them continues
+// close price of latest bar.
+func (s *RealtimeSymbolBars) openPrice(intervalBars map[int64]*OHLCVBar, trades []*Trade) float64 {
+	for _, trade := range trades {
+		if !trade.Block {
+			return trade.Price
+		}
+	}
+
+	var latestBar *OHLCVBar
+	for _, bar := range intervalBars {
+		latestBar = latest(latestBar, bar)
+	}
+	if latestBar != nil {
+		return latestBar.ClosePrice
+	}
+	return trades[0].Price
+}
+
 func latest(bar1 *OHLCVBar, bar2 *OHLCVBar) *OHLCVBar {
 	if bar1 == nil && bar2 == nil {
 		return nil
@@ -0,0 +1,231 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+const adminColumns = `id, username, password_hash, role, totp_secret, disabled, created_at, updated_at`
+
+type adminRepository struct {
+}
+
+func NewAdminRepository() repository.IAdminRepository {
+	return &adminRepository{}
+}
+
+func (a adminRepository) Insert(ctx context.Context, db repository.DBExecutor, admin *dto.Admin) error {
+	query := `INSERT INTO admins (` + adminColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	admin.CreatedAt = time.Now()
+	admin.UpdatedAt = admin.CreatedAt
+	_, err := db.ExecContext(ctx, query,
+		admin.ID,
+		admin.Username,
+		admin.PasswordHash,
+		admin.Role,
+		admin.TotpSecret,
+		admin.Disabled,
+		admin.CreatedAt,
+		admin.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert admin: %w", err)
+	}
+	return nil
+}
+
+func (a adminRepository) GetAdminById(ctx context.Context, db repository.DBExecutor, adminId string) (*dto.Admin, error) {
+	query := `SELECT ` + adminColumns + ` FROM admins WHERE id = ?`
+
+	admin, err := scanAdmin(db.QueryRowContext(ctx, query, adminId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("admin with id %s not found", adminId)
+		}
+		return nil, fmt.Errorf("failed to get admin by id: %w", err)
+	}
+	return admin, nil
+}
+
+func (a adminRepository) GetAdminByUsername(ctx context.Context, db repository.DBExecutor, username string) (*dto.Admin, error) {
+	query := `SELECT ` + adminColumns + ` FROM admins WHERE username = ?`
+
+	admin, err := scanAdmin(db.QueryRowContext(ctx, query, username))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("admin with username %s not found", username)
+		}
+		return nil, fmt.Errorf("failed to get admin by username: %w", err)
+	}
+	return admin, nil
+}
+
+func (a adminRepository) GetAdmins(ctx context.Context, db repository.DBExecutor) ([]*dto.Admin, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+adminColumns+` FROM admins ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query admins: %w", err)
+	}
+	defer rows.Close()
+
+	var admins []*dto.Admin
+	for rows.Next() {
+		admin, err := scanAdmin(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan admin: %w", err)
+		}
+		admins = append(admins, admin)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return admins, nil
+}
+
+func (a adminRepository) CountAdmins(ctx context.Context, db repository.DBExecutor) (int64, error) {
+	var count int64
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM admins`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count admins: %w", err)
+	}
+	return count, nil
+}
+
+func (a adminRepository) CountActiveByRole(ctx context.Context, db repository.DBExecutor, role dto.AdminRole) (int64, error) {
+	var count int64
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM admins WHERE role = ? AND disabled = 0`, role).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count admins by role: %w", err)
+	}
+	return count, nil
+}
+
+func (a adminRepository) UpdateRoleAndDisabled(ctx context.Context, db repository.DBExecutor, admin *dto.Admin) error {
+	query := `UPDATE admins SET role = ?, disabled = ?, updated_at = ? WHERE id = ?`
+
+	admin.UpdatedAt = time.Now()
+	result, err := db.ExecContext(ctx, query, admin.Role, admin.Disabled, admin.UpdatedAt, admin.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update admin: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("admin with id %s not found", admin.ID)
+	}
+	return nil
+}
+
+func (a adminRepository) UpdatePasswordHash(ctx context.Context, db repository.DBExecutor, adminId, passwordHash string) error {
+	return a.update(ctx, db, `UPDATE admins SET password_hash = ?, updated_at = ? WHERE id = ?`, passwordHash, adminId)
+}
+
+func (a adminRepository) UpdateTotpSecret(ctx context.Context, db repository.DBExecutor, adminId, totpSecret string) error {
+	return a.update(ctx, db, `UPDATE admins SET totp_secret = ?, updated_at = ? WHERE id = ?`, totpSecret, adminId)
+}
+
+func (a adminRepository) update(ctx context.Context, db repository.DBExecutor, query, value, adminId string) error {
+	result, err := db.ExecContext(ctx, query, value, time.Now(), adminId)
+	if err != nil {
+		return fmt.Errorf("failed to update admin: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("admin with id %s not found", adminId)
+	}
+	return nil
+}
+
+func (a adminRepository) InsertManualAdjustment(ctx context.Context, db repository.DBExecutor, adjustment *dto.ManualAdjustment) error {
+	query := `INSERT INTO manual_adjustments (admin_id, user_id, asset, amount, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	adjustment.CreatedAt = time.Now()
+	result, err := db.ExecContext(ctx, query,
+		adjustment.AdminID,
+		adjustment.UserID,
+		adjustment.Asset,
+		adjustment.Amount,
+		adjustment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert manual adjustment: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	adjustment.ID = id
+
+	return nil
+}
+
+func (a adminRepository) GetManualAdjustments(ctx context.Context, db repository.DBExecutor, userId string, limit int) ([]*dto.ManualAdjustment, error) {
+	query := `SELECT m.id, m.admin_id, COALESCE(a.username, ''), m.user_id, m.asset, m.amount, m.created_at
+		FROM manual_adjustments m LEFT JOIN admins a ON a.id = m.admin_id
+		WHERE (? = '' OR m.user_id = ?) ORDER BY m.id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, userId, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query manual adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	var adjustments []*dto.ManualAdjustment
+	for rows.Next() {
+		adjustment := &dto.ManualAdjustment{}
+		err := rows.Scan(
+			&adjustment.ID,
+			&adjustment.AdminID,
+			&adjustment.AdminUsername,
+			&adjustment.UserID,
+			&adjustment.Asset,
+			&adjustment.Amount,
+			&adjustment.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan manual adjustment: %w", err)
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+type adminRowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAdmin(row adminRowScanner) (*dto.Admin, error) {
+	admin := &dto.Admin{}
+	err := row.Scan(
+		&admin.ID,
+		&admin.Username,
+		&admin.PasswordHash,
+		&admin.Role,
+		&admin.TotpSecret,
+		&admin.Disabled,
+		&admin.CreatedAt,
+		&admin.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
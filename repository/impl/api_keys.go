@@ -0,0 +1,180 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"strings"
+	"time"
+)
+
+const apiKeyColumns = `api_key, user_id, label, secret, scopes, ip_allowlist, expires_at, revoked, last_used_at, created_at`
+
+type apiKeyRepository struct {
+}
+
+func NewApiKeyRepository() repository.IApiKeyRepository {
+	return &apiKeyRepository{}
+}
+
+func (a apiKeyRepository) Insert(ctx context.Context, db repository.DBExecutor, apiKey *dto.ApiKey) error {
+	query := `INSERT INTO api_keys (` + apiKeyColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	scopes := make([]string, 0, len(apiKey.Scopes))
+	for _, scope := range apiKey.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	apiKey.CreatedAt = time.Now()
+	_, err := db.ExecContext(ctx, query,
+		apiKey.ApiKey,
+		apiKey.UserID,
+		apiKey.Label,
+		apiKey.Secret,
+		strings.Join(scopes, ","),
+		strings.Join(apiKey.IPAllowlist, ","),
+		apiKey.ExpiresAt,
+		apiKey.Revoked,
+		apiKey.LastUsedAt,
+		apiKey.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+func (a apiKeyRepository) GetApiKey(ctx context.Context, db repository.DBExecutor, apiKey string) (*dto.ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE api_key = ?`
+
+	result, err := scanApiKey(db.QueryRowContext(ctx, query, apiKey))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key %s not found", apiKey)
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return result, nil
+}
+
+func (a apiKeyRepository) GetApiKeysByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = ? ORDER BY created_at DESC`
+
+	rows, err := db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var apiKeys []*dto.ApiKey
+	for rows.Next() {
+		apiKey, err := scanApiKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (a apiKeyRepository) CountActiveByUserId(ctx context.Context, db repository.DBExecutor, userId string) (int64, error) {
+	query := `SELECT COUNT(*) FROM api_keys WHERE user_id = ? AND revoked = 0`
+
+	var count int64
+	if err := db.QueryRowContext(ctx, query, userId).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count api keys: %w", err)
+	}
+	return count, nil
+}
+
+func (a apiKeyRepository) UpdateLabel(ctx context.Context, db repository.DBExecutor, userId, apiKey, label string) error {
+	query := `UPDATE api_keys SET label = ? WHERE api_key = ? AND user_id = ?`
+
+	result, err := db.ExecContext(ctx, query, label, apiKey, userId)
+	if err != nil {
+		return fmt.Errorf("failed to update api key label: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("api key %s not found", apiKey)
+	}
+	return nil
+}
+
+func (a apiKeyRepository) Revoke(ctx context.Context, db repository.DBExecutor, userId, apiKey string) error {
+	query := `UPDATE api_keys SET revoked = 1 WHERE api_key = ? AND user_id = ? AND revoked = 0`
+
+	result, err := db.ExecContext(ctx, query, apiKey, userId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("active api key %s not found", apiKey)
+	}
+	return nil
+}
+
+func (a apiKeyRepository) UpdateLastUsedAt(ctx context.Context, db repository.DBExecutor, apiKey string, lastUsedAt time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE api_key = ?`, lastUsedAt, apiKey)
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used at: %w", err)
+	}
+	return nil
+}
+
+type apiKeyRowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanApiKey(row apiKeyRowScanner) (*dto.ApiKey, error) {
+	apiKey := &dto.ApiKey{}
+	var scopes, ipAllowlist string
+	err := row.Scan(
+		&apiKey.ApiKey,
+		&apiKey.UserID,
+		&apiKey.Label,
+		&apiKey.Secret,
+		&scopes,
+		&ipAllowlist,
+		&apiKey.ExpiresAt,
+		&apiKey.Revoked,
+		&apiKey.LastUsedAt,
+		&apiKey.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey.Scopes = make([]dto.ApiKeyScope, 0)
+	for _, scope := range splitNonEmpty(scopes) {
+		apiKey.Scopes = append(apiKey.Scopes, dto.ApiKeyScope(scope))
+	}
+	apiKey.IPAllowlist = splitNonEmpty(ipAllowlist)
+	return apiKey, nil
+}
+
+func splitNonEmpty(s string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
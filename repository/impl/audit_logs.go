@@ -0,0 +1,121 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+const auditLogColumns = `id, actor_type, actor_id, actor_name, action, target, client_ip, user_agent, request_id,
+	before_value, after_value, prev_hash, hash, created_at`
+
+type auditLogRepository struct {
+}
+
+func NewAuditLogRepository() repository.IAuditLogRepository {
+	return &auditLogRepository{}
+}
+
+func (r auditLogRepository) Insert(ctx context.Context, db repository.DBExecutor, auditLog *dto.AuditLog) error {
+	query := `INSERT INTO audit_logs (actor_type, actor_id, actor_name, action, target, client_ip, user_agent, request_id,
+		before_value, after_value, prev_hash, hash, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := db.ExecContext(ctx, query,
+		auditLog.ActorType,
+		auditLog.ActorID,
+		auditLog.ActorName,
+		auditLog.Action,
+		auditLog.Target,
+		auditLog.ClientIP,
+		auditLog.UserAgent,
+		auditLog.RequestID,
+		auditLog.Before,
+		auditLog.After,
+		auditLog.PrevHash,
+		auditLog.Hash,
+		auditLog.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit log: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	auditLog.ID = id
+
+	return nil
+}
+
+func (r auditLogRepository) GetLastHash(ctx context.Context, db repository.DBExecutor) (string, error) {
+	var hash string
+	err := db.QueryRowContext(ctx, `SELECT hash FROM audit_logs ORDER BY id DESC LIMIT 1`).Scan(&hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get last audit log hash: %w", err)
+	}
+	return hash, nil
+}
+
+func (r auditLogRepository) GetAuditLogs(ctx context.Context, db repository.DBExecutor, req *dto.AuditLogQueryReq, from, to time.Time, limit int) ([]*dto.AuditLog, error) {
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs
+		WHERE (? = '' OR actor_id = ?) AND (? = '' OR action = ?) AND (? = '' OR target = ?)
+		AND created_at >= ? AND created_at < ?
+		ORDER BY id DESC LIMIT ?`
+
+	return r.queryAuditLogs(ctx, db, query,
+		req.ActorID, req.ActorID,
+		req.Action, req.Action,
+		req.Target, req.Target,
+		from, to, limit)
+}
+
+func (r auditLogRepository) GetAuditLogsAfterId(ctx context.Context, db repository.DBExecutor, afterId int64, limit int) ([]*dto.AuditLog, error) {
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs WHERE id > ? ORDER BY id LIMIT ?`
+	return r.queryAuditLogs(ctx, db, query, afterId, limit)
+}
+
+func (r auditLogRepository) queryAuditLogs(ctx context.Context, db repository.DBExecutor, query string, args ...any) ([]*dto.AuditLog, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var auditLogs []*dto.AuditLog
+	for rows.Next() {
+		auditLog := &dto.AuditLog{}
+		err := rows.Scan(
+			&auditLog.ID,
+			&auditLog.ActorType,
+			&auditLog.ActorID,
+			&auditLog.ActorName,
+			&auditLog.Action,
+			&auditLog.Target,
+			&auditLog.ClientIP,
+			&auditLog.UserAgent,
+			&auditLog.RequestID,
+			&auditLog.Before,
+			&auditLog.After,
+			&auditLog.PrevHash,
+			&auditLog.Hash,
+			&auditLog.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return auditLogs, nil
+}
@@ -0,0 +1,95 @@
+package repositoryImpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+type convertRepository struct {
+}
+
+func NewConvertRepository() repository.IConvertRepository {
+	return &convertRepository{}
+}
+
+func (c convertRepository) InsertConvert(ctx context.Context, db repository.DBExecutor, convert *dto.Convert) error {
+	query := `INSERT INTO converts
+		(user_id, quote_id, from_asset, to_asset, from_amount, to_amount, quoted_to_amount, order_ids, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	orderIds, err := json.Marshal(convert.OrderIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal convert order ids: %w", err)
+	}
+
+	convert.CreatedAt = time.Now()
+	result, err := db.ExecContext(ctx, query,
+		convert.UserID,
+		convert.QuoteID,
+		convert.FromAsset,
+		convert.ToAsset,
+		convert.FromAmount,
+		convert.ToAmount,
+		convert.QuotedToAmount,
+		string(orderIds),
+		convert.Status,
+		convert.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert convert: %w", err)
+	}
+
+	convert.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	return nil
+}
+
+func (c convertRepository) GetConvertsByUserId(ctx context.Context, db repository.DBExecutor, userId string, limit int) ([]*dto.Convert, error) {
+	query := `SELECT id, user_id, quote_id, from_asset, to_asset, from_amount, to_amount, quoted_to_amount, order_ids, status, created_at
+		FROM converts WHERE user_id = ? ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query converts: %w", err)
+	}
+	defer rows.Close()
+
+	var converts []*dto.Convert
+	for rows.Next() {
+		convert := &dto.Convert{}
+		var orderIds string
+		err := rows.Scan(
+			&convert.ID,
+			&convert.UserID,
+			&convert.QuoteID,
+			&convert.FromAsset,
+			&convert.ToAsset,
+			&convert.FromAmount,
+			&convert.ToAmount,
+			&convert.QuotedToAmount,
+			&orderIds,
+			&convert.Status,
+			&convert.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan convert: %w", err)
+		}
+		if err := json.Unmarshal([]byte(orderIds), &convert.OrderIDs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal convert order ids: %w", err)
+		}
+		converts = append(converts, convert)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return converts, nil
+}
@@ -0,0 +1,64 @@
+package repositoryImpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+type equitySnapshotRepository struct {
+}
+
+func NewEquitySnapshotRepository() repository.IEquitySnapshotRepository {
+	return &equitySnapshotRepository{}
+}
+
+// Upsert insert snapshot or overwrite the same user and date one, so snapshot job is safe to rerun.
+func (e equitySnapshotRepository) Upsert(ctx context.Context, db repository.DBExecutor, snapshot *dto.EquitySnapshot) error {
+	query := `INSERT INTO equity_snapshots (user_id, snapshot_date, equity, quote_asset, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, snapshot_date) DO UPDATE SET
+		equity = excluded.equity, quote_asset = excluded.quote_asset, created_at = excluded.created_at`
+
+	_, err := db.ExecContext(ctx, query, snapshot.UserID, snapshot.SnapshotDate, snapshot.Equity, snapshot.QuoteAsset, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to upsert equity snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUserIdSince query user snapshots which date >= sinceDate (2006-01-02) order by date asc.
+func (e equitySnapshotRepository) GetByUserIdSince(ctx context.Context, db repository.DBExecutor, userId string, sinceDate string) ([]*dto.EquitySnapshot, error) {
+	query := `SELECT user_id, snapshot_date, equity, quote_asset, created_at FROM equity_snapshots
+		WHERE user_id = ? AND snapshot_date >= ? ORDER BY snapshot_date ASC`
+
+	rows, err := db.QueryContext(ctx, query, userId, sinceDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query equity snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*dto.EquitySnapshot
+	for rows.Next() {
+		snapshot := &dto.EquitySnapshot{}
+		err := rows.Scan(
+			&snapshot.UserID,
+			&snapshot.SnapshotDate,
+			&snapshot.Equity,
+			&snapshot.QuoteAsset,
+			&snapshot.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan equity snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return snapshots, nil
+}
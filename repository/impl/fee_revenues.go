@@ -0,0 +1,64 @@
+package repositoryImpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+type feeRevenueRepository struct {
+}
+
+func NewFeeRevenueRepository() repository.IFeeRevenueRepository {
+	return &feeRevenueRepository{}
+}
+
+func (f feeRevenueRepository) Insert(ctx context.Context, db repository.DBExecutor, revenue *dto.FeeRevenue) error {
+	query := `INSERT INTO fee_revenues (market, asset, fee_income, rebate, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		revenue.Market,
+		revenue.Asset,
+		revenue.FeeIncome,
+		revenue.Rebate,
+		revenue.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert fee revenue: %w", err)
+	}
+
+	return nil
+}
+
+func (f feeRevenueRepository) SumByAssetBetween(ctx context.Context, db repository.DBExecutor, from, to time.Time) ([]*dto.FeeRevenueSummary, error) {
+	query := `
+        SELECT asset, COALESCE(SUM(fee_income), 0), COALESCE(SUM(rebate), 0)
+        FROM fee_revenues
+        WHERE created_at >= ? AND created_at < ?
+        GROUP BY asset
+        ORDER BY asset`
+
+	rows, err := db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fee revenues: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []*dto.FeeRevenueSummary
+	for rows.Next() {
+		summary := &dto.FeeRevenueSummary{}
+		if err := rows.Scan(&summary.Asset, &summary.FeeIncome, &summary.Rebate); err != nil {
+			return nil, fmt.Errorf("failed to scan fee revenue: %w", err)
+		}
+		summary.NetRevenue = summary.FeeIncome - summary.Rebate
+		summaries = append(summaries, summary)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,98 @@
+package repositoryImpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+)
+
+type feeScheduleRepository struct {
+}
+
+func NewFeeScheduleRepository() repository.IFeeScheduleRepository {
+	return &feeScheduleRepository{}
+}
+
+// GetFeeSchedules get all fee schedules order by min_volume asc.
+func (f feeScheduleRepository) GetFeeSchedules(ctx context.Context, db repository.DBExecutor) ([]*dto.FeeSchedule, error) {
+	query := `SELECT vip_level, min_volume, maker_fee, taker_fee FROM fee_schedules ORDER BY min_volume ASC`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fee schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*dto.FeeSchedule
+	for rows.Next() {
+		schedule := &dto.FeeSchedule{}
+		if err := rows.Scan(&schedule.VipLevel, &schedule.MinVolume, &schedule.MakerFee, &schedule.TakerFee); err != nil {
+			return nil, fmt.Errorf("failed to scan fee schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return schedules, nil
+}
+
+func (f feeScheduleRepository) InsertVipLevelHistory(ctx context.Context, db repository.DBExecutor, history *dto.VipLevelHistory) error {
+	query := `INSERT INTO vip_level_histories (user_id, from_vip_level, to_vip_level, maker_fee, taker_fee, volume_30d, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		history.UserID,
+		history.FromVipLevel,
+		history.ToVipLevel,
+		history.MakerFee,
+		history.TakerFee,
+		history.Volume30D,
+		history.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert vip level history: %w", err)
+	}
+
+	return nil
+}
+
+func (f feeScheduleRepository) GetVipLevelHistoriesByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.VipLevelHistory, error) {
+	query := `SELECT id, user_id, from_vip_level, to_vip_level, maker_fee, taker_fee, volume_30d, created_at
+		FROM vip_level_histories WHERE user_id = ?
+		ORDER BY created_at DESC`
+
+	rows, err := db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vip level histories: %w", err)
+	}
+	defer rows.Close()
+
+	var histories []*dto.VipLevelHistory
+	for rows.Next() {
+		history := &dto.VipLevelHistory{}
+		err := rows.Scan(
+			&history.ID,
+			&history.UserID,
+			&history.FromVipLevel,
+			&history.ToVipLevel,
+			&history.MakerFee,
+			&history.TakerFee,
+			&history.Volume30D,
+			&history.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan vip level history: %w", err)
+		}
+		histories = append(histories, history)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return histories, nil
+}
@@ -0,0 +1,116 @@
+package repositoryImpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"strings"
+	"time"
+)
+
+const (
+	ledgerBaseAsset  = "substr(market, 1, instr(market, '-') - 1)"
+	ledgerQuoteAsset = "substr(market, instr(market, '-') + 1)"
+	// ledgerSpotMarket perpetual fills never move base asset, their pnl is settled by realized_pnls.
+	ledgerSpotMarket      = "market NOT LIKE '%-PERP'"
+	ledgerPerpetualMarket = "market LIKE '%-PERP'"
+)
+
+// ledgerSources each source selects balance movements of one user in [from, to), placeholders are user_id, from, to.
+var ledgerSources = []string{
+	// bid side fills: + base, - quote, - fee
+	ledgerSource(dto.LEDGER_ENTRY_TRADE, ledgerBaseAsset, "size", "CAST(id AS TEXT)", "market", "timestamp",
+		"trades", "bid_user_id = ? AND "+ledgerSpotMarket),
+	ledgerSource(dto.LEDGER_ENTRY_TRADE, ledgerQuoteAsset, "-price * size", "CAST(id AS TEXT)", "market", "timestamp",
+		"trades", "bid_user_id = ? AND "+ledgerSpotMarket),
+	ledgerSource(dto.LEDGER_ENTRY_FEE, "bid_fee_asset", "-bid_fee", "CAST(id AS TEXT)", "market", "timestamp",
+		"trades", "bid_user_id = ? AND bid_fee != 0"),
+	// ask side fills: - base, + quote, - fee
+	ledgerSource(dto.LEDGER_ENTRY_TRADE, ledgerBaseAsset, "-size", "CAST(id AS TEXT)", "market", "timestamp",
+		"trades", "ask_user_id = ? AND "+ledgerSpotMarket),
+	ledgerSource(dto.LEDGER_ENTRY_TRADE, ledgerQuoteAsset, "price * size", "CAST(id AS TEXT)", "market", "timestamp",
+		"trades", "ask_user_id = ? AND "+ledgerSpotMarket),
+	ledgerSource(dto.LEDGER_ENTRY_FEE, "ask_fee_asset", "-ask_fee", "CAST(id AS TEXT)", "market", "timestamp",
+		"trades", "ask_user_id = ? AND ask_fee != 0"),
+	// withdrawals, rejected ones are refunded at rejecting time (updated_at)
+	ledgerSource(dto.LEDGER_ENTRY_WITHDRAWAL, "asset", "-amount", "id", "''", "created_at",
+		"withdrawals", "user_id = ?"),
+	ledgerSource(dto.LEDGER_ENTRY_WITHDRAWAL_REFUND, "asset", "amount", "id", "''", "updated_at",
+		"withdrawals", fmt.Sprintf("user_id = ? AND status = '%s'", dto.WITHDRAWAL_STATUS_REJECTED)),
+	// transfers
+	ledgerSource(dto.LEDGER_ENTRY_TRANSFER_OUT, "asset", "-amount", "id", "''", "created_at",
+		"transfers", "from_user_id = ?"),
+	ledgerSource(dto.LEDGER_ENTRY_TRANSFER_IN, "asset", "amount", "id", "''", "created_at",
+		"transfers", "to_user_id = ?"),
+	// referral commissions
+	ledgerSource(dto.LEDGER_ENTRY_REFERRAL_COMMISSION, "asset", "commission", "CAST(id AS TEXT)", "market", "created_at",
+		"referral_commissions", "referrer_id = ?"),
+	// perpetual realized pnl and funding payments in quote asset
+	ledgerSource(dto.LEDGER_ENTRY_REALIZED_PNL, "asset", "pnl", "CAST(id AS TEXT)", "market", "created_at",
+		"realized_pnls", "user_id = ? AND "+ledgerPerpetualMarket),
+	ledgerSource(dto.LEDGER_ENTRY_FUNDING, "asset", "amount", "CAST(id AS TEXT)", "market", "created_at",
+		"funding_payments", "user_id = ?"),
+}
+
+func ledgerSource(entryType dto.LedgerEntryType, asset, amount, refId, market, ts, table, userCondition string) string {
+	return fmt.Sprintf(`SELECT '%s' AS type, %s AS asset, %s AS amount, %s AS ref_id, %s AS market, %s AS ts
+            FROM %s WHERE %s AND %s >= ? AND %s < ?`,
+		entryType, asset, amount, refId, market, ts, table, userCondition, ts, ts)
+}
+
+type ledgerRepository struct {
+}
+
+func NewLedgerRepository() repository.ILedgerRepository {
+	return &ledgerRepository{}
+}
+
+// GetEntriesByUserId query user balance movements derived from trades, withdrawals, transfers, referral commissions
+// and perpetual settlements,
+// order by time desc.
+func (l ledgerRepository) GetEntriesByUserId(ctx context.Context, db repository.DBExecutor, userId string, from, to time.Time, limit, offset int) ([]*dto.LedgerEntry, error) {
+	args := make([]interface{}, 0, len(ledgerSources)*3+2)
+	for range ledgerSources {
+		args = append(args, userId, from, to)
+	}
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+        SELECT type, asset, amount, ref_id, market, ts FROM (
+            %s
+        )
+        ORDER BY ts DESC, ref_id DESC, type ASC, asset ASC, amount ASC
+        LIMIT ? OFFSET ?`, strings.Join(ledgerSources, "\n            UNION ALL\n            "))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*dto.LedgerEntry
+	for rows.Next() {
+		entry := &dto.LedgerEntry{}
+		err := rows.Scan(
+			&entry.Type,
+			&entry.Asset,
+			&entry.Amount,
+			&entry.RefID,
+			&entry.Market,
+			&entry.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entry.Amount = utils.RoundFloat(entry.Amount)
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return entries, nil
+}
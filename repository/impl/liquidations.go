@@ -0,0 +1,138 @@
+package repositoryImpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+type liquidationRepository struct {
+}
+
+func NewLiquidationRepository() repository.ILiquidationRepository {
+	return &liquidationRepository{}
+}
+
+func (l liquidationRepository) InsertMarginCall(ctx context.Context, db repository.DBExecutor, call *dto.MarginCall) error {
+	query := `INSERT INTO margin_calls (user_id, margin_ratio, asset_value, debt_value, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	call.CreatedAt = time.Now()
+	result, err := db.ExecContext(ctx, query, call.UserID, call.MarginRatio, call.AssetValue, call.DebtValue, call.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert margin call: %w", err)
+	}
+
+	call.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	return nil
+}
+
+func (l liquidationRepository) GetMarginCallsByUserId(ctx context.Context, db repository.DBExecutor, userId string, limit int) ([]*dto.MarginCall, error) {
+	query := `SELECT id, user_id, margin_ratio, asset_value, debt_value, created_at FROM margin_calls
+		WHERE user_id = ? ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query margin calls: %w", err)
+	}
+	defer rows.Close()
+
+	var calls []*dto.MarginCall
+	for rows.Next() {
+		call := &dto.MarginCall{}
+		err := rows.Scan(&call.ID, &call.UserID, &call.MarginRatio, &call.AssetValue, &call.DebtValue, &call.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan margin call: %w", err)
+		}
+		calls = append(calls, call)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return calls, nil
+}
+
+func (l liquidationRepository) InsertLiquidation(ctx context.Context, db repository.DBExecutor, liquidation *dto.Liquidation) error {
+	query := `INSERT INTO liquidations
+		(user_id, margin_user_id, margin_ratio, asset_value, debt_value, status, details, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	details, err := json.Marshal(liquidation.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal liquidation details: %w", err)
+	}
+
+	liquidation.CreatedAt = time.Now()
+	result, err := db.ExecContext(ctx, query,
+		liquidation.UserID,
+		liquidation.MarginUserID,
+		liquidation.MarginRatio,
+		liquidation.AssetValue,
+		liquidation.DebtValue,
+		liquidation.Status,
+		string(details),
+		liquidation.Error,
+		liquidation.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert liquidation: %w", err)
+	}
+
+	liquidation.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	return nil
+}
+
+// GetLiquidations latest liquidations, all users if userId is empty.
+func (l liquidationRepository) GetLiquidations(ctx context.Context, db repository.DBExecutor, userId string, limit int) ([]*dto.Liquidation, error) {
+	query := `SELECT id, user_id, margin_user_id, margin_ratio, asset_value, debt_value, status, details, error, created_at
+		FROM liquidations WHERE (? = '' OR user_id = ?) ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, userId, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query liquidations: %w", err)
+	}
+	defer rows.Close()
+
+	var liquidations []*dto.Liquidation
+	for rows.Next() {
+		liquidation := &dto.Liquidation{}
+		var details string
+		err := rows.Scan(
+			&liquidation.ID,
+			&liquidation.UserID,
+			&liquidation.MarginUserID,
+			&liquidation.MarginRatio,
+			&liquidation.AssetValue,
+			&liquidation.DebtValue,
+			&liquidation.Status,
+			&details,
+			&liquidation.Error,
+			&liquidation.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan liquidation: %w", err)
+		}
+		if err := json.Unmarshal([]byte(details), &liquidation.Details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal liquidation details: %w", err)
+		}
+		liquidations = append(liquidations, liquidation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return liquidations, nil
+}
@@ -0,0 +1,227 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+type marginRepository struct {
+}
+
+func NewMarginRepository() repository.IMarginRepository {
+	return &marginRepository{}
+}
+
+func (m marginRepository) InsertAccount(ctx context.Context, db repository.DBExecutor, account *dto.MarginAccount) error {
+	query := `INSERT INTO margin_accounts (user_id, margin_user_id, created_at) VALUES (?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query, account.UserID, account.MarginUserID, account.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert margin account: %w", err)
+	}
+
+	return nil
+}
+
+func (m marginRepository) GetAccountByUserId(ctx context.Context, db repository.DBExecutor, userId string) (*dto.MarginAccount, error) {
+	query := `SELECT user_id, margin_user_id, created_at FROM margin_accounts WHERE user_id = ?`
+	return m.getAccount(ctx, db, query, userId)
+}
+
+func (m marginRepository) GetAccountByMarginUserId(ctx context.Context, db repository.DBExecutor, marginUserId string) (*dto.MarginAccount, error) {
+	query := `SELECT user_id, margin_user_id, created_at FROM margin_accounts WHERE margin_user_id = ?`
+	return m.getAccount(ctx, db, query, marginUserId)
+}
+
+// GetAccountsWithLoans margin accounts having outstanding loans.
+func (m marginRepository) GetAccountsWithLoans(ctx context.Context, db repository.DBExecutor) ([]*dto.MarginAccount, error) {
+	query := `SELECT a.user_id, a.margin_user_id, a.created_at FROM margin_accounts a
+		WHERE EXISTS (SELECT 1 FROM margin_loans l WHERE l.user_id = a.user_id AND (l.principal > 0 OR l.interest > 0))`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query margin accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*dto.MarginAccount
+	for rows.Next() {
+		account := &dto.MarginAccount{}
+		if err := rows.Scan(&account.UserID, &account.MarginUserID, &account.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan margin account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return accounts, nil
+}
+
+func (m marginRepository) getAccount(ctx context.Context, db repository.DBExecutor, query string, id string) (*dto.MarginAccount, error) {
+	account := &dto.MarginAccount{}
+	err := db.QueryRowContext(ctx, query, id).Scan(&account.UserID, &account.MarginUserID, &account.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("margin account of %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get margin account: %w", err)
+	}
+	return account, nil
+}
+
+func (m marginRepository) GetLoansByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.MarginLoan, error) {
+	query := `SELECT user_id, asset, principal, interest, accrued_at, updated_at FROM margin_loans
+		WHERE user_id = ? AND (principal > 0 OR interest > 0) ORDER BY asset`
+	return m.getLoans(ctx, db, query, userId)
+}
+
+// GetAccruingLoans loans of all users having outstanding principal.
+func (m marginRepository) GetAccruingLoans(ctx context.Context, db repository.DBExecutor) ([]*dto.MarginLoan, error) {
+	query := `SELECT user_id, asset, principal, interest, accrued_at, updated_at FROM margin_loans
+		WHERE principal > 0 ORDER BY user_id, asset`
+	return m.getLoans(ctx, db, query)
+}
+
+func (m marginRepository) getLoans(ctx context.Context, db repository.DBExecutor, query string, args ...any) ([]*dto.MarginLoan, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query margin loans: %w", err)
+	}
+	defer rows.Close()
+
+	var loans []*dto.MarginLoan
+	for rows.Next() {
+		loan := &dto.MarginLoan{}
+		if err := rows.Scan(&loan.UserID, &loan.Asset, &loan.Principal, &loan.Interest, &loan.AccruedAt, &loan.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan margin loan: %w", err)
+		}
+		loans = append(loans, loan)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return loans, nil
+}
+
+// IncreaseLoan add borrowed principal of user asset loan, interest accrues from now if no principal was outstanding.
+func (m marginRepository) IncreaseLoan(ctx context.Context, db repository.DBExecutor, userId, asset string, principal float64) error {
+	query := `INSERT INTO margin_loans (user_id, asset, principal, interest, accrued_at, updated_at) VALUES (?, ?, ?, 0, ?, ?)
+		ON CONFLICT (user_id, asset) DO UPDATE SET
+		accrued_at = CASE WHEN principal > 0 THEN accrued_at ELSE excluded.accrued_at END,
+		principal = principal + excluded.principal, updated_at = excluded.updated_at`
+
+	now := time.Now()
+	_, err := db.ExecContext(ctx, query, userId, asset, principal, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to increase margin loan: %w", err)
+	}
+
+	return nil
+}
+
+// DecreaseLoan repay interest and principal of user asset loan, return error if exceed outstanding amount.
+func (m marginRepository) DecreaseLoan(ctx context.Context, db repository.DBExecutor, userId, asset string, principal, interest float64) error {
+	query := `UPDATE margin_loans SET principal = ROUND(principal - ?, 8), interest = ROUND(interest - ?, 8), updated_at = ?
+		WHERE user_id = ? AND asset = ? AND principal >= ? AND interest >= ?`
+
+	result, err := db.ExecContext(ctx, query, principal, interest, time.Now(), userId, asset, principal, interest)
+	if err != nil {
+		return fmt.Errorf("failed to decrease margin loan: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("margin loan of user %s asset %s not enough to repay", userId, asset)
+	}
+
+	return nil
+}
+
+// AccrueInterest add interest to user asset loan accrued up to accruedAt.
+func (m marginRepository) AccrueInterest(ctx context.Context, db repository.DBExecutor, userId, asset string, interest float64, accruedAt time.Time) error {
+	query := `UPDATE margin_loans SET interest = ROUND(interest + ?, 8), accrued_at = ?, updated_at = ?
+		WHERE user_id = ? AND asset = ?`
+
+	result, err := db.ExecContext(ctx, query, interest, accruedAt, time.Now(), userId, asset)
+	if err != nil {
+		return fmt.Errorf("failed to accrue margin interest: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("margin loan not found, user: %s, asset: %s", userId, asset)
+	}
+
+	return nil
+}
+
+func (m marginRepository) InsertLoanRecord(ctx context.Context, db repository.DBExecutor, record *dto.MarginLoanRecord) error {
+	query := `INSERT INTO margin_loan_records (user_id, asset, type, principal, interest, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := db.ExecContext(ctx, query, record.UserID, record.Asset, record.Type, record.Principal, record.Interest, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert margin loan record: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no rows inserted")
+	}
+
+	return nil
+}
+
+func (m marginRepository) GetLoanRecordsByUserId(ctx context.Context, db repository.DBExecutor, userId string, limit int) ([]*dto.MarginLoanRecord, error) {
+	query := `SELECT id, user_id, asset, type, principal, interest, created_at FROM margin_loan_records
+		WHERE user_id = ? ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query margin loan records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*dto.MarginLoanRecord
+	for rows.Next() {
+		record := &dto.MarginLoanRecord{}
+		err := rows.Scan(
+			&record.ID,
+			&record.UserID,
+			&record.Asset,
+			&record.Type,
+			&record.Principal,
+			&record.Interest,
+			&record.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan margin loan record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return records, nil
+}
@@ -277,6 +277,87 @@ func (o orderRepository) SyncTradeMatchingResult(ctx context.Context, db reposit
 	return nil
 }
 
+func (o orderRepository) GetOrdersByIds(ctx context.Context, db repository.DBExecutor, orderIds []string) ([]*dto.Order, error) {
+	if len(orderIds) == 0 {
+		return []*dto.Order{}, nil
+	}
+
+	// create IN prepare statement
+	placeholders := make([]string, len(orderIds))
+	args := make([]interface{}, len(orderIds))
+	for i, orderId := range orderIds {
+		placeholders[i] = "?"
+		args[i] = orderId
+	}
+
+	query := fmt.Sprintf(`SELECT id, user_id, market, side, price, original_size, remaining_size, 
+		quote_amount, avg_dealt_price, type, mode, status, created_at, updated_at, fee_asset, fee_rate, fees
+		FROM orders WHERE id IN (%s)`, strings.Join(placeholders, ","))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []*dto.Order
+	for rows.Next() {
+		order := &dto.Order{}
+
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.Market,
+			&order.Side,
+			&order.Price,
+			&order.OriginalSize,
+			&order.RemainingSize,
+			&order.QuoteAmount,
+			&order.AvgDealtPrice,
+			&order.Type,
+			&order.Mode,
+			&order.Status,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.FeeAsset,
+			&order.FeeRate,
+			&order.Fees,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+
+		orders = append(orders, order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return orders, nil
+}
+
+// UpdateFeeAsset overwrite order fee asset and accumulated fees.
+func (o orderRepository) UpdateFeeAsset(ctx context.Context, db repository.DBExecutor, orderId string, feeAsset string, fees float64) error {
+	query := `UPDATE orders SET fee_asset = ?, fees = ?, updated_at = ? WHERE id = ?`
+
+	result, err := db.ExecContext(ctx, query, feeAsset, fees, time.Now(), orderId)
+	if err != nil {
+		return fmt.Errorf("failed to update order fee asset: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("order with id %s not found", orderId)
+	}
+	return nil
+}
+
 func (o orderRepository) CancelOrder(ctx context.Context, db repository.DBExecutor, orderId string, remainingSize float64) error {
 	query := `UPDATE orders SET 
 		remaining_size = ?, status = ?, updated_at = ?
@@ -500,6 +581,16 @@ func (o orderRepository) PaginationQuery(ctx context.Context, db repository.DBEx
 		args = append(args, endTime)
 	}
 
+	// created_at range filter (optional)
+	if !query.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, query.CreatedTo)
+	}
+
 	whereClause := strings.Join(conditions, " AND ")
 
 	// Count total records
@@ -520,7 +611,7 @@ func (o orderRepository) PaginationQuery(ctx context.Context, db repository.DBEx
                fees, fee_asset, created_at, updated_at 
         FROM orders 
         WHERE %s 
-        ORDER BY created_at DESC 
+        ORDER BY created_at DESC, id DESC 
         LIMIT ? OFFSET ?`, whereClause)
 
 	args = append(args, query.PageSize, offset)
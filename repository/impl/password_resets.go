@@ -0,0 +1,89 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+)
+
+const passwordResetColumns = `token_hash, user_id, expires_at, created_at`
+
+type passwordResetRepository struct {
+}
+
+func NewPasswordResetRepository() repository.IPasswordResetRepository {
+	return &passwordResetRepository{}
+}
+
+func (r passwordResetRepository) Insert(ctx context.Context, db repository.DBExecutor, resetToken *dto.PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (` + passwordResetColumns + `) VALUES (?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		resetToken.TokenHash,
+		resetToken.UserID,
+		resetToken.ExpiresAt,
+		resetToken.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert password reset token: %w", err)
+	}
+	return nil
+}
+
+func (r passwordResetRepository) GetByTokenHash(ctx context.Context, db repository.DBExecutor, tokenHash string) (*dto.PasswordResetToken, error) {
+	query := `SELECT ` + passwordResetColumns + ` FROM password_reset_tokens WHERE token_hash = ?`
+
+	resetToken, err := scanPasswordResetToken(db.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("password reset token not found")
+		}
+		return nil, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+	return resetToken, nil
+}
+
+func (r passwordResetRepository) GetByUserId(ctx context.Context, db repository.DBExecutor, userId string) (*dto.PasswordResetToken, error) {
+	query := `SELECT ` + passwordResetColumns + ` FROM password_reset_tokens WHERE user_id = ?`
+
+	resetToken, err := scanPasswordResetToken(db.QueryRowContext(ctx, query, userId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("password reset token of user %s not found", userId)
+		}
+		return nil, fmt.Errorf("failed to get password reset token by user id: %w", err)
+	}
+	return resetToken, nil
+}
+
+func (r passwordResetRepository) DeleteByTokenHash(ctx context.Context, db repository.DBExecutor, tokenHash string) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM password_reset_tokens WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete password reset token: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r passwordResetRepository) DeleteByUserId(ctx context.Context, db repository.DBExecutor, userId string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM password_reset_tokens WHERE user_id = ?`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+	return nil
+}
+
+func scanPasswordResetToken(row *sql.Row) (*dto.PasswordResetToken, error) {
+	resetToken := &dto.PasswordResetToken{}
+	err := row.Scan(
+		&resetToken.TokenHash,
+		&resetToken.UserID,
+		&resetToken.ExpiresAt,
+		&resetToken.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resetToken, nil
+}
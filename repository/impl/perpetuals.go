@@ -0,0 +1,321 @@
+package repositoryImpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"strings"
+	"time"
+)
+
+const perpetualPositionColumns = `user_id, market, size, entry_price, margin, realized_pnl, updated_at`
+
+type perpetualRepository struct {
+}
+
+func NewPerpetualRepository() repository.IPerpetualRepository {
+	return &perpetualRepository{}
+}
+
+// GetPositionsByUserIds return user id -> position of market, users without position record are absent.
+func (p perpetualRepository) GetPositionsByUserIds(ctx context.Context, db repository.DBExecutor, userIds []string, market string) (map[string]*dto.PerpetualPosition, error) {
+	positions := make(map[string]*dto.PerpetualPosition)
+	if len(userIds) == 0 {
+		return positions, nil
+	}
+
+	// create IN prepare statement
+	placeholders := make([]string, len(userIds))
+	args := make([]interface{}, 0, len(userIds)+1)
+	args = append(args, market)
+	for i, userId := range userIds {
+		placeholders[i] = "?"
+		args = append(args, userId)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM perpetual_positions WHERE market = ? AND user_id IN (%s)`,
+		perpetualPositionColumns, strings.Join(placeholders, ","))
+
+	list, err := p.queryPositions(ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, position := range list {
+		positions[position.UserID] = position
+	}
+	return positions, nil
+}
+
+// GetPositionsByUserId positions with size or margin.
+func (p perpetualRepository) GetPositionsByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.PerpetualPosition, error) {
+	query := fmt.Sprintf(`SELECT %s FROM perpetual_positions WHERE user_id = ? AND (size != 0 OR margin != 0) ORDER BY market`,
+		perpetualPositionColumns)
+	return p.queryPositions(ctx, db, query, userId)
+}
+
+// GetOpenPositionsByMarket positions with size of market.
+func (p perpetualRepository) GetOpenPositionsByMarket(ctx context.Context, db repository.DBExecutor, market string) ([]*dto.PerpetualPosition, error) {
+	query := fmt.Sprintf(`SELECT %s FROM perpetual_positions WHERE market = ? AND size != 0`, perpetualPositionColumns)
+	return p.queryPositions(ctx, db, query, market)
+}
+
+func (p perpetualRepository) queryPositions(ctx context.Context, db repository.DBExecutor, query string, args ...interface{}) ([]*dto.PerpetualPosition, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query perpetual positions: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []*dto.PerpetualPosition
+	for rows.Next() {
+		position := &dto.PerpetualPosition{}
+		err := rows.Scan(
+			&position.UserID,
+			&position.Market,
+			&position.Size,
+			&position.EntryPrice,
+			&position.Margin,
+			&position.RealizedPnl,
+			&position.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan perpetual position: %w", err)
+		}
+		positions = append(positions, position)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return positions, nil
+}
+
+func (p perpetualRepository) UpsertPosition(ctx context.Context, db repository.DBExecutor, position *dto.PerpetualPosition) error {
+	query := `INSERT INTO perpetual_positions (user_id, market, size, entry_price, margin, realized_pnl, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, market) DO UPDATE SET
+		size = excluded.size, entry_price = excluded.entry_price, margin = excluded.margin,
+		realized_pnl = excluded.realized_pnl, updated_at = excluded.updated_at`
+
+	position.UpdatedAt = time.Now()
+	_, err := db.ExecContext(ctx, query,
+		position.UserID,
+		position.Market,
+		position.Size,
+		position.EntryPrice,
+		position.Margin,
+		position.RealizedPnl,
+		position.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert perpetual position: %w", err)
+	}
+
+	return nil
+}
+
+func (p perpetualRepository) InsertFundingRate(ctx context.Context, db repository.DBExecutor, rate *dto.FundingRate) error {
+	query := `INSERT INTO funding_rates (market, funding_rate, mark_price, index_price, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	rate.CreatedAt = time.Now()
+	_, err := db.ExecContext(ctx, query, rate.Market, rate.FundingRate, rate.MarkPrice, rate.IndexPrice, rate.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert funding rate: %w", err)
+	}
+
+	return nil
+}
+
+func (p perpetualRepository) GetFundingRates(ctx context.Context, db repository.DBExecutor, market string, limit int) ([]*dto.FundingRate, error) {
+	query := `SELECT id, market, funding_rate, mark_price, index_price, created_at FROM funding_rates
+		WHERE market = ? ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, market, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []*dto.FundingRate
+	for rows.Next() {
+		rate := &dto.FundingRate{}
+		if err := rows.Scan(&rate.ID, &rate.Market, &rate.FundingRate, &rate.MarkPrice, &rate.IndexPrice, &rate.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan funding rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return rates, nil
+}
+
+func (p perpetualRepository) InsertFundingPayment(ctx context.Context, db repository.DBExecutor, payment *dto.FundingPayment) error {
+	query := `INSERT INTO funding_payments (user_id, market, asset, position_size, mark_price, funding_rate, amount, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	payment.CreatedAt = time.Now()
+	_, err := db.ExecContext(ctx, query,
+		payment.UserID,
+		payment.Market,
+		payment.Asset,
+		payment.PositionSize,
+		payment.MarkPrice,
+		payment.FundingRate,
+		payment.Amount,
+		payment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert funding payment: %w", err)
+	}
+
+	return nil
+}
+
+// GetFundingPaymentsByUserId latest funding payments, all markets if market is empty.
+func (p perpetualRepository) GetFundingPaymentsByUserId(ctx context.Context, db repository.DBExecutor, userId, market string, limit int) ([]*dto.FundingPayment, error) {
+	query := `SELECT id, user_id, market, asset, position_size, mark_price, funding_rate, amount, created_at
+		FROM funding_payments WHERE user_id = ? AND (? = '' OR market = ?) ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, userId, market, market, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []*dto.FundingPayment
+	for rows.Next() {
+		payment := &dto.FundingPayment{}
+		err := rows.Scan(
+			&payment.ID,
+			&payment.UserID,
+			&payment.Market,
+			&payment.Asset,
+			&payment.PositionSize,
+			&payment.MarkPrice,
+			&payment.FundingRate,
+			&payment.Amount,
+			&payment.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan funding payment: %w", err)
+		}
+		payments = append(payments, payment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return payments, nil
+}
+
+func (p perpetualRepository) InsertLiquidation(ctx context.Context, db repository.DBExecutor, liquidation *dto.PerpetualLiquidation) error {
+	query := `INSERT INTO perpetual_liquidations (user_id, market, size, entry_price, mark_price, margin, pnl, bad_debt, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	liquidation.CreatedAt = time.Now()
+	result, err := db.ExecContext(ctx, query,
+		liquidation.UserID,
+		liquidation.Market,
+		liquidation.Size,
+		liquidation.EntryPrice,
+		liquidation.MarkPrice,
+		liquidation.Margin,
+		liquidation.Pnl,
+		liquidation.BadDebt,
+		liquidation.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert perpetual liquidation: %w", err)
+	}
+
+	liquidation.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get perpetual liquidation id: %w", err)
+	}
+	return nil
+}
+
+// GetLiquidationsByUserId latest liquidations, all markets if market is empty.
+func (p perpetualRepository) GetLiquidationsByUserId(ctx context.Context, db repository.DBExecutor, userId, market string, limit int) ([]*dto.PerpetualLiquidation, error) {
+	query := `SELECT id, user_id, market, size, entry_price, mark_price, margin, pnl, bad_debt, created_at
+		FROM perpetual_liquidations WHERE user_id = ? AND (? = '' OR market = ?) ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, userId, market, market, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query perpetual liquidations: %w", err)
+	}
+	defer rows.Close()
+
+	var liquidations []*dto.PerpetualLiquidation
+	for rows.Next() {
+		liquidation := &dto.PerpetualLiquidation{}
+		err := rows.Scan(
+			&liquidation.ID,
+			&liquidation.UserID,
+			&liquidation.Market,
+			&liquidation.Size,
+			&liquidation.EntryPrice,
+			&liquidation.MarkPrice,
+			&liquidation.Margin,
+			&liquidation.Pnl,
+			&liquidation.BadDebt,
+			&liquidation.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan perpetual liquidation: %w", err)
+		}
+		liquidations = append(liquidations, liquidation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return liquidations, nil
+}
+
+// InsertMarketHalt keep the first halt if market is already halted.
+func (p perpetualRepository) InsertMarketHalt(ctx context.Context, db repository.DBExecutor, halt *dto.PerpetualMarketHalt) error {
+	query := `INSERT INTO perpetual_market_halts (market, reason, created_at) VALUES (?, ?, ?) ON CONFLICT (market) DO NOTHING`
+
+	halt.CreatedAt = time.Now()
+	if _, err := db.ExecContext(ctx, query, halt.Market, halt.Reason, halt.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert perpetual market halt: %w", err)
+	}
+
+	return nil
+}
+
+func (p perpetualRepository) IsMarketHalted(ctx context.Context, db repository.DBExecutor, market string) (bool, error) {
+	query := `SELECT COUNT(*) FROM perpetual_market_halts WHERE market = ?`
+
+	var count int
+	if err := db.QueryRowContext(ctx, query, market).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to query perpetual market halt: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DeleteMarketHalt return error if market is not halted.
+func (p perpetualRepository) DeleteMarketHalt(ctx context.Context, db repository.DBExecutor, market string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM perpetual_market_halts WHERE market = ?`, market)
+	if err != nil {
+		return fmt.Errorf("failed to delete perpetual market halt: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("market %s is not halted", market)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package repositoryImpl
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"strings"
+	"time"
+)
+
+type pnlRepository struct {
+}
+
+func NewPnlRepository() repository.IPnlRepository {
+	return &pnlRepository{}
+}
+
+// GetCostBasesByUserIds return user id -> cost basis of asset, users without cost basis are absent.
+func (p pnlRepository) GetCostBasesByUserIds(ctx context.Context, db repository.DBExecutor, userIds []string, asset string) (map[string]*dto.CostBasis, error) {
+	bases := make(map[string]*dto.CostBasis)
+	if len(userIds) == 0 {
+		return bases, nil
+	}
+
+	// create IN prepare statement
+	placeholders := make([]string, len(userIds))
+	args := make([]interface{}, 0, len(userIds)+1)
+	args = append(args, asset)
+	for i, userId := range userIds {
+		placeholders[i] = "?"
+		args = append(args, userId)
+	}
+
+	query := fmt.Sprintf(`SELECT user_id, asset, quantity, avg_cost, updated_at FROM cost_bases
+		WHERE asset = ? AND user_id IN (%s)`, strings.Join(placeholders, ","))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost bases: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		basis := &dto.CostBasis{}
+		if err := rows.Scan(&basis.UserID, &basis.Asset, &basis.Quantity, &basis.AvgCost, &basis.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cost basis: %w", err)
+		}
+		bases[basis.UserID] = basis
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return bases, nil
+}
+
+func (p pnlRepository) GetCostBasesByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.CostBasis, error) {
+	query := `SELECT user_id, asset, quantity, avg_cost, updated_at FROM cost_bases WHERE user_id = ?`
+
+	rows, err := db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost bases: %w", err)
+	}
+	defer rows.Close()
+
+	var bases []*dto.CostBasis
+	for rows.Next() {
+		basis := &dto.CostBasis{}
+		if err := rows.Scan(&basis.UserID, &basis.Asset, &basis.Quantity, &basis.AvgCost, &basis.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cost basis: %w", err)
+		}
+		bases = append(bases, basis)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return bases, nil
+}
+
+func (p pnlRepository) UpsertCostBasis(ctx context.Context, db repository.DBExecutor, basis *dto.CostBasis) error {
+	query := `INSERT INTO cost_bases (user_id, asset, quantity, avg_cost, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, asset) DO UPDATE SET
+		quantity = excluded.quantity, avg_cost = excluded.avg_cost, updated_at = excluded.updated_at`
+
+	_, err := db.ExecContext(ctx, query, basis.UserID, basis.Asset, basis.Quantity, basis.AvgCost, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to upsert cost basis: %w", err)
+	}
+
+	return nil
+}
+
+func (p pnlRepository) InsertRealizedPnl(ctx context.Context, db repository.DBExecutor, realized *dto.RealizedPnl) error {
+	query := `INSERT INTO realized_pnls (user_id, market, asset, size, proceeds, cost, pnl, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := db.ExecContext(ctx, query,
+		realized.UserID,
+		realized.Market,
+		realized.Asset,
+		realized.Size,
+		realized.Proceeds,
+		realized.Cost,
+		realized.Pnl,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert realized pnl: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no rows inserted")
+	}
+
+	return nil
+}
+
+// SumRealizedPnlByMarket sum user realized pnl group by market in [from, to).
+func (p pnlRepository) SumRealizedPnlByMarket(ctx context.Context, db repository.DBExecutor, userId string, from, to time.Time) ([]*dto.MarketRealizedPnl, error) {
+	query := `
+        SELECT market, COALESCE(SUM(size), 0), COALESCE(SUM(proceeds), 0), COALESCE(SUM(cost), 0), COALESCE(SUM(pnl), 0)
+        FROM realized_pnls
+        WHERE user_id = ? AND created_at >= ? AND created_at < ?
+        GROUP BY market
+        ORDER BY market`
+
+	rows, err := db.QueryContext(ctx, query, userId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query realized pnl: %w", err)
+	}
+	defer rows.Close()
+
+	var pnls []*dto.MarketRealizedPnl
+	for rows.Next() {
+		pnl := &dto.MarketRealizedPnl{}
+		if err := rows.Scan(&pnl.Market, &pnl.Size, &pnl.Proceeds, &pnl.Cost, &pnl.Pnl); err != nil {
+			return nil, fmt.Errorf("failed to scan realized pnl: %w", err)
+		}
+		pnls = append(pnls, pnl)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return pnls, nil
+}
@@ -0,0 +1,213 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"strings"
+	"time"
+)
+
+type referralRepository struct {
+}
+
+func NewReferralRepository() repository.IReferralRepository {
+	return &referralRepository{}
+}
+
+func (r referralRepository) InsertCode(ctx context.Context, db repository.DBExecutor, userId, code string) error {
+	query := `INSERT INTO referral_codes (user_id, code, created_at) VALUES (?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query, userId, code, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert referral code: %w", err)
+	}
+
+	return nil
+}
+
+func (r referralRepository) GetCodeByUserId(ctx context.Context, db repository.DBExecutor, userId string) (string, error) {
+	query := `SELECT code FROM referral_codes WHERE user_id = ?`
+
+	var code string
+	err := db.QueryRowContext(ctx, query, userId).Scan(&code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("referral code of user %s not found", userId)
+		}
+		return "", fmt.Errorf("failed to get referral code: %w", err)
+	}
+
+	return code, nil
+}
+
+func (r referralRepository) GetUserIdByCode(ctx context.Context, db repository.DBExecutor, code string) (string, error) {
+	query := `SELECT user_id FROM referral_codes WHERE code = ?`
+
+	var userId string
+	err := db.QueryRowContext(ctx, query, code).Scan(&userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("referral code %s not found", code)
+		}
+		return "", fmt.Errorf("failed to get user by referral code: %w", err)
+	}
+
+	return userId, nil
+}
+
+func (r referralRepository) InsertReferral(ctx context.Context, db repository.DBExecutor, referrerId, refereeId string) error {
+	query := `INSERT INTO referrals (referee_id, referrer_id, created_at) VALUES (?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query, refereeId, referrerId, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert referral: %w", err)
+	}
+
+	return nil
+}
+
+func (r referralRepository) CountReferees(ctx context.Context, db repository.DBExecutor, referrerId string) (int64, error) {
+	query := `SELECT COUNT(*) FROM referrals WHERE referrer_id = ?`
+
+	var count int64
+	if err := db.QueryRowContext(ctx, query, referrerId).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count referees: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetReferrerIdsByRefereeIds return referee id -> referrer id, referees without referrer are absent.
+func (r referralRepository) GetReferrerIdsByRefereeIds(ctx context.Context, db repository.DBExecutor, refereeIds []string) (map[string]string, error) {
+	referrers := make(map[string]string)
+	if len(refereeIds) == 0 {
+		return referrers, nil
+	}
+
+	// create IN prepare statement
+	placeholders := make([]string, len(refereeIds))
+	args := make([]interface{}, len(refereeIds))
+	for i, refereeId := range refereeIds {
+		placeholders[i] = "?"
+		args[i] = refereeId
+	}
+
+	query := fmt.Sprintf(`SELECT referee_id, referrer_id FROM referrals WHERE referee_id IN (%s)`, strings.Join(placeholders, ","))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referrals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var refereeId, referrerId string
+		if err := rows.Scan(&refereeId, &referrerId); err != nil {
+			return nil, fmt.Errorf("failed to scan referral: %w", err)
+		}
+		referrers[refereeId] = referrerId
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return referrers, nil
+}
+
+func (r referralRepository) InsertCommission(ctx context.Context, db repository.DBExecutor, commission *dto.ReferralCommission) error {
+	query := `INSERT INTO referral_commissions (
+		referrer_id, referee_id, order_id, market, asset, fees, commission, created_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := db.ExecContext(ctx, query,
+		commission.ReferrerID,
+		commission.RefereeID,
+		commission.OrderID,
+		commission.Market,
+		commission.Asset,
+		commission.Fees,
+		commission.Commission,
+		commission.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert referral commission: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	commission.ID = id
+
+	return nil
+}
+
+func (r referralRepository) GetCommissionsByReferrerId(ctx context.Context, db repository.DBExecutor, referrerId string, limit int) ([]*dto.ReferralCommission, error) {
+	query := `SELECT id, referrer_id, referee_id, order_id, market, asset, fees, commission, created_at
+		FROM referral_commissions WHERE referrer_id = ?
+		ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, referrerId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referral commissions: %w", err)
+	}
+	defer rows.Close()
+
+	var commissions []*dto.ReferralCommission
+	for rows.Next() {
+		commission := &dto.ReferralCommission{}
+		err := rows.Scan(
+			&commission.ID,
+			&commission.ReferrerID,
+			&commission.RefereeID,
+			&commission.OrderID,
+			&commission.Market,
+			&commission.Asset,
+			&commission.Fees,
+			&commission.Commission,
+			&commission.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan referral commission: %w", err)
+		}
+		commissions = append(commissions, commission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return commissions, nil
+}
+
+func (r referralRepository) SumCommissionsByReferrerId(ctx context.Context, db repository.DBExecutor, referrerId string) ([]*dto.ReferralCommissionTotal, error) {
+	query := `SELECT asset, COALESCE(SUM(commission), 0)
+		FROM referral_commissions WHERE referrer_id = ?
+		GROUP BY asset
+		ORDER BY asset`
+
+	rows, err := db.QueryContext(ctx, query, referrerId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum referral commissions: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []*dto.ReferralCommissionTotal
+	for rows.Next() {
+		total := &dto.ReferralCommissionTotal{}
+		if err := rows.Scan(&total.Asset, &total.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan referral commission total: %w", err)
+		}
+		totals = append(totals, total)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return totals, nil
+}
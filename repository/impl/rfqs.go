@@ -0,0 +1,304 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+const (
+	rfqRequestColumns = `id, user_id, market, side, size, status, accepted_quote_id, expires_at, created_at, updated_at`
+	rfqQuoteColumns   = `id, request_id, provider_id, price, status, created_at, updated_at`
+)
+
+type rfqRepository struct {
+}
+
+func NewRfqRepository() repository.IRfqRepository {
+	return &rfqRepository{}
+}
+
+func (r rfqRepository) InsertProvider(ctx context.Context, db repository.DBExecutor, provider *dto.RfqProvider) error {
+	query := `INSERT INTO rfq_providers (user_id, created_at) VALUES (?, ?)`
+
+	provider.CreatedAt = time.Now()
+	_, err := db.ExecContext(ctx, query, provider.UserID, provider.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert rfq provider: %w", err)
+	}
+	return nil
+}
+
+func (r rfqRepository) DeleteProvider(ctx context.Context, db repository.DBExecutor, userId string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM rfq_providers WHERE user_id = ?`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete rfq provider: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("rfq provider %s not found", userId)
+	}
+	return nil
+}
+
+func (r rfqRepository) GetProviders(ctx context.Context, db repository.DBExecutor) ([]*dto.RfqProvider, error) {
+	rows, err := db.QueryContext(ctx, `SELECT user_id, created_at FROM rfq_providers ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rfq providers: %w", err)
+	}
+	defer rows.Close()
+
+	var providers []*dto.RfqProvider
+	for rows.Next() {
+		provider := &dto.RfqProvider{}
+		if err := rows.Scan(&provider.UserID, &provider.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rfq provider: %w", err)
+		}
+		providers = append(providers, provider)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return providers, nil
+}
+
+func (r rfqRepository) IsProvider(ctx context.Context, db repository.DBExecutor, userId string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM rfq_providers WHERE user_id = ?`, userId).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to query rfq provider: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r rfqRepository) InsertRequest(ctx context.Context, db repository.DBExecutor, request *dto.RfqRequest) error {
+	query := `INSERT INTO rfq_requests (` + rfqRequestColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now()
+	request.CreatedAt = now
+	request.UpdatedAt = now
+	_, err := db.ExecContext(ctx, query,
+		request.ID,
+		request.UserID,
+		request.Market,
+		request.Side,
+		request.Size,
+		request.Status,
+		request.AcceptedQuoteID,
+		request.ExpiresAt,
+		request.CreatedAt,
+		request.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert rfq request: %w", err)
+	}
+	return nil
+}
+
+func (r rfqRepository) GetRequestById(ctx context.Context, db repository.DBExecutor, requestId string) (*dto.RfqRequest, error) {
+	query := `SELECT ` + rfqRequestColumns + ` FROM rfq_requests WHERE id = ?`
+
+	request, err := scanRfqRequest(db.QueryRowContext(ctx, query, requestId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("rfq request with id %s not found", requestId)
+		}
+		return nil, fmt.Errorf("failed to get rfq request by id: %w", err)
+	}
+	return request, nil
+}
+
+func (r rfqRepository) GetRequestsByUserId(ctx context.Context, db repository.DBExecutor, userId string, limit int) ([]*dto.RfqRequest, error) {
+	query := `SELECT ` + rfqRequestColumns + ` FROM rfq_requests WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`
+	return r.queryRequests(ctx, db, query, userId, limit)
+}
+
+// GetOpenRequests OPEN requests not expired at now.
+func (r rfqRepository) GetOpenRequests(ctx context.Context, db repository.DBExecutor, now time.Time) ([]*dto.RfqRequest, error) {
+	query := `SELECT ` + rfqRequestColumns + ` FROM rfq_requests WHERE status = ? AND expires_at > ? ORDER BY created_at`
+	return r.queryRequests(ctx, db, query, dto.RFQ_REQUEST_STATUS_OPEN, now)
+}
+
+// GetExpiredOpenRequests OPEN requests expired at now.
+func (r rfqRepository) GetExpiredOpenRequests(ctx context.Context, db repository.DBExecutor, now time.Time) ([]*dto.RfqRequest, error) {
+	query := `SELECT ` + rfqRequestColumns + ` FROM rfq_requests WHERE status = ? AND expires_at <= ? ORDER BY created_at`
+	return r.queryRequests(ctx, db, query, dto.RFQ_REQUEST_STATUS_OPEN, now)
+}
+
+// UpdateRequestStatus update status only if current status == fromStatus, return error if not matched.
+func (r rfqRepository) UpdateRequestStatus(ctx context.Context, db repository.DBExecutor, requestId string, fromStatus, toStatus dto.RfqRequestStatus, acceptedQuoteId string) error {
+	query := `UPDATE rfq_requests SET status = ?, accepted_quote_id = ?, updated_at = ? WHERE id = ? AND status = ?`
+
+	result, err := db.ExecContext(ctx, query, toStatus, acceptedQuoteId, time.Now(), requestId, fromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update rfq request status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("rfq request with id %s and status %s not found", requestId, fromStatus)
+	}
+	return nil
+}
+
+func (r rfqRepository) InsertQuote(ctx context.Context, db repository.DBExecutor, quote *dto.RfqQuote) error {
+	query := `INSERT INTO rfq_quotes (` + rfqQuoteColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now()
+	quote.CreatedAt = now
+	quote.UpdatedAt = now
+	_, err := db.ExecContext(ctx, query,
+		quote.ID,
+		quote.RequestID,
+		quote.ProviderID,
+		quote.Price,
+		quote.Status,
+		quote.CreatedAt,
+		quote.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert rfq quote: %w", err)
+	}
+	return nil
+}
+
+func (r rfqRepository) GetQuoteById(ctx context.Context, db repository.DBExecutor, quoteId string) (*dto.RfqQuote, error) {
+	query := `SELECT ` + rfqQuoteColumns + ` FROM rfq_quotes WHERE id = ?`
+
+	quote, err := scanRfqQuote(db.QueryRowContext(ctx, query, quoteId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("rfq quote with id %s not found", quoteId)
+		}
+		return nil, fmt.Errorf("failed to get rfq quote by id: %w", err)
+	}
+	return quote, nil
+}
+
+func (r rfqRepository) GetQuotesByRequestId(ctx context.Context, db repository.DBExecutor, requestId string) ([]*dto.RfqQuote, error) {
+	query := `SELECT ` + rfqQuoteColumns + ` FROM rfq_quotes WHERE request_id = ? ORDER BY created_at`
+	return r.queryQuotes(ctx, db, query, requestId)
+}
+
+func (r rfqRepository) GetQuotesByProviderId(ctx context.Context, db repository.DBExecutor, providerId string, limit int) ([]*dto.RfqQuote, error) {
+	query := `SELECT ` + rfqQuoteColumns + ` FROM rfq_quotes WHERE provider_id = ? ORDER BY created_at DESC LIMIT ?`
+	return r.queryQuotes(ctx, db, query, providerId, limit)
+}
+
+// UpdateQuoteStatus update status only if current status == fromStatus, return error if not matched.
+func (r rfqRepository) UpdateQuoteStatus(ctx context.Context, db repository.DBExecutor, quoteId string, fromStatus, toStatus dto.RfqQuoteStatus) error {
+	query := `UPDATE rfq_quotes SET status = ?, updated_at = ? WHERE id = ? AND status = ?`
+
+	result, err := db.ExecContext(ctx, query, toStatus, time.Now(), quoteId, fromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update rfq quote status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("rfq quote with id %s and status %s not found", quoteId, fromStatus)
+	}
+	return nil
+}
+
+func (r rfqRepository) queryRequests(ctx context.Context, db repository.DBExecutor, query string, args ...interface{}) ([]*dto.RfqRequest, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rfq requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*dto.RfqRequest
+	for rows.Next() {
+		request, err := scanRfqRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rfq request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return requests, nil
+}
+
+func (r rfqRepository) queryQuotes(ctx context.Context, db repository.DBExecutor, query string, args ...interface{}) ([]*dto.RfqQuote, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rfq quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var quotes []*dto.RfqQuote
+	for rows.Next() {
+		quote, err := scanRfqQuote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rfq quote: %w", err)
+		}
+		quotes = append(quotes, quote)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return quotes, nil
+}
+
+type rfqRowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRfqRequest(row rfqRowScanner) (*dto.RfqRequest, error) {
+	request := &dto.RfqRequest{}
+	err := row.Scan(
+		&request.ID,
+		&request.UserID,
+		&request.Market,
+		&request.Side,
+		&request.Size,
+		&request.Status,
+		&request.AcceptedQuoteID,
+		&request.ExpiresAt,
+		&request.CreatedAt,
+		&request.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func scanRfqQuote(row rfqRowScanner) (*dto.RfqQuote, error) {
+	quote := &dto.RfqQuote{}
+	err := row.Scan(
+		&quote.ID,
+		&quote.RequestID,
+		&quote.ProviderID,
+		&quote.Price,
+		&quote.Status,
+		&quote.CreatedAt,
+		&quote.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
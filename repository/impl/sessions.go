@@ -0,0 +1,179 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+const sessionColumns = `id, user_id, token_hash, refresh_token_hash, client_ip, user_agent, created_at, last_active_at, expires_at, refresh_expires_at`
+
+type sessionRepository struct {
+}
+
+func NewSessionRepository() repository.ISessionRepository {
+	return &sessionRepository{}
+}
+
+func (r sessionRepository) Insert(ctx context.Context, db repository.DBExecutor, session *dto.Session) error {
+	query := `INSERT INTO sessions (` + sessionColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.TokenHash,
+		session.RefreshTokenHash,
+		session.ClientIP,
+		session.UserAgent,
+		session.CreatedAt,
+		session.LastActiveAt,
+		session.ExpiresAt,
+		session.RefreshExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	return nil
+}
+
+func (r sessionRepository) GetByTokenHash(ctx context.Context, db repository.DBExecutor, tokenHash string) (*dto.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE token_hash = ?`
+	return r.getSession(ctx, db, query, tokenHash)
+}
+
+func (r sessionRepository) GetByRefreshTokenHash(ctx context.Context, db repository.DBExecutor, refreshTokenHash string) (*dto.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE refresh_token_hash = ?`
+	return r.getSession(ctx, db, query, refreshTokenHash)
+}
+
+func (r sessionRepository) getSession(ctx context.Context, db repository.DBExecutor, query string, args ...any) (*dto.Session, error) {
+	session, err := scanSession(db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+func (r sessionRepository) GetActiveSessionsByUserId(ctx context.Context, db repository.DBExecutor, userId string, now time.Time) ([]*dto.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE user_id = ? AND refresh_expires_at > ?
+		ORDER BY last_active_at DESC`
+
+	rows, err := db.QueryContext(ctx, query, userId, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*dto.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (r sessionRepository) UpdateLastActiveAt(ctx context.Context, db repository.DBExecutor, sessionId string, lastActiveAt time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE sessions SET last_active_at = ? WHERE id = ?`, lastActiveAt, sessionId)
+	if err != nil {
+		return fmt.Errorf("failed to update session last active at: %w", err)
+	}
+	return nil
+}
+
+func (r sessionRepository) Rotate(ctx context.Context, db repository.DBExecutor, session *dto.Session, fromRefreshTokenHash string) error {
+	query := `UPDATE sessions SET token_hash = ?, refresh_token_hash = ?, client_ip = ?, user_agent = ?, last_active_at = ?, expires_at = ?
+		WHERE id = ? AND refresh_token_hash = ?`
+
+	result, err := db.ExecContext(ctx, query,
+		session.TokenHash,
+		session.RefreshTokenHash,
+		session.ClientIP,
+		session.UserAgent,
+		session.LastActiveAt,
+		session.ExpiresAt,
+		session.ID,
+		fromRefreshTokenHash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("session with id %s and refresh token not found", session.ID)
+	}
+	return nil
+}
+
+func (r sessionRepository) DeleteByTokenHash(ctx context.Context, db repository.DBExecutor, tokenHash string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (r sessionRepository) DeleteByUserId(ctx context.Context, db repository.DBExecutor, userId string) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, userId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r sessionRepository) DeleteByUserIdExcept(ctx context.Context, db repository.DBExecutor, userId, tokenHash string) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ? AND token_hash != ?`, userId, tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r sessionRepository) DeleteExpired(ctx context.Context, db repository.DBExecutor, now time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE refresh_expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+type sessionRowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row sessionRowScanner) (*dto.Session, error) {
+	session := &dto.Session{}
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.TokenHash,
+		&session.RefreshTokenHash,
+		&session.ClientIP,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&session.LastActiveAt,
+		&session.ExpiresAt,
+		&session.RefreshExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
@@ -0,0 +1,91 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+)
+
+type subAccountRepository struct {
+}
+
+func NewSubAccountRepository() repository.ISubAccountRepository {
+	return &subAccountRepository{}
+}
+
+func (s subAccountRepository) Insert(ctx context.Context, db repository.DBExecutor, subAccount *dto.SubAccount) error {
+	query := `INSERT INTO sub_accounts (sub_user_id, master_user_id, label, created_at) VALUES (?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		subAccount.SubUserID,
+		subAccount.MasterUserID,
+		subAccount.Label,
+		subAccount.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert sub account: %w", err)
+	}
+
+	return nil
+}
+
+func (s subAccountRepository) GetSubAccountsByMasterUserId(ctx context.Context, db repository.DBExecutor, masterUserId string) ([]*dto.SubAccount, error) {
+	query := `SELECT s.sub_user_id, s.master_user_id, u.username, s.label, s.created_at
+		FROM sub_accounts s JOIN users u ON u.id = s.sub_user_id
+		WHERE s.master_user_id = ?
+		ORDER BY s.created_at ASC`
+
+	rows, err := db.QueryContext(ctx, query, masterUserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sub accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var subAccounts []*dto.SubAccount
+	for rows.Next() {
+		subAccount := &dto.SubAccount{}
+		err := rows.Scan(
+			&subAccount.SubUserID,
+			&subAccount.MasterUserID,
+			&subAccount.Username,
+			&subAccount.Label,
+			&subAccount.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sub account: %w", err)
+		}
+		subAccounts = append(subAccounts, subAccount)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return subAccounts, nil
+}
+
+func (s subAccountRepository) GetSubAccountBySubUserId(ctx context.Context, db repository.DBExecutor, subUserId string) (*dto.SubAccount, error) {
+	query := `SELECT s.sub_user_id, s.master_user_id, u.username, s.label, s.created_at
+		FROM sub_accounts s JOIN users u ON u.id = s.sub_user_id
+		WHERE s.sub_user_id = ?`
+
+	var subAccount dto.SubAccount
+	err := db.QueryRowContext(ctx, query, subUserId).Scan(
+		&subAccount.SubUserID,
+		&subAccount.MasterUserID,
+		&subAccount.Username,
+		&subAccount.Label,
+		&subAccount.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("sub account with id %s not found", subUserId)
+		}
+		return nil, fmt.Errorf("failed to get sub account: %w", err)
+	}
+
+	return &subAccount, nil
+}
@@ -2,9 +2,13 @@ package repositoryImpl
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
 	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/utils"
 	"strings"
 	"time"
 )
@@ -16,16 +20,17 @@ func NewTradeRepository() repository.ITradeRepository {
 	return &tradeRepository{}
 }
 
-func (t tradeRepository) BatchInsert(ctx context.Context, db repository.DBExecutor, trades []book.Trade) error {
+// BatchInsert insert trades with fees in normal asset (bid: base asset, ask: quote asset).
+func (t tradeRepository) BatchInsert(ctx context.Context, db repository.DBExecutor, trades []book.Trade, takerSide model.Side, baseAsset, quoteAsset string) error {
 	if len(trades) == 0 {
 		return nil
 	}
 
 	valueStrings := make([]string, 0, len(trades))
-	valueArgs := make([]interface{}, 0, len(trades)*8) // 8 columns
+	valueArgs := make([]interface{}, 0, len(trades)*15) // 15 columns
 
 	for _, trade := range trades {
-		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 		valueArgs = append(valueArgs,
 			trade.Market,
 			trade.AskOrderID,
@@ -34,11 +39,19 @@ func (t tradeRepository) BatchInsert(ctx context.Context, db repository.DBExecut
 			trade.BidFeeRate,
 			trade.Price,
 			trade.Size,
+			trade.BidUserID,
+			trade.AskUserID,
+			takerSide,
+			utils.RoundFloat(trade.Size*trade.BidFeeRate),
+			baseAsset,
+			utils.RoundFloat(trade.Price*trade.Size*trade.AskFeeRate),
+			quoteAsset,
 			trade.Timestamp,
 		)
 	}
 
-	query := fmt.Sprintf("INSERT INTO trades (market, ask_order_id, bid_order_id, ask_fee_rate, bid_fee_rate, price, size, timestamp) VALUES %s",
+	query := fmt.Sprintf(`INSERT INTO trades (market, ask_order_id, bid_order_id, ask_fee_rate, bid_fee_rate, price, size,
+		bid_user_id, ask_user_id, taker_side, bid_fee, bid_fee_asset, ask_fee, ask_fee_asset, timestamp) VALUES %s`,
 		strings.Join(valueStrings, ","))
 
 	_, err := db.ExecContext(ctx, query, valueArgs...)
@@ -49,9 +62,39 @@ func (t tradeRepository) BatchInsert(ctx context.Context, db repository.DBExecut
 	return nil
 }
 
+// InsertBlockTrade insert RFQ block trade (flagged block), fees are given by caller (bid: base asset, ask: quote asset).
+func (t tradeRepository) InsertBlockTrade(ctx context.Context, db repository.DBExecutor, trade book.Trade, takerSide model.Side, bidFee, askFee float64, baseAsset, quoteAsset string) error {
+	query := `INSERT INTO trades (market, ask_order_id, bid_order_id, ask_fee_rate, bid_fee_rate, price, size,
+		bid_user_id, ask_user_id, taker_side, bid_fee, bid_fee_asset, ask_fee, ask_fee_asset, block, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		trade.Market,
+		trade.AskOrderID,
+		trade.BidOrderID,
+		trade.AskFeeRate,
+		trade.BidFeeRate,
+		trade.Price,
+		trade.Size,
+		trade.BidUserID,
+		trade.AskUserID,
+		takerSide,
+		bidFee,
+		baseAsset,
+		askFee,
+		quoteAsset,
+		trade.Timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert block trade: %w", err)
+	}
+
+	return nil
+}
+
 func (t tradeRepository) GetMarketLatestPrice(ctx context.Context, db repository.DBExecutor, marketName string) (float64, error) {
 	query := `SELECT price
-		FROM trades WHERE market = ? 
+		FROM trades WHERE market = ? AND block = 0
 		ORDER BY timestamp DESC LIMIT 1`
 
 	rows, err := db.QueryContext(ctx, query, marketName)
@@ -75,7 +118,7 @@ func (t tradeRepository) GetMarketPriceTimesAgo(ctx context.Context, db reposito
 	query := `
         SELECT price 
         FROM trades 
-        WHERE market = ? AND timestamp <= ? 
+        WHERE market = ? AND block = 0 AND timestamp <= ? 
         ORDER BY timestamp DESC 
         LIMIT 1`
 
@@ -100,3 +143,282 @@ func (t tradeRepository) GetMarketVolumeByTimeRange(ctx context.Context, db repo
 	}
 	return volume, nil
 }
+
+// GetUsersQuoteVolumeSince sum quote volume (price * size) group by user (both bid and ask side) since startTime.
+func (t tradeRepository) GetUsersQuoteVolumeSince(ctx context.Context, db repository.DBExecutor, startTime time.Time) (map[string]float64, error) {
+	query := `
+        SELECT user_id, COALESCE(SUM(quote_volume), 0) FROM (
+            SELECT bid_user_id AS user_id, price * size AS quote_volume
+            FROM trades WHERE timestamp >= ?
+            UNION ALL
+            SELECT ask_user_id AS user_id, price * size AS quote_volume
+            FROM trades WHERE timestamp >= ?
+        ) GROUP BY user_id`
+
+	rows, err := db.QueryContext(ctx, query, startTime, startTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users quote volume: %w", err)
+	}
+	defer rows.Close()
+
+	volumes := make(map[string]float64)
+	for rows.Next() {
+		var userId string
+		var volume float64
+		if err := rows.Scan(&userId, &volume); err != nil {
+			return nil, fmt.Errorf("failed to scan users quote volume: %w", err)
+		}
+		volumes[userId] = volume
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return volumes, nil
+}
+
+func (t tradeRepository) GetUserQuoteVolumeSince(ctx context.Context, db repository.DBExecutor, userId string, startTime time.Time) (float64, error) {
+	query := `
+        SELECT COALESCE(SUM(price * size), 0)
+        FROM trades
+        WHERE (bid_user_id = ? OR ask_user_id = ?) AND timestamp >= ?`
+
+	var volume float64
+	err := db.QueryRowContext(ctx, query, userId, userId, startTime).Scan(&volume)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query user quote volume: %w", err)
+	}
+	return volume, nil
+}
+
+// UpdateFee overwrite fee and fee asset of one side, a bid/ask order pair only match once so it identifies a trade.
+func (t tradeRepository) UpdateFee(ctx context.Context, db repository.DBExecutor, bidOrderId, askOrderId string, side model.Side, fee float64, feeAsset string) error {
+	query := `UPDATE trades SET bid_fee = ?, bid_fee_asset = ? WHERE bid_order_id = ? AND ask_order_id = ?`
+	if side == model.ASK {
+		query = `UPDATE trades SET ask_fee = ?, ask_fee_asset = ? WHERE bid_order_id = ? AND ask_order_id = ?`
+	}
+
+	result, err := db.ExecContext(ctx, query, fee, feeAsset, bidOrderId, askOrderId)
+	if err != nil {
+		return fmt.Errorf("failed to update trade fee: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("trade of bid order %s and ask order %s not found", bidOrderId, askOrderId)
+	}
+
+	return nil
+}
+
+// GetFillsByUserId query user fills (both bid and ask side) order by trade id desc.
+func (t tradeRepository) GetFillsByUserId(ctx context.Context, db repository.DBExecutor, userId string, query *dto.GetFillsQueryReq, limit int) ([]*dto.Fill, error) {
+	conditions := []string{"user_id = ?"}
+	conditionArgs := []interface{}{userId}
+	if query.Market != "" {
+		conditions = append(conditions, "market = ?")
+		conditionArgs = append(conditionArgs, query.Market)
+	}
+	if query.StartTime > 0 {
+		conditions = append(conditions, "timestamp >= ?")
+		conditionArgs = append(conditionArgs, time.UnixMilli(query.StartTime))
+	}
+	if query.EndTime > 0 {
+		conditions = append(conditions, "timestamp < ?")
+		conditionArgs = append(conditionArgs, time.UnixMilli(query.EndTime))
+	}
+	if query.Cursor > 0 {
+		conditions = append(conditions, "trade_id < ?")
+		conditionArgs = append(conditionArgs, query.Cursor)
+	}
+
+	sqlQuery := fmt.Sprintf(`
+        SELECT trade_id, order_id, market, side, role, price, size, fee, fee_asset, block, timestamp FROM (
+            SELECT id AS trade_id, bid_order_id AS order_id, bid_user_id AS user_id, market, %d AS side,
+                   CASE WHEN taker_side = %d THEN %d ELSE %d END AS role,
+                   price, size, bid_fee AS fee, bid_fee_asset AS fee_asset, block, timestamp
+            FROM trades
+            UNION ALL
+            SELECT id AS trade_id, ask_order_id AS order_id, ask_user_id AS user_id, market, %d AS side,
+                   CASE WHEN taker_side = %d THEN %d ELSE %d END AS role,
+                   price, size, ask_fee AS fee, ask_fee_asset AS fee_asset, block, timestamp
+            FROM trades
+        ) WHERE %s
+        ORDER BY trade_id DESC, side ASC
+        LIMIT ?`,
+		model.BID, model.BID, model.TAKER, model.MAKER,
+		model.ASK, model.ASK, model.TAKER, model.MAKER,
+		strings.Join(conditions, " AND "))
+
+	args := append(conditionArgs, limit)
+	rows, err := db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fills: %w", err)
+	}
+	defer rows.Close()
+
+	var fills []*dto.Fill
+	for rows.Next() {
+		fill := &dto.Fill{}
+		err := rows.Scan(
+			&fill.TradeID,
+			&fill.OrderID,
+			&fill.Market,
+			&fill.Side,
+			&fill.Role,
+			&fill.Price,
+			&fill.Size,
+			&fill.Fee,
+			&fill.FeeAsset,
+			&fill.Block,
+			&fill.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fill: %w", err)
+		}
+		fill.QuoteAmount = utils.RoundFloat(fill.Price * fill.Size)
+		fills = append(fills, fill)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return fills, nil
+}
+
+func (t tradeRepository) GetFillsByTrades(ctx context.Context, db repository.DBExecutor, trades []book.Trade) ([]*dto.Fill, error) {
+	if len(trades) == 0 {
+		return []*dto.Fill{}, nil
+	}
+
+	conditions, args := orderPairConditions(trades)
+	query := fmt.Sprintf(`SELECT id, bid_order_id, ask_order_id, market, taker_side, price, size,
+		bid_fee, bid_fee_asset, ask_fee, ask_fee_asset, block, timestamp
+		FROM trades WHERE %s ORDER BY id`, conditions)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fills by trades: %w", err)
+	}
+	defer rows.Close()
+
+	fills := make([]*dto.Fill, 0, len(trades)*2)
+	for rows.Next() {
+		bid := &dto.Fill{Side: model.BID, Role: model.MAKER}
+		var askOrderId, askFeeAsset string
+		var askFee float64
+		var takerSide model.Side
+		err := rows.Scan(
+			&bid.TradeID,
+			&bid.OrderID,
+			&askOrderId,
+			&bid.Market,
+			&takerSide,
+			&bid.Price,
+			&bid.Size,
+			&bid.Fee,
+			&bid.FeeAsset,
+			&askFee,
+			&askFeeAsset,
+			&bid.Block,
+			&bid.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fill: %w", err)
+		}
+		bid.QuoteAmount = utils.RoundFloat(bid.Price * bid.Size)
+
+		ask := *bid
+		ask.OrderID = askOrderId
+		ask.Side = model.ASK
+		ask.Fee = askFee
+		ask.FeeAsset = askFeeAsset
+		if takerSide == model.BID {
+			bid.Role = model.TAKER
+		} else {
+			ask.Role = model.TAKER
+		}
+		fills = append(fills, bid, &ask)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return fills, nil
+}
+
+func (t tradeRepository) GetRecentTrades(ctx context.Context, db repository.DBExecutor, market string, limit int) ([]*dto.MarketTrade, error) {
+	query := `SELECT ` + marketTradeColumns + ` FROM trades WHERE market = ? ORDER BY id DESC LIMIT ?`
+
+	rows, err := db.QueryContext(ctx, query, market, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent trades: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMarketTrades(rows)
+}
+
+func (t tradeRepository) GetMarketTradesByTrades(ctx context.Context, db repository.DBExecutor, trades []book.Trade) ([]*dto.MarketTrade, error) {
+	if len(trades) == 0 {
+		return []*dto.MarketTrade{}, nil
+	}
+
+	conditions, args := orderPairConditions(trades)
+	query := fmt.Sprintf(`SELECT %s FROM trades WHERE %s ORDER BY id`, marketTradeColumns, conditions)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market trades by trades: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMarketTrades(rows)
+}
+
+const marketTradeColumns = `id, market, price, size, taker_side, block, timestamp`
+
+func scanMarketTrades(rows *sql.Rows) ([]*dto.MarketTrade, error) {
+	trades := make([]*dto.MarketTrade, 0)
+	for rows.Next() {
+		trade := &dto.MarketTrade{}
+		err := rows.Scan(
+			&trade.ID,
+			&trade.Market,
+			&trade.Price,
+			&trade.Size,
+			&trade.TakerSide,
+			&trade.Block,
+			&trade.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan market trade: %w", err)
+		}
+		trade.QuoteAmount = utils.RoundFloat(trade.Price * trade.Size)
+		trades = append(trades, trade)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return trades, nil
+}
+
+// orderPairConditions where clause of trades by bid/ask order pair, a bid/ask order pair only match once.
+func orderPairConditions(trades []book.Trade) (string, []interface{}) {
+	conditions := make([]string, len(trades))
+	args := make([]interface{}, 0, len(trades)*2)
+	for i, trade := range trades {
+		conditions[i] = "(bid_order_id = ? AND ask_order_id = ?)"
+		args = append(args, trade.BidOrderID, trade.AskOrderID)
+	}
+	return strings.Join(conditions, " OR "), args
+}
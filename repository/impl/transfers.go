@@ -0,0 +1,123 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+type transferRepository struct {
+}
+
+func NewTransferRepository() repository.ITransferRepository {
+	return &transferRepository{}
+}
+
+func (t transferRepository) Insert(ctx context.Context, db repository.DBExecutor, transfer *dto.Transfer) error {
+	query := `INSERT INTO transfers (
+		id, idempotency_key, from_user_id, to_user_id, asset, amount, type, remark, valuation, created_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		transfer.ID,
+		transfer.IdempotencyKey,
+		transfer.FromUserID,
+		transfer.ToUserID,
+		transfer.Asset,
+		transfer.Amount,
+		transfer.Type,
+		transfer.Remark,
+		transfer.Valuation,
+		transfer.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert transfer: %w", err)
+	}
+
+	return nil
+}
+
+func (t transferRepository) GetTransferByIdempotencyKey(ctx context.Context, db repository.DBExecutor, fromUserId, idempotencyKey string) (*dto.Transfer, error) {
+	query := `SELECT id, idempotency_key, from_user_id, to_user_id, asset, amount, type, remark, valuation, created_at
+		FROM transfers WHERE from_user_id = ? AND idempotency_key = ?`
+
+	var transfer dto.Transfer
+	err := db.QueryRowContext(ctx, query, fromUserId, idempotencyKey).Scan(
+		&transfer.ID,
+		&transfer.IdempotencyKey,
+		&transfer.FromUserID,
+		&transfer.ToUserID,
+		&transfer.Asset,
+		&transfer.Amount,
+		&transfer.Type,
+		&transfer.Remark,
+		&transfer.Valuation,
+		&transfer.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("transfer with idempotency key %s not found", idempotencyKey)
+		}
+		return nil, fmt.Errorf("failed to get transfer by idempotency key: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+// GetTransfersByUserId get transfers which user is sender or receiver.
+func (t transferRepository) GetTransfersByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.Transfer, error) {
+	query := `SELECT id, idempotency_key, from_user_id, to_user_id, asset, amount, type, remark, valuation, created_at
+		FROM transfers WHERE from_user_id = ? OR to_user_id = ?
+		ORDER BY created_at DESC`
+
+	rows, err := db.QueryContext(ctx, query, userId, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []*dto.Transfer
+	for rows.Next() {
+		transfer := &dto.Transfer{}
+		err := rows.Scan(
+			&transfer.ID,
+			&transfer.IdempotencyKey,
+			&transfer.FromUserID,
+			&transfer.ToUserID,
+			&transfer.Asset,
+			&transfer.Amount,
+			&transfer.Type,
+			&transfer.Remark,
+			&transfer.Valuation,
+			&transfer.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transfer: %w", err)
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// SumValuationSince sum valuation of transfers sent by user created after since.
+func (t transferRepository) SumValuationSince(ctx context.Context, db repository.DBExecutor, fromUserId string, since time.Time) (float64, error) {
+	query := `
+        SELECT COALESCE(SUM(valuation), 0)
+        FROM transfers
+        WHERE from_user_id = ? AND created_at >= ?`
+
+	var total float64
+	if err := db.QueryRowContext(ctx, query, fromUserId, since).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to sum transfer valuation: %w", err)
+	}
+	return total, nil
+}
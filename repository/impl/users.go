@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/repository"
+	"strings"
 )
 
 type userRepository struct {
@@ -16,9 +17,12 @@ func NewUserRepository() repository.IUserRepository {
 }
 
 func (u userRepository) GetUserById(ctx context.Context, db repository.DBExecutor, userId string) (*dto.User, error) {
-	query := `SELECT id, username, password_hash, vip_level, maker_fee, taker_fee, created_at FROM users WHERE id = ?`
+	query := `SELECT id, username, password_hash, vip_level, maker_fee, taker_fee, pay_fee_in_btse, created_at,
+		totp_secret, totp_enabled, totp_backup_codes, totp_last_step,
+		status, trade_disabled, withdraw_disabled, status_reason FROM users WHERE id = ?`
 
 	var user dto.User
+	var backupCodes string
 
 	err := db.QueryRowContext(ctx, query, userId).Scan(
 		&user.ID,
@@ -27,7 +31,16 @@ func (u userRepository) GetUserById(ctx context.Context, db repository.DBExecuto
 		&user.VipLevel,
 		&user.MakerFee,
 		&user.TakerFee,
+		&user.PayFeeInBTSE,
 		&user.CreatedAt,
+		&user.TotpSecret,
+		&user.TotpEnabled,
+		&backupCodes,
+		&user.TotpLastStep,
+		&user.Status,
+		&user.TradeDisabled,
+		&user.WithdrawDisabled,
+		&user.StatusReason,
 	)
 
 	if err != nil {
@@ -37,13 +50,17 @@ func (u userRepository) GetUserById(ctx context.Context, db repository.DBExecuto
 		return nil, fmt.Errorf("failed to get user by id: %w", err)
 	}
 
+	user.TotpBackupCodes = splitNonEmpty(backupCodes)
 	return &user, nil
 }
 
 func (u userRepository) GetUserByUsername(ctx context.Context, db repository.DBExecutor, username string) (*dto.User, error) {
-	query := `SELECT id, username, password_hash, vip_level, maker_fee, taker_fee, created_at FROM users WHERE username = ?`
+	query := `SELECT id, username, password_hash, vip_level, maker_fee, taker_fee, pay_fee_in_btse, created_at,
+		totp_secret, totp_enabled, totp_backup_codes, totp_last_step,
+		status, trade_disabled, withdraw_disabled, status_reason FROM users WHERE username = ?`
 
 	var user dto.User
+	var backupCodes string
 
 	err := db.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
@@ -52,7 +69,16 @@ func (u userRepository) GetUserByUsername(ctx context.Context, db repository.DBE
 		&user.VipLevel,
 		&user.MakerFee,
 		&user.TakerFee,
+		&user.PayFeeInBTSE,
 		&user.CreatedAt,
+		&user.TotpSecret,
+		&user.TotpEnabled,
+		&backupCodes,
+		&user.TotpLastStep,
+		&user.Status,
+		&user.TradeDisabled,
+		&user.WithdrawDisabled,
+		&user.StatusReason,
 	)
 
 	if err != nil {
@@ -62,6 +88,7 @@ func (u userRepository) GetUserByUsername(ctx context.Context, db repository.DBE
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
 
+	user.TotpBackupCodes = splitNonEmpty(backupCodes)
 	return &user, nil
 }
 
@@ -97,6 +124,53 @@ func (u userRepository) UpdatePwd(ctx context.Context, db repository.DBExecutor,
 	return nil
 }
 
+func (u userRepository) GetAllUsers(ctx context.Context, db repository.DBExecutor) ([]*dto.User, error) {
+	query := `SELECT id, username, password_hash, vip_level, maker_fee, taker_fee, pay_fee_in_btse, created_at,
+		totp_secret, totp_enabled, totp_backup_codes, totp_last_step,
+		status, trade_disabled, withdraw_disabled, status_reason FROM users`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*dto.User
+	for rows.Next() {
+		user := &dto.User{}
+		var backupCodes string
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.PasswordHash,
+			&user.VipLevel,
+			&user.MakerFee,
+			&user.TakerFee,
+			&user.PayFeeInBTSE,
+			&user.CreatedAt,
+			&user.TotpSecret,
+			&user.TotpEnabled,
+			&backupCodes,
+			&user.TotpLastStep,
+			&user.Status,
+			&user.TradeDisabled,
+			&user.WithdrawDisabled,
+			&user.StatusReason,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.TotpBackupCodes = splitNonEmpty(backupCodes)
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return users, nil
+}
+
 func (u userRepository) UpdateVipLevel(ctx context.Context, db repository.DBExecutor, user *dto.User) error {
 	query := `UPDATE users SET vip_level = ?, maker_fee = ?, taker_fee = ? WHERE id = ?`
 
@@ -116,3 +190,75 @@ func (u userRepository) UpdateVipLevel(ctx context.Context, db repository.DBExec
 
 	return nil
 }
+
+func (u userRepository) UpdatePayFeeInBTSE(ctx context.Context, db repository.DBExecutor, userId string, payFeeInBTSE bool) error {
+	query := `UPDATE users SET pay_fee_in_btse = ? WHERE id = ?`
+
+	result, err := db.ExecContext(ctx, query, payFeeInBTSE, userId)
+	if err != nil {
+		return fmt.Errorf("failed to update user pay_fee_in_btse: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with id %s not found", userId)
+	}
+
+	return nil
+}
+
+func (u userRepository) UpdateTotp(ctx context.Context, db repository.DBExecutor, user *dto.User) error {
+	query := `UPDATE users SET totp_secret = ?, totp_enabled = ?, totp_backup_codes = ?, totp_last_step = ? WHERE id = ?`
+
+	result, err := db.ExecContext(ctx, query,
+		user.TotpSecret,
+		user.TotpEnabled,
+		strings.Join(user.TotpBackupCodes, ","),
+		user.TotpLastStep,
+		user.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user totp: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with id %s not found", user.ID)
+	}
+
+	return nil
+}
+
+func (u userRepository) UpdateStatus(ctx context.Context, db repository.DBExecutor, user *dto.User) error {
+	query := `UPDATE users SET status = ?, trade_disabled = ?, withdraw_disabled = ?, status_reason = ? WHERE id = ?`
+
+	result, err := db.ExecContext(ctx, query,
+		user.Status,
+		user.TradeDisabled,
+		user.WithdrawDisabled,
+		user.StatusReason,
+		user.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with id %s not found", user.ID)
+	}
+
+	return nil
+}
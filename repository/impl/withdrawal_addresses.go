@@ -0,0 +1,122 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+)
+
+type withdrawalAddressRepository struct {
+}
+
+func NewWithdrawalAddressRepository() repository.IWithdrawalAddressRepository {
+	return &withdrawalAddressRepository{}
+}
+
+func (w withdrawalAddressRepository) Insert(ctx context.Context, db repository.DBExecutor, address *dto.WithdrawalAddress) error {
+	query := `INSERT INTO withdrawal_addresses (user_id, asset, address, label, created_at, available_at)
+			VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := db.ExecContext(ctx, query,
+		address.UserID,
+		address.Asset,
+		address.Address,
+		address.Label,
+		address.CreatedAt,
+		address.AvailableAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert withdrawal address: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	address.ID = id
+
+	return nil
+}
+
+func (w withdrawalAddressRepository) GetAddressesByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.WithdrawalAddress, error) {
+	query := `SELECT id, user_id, asset, address, label, created_at, available_at
+		FROM withdrawal_addresses WHERE user_id = ?
+		ORDER BY created_at DESC`
+
+	rows, err := db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query withdrawal addresses: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []*dto.WithdrawalAddress
+	for rows.Next() {
+		address := &dto.WithdrawalAddress{}
+		err := rows.Scan(
+			&address.ID,
+			&address.UserID,
+			&address.Asset,
+			&address.Address,
+			&address.Label,
+			&address.CreatedAt,
+			&address.AvailableAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan withdrawal address: %w", err)
+		}
+		addresses = append(addresses, address)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return addresses, nil
+}
+
+func (w withdrawalAddressRepository) GetAddressByUserIdAndAssetAndAddress(ctx context.Context, db repository.DBExecutor, userId, asset, address string) (*dto.WithdrawalAddress, error) {
+	query := `SELECT id, user_id, asset, address, label, created_at, available_at
+		FROM withdrawal_addresses WHERE user_id = ? AND asset = ? AND address = ?`
+
+	var result dto.WithdrawalAddress
+	err := db.QueryRowContext(ctx, query, userId, asset, address).Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Asset,
+		&result.Address,
+		&result.Label,
+		&result.CreatedAt,
+		&result.AvailableAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("withdrawal address %s not found", address)
+		}
+		return nil, fmt.Errorf("failed to get withdrawal address: %w", err)
+	}
+
+	return &result, nil
+}
+
+func (w withdrawalAddressRepository) Delete(ctx context.Context, db repository.DBExecutor, userId string, addressId int64) error {
+	query := `DELETE FROM withdrawal_addresses WHERE id = ? AND user_id = ?`
+
+	result, err := db.ExecContext(ctx, query, addressId, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete withdrawal address: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("withdrawal address with id %d not found", addressId)
+	}
+
+	return nil
+}
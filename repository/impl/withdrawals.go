@@ -0,0 +1,224 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"time"
+)
+
+type withdrawalRepository struct {
+}
+
+func NewWithdrawalRepository() repository.IWithdrawalRepository {
+	return &withdrawalRepository{}
+}
+
+func (w withdrawalRepository) Insert(ctx context.Context, db repository.DBExecutor, withdrawal *dto.Withdrawal) error {
+	query := `INSERT INTO withdrawals (
+		id, user_id, asset, amount, address, valuation, status, tx_hash, remark, created_at, updated_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		withdrawal.ID,
+		withdrawal.UserID,
+		withdrawal.Asset,
+		withdrawal.Amount,
+		withdrawal.Address,
+		withdrawal.Valuation,
+		withdrawal.Status,
+		withdrawal.TxHash,
+		withdrawal.Remark,
+		withdrawal.CreatedAt,
+		withdrawal.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert withdrawal: %w", err)
+	}
+
+	return nil
+}
+
+func (w withdrawalRepository) GetWithdrawalById(ctx context.Context, db repository.DBExecutor, withdrawalId string) (*dto.Withdrawal, error) {
+	query := `SELECT id, user_id, asset, amount, address, valuation, status, tx_hash, remark, created_at, updated_at
+		FROM withdrawals WHERE id = ?`
+
+	var withdrawal dto.Withdrawal
+	err := db.QueryRowContext(ctx, query, withdrawalId).Scan(
+		&withdrawal.ID,
+		&withdrawal.UserID,
+		&withdrawal.Asset,
+		&withdrawal.Amount,
+		&withdrawal.Address,
+		&withdrawal.Valuation,
+		&withdrawal.Status,
+		&withdrawal.TxHash,
+		&withdrawal.Remark,
+		&withdrawal.CreatedAt,
+		&withdrawal.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("withdrawal with id %s not found", withdrawalId)
+		}
+		return nil, fmt.Errorf("failed to get withdrawal by id: %w", err)
+	}
+
+	return &withdrawal, nil
+}
+
+func (w withdrawalRepository) GetWithdrawalsByUserId(ctx context.Context, db repository.DBExecutor, userId string) ([]*dto.Withdrawal, error) {
+	query := `SELECT id, user_id, asset, amount, address, valuation, status, tx_hash, remark, created_at, updated_at
+		FROM withdrawals WHERE user_id = ?
+		ORDER BY created_at DESC`
+
+	return w.queryWithdrawals(ctx, db, query, userId)
+}
+
+func (w withdrawalRepository) GetWithdrawalsByStatus(ctx context.Context, db repository.DBExecutor, status dto.WithdrawalStatus) ([]*dto.Withdrawal, error) {
+	query := `SELECT id, user_id, asset, amount, address, valuation, status, tx_hash, remark, created_at, updated_at
+		FROM withdrawals WHERE status = ?
+		ORDER BY created_at ASC`
+
+	return w.queryWithdrawals(ctx, db, query, string(status))
+}
+
+func (w withdrawalRepository) queryWithdrawals(ctx context.Context, db repository.DBExecutor, query string, args ...any) ([]*dto.Withdrawal, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query withdrawals: %w", err)
+	}
+	defer rows.Close()
+
+	var withdrawals []*dto.Withdrawal
+	for rows.Next() {
+		withdrawal := &dto.Withdrawal{}
+		err := rows.Scan(
+			&withdrawal.ID,
+			&withdrawal.UserID,
+			&withdrawal.Asset,
+			&withdrawal.Amount,
+			&withdrawal.Address,
+			&withdrawal.Valuation,
+			&withdrawal.Status,
+			&withdrawal.TxHash,
+			&withdrawal.Remark,
+			&withdrawal.CreatedAt,
+			&withdrawal.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan withdrawal: %w", err)
+		}
+		withdrawals = append(withdrawals, withdrawal)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return withdrawals, nil
+}
+
+// UpdateStatus update status only if current status == fromStatus, return error if not matched.
+func (w withdrawalRepository) UpdateStatus(ctx context.Context, db repository.DBExecutor, withdrawalId string, fromStatus, toStatus dto.WithdrawalStatus, txHash, remark string) error {
+	query := `UPDATE withdrawals SET
+		status = ?, tx_hash = CASE WHEN ? = '' THEN tx_hash ELSE ? END, remark = ?, updated_at = ?
+		WHERE id = ? AND status = ?`
+
+	result, err := db.ExecContext(ctx, query,
+		toStatus,
+		txHash,
+		txHash,
+		remark,
+		time.Now(),
+		withdrawalId,
+		fromStatus,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update withdrawal status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("withdrawal with id %s and status %s not found", withdrawalId, fromStatus)
+	}
+
+	return nil
+}
+
+// SumValuationSince sum user withdrawals valuation (exclude REJECTED) created after since.
+func (w withdrawalRepository) SumValuationSince(ctx context.Context, db repository.DBExecutor, userId string, since time.Time) (float64, error) {
+	query := `
+        SELECT COALESCE(SUM(valuation), 0)
+        FROM withdrawals
+        WHERE user_id = ? AND status != ? AND created_at >= ?`
+
+	var total float64
+	err := db.QueryRowContext(ctx, query, userId, dto.WITHDRAWAL_STATUS_REJECTED, since).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum withdrawal valuation: %w", err)
+	}
+	return total, nil
+}
+
+func (w withdrawalRepository) InsertAudit(ctx context.Context, db repository.DBExecutor, audit *dto.WithdrawalAudit) error {
+	query := `INSERT INTO withdrawal_audits (withdrawal_id, from_status, to_status, operator, remark, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := db.ExecContext(ctx, query,
+		audit.WithdrawalID,
+		audit.FromStatus,
+		audit.ToStatus,
+		audit.Operator,
+		audit.Remark,
+		audit.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert withdrawal audit: %w", err)
+	}
+
+	return nil
+}
+
+func (w withdrawalRepository) GetAuditsByWithdrawalId(ctx context.Context, db repository.DBExecutor, withdrawalId string) ([]*dto.WithdrawalAudit, error) {
+	query := `SELECT id, withdrawal_id, from_status, to_status, operator, remark, created_at
+		FROM withdrawal_audits WHERE withdrawal_id = ?
+		ORDER BY id ASC`
+
+	rows, err := db.QueryContext(ctx, query, withdrawalId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query withdrawal audits: %w", err)
+	}
+	defer rows.Close()
+
+	var audits []*dto.WithdrawalAudit
+	for rows.Next() {
+		audit := &dto.WithdrawalAudit{}
+		err := rows.Scan(
+			&audit.ID,
+			&audit.WithdrawalID,
+			&audit.FromStatus,
+			&audit.ToStatus,
+			&audit.Operator,
+			&audit.Remark,
+			&audit.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan withdrawal audit: %w", err)
+		}
+		audits = append(audits, audit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return audits, nil
+}
@@ -21,6 +21,12 @@ type IUserRepository interface {
 	Insert(ctx context.Context, db DBExecutor, user *dto.User) error
 	UpdatePwd(ctx context.Context, db DBExecutor, user *dto.User) error
 	UpdateVipLevel(ctx context.Context, db DBExecutor, user *dto.User) error
+	GetAllUsers(ctx context.Context, db DBExecutor) ([]*dto.User, error)
+	UpdatePayFeeInBTSE(ctx context.Context, db DBExecutor, userId string, payFeeInBTSE bool) error
+	// UpdateTotp update TOTP secret, enabled flag, backup codes and last used step.
+	UpdateTotp(ctx context.Context, db DBExecutor, user *dto.User) error
+	// UpdateStatus update account status, trade/withdraw disabled flags and reason.
+	UpdateStatus(ctx context.Context, db DBExecutor, user *dto.User) error
 }
 
 type IBalanceRepository interface {
@@ -52,11 +58,256 @@ type IOrderRepository interface {
 	PaginationQuery(ctx context.Context, db DBExecutor, query *dto.GetOrdersQueryReq, statuses []model.OrderStatus, endTime time.Time) (*dto.PaginationResp[*dto.Order], error)
 	GetOrdersByUserIdAndMarketAndStatuses(ctx context.Context, b DBExecutor, userId string, market string, statuses []model.OrderStatus) ([]*dto.Order, error)
 	CountOpenOrders(ctx context.Context, db *sql.DB, marketName string) (int64, error)
+	GetOrdersByIds(ctx context.Context, db DBExecutor, orderIds []string) ([]*dto.Order, error)
+	// UpdateFeeAsset overwrite order fee asset and accumulated fees.
+	UpdateFeeAsset(ctx context.Context, db DBExecutor, orderId string, feeAsset string, fees float64) error
 }
 
 type ITradeRepository interface {
-	BatchInsert(ctx context.Context, db DBExecutor, trades []book.Trade) error
+	// BatchInsert insert trades with fees in normal asset (bid: base asset, ask: quote asset).
+	BatchInsert(ctx context.Context, db DBExecutor, trades []book.Trade, takerSide model.Side, baseAsset, quoteAsset string) error
+	// InsertBlockTrade insert RFQ block trade (flagged block), fees are given by caller (bid: base asset, ask: quote asset).
+	InsertBlockTrade(ctx context.Context, db DBExecutor, trade book.Trade, takerSide model.Side, bidFee, askFee float64, baseAsset, quoteAsset string) error
+	// UpdateFee overwrite fee and fee asset of one side, a bid/ask order pair only match once so it identifies a trade.
+	UpdateFee(ctx context.Context, db DBExecutor, bidOrderId, askOrderId string, side model.Side, fee float64, feeAsset string) error
+	// GetFillsByUserId query user fills (both bid and ask side) order by trade id desc.
+	GetFillsByUserId(ctx context.Context, db DBExecutor, userId string, query *dto.GetFillsQueryReq, limit int) ([]*dto.Fill, error)
+	// GetFillsByTrades query fills of both sides of trades (bid fill first), trades are found by bid/ask order pair.
+	GetFillsByTrades(ctx context.Context, db DBExecutor, trades []book.Trade) ([]*dto.Fill, error)
+	// GetRecentTrades query latest trades of market order by trade id desc.
+	GetRecentTrades(ctx context.Context, db DBExecutor, market string, limit int) ([]*dto.MarketTrade, error)
+	// GetMarketTradesByTrades query persisted trades order by trade id, trades are found by bid/ask order pair.
+	GetMarketTradesByTrades(ctx context.Context, db DBExecutor, trades []book.Trade) ([]*dto.MarketTrade, error)
 	GetMarketLatestPrice(ctx context.Context, db DBExecutor, market string) (float64, error)
 	GetMarketPriceTimesAgo(ctx context.Context, db DBExecutor, market string, timeAgo time.Time) (float64, error)
 	GetMarketVolumeByTimeRange(ctx context.Context, db DBExecutor, market string, startTime time.Time, endTime time.Time) (float64, error)
+	// GetUsersQuoteVolumeSince sum quote volume (price * size) group by user (both bid and ask side) since startTime.
+	GetUsersQuoteVolumeSince(ctx context.Context, db DBExecutor, startTime time.Time) (map[string]float64, error)
+	GetUserQuoteVolumeSince(ctx context.Context, db DBExecutor, userId string, startTime time.Time) (float64, error)
+}
+
+type IPnlRepository interface {
+	// GetCostBasesByUserIds return user id -> cost basis of asset, users without cost basis are absent.
+	GetCostBasesByUserIds(ctx context.Context, db DBExecutor, userIds []string, asset string) (map[string]*dto.CostBasis, error)
+	GetCostBasesByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.CostBasis, error)
+	UpsertCostBasis(ctx context.Context, db DBExecutor, basis *dto.CostBasis) error
+	InsertRealizedPnl(ctx context.Context, db DBExecutor, realized *dto.RealizedPnl) error
+	// SumRealizedPnlByMarket sum user realized pnl group by market in [from, to).
+	SumRealizedPnlByMarket(ctx context.Context, db DBExecutor, userId string, from, to time.Time) ([]*dto.MarketRealizedPnl, error)
+}
+
+type IMarginRepository interface {
+	InsertAccount(ctx context.Context, db DBExecutor, account *dto.MarginAccount) error
+	GetAccountByUserId(ctx context.Context, db DBExecutor, userId string) (*dto.MarginAccount, error)
+	GetAccountByMarginUserId(ctx context.Context, db DBExecutor, marginUserId string) (*dto.MarginAccount, error)
+	// GetAccountsWithLoans margin accounts having outstanding loans.
+	GetAccountsWithLoans(ctx context.Context, db DBExecutor) ([]*dto.MarginAccount, error)
+	GetLoansByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.MarginLoan, error)
+	// GetAccruingLoans loans of all users having outstanding principal.
+	GetAccruingLoans(ctx context.Context, db DBExecutor) ([]*dto.MarginLoan, error)
+	// IncreaseLoan add borrowed principal of user asset loan, interest accrues from now if no principal was outstanding.
+	IncreaseLoan(ctx context.Context, db DBExecutor, userId, asset string, principal float64) error
+	// DecreaseLoan repay interest and principal of user asset loan, return error if exceed outstanding amount.
+	DecreaseLoan(ctx context.Context, db DBExecutor, userId, asset string, principal, interest float64) error
+	// AccrueInterest add interest to user asset loan accrued up to accruedAt.
+	AccrueInterest(ctx context.Context, db DBExecutor, userId, asset string, interest float64, accruedAt time.Time) error
+	InsertLoanRecord(ctx context.Context, db DBExecutor, record *dto.MarginLoanRecord) error
+	GetLoanRecordsByUserId(ctx context.Context, db DBExecutor, userId string, limit int) ([]*dto.MarginLoanRecord, error)
+}
+
+type ILiquidationRepository interface {
+	InsertMarginCall(ctx context.Context, db DBExecutor, call *dto.MarginCall) error
+	GetMarginCallsByUserId(ctx context.Context, db DBExecutor, userId string, limit int) ([]*dto.MarginCall, error)
+	InsertLiquidation(ctx context.Context, db DBExecutor, liquidation *dto.Liquidation) error
+	// GetLiquidations latest liquidations, all users if userId is empty.
+	GetLiquidations(ctx context.Context, db DBExecutor, userId string, limit int) ([]*dto.Liquidation, error)
+}
+
+type IPerpetualRepository interface {
+	// GetPositionsByUserIds return user id -> position of market, users without position record are absent.
+	GetPositionsByUserIds(ctx context.Context, db DBExecutor, userIds []string, market string) (map[string]*dto.PerpetualPosition, error)
+	// GetPositionsByUserId positions with size or margin.
+	GetPositionsByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.PerpetualPosition, error)
+	// GetOpenPositionsByMarket positions with size of market.
+	GetOpenPositionsByMarket(ctx context.Context, db DBExecutor, market string) ([]*dto.PerpetualPosition, error)
+	UpsertPosition(ctx context.Context, db DBExecutor, position *dto.PerpetualPosition) error
+	InsertFundingRate(ctx context.Context, db DBExecutor, rate *dto.FundingRate) error
+	GetFundingRates(ctx context.Context, db DBExecutor, market string, limit int) ([]*dto.FundingRate, error)
+	InsertFundingPayment(ctx context.Context, db DBExecutor, payment *dto.FundingPayment) error
+	// GetFundingPaymentsByUserId latest funding payments, all markets if market is empty.
+	GetFundingPaymentsByUserId(ctx context.Context, db DBExecutor, userId, market string, limit int) ([]*dto.FundingPayment, error)
+	InsertLiquidation(ctx context.Context, db DBExecutor, liquidation *dto.PerpetualLiquidation) error
+	// GetLiquidationsByUserId latest liquidations, all markets if market is empty.
+	GetLiquidationsByUserId(ctx context.Context, db DBExecutor, userId, market string, limit int) ([]*dto.PerpetualLiquidation, error)
+	// InsertMarketHalt keep the first halt if market is already halted.
+	InsertMarketHalt(ctx context.Context, db DBExecutor, halt *dto.PerpetualMarketHalt) error
+	IsMarketHalted(ctx context.Context, db DBExecutor, market string) (bool, error)
+	// DeleteMarketHalt return error if market is not halted.
+	DeleteMarketHalt(ctx context.Context, db DBExecutor, market string) error
+}
+
+type IEquitySnapshotRepository interface {
+	// Upsert insert snapshot or overwrite the same user and date one, so snapshot job is safe to rerun.
+	Upsert(ctx context.Context, db DBExecutor, snapshot *dto.EquitySnapshot) error
+	// GetByUserIdSince query user snapshots which date >= sinceDate (2006-01-02) order by date asc.
+	GetByUserIdSince(ctx context.Context, db DBExecutor, userId string, sinceDate string) ([]*dto.EquitySnapshot, error)
+}
+
+type ILedgerRepository interface {
+	// GetEntriesByUserId query user balance movements derived from trades, withdrawals, transfers and referral commissions,
+	// order by time desc.
+	GetEntriesByUserId(ctx context.Context, db DBExecutor, userId string, from, to time.Time, limit, offset int) ([]*dto.LedgerEntry, error)
+}
+
+type IWithdrawalRepository interface {
+	Insert(ctx context.Context, db DBExecutor, withdrawal *dto.Withdrawal) error
+	GetWithdrawalById(ctx context.Context, db DBExecutor, withdrawalId string) (*dto.Withdrawal, error)
+	GetWithdrawalsByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.Withdrawal, error)
+	GetWithdrawalsByStatus(ctx context.Context, db DBExecutor, status dto.WithdrawalStatus) ([]*dto.Withdrawal, error)
+	// UpdateStatus update status only if current status == fromStatus, return error if not matched.
+	UpdateStatus(ctx context.Context, db DBExecutor, withdrawalId string, fromStatus, toStatus dto.WithdrawalStatus, txHash, remark string) error
+	// SumValuationSince sum user withdrawals valuation (exclude REJECTED) created after since.
+	SumValuationSince(ctx context.Context, db DBExecutor, userId string, since time.Time) (float64, error)
+	InsertAudit(ctx context.Context, db DBExecutor, audit *dto.WithdrawalAudit) error
+	GetAuditsByWithdrawalId(ctx context.Context, db DBExecutor, withdrawalId string) ([]*dto.WithdrawalAudit, error)
+}
+
+type IWithdrawalAddressRepository interface {
+	Insert(ctx context.Context, db DBExecutor, address *dto.WithdrawalAddress) error
+	GetAddressesByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.WithdrawalAddress, error)
+	GetAddressByUserIdAndAssetAndAddress(ctx context.Context, db DBExecutor, userId, asset, address string) (*dto.WithdrawalAddress, error)
+	Delete(ctx context.Context, db DBExecutor, userId string, addressId int64) error
+}
+
+type ITransferRepository interface {
+	Insert(ctx context.Context, db DBExecutor, transfer *dto.Transfer) error
+	GetTransferByIdempotencyKey(ctx context.Context, db DBExecutor, fromUserId, idempotencyKey string) (*dto.Transfer, error)
+	// GetTransfersByUserId get transfers which user is sender or receiver.
+	GetTransfersByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.Transfer, error)
+	// SumValuationSince sum valuation of transfers sent by user created after since.
+	SumValuationSince(ctx context.Context, db DBExecutor, fromUserId string, since time.Time) (float64, error)
+}
+
+type ISubAccountRepository interface {
+	Insert(ctx context.Context, db DBExecutor, subAccount *dto.SubAccount) error
+	GetSubAccountsByMasterUserId(ctx context.Context, db DBExecutor, masterUserId string) ([]*dto.SubAccount, error)
+	GetSubAccountBySubUserId(ctx context.Context, db DBExecutor, subUserId string) (*dto.SubAccount, error)
+}
+
+type IFeeScheduleRepository interface {
+	// GetFeeSchedules get all fee schedules order by min_volume asc.
+	GetFeeSchedules(ctx context.Context, db DBExecutor) ([]*dto.FeeSchedule, error)
+	InsertVipLevelHistory(ctx context.Context, db DBExecutor, history *dto.VipLevelHistory) error
+	GetVipLevelHistoriesByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.VipLevelHistory, error)
+}
+
+type IReferralRepository interface {
+	InsertCode(ctx context.Context, db DBExecutor, userId, code string) error
+	GetCodeByUserId(ctx context.Context, db DBExecutor, userId string) (string, error)
+	GetUserIdByCode(ctx context.Context, db DBExecutor, code string) (string, error)
+	InsertReferral(ctx context.Context, db DBExecutor, referrerId, refereeId string) error
+	CountReferees(ctx context.Context, db DBExecutor, referrerId string) (int64, error)
+	// GetReferrerIdsByRefereeIds return referee id -> referrer id, referees without referrer are absent.
+	GetReferrerIdsByRefereeIds(ctx context.Context, db DBExecutor, refereeIds []string) (map[string]string, error)
+	InsertCommission(ctx context.Context, db DBExecutor, commission *dto.ReferralCommission) error
+	GetCommissionsByReferrerId(ctx context.Context, db DBExecutor, referrerId string, limit int) ([]*dto.ReferralCommission, error)
+	SumCommissionsByReferrerId(ctx context.Context, db DBExecutor, referrerId string) ([]*dto.ReferralCommissionTotal, error)
+}
+
+type IFeeRevenueRepository interface {
+	Insert(ctx context.Context, db DBExecutor, revenue *dto.FeeRevenue) error
+	// SumByAssetBetween sum fee income and rebates group by asset, from <= created_at < to.
+	SumByAssetBetween(ctx context.Context, db DBExecutor, from, to time.Time) ([]*dto.FeeRevenueSummary, error)
+}
+
+type IConvertRepository interface {
+	InsertConvert(ctx context.Context, db DBExecutor, convert *dto.Convert) error
+	GetConvertsByUserId(ctx context.Context, db DBExecutor, userId string, limit int) ([]*dto.Convert, error)
+}
+
+type IRfqRepository interface {
+	InsertProvider(ctx context.Context, db DBExecutor, provider *dto.RfqProvider) error
+	DeleteProvider(ctx context.Context, db DBExecutor, userId string) error
+	GetProviders(ctx context.Context, db DBExecutor) ([]*dto.RfqProvider, error)
+	IsProvider(ctx context.Context, db DBExecutor, userId string) (bool, error)
+	InsertRequest(ctx context.Context, db DBExecutor, request *dto.RfqRequest) error
+	GetRequestById(ctx context.Context, db DBExecutor, requestId string) (*dto.RfqRequest, error)
+	GetRequestsByUserId(ctx context.Context, db DBExecutor, userId string, limit int) ([]*dto.RfqRequest, error)
+	// GetOpenRequests OPEN requests not expired at now.
+	GetOpenRequests(ctx context.Context, db DBExecutor, now time.Time) ([]*dto.RfqRequest, error)
+	// GetExpiredOpenRequests OPEN requests expired at now.
+	GetExpiredOpenRequests(ctx context.Context, db DBExecutor, now time.Time) ([]*dto.RfqRequest, error)
+	// UpdateRequestStatus update status only if current status == fromStatus, return error if not matched.
+	UpdateRequestStatus(ctx context.Context, db DBExecutor, requestId string, fromStatus, toStatus dto.RfqRequestStatus, acceptedQuoteId string) error
+	InsertQuote(ctx context.Context, db DBExecutor, quote *dto.RfqQuote) error
+	GetQuoteById(ctx context.Context, db DBExecutor, quoteId string) (*dto.RfqQuote, error)
+	GetQuotesByRequestId(ctx context.Context, db DBExecutor, requestId string) ([]*dto.RfqQuote, error)
+	GetQuotesByProviderId(ctx context.Context, db DBExecutor, providerId string, limit int) ([]*dto.RfqQuote, error)
+	// UpdateQuoteStatus update status only if current status == fromStatus, return error if not matched.
+	UpdateQuoteStatus(ctx context.Context, db DBExecutor, quoteId string, fromStatus, toStatus dto.RfqQuoteStatus) error
+}
+
+type IApiKeyRepository interface {
+	Insert(ctx context.Context, db DBExecutor, apiKey *dto.ApiKey) error
+	GetApiKey(ctx context.Context, db DBExecutor, apiKey string) (*dto.ApiKey, error)
+	GetApiKeysByUserId(ctx context.Context, db DBExecutor, userId string) ([]*dto.ApiKey, error)
+	CountActiveByUserId(ctx context.Context, db DBExecutor, userId string) (int64, error)
+	UpdateLabel(ctx context.Context, db DBExecutor, userId, apiKey, label string) error
+	// Revoke revoke key only if it is not revoked, return error if not matched.
+	Revoke(ctx context.Context, db DBExecutor, userId, apiKey string) error
+	UpdateLastUsedAt(ctx context.Context, db DBExecutor, apiKey string, lastUsedAt time.Time) error
+}
+
+type ISessionRepository interface {
+	Insert(ctx context.Context, db DBExecutor, session *dto.Session) error
+	GetByTokenHash(ctx context.Context, db DBExecutor, tokenHash string) (*dto.Session, error)
+	GetByRefreshTokenHash(ctx context.Context, db DBExecutor, refreshTokenHash string) (*dto.Session, error)
+	// GetActiveSessionsByUserId sessions with refresh token not expired at now.
+	GetActiveSessionsByUserId(ctx context.Context, db DBExecutor, userId string, now time.Time) ([]*dto.Session, error)
+	UpdateLastActiveAt(ctx context.Context, db DBExecutor, sessionId string, lastActiveAt time.Time) error
+	// Rotate replace tokens only if refresh token is still fromRefreshTokenHash, return error if not matched.
+	Rotate(ctx context.Context, db DBExecutor, session *dto.Session, fromRefreshTokenHash string) error
+	DeleteByTokenHash(ctx context.Context, db DBExecutor, tokenHash string) error
+	DeleteByUserId(ctx context.Context, db DBExecutor, userId string) (int64, error)
+	// DeleteByUserIdExcept delete sessions of user except the one of tokenHash.
+	DeleteByUserIdExcept(ctx context.Context, db DBExecutor, userId, tokenHash string) (int64, error)
+	// DeleteExpired delete sessions with refresh token expired at now.
+	DeleteExpired(ctx context.Context, db DBExecutor, now time.Time) (int64, error)
+}
+
+// IPasswordResetRepository at most one reset token per user, token row is deleted when used.
+type IPasswordResetRepository interface {
+	Insert(ctx context.Context, db DBExecutor, resetToken *dto.PasswordResetToken) error
+	GetByTokenHash(ctx context.Context, db DBExecutor, tokenHash string) (*dto.PasswordResetToken, error)
+	GetByUserId(ctx context.Context, db DBExecutor, userId string) (*dto.PasswordResetToken, error)
+	// DeleteByTokenHash return deleted count, 0 if token is already used.
+	DeleteByTokenHash(ctx context.Context, db DBExecutor, tokenHash string) (int64, error)
+	DeleteByUserId(ctx context.Context, db DBExecutor, userId string) error
+}
+
+type IAdminRepository interface {
+	Insert(ctx context.Context, db DBExecutor, admin *dto.Admin) error
+	GetAdminById(ctx context.Context, db DBExecutor, adminId string) (*dto.Admin, error)
+	GetAdminByUsername(ctx context.Context, db DBExecutor, username string) (*dto.Admin, error)
+	GetAdmins(ctx context.Context, db DBExecutor) ([]*dto.Admin, error)
+	CountAdmins(ctx context.Context, db DBExecutor) (int64, error)
+	// CountActiveByRole count not disabled admins of role.
+	CountActiveByRole(ctx context.Context, db DBExecutor, role dto.AdminRole) (int64, error)
+	UpdateRoleAndDisabled(ctx context.Context, db DBExecutor, admin *dto.Admin) error
+	UpdatePasswordHash(ctx context.Context, db DBExecutor, adminId, passwordHash string) error
+	UpdateTotpSecret(ctx context.Context, db DBExecutor, adminId, totpSecret string) error
+	InsertManualAdjustment(ctx context.Context, db DBExecutor, adjustment *dto.ManualAdjustment) error
+	// GetManualAdjustments latest adjustments, all users if userId is empty.
+	GetManualAdjustments(ctx context.Context, db DBExecutor, userId string, limit int) ([]*dto.ManualAdjustment, error)
+}
+
+// IAuditLogRepository append-only, there is no update or delete.
+type IAuditLogRepository interface {
+	Insert(ctx context.Context, db DBExecutor, auditLog *dto.AuditLog) error
+	// GetLastHash hash of latest row, empty if there is no row.
+	GetLastHash(ctx context.Context, db DBExecutor) (string, error)
+	// GetAuditLogs latest rows created in [from, to), empty filters of req match all.
+	GetAuditLogs(ctx context.Context, db DBExecutor, req *dto.AuditLogQueryReq, from, to time.Time, limit int) ([]*dto.AuditLog, error)
+	// GetAuditLogsAfterId rows in id order, for verifying hash chain by batches.
+	GetAuditLogsAfterId(ctx context.Context, db DBExecutor, afterId int64, limit int) ([]*dto.AuditLog, error)
 }
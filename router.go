@@ -4,18 +4,25 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/johnny1110/crypto-exchange/container"
 	"github.com/johnny1110/crypto-exchange/controller"
+	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/middleware"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 )
 
 func setupRouter(c *container.Container) *gin.Engine {
 	router := gin.Default()
+	// client IP of X-Forwarded-For is only trusted from configured reverse proxies.
+	if err := router.SetTrustedProxies(settings.TRUSTED_PROXIES); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
 
 	// add middleware
 	router.Use(middleware.CORS())
+	router.Use(middleware.RequestMeta())
 	router.Use(middleware.ErrorHandler())
-	router.Use(middleware.RateLimitMiddleware())
 
 	// create controller
 	userController := controller.NewUserController(c.UserService)
@@ -24,10 +31,25 @@ func setupRouter(c *container.Container) *gin.Engine {
 	adminController := controller.NewAdminController(c.AdminService)
 	orderBookController := controller.NewOrderBookController(c.OrderBookService)
 	marketDataController := controller.NewMarketDataController(c.MarketDataService)
+	withdrawalController := controller.NewWithdrawalController(c.WithdrawalService)
+	transferController := controller.NewTransferController(c.TransferService)
+	referralController := controller.NewReferralController(c.ReferralService)
+	statementController := controller.NewStatementController(c.StatementService)
+	portfolioController := controller.NewPortfolioController(c.PortfolioService)
+	marginController := controller.NewMarginController(c.MarginService, c.LiquidationService)
+	perpetualController := controller.NewPerpetualController(c.PerpetualService)
+	convertController := controller.NewConvertController(c.ConvertService)
+	rfqController := controller.NewRfqController(c.RfqService)
+	apiKeyController := controller.NewApiKeyController(c.ApiKeyService)
+	adminAccountController := controller.NewAdminAccountController(c.AdminAccountService)
+	twoFactorController := controller.NewTwoFactorController(c.TwoFactorService)
+	auditLogController := controller.NewAuditLogController(c.AuditService)
 
 	// setup routes
 	setupRoutes(router, c, userController, balanceController, orderController,
-		adminController, orderBookController, marketDataController)
+		adminController, orderBookController, marketDataController, withdrawalController, transferController,
+		referralController, statementController, portfolioController, marginController, perpetualController,
+		convertController, rfqController, apiKeyController, adminAccountController, twoFactorController, auditLogController)
 
 	return router
 }
@@ -41,6 +63,19 @@ func setupRoutes(
 	adminController *controller.AdminController,
 	orderBookController *controller.OrderBookController,
 	marketDataController *controller.MarketDataController,
+	withdrawalController *controller.WithdrawalController,
+	transferController *controller.TransferController,
+	referralController *controller.ReferralController,
+	statementController *controller.StatementController,
+	portfolioController *controller.PortfolioController,
+	marginController *controller.MarginController,
+	perpetualController *controller.PerpetualController,
+	convertController *controller.ConvertController,
+	rfqController *controller.RfqController,
+	apiKeyController *controller.ApiKeyController,
+	adminAccountController *controller.AdminAccountController,
+	twoFactorController *controller.TwoFactorController,
+	auditLogController *controller.AuditLogController,
 ) {
 	// Health check
 	router.GET("/health", func(ctx *gin.Context) {
@@ -54,37 +89,155 @@ func setupRoutes(
 
 	// Public router
 	public := router.Group("/api/v1")
+	public.Use(middleware.RateLimitMiddleware(c.IPRateLimiter))
 	{
 		// user etc.
 		public.POST("/users/register", userController.Register)
 		public.POST("/users/login", userController.Login)
+		public.POST("/users/refresh-token", userController.RefreshToken)
+		public.POST("/users/password-reset/request", userController.RequestPasswordReset)
+		public.POST("/users/password-reset/confirm", userController.ResetPassword)
 		public.GET("/orderbooks/:market/snapshot", orderBookController.OrderbooksSnapshot)
 		public.GET("/markets", marketDataController.GetAllMarketsData)
 		public.GET("/markets/:market", marketDataController.GetMarketsData)
 		public.GET("/markets/:market/ohlcv-history/:interval", marketDataController.GetOHLCVHistory)
+		public.GET("/markets/:market/trades", marketDataController.GetRecentTrades)
+		public.GET("/perpetuals/markets", perpetualController.GetMarkets)
+		public.GET("/perpetuals/funding-rates", perpetualController.GetFundingRates)
 	}
 
 	// Auth router
 	private := router.Group("/api/v1")
-	private.Use(middleware.AuthMiddleware(c.CredentialCache))
+	private.Use(middleware.ApiKeyMiddleware(c.ApiKeyService))
+	private.Use(middleware.AuthMiddleware(c.SessionStore))
+	private.Use(middleware.UserRateLimitMiddleware(c.UserRateLimiter))
 	{
 		// users
 		private.GET("/users/profile", userController.GetProfile)
 		private.POST("/users/logout", userController.Logout)
+		private.POST("/users/logout-all", userController.LogoutAll)
+		private.GET("/users/sessions", userController.GetSessions)
+		private.PUT("/users/password", userController.ChangePassword)
+		private.PUT("/users/fee-settings", userController.UpdateFeeSettings)
+		private.POST("/users/2fa/enroll", twoFactorController.Enroll)
+		private.POST("/users/2fa/enable", twoFactorController.Enable)
+		private.POST("/users/2fa/disable", twoFactorController.Disable)
+		private.POST("/users/2fa/backup-codes", twoFactorController.RegenerateBackupCodes)
+		// api keys
+		private.POST("/api-keys", apiKeyController.CreateApiKey)
+		private.GET("/api-keys", apiKeyController.GetApiKeys)
+		private.PUT("/api-keys/:apiKey", apiKeyController.UpdateLabel)
+		private.DELETE("/api-keys/:apiKey", apiKeyController.RevokeApiKey)
 		// balances
 		private.GET("/balances", balanceController.GetBalances)
+		// portfolio
+		private.GET("/portfolio", portfolioController.GetPortfolio)
+		private.GET("/portfolio/pnl", portfolioController.GetPnl)
+		private.GET("/portfolio/equity-history", portfolioController.GetEquityHistory)
+		// margin
+		private.GET("/margin/account", marginController.GetAccount)
+		private.POST("/margin/transfer", marginController.Transfer)
+		private.POST("/margin/borrow", marginController.Borrow)
+		private.POST("/margin/repay", marginController.Repay)
+		private.GET("/margin/loans/records", marginController.GetLoanRecords)
+		private.GET("/margin/calls", marginController.GetMarginCalls)
+		private.GET("/margin/liquidations", marginController.GetLiquidations)
+		// perpetuals
+		private.GET("/perpetuals/positions", perpetualController.GetPositions)
+		private.GET("/perpetuals/funding-payments", perpetualController.GetFundingPayments)
+		private.GET("/perpetuals/liquidations", perpetualController.GetLiquidations)
+		// convert
+		private.POST("/convert/quote", convertController.Quote)
+		private.POST("/convert/accept", convertController.Accept)
+		private.GET("/convert/history", convertController.GetHistory)
+		// rfq (taker)
+		private.POST("/rfq/requests", rfqController.CreateRequest)
+		private.GET("/rfq/requests", rfqController.GetRequests)
+		private.GET("/rfq/requests/:requestId", rfqController.GetRequest)
+		private.DELETE("/rfq/requests/:requestId", rfqController.CancelRequest)
+		private.POST("/rfq/requests/:requestId/accept", rfqController.Accept)
+		// rfq (liquidity provider)
+		private.GET("/rfq/open-requests", rfqController.GetOpenRequests)
+		private.POST("/rfq/requests/:requestId/quotes", rfqController.SubmitQuote)
+		private.GET("/rfq/quotes", rfqController.GetQuotes)
+		private.DELETE("/rfq/quotes/:quoteId", rfqController.CancelQuote)
 		// orders
-		private.POST("/orders/:market", orderController.PlaceOrder)
+		private.POST("/orders/:market", middleware.OrderRateLimitMiddleware(c.OrderRateLimiter), orderController.PlaceOrder)
 		private.DELETE("/orders/:orderId", orderController.CancelOrder)
 		private.GET("/orders", orderController.GetOrders)
+		private.GET("/fills", orderController.GetFills)
+		private.GET("/statements", statementController.ExportStatement)
+		// withdrawals
+		private.POST("/withdrawals", withdrawalController.Withdraw)
+		private.GET("/withdrawals", withdrawalController.GetWithdrawals)
+		private.POST("/withdrawals/addresses", withdrawalController.AddAddress)
+		private.GET("/withdrawals/addresses", withdrawalController.GetAddresses)
+		private.DELETE("/withdrawals/addresses/:addressId", withdrawalController.RemoveAddress)
+		// transfers
+		private.POST("/transfers", transferController.Transfer)
+		private.GET("/transfers", transferController.GetTransfers)
+		// sub-accounts
+		private.POST("/sub-accounts", transferController.CreateSubAccount)
+		private.GET("/sub-accounts", transferController.GetSubAccounts)
+		private.POST("/sub-accounts/transfers", transferController.SubAccountTransfer)
+		private.GET("/sub-accounts/balances", transferController.GetConsolidatedBalances)
+		// referrals
+		private.GET("/referrals", referralController.GetReferralInfo)
+		private.GET("/referrals/commissions", referralController.GetCommissions)
+		private.GET("/referrals/commissions/totals", referralController.GetCommissionTotals)
+
+	}
 
+	// Admin public router
+	adminPublic := router.Group("/admin/api/v1")
+	adminPublic.Use(middleware.RateLimitMiddleware(c.IPRateLimiter))
+	{
+		adminPublic.POST("/login", adminAccountController.Login)
 	}
 
 	// Admin router
+	read := middleware.AdminPermission(dto.ADMIN_PERMISSION_READ)
+	funds := middleware.AdminPermission(dto.ADMIN_PERMISSION_FUNDS)
+	markets := middleware.AdminPermission(dto.ADMIN_PERMISSION_MARKETS)
+	admins := middleware.AdminPermission(dto.ADMIN_PERMISSION_ADMINS)
+
 	admin := router.Group("/admin/api/v1")
-	admin.Use(middleware.AdminMiddleware())
+	admin.Use(middleware.AdminMiddleware(c.AdminSessionCache))
 	{
-		admin.POST("/manual-adjustment", adminController.ManualAdjustment)
-		admin.POST("/test-make-market", adminController.TestMakeMarket)
+		// admin accounts
+		admin.POST("/logout", adminAccountController.Logout)
+		admin.GET("/admins/me", adminAccountController.GetMe)
+		admin.PUT("/admins/me/password", adminAccountController.ChangePassword)
+		admin.POST("/admins/me/totp", adminAccountController.RotateTotp)
+		admin.GET("/admins", admins, adminAccountController.GetAdmins)
+		admin.POST("/admins", admins, adminAccountController.CreateAdmin)
+		admin.PUT("/admins/:adminId", admins, adminAccountController.UpdateAdmin)
+
+		admin.POST("/manual-adjustment", funds, adminController.ManualAdjustment)
+		admin.GET("/manual-adjustments", read, adminController.GetManualAdjustments)
+		admin.POST("/test-make-market", markets, adminController.TestMakeMarket)
+		// withdrawals review
+		admin.GET("/withdrawals", read, adminController.GetWithdrawals)
+		admin.GET("/withdrawals/:withdrawalId/audits", read, adminController.GetWithdrawalAudits)
+		admin.POST("/withdrawals/:withdrawalId/approve", funds, adminController.ApproveWithdrawal)
+		admin.POST("/withdrawals/:withdrawalId/reject", funds, adminController.RejectWithdrawal)
+		admin.POST("/withdrawals/:withdrawalId/broadcast", funds, adminController.BroadcastWithdrawal)
+		admin.POST("/withdrawals/:withdrawalId/confirm", funds, adminController.ConfirmWithdrawal)
+		// account status
+		admin.GET("/users/:userId/status", read, adminController.GetAccountStatus)
+		admin.PUT("/users/:userId/status", funds, adminController.UpdateAccountStatus)
+		// fees
+		admin.POST("/users/market-maker-tier", funds, adminController.AssignMarketMakerTier)
+		admin.GET("/fees/revenues", read, adminController.GetFeeRevenues)
+		admin.GET("/margin/liquidations", read, adminController.GetLiquidations)
+		// rfq
+		admin.GET("/rfq/providers", read, rfqController.GetProviders)
+		admin.POST("/rfq/providers", markets, rfqController.AddProvider)
+		admin.DELETE("/rfq/providers/:userId", markets, rfqController.RemoveProvider)
+		// perpetuals
+		admin.POST("/perpetuals/:market/resume", markets, perpetualController.ResumeMarket)
+		// audit logs
+		admin.GET("/audit-logs", read, auditLogController.GetAuditLogs)
+		admin.GET("/audit-logs/verify", read, auditLogController.VerifyChain)
 	}
 }
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// EquitySnapshotScheduler snapshot all users total equity every day at 00:00 UTC.
+type EquitySnapshotScheduler struct {
+	portfolioService service.IPortfolioService
+	timer            *time.Timer
+	stopCh           chan struct{}
+
+	runTimes int64
+	mu       sync.RWMutex //RW mutex
+}
+
+func NewEquitySnapshotScheduler(portfolioService service.IPortfolioService) Scheduler {
+	return &EquitySnapshotScheduler{
+		portfolioService: portfolioService,
+		stopCh:           make(chan struct{}),
+
+		runTimes: 0,
+	}
+}
+
+func (s *EquitySnapshotScheduler) Name() string {
+	return "EquitySnapshotScheduler"
+}
+
+func (s *EquitySnapshotScheduler) RunTimes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runTimes
+}
+
+func (s *EquitySnapshotScheduler) countRunTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runTimes += 1
+	log.Debugf("[EquitySnapshotScheduler] run time count: %d]", s.runTimes)
+}
+
+func (s *EquitySnapshotScheduler) Start() error {
+	s.timer = time.NewTimer(untilNextUTCMidnight(time.Now()))
+	log.Infof("[EquitySnapshotScheduler] started, next run at: %v", nextUTCMidnight(time.Now()))
+
+	go func() {
+		for {
+			select {
+			case <-s.timer.C:
+				s.snapshot()
+				s.timer.Reset(untilNextUTCMidnight(time.Now()))
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *EquitySnapshotScheduler) Stop() error {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	close(s.stopCh)
+	log.Info("[EquitySnapshotScheduler] stopped")
+	return nil
+}
+
+func (s *EquitySnapshotScheduler) snapshot() {
+	s.countRunTime()
+	if err := s.portfolioService.SnapshotAllEquities(context.Background()); err != nil {
+		log.Errorf("[EquitySnapshotScheduler] snapshot failed, error: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// FeeTierScheduler recalculate users vip level every day at 00:00 UTC.
+type FeeTierScheduler struct {
+	feeTierService service.IFeeTierService
+	timer          *time.Timer
+	stopCh         chan struct{}
+
+	runTimes int64
+	mu       sync.RWMutex //RW mutex
+}
+
+func NewFeeTierScheduler(feeTierService service.IFeeTierService) Scheduler {
+	return &FeeTierScheduler{
+		feeTierService: feeTierService,
+		stopCh:         make(chan struct{}),
+
+		runTimes: 0,
+	}
+}
+
+func (s *FeeTierScheduler) Name() string {
+	return "FeeTierScheduler"
+}
+
+func (s *FeeTierScheduler) RunTimes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runTimes
+}
+
+func (s *FeeTierScheduler) countRunTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runTimes += 1
+	log.Debugf("[FeeTierScheduler] run time count: %d]", s.runTimes)
+}
+
+func (s *FeeTierScheduler) Start() error {
+	s.timer = time.NewTimer(untilNextUTCMidnight(time.Now()))
+	log.Infof("[FeeTierScheduler] started, next run at: %v", nextUTCMidnight(time.Now()))
+
+	go func() {
+		for {
+			select {
+			case <-s.timer.C:
+				s.recalculate()
+				s.timer.Reset(untilNextUTCMidnight(time.Now()))
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *FeeTierScheduler) Stop() error {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	close(s.stopCh)
+	log.Info("[FeeTierScheduler] stopped")
+	return nil
+}
+
+func (s *FeeTierScheduler) recalculate() {
+	s.countRunTime()
+	if err := s.feeTierService.RecalculateAll(context.Background()); err != nil {
+		log.Errorf("[FeeTierScheduler] recalculate failed, error: %v", err)
+	}
+}
+
+func nextUTCMidnight(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+func untilNextUTCMidnight(now time.Time) time.Duration {
+	return nextUTCMidnight(now).Sub(now)
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// FundingScheduler settle perpetual funding every settings.PERPETUAL_FUNDING_INTERVAL (00:00, 08:00, 16:00 UTC).
+type FundingScheduler struct {
+	perpetualService service.IPerpetualService
+	timer            *time.Timer
+	stopCh           chan struct{}
+
+	runTimes int64
+	mu       sync.RWMutex //RW mutex
+}
+
+func NewFundingScheduler(perpetualService service.IPerpetualService) Scheduler {
+	return &FundingScheduler{
+		perpetualService: perpetualService,
+		stopCh:           make(chan struct{}),
+
+		runTimes: 0,
+	}
+}
+
+func (s *FundingScheduler) Name() string {
+	return "FundingScheduler"
+}
+
+func (s *FundingScheduler) RunTimes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runTimes
+}
+
+func (s *FundingScheduler) countRunTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runTimes += 1
+	log.Debugf("[FundingScheduler] run time count: %d]", s.runTimes)
+}
+
+func (s *FundingScheduler) Start() error {
+	s.timer = time.NewTimer(time.Until(serviceHelper.NextFundingTime(time.Now())))
+	log.Infof("[FundingScheduler] started, next run at: %v", serviceHelper.NextFundingTime(time.Now()))
+
+	go func() {
+		for {
+			select {
+			case <-s.timer.C:
+				s.settle()
+				s.timer.Reset(time.Until(serviceHelper.NextFundingTime(time.Now())))
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *FundingScheduler) Stop() error {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	close(s.stopCh)
+	log.Info("[FundingScheduler] stopped")
+	return nil
+}
+
+func (s *FundingScheduler) settle() {
+	s.countRunTime()
+	if err := s.perpetualService.SettleFunding(context.Background()); err != nil {
+		log.Errorf("[FundingScheduler] settle funding failed, error: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// LiquidationScheduler check all margin accounts risk every duration, issue margin calls and liquidate accounts
+// lower than maintenance ratio, then liquidate perpetual positions under maintenance margin rate.
+type LiquidationScheduler struct {
+	liquidationService service.ILiquidationService
+	perpetualService   service.IPerpetualService
+	ticker             *time.Ticker
+	stopCh             chan struct{}
+	duration           time.Duration
+
+	runTimes int64
+	mu       sync.RWMutex //RW mutex
+}
+
+func NewLiquidationScheduler(liquidationService service.ILiquidationService, perpetualService service.IPerpetualService, duration time.Duration) Scheduler {
+	return &LiquidationScheduler{
+		liquidationService: liquidationService,
+		perpetualService:   perpetualService,
+		stopCh:             make(chan struct{}),
+		duration:           duration,
+
+		runTimes: 0,
+	}
+}
+
+func (s *LiquidationScheduler) Name() string {
+	return "LiquidationScheduler"
+}
+
+func (s *LiquidationScheduler) RunTimes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runTimes
+}
+
+func (s *LiquidationScheduler) countRunTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runTimes += 1
+	log.Debugf("[LiquidationScheduler] run time count: %d]", s.runTimes)
+}
+
+func (s *LiquidationScheduler) Start() error {
+	s.ticker = time.NewTicker(s.duration)
+	log.Infof("[LiquidationScheduler] started, check margin risk every %v", s.duration)
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.check()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *LiquidationScheduler) Stop() error {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	close(s.stopCh)
+	log.Info("[LiquidationScheduler] stopped")
+	return nil
+}
+
+func (s *LiquidationScheduler) check() {
+	s.countRunTime()
+	if err := s.liquidationService.CheckAll(context.Background()); err != nil {
+		log.Errorf("[LiquidationScheduler] check margin risk failed, error: %v", err)
+	}
+	if err := s.perpetualService.LiquidatePositions(context.Background()); err != nil {
+		log.Errorf("[LiquidationScheduler] liquidate perpetual positions failed, error: %v", err)
+	}
+}
@@ -61,6 +61,10 @@ func (L *LQDTScheduler) Start() error {
 		for range ticker.C {
 			L.countRunTime()
 			for _, marketInfo := range settings.ALL_MARKETS {
+				// AMM quotes spot markets by spot balances only
+				if marketInfo.IsPerpetual() {
+					continue
+				}
 				maxQuoteAmtPerLevel, ok := settings.MAX_QUOTE_AMT_PER_LEVEL_MAP[marketInfo.Name]
 				if !ok {
 					log.Warnf("[LQDTScheduler] no found maxQuoteAmtPerLevel param for market: %s, using default 1 USDT", marketInfo.Name)
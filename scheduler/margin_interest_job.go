@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// MarginInterestScheduler accrue margin loans interest every duration (hourly), also run at start up to catch up
+// hours missed while stopped (loans are accrued by their own accrued time, never charged twice).
+type MarginInterestScheduler struct {
+	marginService service.IMarginService
+	ticker        *time.Ticker
+	stopCh        chan struct{}
+	duration      time.Duration
+
+	runTimes int64
+	mu       sync.RWMutex //RW mutex
+}
+
+func NewMarginInterestScheduler(marginService service.IMarginService, duration time.Duration) Scheduler {
+	return &MarginInterestScheduler{
+		marginService: marginService,
+		stopCh:        make(chan struct{}),
+		duration:      duration,
+
+		runTimes: 0,
+	}
+}
+
+func (s *MarginInterestScheduler) Name() string {
+	return "MarginInterestScheduler"
+}
+
+func (s *MarginInterestScheduler) RunTimes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runTimes
+}
+
+func (s *MarginInterestScheduler) countRunTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runTimes += 1
+	log.Debugf("[MarginInterestScheduler] run time count: %d]", s.runTimes)
+}
+
+func (s *MarginInterestScheduler) Start() error {
+	s.ticker = time.NewTicker(s.duration)
+	log.Infof("[MarginInterestScheduler] started, accrue interest every %v", s.duration)
+
+	go func() {
+		s.accrue()
+		for {
+			select {
+			case <-s.ticker.C:
+				s.accrue()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *MarginInterestScheduler) Stop() error {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	close(s.stopCh)
+	log.Info("[MarginInterestScheduler] stopped")
+	return nil
+}
+
+func (s *MarginInterestScheduler) accrue() {
+	s.countRunTime()
+	if err := s.marginService.AccrueInterest(context.Background()); err != nil {
+		log.Errorf("[MarginInterestScheduler] accrue interest failed, error: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// RfqExpireScheduler expire RFQ requests over ttl every duration and release providers' locked funds.
+type RfqExpireScheduler struct {
+	rfqService service.IRfqService
+	ticker     *time.Ticker
+	stopCh     chan struct{}
+	duration   time.Duration
+
+	runTimes int64
+	mu       sync.RWMutex //RW mutex
+}
+
+func NewRfqExpireScheduler(rfqService service.IRfqService, duration time.Duration) Scheduler {
+	return &RfqExpireScheduler{
+		rfqService: rfqService,
+		stopCh:     make(chan struct{}),
+		duration:   duration,
+
+		runTimes: 0,
+	}
+}
+
+func (s *RfqExpireScheduler) Name() string {
+	return "RfqExpireScheduler"
+}
+
+func (s *RfqExpireScheduler) RunTimes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runTimes
+}
+
+func (s *RfqExpireScheduler) countRunTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runTimes += 1
+	log.Debugf("[RfqExpireScheduler] run time count: %d]", s.runTimes)
+}
+
+func (s *RfqExpireScheduler) Start() error {
+	s.ticker = time.NewTicker(s.duration)
+	log.Infof("[RfqExpireScheduler] started, expire rfq requests every %v", s.duration)
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.expire()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *RfqExpireScheduler) Stop() error {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	close(s.stopCh)
+	log.Info("[RfqExpireScheduler] stopped")
+	return nil
+}
+
+func (s *RfqExpireScheduler) expire() {
+	s.countRunTime()
+	if err := s.rfqService.ExpireRequests(context.Background()); err != nil {
+		log.Errorf("[RfqExpireScheduler] expire rfq requests failed, error: %v", err)
+	}
+}
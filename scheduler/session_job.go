@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// SessionCleanupScheduler delete sessions can not be refreshed anymore every duration.
+type SessionCleanupScheduler struct {
+	sessionStore security.SessionStore
+	ticker       *time.Ticker
+	stopCh       chan struct{}
+	duration     time.Duration
+
+	runTimes int64
+	mu       sync.RWMutex //RW mutex
+}
+
+func NewSessionCleanupScheduler(sessionStore security.SessionStore, duration time.Duration) Scheduler {
+	return &SessionCleanupScheduler{
+		sessionStore: sessionStore,
+		stopCh:       make(chan struct{}),
+		duration:     duration,
+
+		runTimes: 0,
+	}
+}
+
+func (s *SessionCleanupScheduler) Name() string {
+	return "SessionCleanupScheduler"
+}
+
+func (s *SessionCleanupScheduler) RunTimes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runTimes
+}
+
+func (s *SessionCleanupScheduler) countRunTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runTimes += 1
+	log.Debugf("[SessionCleanupScheduler] run time count: %d]", s.runTimes)
+}
+
+func (s *SessionCleanupScheduler) Start() error {
+	s.ticker = time.NewTicker(s.duration)
+	log.Infof("[SessionCleanupScheduler] started, delete expired sessions every %v", s.duration)
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.cleanup()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *SessionCleanupScheduler) Stop() error {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	close(s.stopCh)
+	log.Info("[SessionCleanupScheduler] stopped")
+	return nil
+}
+
+func (s *SessionCleanupScheduler) cleanup() {
+	s.countRunTime()
+	deleted, err := s.sessionStore.DeleteExpired(context.Background())
+	if err != nil {
+		log.Errorf("[SessionCleanupScheduler] delete expired sessions failed, error: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Infof("[SessionCleanupScheduler] deleted %d expired sessions", deleted)
+	}
+}
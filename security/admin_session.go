@@ -0,0 +1,95 @@
+package security
+
+import (
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"sync"
+	"time"
+)
+
+var ErrInvalidAdminSession = errors.New("invalid or expired admin token")
+
+type adminSession struct {
+	admin     *dto.Admin
+	expiresAt time.Time
+}
+
+// AdminSessionCache admin login tokens, kept in memory so admins log in again after restart.
+type AdminSessionCache struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	// token hash: session
+	sessions map[string]*adminSession
+}
+
+func NewAdminSessionCache(ttl time.Duration) *AdminSessionCache {
+	return &AdminSessionCache{
+		ttl:      ttl,
+		sessions: make(map[string]*adminSession),
+	}
+}
+
+// Create issue token of admin.
+func (c *AdminSessionCache) Create(admin *dto.Admin) (string, time.Time, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for tokenHash, session := range c.sessions {
+		if !now.Before(session.expiresAt) {
+			delete(c.sessions, tokenHash)
+		}
+	}
+
+	expiresAt := now.Add(c.ttl)
+	c.sessions[HashToken(token)] = &adminSession{admin: admin, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+func (c *AdminSessionCache) Get(token string) (*dto.Admin, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, ok := c.sessions[HashToken(token)]
+	if !ok || !time.Now().Before(session.expiresAt) {
+		return nil, ErrInvalidAdminSession
+	}
+	return session.admin, nil
+}
+
+func (c *AdminSessionCache) Delete(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.sessions, HashToken(token))
+}
+
+// DeleteOthers log out admin everywhere except session of token, e.g. password changed.
+func (c *AdminSessionCache) DeleteOthers(adminId, token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := HashToken(token)
+	for tokenHash, session := range c.sessions {
+		if session.admin.ID == adminId && tokenHash != current {
+			delete(c.sessions, tokenHash)
+		}
+	}
+}
+
+// DeleteByAdminId log out admin everywhere, e.g. role changed or disabled.
+func (c *AdminSessionCache) DeleteByAdminId(adminId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for tokenHash, session := range c.sessions {
+		if session.admin.ID == adminId {
+			delete(c.sessions, tokenHash)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GenerateApiKey random hex key and secret.
+func GenerateApiKey() (apiKey, secret string, err error) {
+	keyBytes := make([]byte, 16)
+	if _, err = rand.Read(keyBytes); err != nil {
+		return "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(keyBytes), hex.EncodeToString(secretBytes), nil
+}
+
+// SignRequest hex HMAC-SHA256 of timestamp + method + path + body, path includes query string.
+func SignRequest(secret, timestamp, method, path, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + method + path + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifySignature(secret, timestamp, method, path, body, signature string) bool {
+	expected := SignRequest(secret, timestamp, method, path, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// ReplayGuard reject signed requests out of receive window or seen within it.
+type ReplayGuard struct {
+	mu         sync.Mutex
+	recvWindow time.Duration
+	// signature: request timestamp
+	seen map[string]time.Time
+}
+
+func NewReplayGuard(recvWindow time.Duration) *ReplayGuard {
+	return &ReplayGuard{
+		recvWindow: recvWindow,
+		seen:       make(map[string]time.Time),
+	}
+}
+
+// Check validate timestamp (unix milliseconds) and remember signature, call it only with verified signature.
+func (g *ReplayGuard) Check(timestamp, signature string, now time.Time) error {
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	requestTime := time.UnixMilli(ms)
+	if requestTime.Before(now.Add(-g.recvWindow)) || requestTime.After(now.Add(g.recvWindow)) {
+		return errors.New("timestamp out of receive window")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for seenSignature, seenTime := range g.seen {
+		if seenTime.Before(now.Add(-g.recvWindow)) {
+			delete(g.seen, seenSignature)
+		}
+	}
+	if _, ok := g.seen[signature]; ok {
+		return errors.New("replayed request")
+	}
+	g.seen[signature] = requestTime
+	return nil
+}
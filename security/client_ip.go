@@ -0,0 +1,51 @@
+package security
+
+import (
+	"github.com/johnny1110/crypto-exchange/settings"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP peer address of request. X-Forwarded-For is only honored if peer is one of settings.TRUSTED_PROXIES,
+// then client is the right-most address not in trusted proxies, so forged entries prepended by client are skipped.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// isTrustedProxy ip is in settings.TRUSTED_PROXIES, entries are IPs or CIDRs.
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range settings.TRUSTED_PROXIES {
+		if _, cidr, err := net.ParseCIDR(proxy); err == nil {
+			if cidr.Contains(parsed) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
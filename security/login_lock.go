@@ -0,0 +1,79 @@
+package security
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginLock counts failed attempts by key (e.g. username), key is locked for lockDuration after maxFailures failures
+// in a row. Failures older than lockDuration are forgotten, kept in memory like admin sessions.
+type LoginLock struct {
+	mu           sync.Mutex
+	maxFailures  int
+	lockDuration time.Duration
+	entries      map[string]*loginFailures
+}
+
+type loginFailures struct {
+	count       int
+	lastFailed  time.Time
+	lockedUntil time.Time
+}
+
+func NewLoginLock(maxFailures int, lockDuration time.Duration) *LoginLock {
+	return &LoginLock{
+		maxFailures:  maxFailures,
+		lockDuration: lockDuration,
+		entries:      make(map[string]*loginFailures),
+	}
+}
+
+// Locked remaining lock duration of key, 0 if key is not locked.
+func (l *LoginLock) Locked(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok || !now.Before(entry.lockedUntil) {
+		return 0
+	}
+	return entry.lockedUntil.Sub(now)
+}
+
+// Fail count a failed attempt of key, return true if key is locked by this failure.
+func (l *LoginLock) Fail(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	entry, ok := l.entries[key]
+	if !ok || now.Sub(entry.lastFailed) >= l.lockDuration {
+		entry = &loginFailures{}
+		l.entries[key] = entry
+	}
+	entry.count++
+	entry.lastFailed = now
+	if entry.count < l.maxFailures {
+		return false
+	}
+	entry.count = 0
+	entry.lockedUntil = now.Add(l.lockDuration)
+	return true
+}
+
+// Reset forget failures of key after successful attempt.
+func (l *LoginLock) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+// sweep drop keys neither locked nor failed within lockDuration, they are the same as new keys.
+func (l *LoginLock) sweep(now time.Time) {
+	for key, entry := range l.entries {
+		if !now.Before(entry.lockedUntil) && now.Sub(entry.lastFailed) >= l.lockDuration {
+			delete(l.entries, key)
+		}
+	}
+}
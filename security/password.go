@@ -0,0 +1,30 @@
+package security
+
+import (
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"unicode"
+)
+
+// bcryptMaxLength bcrypt ignores bytes after 72.
+const bcryptMaxLength = 72
+
+var PasswordRule = fmt.Sprintf("password must be %d to %d characters with at least one letter and one digit", settings.PASSWORD_MIN_LENGTH, bcryptMaxLength)
+
+// IsStrongPassword see PasswordRule.
+func IsStrongPassword(password string) bool {
+	if len([]rune(password)) < settings.PASSWORD_MIN_LENGTH || len(password) > bcryptMaxLength {
+		return false
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return hasLetter && hasDigit
+}
@@ -0,0 +1,93 @@
+package security
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter token buckets by key, each bucket holds capacity tokens and refills full capacity in window.
+type RateLimiter struct {
+	mu         sync.Mutex
+	capacity   float64
+	window     time.Duration
+	refillRate float64 // tokens per second
+	buckets    map[string]*tokenBucket
+	lastSweep  time.Time
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// RateLimitResult Reset is duration until bucket is full again, RetryAfter is set only if not allowed.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+func NewRateLimiter(capacity int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		capacity:   float64(capacity),
+		window:     window,
+		refillRate: float64(capacity) / window.Seconds(),
+		buckets:    make(map[string]*tokenBucket),
+	}
+}
+
+// Allow take weight tokens of key, nothing is taken if tokens are not enough. weight over capacity is capped to capacity.
+func (l *RateLimiter) Allow(key string, weight int, now time.Time) RateLimitResult {
+	cost := math.Min(float64(weight), l.capacity)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.capacity, updatedAt: now}
+		l.buckets[key] = bucket
+	}
+	l.refill(bucket, now)
+
+	result := RateLimitResult{Limit: int(l.capacity)}
+	if bucket.tokens >= cost {
+		bucket.tokens -= cost
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.durationOf(cost - bucket.tokens)
+	}
+	result.Remaining = int(math.Floor(bucket.tokens))
+	result.Reset = l.durationOf(l.capacity - bucket.tokens)
+	return result
+}
+
+func (l *RateLimiter) refill(bucket *tokenBucket, now time.Time) {
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(l.capacity, bucket.tokens+elapsed*l.refillRate)
+		bucket.updatedAt = now
+	}
+}
+
+// sweep drop buckets refilled to full once per window, they are the same as new buckets.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		l.refill(bucket, now)
+		if bucket.tokens >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *RateLimiter) durationOf(tokens float64) time.Duration {
+	return time.Duration(tokens / l.refillRate * float64(time.Second))
+}
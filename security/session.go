@@ -0,0 +1,102 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"time"
+)
+
+var (
+	ErrInvalidSession      = errors.New("invalid or expired token")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
+// SessionStore login sessions, implementations must be safe for concurrent use.
+// Access token expires after settings.SESSION_IDLE_TIMEOUT idle or settings.SESSION_TOKEN_TTL, refresh token
+// renews it until settings.SESSION_REFRESH_TTL since login.
+type SessionStore interface {
+	// Create issue new session of user, returned session carries plain access token and refresh token.
+	Create(ctx context.Context, user *dto.User, clientIP, userAgent string) (*dto.Session, error)
+	// Get user of valid access token, session last active time is updated.
+	Get(ctx context.Context, token string) (*dto.User, error)
+	// Refresh rotate access token and refresh token of session, old tokens are invalid after.
+	Refresh(ctx context.Context, refreshToken, clientIP, userAgent string) (*dto.Session, error)
+	Delete(ctx context.Context, token string) error
+	// DeleteByUserId log out all devices of user.
+	DeleteByUserId(ctx context.Context, userId string) error
+	// DeleteOthers log out all devices of user except session of currentToken.
+	DeleteOthers(ctx context.Context, userId, currentToken string) error
+	// GetSessionsByUserId active sessions of user, session of currentToken is marked current.
+	GetSessionsByUserId(ctx context.Context, userId, currentToken string) ([]*dto.Session, error)
+	// RefreshUser replace cached user data of all sessions belong to user.
+	RefreshUser(user *dto.User)
+	// DeleteExpired delete sessions can not be refreshed anymore, return deleted count.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func newSession(userId, clientIP, userAgent string, now time.Time) (*dto.Session, error) {
+	session := &dto.Session{
+		ID:               uuid.NewString(),
+		UserID:           userId,
+		CreatedAt:        now,
+		RefreshExpiresAt: now.Add(settings.SESSION_REFRESH_TTL),
+	}
+	if err := issueTokens(session, clientIP, userAgent, now); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// issueTokens set new access token and refresh token of session, access token never outlives session.
+func issueTokens(session *dto.Session, clientIP, userAgent string, now time.Time) error {
+	token, err := GenerateToken()
+	if err != nil {
+		return err
+	}
+	refreshToken, err := GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	session.Token = token
+	session.RefreshToken = refreshToken
+	session.TokenHash = HashToken(token)
+	session.RefreshTokenHash = HashToken(refreshToken)
+	session.ClientIP = clientIP
+	session.UserAgent = userAgent
+	session.LastActiveAt = now
+	session.ExpiresAt = now.Add(settings.SESSION_TOKEN_TTL)
+	if session.ExpiresAt.After(session.RefreshExpiresAt) {
+		session.ExpiresAt = session.RefreshExpiresAt
+	}
+	return nil
+}
+
+func tokenActive(session *dto.Session, now time.Time) bool {
+	return now.Before(session.ExpiresAt) && now.Before(session.LastActiveAt.Add(settings.SESSION_IDLE_TIMEOUT))
+}
+
+func refreshable(session *dto.Session, now time.Time) bool {
+	return now.Before(session.RefreshExpiresAt)
+}
+
+// GenerateToken random 32 bytes hex string.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken sha256 hex of token, only hashes of tokens are stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
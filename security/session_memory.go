@@ -0,0 +1,179 @@
+package security
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memorySessionStore sessions are kept in process and lost on restart, keeps user snapshot of login.
+type memorySessionStore struct {
+	mu sync.Mutex
+	// token hash: session
+	sessions map[string]*dto.Session
+	// refresh token hash: token hash
+	refreshIndex map[string]string
+	// userId: user
+	users map[string]*dto.User
+}
+
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{
+		sessions:     make(map[string]*dto.Session),
+		refreshIndex: make(map[string]string),
+		users:        make(map[string]*dto.User),
+	}
+}
+
+func (m *memorySessionStore) Create(ctx context.Context, user *dto.User, clientIP, userAgent string) (*dto.Session, error) {
+	session, err := newSession(user.ID, clientIP, userAgent, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.put(session)
+	m.users[user.ID] = user
+	return session, nil
+}
+
+func (m *memorySessionStore) Get(ctx context.Context, token string) (*dto.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	session, ok := m.sessions[HashToken(token)]
+	if !ok || !tokenActive(session, now) {
+		return nil, ErrInvalidSession
+	}
+	user, ok := m.users[session.UserID]
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+	session.LastActiveAt = now
+	return user, nil
+}
+
+func (m *memorySessionStore) Refresh(ctx context.Context, refreshToken, clientIP, userAgent string) (*dto.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	tokenHash, ok := m.refreshIndex[HashToken(refreshToken)]
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	session := m.sessions[tokenHash]
+	if !refreshable(session, now) {
+		m.remove(session)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rotated := *session
+	if err := issueTokens(&rotated, clientIP, userAgent, now); err != nil {
+		return nil, err
+	}
+	m.remove(session)
+	m.put(&rotated)
+	return &rotated, nil
+}
+
+func (m *memorySessionStore) Delete(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[HashToken(token)]; ok {
+		m.remove(session)
+	}
+	return nil
+}
+
+func (m *memorySessionStore) DeleteByUserId(ctx context.Context, userId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, session := range m.sessions {
+		if session.UserID == userId {
+			m.remove(session)
+		}
+	}
+	delete(m.users, userId)
+	return nil
+}
+
+func (m *memorySessionStore) DeleteOthers(ctx context.Context, userId, currentToken string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currentHash := HashToken(currentToken)
+	for tokenHash, session := range m.sessions {
+		if session.UserID == userId && tokenHash != currentHash {
+			m.remove(session)
+		}
+	}
+	return nil
+}
+
+func (m *memorySessionStore) GetSessionsByUserId(ctx context.Context, userId, currentToken string) ([]*dto.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	currentHash := HashToken(currentToken)
+	sessions := make([]*dto.Session, 0)
+	for tokenHash, session := range m.sessions {
+		if session.UserID != userId || !refreshable(session, now) {
+			continue
+		}
+		view := *session
+		view.Token, view.RefreshToken = "", ""
+		view.Current = tokenHash == currentHash
+		sessions = append(sessions, &view)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActiveAt.After(sessions[j].LastActiveAt)
+	})
+	return sessions, nil
+}
+
+func (m *memorySessionStore) RefreshUser(user *dto.User) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.ID]; ok {
+		m.users[user.ID] = user
+	}
+}
+
+func (m *memorySessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var deleted int64
+	for _, session := range m.sessions {
+		if !refreshable(session, now) {
+			m.remove(session)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// put store session without plain tokens, caller must hold lock.
+func (m *memorySessionStore) put(session *dto.Session) {
+	stored := *session
+	stored.Token, stored.RefreshToken = "", ""
+	m.sessions[stored.TokenHash] = &stored
+	m.refreshIndex[stored.RefreshTokenHash] = stored.TokenHash
+}
+
+// remove caller must hold lock.
+func (m *memorySessionStore) remove(session *dto.Session) {
+	delete(m.sessions, session.TokenHash)
+	delete(m.refreshIndex, session.RefreshTokenHash)
+}
@@ -0,0 +1,116 @@
+package security
+
+import (
+	"context"
+	"database/sql"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+// sqliteSessionStore sessions survive restarts, user is loaded on every Get so RefreshUser is not needed.
+type sqliteSessionStore struct {
+	db          *sql.DB
+	sessionRepo repository.ISessionRepository
+	userRepo    repository.IUserRepository
+}
+
+func NewSQLiteSessionStore(db *sql.DB, sessionRepo repository.ISessionRepository, userRepo repository.IUserRepository) SessionStore {
+	return &sqliteSessionStore{
+		db:          db,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+	}
+}
+
+func (s *sqliteSessionStore) Create(ctx context.Context, user *dto.User, clientIP, userAgent string) (*dto.Session, error) {
+	session, err := newSession(user.ID, clientIP, userAgent, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Insert(ctx, s.db, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *sqliteSessionStore) Get(ctx context.Context, token string) (*dto.User, error) {
+	session, err := s.sessionRepo.GetByTokenHash(ctx, s.db, HashToken(token))
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	now := time.Now()
+	if !tokenActive(session, now) {
+		return nil, ErrInvalidSession
+	}
+	if now.Sub(session.LastActiveAt) >= settings.SESSION_TOUCH_INTERVAL {
+		if err := s.sessionRepo.UpdateLastActiveAt(ctx, s.db, session.ID, now); err != nil {
+			log.Warnf("[SessionStore] failed to update last active at, sessionId: %s, error: %v", session.ID, err)
+		}
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, s.db, session.UserID)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	return user, nil
+}
+
+// Refresh rotation is conditional on the old refresh token, concurrent refreshes of one token succeed only once.
+func (s *sqliteSessionStore) Refresh(ctx context.Context, refreshToken, clientIP, userAgent string) (*dto.Session, error) {
+	refreshTokenHash := HashToken(refreshToken)
+	session, err := s.sessionRepo.GetByRefreshTokenHash(ctx, s.db, refreshTokenHash)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if !refreshable(session, now) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := issueTokens(session, clientIP, userAgent, now); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Rotate(ctx, s.db, session, refreshTokenHash); err != nil {
+		log.Warnf("[SessionStore] failed to rotate session %s, error: %v", session.ID, err)
+		return nil, ErrInvalidRefreshToken
+	}
+	return session, nil
+}
+
+func (s *sqliteSessionStore) Delete(ctx context.Context, token string) error {
+	return s.sessionRepo.DeleteByTokenHash(ctx, s.db, HashToken(token))
+}
+
+func (s *sqliteSessionStore) DeleteByUserId(ctx context.Context, userId string) error {
+	_, err := s.sessionRepo.DeleteByUserId(ctx, s.db, userId)
+	return err
+}
+
+func (s *sqliteSessionStore) DeleteOthers(ctx context.Context, userId, currentToken string) error {
+	_, err := s.sessionRepo.DeleteByUserIdExcept(ctx, s.db, userId, HashToken(currentToken))
+	return err
+}
+
+func (s *sqliteSessionStore) GetSessionsByUserId(ctx context.Context, userId, currentToken string) ([]*dto.Session, error) {
+	sessions, err := s.sessionRepo.GetActiveSessionsByUserId(ctx, s.db, userId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	currentHash := HashToken(currentToken)
+	for _, session := range sessions {
+		session.Current = session.TokenHash == currentHash
+	}
+	return sessions, nil
+}
+
+func (s *sqliteSessionStore) RefreshUser(user *dto.User) {
+}
+
+func (s *sqliteSessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.sessionRepo.DeleteExpired(ctx, s.db, time.Now())
+}
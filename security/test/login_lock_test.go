@@ -0,0 +1,48 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/security"
+	"testing"
+	"time"
+)
+
+func TestLoginLock(t *testing.T) {
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	lock := security.NewLoginLock(3, 15*time.Minute)
+
+	if lock.Fail("alice", now) || lock.Fail("alice", now.Add(time.Second)) {
+		t.Fatal("Expected not locked before 3 failures")
+	}
+	if !lock.Fail("alice", now.Add(2*time.Second)) {
+		t.Fatal("Expected locked by 3rd failure")
+	}
+	if remaining := lock.Locked("alice", now.Add(2*time.Second)); remaining != 15*time.Minute {
+		t.Errorf("Expected locked for %v, got %v", 15*time.Minute, remaining)
+	}
+	if remaining := lock.Locked("bob", now); remaining != 0 {
+		t.Errorf("Expected other key not locked, got %v", remaining)
+	}
+	if remaining := lock.Locked("alice", now.Add(16*time.Minute)); remaining != 0 {
+		t.Errorf("Expected lock expired, got %v", remaining)
+	}
+}
+
+func TestLoginLock_ResetAndForget(t *testing.T) {
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	lock := security.NewLoginLock(2, 15*time.Minute)
+
+	// success resets failures.
+	lock.Fail("alice", now)
+	lock.Reset("alice")
+	if lock.Fail("alice", now.Add(time.Second)) {
+		t.Error("Expected failures reset after success")
+	}
+
+	// failure older than lock duration is forgotten.
+	if lock.Fail("alice", now.Add(20*time.Minute)) {
+		t.Error("Expected old failure forgotten")
+	}
+	if !lock.Fail("alice", now.Add(21*time.Minute)) {
+		t.Error("Expected locked by failures in a row")
+	}
+}
@@ -0,0 +1,371 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidAdminCredentials = errors.New("invalid credentials")
+	ErrInvalidAdminRole        = errors.New("invalid admin role")
+	ErrAdminUsernameExists     = errors.New("admin username already exists")
+	ErrLastSuperAdmin          = errors.New("can not demote or disable the last active superadmin")
+	ErrAdminLoginLocked        = errors.New("too many failed login attempts, try again later")
+	ErrSameAdminPassword       = errors.New("new password must be different from old password")
+)
+
+type adminAccountService struct {
+	db                *sql.DB
+	adminRepo         repository.IAdminRepository
+	adminSessionCache *security.AdminSessionCache
+	loginLock         *security.LoginLock
+	auditLogRepo      repository.IAuditLogRepository
+}
+
+func NewIAdminAccountService(db *sql.DB, adminRepo repository.IAdminRepository, adminSessionCache *security.AdminSessionCache,
+	loginLock *security.LoginLock, auditLogRepo repository.IAuditLogRepository) service.IAdminAccountService {
+	return &adminAccountService{
+		db:                db,
+		adminRepo:         adminRepo,
+		adminSessionCache: adminSessionCache,
+		loginLock:         loginLock,
+		auditLogRepo:      auditLogRepo,
+	}
+}
+
+// Login failures of unknown username, wrong password, wrong TOTP code and disabled admin share one error,
+// the actual reason is kept in audit log. Username is locked after settings.ADMIN_LOGIN_MAX_FAILURES failures.
+func (s *adminAccountService) Login(ctx context.Context, req *dto.AdminLoginReq) (*dto.AdminSession, error) {
+	now := time.Now()
+	if remaining := s.loginLock.Locked(req.Username, now); remaining > 0 {
+		if err := s.recordLoginFailed(ctx, &dto.Admin{Username: req.Username}, "locked"); err != nil {
+			return nil, err
+		}
+		return nil, ErrAdminLoginLocked
+	}
+
+	admin, err := s.adminRepo.GetAdminByUsername(ctx, s.db, req.Username)
+	if err != nil {
+		return nil, s.loginFailed(ctx, &dto.Admin{Username: req.Username}, "unknown username", now)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, s.loginFailed(ctx, admin, "invalid password", now)
+	}
+	if !security.VerifyTotp(admin.TotpSecret, req.TotpCode, now) {
+		log.Warnf("[AdminAccountService] invalid TOTP code, admin: %s", admin.Username)
+		return nil, s.loginFailed(ctx, admin, "invalid TOTP code", now)
+	}
+	if admin.Disabled {
+		log.Warnf("[AdminAccountService] disabled admin login, admin: %s", admin.Username)
+		return nil, s.loginFailed(ctx, admin, "admin disabled", now)
+	}
+	s.loginLock.Reset(req.Username)
+
+	// login is refused if it can not be audited.
+	if err := recordAuditLog(ctx, s.db, s.auditLogRepo, newAdminAuditLog(admin, dto.AUDIT_ADMIN_LOGIN, admin.ID)); err != nil {
+		return nil, err
+	}
+	admin.TotpSecret = ""
+	token, expiresAt, err := s.adminSessionCache.Create(admin)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[AdminAccountService] admin logged in, admin: %s, role: %s", admin.Username, admin.Role)
+	return &dto.AdminSession{
+		Token:     token,
+		Admin:     admin,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// loginFailed record failed login and count it to lock username, return ErrInvalidAdminCredentials or error of recording.
+func (s *adminAccountService) loginFailed(ctx context.Context, admin *dto.Admin, reason string, now time.Time) error {
+	if s.loginLock.Fail(admin.Username, now) {
+		log.Warnf("[AdminAccountService] too many failed logins, admin %s locked for %v", admin.Username, settings.ADMIN_LOGIN_LOCK_DURATION)
+		reason += ", locked"
+	}
+	if err := s.recordLoginFailed(ctx, admin, reason); err != nil {
+		return err
+	}
+	return ErrInvalidAdminCredentials
+}
+
+func (s *adminAccountService) recordLoginFailed(ctx context.Context, admin *dto.Admin, reason string) error {
+	auditLog := newAdminAuditLog(admin, dto.AUDIT_ADMIN_LOGIN_FAILED, admin.ID)
+	auditLog.After = auditValue(map[string]string{"reason": reason})
+	return recordAuditLog(ctx, s.db, s.auditLogRepo, auditLog)
+}
+
+func (s *adminAccountService) Logout(ctx context.Context, token string) error {
+	admin, err := s.adminSessionCache.Get(token)
+	s.adminSessionCache.Delete(token)
+	if err != nil {
+		return nil
+	}
+	return recordAuditLog(ctx, s.db, s.auditLogRepo, newAdminAuditLog(admin, dto.AUDIT_ADMIN_LOGOUT, admin.ID))
+}
+
+func (s *adminAccountService) CreateAdmin(ctx context.Context, operator *dto.Admin, req *dto.CreateAdminReq) (*dto.Admin, error) {
+	if !req.Role.Valid() {
+		return nil, ErrInvalidAdminRole
+	}
+
+	admin, err := newAdmin(req.Username, req.Password, req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := s.adminRepo.GetAdminByUsername(ctx, tx, req.Username); err == nil {
+			return ErrAdminUsernameExists
+		}
+		if err := s.adminRepo.Insert(ctx, tx, admin); err != nil {
+			return err
+		}
+		auditLog := newAdminAuditLog(operator, dto.AUDIT_ADMIN_CREATE, admin.ID)
+		auditLog.After = auditValue(adminAuditValue(admin))
+		return appendAuditLog(ctx, tx, s.auditLogRepo, auditLog)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[AdminAccountService] created admin %s, role: %s", admin.Username, admin.Role)
+	admin.TotpURI = security.TotpURI(settings.TOTP_ISSUER, admin.Username, admin.TotpSecret)
+	return admin, nil
+}
+
+func (s *adminAccountService) GetAdmins(ctx context.Context) ([]*dto.Admin, error) {
+	admins, err := s.adminRepo.GetAdmins(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	for _, admin := range admins {
+		admin.TotpSecret = ""
+	}
+	return admins, nil
+}
+
+func (s *adminAccountService) UpdateAdmin(ctx context.Context, operator *dto.Admin, adminId string, req *dto.UpdateAdminReq) (*dto.Admin, error) {
+	if req.Role != nil && !req.Role.Valid() {
+		return nil, ErrInvalidAdminRole
+	}
+
+	var admin *dto.Admin
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		admin, err = s.adminRepo.GetAdminById(ctx, tx, adminId)
+		if err != nil {
+			return err
+		}
+		auditLog := newAdminAuditLog(operator, dto.AUDIT_ADMIN_UPDATE, admin.ID)
+		auditLog.Before = auditValue(adminAuditValue(admin))
+
+		wasActiveSuperAdmin := admin.Role == dto.ADMIN_ROLE_SUPERADMIN && !admin.Disabled
+		if req.Role != nil {
+			admin.Role = *req.Role
+		}
+		if req.Disabled != nil {
+			admin.Disabled = *req.Disabled
+		}
+		if wasActiveSuperAdmin && (admin.Role != dto.ADMIN_ROLE_SUPERADMIN || admin.Disabled) {
+			count, err := s.adminRepo.CountActiveByRole(ctx, tx, dto.ADMIN_ROLE_SUPERADMIN)
+			if err != nil {
+				return err
+			}
+			if count <= 1 {
+				return ErrLastSuperAdmin
+			}
+		}
+
+		if err := s.adminRepo.UpdateRoleAndDisabled(ctx, tx, admin); err != nil {
+			return err
+		}
+		auditLog.After = auditValue(adminAuditValue(admin))
+		return appendAuditLog(ctx, tx, s.auditLogRepo, auditLog)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// logged-in sessions keep admin snapshot, log them out to apply new role immediately.
+	s.adminSessionCache.DeleteByAdminId(admin.ID)
+	log.Infof("[AdminAccountService] updated admin %s, role: %s, disabled: %v", admin.Username, admin.Role, admin.Disabled)
+	admin.TotpSecret = ""
+	return admin, nil
+}
+
+// ChangePassword wrong password or TOTP code counts to lock username like failed login.
+func (s *adminAccountService) ChangePassword(ctx context.Context, admin *dto.Admin, token string, req *dto.AdminChangePasswordReq) error {
+	if req.NewPassword == req.OldPassword {
+		return ErrSameAdminPassword
+	}
+	if _, err := s.verifyCredentials(ctx, admin, req.OldPassword, req.TotpCode); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.adminRepo.UpdatePasswordHash(ctx, tx, admin.ID, string(hash)); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newAdminAuditLog(admin, dto.AUDIT_ADMIN_PASSWORD, admin.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.adminSessionCache.DeleteOthers(admin.ID, token)
+	log.Infof("[AdminAccountService] password changed, admin: %s", admin.Username)
+	return nil
+}
+
+// RotateTotp replace TOTP secret of admin, codes of old secret are invalid right after.
+func (s *adminAccountService) RotateTotp(ctx context.Context, admin *dto.Admin, req *dto.AdminRotateTotpReq) (*dto.Admin, error) {
+	current, err := s.verifyCredentials(ctx, admin, req.Password, req.TotpCode)
+	if err != nil {
+		return nil, err
+	}
+
+	totpSecret, err := security.GenerateTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.adminRepo.UpdateTotpSecret(ctx, tx, admin.ID, totpSecret); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newAdminAuditLog(admin, dto.AUDIT_ADMIN_TOTP_ROTATE, admin.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[AdminAccountService] TOTP secret rotated, admin: %s", admin.Username)
+	current.PasswordHash = ""
+	current.TotpSecret = totpSecret
+	current.TotpURI = security.TotpURI(settings.TOTP_ISSUER, current.Username, totpSecret)
+	return current, nil
+}
+
+// verifyCredentials check password and TOTP code of logged-in admin, failures count to lock username.
+func (s *adminAccountService) verifyCredentials(ctx context.Context, admin *dto.Admin, password, totpCode string) (*dto.Admin, error) {
+	now := time.Now()
+	if remaining := s.loginLock.Locked(admin.Username, now); remaining > 0 {
+		return nil, ErrAdminLoginLocked
+	}
+	current, err := s.adminRepo.GetAdminById(ctx, s.db, admin.ID)
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(current.PasswordHash), []byte(password)) != nil ||
+		!security.VerifyTotp(current.TotpSecret, totpCode, now) {
+		if s.loginLock.Fail(admin.Username, now) {
+			log.Warnf("[AdminAccountService] too many failed verifications, admin %s locked for %v", admin.Username, settings.ADMIN_LOGIN_LOCK_DURATION)
+		}
+		return nil, ErrInvalidAdminCredentials
+	}
+	s.loginLock.Reset(admin.Username)
+	return current, nil
+}
+
+// BootstrapSuperAdmin password and TOTP URI of created superadmin are written to secretFile (0600), it must not exist
+// yet so an old secret is never overwritten. Secrets are never logged.
+func (s *adminAccountService) BootstrapSuperAdmin(ctx context.Context, secretFile string) error {
+	count, err := s.adminRepo.CountAdmins(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	password, err := security.GenerateToken()
+	if err != nil {
+		return err
+	}
+	admin, err := newAdmin(settings.ADMIN_BOOTSTRAP_USERNAME, password, dto.ADMIN_ROLE_SUPERADMIN)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(secretFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap secret file: %w", err)
+	}
+	defer file.Close()
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.adminRepo.Insert(ctx, tx, admin); err != nil {
+			return err
+		}
+		err := appendAuditLog(ctx, tx, s.auditLogRepo, &dto.AuditLog{
+			ActorType: dto.AUDIT_ACTOR_SYSTEM,
+			Action:    dto.AUDIT_ADMIN_CREATE,
+			Target:    admin.ID,
+			After:     auditValue(adminAuditValue(admin)),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(file, "username: %s\npassword: %s\ntotp_uri: %s\n",
+			admin.Username, password, security.TotpURI(settings.TOTP_ISSUER, admin.Username, admin.TotpSecret))
+		if err != nil {
+			return err
+		}
+		return file.Sync()
+	})
+	if err != nil {
+		file.Close()
+		if rmErr := os.Remove(secretFile); rmErr != nil {
+			log.Errorf("[AdminAccountService] failed to remove bootstrap secret file %s: %v", secretFile, rmErr)
+		}
+		return err
+	}
+
+	log.Warnf("[AdminAccountService] no admin found, created superadmin %s, password and TOTP secret are written to %s, delete it after first login",
+		admin.Username, secretFile)
+	return nil
+}
+
+// adminAuditValue audited fields of admin, secrets are excluded.
+func adminAuditValue(admin *dto.Admin) map[string]any {
+	return map[string]any{
+		"username": admin.Username,
+		"role":     admin.Role,
+		"disabled": admin.Disabled,
+	}
+}
+
+func newAdmin(username, password string, role dto.AdminRole) (*dto.Admin, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	totpSecret, err := security.GenerateTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &dto.Admin{
+		ID:           uuid.NewString(),
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         role,
+		TotpSecret:   totpSecret,
+	}, nil
+}
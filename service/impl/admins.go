@@ -4,32 +4,67 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/engine-v2/model"
 	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/security"
 	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/labstack/gommon/log"
+	"time"
 )
 
 type adminService struct {
-	db           *sql.DB
-	userRepo     repository.IUserRepository
-	balanceRepo  repository.IBalanceRepository
-	orderService service.IOrderService
+	db                 *sql.DB
+	userRepo           repository.IUserRepository
+	balanceRepo        repository.IBalanceRepository
+	orderService       service.IOrderService
+	withdrawalService  service.IWithdrawalService
+	feeTierService     service.IFeeTierService
+	feeRevenueRepo     repository.IFeeRevenueRepository
+	liquidationService service.ILiquidationService
+	adminRepo          repository.IAdminRepository
+	auditLogRepo       repository.IAuditLogRepository
+	sessionStore       security.SessionStore
+	subAccountRepo     repository.ISubAccountRepository
 }
 
+const (
+	defaultAdjustmentLimit = 50
+	adjustmentMaxLimit     = 500
+)
+
 func NewIAdminService(db *sql.DB,
 	userRepo repository.IUserRepository,
 	balanceRepo repository.IBalanceRepository,
-	orderService service.IOrderService) service.IAdminService {
+	orderService service.IOrderService,
+	withdrawalService service.IWithdrawalService,
+	feeTierService service.IFeeTierService,
+	feeRevenueRepo repository.IFeeRevenueRepository,
+	liquidationService service.ILiquidationService,
+	adminRepo repository.IAdminRepository,
+	auditLogRepo repository.IAuditLogRepository,
+	sessionStore security.SessionStore,
+	subAccountRepo repository.ISubAccountRepository) service.IAdminService {
 	return &adminService{
-		db:           db,
-		userRepo:     userRepo,
-		balanceRepo:  balanceRepo,
-		orderService: orderService,
+		db:                 db,
+		userRepo:           userRepo,
+		balanceRepo:        balanceRepo,
+		orderService:       orderService,
+		withdrawalService:  withdrawalService,
+		feeTierService:     feeTierService,
+		feeRevenueRepo:     feeRevenueRepo,
+		liquidationService: liquidationService,
+		adminRepo:          adminRepo,
+		auditLogRepo:       auditLogRepo,
+		sessionStore:       sessionStore,
+		subAccountRepo:     subAccountRepo,
 	}
 }
 
-func (as adminService) Settlement(ctx context.Context, req dto.SettlementReq) error {
+// Settlement credit user balance and record the admin performed it, audit log is appended in the same tx,
+// so there is no adjustment without audit log.
+func (as adminService) Settlement(ctx context.Context, admin *dto.Admin, req dto.SettlementReq) error {
 	err := WithTx(ctx, as.db, func(tx *sql.Tx) error {
 		user, err := as.userRepo.GetUserByUsername(ctx, tx, req.Username)
 		if err != nil {
@@ -39,18 +74,246 @@ func (as adminService) Settlement(ctx context.Context, req dto.SettlementReq) er
 			return errors.New("user not found by username")
 		}
 
+		before, err := as.getBalance(ctx, tx, user.ID, req.Asset)
+		if err != nil {
+			return err
+		}
 		err = as.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, user.ID, req.Asset, true, req.Amount)
 		if err != nil {
 			return err
 		}
+		after, err := as.getBalance(ctx, tx, user.ID, req.Asset)
+		if err != nil {
+			return err
+		}
 
-		return nil
+		adjustment := &dto.ManualAdjustment{
+			AdminID: admin.ID,
+			UserID:  user.ID,
+			Asset:   req.Asset,
+			Amount:  req.Amount,
+		}
+		if err := as.adminRepo.InsertManualAdjustment(ctx, tx, adjustment); err != nil {
+			return err
+		}
+
+		auditLog := newAdminAuditLog(admin, dto.AUDIT_MANUAL_ADJUSTMENT, user.ID)
+		auditLog.Before = auditValue(map[string]any{
+			"asset":     req.Asset,
+			"available": before.Available,
+			"locked":    before.Locked,
+		})
+		auditLog.After = auditValue(map[string]any{
+			"asset":         req.Asset,
+			"available":     after.Available,
+			"locked":        after.Locked,
+			"amount":        req.Amount,
+			"adjustment_id": adjustment.ID,
+		})
+		return appendAuditLog(ctx, tx, as.auditLogRepo, auditLog)
 	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("[Settlement] admin %s credited %v %s to user %s", admin.Username, req.Amount, req.Asset, req.Username)
+	return nil
+}
+
+// getBalance balance of asset, zero balance if user has no balance row of asset.
+func (as adminService) getBalance(ctx context.Context, db repository.DBExecutor, userId, asset string) (*dto.Balance, error) {
+	balances, err := as.balanceRepo.GetBalancesByUserId(ctx, db, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, balance := range balances {
+		if balance.Asset == asset {
+			return balance, nil
+		}
+	}
+	return &dto.Balance{Asset: asset}, nil
+}
 
-	return err
+func (as adminService) GetManualAdjustments(ctx context.Context, userId string, limit int) ([]*dto.ManualAdjustment, error) {
+	if limit <= 0 {
+		limit = defaultAdjustmentLimit
+	}
+	return as.adminRepo.GetManualAdjustments(ctx, as.db, userId, min(limit, adjustmentMaxLimit))
 }
 
-func (as adminService) TestAutoMakeMarket(ctx context.Context) error {
+func (as adminService) GetWithdrawals(ctx context.Context, status dto.WithdrawalStatus) ([]*dto.Withdrawal, error) {
+	return as.withdrawalService.GetWithdrawalsByStatus(ctx, status)
+}
+
+func (as adminService) GetLiquidations(ctx context.Context, userId string, limit int) ([]*dto.Liquidation, error) {
+	return as.liquidationService.GetLiquidations(ctx, userId, limit)
+}
+
+func (as adminService) GetWithdrawalAudits(ctx context.Context, withdrawalId string) ([]*dto.WithdrawalAudit, error) {
+	return as.withdrawalService.GetAudits(ctx, withdrawalId)
+}
+
+func (as adminService) ApproveWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error) {
+	return as.reviewWithdrawal(ctx, admin, withdrawalId, dto.WITHDRAWAL_STATUS_APPROVED, req)
+}
+
+func (as adminService) RejectWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error) {
+	return as.reviewWithdrawal(ctx, admin, withdrawalId, dto.WITHDRAWAL_STATUS_REJECTED, req)
+}
+
+func (as adminService) BroadcastWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error) {
+	return as.reviewWithdrawal(ctx, admin, withdrawalId, dto.WITHDRAWAL_STATUS_BROADCAST, req)
+}
+
+func (as adminService) ConfirmWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error) {
+	return as.reviewWithdrawal(ctx, admin, withdrawalId, dto.WITHDRAWAL_STATUS_CONFIRMED, req)
+}
+
+func (as adminService) reviewWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, toStatus dto.WithdrawalStatus, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error) {
+	auditLog := newAdminAuditLog(admin, dto.AUDIT_WITHDRAWAL_REVIEW, withdrawalId)
+	auditLog.After = auditValue(map[string]any{
+		"status":  toStatus,
+		"remark":  req.Remark,
+		"tx_hash": req.TxHash,
+	})
+	return as.withdrawalService.Transit(ctx, withdrawalId, toStatus, dto.AdminOperator(admin), req, auditLog)
+}
+
+func (as adminService) AssignMarketMakerTier(ctx context.Context, admin *dto.Admin, req *dto.AssignMarketMakerTierReq) (*dto.User, error) {
+	return as.feeTierService.AssignMarketMakerTier(ctx, req.Username, req.VipLevel, newAdminAuditLog(admin, dto.AUDIT_MARKET_MAKER_TIER, ""))
+}
+
+func (as adminService) GetAccountStatus(ctx context.Context, userId string) (*dto.AccountStatusInfo, error) {
+	user, err := as.userRepo.GetUserById(ctx, as.db, userId)
+	if err != nil {
+		return nil, err
+	}
+	return dto.NewAccountStatusInfo(user), nil
+}
+
+// UpdateAccountStatus takes effect on next request of user, sessions of closed account are deleted.
+// Open orders are canceled after status is changed, so user can not place new orders meanwhile.
+// Freezing or closing master account freezes or closes its sub-accounts too.
+func (as adminService) UpdateAccountStatus(ctx context.Context, admin *dto.Admin, userId string, req *dto.UpdateAccountStatusReq) (*dto.AccountStatusInfo, error) {
+	if !req.Status.IsValid() {
+		return nil, ErrInvalidAccountStatus
+	}
+
+	var user *dto.User
+	var subUsers []*dto.User
+	err := WithTx(ctx, as.db, func(tx *sql.Tx) error {
+		var err error
+		user, err = as.userRepo.GetUserById(ctx, tx, userId)
+		if err != nil {
+			return err
+		}
+		if user.Status == dto.ACCOUNT_STATUS_CLOSED {
+			return ErrAccountClosed
+		}
+
+		auditLog := newAdminAuditLog(admin, dto.AUDIT_ACCOUNT_STATUS, user.ID)
+		auditLog.Before = auditValue(accountStatusAuditValue(user))
+		user.Status = req.Status
+		user.TradeDisabled = req.TradeDisabled
+		user.WithdrawDisabled = req.WithdrawDisabled
+		user.StatusReason = req.Reason
+		if err = as.userRepo.UpdateStatus(ctx, tx, user); err != nil {
+			return err
+		}
+		subUsers, err = as.cascadeSubAccountStatus(ctx, tx, user)
+		if err != nil {
+			return err
+		}
+
+		after := accountStatusAuditValue(user)
+		after["cancel_orders"] = req.CancelOrders
+		if len(subUsers) > 0 {
+			after["sub_accounts"] = len(subUsers)
+		}
+		auditLog.After = auditValue(after)
+		return appendAuditLog(ctx, tx, as.auditLogRepo, auditLog)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := dto.NewAccountStatusInfo(user)
+	info.CanceledOrderIDs = as.applyAccountStatus(ctx, user, req.CancelOrders)
+	for _, subUser := range subUsers {
+		info.CanceledOrderIDs = append(info.CanceledOrderIDs, as.applyAccountStatus(ctx, subUser, req.CancelOrders)...)
+	}
+
+	log.Infof("[AdminService] admin %s changed account %s status to %s, trade_disabled: %v, withdraw_disabled: %v, reason: %s",
+		admin.Username, user.ID, user.Status, user.TradeDisabled, user.WithdrawDisabled, user.StatusReason)
+	return info, nil
+}
+
+// cascadeSubAccountStatus freeze or close sub-accounts of frozen or closed master, return changed sub-accounts.
+func (as adminService) cascadeSubAccountStatus(ctx context.Context, tx *sql.Tx, master *dto.User) ([]*dto.User, error) {
+	if master.Status != dto.ACCOUNT_STATUS_FROZEN && master.Status != dto.ACCOUNT_STATUS_CLOSED {
+		return nil, nil
+	}
+	subAccounts, err := as.subAccountRepo.GetSubAccountsByMasterUserId(ctx, tx, master.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var subUsers []*dto.User
+	for _, sub := range subAccounts {
+		subUser, err := as.userRepo.GetUserById(ctx, tx, sub.SubUserID)
+		if err != nil {
+			return nil, err
+		}
+		if subUser.Status == master.Status || subUser.Status == dto.ACCOUNT_STATUS_CLOSED {
+			continue
+		}
+		subUser.Status = master.Status
+		subUser.StatusReason = fmt.Sprintf("master account %s: %s", master.Status, master.StatusReason)
+		if err = as.userRepo.UpdateStatus(ctx, tx, subUser); err != nil {
+			return nil, err
+		}
+		subUsers = append(subUsers, subUser)
+	}
+	return subUsers, nil
+}
+
+// applyAccountStatus refresh sessions of user after status changed, delete them if closed,
+// cancel open orders if closed or asked, return canceled order ids.
+func (as adminService) applyAccountStatus(ctx context.Context, user *dto.User, cancelOrders bool) []string {
+	as.sessionStore.RefreshUser(user)
+	if user.Status == dto.ACCOUNT_STATUS_CLOSED {
+		if err := as.sessionStore.DeleteByUserId(ctx, user.ID); err != nil {
+			log.Errorf("[AdminService] failed to delete sessions of closed account %s: %v", user.ID, err)
+		}
+	}
+
+	if !cancelOrders && user.Status != dto.ACCOUNT_STATUS_CLOSED {
+		return nil
+	}
+	canceled, err := as.orderService.CancelAllOrders(ctx, user.ID)
+	if err != nil {
+		log.Errorf("[AdminService] failed to cancel all orders of user %s, canceled: %d, error: %v", user.ID, len(canceled), err)
+	}
+	return canceled
+}
+
+func accountStatusAuditValue(user *dto.User) map[string]any {
+	return map[string]any{
+		"status":            user.Status,
+		"trade_disabled":    user.TradeDisabled,
+		"withdraw_disabled": user.WithdrawDisabled,
+		"reason":            user.StatusReason,
+	}
+}
+
+func (as adminService) GetFeeRevenues(ctx context.Context, from, to time.Time) ([]*dto.FeeRevenueSummary, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidInput
+	}
+	return as.feeRevenueRepo.SumByAssetBetween(ctx, as.db, from, to)
+}
+
+func (as adminService) TestAutoMakeMarket(ctx context.Context, admin *dto.Admin) error {
 	// make some testing maker
 	user := &dto.User{
 		Username: "market_maker",
@@ -60,6 +323,11 @@ func (as adminService) TestAutoMakeMarket(ctx context.Context) error {
 		TakerFee: 0.002,
 	}
 	market := "ETH-USDT"
+	auditLog := newAdminAuditLog(admin, dto.AUDIT_TEST_MAKE_MARKET, market)
+	auditLog.After = auditValue(map[string]any{"maker_user_id": user.ID, "bids": 5, "asks": 5})
+	if err := recordAuditLog(ctx, as.db, as.auditLogRepo, auditLog); err != nil {
+		return err
+	}
 	// make 5 bid orders
 	_, _ = as.orderService.PlaceOrder(ctx, market, user, &dto.OrderReq{
 		Side:      model.BID,
@@ -133,6 +401,5 @@ func (as adminService) TestAutoMakeMarket(ctx context.Context) error {
 		Price:     3100,
 		Size:      10,
 	})
-
 	return nil
 }
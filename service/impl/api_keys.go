@@ -0,0 +1,288 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	"net"
+	"time"
+)
+
+var (
+	ErrInvalidApiKeyScope    = errors.New("invalid api key scope")
+	ErrInvalidIPAllowlist    = errors.New("invalid ip allowlist, items must be IPs or CIDRs")
+	ErrInvalidApiKeyExpiry   = errors.New("api key expires_at must be in the future")
+	ErrApiKeyLimitExceeded   = errors.New("active api keys limit exceeded")
+	ErrInvalidApiKey         = errors.New("invalid api key")
+	ErrInvalidApiKeySign     = errors.New("invalid api key signature")
+	ErrApiKeyExpired         = errors.New("api key expired")
+	ErrApiKeyRevoked         = errors.New("api key revoked")
+	ErrApiKeyIPNotAllowed    = errors.New("ip not allowed for api key")
+	ErrApiKeyOwnerNotFound   = errors.New("api key owner not found")
+	ErrApiKeyHeadersRequired = errors.New("api key, timestamp and signature are required")
+)
+
+type apiKeyService struct {
+	db          *sql.DB
+	userRepo    repository.IUserRepository
+	apiKeyRepo  repository.IApiKeyRepository
+	replayGuard *security.ReplayGuard
+
+	twoFactorService service.ITwoFactorService
+	auditLogRepo     repository.IAuditLogRepository
+}
+
+func NewIApiKeyService(db *sql.DB, userRepo repository.IUserRepository, apiKeyRepo repository.IApiKeyRepository, twoFactorService service.ITwoFactorService, auditLogRepo repository.IAuditLogRepository) service.IApiKeyService {
+	return &apiKeyService{
+		db:               db,
+		userRepo:         userRepo,
+		apiKeyRepo:       apiKeyRepo,
+		replayGuard:      security.NewReplayGuard(settings.API_KEY_RECV_WINDOW),
+		twoFactorService: twoFactorService,
+		auditLogRepo:     auditLogRepo,
+	}
+}
+
+func (s *apiKeyService) CreateApiKey(ctx context.Context, userId string, req *dto.CreateApiKeyReq) (*dto.ApiKey, error) {
+	if err := validateApiKeyScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+	if err := validateIPAllowlist(req.IPAllowlist); err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt > 0 {
+		t := time.UnixMilli(req.ExpiresAt)
+		if !t.After(time.Now()) {
+			return nil, ErrInvalidApiKeyExpiry
+		}
+		expiresAt = &t
+	}
+	if err := s.twoFactorService.Verify(ctx, userId, req.TotpCode); err != nil {
+		return nil, err
+	}
+
+	key, secret, err := security.GenerateApiKey()
+	if err != nil {
+		log.Errorf("[ApiKeyService] failed to generate api key: %v", err)
+		return nil, err
+	}
+
+	ipAllowlist := req.IPAllowlist
+	if ipAllowlist == nil {
+		ipAllowlist = make([]string, 0)
+	}
+	apiKey := &dto.ApiKey{
+		ApiKey:      key,
+		UserID:      userId,
+		Label:       req.Label,
+		Secret:      secret,
+		Scopes:      req.Scopes,
+		IPAllowlist: ipAllowlist,
+		ExpiresAt:   expiresAt,
+	}
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		count, err := s.apiKeyRepo.CountActiveByUserId(ctx, tx, userId)
+		if err != nil {
+			return err
+		}
+		if count >= settings.API_KEY_MAX_PER_USER {
+			return ErrApiKeyLimitExceeded
+		}
+		if err := s.apiKeyRepo.Insert(ctx, tx, apiKey); err != nil {
+			return err
+		}
+		auditLog := newUserAuditLog(userId, "", dto.AUDIT_API_KEY_CREATE, key)
+		auditLog.After = auditValue(apiKeyAuditValue(apiKey))
+		return appendAuditLog(ctx, tx, s.auditLogRepo, auditLog)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[ApiKeyService] created api key %s, userId: %s, scopes: %v", key, userId, req.Scopes)
+	return apiKey, nil
+}
+
+func (s *apiKeyService) GetApiKeys(ctx context.Context, userId string) ([]*dto.ApiKey, error) {
+	apiKeys, err := s.apiKeyRepo.GetApiKeysByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, apiKey := range apiKeys {
+		apiKey.Secret = ""
+	}
+	return apiKeys, nil
+}
+
+func (s *apiKeyService) UpdateLabel(ctx context.Context, userId, apiKey string, req *dto.UpdateApiKeyReq) (*dto.ApiKey, error) {
+	var result *dto.ApiKey
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := s.apiKeyRepo.GetApiKey(ctx, tx, apiKey)
+		if err != nil {
+			return err
+		}
+		if err := s.apiKeyRepo.UpdateLabel(ctx, tx, userId, apiKey, req.Label); err != nil {
+			return err
+		}
+		result, err = s.apiKeyRepo.GetApiKey(ctx, tx, apiKey)
+		if err != nil {
+			return err
+		}
+
+		auditLog := newUserAuditLog(userId, "", dto.AUDIT_API_KEY_UPDATE, apiKey)
+		auditLog.Before = auditValue(map[string]string{"label": before.Label})
+		auditLog.After = auditValue(map[string]string{"label": result.Label})
+		return appendAuditLog(ctx, tx, s.auditLogRepo, auditLog)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Secret = ""
+	return result, nil
+}
+
+func (s *apiKeyService) RevokeApiKey(ctx context.Context, userId, apiKey string) error {
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.apiKeyRepo.Revoke(ctx, tx, userId, apiKey); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newUserAuditLog(userId, "", dto.AUDIT_API_KEY_REVOKE, apiKey))
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("[ApiKeyService] revoked api key %s, userId: %s", apiKey, userId)
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, auth *dto.ApiKeyAuth) (*dto.User, *dto.ApiKey, error) {
+	if auth.ApiKey == "" || auth.Timestamp == "" || auth.Signature == "" {
+		return nil, nil, ErrApiKeyHeadersRequired
+	}
+
+	apiKey, err := s.apiKeyRepo.GetApiKey(ctx, s.db, auth.ApiKey)
+	if err != nil {
+		return nil, nil, ErrInvalidApiKey
+	}
+	if !security.VerifySignature(apiKey.Secret, auth.Timestamp, auth.Method, auth.Path, auth.Body, auth.Signature) {
+		return nil, nil, ErrInvalidApiKeySign
+	}
+
+	now := time.Now()
+	if err := s.replayGuard.Check(auth.Timestamp, auth.Signature, now); err != nil {
+		return nil, nil, err
+	}
+	if err := checkApiKeyActive(apiKey, now); err != nil {
+		return nil, nil, err
+	}
+	if !ipAllowed(apiKey.IPAllowlist, auth.ClientIP) {
+		log.Warnf("[ApiKeyService] ip %s not allowed for api key %s", auth.ClientIP, apiKey.ApiKey)
+		return nil, nil, ErrApiKeyIPNotAllowed
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, s.db, apiKey.UserID)
+	if err != nil {
+		return nil, nil, ErrApiKeyOwnerNotFound
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= settings.API_KEY_TOUCH_INTERVAL {
+		if err := s.apiKeyRepo.UpdateLastUsedAt(ctx, s.db, apiKey.ApiKey, now); err != nil {
+			log.Warnf("[ApiKeyService] failed to update last used at, api key: %s, error: %v", apiKey.ApiKey, err)
+		}
+	}
+	apiKey.Secret = ""
+	return user, apiKey, nil
+}
+
+func (s *apiKeyService) GetActiveApiKeyOwner(ctx context.Context, apiKey string) (*dto.User, error) {
+	key, err := s.apiKeyRepo.GetApiKey(ctx, s.db, apiKey)
+	if err != nil {
+		return nil, ErrInvalidApiKey
+	}
+	if err := checkApiKeyActive(key, time.Now()); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserById(ctx, s.db, key.UserID)
+	if err != nil {
+		return nil, ErrApiKeyOwnerNotFound
+	}
+	return user, nil
+}
+
+func checkApiKeyActive(apiKey *dto.ApiKey, now time.Time) error {
+	if apiKey.Revoked {
+		return ErrApiKeyRevoked
+	}
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		return ErrApiKeyExpired
+	}
+	return nil
+}
+
+// apiKeyAuditValue audited fields of api key, secret is excluded.
+func apiKeyAuditValue(apiKey *dto.ApiKey) map[string]any {
+	return map[string]any{
+		"label":        apiKey.Label,
+		"scopes":       apiKey.Scopes,
+		"ip_allowlist": apiKey.IPAllowlist,
+		"expires_at":   apiKey.ExpiresAt,
+	}
+}
+
+func validateApiKeyScopes(scopes []dto.ApiKeyScope) error {
+	if len(scopes) == 0 {
+		return ErrInvalidApiKeyScope
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case dto.API_KEY_SCOPE_READ, dto.API_KEY_SCOPE_TRADE, dto.API_KEY_SCOPE_WITHDRAW:
+		default:
+			return ErrInvalidApiKeyScope
+		}
+	}
+	return nil
+}
+
+func validateIPAllowlist(ipAllowlist []string) error {
+	for _, item := range ipAllowlist {
+		if net.ParseIP(item) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(item); err != nil {
+			return ErrInvalidIPAllowlist
+		}
+	}
+	return nil
+}
+
+// ipAllowed empty allowlist allows any IP.
+func ipAllowed(ipAllowlist []string, clientIP string) bool {
+	if len(ipAllowlist) == 0 {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, item := range ipAllowlist {
+		if allowed := net.ParseIP(item); allowed != nil {
+			if allowed.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(item); err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
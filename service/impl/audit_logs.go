@@ -0,0 +1,148 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"time"
+)
+
+const (
+	defaultAuditLogLimit = 50
+	auditLogMaxLimit     = 500
+	// auditVerifyBatchSize rows loaded per query when verifying hash chain.
+	auditVerifyBatchSize = 1000
+)
+
+type auditService struct {
+	db           *sql.DB
+	auditLogRepo repository.IAuditLogRepository
+}
+
+func NewIAuditService(db *sql.DB, auditLogRepo repository.IAuditLogRepository) service.IAuditService {
+	return &auditService{
+		db:           db,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+func (s *auditService) GetAuditLogs(ctx context.Context, req *dto.AuditLogQueryReq) ([]*dto.AuditLog, error) {
+	from := time.UnixMilli(req.From)
+	to := time.Now()
+	if req.To > 0 {
+		to = time.UnixMilli(req.To)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidInput
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAuditLogLimit
+	}
+	return s.auditLogRepo.GetAuditLogs(ctx, s.db, req, from, to, min(limit, auditLogMaxLimit))
+}
+
+// VerifyChain walk all rows in id order, stop at first row whose prev_hash is not hash of previous row
+// (row deleted or inserted) or whose hash does not match its fields (row modified).
+func (s *auditService) VerifyChain(ctx context.Context) (*dto.AuditVerifyResult, error) {
+	result := &dto.AuditVerifyResult{}
+	var lastId int64
+	var lastHash string
+
+	for {
+		auditLogs, err := s.auditLogRepo.GetAuditLogsAfterId(ctx, s.db, lastId, auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, auditLog := range auditLogs {
+			if auditLog.PrevHash != lastHash {
+				result.BrokenID = auditLog.ID
+				result.Reason = "prev_hash does not match previous row, rows deleted or inserted"
+				return result, nil
+			}
+			if serviceHelper.AuditLogHash(auditLog) != auditLog.Hash {
+				result.BrokenID = auditLog.ID
+				result.Reason = "hash does not match row, row modified"
+				return result, nil
+			}
+			result.Checked++
+			lastId = auditLog.ID
+			lastHash = auditLog.Hash
+		}
+
+		if len(auditLogs) < auditVerifyBatchSize {
+			result.Valid = true
+			return result, nil
+		}
+	}
+}
+
+// recordAuditLog append auditLog in its own tx, only for actions changing no data (e.g. login, logout),
+// actions changing data append it inside their own tx so they never happen without audit log.
+func recordAuditLog(ctx context.Context, db *sql.DB, auditLogRepo repository.IAuditLogRepository, auditLog *dto.AuditLog) error {
+	return WithTx(ctx, db, func(tx *sql.Tx) error {
+		return appendAuditLog(ctx, tx, auditLogRepo, auditLog)
+	})
+}
+
+// appendAuditLog chain auditLog to latest row, use it inside tx of the audited action to make them atomic.
+// request meta is taken from ctx if not set.
+func appendAuditLog(ctx context.Context, db repository.DBExecutor, auditLogRepo repository.IAuditLogRepository, auditLog *dto.AuditLog) error {
+	meta := dto.RequestMetaFrom(ctx)
+	if auditLog.RequestID == "" {
+		auditLog.RequestID = meta.RequestID
+	}
+	if auditLog.ClientIP == "" {
+		auditLog.ClientIP = meta.ClientIP
+	}
+	if auditLog.UserAgent == "" {
+		auditLog.UserAgent = meta.UserAgent
+	}
+
+	prevHash, err := auditLogRepo.GetLastHash(ctx, db)
+	if err != nil {
+		return err
+	}
+	auditLog.PrevHash = prevHash
+	auditLog.CreatedAt = time.Now()
+	auditLog.Hash = serviceHelper.AuditLogHash(auditLog)
+	return auditLogRepo.Insert(ctx, db, auditLog)
+}
+
+func newAdminAuditLog(admin *dto.Admin, action dto.AuditAction, target string) *dto.AuditLog {
+	return &dto.AuditLog{
+		ActorType: dto.AUDIT_ACTOR_ADMIN,
+		ActorID:   admin.ID,
+		ActorName: admin.Username,
+		Action:    action,
+		Target:    target,
+	}
+}
+
+func newUserAuditLog(userId, username string, action dto.AuditAction, target string) *dto.AuditLog {
+	return &dto.AuditLog{
+		ActorType: dto.AUDIT_ACTOR_USER,
+		ActorID:   userId,
+		ActorName: username,
+		Action:    action,
+		Target:    target,
+	}
+}
+
+// auditValue JSON of before/after value, empty if v is nil.
+func auditValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
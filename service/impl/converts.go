@@ -0,0 +1,312 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrConvertAsset               = errors.New("convert asset not supported")
+	ErrConvertSameAsset           = errors.New("from asset and to asset must be different")
+	ErrConvertInsufficientDepth   = errors.New("order book depth is not enough for convert amount")
+	ErrConvertAmountTooSmall      = errors.New("convert amount too small")
+	ErrConvertInsufficientBalance = errors.New("insufficient balance to convert")
+	ErrConvertQuoteNotFound       = errors.New("convert quote not found")
+	ErrConvertQuoteExpired        = errors.New("convert quote expired")
+	ErrConvertQuoteDeviated       = errors.New("order book moved away from convert quote, please request a new quote")
+)
+
+const (
+	defaultConvertLimit = 50
+	convertMaxLimit     = 500
+)
+
+type convertService struct {
+	db               *sql.DB
+	balanceRepo      repository.IBalanceRepository
+	convertRepo      repository.IConvertRepository
+	orderService     service.IOrderService
+	orderBookService service.IOrderBookService
+
+	// quotes quote id -> quote, held in memory until accepted or expired.
+	quotes map[string]*dto.ConvertQuote
+	mu     sync.Mutex
+}
+
+func NewIConvertService(db *sql.DB,
+	balanceRepo repository.IBalanceRepository,
+	convertRepo repository.IConvertRepository,
+	orderService service.IOrderService,
+	orderBookService service.IOrderBookService) service.IConvertService {
+	return &convertService{
+		db:               db,
+		balanceRepo:      balanceRepo,
+		convertRepo:      convertRepo,
+		orderService:     orderService,
+		orderBookService: orderBookService,
+		quotes:           make(map[string]*dto.ConvertQuote),
+	}
+}
+
+func (s *convertService) Quote(ctx context.Context, user *dto.User, req *dto.ConvertQuoteReq) (*dto.ConvertQuote, error) {
+	quote, err := s.priceQuote(ctx, user, req.FromAsset, req.ToAsset, utils.RoundFloat(req.FromAmount))
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := s.getAvailable(ctx, user.ID, req.FromAsset)
+	if err != nil {
+		return nil, err
+	}
+	if available < quote.FromAmount {
+		return nil, ErrConvertInsufficientBalance
+	}
+
+	quote.QuoteID = uuid.NewString()
+	quote.UserID = user.ID
+	quote.ExpiresAt = time.Now().Add(settings.CONVERT_QUOTE_TTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeExpiredQuotes()
+	s.quotes[quote.QuoteID] = quote
+
+	return quote, nil
+}
+
+// Accept re-price quoted route and refuse if it can not deliver quoted amount anymore, otherwise execute legs as IOC
+// orders at quoted limit prices. A leg not fully filled (or failed after the first one) stops the route, the convert is
+// recorded and returned as PARTIAL and unconverted amount stays in from asset or CONVERT_ROUTE_ASSET.
+func (s *convertService) Accept(ctx context.Context, user *dto.User, req *dto.ConvertAcceptReq) (*dto.Convert, error) {
+	quote, err := s.takeQuote(user.ID, req.QuoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.priceQuote(ctx, user, quote.FromAsset, quote.ToAsset, quote.FromAmount)
+	if err != nil {
+		log.Warnf("[ConvertService] re-price quote %s failed, error: %v", quote.QuoteID, err)
+		return nil, ErrConvertQuoteDeviated
+	}
+	if current.ToAmount < quote.ToAmount {
+		log.Infof("[ConvertService] quote %s deviated, quoted: %v, current: %v", quote.QuoteID, quote.ToAmount, current.ToAmount)
+		return nil, ErrConvertQuoteDeviated
+	}
+
+	convert := &dto.Convert{
+		UserID:         user.ID,
+		QuoteID:        quote.QuoteID,
+		FromAsset:      quote.FromAsset,
+		ToAsset:        quote.ToAsset,
+		QuotedToAmount: quote.ToAmount,
+		OrderIDs:       make([]string, 0, len(quote.Legs)),
+		Status:         dto.CONVERT_STATUS_COMPLETED,
+	}
+
+	var received float64
+	for i, leg := range quote.Legs {
+		size := leg.Size
+		if i > 0 && leg.Side == model.BID {
+			// previous leg may fill at worse levels than quoted, never spend more than it received
+			size = min(size, utils.FloorFloat(received/leg.Price))
+		}
+
+		orderId, spent, legReceived, filled, err := s.executeLeg(ctx, user, leg, size)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			log.Errorf("[ConvertService] quote %s leg %s failed, error: %v", quote.QuoteID, leg.Market, err)
+			convert.Status = dto.CONVERT_STATUS_PARTIAL
+			break
+		}
+		if i == 0 {
+			convert.FromAmount = spent
+		}
+		convert.OrderIDs = append(convert.OrderIDs, orderId)
+		received = legReceived
+		if !filled {
+			convert.Status = dto.CONVERT_STATUS_PARTIAL
+			break
+		}
+	}
+	if len(convert.OrderIDs) == len(quote.Legs) {
+		// last leg delivers to asset, even if it is not fully filled
+		convert.ToAmount = received
+	}
+	if convert.Status == dto.CONVERT_STATUS_PARTIAL {
+		log.Warnf("[ConvertService] quote %s route did not complete, legs executed: %d/%d", quote.QuoteID, len(convert.OrderIDs), len(quote.Legs))
+	}
+
+	if err := s.convertRepo.InsertConvert(ctx, s.db, convert); err != nil {
+		// orders are already executed, failure here only affects convert history
+		log.Errorf("[ConvertService] InsertConvert error: %v", err)
+	}
+	return convert, nil
+}
+
+func (s *convertService) GetConverts(ctx context.Context, userId string, limit int) ([]*dto.Convert, error) {
+	return s.convertRepo.GetConvertsByUserId(ctx, s.db, userId, normalizeConvertLimit(limit))
+}
+
+// executeLeg place LIMIT TAKER order and cancel its remainder (IOC).
+func (s *convertService) executeLeg(ctx context.Context, user *dto.User, leg *dto.ConvertLeg, size float64) (orderId string, spent, received float64, filled bool, err error) {
+	if size <= utils.Scale {
+		return "", 0, 0, false, ErrConvertAmountTooSmall
+	}
+
+	result, err := s.orderService.PlaceOrder(ctx, leg.Market, user, &dto.OrderReq{
+		Side:      leg.Side,
+		OrderType: model.LIMIT,
+		Mode:      model.TAKER,
+		Price:     leg.Price,
+		Size:      size,
+	})
+	if err != nil {
+		return "", 0, 0, false, fmt.Errorf("failed to place convert order: %w", err)
+	}
+	spent, received = serviceHelper.ConvertLegReceived(leg, result)
+
+	var dealtSize float64
+	for _, match := range result.Matches {
+		dealtSize += match.Size
+	}
+	filled = dealtSize >= size-utils.Scale
+	if !filled {
+		if _, err := s.orderService.CancelOrder(ctx, user.ID, result.Order.ID); err != nil {
+			log.Errorf("[ConvertService] cancel remainder of order %s failed, error: %v", result.Order.ID, err)
+		}
+	}
+	return result.Order.ID, spent, received, filled, nil
+}
+
+// priceQuote route convert through CONVERT_ROUTE_ASSET markets and price legs off current snapshot depth,
+// fees are deducted at user taker rate.
+func (s *convertService) priceQuote(ctx context.Context, user *dto.User, fromAsset, toAsset string, fromAmount float64) (*dto.ConvertQuote, error) {
+	allAssets := settings.GetAllAssets()
+	switch {
+	case !slices.Contains(allAssets, fromAsset) || !slices.Contains(allAssets, toAsset):
+		return nil, ErrConvertAsset
+	case fromAsset == toAsset:
+		return nil, ErrConvertSameAsset
+	}
+
+	quote := &dto.ConvertQuote{
+		FromAsset:  fromAsset,
+		ToAsset:    toAsset,
+		FromAmount: fromAmount,
+		Legs:       make([]*dto.ConvertLeg, 0, 2),
+	}
+
+	amount := fromAmount
+	if fromAsset != settings.CONVERT_ROUTE_ASSET {
+		leg, err := s.priceLeg(ctx, user, fromAsset, model.ASK, amount)
+		if err != nil {
+			return nil, err
+		}
+		quote.Legs = append(quote.Legs, leg)
+		amount = leg.ToAmount
+	}
+	if toAsset != settings.CONVERT_ROUTE_ASSET {
+		leg, err := s.priceLeg(ctx, user, toAsset, model.BID, amount)
+		if err != nil {
+			return nil, err
+		}
+		quote.Legs = append(quote.Legs, leg)
+		amount = leg.ToAmount
+	}
+
+	if amount <= utils.Scale {
+		return nil, ErrConvertAmountTooSmall
+	}
+	quote.ToAmount = amount
+	quote.Price = utils.RoundFloat(amount / fromAmount)
+	return quote, nil
+}
+
+func (s *convertService) priceLeg(ctx context.Context, user *dto.User, baseAsset string, side model.Side, amount float64) (*dto.ConvertLeg, error) {
+	marketName := baseAsset + "-" + settings.CONVERT_ROUTE_ASSET
+	snapshot, err := s.orderBookService.GetSnapshot(ctx, marketName)
+	if err != nil {
+		return nil, ErrConvertAsset
+	}
+
+	feeReq := &dto.OrderReq{Side: side, OrderType: model.LIMIT, Mode: model.TAKER}
+	_, feeRate := serviceHelper.DetermineFeeInfo(feeReq, user, baseAsset, settings.CONVERT_ROUTE_ASSET)
+
+	var leg *dto.ConvertLeg
+	var ok bool
+	if side == model.ASK {
+		leg, ok = serviceHelper.PriceConvertAskLeg(marketName, baseAsset, settings.CONVERT_ROUTE_ASSET, snapshot.BidSide, amount, feeRate)
+	} else {
+		leg, ok = serviceHelper.PriceConvertBidLeg(marketName, baseAsset, settings.CONVERT_ROUTE_ASSET, snapshot.AskSide, amount, feeRate)
+	}
+	if !ok {
+		return nil, ErrConvertInsufficientDepth
+	}
+	if leg.Size <= utils.Scale || leg.ToAmount <= utils.Scale {
+		return nil, ErrConvertAmountTooSmall
+	}
+	return leg, nil
+}
+
+// takeQuote quote can be accepted once by its owner.
+func (s *convertService) takeQuote(userId, quoteId string) (*dto.ConvertQuote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quote, ok := s.quotes[quoteId]
+	if !ok || quote.UserID != userId {
+		return nil, ErrConvertQuoteNotFound
+	}
+	delete(s.quotes, quoteId)
+
+	if time.Now().After(quote.ExpiresAt) {
+		return nil, ErrConvertQuoteExpired
+	}
+	return quote, nil
+}
+
+// removeExpiredQuotes must be called with mu held.
+func (s *convertService) removeExpiredQuotes() {
+	now := time.Now()
+	for id, quote := range s.quotes {
+		if now.After(quote.ExpiresAt) {
+			delete(s.quotes, id)
+		}
+	}
+}
+
+func (s *convertService) getAvailable(ctx context.Context, userId, asset string) (float64, error) {
+	balances, err := s.balanceRepo.GetBalancesByUserId(ctx, s.db, userId)
+	if err != nil {
+		return 0, err
+	}
+	for _, balance := range balances {
+		if balance.Asset == asset {
+			return balance.Available, nil
+		}
+	}
+	return 0, nil
+}
+
+func normalizeConvertLimit(limit int) int {
+	if limit <= 0 {
+		return defaultConvertLimit
+	}
+	return min(limit, convertMaxLimit)
+}
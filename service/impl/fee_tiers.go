@@ -0,0 +1,199 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+const feeTierVolumeWindow = 30 * 24 * time.Hour
+
+var ErrUnknownMarketMakerTier = errors.New("unknown market maker tier")
+
+type feeTierService struct {
+	db              *sql.DB
+	userRepo        repository.IUserRepository
+	tradeRepo       repository.ITradeRepository
+	feeScheduleRepo repository.IFeeScheduleRepository
+	sessionStore    security.SessionStore
+	auditLogRepo    repository.IAuditLogRepository
+}
+
+func NewIFeeTierService(db *sql.DB,
+	userRepo repository.IUserRepository,
+	tradeRepo repository.ITradeRepository,
+	feeScheduleRepo repository.IFeeScheduleRepository,
+	sessionStore security.SessionStore,
+	auditLogRepo repository.IAuditLogRepository) service.IFeeTierService {
+	return &feeTierService{
+		db:              db,
+		userRepo:        userRepo,
+		tradeRepo:       tradeRepo,
+		feeScheduleRepo: feeScheduleRepo,
+		sessionStore:    sessionStore,
+		auditLogRepo:    auditLogRepo,
+	}
+}
+
+func (s *feeTierService) GetFeeSchedules(ctx context.Context) ([]*dto.FeeSchedule, error) {
+	return s.feeScheduleRepo.GetFeeSchedules(ctx, s.db)
+}
+
+// RecalculateAll update every user's vip level and fee rates by rolling 30-day quote volume.
+// Users on a level not listed in fee schedules (system accounts, designated market makers) are skipped.
+func (s *feeTierService) RecalculateAll(ctx context.Context) error {
+	schedules, err := s.feeScheduleRepo.GetFeeSchedules(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		log.Warnf("[FeeTierService] no fee schedule found, skip recalculate")
+		return nil
+	}
+
+	managedLevels := make(map[int]bool, len(schedules))
+	for _, schedule := range schedules {
+		managedLevels[schedule.VipLevel] = true
+	}
+
+	users, err := s.userRepo.GetAllUsers(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	volumes, err := s.tradeRepo.GetUsersQuoteVolumeSince(ctx, s.db, time.Now().Add(-feeTierVolumeWindow))
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, user := range users {
+		if !managedLevels[user.VipLevel] {
+			continue
+		}
+
+		volume := volumes[user.ID]
+		schedule, _ := dto.MatchFeeSchedule(schedules, volume)
+		if schedule == nil {
+			continue
+		}
+		if schedule.VipLevel == user.VipLevel && schedule.MakerFee == user.MakerFee && schedule.TakerFee == user.TakerFee {
+			continue
+		}
+
+		if err = s.updateUserTier(ctx, user, schedule, volume, nil); err != nil {
+			log.Errorf("[FeeTierService] update user: %s tier failed, error: %v", user.ID, err)
+			continue
+		}
+		changed++
+	}
+
+	log.Infof("[FeeTierService] recalculate done, users: %d, changed: %d", len(users), changed)
+	return nil
+}
+
+// AssignMarketMakerTier set user to designated market maker tier, maker fee rate of these tiers can be negative (rebate).
+func (s *feeTierService) AssignMarketMakerTier(ctx context.Context, username string, vipLevel int, auditLog *dto.AuditLog) (*dto.User, error) {
+	feeRate, ok := settings.MARKET_MAKER_FEE_TIERS[vipLevel]
+	if !ok {
+		return nil, ErrUnknownMarketMakerTier
+	}
+
+	user, err := s.userRepo.GetUserByUsername(ctx, s.db, username)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := s.tradeRepo.GetUserQuoteVolumeSince(ctx, s.db, user.ID, time.Now().Add(-feeTierVolumeWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &dto.FeeSchedule{
+		VipLevel: vipLevel,
+		MakerFee: feeRate.MakerFee,
+		TakerFee: feeRate.TakerFee,
+	}
+	if err = s.updateUserTier(ctx, user, schedule, volume, auditLog); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// updateUserTier auditLog is appended in the same tx with fee rates before and after if not nil.
+func (s *feeTierService) updateUserTier(ctx context.Context, user *dto.User, schedule *dto.FeeSchedule, volume float64, auditLog *dto.AuditLog) error {
+	fromLevel := user.VipLevel
+	if auditLog != nil {
+		auditLog.Target = user.ID
+		auditLog.Before = auditValue(feeTierAuditValue(user.VipLevel, user.MakerFee, user.TakerFee))
+		auditLog.After = auditValue(feeTierAuditValue(schedule.VipLevel, schedule.MakerFee, schedule.TakerFee))
+	}
+
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		user.VipLevel = schedule.VipLevel
+		user.MakerFee = schedule.MakerFee
+		user.TakerFee = schedule.TakerFee
+		if err := s.userRepo.UpdateVipLevel(ctx, tx, user); err != nil {
+			return err
+		}
+		err := s.feeScheduleRepo.InsertVipLevelHistory(ctx, tx, &dto.VipLevelHistory{
+			UserID:       user.ID,
+			FromVipLevel: fromLevel,
+			ToVipLevel:   schedule.VipLevel,
+			MakerFee:     schedule.MakerFee,
+			TakerFee:     schedule.TakerFee,
+			Volume30D:    volume,
+			CreatedAt:    time.Now(),
+		})
+		if err != nil || auditLog == nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, auditLog)
+	})
+	if err != nil {
+		return err
+	}
+
+	// logged-in sessions keep user snapshot, refresh it to apply new fee rates immediately.
+	s.sessionStore.RefreshUser(user)
+	log.Infof("[FeeTierService] user: %s vip level %d -> %d, 30d volume: %.2f", user.ID, fromLevel, schedule.VipLevel, volume)
+	return nil
+}
+
+func (s *feeTierService) GetTierProgress(ctx context.Context, user *dto.User) (*dto.FeeTierProgress, error) {
+	schedules, err := s.feeScheduleRepo.GetFeeSchedules(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := s.tradeRepo.GetUserQuoteVolumeSince(ctx, s.db, user.ID, time.Now().Add(-feeTierVolumeWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &dto.FeeTierProgress{
+		VipLevel:  user.VipLevel,
+		Volume30D: volume,
+	}
+
+	_, next := dto.MatchFeeSchedule(schedules, volume)
+	if next != nil && next.VipLevel > user.VipLevel {
+		progress.NextVipLevel = next.VipLevel
+		progress.NextLevelVolume = next.MinVolume
+		progress.VolumeToNextLevel = next.MinVolume - volume
+	}
+
+	return progress, nil
+}
+
+func (s *feeTierService) GetVipLevelHistories(ctx context.Context, userId string) ([]*dto.VipLevelHistory, error) {
+	return s.feeScheduleRepo.GetVipLevelHistoriesByUserId(ctx, s.db, userId)
+}
+
+func feeTierAuditValue(vipLevel int, makerFee, takerFee float64) map[string]any {
+	return map[string]any{"vip_level": vipLevel, "maker_fee": makerFee, "taker_fee": takerFee}
+}
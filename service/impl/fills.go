@@ -0,0 +1,50 @@
+package serviceImpl
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+)
+
+const (
+	defaultFillsQueryLimit = 50
+	fillsMaxLimit          = 500
+)
+
+// GetFills query user fills by cursor (trade id), both sides of a self-trade are always in the same page.
+func (s *orderService) GetFills(ctx context.Context, userId string, query *dto.GetFillsQueryReq) (*dto.FillsResp, error) {
+	if query == nil || query.Cursor < 0 || (query.EndTime > 0 && query.StartTime > query.EndTime) {
+		return nil, ErrInvalidInput
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultFillsQueryLimit
+	}
+	limit = min(limit, fillsMaxLimit)
+
+	// fetch 2 more rows to know if there is next page and not to split a self-trade.
+	fills, err := s.tradeRepo.GetFillsByUserId(ctx, s.db, userId, query, limit+2)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.FillsResp{Fills: fills}
+	if len(fills) <= limit {
+		return resp, nil
+	}
+
+	end := limit
+	if fills[end].TradeID == fills[end-1].TradeID {
+		if end > 1 && fills[end-2].TradeID != fills[end-1].TradeID {
+			end--
+		} else {
+			end++
+		}
+	}
+	if end < len(fills) {
+		resp.Fills = fills[:end]
+		resp.NextCursor = fills[end-1].TradeID
+	}
+
+	return resp, nil
+}
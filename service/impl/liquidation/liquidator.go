@@ -0,0 +1,313 @@
+package liquidation
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+)
+
+// RiskLevel of margin account by margin ratio.
+type RiskLevel int
+
+const (
+	RISK_NORMAL      RiskLevel = iota
+	RISK_MARGIN_CALL           // margin ratio < settings.MARGIN_CALL_RATIO
+	RISK_LIQUIDATION           // margin ratio < settings.MARGIN_MAINTENANCE_RATIO
+)
+
+// GetRiskLevel account without debt is always normal.
+func GetRiskLevel(risk *dto.MarginRisk) RiskLevel {
+	switch {
+	case risk.DebtValue <= 0:
+		return RISK_NORMAL
+	case risk.MarginRatio < settings.MARGIN_MAINTENANCE_RATIO:
+		return RISK_LIQUIDATION
+	case risk.MarginRatio < settings.MARGIN_CALL_RATIO:
+		return RISK_MARGIN_CALL
+	default:
+		return RISK_NORMAL
+	}
+}
+
+// PriceSource USDT price of assets, matching engine latest price in service, fake prices in tests.
+type PriceSource interface {
+	GetUSDTPrices(ctx context.Context, assets []string) (map[string]float64, error)
+}
+
+// ILiquidationFuncProxy exchange functions used by Liquidator.
+type ILiquidationFuncProxy interface {
+	GetMarginAccountsWithLoans(ctx context.Context) ([]*dto.MarginAccount, error)
+	// GetMarginAssets margin wallet balances and outstanding loans.
+	GetMarginAssets(ctx context.Context, account *dto.MarginAccount) ([]*dto.Balance, []*dto.MarginLoan, error)
+	NotifyMarginCall(ctx context.Context, account *dto.MarginAccount, risk *dto.MarginRisk) error
+	// CancelOpenOrders cancel all open orders of margin wallet, return canceled order ids.
+	CancelOpenOrders(ctx context.Context, account *dto.MarginAccount) ([]string, error)
+	PlaceOrder(ctx context.Context, account *dto.MarginAccount, market string, req *dto.OrderReq) (*dto.PlaceOrderResult, error)
+	Repay(ctx context.Context, account *dto.MarginAccount, asset string, amount float64) (*dto.MarginLoanRecord, error)
+	// CoverShortfall insurance fund repay the remaining loan to lending pool.
+	CoverShortfall(ctx context.Context, account *dto.MarginAccount, asset string, principal, interest float64) error
+	SaveLiquidation(ctx context.Context, liquidation *dto.Liquidation) error
+}
+
+type Liquidator struct {
+	proxy       ILiquidationFuncProxy
+	priceSource PriceSource
+}
+
+func NewLiquidator(proxy ILiquidationFuncProxy, priceSource PriceSource) *Liquidator {
+	return &Liquidator{
+		proxy:       proxy,
+		priceSource: priceSource,
+	}
+}
+
+// CheckAll check all margin accounts with loans, failure of one account is logged and skipped.
+func (l *Liquidator) CheckAll(ctx context.Context) error {
+	accounts, err := l.proxy.GetMarginAccountsWithLoans(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		if _, err := l.Check(ctx, account); err != nil {
+			log.Errorf("[Liquidator] check margin account of user %s failed, error: %v", account.UserID, err)
+		}
+	}
+	return nil
+}
+
+// Check issue margin call or liquidate margin account by its risk level.
+func (l *Liquidator) Check(ctx context.Context, account *dto.MarginAccount) (RiskLevel, error) {
+	_, _, _, risk, err := l.evaluate(ctx, account)
+	if err != nil {
+		return RISK_NORMAL, err
+	}
+
+	level := GetRiskLevel(risk)
+	switch level {
+	case RISK_MARGIN_CALL:
+		return level, l.proxy.NotifyMarginCall(ctx, account, risk)
+	case RISK_LIQUIDATION:
+		return level, l.liquidate(ctx, account, risk)
+	default:
+		return level, nil
+	}
+}
+
+func (l *Liquidator) evaluate(ctx context.Context, account *dto.MarginAccount) ([]*dto.Balance, []*dto.MarginLoan, map[string]float64, *dto.MarginRisk, error) {
+	balances, loans, err := l.proxy.GetMarginAssets(ctx, account)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	assets := make([]string, 0, len(balances)+len(loans))
+	for _, balance := range balances {
+		if balance.Available+balance.Locked > 0 {
+			assets = append(assets, balance.Asset)
+		}
+	}
+	for _, loan := range loans {
+		assets = append(assets, loan.Asset)
+	}
+	prices, err := l.priceSource.GetUSDTPrices(ctx, assets)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return balances, loans, prices, serviceHelper.CalculateMarginRisk(balances, loans, prices), nil
+}
+
+// liquidate cancel open orders, sell collateral into USDT, buy loan assets, repay loans and
+// cover shortfall by insurance fund once no collateral is left. Every step is recorded in liquidation details,
+// failed steps mark liquidation FAILED and account is liquidated again by next check.
+func (l *Liquidator) liquidate(ctx context.Context, account *dto.MarginAccount, risk *dto.MarginRisk) error {
+	log.Warnf("[Liquidator] liquidate margin account of user %s, margin ratio: %v, asset value: %v, debt value: %v",
+		account.UserID, risk.MarginRatio, risk.AssetValue, risk.DebtValue)
+
+	liquidation := &dto.Liquidation{
+		UserID:       account.UserID,
+		MarginUserID: account.MarginUserID,
+		MarginRatio:  risk.MarginRatio,
+		AssetValue:   risk.AssetValue,
+		DebtValue:    risk.DebtValue,
+		Status:       dto.LIQUIDATION_STATUS_COMPLETED,
+	}
+
+	// 1. cancel open orders to release locked collateral.
+	canceled, err := l.proxy.CancelOpenOrders(ctx, account)
+	liquidation.Details.CanceledOrderIDs = canceled
+	if err != nil {
+		return l.fail(ctx, liquidation, fmt.Errorf("failed to cancel open orders: %w", err))
+	}
+
+	// 2. sell collateral not needed to repay loan of same asset.
+	balances, loans, _, _, err := l.evaluate(ctx, account)
+	if err != nil {
+		return l.fail(ctx, liquidation, err)
+	}
+	debts := debtsByAsset(loans)
+	for _, balance := range balances {
+		if balance.Asset == "USDT" {
+			continue
+		}
+		size := utils.RoundFloat(balance.Available - debts[balance.Asset])
+		if size <= utils.Scale {
+			continue
+		}
+		err := l.placeOrder(ctx, account, liquidation, balance.Asset, &dto.OrderReq{
+			Side:      model.ASK,
+			OrderType: model.MARKET,
+			Mode:      model.TAKER,
+			Size:      size,
+		})
+		if err != nil {
+			return l.fail(ctx, liquidation, err)
+		}
+	}
+
+	// 3. buy loan assets lacking with USDT left after reserving USDT loan.
+	balances, loans, prices, _, err := l.evaluate(ctx, account)
+	if err != nil {
+		return l.fail(ctx, liquidation, err)
+	}
+	debts = debtsByAsset(loans)
+	available := availableByAsset(balances)
+	spendable := available["USDT"] - debts["USDT"]
+	for _, loan := range loans {
+		if loan.Asset == "USDT" {
+			continue
+		}
+		lack := debts[loan.Asset] - available[loan.Asset]
+		quoteAmount := utils.RoundFloat(min(lack*prices[loan.Asset]*(1+settings.LIQUIDATION_SLIPPAGE), spendable))
+		if lack <= utils.Scale || quoteAmount <= utils.Scale {
+			continue
+		}
+		spendable -= quoteAmount
+		err := l.placeOrder(ctx, account, liquidation, loan.Asset, &dto.OrderReq{
+			Side:        model.BID,
+			OrderType:   model.MARKET,
+			Mode:        model.TAKER,
+			QuoteAmount: quoteAmount,
+		})
+		if err != nil {
+			return l.fail(ctx, liquidation, err)
+		}
+	}
+
+	// 4. repay loans with margin wallet balances.
+	balances, loans, err = l.proxy.GetMarginAssets(ctx, account)
+	if err != nil {
+		return l.fail(ctx, liquidation, err)
+	}
+	available = availableByAsset(balances)
+	for _, loan := range loans {
+		amount := utils.RoundFloat(min(available[loan.Asset], loan.Principal+loan.Interest))
+		if amount <= utils.Scale {
+			continue
+		}
+		record, err := l.proxy.Repay(ctx, account, loan.Asset, amount)
+		if err != nil {
+			return l.fail(ctx, liquidation, fmt.Errorf("failed to repay %s loan: %w", loan.Asset, err))
+		}
+		liquidation.Details.Repayments = append(liquidation.Details.Repayments, &dto.LiquidationRepayment{
+			Asset:     record.Asset,
+			Principal: record.Principal,
+			Interest:  record.Interest,
+		})
+	}
+
+	// 5. cover shortfall by insurance fund, only after all collateral is sold (orders may be partially filled).
+	balances, loans, err = l.proxy.GetMarginAssets(ctx, account)
+	if err != nil {
+		return l.fail(ctx, liquidation, err)
+	}
+	if len(loans) > 0 {
+		if asset, ok := remainingCollateral(balances); ok {
+			return l.fail(ctx, liquidation, fmt.Errorf("%s collateral left after liquidation orders, shortfall is not covered", asset))
+		}
+	}
+	for _, loan := range loans {
+		shortfall := &dto.LiquidationShortfall{
+			Asset:     loan.Asset,
+			Principal: loan.Principal,
+			Interest:  loan.Interest,
+			Value:     utils.RoundFloat((loan.Principal + loan.Interest) * prices[loan.Asset]),
+		}
+		if err := l.proxy.CoverShortfall(ctx, account, loan.Asset, loan.Principal, loan.Interest); err != nil {
+			log.Errorf("[Liquidator] insurance fund failed to cover %s shortfall of user %s, error: %v", loan.Asset, account.UserID, err)
+			liquidation.Status = dto.LIQUIDATION_STATUS_BAD_DEBT
+		} else {
+			shortfall.Covered = true
+		}
+		liquidation.Details.Shortfalls = append(liquidation.Details.Shortfalls, shortfall)
+	}
+
+	log.Infof("[Liquidator] liquidated margin account of user %s, status: %s, orders: %d, repayments: %d, shortfalls: %d",
+		account.UserID, liquidation.Status, len(liquidation.Details.Orders), len(liquidation.Details.Repayments), len(liquidation.Details.Shortfalls))
+	return l.proxy.SaveLiquidation(ctx, liquidation)
+}
+
+// placeOrder place market order of asset-USDT market, failure is recorded in liquidation details and returned.
+func (l *Liquidator) placeOrder(ctx context.Context, account *dto.MarginAccount, liquidation *dto.Liquidation, asset string, req *dto.OrderReq) error {
+	order := &dto.LiquidationOrder{
+		Market:      fmt.Sprintf("%v-USDT", asset),
+		Side:        req.Side,
+		Size:        req.Size,
+		QuoteAmount: req.QuoteAmount,
+	}
+	liquidation.Details.Orders = append(liquidation.Details.Orders, order)
+
+	result, err := l.proxy.PlaceOrder(ctx, account, order.Market, req)
+	if err != nil {
+		log.Errorf("[Liquidator] failed to place liquidation order of user %s, market: %s, error: %v", account.UserID, order.Market, err)
+		order.Error = err.Error()
+		return fmt.Errorf("failed to place liquidation order of %s: %w", order.Market, err)
+	}
+
+	order.OrderID = result.Order.ID
+	for _, match := range result.Matches {
+		order.FilledSize += match.Size
+	}
+	order.FilledSize = utils.RoundFloat(order.FilledSize)
+	return nil
+}
+
+func (l *Liquidator) fail(ctx context.Context, liquidation *dto.Liquidation, err error) error {
+	liquidation.Status = dto.LIQUIDATION_STATUS_FAILED
+	liquidation.Error = err.Error()
+	if saveErr := l.proxy.SaveLiquidation(ctx, liquidation); saveErr != nil {
+		log.Errorf("[Liquidator] failed to save liquidation of user %s, error: %v", liquidation.UserID, saveErr)
+	}
+	return err
+}
+
+func debtsByAsset(loans []*dto.MarginLoan) map[string]float64 {
+	debts := make(map[string]float64, len(loans))
+	for _, loan := range loans {
+		debts[loan.Asset] += loan.Principal + loan.Interest
+	}
+	return debts
+}
+
+// remainingCollateral first asset with balance left in margin wallet.
+func remainingCollateral(balances []*dto.Balance) (string, bool) {
+	for _, balance := range balances {
+		if balance.Available+balance.Locked > utils.Scale {
+			return balance.Asset, true
+		}
+	}
+	return "", false
+}
+
+func availableByAsset(balances []*dto.Balance) map[string]float64 {
+	available := make(map[string]float64, len(balances))
+	for _, balance := range balances {
+		available[balance.Asset] = balance.Available
+	}
+	return available
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service/impl/liquidation"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func assert(t *testing.T, a, b any) {
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
+
+func assertFloat(t *testing.T, a, b float64) {
+	if math.Abs(a-b) > 1e-6 {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
+
+func check(t *testing.T, proxy *MockLiquidationFuncProxy) liquidation.RiskLevel {
+	liquidator := liquidation.NewLiquidator(proxy, proxy.PriceSource)
+	level, err := liquidator.Check(context.Background(), proxy.Account)
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	return level
+}
+
+func Test_Liquidation_GetRiskLevel(t *testing.T) {
+	assert(t, liquidation.GetRiskLevel(&dto.MarginRisk{MarginRatio: 0}), liquidation.RISK_NORMAL)
+	assert(t, liquidation.GetRiskLevel(&dto.MarginRisk{DebtValue: 1, MarginRatio: 0.2}), liquidation.RISK_NORMAL)
+	assert(t, liquidation.GetRiskLevel(&dto.MarginRisk{DebtValue: 1, MarginRatio: 0.12}), liquidation.RISK_MARGIN_CALL)
+	assert(t, liquidation.GetRiskLevel(&dto.MarginRisk{DebtValue: 1, MarginRatio: 0.05}), liquidation.RISK_LIQUIDATION)
+	assert(t, liquidation.GetRiskLevel(&dto.MarginRisk{DebtValue: 1, MarginRatio: -0.1}), liquidation.RISK_LIQUIDATION)
+}
+
+func Test_Liquidation_Check_Normal(t *testing.T) {
+	proxy := newShortEthProxy(3000) // ratio 0.25
+	assert(t, check(t, proxy), liquidation.RISK_NORMAL)
+	assert(t, len(proxy.MarginCalls), 0)
+	assert(t, len(proxy.Liquidations), 0)
+}
+
+func Test_Liquidation_Check_MarginCall(t *testing.T) {
+	proxy := newShortEthProxy(3500) // ratio 0.125
+	assert(t, check(t, proxy), liquidation.RISK_MARGIN_CALL)
+	assert(t, len(proxy.MarginCalls), 1)
+	assert(t, len(proxy.Liquidations), 0)
+	assert(t, proxy.OpenOrderIDs, []string{"O01"})
+}
+
+func Test_Liquidation_Check_Liquidate(t *testing.T) {
+	proxy := newShortEthProxy(3700) // ratio 0.075
+	assert(t, check(t, proxy), liquidation.RISK_LIQUIDATION)
+	assert(t, len(proxy.Liquidations), 1)
+
+	l := proxy.Liquidations[0]
+	assert(t, l.Status, dto.LIQUIDATION_STATUS_COMPLETED)
+	assert(t, l.Details.CanceledOrderIDs, []string{"O01"})
+	assert(t, len(l.Details.Orders), 1)
+	assertFloat(t, l.Details.Orders[0].QuoteAmount, 3885) // 1 ETH * 3700 * (1 + slippage)
+	assert(t, len(l.Details.Repayments), 1)
+	assertFloat(t, l.Details.Repayments[0].Principal, 1)
+	assert(t, len(l.Details.Shortfalls), 0)
+
+	assertFloat(t, proxy.Loans["ETH"].Principal, 0)
+	assertFloat(t, proxy.Balances["USDT"], 115)
+	assertFloat(t, proxy.Balances["ETH"], 0.05)
+}
+
+func Test_Liquidation_Check_ShortfallCoveredByInsurance(t *testing.T) {
+	proxy := newShortEthProxy(4200) // equity < 0
+	assert(t, check(t, proxy), liquidation.RISK_LIQUIDATION)
+
+	l := proxy.Liquidations[0]
+	assert(t, l.Status, dto.LIQUIDATION_STATUS_COMPLETED)
+	assertFloat(t, l.Details.Orders[0].QuoteAmount, 4000) // all USDT
+	assert(t, len(l.Details.Shortfalls), 1)
+	assert(t, l.Details.Shortfalls[0].Covered, true)
+	assertFloat(t, l.Details.Shortfalls[0].Principal, 1-4000.0/4200)
+	assertFloat(t, proxy.Insurance["ETH"], 4000.0/4200)
+	assert(t, len(proxy.Loans), 0)
+}
+
+func Test_Liquidation_Check_BadDebt(t *testing.T) {
+	proxy := newShortEthProxy(4200)
+	proxy.Insurance = map[string]float64{}
+	assert(t, check(t, proxy), liquidation.RISK_LIQUIDATION)
+
+	l := proxy.Liquidations[0]
+	assert(t, l.Status, dto.LIQUIDATION_STATUS_BAD_DEBT)
+	assert(t, l.Details.Shortfalls[0].Covered, false)
+	assertFloat(t, proxy.Loans["ETH"].Principal, 1-4000.0/4200)
+}
+
+func Test_Liquidation_Check_PlaceOrderFailed(t *testing.T) {
+	proxy := newShortEthProxy(4200)
+	proxy.PlaceOrderErr = errors.New("market closed")
+	liquidator := liquidation.NewLiquidator(proxy, proxy.PriceSource)
+	if _, err := liquidator.Check(context.Background(), proxy.Account); err == nil {
+		t.Fatal("Expected liquidation error")
+	}
+
+	l := proxy.Liquidations[0]
+	assert(t, l.Status, dto.LIQUIDATION_STATUS_FAILED)
+	assert(t, l.Details.Orders[0].Error, "market closed")
+	assert(t, len(l.Details.Shortfalls), 0)
+	// insurance fund does not pay while collateral is not sold.
+	assertFloat(t, proxy.Insurance["ETH"], 1)
+	assertFloat(t, proxy.Loans["ETH"].Principal, 1)
+	assertFloat(t, proxy.Balances["USDT"], 4000)
+}
+
+func Test_Liquidation_Check_PartiallyFilledRetried(t *testing.T) {
+	proxy := newShortEthProxy(4200)
+	proxy.FillRatio = 0.5
+	liquidator := liquidation.NewLiquidator(proxy, proxy.PriceSource)
+	if _, err := liquidator.Check(context.Background(), proxy.Account); err == nil {
+		t.Fatal("Expected liquidation error")
+	}
+
+	l := proxy.Liquidations[0]
+	assert(t, l.Status, dto.LIQUIDATION_STATUS_FAILED)
+	assertFloat(t, l.Details.Repayments[0].Principal, 2000.0/4200)
+	assert(t, len(l.Details.Shortfalls), 0)
+	assertFloat(t, proxy.Insurance["ETH"], 1)
+	assertFloat(t, proxy.Balances["USDT"], 2000)
+
+	// next check sells remaining collateral, then shortfall is covered.
+	proxy.FillRatio = 1
+	assert(t, check(t, proxy), liquidation.RISK_LIQUIDATION)
+	l = proxy.Liquidations[1]
+	assert(t, l.Status, dto.LIQUIDATION_STATUS_COMPLETED)
+	assert(t, l.Details.Shortfalls[0].Covered, true)
+	assertFloat(t, proxy.Insurance["ETH"], 4000.0/4200)
+	assert(t, len(proxy.Loans), 0)
+}
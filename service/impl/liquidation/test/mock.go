@@ -0,0 +1,144 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"strings"
+)
+
+// FakePriceSource fixed USDT prices of assets.
+type FakePriceSource struct {
+	Prices map[string]float64
+}
+
+func (f *FakePriceSource) GetUSDTPrices(ctx context.Context, assets []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(assets))
+	for _, asset := range assets {
+		if asset == "USDT" {
+			prices[asset] = 1
+			continue
+		}
+		price, ok := f.Prices[asset]
+		if !ok {
+			return nil, fmt.Errorf("no price available for asset %s", asset)
+		}
+		prices[asset] = price
+	}
+	return prices, nil
+}
+
+// MockLiquidationFuncProxy in memory margin wallet, market orders are filled at fake price,
+// fully unless FillRatio is set, or fail with PlaceOrderErr.
+type MockLiquidationFuncProxy struct {
+	Account       *dto.MarginAccount
+	PlaceOrderErr error
+	FillRatio     float64
+	Balances      map[string]float64
+	Loans         map[string]*dto.MarginLoan
+	OpenOrderIDs  []string
+	Insurance     map[string]float64
+	PriceSource   *FakePriceSource
+
+	MarginCalls  []*dto.MarginRisk
+	Liquidations []*dto.Liquidation
+}
+
+func (m *MockLiquidationFuncProxy) GetMarginAccountsWithLoans(ctx context.Context) ([]*dto.MarginAccount, error) {
+	return []*dto.MarginAccount{m.Account}, nil
+}
+
+func (m *MockLiquidationFuncProxy) GetMarginAssets(ctx context.Context, account *dto.MarginAccount) ([]*dto.Balance, []*dto.MarginLoan, error) {
+	var balances []*dto.Balance
+	for asset, available := range m.Balances {
+		balances = append(balances, &dto.Balance{Asset: asset, Available: available})
+	}
+	var loans []*dto.MarginLoan
+	for _, loan := range m.Loans {
+		if loan.Principal+loan.Interest > 0 {
+			copied := *loan
+			loans = append(loans, &copied)
+		}
+	}
+	return balances, loans, nil
+}
+
+func (m *MockLiquidationFuncProxy) NotifyMarginCall(ctx context.Context, account *dto.MarginAccount, risk *dto.MarginRisk) error {
+	m.MarginCalls = append(m.MarginCalls, risk)
+	return nil
+}
+
+func (m *MockLiquidationFuncProxy) CancelOpenOrders(ctx context.Context, account *dto.MarginAccount) ([]string, error) {
+	canceled := m.OpenOrderIDs
+	m.OpenOrderIDs = nil
+	return canceled, nil
+}
+
+func (m *MockLiquidationFuncProxy) PlaceOrder(ctx context.Context, account *dto.MarginAccount, market string, req *dto.OrderReq) (*dto.PlaceOrderResult, error) {
+	if m.PlaceOrderErr != nil {
+		return nil, m.PlaceOrderErr
+	}
+	asset := strings.TrimSuffix(market, "-USDT")
+	price := m.PriceSource.Prices[asset]
+	fillRatio := m.FillRatio
+	if fillRatio == 0 {
+		fillRatio = 1
+	}
+
+	size := utils.RoundFloat(req.Size * fillRatio)
+	if req.Side == model.ASK {
+		m.Balances[asset] = utils.RoundFloat(m.Balances[asset] - size)
+		m.Balances["USDT"] = utils.RoundFloat(m.Balances["USDT"] + size*price)
+	} else {
+		quoteAmount := utils.RoundFloat(req.QuoteAmount * fillRatio)
+		size = utils.RoundFloat(quoteAmount / price)
+		m.Balances["USDT"] = utils.RoundFloat(m.Balances["USDT"] - quoteAmount)
+		m.Balances[asset] = utils.RoundFloat(m.Balances[asset] + size)
+	}
+
+	return &dto.PlaceOrderResult{
+		Order:   dto.Order{ID: fmt.Sprintf("L-%s-%d", market, req.Side)},
+		Matches: []*dto.Match{{Price: price, Size: size}},
+	}, nil
+}
+
+func (m *MockLiquidationFuncProxy) Repay(ctx context.Context, account *dto.MarginAccount, asset string, amount float64) (*dto.MarginLoanRecord, error) {
+	loan := m.Loans[asset]
+	interest := min(amount, loan.Interest)
+	principal := min(amount-interest, loan.Principal)
+	loan.Interest = utils.RoundFloat(loan.Interest - interest)
+	loan.Principal = utils.RoundFloat(loan.Principal - principal)
+	m.Balances[asset] = utils.RoundFloat(m.Balances[asset] - interest - principal)
+	return &dto.MarginLoanRecord{Asset: asset, Type: dto.MARGIN_LOAN_REPAY, Principal: principal, Interest: interest}, nil
+}
+
+func (m *MockLiquidationFuncProxy) CoverShortfall(ctx context.Context, account *dto.MarginAccount, asset string, principal, interest float64) error {
+	amount := utils.RoundFloat(principal + interest)
+	if m.Insurance[asset] < amount {
+		return fmt.Errorf("insurance fund insufficient")
+	}
+	m.Insurance[asset] = utils.RoundFloat(m.Insurance[asset] - amount)
+	delete(m.Loans, asset)
+	return nil
+}
+
+func (m *MockLiquidationFuncProxy) SaveLiquidation(ctx context.Context, liquidation *dto.Liquidation) error {
+	m.Liquidations = append(m.Liquidations, liquidation)
+	return nil
+}
+
+// newShortEthProxy margin wallet borrowed 1 ETH and sold it at 3000 with 1000 USDT collateral.
+func newShortEthProxy(ethPrice float64) *MockLiquidationFuncProxy {
+	return &MockLiquidationFuncProxy{
+		Account:  &dto.MarginAccount{UserID: "U01", MarginUserID: "M01"},
+		Balances: map[string]float64{"USDT": 4000},
+		Loans: map[string]*dto.MarginLoan{
+			"ETH": {UserID: "U01", Asset: "ETH", Principal: 1},
+		},
+		OpenOrderIDs: []string{"O01"},
+		Insurance:    map[string]float64{"ETH": 1},
+		PriceSource:  &FakePriceSource{Prices: map[string]float64{"ETH": ethPrice}},
+	}
+}
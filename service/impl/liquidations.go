@@ -0,0 +1,174 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/impl/liquidation"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var ErrInsuranceFundInsufficient = errors.New("insurance fund insufficient")
+
+const (
+	defaultLiquidationsLimit = 50
+	liquidationsMaxLimit     = 500
+)
+
+// liquidationService check margin accounts risk by liquidation.Liquidator, it is also the exchange functions proxy of Liquidator.
+type liquidationService struct {
+	db              *sql.DB
+	userRepo        repository.IUserRepository
+	balanceRepo     repository.IBalanceRepository
+	marginRepo      repository.IMarginRepository
+	liquidationRepo repository.ILiquidationRepository
+	orderService    service.IOrderService
+	marginService   service.IMarginService
+	liquidator      *liquidation.Liquidator
+}
+
+func NewILiquidationService(db *sql.DB,
+	userRepo repository.IUserRepository,
+	balanceRepo repository.IBalanceRepository,
+	marginRepo repository.IMarginRepository,
+	liquidationRepo repository.ILiquidationRepository,
+	orderService service.IOrderService,
+	marginService service.IMarginService,
+	priceSource liquidation.PriceSource) service.ILiquidationService {
+	s := &liquidationService{
+		db:              db,
+		userRepo:        userRepo,
+		balanceRepo:     balanceRepo,
+		marginRepo:      marginRepo,
+		liquidationRepo: liquidationRepo,
+		orderService:    orderService,
+		marginService:   marginService,
+	}
+	s.liquidator = liquidation.NewLiquidator(s, priceSource)
+	return s
+}
+
+func (s *liquidationService) CheckAll(ctx context.Context) error {
+	return s.liquidator.CheckAll(ctx)
+}
+
+func (s *liquidationService) GetMarginCalls(ctx context.Context, userId string, limit int) ([]*dto.MarginCall, error) {
+	return s.liquidationRepo.GetMarginCallsByUserId(ctx, s.db, userId, normalizeLiquidationsLimit(limit))
+}
+
+func (s *liquidationService) GetLiquidations(ctx context.Context, userId string, limit int) ([]*dto.Liquidation, error) {
+	return s.liquidationRepo.GetLiquidations(ctx, s.db, userId, normalizeLiquidationsLimit(limit))
+}
+
+// liquidation.ILiquidationFuncProxy >>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>
+
+func (s *liquidationService) GetMarginAccountsWithLoans(ctx context.Context) ([]*dto.MarginAccount, error) {
+	return s.marginRepo.GetAccountsWithLoans(ctx, s.db)
+}
+
+func (s *liquidationService) GetMarginAssets(ctx context.Context, account *dto.MarginAccount) ([]*dto.Balance, []*dto.MarginLoan, error) {
+	balances, err := s.balanceRepo.GetBalancesByUserId(ctx, s.db, account.MarginUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	loans, err := s.marginRepo.GetLoansByUserId(ctx, s.db, account.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return balances, loans, nil
+}
+
+// NotifyMarginCall record margin call at most once per settings.MARGIN_CALL_INTERVAL for each user.
+func (s *liquidationService) NotifyMarginCall(ctx context.Context, account *dto.MarginAccount, risk *dto.MarginRisk) error {
+	calls, err := s.liquidationRepo.GetMarginCallsByUserId(ctx, s.db, account.UserID, 1)
+	if err != nil {
+		return err
+	}
+	if len(calls) > 0 && time.Since(calls[0].CreatedAt) < settings.MARGIN_CALL_INTERVAL {
+		return nil
+	}
+
+	call := &dto.MarginCall{
+		UserID:      account.UserID,
+		MarginRatio: risk.MarginRatio,
+		AssetValue:  risk.AssetValue,
+		DebtValue:   risk.DebtValue,
+	}
+	if err := s.liquidationRepo.InsertMarginCall(ctx, s.db, call); err != nil {
+		return err
+	}
+
+	log.Warnf("[LiquidationService] margin call user %s, margin ratio: %v", account.UserID, risk.MarginRatio)
+	return nil
+}
+
+func (s *liquidationService) CancelOpenOrders(ctx context.Context, account *dto.MarginAccount) ([]string, error) {
+	orders, err := s.orderService.QueryOrder(ctx, account.MarginUserID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	canceled := make([]string, 0, len(orders))
+	for _, order := range orders {
+		if _, err := s.orderService.CancelOrder(ctx, account.MarginUserID, order.ID); err != nil {
+			return canceled, err
+		}
+		canceled = append(canceled, order.ID)
+	}
+	return canceled, nil
+}
+
+func (s *liquidationService) PlaceOrder(ctx context.Context, account *dto.MarginAccount, market string, req *dto.OrderReq) (*dto.PlaceOrderResult, error) {
+	marginUser, err := s.userRepo.GetUserById(ctx, s.db, account.MarginUserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.orderService.PlaceOrder(ctx, market, marginUser, req)
+}
+
+func (s *liquidationService) Repay(ctx context.Context, account *dto.MarginAccount, asset string, amount float64) (*dto.MarginLoanRecord, error) {
+	return s.marginService.Repay(ctx, account.UserID, &dto.MarginLoanReq{
+		Asset:  asset,
+		Amount: amount,
+	})
+}
+
+func (s *liquidationService) CoverShortfall(ctx context.Context, account *dto.MarginAccount, asset string, principal, interest float64) error {
+	amount := utils.RoundFloat(principal + interest)
+	return WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, settings.INSURANCE_FUND_ACCOUNT_ID, asset, false, amount); err != nil {
+			log.Warnf("[LiquidationService] insurance fund insufficient, asset: %s, amount: %v, error: %v", asset, amount, err)
+			return ErrInsuranceFundInsufficient
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, settings.MARGIN_LENDING_POOL_ACCOUNT_ID, asset, true, amount); err != nil {
+			return err
+		}
+		if err := s.marginRepo.DecreaseLoan(ctx, tx, account.UserID, asset, principal, interest); err != nil {
+			return err
+		}
+		return s.marginRepo.InsertLoanRecord(ctx, tx, &dto.MarginLoanRecord{
+			UserID:    account.UserID,
+			Asset:     asset,
+			Type:      dto.MARGIN_LOAN_INSURANCE,
+			Principal: principal,
+			Interest:  interest,
+		})
+	})
+}
+
+func (s *liquidationService) SaveLiquidation(ctx context.Context, liquidation *dto.Liquidation) error {
+	return s.liquidationRepo.InsertLiquidation(ctx, s.db, liquidation)
+}
+
+func normalizeLiquidationsLimit(limit int) int {
+	if limit <= 0 {
+		return defaultLiquidationsLimit
+	}
+	return min(limit, liquidationsMaxLimit)
+}
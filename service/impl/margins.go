@@ -0,0 +1,380 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var (
+	ErrMarginAccountNotFound   = errors.New("margin account not found, transfer collateral in first")
+	ErrMarginRatioTooLow       = errors.New("margin ratio would be lower than initial ratio")
+	ErrLendingPoolInsufficient = errors.New("lending pool insufficient")
+	ErrNoOutstandingMarginLoan = errors.New("no outstanding margin loan")
+)
+
+const (
+	defaultMarginRecordsLimit = 50
+	marginRecordsMaxLimit     = 500
+	// marginUsernamePrefix internal margin user has no password, can not login.
+	marginUsernamePrefix = "margin@"
+)
+
+type marginService struct {
+	db               *sql.DB
+	userRepo         repository.IUserRepository
+	balanceRepo      repository.IBalanceRepository
+	marginRepo       repository.IMarginRepository
+	transferRepo     repository.ITransferRepository
+	portfolioService service.IPortfolioService
+}
+
+func NewIMarginService(db *sql.DB,
+	userRepo repository.IUserRepository,
+	balanceRepo repository.IBalanceRepository,
+	marginRepo repository.IMarginRepository,
+	transferRepo repository.ITransferRepository,
+	portfolioService service.IPortfolioService) service.IMarginService {
+	return &marginService{
+		db:               db,
+		userRepo:         userRepo,
+		balanceRepo:      balanceRepo,
+		marginRepo:       marginRepo,
+		transferRepo:     transferRepo,
+		portfolioService: portfolioService,
+	}
+}
+
+func (s *marginService) GetAccount(ctx context.Context, userId string) (*dto.MarginAccountInfo, error) {
+	account, err := s.marginRepo.GetAccountByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, ErrMarginAccountNotFound
+	}
+
+	balances, loans, risk, err := s.getRisk(ctx, s.db, account)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MarginAccountInfo{
+		Balances:          balances,
+		Loans:             loans,
+		Risk:              risk,
+		MaintenanceRatio:  settings.MARGIN_MAINTENANCE_RATIO,
+		InitialRatio:      settings.MARGIN_INITIAL_RATIO,
+		MaxLeverage:       settings.MARGIN_MAX_LEVERAGE,
+		MaxBorrowableUSDT: serviceHelper.MaxBorrowableValue(risk),
+	}, nil
+}
+
+// Transfer move collateral between spot wallet and margin wallet (created at first transfer in),
+// transferring out is rejected if margin ratio would be lower than initial ratio.
+func (s *marginService) Transfer(ctx context.Context, user *dto.User, req *dto.MarginTransferReq) (*dto.Transfer, error) {
+	if user == nil || req == nil || req.Amount <= 0 {
+		return nil, ErrInvalidInput
+	}
+	if !isSupportedAsset(req.Asset) {
+		return nil, ErrUnsupportedAsset
+	}
+
+	var account *dto.MarginAccount
+	var err error
+	if req.Direction == dto.MARGIN_TRANSFER_IN {
+		account, err = s.getOrCreateAccount(ctx, user)
+	} else {
+		account, err = s.marginRepo.GetAccountByUserId(ctx, s.db, user.ID)
+		if err != nil {
+			err = ErrMarginAccountNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := &dto.Transfer{
+		ID:             uuid.NewString(),
+		IdempotencyKey: req.IdempotencyKey,
+		FromUserID:     user.ID,
+		ToUserID:       account.MarginUserID,
+		Asset:          req.Asset,
+		Amount:         req.Amount,
+		Type:           dto.TRANSFER_TYPE_MARGIN,
+		CreatedAt:      time.Now(),
+	}
+	if req.Direction == dto.MARGIN_TRANSFER_OUT {
+		transfer.FromUserID, transfer.ToUserID = account.MarginUserID, user.ID
+	}
+
+	if existing, err := s.transferRepo.GetTransferByIdempotencyKey(ctx, s.db, transfer.FromUserID, transfer.IdempotencyKey); err == nil {
+		return checkReplayedTransfer(existing, transfer)
+	}
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		// margin wallet is re-read in tx, concurrent borrow or transfer can not pass the check on stale balances.
+		if req.Direction == dto.MARGIN_TRANSFER_OUT {
+			if err := s.checkInitialRatio(ctx, tx, account, req.Asset, -req.Amount, 0); err != nil {
+				return err
+			}
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, transfer.FromUserID, transfer.Asset, false, transfer.Amount); err != nil {
+			log.Warnf("[MarginService] failed to decrease sender balance, %v", err)
+			return ErrInsufficientBalance
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, transfer.ToUserID, transfer.Asset, true, transfer.Amount); err != nil {
+			return err
+		}
+		return s.transferRepo.Insert(ctx, tx, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[MarginService] margin transfer %s: %s -> %s, %v %s", transfer.ID, transfer.FromUserID, transfer.ToUserID, transfer.Amount, transfer.Asset)
+	return transfer, nil
+}
+
+// Borrow asset from lending pool into margin wallet, rejected if margin ratio would be lower than initial ratio.
+func (s *marginService) Borrow(ctx context.Context, userId string, req *dto.MarginLoanReq) (*dto.MarginLoanRecord, error) {
+	if req == nil || req.Amount <= 0 {
+		return nil, ErrInvalidInput
+	}
+	if !isSupportedAsset(req.Asset) {
+		return nil, ErrUnsupportedAsset
+	}
+
+	account, err := s.marginRepo.GetAccountByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, ErrMarginAccountNotFound
+	}
+
+	record := &dto.MarginLoanRecord{
+		UserID:    userId,
+		Asset:     req.Asset,
+		Type:      dto.MARGIN_LOAN_BORROW,
+		Principal: req.Amount,
+	}
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		// borrowed amount increases both asset and debt, checked on balances and loans re-read in tx.
+		if err := s.checkInitialRatio(ctx, tx, account, req.Asset, req.Amount, req.Amount); err != nil {
+			return err
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, settings.MARGIN_LENDING_POOL_ACCOUNT_ID, req.Asset, false, req.Amount); err != nil {
+			log.Warnf("[MarginService] lending pool insufficient, asset: %s, amount: %v, error: %v", req.Asset, req.Amount, err)
+			return ErrLendingPoolInsufficient
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, account.MarginUserID, req.Asset, true, req.Amount); err != nil {
+			return err
+		}
+		if err := s.marginRepo.IncreaseLoan(ctx, tx, userId, req.Asset, req.Amount); err != nil {
+			return err
+		}
+		return s.marginRepo.InsertLoanRecord(ctx, tx, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[MarginService] user %s borrowed %v %s", userId, req.Amount, req.Asset)
+	return record, nil
+}
+
+// Repay loan from margin wallet available balance, interest first then principal, amount exceeding loan is ignored.
+func (s *marginService) Repay(ctx context.Context, userId string, req *dto.MarginLoanReq) (*dto.MarginLoanRecord, error) {
+	if req == nil || req.Amount <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	account, err := s.marginRepo.GetAccountByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, ErrMarginAccountNotFound
+	}
+
+	loans, err := s.marginRepo.GetLoansByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+	var loan *dto.MarginLoan
+	for _, l := range loans {
+		if l.Asset == req.Asset {
+			loan = l
+		}
+	}
+	if loan == nil {
+		return nil, ErrNoOutstandingMarginLoan
+	}
+
+	interest := utils.RoundFloat(min(req.Amount, loan.Interest))
+	principal := utils.RoundFloat(min(req.Amount-interest, loan.Principal))
+	record := &dto.MarginLoanRecord{
+		UserID:    userId,
+		Asset:     req.Asset,
+		Type:      dto.MARGIN_LOAN_REPAY,
+		Principal: principal,
+		Interest:  interest,
+	}
+	err = s.repay(ctx, account, record)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[MarginService] user %s repaid %s, principal: %v, interest: %v", userId, req.Asset, principal, interest)
+	return record, nil
+}
+
+func (s *marginService) repay(ctx context.Context, account *dto.MarginAccount, record *dto.MarginLoanRecord) error {
+	amount := utils.RoundFloat(record.Principal + record.Interest)
+	return WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, account.MarginUserID, record.Asset, false, amount); err != nil {
+			log.Warnf("[MarginService] failed to decrease margin wallet balance, %v", err)
+			return ErrInsufficientBalance
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, settings.MARGIN_LENDING_POOL_ACCOUNT_ID, record.Asset, true, amount); err != nil {
+			return err
+		}
+		if err := s.marginRepo.DecreaseLoan(ctx, tx, account.UserID, record.Asset, record.Principal, record.Interest); err != nil {
+			return err
+		}
+		return s.marginRepo.InsertLoanRecord(ctx, tx, record)
+	})
+}
+
+func (s *marginService) GetLoanRecords(ctx context.Context, userId string, limit int) ([]*dto.MarginLoanRecord, error) {
+	if limit <= 0 {
+		limit = defaultMarginRecordsLimit
+	}
+	limit = min(limit, marginRecordsMaxLimit)
+	return s.marginRepo.GetLoanRecordsByUserId(ctx, s.db, userId, limit)
+}
+
+// AccrueInterest add interest of every full hour elapsed since each outstanding loan last accrued, hours missed
+// while server was stopped are caught up and accruing again in the same hour adds nothing.
+func (s *marginService) AccrueInterest(ctx context.Context) error {
+	now := time.Now()
+	var total int
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		loans, err := s.marginRepo.GetAccruingLoans(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, loan := range loans {
+			interest, accruedAt := serviceHelper.AccrueMarginInterest(loan, now)
+			if accruedAt.Equal(loan.AccruedAt) {
+				continue
+			}
+			if err := s.marginRepo.AccrueInterest(ctx, tx, loan.UserID, loan.Asset, interest, accruedAt); err != nil {
+				return err
+			}
+			total++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("[MarginService] accrued interest for %d loans", total)
+	return nil
+}
+
+// getRisk return margin wallet balances, outstanding loans and risk valued in USDT.
+func (s *marginService) getRisk(ctx context.Context, db repository.DBExecutor, account *dto.MarginAccount) ([]*dto.Balance, []*dto.MarginLoan, *dto.MarginRisk, error) {
+	balances, err := s.balanceRepo.GetBalancesByUserId(ctx, db, account.MarginUserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	loans, err := s.marginRepo.GetLoansByUserId(ctx, db, account.UserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	assets := make([]string, 0, len(balances)+len(loans))
+	for _, balance := range balances {
+		if balance.Available+balance.Locked > 0 {
+			assets = append(assets, balance.Asset)
+		}
+	}
+	for _, loan := range loans {
+		assets = append(assets, loan.Asset)
+	}
+	prices, err := s.portfolioService.GetUSDTPrices(ctx, assets)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return balances, loans, serviceHelper.CalculateMarginRisk(balances, loans, prices), nil
+}
+
+// checkInitialRatio check margin ratio after asset and debt of asset changed, skip if no debt after change.
+// Call it in the tx of the change (db is the tx).
+func (s *marginService) checkInitialRatio(ctx context.Context, db repository.DBExecutor, account *dto.MarginAccount, asset string, assetChanging, debtChanging float64) error {
+	_, _, risk, err := s.getRisk(ctx, db, account)
+	if err != nil {
+		return err
+	}
+	prices, err := s.portfolioService.GetUSDTPrices(ctx, []string{asset})
+	if err != nil {
+		return err
+	}
+
+	price := prices[asset]
+	if risk.DebtValue+debtChanging*price <= 0 {
+		return nil
+	}
+	if serviceHelper.MarginRatioAfter(risk, assetChanging*price, debtChanging*price) < settings.MARGIN_INITIAL_RATIO {
+		return ErrMarginRatioTooLow
+	}
+	return nil
+}
+
+// getOrCreateAccount create internal margin user (can not login) with user's fee rates and balances.
+func (s *marginService) getOrCreateAccount(ctx context.Context, user *dto.User) (*dto.MarginAccount, error) {
+	if account, err := s.marginRepo.GetAccountByUserId(ctx, s.db, user.ID); err == nil {
+		return account, nil
+	}
+
+	marginUserId, err := genUIDSecure()
+	if err != nil {
+		log.Errorf("[MarginService] failed to generate user id: %v", err)
+		return nil, fmt.Errorf("failed to create margin account")
+	}
+
+	account := &dto.MarginAccount{
+		UserID:       user.ID,
+		MarginUserID: marginUserId,
+		CreatedAt:    time.Now(),
+	}
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := s.userRepo.Insert(ctx, tx, &dto.User{
+			ID:       marginUserId,
+			Username: marginUsernamePrefix + user.ID,
+			VipLevel: user.VipLevel,
+			MakerFee: user.MakerFee,
+			TakerFee: user.TakerFee,
+		})
+		if err != nil {
+			return err
+		}
+		if err = s.balanceRepo.BatchCreate(ctx, tx, marginUserId, settings.GetAllAssets()); err != nil {
+			return err
+		}
+		return s.marginRepo.InsertAccount(ctx, tx, account)
+	})
+	if err != nil {
+		// concurrent request may win the race.
+		if existing, qErr := s.marginRepo.GetAccountByUserId(ctx, s.db, user.ID); qErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	return account, nil
+}
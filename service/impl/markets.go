@@ -3,16 +3,26 @@ package serviceImpl
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
 	"github.com/johnny1110/crypto-exchange/ohlcv"
 	"github.com/johnny1110/crypto-exchange/repository"
 	"github.com/johnny1110/crypto-exchange/service"
 	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/ws"
 	"github.com/labstack/gommon/log"
 	"time"
 )
 
+const (
+	defaultRecentTradesLimit = 100
+	recentTradesMaxLimit     = 1000
+)
+
+var ErrMarketNotFound = errors.New("market not found")
+
 type MarketDataService struct {
 	db        *sql.DB
 	tradeRepo repository.ITradeRepository
@@ -52,6 +62,38 @@ func (d *MarketDataService) GetOHLCVHistory(ctx context.Context, req *ohlcv.GetO
 	return d.ohlcvAgg.GetOHLCVData(ctx, req)
 }
 
+func (d *MarketDataService) GetRecentTrades(ctx context.Context, market string, limit int) ([]*dto.MarketTrade, error) {
+	if !marketExists(market) {
+		return nil, ErrMarketNotFound
+	}
+	if limit <= 0 {
+		limit = defaultRecentTradesLimit
+	}
+	return d.tradeRepo.GetRecentTrades(ctx, d.db, market, min(limit, recentTradesMaxLimit))
+}
+
+func marketExists(name string) bool {
+	for _, info := range settings.ALL_MARKETS {
+		if info.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// publishMarketTrades push persisted trades to trades channel of market, trade ids are queried only if subscribed.
+func publishMarketTrades(ctx context.Context, db *sql.DB, tradeRepo repository.ITradeRepository, publisher ws.TradePublisher, market string, trades []book.Trade) {
+	if len(trades) == 0 || !publisher.TradesSubscribed(market) {
+		return
+	}
+	marketTrades, err := tradeRepo.GetMarketTradesByTrades(ctx, db, trades)
+	if err != nil {
+		log.Errorf("[publishMarketTrades] failed to get trades of %s: %v", market, err)
+		return
+	}
+	publisher.PublishTrades(market, marketTrades)
+}
+
 func NewMarketDataService(
 	db *sql.DB,
 	tradeRepo repository.ITradeRepository,
@@ -0,0 +1,175 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+)
+
+// settleFeesInFeeToken charge fees of orders opted-in settings.FEE_TOKEN with discount.
+// Fees in settlement result are in normal asset (bid: base, ask: quote), if fee token balance is insufficient
+// fall back to normal asset and switch order fee asset, previous fee token fees are converted at current rate.
+func (s *orderService) settleFeesInFeeToken(ctx context.Context, tx *sql.Tx, orderCtx *dto.PlaceOrderContext, result *serviceHelper.TradeSettlementResult) error {
+	orders, err := s.getFeeTokenOrders(ctx, tx, orderCtx, result.OrderUpdates)
+	if err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+
+	rates := make(map[string]float64, 2)
+	for _, update := range result.OrderUpdates {
+		order, ok := orders[update.OrderID]
+		if !ok || update.FeesIncreasing == 0 {
+			continue
+		}
+
+		normalAsset := result.QuoteAsset
+		if order.Side == model.BID {
+			normalAsset = result.BaseAsset
+		}
+		normalFees := update.FeesIncreasing
+		prevFees := order.Fees
+		if order == orderCtx.OrderDTO {
+			// eaten order fees already accumulated by ProcessTradeSettlement.
+			prevFees -= normalFees
+		}
+
+		rate, rateErr := s.feeTokenRate(ctx, normalAsset, rates)
+		if rateErr != nil {
+			log.Warnf("[settleFeesInFeeToken] failed to get fee token rate, asset: %s, error: %v", normalAsset, rateErr)
+		}
+
+		// rebates are always paid in normal asset.
+		if normalFees > 0 && rateErr == nil {
+			tokenRate := rate * (1 - settings.FEE_TOKEN_DISCOUNT)
+			tokenFees := utils.RoundFloat(normalFees * tokenRate)
+			err = s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, order.UserID, settings.FEE_TOKEN, false, tokenFees)
+			if err == nil {
+				result.RefundFees(order.UserID, order.Side, normalFees)
+				result.TotalFeeTokenFees += tokenFees
+				update.FeesIncreasing = tokenFees
+				update.FeeAsset = settings.FEE_TOKEN
+				update.FeeTokenRate = tokenRate
+				if order == orderCtx.OrderDTO {
+					orderCtx.OrderDTO.Fees = prevFees + tokenFees
+				}
+				continue
+			}
+			log.Infof("[settleFeesInFeeToken] fee token balance insufficient, fall back to %s, orderId: %s", normalAsset, order.ID)
+		}
+
+		// fall back to normal asset.
+		convertedFees := prevFees
+		if prevFees != 0 && rateErr == nil {
+			convertedFees = utils.RoundFloat(prevFees / (rate * (1 - settings.FEE_TOKEN_DISCOUNT)))
+		}
+		update.SwitchFeeAsset = normalAsset
+		update.ConvertedFees = convertedFees
+		if order == orderCtx.OrderDTO {
+			orderCtx.OrderDTO.FeeAsset = normalAsset
+			orderCtx.OrderDTO.Fees = convertedFees + normalFees
+		}
+	}
+
+	return nil
+}
+
+// syncFillFeesInFeeToken overwrite trade fees of sides paid in settings.FEE_TOKEN, trades are inserted with normal asset fees.
+func (s *orderService) syncFillFeesInFeeToken(ctx context.Context, tx *sql.Tx, orderCtx *dto.PlaceOrderContext, updates []*serviceHelper.OrderUpdateData) error {
+	tokenRates := make(map[string]float64)
+	for _, update := range updates {
+		if update.FeeTokenRate != 0 {
+			tokenRates[update.OrderID] = update.FeeTokenRate
+		}
+	}
+	if len(tokenRates) == 0 {
+		return nil
+	}
+
+	for _, trade := range orderCtx.Trades {
+		if rate, ok := tokenRates[trade.BidOrderID]; ok && trade.BidFeeRate > 0 {
+			fees := utils.RoundFloat(utils.RoundFloat(trade.Size*trade.BidFeeRate) * rate)
+			if err := s.tradeRepo.UpdateFee(ctx, tx, trade.BidOrderID, trade.AskOrderID, model.BID, fees, settings.FEE_TOKEN); err != nil {
+				return err
+			}
+		}
+		if rate, ok := tokenRates[trade.AskOrderID]; ok && trade.AskFeeRate > 0 {
+			fees := utils.RoundFloat(utils.RoundFloat(trade.Price*trade.Size*trade.AskFeeRate) * rate)
+			if err := s.tradeRepo.UpdateFee(ctx, tx, trade.BidOrderID, trade.AskOrderID, model.ASK, fees, settings.FEE_TOKEN); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getFeeTokenOrders return orders (by id) in updates which fee asset is settings.FEE_TOKEN.
+func (s *orderService) getFeeTokenOrders(ctx context.Context, tx *sql.Tx, orderCtx *dto.PlaceOrderContext, updates []*serviceHelper.OrderUpdateData) (map[string]*dto.Order, error) {
+	orders := make(map[string]*dto.Order)
+
+	orderIds := make([]string, 0, len(updates))
+	for _, update := range updates {
+		if update.OrderID == orderCtx.OrderDTO.ID {
+			if orderCtx.OrderDTO.FeeAsset == settings.FEE_TOKEN {
+				orders[update.OrderID] = orderCtx.OrderDTO
+			}
+			continue
+		}
+		orderIds = append(orderIds, update.OrderID)
+	}
+
+	oppositeOrders, err := s.orderRepo.GetOrdersByIds(ctx, tx, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range oppositeOrders {
+		if order.FeeAsset == settings.FEE_TOKEN {
+			orders[order.ID] = order
+		}
+	}
+
+	return orders, nil
+}
+
+// feeTokenRate return how many settings.FEE_TOKEN per asset unit, cross by USDT latest price.
+func (s *orderService) feeTokenRate(ctx context.Context, asset string, cache map[string]float64) (float64, error) {
+	if rate, ok := cache[asset]; ok {
+		return rate, nil
+	}
+
+	assetPrice, err := s.latestUSDTPrice(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	tokenPrice, err := s.latestUSDTPrice(ctx, settings.FEE_TOKEN)
+	if err != nil {
+		return 0, err
+	}
+
+	rate := assetPrice / tokenPrice
+	cache[asset] = rate
+	return rate, nil
+}
+
+func (s *orderService) latestUSDTPrice(ctx context.Context, asset string) (float64, error) {
+	if asset == "USDT" {
+		return 1, nil
+	}
+	price, err := s.orderBookService.GetLatestPrice(ctx, fmt.Sprintf("%v-USDT", asset))
+	if err != nil {
+		return 0, err
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("invalid latest price for asset %s", asset)
+	}
+	return price, nil
+}
@@ -0,0 +1,24 @@
+package serviceImpl
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+)
+
+// getMarginUser return user's margin wallet user to place margin order, it keeps user's fee settings.
+func (s *orderService) getMarginUser(ctx context.Context, user *dto.User) (*dto.User, error) {
+	account, err := s.marginRepo.GetAccountByUserId(ctx, s.db, user.ID)
+	if err != nil {
+		return nil, ErrMarginAccountNotFound
+	}
+
+	marginUser := *user
+	marginUser.ID = account.MarginUserID
+	return &marginUser, nil
+}
+
+// isMarginUserOf check if marginUserId is userId's margin wallet user.
+func (s *orderService) isMarginUserOf(ctx context.Context, userId, marginUserId string) bool {
+	account, err := s.marginRepo.GetAccountByMarginUserId(ctx, s.db, marginUserId)
+	return err == nil && account.UserID == userId
+}
@@ -0,0 +1,173 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+)
+
+// initializePerpetualOrderContext perpetual orders lock quote asset margin and pay fees in quote asset,
+// fee token and margin wallet are not supported.
+func (s *orderService) initializePerpetualOrderContext(marketInfo *market.MarketInfo, user *dto.User, req *dto.OrderReq) (*dto.PlaceOrderContext, error) {
+	if req.Margin {
+		return nil, ErrPerpetualMarginOrder
+	}
+
+	_, feeRate := serviceHelper.DetermineFeeInfo(req, user, marketInfo.BaseAsset, marketInfo.QuoteAsset)
+
+	var bestBidPrice float64
+	if req.OrderType == model.MARKET && req.Side == model.ASK {
+		orderBook, err := s.engine.GetOrderBook(marketInfo.Name)
+		if err != nil {
+			return nil, err
+		}
+		bestBidPrice, _, err = orderBook.BestBid()
+		if err != nil || bestBidPrice <= 0 {
+			return nil, ErrPerpetualNoLiquidity
+		}
+	}
+	freezeAsset, freezeAmt := serviceHelper.DeterminePerpetualFreezeValue(req, marketInfo, feeRate, bestBidPrice)
+
+	return &dto.PlaceOrderContext{
+		Market:    marketInfo.Name,
+		UserID:    user.ID,
+		Request:   req,
+		FeeRate:   feeRate,
+		FeeAsset:  marketInfo.QuoteAsset,
+		Perpetual: marketInfo,
+		Assets: &dto.AssetDetails{
+			BaseAsset:   marketInfo.BaseAsset,
+			QuoteAsset:  marketInfo.QuoteAsset,
+			FreezeAsset: freezeAsset,
+			FreezeAmt:   freezeAmt,
+		},
+	}, nil
+}
+
+// updatePerpetualTradeFees trades are inserted with spot fees, overwrite both sides with perpetual fees in quote asset.
+func (s *orderService) updatePerpetualTradeFees(ctx context.Context, tx *sql.Tx, orderCtx *dto.PlaceOrderContext) error {
+	if orderCtx.Perpetual == nil {
+		return nil
+	}
+
+	quoteAsset, contractSize := orderCtx.Perpetual.QuoteAsset, orderCtx.Perpetual.ContractSize
+	for _, trade := range orderCtx.Trades {
+		bidFee := serviceHelper.PerpetualFee(trade.Price, trade.Size, contractSize, trade.BidFeeRate)
+		if err := s.tradeRepo.UpdateFee(ctx, tx, trade.BidOrderID, trade.AskOrderID, model.BID, bidFee, quoteAsset); err != nil {
+			return err
+		}
+		askFee := serviceHelper.PerpetualFee(trade.Price, trade.Size, contractSize, trade.AskFeeRate)
+		if err := s.tradeRepo.UpdateFee(ctx, tx, trade.BidOrderID, trade.AskOrderID, model.ASK, askFee, quoteAsset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guardPerpetualMakerRebates perpetual rebates of both sides are paid in quote asset in placement tx.
+func (s *orderService) guardPerpetualMakerRebates(ctx context.Context, tx *sql.Tx, orderCtx *dto.PlaceOrderContext) error {
+	rebates := serviceHelper.CalculatePerpetualRebates(orderCtx.Trades, orderCtx.Perpetual.ContractSize)
+	if rebates == 0 {
+		return nil
+	}
+
+	balances, err := s.balanceRepo.GetBalancesByUserId(ctx, tx, settings.MARGIN_ACCOUNT_ID)
+	if err != nil {
+		return err
+	}
+	for _, balance := range balances {
+		if balance.Asset == orderCtx.Perpetual.QuoteAsset && balance.Available >= rebates {
+			return s.payRebates(ctx, tx, orderCtx.Perpetual.QuoteAsset, rebates)
+		}
+	}
+
+	log.Warnf("[guardMakerRebates] margin account can not afford rebates, market: %s, quote rebates: %v", orderCtx.Market, rebates)
+	serviceHelper.DisableRebates(orderCtx.Trades, true, true)
+	return nil
+}
+
+// checkPerpetualMarketHalt orders of halted perpetual market are refused.
+func (s *orderService) checkPerpetualMarketHalt(ctx context.Context, orderCtx *dto.PlaceOrderContext) error {
+	if orderCtx.Perpetual == nil {
+		return nil
+	}
+	halted, err := s.perpetualRepo.IsMarketHalted(ctx, s.db, orderCtx.Market)
+	if err != nil {
+		return err
+	}
+	if halted {
+		return ErrPerpetualMarketHalt
+	}
+	return nil
+}
+
+// executePerpetualSettlementPhase settle fills into positions and quote asset balances, realized pnl is paid by
+// (or collected into) the insurance fund. Positions, orders, balances and fees are settled all or nothing,
+// if insurance fund can not pay net realized pnl nothing is settled and market is halted.
+func (s *orderService) executePerpetualSettlementPhase(ctx context.Context, orderCtx *dto.PlaceOrderContext) error {
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		positions, err := s.perpetualRepo.GetPositionsByUserIds(ctx, tx, serviceHelper.TradeUserIds(orderCtx.Trades), orderCtx.Market)
+		if err != nil {
+			return fmt.Errorf("failed to get perpetual positions: %w", err)
+		}
+
+		result, err := serviceHelper.ProcessPerpetualSettlement(orderCtx, positions)
+		if err != nil {
+			return fmt.Errorf("failed to process perpetual settlement: %w", err)
+		}
+
+		// Update orders
+		for _, orderUpdate := range result.OrderUpdates {
+			if err := s.orderRepo.SyncTradeMatchingResult(ctx, tx, orderUpdate.OrderID, orderUpdate.RemainingSizeDecreasing, orderUpdate.DealtQuoteAmountIncreasing, orderUpdate.FeesIncreasing); err != nil {
+				return fmt.Errorf("failed to sync trade matching result for order %s: %w", orderUpdate.OrderID, err)
+			}
+		}
+
+		// Update user quote balances and positions
+		for userID, settlement := range result.UserSettlements {
+			if err := s.balanceRepo.UpdateAsset(ctx, tx, userID, result.QuoteAsset, settlement.Available, settlement.Locked); err != nil {
+				log.Errorf("updateUserAssets error: %v", err)
+				return fmt.Errorf("failed to update quote asset: %w", err)
+			}
+		}
+		for _, position := range result.Positions {
+			if err := s.perpetualRepo.UpsertPosition(ctx, tx, position); err != nil {
+				return err
+			}
+		}
+
+		// Realized pnl is settled against insurance fund
+		if err := settleWithInsuranceFund(ctx, tx, s.balanceRepo, result.QuoteAsset, -result.NetPnl); err != nil {
+			return fmt.Errorf("failed to settle realized pnl with insurance fund: %w", err)
+		}
+		if result.BadDebt > 0 {
+			log.Warnf("[executePerpetualSettlementPhase] losses exceeded position margin, market: %s, bad debt: %v", orderCtx.Market, result.BadDebt)
+		}
+
+		// settle Fees Revenue to exchange's margin account
+		if err := s.settleAssetFeesRevenue(ctx, tx, orderCtx.Market, result.QuoteAsset, result.TotalFees, result.TotalRebates); err != nil {
+			log.Errorf("[PlaceOrder] settleFeesRevenue failed, error %v", err)
+			return err
+		}
+
+		// Record realized pnl, balances are already settled so failure here only affects reporting
+		for _, realized := range result.RealizedPnls {
+			if err := s.pnlRepo.InsertRealizedPnl(ctx, tx, realized); err != nil {
+				log.Errorf("[executePerpetualSettlementPhase] InsertRealizedPnl error: %v", err)
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, ErrInsuranceFundShort) {
+		haltPerpetualMarket(ctx, s.db, s.perpetualRepo, orderCtx.Market, "insurance fund can not cover realized pnl")
+	}
+	return err
+}
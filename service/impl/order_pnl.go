@@ -0,0 +1,62 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/utils"
+)
+
+// updateCostBases apply settlement position changes to users average cost basis of base asset and record realized pnl.
+func (s *orderService) updateCostBases(ctx context.Context, db *sql.Tx, market string, result *serviceHelper.TradeSettlementResult) error {
+	return applyPositionChanges(ctx, db, s.pnlRepo, market, result.BaseAsset, result.PositionChanges)
+}
+
+// applyPositionChanges apply position changes (user id -> change) to average cost basis of base asset and record realized pnl.
+func applyPositionChanges(ctx context.Context, db repository.DBExecutor, pnlRepo repository.IPnlRepository, market, baseAsset string, changes map[string]*serviceHelper.PositionChangeData) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	userIds := make([]string, 0, len(changes))
+	for userId := range changes {
+		userIds = append(userIds, userId)
+	}
+
+	bases, err := pnlRepo.GetCostBasesByUserIds(ctx, db, userIds, baseAsset)
+	if err != nil {
+		return err
+	}
+
+	for userId, change := range changes {
+		basis, ok := bases[userId]
+		if !ok {
+			basis = &dto.CostBasis{UserID: userId, Asset: baseAsset}
+		}
+
+		soldCost := serviceHelper.ApplyPositionChange(basis, change)
+		if err := pnlRepo.UpsertCostBasis(ctx, db, basis); err != nil {
+			return err
+		}
+
+		if change.SoldSize > 0 {
+			proceeds := utils.RoundFloat(change.SoldProceeds)
+			err := pnlRepo.InsertRealizedPnl(ctx, db, &dto.RealizedPnl{
+				UserID:   userId,
+				Market:   market,
+				Asset:    baseAsset,
+				Size:     utils.RoundFloat(change.SoldSize),
+				Proceeds: proceeds,
+				Cost:     soldCost,
+				Pnl:      utils.RoundFloat(proceeds - soldCost),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+// settleReferralCommissions pay settings.REFERRAL_COMMISSION_RATE of each referee's order fees from margin account to referrer.
+func (s *orderService) settleReferralCommissions(ctx context.Context, db *sql.Tx, market string, result *serviceHelper.TradeSettlementResult) error {
+	userIds := make([]string, 0, len(result.OrderUpdates))
+	for _, update := range result.OrderUpdates {
+		if update.FeesIncreasing > 0 {
+			userIds = append(userIds, update.UserID)
+		}
+	}
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	referrers, err := s.referralRepo.GetReferrerIdsByRefereeIds(ctx, db, userIds)
+	if err != nil {
+		return err
+	}
+
+	for _, update := range result.OrderUpdates {
+		referrerId, ok := referrers[update.UserID]
+		if !ok || update.FeesIncreasing <= 0 {
+			continue
+		}
+
+		commission := utils.RoundFloat(update.FeesIncreasing * settings.REFERRAL_COMMISSION_RATE)
+		if commission <= 0 {
+			continue
+		}
+
+		// margin account can not go negative, skip commission if it can not afford.
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, db, settings.MARGIN_ACCOUNT_ID, update.FeeAsset, false, commission); err != nil {
+			log.Warnf("[settleReferralCommissions] margin account failed to pay commission, orderId: %s, error: %v", update.OrderID, err)
+			continue
+		}
+		if err := s.balanceRepo.UpdateAsset(ctx, db, referrerId, update.FeeAsset, commission, 0); err != nil {
+			return err
+		}
+
+		err = s.referralRepo.InsertCommission(ctx, db, &dto.ReferralCommission{
+			ReferrerID: referrerId,
+			RefereeID:  update.UserID,
+			OrderID:    update.OrderID,
+			Market:     market,
+			Asset:      update.FeeAsset,
+			Fees:       update.FeesIncreasing,
+			Commission: commission,
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
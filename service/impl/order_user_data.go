@@ -0,0 +1,125 @@
+package serviceImpl
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/ws"
+	"github.com/labstack/gommon/log"
+)
+
+// userDataWallet owner receives private data of wallet, margin is true if wallet is owner's margin wallet.
+type userDataWallet struct {
+	ownerId string
+	margin  bool
+}
+
+// publishPlacementUserData push taker and maker orders, fills and balances changed by placement.
+func (s *orderService) publishPlacementUserData(ctx context.Context, orderCtx *dto.PlaceOrderContext) {
+	orderIds := []string{orderCtx.OrderDTO.ID}
+	userIds := []string{orderCtx.UserID}
+	for _, trade := range orderCtx.Trades {
+		orderIds = append(orderIds, trade.BidOrderID, trade.AskOrderID)
+		userIds = append(userIds, trade.BidUserID, trade.AskUserID)
+	}
+	s.publishUserData(ctx, orderIds, orderCtx.Trades, userIds)
+}
+
+// publishUserData push changes to private websocket channels of owners, data is only queried for subscribed owners.
+// failures are only logged, orders are already settled.
+func (s *orderService) publishUserData(ctx context.Context, orderIds []string, trades []book.Trade, userIds []string) {
+	wallets := s.resolveUserDataWallets(ctx, userIds)
+	s.publishOrdersUpdate(ctx, wallets, orderIds)
+	s.publishFillsUpdate(ctx, wallets, trades)
+	s.publishBalancesUpdate(ctx, wallets)
+}
+
+// resolveUserDataWallets user id -> wallet, margin wallet users are resolved to their owners.
+func (s *orderService) resolveUserDataWallets(ctx context.Context, userIds []string) map[string]userDataWallet {
+	wallets := make(map[string]userDataWallet, len(userIds))
+	for _, userId := range userIds {
+		if _, ok := wallets[userId]; ok {
+			continue
+		}
+		if account, err := s.marginRepo.GetAccountByMarginUserId(ctx, s.db, userId); err == nil {
+			wallets[userId] = userDataWallet{ownerId: account.UserID, margin: true}
+		} else {
+			wallets[userId] = userDataWallet{ownerId: userId}
+		}
+	}
+	return wallets
+}
+
+func (s *orderService) anySubscribed(channel ws.WSChannel, wallets map[string]userDataWallet) bool {
+	for _, wallet := range wallets {
+		if s.userDataPublisher.Subscribed(channel, wallet.ownerId) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *orderService) publishOrdersUpdate(ctx context.Context, wallets map[string]userDataWallet, orderIds []string) {
+	if !s.anySubscribed(ws.ORDERS, wallets) {
+		return
+	}
+	orders, err := s.orderRepo.GetOrdersByIds(ctx, s.db, orderIds)
+	if err != nil {
+		log.Errorf("[publishOrdersUpdate] failed to get orders: %v", err)
+		return
+	}
+
+	ordersByUser := make(map[string][]*dto.Order)
+	for _, order := range orders {
+		ordersByUser[order.UserID] = append(ordersByUser[order.UserID], order)
+	}
+	for userId, userOrders := range ordersByUser {
+		wallet := wallets[userId]
+		if s.userDataPublisher.Subscribed(ws.ORDERS, wallet.ownerId) {
+			s.userDataPublisher.PublishToUser(ws.ORDERS, wallet.ownerId, &dto.OrdersUpdate{Margin: wallet.margin, Orders: userOrders})
+		}
+	}
+}
+
+func (s *orderService) publishFillsUpdate(ctx context.Context, wallets map[string]userDataWallet, trades []book.Trade) {
+	if len(trades) == 0 || !s.anySubscribed(ws.FILLS, wallets) {
+		return
+	}
+	fills, err := s.tradeRepo.GetFillsByTrades(ctx, s.db, trades)
+	if err != nil {
+		log.Errorf("[publishFillsUpdate] failed to get fills: %v", err)
+		return
+	}
+
+	// order id tells owner of fill.
+	userByOrder := make(map[string]string, len(trades)*2)
+	for _, trade := range trades {
+		userByOrder[trade.BidOrderID] = trade.BidUserID
+		userByOrder[trade.AskOrderID] = trade.AskUserID
+	}
+	fillsByUser := make(map[string][]*dto.Fill)
+	for _, fill := range fills {
+		userId := userByOrder[fill.OrderID]
+		fillsByUser[userId] = append(fillsByUser[userId], fill)
+	}
+	for userId, userFills := range fillsByUser {
+		wallet := wallets[userId]
+		if s.userDataPublisher.Subscribed(ws.FILLS, wallet.ownerId) {
+			s.userDataPublisher.PublishToUser(ws.FILLS, wallet.ownerId, &dto.FillsUpdate{Margin: wallet.margin, Fills: userFills})
+		}
+	}
+}
+
+func (s *orderService) publishBalancesUpdate(ctx context.Context, wallets map[string]userDataWallet) {
+	for userId, wallet := range wallets {
+		if !s.userDataPublisher.Subscribed(ws.BALANCES, wallet.ownerId) {
+			continue
+		}
+		balances, err := s.balanceRepo.GetBalancesByUserId(ctx, s.db, userId)
+		if err != nil {
+			log.Errorf("[publishBalancesUpdate] failed to get balances of user %s: %v", userId, err)
+			continue
+		}
+		s.userDataPublisher.PublishToUser(ws.BALANCES, wallet.ownerId, &dto.BalancesUpdate{Margin: wallet.margin, Balances: balances})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
 	"github.com/johnny1110/crypto-exchange/settings"
 	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/johnny1110/crypto-exchange/ws"
 	"github.com/labstack/gommon/log"
 	"time"
 )
@@ -28,12 +29,20 @@ var (
 )
 
 type orderService struct {
-	db               *sql.DB
-	engine           *core.MatchingEngine
-	orderRepo        repository.IOrderRepository
-	tradeRepo        repository.ITradeRepository
-	balanceRepo      repository.IBalanceRepository
-	klineTradeStream ohlcv.TradeStream
+	db                *sql.DB
+	engine            *core.MatchingEngine
+	orderRepo         repository.IOrderRepository
+	tradeRepo         repository.ITradeRepository
+	balanceRepo       repository.IBalanceRepository
+	feeRevenueRepo    repository.IFeeRevenueRepository
+	referralRepo      repository.IReferralRepository
+	pnlRepo           repository.IPnlRepository
+	marginRepo        repository.IMarginRepository
+	perpetualRepo     repository.IPerpetualRepository
+	orderBookService  service.IOrderBookService
+	klineTradeStream  ohlcv.TradeStream
+	userDataPublisher ws.UserDataPublisher
+	tradePublisher    ws.TradePublisher
 }
 
 func NewIOrderService(
@@ -42,23 +51,58 @@ func NewIOrderService(
 	orderRepo repository.IOrderRepository,
 	tradeRepo repository.ITradeRepository,
 	balanceRepo repository.IBalanceRepository,
-	klineTradeStream ohlcv.TradeStream) service.IOrderService {
+	feeRevenueRepo repository.IFeeRevenueRepository,
+	referralRepo repository.IReferralRepository,
+	pnlRepo repository.IPnlRepository,
+	marginRepo repository.IMarginRepository,
+	perpetualRepo repository.IPerpetualRepository,
+	orderBookService service.IOrderBookService,
+	klineTradeStream ohlcv.TradeStream,
+	userDataPublisher ws.UserDataPublisher,
+	tradePublisher ws.TradePublisher) service.IOrderService {
 	return &orderService{
-		db:               db,
-		engine:           engine,
-		orderRepo:        orderRepo,
-		tradeRepo:        tradeRepo,
-		balanceRepo:      balanceRepo,
-		klineTradeStream: klineTradeStream,
+		db:                db,
+		engine:            engine,
+		orderRepo:         orderRepo,
+		tradeRepo:         tradeRepo,
+		balanceRepo:       balanceRepo,
+		feeRevenueRepo:    feeRevenueRepo,
+		referralRepo:      referralRepo,
+		pnlRepo:           pnlRepo,
+		marginRepo:        marginRepo,
+		perpetualRepo:     perpetualRepo,
+		orderBookService:  orderBookService,
+		klineTradeStream:  klineTradeStream,
+		userDataPublisher: userDataPublisher,
+		tradePublisher:    tradePublisher,
 	}
 }
 
 func (s *orderService) PlaceOrder(ctx context.Context, market string, user *dto.User, req *dto.OrderReq) (*dto.PlaceOrderResult, error) {
+	// checked on user before switching to margin wallet, margin wallet follows user's status.
+	if user != nil {
+		if err := checkCanTrade(user); err != nil {
+			return nil, err
+		}
+	}
+
+	// Margin orders are placed by user's margin wallet
+	if req != nil && req.Margin && user != nil {
+		marginUser, err := s.getMarginUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		user = marginUser
+	}
+
 	// Initialize order context
 	orderCtx, err := s.initializeOrderContext(market, user, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize order context: %w", err)
 	}
+	if err := s.checkPerpetualMarketHalt(ctx, orderCtx); err != nil {
+		return nil, err
+	}
 
 	// Execute order placement strategy
 	strategy, err := s.getOrderPlacementStrategy(req.OrderType)
@@ -77,12 +121,21 @@ func (s *orderService) initializeOrderContext(market string, user *dto.User, req
 		return nil, err
 	}
 
-	baseAsset, quoteAsset, err := serviceHelper.ParseMarket(s.engine, market)
+	marketInfo, err := serviceHelper.GetMarketInfo(s.engine, market)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse market: %w", err)
 	}
+	if marketInfo.IsPerpetual() {
+		return s.initializePerpetualOrderContext(marketInfo, user, req)
+	}
+
+	baseAsset, quoteAsset := marketInfo.BaseAsset, marketInfo.QuoteAsset
 	freezeAsset, freezeAmt := serviceHelper.DetermineFreezeValue(req, baseAsset, quoteAsset)
 	feeAsset, feeRate := serviceHelper.DetermineFeeInfo(req, user, baseAsset, quoteAsset)
+	if user.PayFeeInBTSE && feeAsset != settings.FEE_TOKEN {
+		// fee asset may fall back to normal asset in settlement if fee token balance is insufficient.
+		feeAsset = settings.FEE_TOKEN
+	}
 
 	return &dto.PlaceOrderContext{
 		Market:   market,
@@ -145,12 +198,18 @@ func (s *orderService) executeOrderPlacement(ctx context.Context, orderCtx *dto.
 
 	log.Debugf("[executeOrderPlacement] Phase-1 done: %v", orderCtx)
 
+	// Trades are persisted, push them to trades channel
+	publishMarketTrades(ctx, s.db, s.tradeRepo, s.tradePublisher, orderCtx.Market, orderCtx.Trades)
+
 	// Phase 2: Process trade settlement
 	if err := s.executeTradeSettlementPhase(ctx, orderCtx); err != nil {
 		log.Errorf("[executeOrderPlacement] Phase-2 error: %v", err)
 		return UnknownError
 	}
 
+	// Phase 3: Push changes to private websocket channels
+	s.publishPlacementUserData(ctx, orderCtx)
+
 	return nil
 }
 
@@ -179,12 +238,22 @@ func (s *orderService) executeOrderPlacementPhase(ctx context.Context, orderCtx
 		// 4. Update order status from engine result
 		orderCtx.SyncTradeResult(engineOrder, trades)
 
+		// 4.1 Disable maker rebates if margin account can not afford it
+		if err := s.guardMakerRebates(ctx, tx, orderCtx); err != nil {
+			log.Errorf("[executeOrderPlacementPhase] guardMakerRebates error : %v", err)
+			return UnknownError
+		}
+
 		// 5. Save trade records (async to TradeService to make kines)
 		if len(orderCtx.Trades) > 0 {
-			if err := s.tradeRepo.BatchInsert(ctx, tx, trades); err != nil {
+			if err := s.tradeRepo.BatchInsert(ctx, tx, orderCtx.Trades, orderCtx.OrderDTO.Side, orderCtx.Assets.BaseAsset, orderCtx.Assets.QuoteAsset); err != nil {
 				log.Errorf("[executeOrderPlacementPhase] BatchInsert Trades error : %v", err)
 				return UnknownError
 			}
+			if err := s.updatePerpetualTradeFees(ctx, tx, orderCtx); err != nil {
+				log.Errorf("[executeOrderPlacementPhase] updatePerpetualTradeFees error : %v", err)
+				return UnknownError
+			}
 
 			for _, trade := range trades {
 				s.klineTradeStream.SyncTrade(&ohlcv.Trade{
@@ -213,37 +282,63 @@ func (s *orderService) executeTradeSettlementPhase(ctx context.Context, orderCtx
 	if len(orderCtx.Trades) == 0 {
 		return nil // No trades to settle
 	}
+	if orderCtx.Perpetual != nil {
+		return s.executePerpetualSettlementPhase(ctx, orderCtx)
+	}
 
 	settlementResult, err := serviceHelper.ProcessTradeSettlement(orderCtx)
 	if err != nil {
 		return fmt.Errorf("failed to process trade settlement: %w", err)
 	}
 
-	// Update orders
-	for _, orderUpdate := range settlementResult.OrderUpdates {
-		if err := s.orderRepo.SyncTradeMatchingResult(ctx, s.db, orderUpdate.OrderID, orderUpdate.RemainingSizeDecreasing, orderUpdate.DealtQuoteAmountIncreasing, orderUpdate.FeesIncreasing); err != nil {
-			return fmt.Errorf("failed to sync trade matching result for order %s: %w", orderUpdate.OrderID, err)
+	// fee token charges, fills fees, orders and balances are settled all or nothing.
+	return WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		// Charge fees in fee token for opted-in orders
+		if err := s.settleFeesInFeeToken(ctx, tx, orderCtx, settlementResult); err != nil {
+			return fmt.Errorf("failed to settle fees in fee token: %w", err)
 		}
-	}
 
-	// Update user balances
-	for userID, settlement := range settlementResult.UserSettlements {
-		if err := s.updateUserAssets(ctx, s.db, userID, orderCtx.Assets, settlement); err != nil {
-			log.Errorf("updateUserAssets error: %v", err)
+		// Update orders
+		for _, orderUpdate := range settlementResult.OrderUpdates {
+			if orderUpdate.SwitchFeeAsset != "" {
+				if err := s.orderRepo.UpdateFeeAsset(ctx, tx, orderUpdate.OrderID, orderUpdate.SwitchFeeAsset, orderUpdate.ConvertedFees); err != nil {
+					return fmt.Errorf("failed to switch fee asset for order %s: %w", orderUpdate.OrderID, err)
+				}
+			}
+			if err := s.orderRepo.SyncTradeMatchingResult(ctx, tx, orderUpdate.OrderID, orderUpdate.RemainingSizeDecreasing, orderUpdate.DealtQuoteAmountIncreasing, orderUpdate.FeesIncreasing); err != nil {
+				return fmt.Errorf("failed to sync trade matching result for order %s: %w", orderUpdate.OrderID, err)
+			}
+		}
+
+		// Attribute fills fees paid in fee token
+		if err := s.syncFillFeesInFeeToken(ctx, tx, orderCtx, settlementResult.OrderUpdates); err != nil {
+			return fmt.Errorf("failed to sync fill fees in fee token: %w", err)
+		}
+
+		// Update user balances
+		for userID, settlement := range settlementResult.UserSettlements {
+			if err := s.updateUserAssets(ctx, tx, userID, orderCtx.Assets, settlement); err != nil {
+				log.Errorf("updateUserAssets error: %v", err)
+				return err
+			}
+		}
+
+		// settle Fees Revenue to exchange's margin account
+		if err := s.settleFeesRevenue(ctx, tx, orderCtx.Market, settlementResult); err != nil {
 			return err
 		}
-	}
 
-	// settle Fees Revenue to exchange's margin account
-	if err := s.settleFeesRevenue(ctx, s.db, settlementResult); err != nil {
-		return err
-	}
+		// Track cost basis for PnL, balances are already settled so failure here only affects reporting
+		if err := s.updateCostBases(ctx, tx, orderCtx.Market, settlementResult); err != nil {
+			log.Errorf("[executeTradeSettlementPhase] updateCostBases error: %v", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // updateUserAssets Update user base and quote assets.html.
-func (s *orderService) updateUserAssets(ctx context.Context, tx *sql.DB, userID string, assets *dto.AssetDetails, settlement *serviceHelper.UserSettlementData) error {
+func (s *orderService) updateUserAssets(ctx context.Context, tx *sql.Tx, userID string, assets *dto.AssetDetails, settlement *serviceHelper.UserSettlementData) error {
 	// update BASE asset for user.
 	if err := s.balanceRepo.UpdateAsset(ctx, tx, userID, assets.BaseAsset, settlement.BaseAssetAvailable, settlement.BaseAssetLocked); err != nil {
 		return fmt.Errorf("failed to update base asset: %w", err)
@@ -269,7 +364,7 @@ func (s *orderService) CancelOrder(ctx context.Context, userID, orderID string)
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 
-	if orderDto.UserID != userID {
+	if orderDto.UserID != userID && !s.isMarginUserOf(ctx, userID, orderDto.UserID) {
 		return nil, ErrOrderNotBelongsToUser
 	}
 
@@ -299,7 +394,7 @@ func (s *orderService) CancelOrder(ctx context.Context, userID, orderID string)
 		}
 
 		if unlockAmount > 0 {
-			if err := s.balanceRepo.UnlockedByUserIdAndAsset(ctx, tx, userID, unlockAsset, unlockAmount); err != nil {
+			if err := s.balanceRepo.UnlockedByUserIdAndAsset(ctx, tx, orderDto.UserID, unlockAsset, unlockAmount); err != nil {
 				return fmt.Errorf("failed to unlock balance: %w", err)
 			}
 		}
@@ -311,9 +406,36 @@ func (s *orderService) CancelOrder(ctx context.Context, userID, orderID string)
 		return nil, fmt.Errorf("failed to cancel order transaction: %w", err)
 	}
 
+	s.publishUserData(ctx, []string{orderDto.ID}, nil, []string{orderDto.UserID})
 	return orderDto, nil
 }
 
+// CancelAllOrders cancel open orders of user and its margin wallet through engine, keep going if one fails.
+func (s *orderService) CancelAllOrders(ctx context.Context, userID string) ([]string, error) {
+	userIds := []string{userID}
+	if account, err := s.marginRepo.GetAccountByUserId(ctx, s.db, userID); err == nil {
+		userIds = append(userIds, account.MarginUserID)
+	}
+
+	canceled := make([]string, 0)
+	var lastErr error
+	for _, userId := range userIds {
+		orders, err := s.QueryOrder(ctx, userId, true)
+		if err != nil {
+			return canceled, err
+		}
+		for _, order := range orders {
+			if _, err := s.CancelOrder(ctx, userID, order.ID); err != nil {
+				log.Errorf("[OrderService] CancelAllOrders failed to cancel order %s of user %s: %v", order.ID, userId, err)
+				lastErr = err
+				continue
+			}
+			canceled = append(canceled, order.ID)
+		}
+	}
+	return canceled, lastErr
+}
+
 func (s *orderService) QueryOrder(ctx context.Context, userID string, isOpenOrder bool) ([]*dto.Order, error) {
 	if userID == "" {
 		return nil, ErrInvalidInput
@@ -358,6 +480,14 @@ func (s *orderService) PaginationQuery(ctx context.Context, query *dto.GetOrders
 		return nil, ErrInvalidInput
 	}
 
+	if query.Margin {
+		account, err := s.marginRepo.GetAccountByUserId(ctx, s.db, query.UserID)
+		if err != nil {
+			return nil, ErrMarginAccountNotFound
+		}
+		query.UserID = account.MarginUserID
+	}
+
 	var endTime time.Time
 	var statuses []model.OrderStatus
 
@@ -375,21 +505,100 @@ func (s *orderService) PaginationQuery(ctx context.Context, query *dto.GetOrders
 	return s.orderRepo.PaginationQuery(ctx, s.db, query, statuses, endTime)
 }
 
-func (s *orderService) settleFeesRevenue(ctx context.Context, tx *sql.DB, result *serviceHelper.TradeSettlementResult) error {
+// guardMakerRebates margin account pays maker rebates in placement tx, so concurrent orders can not overdraw it,
+// negative fee rates are reset to 0 if it can not afford them. settlement only credits paid rebates to makers.
+func (s *orderService) guardMakerRebates(ctx context.Context, tx *sql.Tx, orderCtx *dto.PlaceOrderContext) error {
+	if orderCtx.Perpetual != nil {
+		return s.guardPerpetualMakerRebates(ctx, tx, orderCtx)
+	}
+
+	baseRebates, quoteRebates := serviceHelper.CalculateRebates(orderCtx.Trades)
+	if baseRebates == 0 && quoteRebates == 0 {
+		return nil
+	}
 
-	err := s.balanceRepo.UpdateAsset(ctx, tx, settings.MARGIN_ACCOUNT_ID, result.BaseAsset, result.TotalBaseFees, 0)
+	balances, err := s.balanceRepo.GetBalancesByUserId(ctx, tx, settings.MARGIN_ACCOUNT_ID)
 	if err != nil {
+		return err
+	}
+	available := make(map[string]float64, len(balances))
+	for _, balance := range balances {
+		available[balance.Asset] = balance.Available
+	}
+
+	disableBase := baseRebates > available[orderCtx.Assets.BaseAsset]
+	disableQuote := quoteRebates > available[orderCtx.Assets.QuoteAsset]
+	if disableBase || disableQuote {
+		log.Warnf("[guardMakerRebates] margin account can not afford rebates, market: %s, base rebates: %v, quote rebates: %v",
+			orderCtx.Market, baseRebates, quoteRebates)
+		serviceHelper.DisableRebates(orderCtx.Trades, disableBase, disableQuote)
+	}
+
+	if !disableBase {
+		if err := s.payRebates(ctx, tx, orderCtx.Assets.BaseAsset, baseRebates); err != nil {
+			return err
+		}
+	}
+	if !disableQuote {
+		if err := s.payRebates(ctx, tx, orderCtx.Assets.QuoteAsset, quoteRebates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// payRebates deduct rebates from margin account available, fails if it is not enough.
+func (s *orderService) payRebates(ctx context.Context, tx *sql.Tx, asset string, rebates float64) error {
+	if rebates <= 0 {
+		return nil
+	}
+	if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, settings.MARGIN_ACCOUNT_ID, asset, false, rebates); err != nil {
+		return fmt.Errorf("margin account failed to pay rebates: %w", err)
+	}
+	return nil
+}
+
+func (s *orderService) settleFeesRevenue(ctx context.Context, tx *sql.Tx, market string, result *serviceHelper.TradeSettlementResult) error {
+	if err := s.settleAssetFeesRevenue(ctx, tx, market, result.BaseAsset, result.TotalBaseFees, result.TotalBaseRebates); err != nil {
 		log.Errorf("[PlaceOrder] settleFeesRevenue failed, error %v", err)
 		return err
 	}
-	err = s.balanceRepo.UpdateAsset(ctx, tx, settings.MARGIN_ACCOUNT_ID, result.QuoteAsset, result.TotalQuoteFees, 0)
-	if err != nil {
+	if err := s.settleAssetFeesRevenue(ctx, tx, market, result.QuoteAsset, result.TotalQuoteFees, result.TotalQuoteRebates); err != nil {
+		log.Errorf("[PlaceOrder] settleFeesRevenue failed, error %v", err)
+		return err
+	}
+	if err := s.settleAssetFeesRevenue(ctx, tx, market, settings.FEE_TOKEN, result.TotalFeeTokenFees, 0); err != nil {
+		log.Errorf("[PlaceOrder] settleFeesRevenue failed, error %v", err)
+		return err
+	}
+	if err := s.settleReferralCommissions(ctx, tx, market, result); err != nil {
 		log.Errorf("[PlaceOrder] settleFeesRevenue failed, error %v", err)
 		return err
 	}
 	return nil
 }
 
+// settleAssetFeesRevenue add fee income to margin account and record revenue, rebates are already paid by guardMakerRebates.
+func (s *orderService) settleAssetFeesRevenue(ctx context.Context, tx repository.DBExecutor, market, asset string, feeIncome, rebate float64) error {
+	if feeIncome == 0 && rebate == 0 {
+		return nil
+	}
+
+	if feeIncome > 0 {
+		if err := s.balanceRepo.UpdateAsset(ctx, tx, settings.MARGIN_ACCOUNT_ID, asset, feeIncome, 0); err != nil {
+			return err
+		}
+	}
+
+	return s.feeRevenueRepo.Insert(ctx, tx, &dto.FeeRevenue{
+		Market:    market,
+		Asset:     asset,
+		FeeIncome: feeIncome,
+		Rebate:    rebate,
+		CreatedAt: time.Now(),
+	})
+}
+
 func getOrderStatusesByOpenFlag(isOpen bool) []model.OrderStatus {
 	if isOpen {
 		return []model.OrderStatus{
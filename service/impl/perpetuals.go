@@ -0,0 +1,392 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var (
+	ErrNotPerpetualMarket   = errors.New("not perpetual market")
+	ErrPerpetualMarginOrder = errors.New("margin order is not supported in perpetual market")
+	ErrPerpetualNoLiquidity = errors.New("no bid in perpetual market")
+	ErrNoMarkPrice          = errors.New("mark price not available")
+	ErrPerpetualMarketHalt  = errors.New("perpetual market is halted")
+	ErrInsuranceFundShort   = errors.New("insurance fund can not cover payment")
+)
+
+const (
+	defaultFundingLimit = 50
+	fundingMaxLimit     = 500
+)
+
+type perpetualService struct {
+	db                *sql.DB
+	balanceRepo       repository.IBalanceRepository
+	perpetualRepo     repository.IPerpetualRepository
+	pnlRepo           repository.IPnlRepository
+	orderBookService  service.IOrderBookService
+	priceIndexService service.IPriceIndexService
+	auditLogRepo      repository.IAuditLogRepository
+}
+
+func NewIPerpetualService(db *sql.DB,
+	balanceRepo repository.IBalanceRepository,
+	perpetualRepo repository.IPerpetualRepository,
+	pnlRepo repository.IPnlRepository,
+	orderBookService service.IOrderBookService,
+	priceIndexService service.IPriceIndexService,
+	auditLogRepo repository.IAuditLogRepository) service.IPerpetualService {
+	return &perpetualService{
+		db:                db,
+		balanceRepo:       balanceRepo,
+		perpetualRepo:     perpetualRepo,
+		pnlRepo:           pnlRepo,
+		orderBookService:  orderBookService,
+		priceIndexService: priceIndexService,
+		auditLogRepo:      auditLogRepo,
+	}
+}
+
+func (s *perpetualService) GetMarkets(ctx context.Context) ([]*dto.PerpetualMarket, error) {
+	perpetualMarkets := settings.GetPerpetualMarkets()
+	markets := make([]*dto.PerpetualMarket, 0, len(perpetualMarkets))
+	nextFundingTime := serviceHelper.NextFundingTime(time.Now()).UnixMilli()
+
+	for _, info := range perpetualMarkets {
+		latestPrice, _ := s.orderBookService.GetLatestPrice(ctx, info.Name)
+		markPrice, indexPrice, err := s.getPrices(ctx, info)
+		if err != nil {
+			log.Warnf("[PerpetualService] no price of market %s, error: %v", info.Name, err)
+		}
+
+		positions, err := s.perpetualRepo.GetOpenPositionsByMarket(ctx, s.db, info.Name)
+		if err != nil {
+			return nil, err
+		}
+		var openInterest float64
+		for _, position := range positions {
+			openInterest += max(position.Size, 0)
+		}
+		halted, err := s.perpetualRepo.IsMarketHalted(ctx, s.db, info.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		markets = append(markets, &dto.PerpetualMarket{
+			Market:          info.Name,
+			BaseAsset:       info.BaseAsset,
+			QuoteAsset:      info.QuoteAsset,
+			ContractSize:    info.ContractSize,
+			LastPrice:       latestPrice,
+			MarkPrice:       markPrice,
+			IndexPrice:      indexPrice,
+			FundingRate:     serviceHelper.CalculateFundingRate(markPrice, indexPrice),
+			NextFundingTime: nextFundingTime,
+			OpenInterest:    utils.RoundFloat(openInterest),
+			Halted:          halted,
+		})
+	}
+
+	return markets, nil
+}
+
+// GetPositions user positions valued at mark price, positions of market without mark price are not valued.
+func (s *perpetualService) GetPositions(ctx context.Context, userId string) ([]*dto.PerpetualPosition, error) {
+	positions, err := s.perpetualRepo.GetPositionsByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, position := range positions {
+		info, err := getPerpetualMarket(position.Market)
+		if err != nil {
+			continue
+		}
+		markPrice, _, err := s.getPrices(ctx, info)
+		if err != nil {
+			log.Warnf("[PerpetualService] no mark price of market %s, error: %v", info.Name, err)
+			continue
+		}
+		serviceHelper.ValuePerpetualPosition(position, markPrice, info.ContractSize)
+	}
+
+	return positions, nil
+}
+
+func (s *perpetualService) GetFundingRates(ctx context.Context, req *dto.FundingQueryReq) ([]*dto.FundingRate, error) {
+	if _, err := getPerpetualMarket(req.Market); err != nil {
+		return nil, err
+	}
+	return s.perpetualRepo.GetFundingRates(ctx, s.db, req.Market, normalizeFundingLimit(req.Limit))
+}
+
+func (s *perpetualService) GetFundingPayments(ctx context.Context, userId string, req *dto.FundingQueryReq) ([]*dto.FundingPayment, error) {
+	return s.perpetualRepo.GetFundingPaymentsByUserId(ctx, s.db, userId, req.Market, normalizeFundingLimit(req.Limit))
+}
+
+func (s *perpetualService) GetLiquidations(ctx context.Context, userId string, req *dto.FundingQueryReq) ([]*dto.PerpetualLiquidation, error) {
+	return s.perpetualRepo.GetLiquidationsByUserId(ctx, s.db, userId, req.Market, normalizeFundingLimit(req.Limit))
+}
+
+// LiquidatePositions liquidate positions of all perpetual markets under maintenance margin rate at mark price,
+// failure of one market or position is logged and skipped.
+func (s *perpetualService) LiquidatePositions(ctx context.Context) error {
+	for _, info := range settings.GetPerpetualMarkets() {
+		markPrice, _, err := s.getPrices(ctx, info)
+		if err != nil {
+			log.Warnf("[PerpetualService] no mark price of market %s, skip liquidation, error: %v", info.Name, err)
+			continue
+		}
+		positions, err := s.perpetualRepo.GetOpenPositionsByMarket(ctx, s.db, info.Name)
+		if err != nil {
+			log.Errorf("[PerpetualService] failed to get positions of market %s, error: %v", info.Name, err)
+			continue
+		}
+
+		for _, position := range positions {
+			serviceHelper.ValuePerpetualPosition(position, markPrice, info.ContractSize)
+			if !serviceHelper.IsPerpetualPositionAtRisk(position) {
+				continue
+			}
+			if _, err := s.liquidatePosition(ctx, info, position.UserID, markPrice); err != nil {
+				log.Errorf("[PerpetualService] liquidate position of user %s in %s failed, error: %v", position.UserID, info.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// liquidatePosition close position at mark price against insurance fund in one tx, position is re-checked in tx,
+// return nil if it is no longer at risk. Market is halted if insurance fund can not pay.
+func (s *perpetualService) liquidatePosition(ctx context.Context, info *market.MarketInfo, userId string, markPrice float64) (*dto.PerpetualLiquidation, error) {
+	var liquidation *dto.PerpetualLiquidation
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		positions, err := s.perpetualRepo.GetPositionsByUserIds(ctx, tx, []string{userId}, info.Name)
+		if err != nil {
+			return err
+		}
+		position, ok := positions[userId]
+		if !ok {
+			return nil
+		}
+		serviceHelper.ValuePerpetualPosition(position, markPrice, info.ContractSize)
+		if !serviceHelper.IsPerpetualPositionAtRisk(position) {
+			return nil
+		}
+
+		var fill *serviceHelper.PerpetualFillResult
+		liquidation, fill = serviceHelper.LiquidatePerpetualPosition(position, markPrice, info.ContractSize)
+		if err := s.perpetualRepo.UpsertPosition(ctx, tx, position); err != nil {
+			return err
+		}
+		if err := s.balanceRepo.UpdateAsset(ctx, tx, userId, info.QuoteAsset, utils.RoundFloat(liquidation.Margin+liquidation.Pnl), -liquidation.Margin); err != nil {
+			return err
+		}
+		if err := settleWithInsuranceFund(ctx, tx, s.balanceRepo, info.QuoteAsset, -liquidation.Pnl); err != nil {
+			return err
+		}
+		if err := s.perpetualRepo.InsertLiquidation(ctx, tx, liquidation); err != nil {
+			return err
+		}
+		return s.pnlRepo.InsertRealizedPnl(ctx, tx, &dto.RealizedPnl{
+			UserID:   userId,
+			Market:   info.Name,
+			Asset:    info.QuoteAsset,
+			Size:     fill.ClosedSize,
+			Proceeds: fill.Proceeds,
+			Cost:     utils.RoundFloat(fill.Proceeds - liquidation.Pnl),
+			Pnl:      liquidation.Pnl,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsuranceFundShort) {
+			haltPerpetualMarket(ctx, s.db, s.perpetualRepo, info.Name, "insurance fund can not cover liquidation")
+		}
+		return nil, err
+	}
+
+	if liquidation != nil {
+		log.Warnf("[PerpetualService] liquidated position of user %s in %s, size: %v, mark price: %v, pnl: %v, bad debt: %v",
+			userId, info.Name, liquidation.Size, markPrice, liquidation.Pnl, liquidation.BadDebt)
+	}
+	return liquidation, nil
+}
+
+// SettleFunding settle funding of all perpetual markets, failure of one market is logged and skipped.
+func (s *perpetualService) SettleFunding(ctx context.Context) error {
+	for _, info := range settings.GetPerpetualMarkets() {
+		if err := s.settleMarketFunding(ctx, info); err != nil {
+			log.Errorf("[PerpetualService] settle funding of market %s failed, error: %v", info.Name, err)
+		}
+	}
+	return nil
+}
+
+// settleMarketFunding positions pay or receive funding from position margin (locked balance), payments are collected
+// into and paid by insurance fund, so shortfall of payers does not affect receivers. If insurance fund can not cover
+// net payment, no funding is settled and market is halted.
+func (s *perpetualService) settleMarketFunding(ctx context.Context, info *market.MarketInfo) error {
+	markPrice, indexPrice, err := s.getPrices(ctx, info)
+	if err != nil {
+		return err
+	}
+	if indexPrice <= 0 {
+		return fmt.Errorf("no index price of %s", info.IndexSymbol())
+	}
+
+	rate := &dto.FundingRate{
+		Market:      info.Name,
+		FundingRate: serviceHelper.CalculateFundingRate(markPrice, indexPrice),
+		MarkPrice:   markPrice,
+		IndexPrice:  indexPrice,
+	}
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.perpetualRepo.InsertFundingRate(ctx, tx, rate); err != nil {
+			return err
+		}
+		if rate.FundingRate == 0 {
+			return nil
+		}
+
+		positions, err := s.perpetualRepo.GetOpenPositionsByMarket(ctx, tx, info.Name)
+		if err != nil {
+			return err
+		}
+
+		var insuranceChanging, shortfall float64
+		for _, position := range positions {
+			amount := serviceHelper.CalculateFundingPayment(position.Size, info.ContractSize, markPrice, rate.FundingRate)
+			if amount < 0 {
+				paid := min(-amount, position.Margin)
+				shortfall += -amount - paid
+				amount = -paid
+			}
+			if amount == 0 {
+				continue
+			}
+
+			position.Margin = utils.RoundFloat(position.Margin + amount)
+			if err := s.perpetualRepo.UpsertPosition(ctx, tx, position); err != nil {
+				return err
+			}
+			if err := s.balanceRepo.UpdateAsset(ctx, tx, position.UserID, info.QuoteAsset, 0, amount); err != nil {
+				return err
+			}
+			err := s.perpetualRepo.InsertFundingPayment(ctx, tx, &dto.FundingPayment{
+				UserID:       position.UserID,
+				Market:       info.Name,
+				Asset:        info.QuoteAsset,
+				PositionSize: position.Size,
+				MarkPrice:    markPrice,
+				FundingRate:  rate.FundingRate,
+				Amount:       amount,
+			})
+			if err != nil {
+				return err
+			}
+			insuranceChanging -= amount
+		}
+
+		if shortfall > 0 {
+			log.Warnf("[PerpetualService] funding payers margin insufficient, market: %s, shortfall: %v", info.Name, utils.RoundFloat(shortfall))
+		}
+		if err := settleWithInsuranceFund(ctx, tx, s.balanceRepo, info.QuoteAsset, utils.RoundFloat(insuranceChanging)); err != nil {
+			return err
+		}
+
+		log.Infof("[PerpetualService] settled funding of market %s, rate: %v, positions: %d", info.Name, rate.FundingRate, len(positions))
+		return nil
+	})
+	if errors.Is(err, ErrInsuranceFundShort) {
+		haltPerpetualMarket(ctx, s.db, s.perpetualRepo, info.Name, "insurance fund can not cover funding payments")
+	}
+	return err
+}
+
+// ResumeMarket lift halt of market, insurance fund should be refilled before.
+func (s *perpetualService) ResumeMarket(ctx context.Context, admin *dto.Admin, market string) error {
+	if _, err := getPerpetualMarket(market); err != nil {
+		return err
+	}
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.perpetualRepo.DeleteMarketHalt(ctx, tx, market); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newAdminAuditLog(admin, dto.AUDIT_PERPETUAL_RESUME, market))
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("[PerpetualService] admin %s resumed market %s", admin.Username, market)
+	return nil
+}
+
+// settleWithInsuranceFund credit (positive) or debit (negative) insurance fund available balance,
+// debit more than available balance fails with ErrInsuranceFundShort.
+func settleWithInsuranceFund(ctx context.Context, tx repository.DBExecutor, balanceRepo repository.IBalanceRepository, asset string, amount float64) error {
+	if amount > 0 {
+		return balanceRepo.UpdateAsset(ctx, tx, settings.INSURANCE_FUND_ACCOUNT_ID, asset, amount, 0)
+	}
+	if amount < 0 {
+		if err := balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, settings.INSURANCE_FUND_ACCOUNT_ID, asset, false, -amount); err != nil {
+			log.Errorf("[PerpetualService] insurance fund can not pay %v %s: %v", -amount, asset, err)
+			return ErrInsuranceFundShort
+		}
+	}
+	return nil
+}
+
+// haltPerpetualMarket refuse orders of market until admin resumes it, call it after tx of the failed payment is rolled back.
+func haltPerpetualMarket(ctx context.Context, db repository.DBExecutor, perpetualRepo repository.IPerpetualRepository, market, reason string) {
+	log.Errorf("[PerpetualService] halt market %s: %s", market, reason)
+	if err := perpetualRepo.InsertMarketHalt(ctx, db, &dto.PerpetualMarketHalt{Market: market, Reason: reason}); err != nil {
+		log.Errorf("[PerpetualService] failed to halt market %s: %v", market, err)
+	}
+}
+
+// getPrices mark price and index price of perpetual market, index price is 0 if it is not available but mark price is.
+func (s *perpetualService) getPrices(ctx context.Context, info *market.MarketInfo) (markPrice, indexPrice float64, err error) {
+	indexPrice, err = s.priceIndexService.GetIndexPrice(ctx, info.IndexSymbol())
+	if err != nil {
+		log.Warnf("[PerpetualService] failed to get index price of %s, error: %v", info.IndexSymbol(), err)
+		indexPrice = 0
+	}
+
+	snapshot, err := s.orderBookService.GetSnapshot(ctx, info.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+	markPrice = serviceHelper.MarkPrice(snapshot, indexPrice)
+	if markPrice <= 0 {
+		return 0, 0, ErrNoMarkPrice
+	}
+	return markPrice, indexPrice, nil
+}
+
+func getPerpetualMarket(name string) (*market.MarketInfo, error) {
+	for _, info := range settings.GetPerpetualMarkets() {
+		if info.Name == name {
+			return info, nil
+		}
+	}
+	return nil, ErrNotPerpetualMarket
+}
+
+func normalizeFundingLimit(limit int) int {
+	if limit <= 0 {
+		return defaultFundingLimit
+	}
+	return min(limit, fundingMaxLimit)
+}
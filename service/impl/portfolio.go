@@ -0,0 +1,294 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/labstack/gommon/log"
+	"sort"
+	"time"
+)
+
+const (
+	defaultPnlPeriod      = 30 * 24 * time.Hour
+	equitySnapshotQuote   = "USDT"
+	equityHistoryMaxDays  = 366
+	equitySnapshotDateFmt = "2006-01-02"
+)
+
+type portfolioService struct {
+	db                 *sql.DB
+	userRepo           repository.IUserRepository
+	balanceRepo        repository.IBalanceRepository
+	pnlRepo            repository.IPnlRepository
+	equitySnapshotRepo repository.IEquitySnapshotRepository
+	orderBookService   service.IOrderBookService
+	priceIndexService  service.IPriceIndexService
+}
+
+func NewIPortfolioService(db *sql.DB,
+	userRepo repository.IUserRepository,
+	balanceRepo repository.IBalanceRepository,
+	pnlRepo repository.IPnlRepository,
+	equitySnapshotRepo repository.IEquitySnapshotRepository,
+	orderBookService service.IOrderBookService,
+	priceIndexService service.IPriceIndexService) service.IPortfolioService {
+	return &portfolioService{
+		db:                 db,
+		userRepo:           userRepo,
+		balanceRepo:        balanceRepo,
+		pnlRepo:            pnlRepo,
+		equitySnapshotRepo: equitySnapshotRepo,
+		orderBookService:   orderBookService,
+		priceIndexService:  priceIndexService,
+	}
+}
+
+// GetPnl realized pnl per market in period and unrealized pnl of current balances valued at market latest price.
+func (s *portfolioService) GetPnl(ctx context.Context, userId string, req *dto.PnlQueryReq) (*dto.PnlResp, error) {
+	if req == nil {
+		return nil, ErrInvalidInput
+	}
+	to := time.Now()
+	if req.To > 0 {
+		to = time.UnixMilli(req.To)
+	}
+	from := to.Add(-defaultPnlPeriod)
+	if req.From > 0 {
+		from = time.UnixMilli(req.From)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidInput
+	}
+
+	realized, err := s.pnlRepo.SumRealizedPnlByMarket(ctx, s.db, userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	unrealized, err := s.getUnrealizedPnl(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.PnlResp{
+		QuoteAsset: "USDT",
+		From:       from.UnixMilli(),
+		To:         to.UnixMilli(),
+		Realized:   realized,
+		Unrealized: unrealized,
+	}
+	for _, pnl := range realized {
+		resp.TotalRealizedPnl += pnl.Pnl
+	}
+	for _, pnl := range unrealized {
+		resp.TotalUnrealizedPnl += pnl.Pnl
+	}
+	resp.TotalRealizedPnl = utils.RoundFloat(resp.TotalRealizedPnl)
+	resp.TotalUnrealizedPnl = utils.RoundFloat(resp.TotalUnrealizedPnl)
+
+	return resp, nil
+}
+
+func (s *portfolioService) getUnrealizedPnl(ctx context.Context, userId string) ([]*dto.AssetUnrealizedPnl, error) {
+	bases, err := s.pnlRepo.GetCostBasesByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.balanceRepo.GetBalancesByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+	quantities := make(map[string]float64, len(balances))
+	for _, balance := range balances {
+		quantities[balance.Asset] = balance.Available + balance.Locked
+	}
+
+	pnls := make([]*dto.AssetUnrealizedPnl, 0, len(bases))
+	for _, basis := range bases {
+		quantity := quantities[basis.Asset]
+		if quantity <= 0 {
+			continue
+		}
+
+		market := fmt.Sprintf("%v-USDT", basis.Asset)
+		price, err := s.orderBookService.GetLatestPrice(ctx, market)
+		if err != nil || price <= 0 {
+			log.Warnf("[PortfolioService] skip unrealized pnl, no latest price of market %s, error: %v", market, err)
+			continue
+		}
+
+		marketValue := utils.RoundFloat(quantity * price)
+		costValue := utils.RoundFloat(quantity * basis.AvgCost)
+		pnls = append(pnls, &dto.AssetUnrealizedPnl{
+			Asset:       basis.Asset,
+			Market:      market,
+			Quantity:    utils.RoundFloat(quantity),
+			AvgCost:     basis.AvgCost,
+			LatestPrice: price,
+			MarketValue: marketValue,
+			CostValue:   costValue,
+			Pnl:         utils.RoundFloat(marketValue - costValue),
+		})
+	}
+
+	return pnls, nil
+}
+
+type usdtPrice struct {
+	price  float64
+	source dto.PriceSource
+}
+
+// GetPortfolio value every asset of user in quote asset, latest price first and fallback to index price.
+func (s *portfolioService) GetPortfolio(ctx context.Context, userId string, quote string) (*dto.Portfolio, error) {
+	if !isValuationAsset(quote) {
+		return nil, ErrInvalidInput
+	}
+
+	balances, err := s.balanceRepo.GetBalancesByUserId(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]usdtPrice)
+	quotePrice := s.getUSDTPrice(ctx, quote, prices)
+	if quotePrice.price <= 0 {
+		return nil, fmt.Errorf("no price available for quote asset %s", quote)
+	}
+
+	portfolio := &dto.Portfolio{
+		QuoteAsset: quote,
+		Assets:     make([]*dto.PortfolioAsset, 0, len(balances)),
+		Timestamp:  time.Now(),
+	}
+	for _, balance := range balances {
+		asset := &dto.PortfolioAsset{
+			Asset:       balance.Asset,
+			Available:   balance.Available,
+			Locked:      balance.Locked,
+			Total:       utils.RoundFloat(balance.Available + balance.Locked),
+			PriceSource: dto.PRICE_SOURCE_NONE,
+		}
+		if asset.Total != 0 {
+			assetPrice := s.getUSDTPrice(ctx, balance.Asset, prices)
+			asset.Price = utils.RoundFloat(assetPrice.price / quotePrice.price)
+			asset.PriceSource = assetPrice.source
+			asset.AvailableValue = utils.RoundFloat(asset.Available * asset.Price)
+			asset.LockedValue = utils.RoundFloat(asset.Locked * asset.Price)
+			asset.Value = utils.RoundFloat(asset.AvailableValue + asset.LockedValue)
+		}
+
+		portfolio.AvailableEquity += asset.AvailableValue
+		portfolio.LockedEquity += asset.LockedValue
+		portfolio.Assets = append(portfolio.Assets, asset)
+	}
+	portfolio.AvailableEquity = utils.RoundFloat(portfolio.AvailableEquity)
+	portfolio.LockedEquity = utils.RoundFloat(portfolio.LockedEquity)
+	portfolio.TotalEquity = utils.RoundFloat(portfolio.AvailableEquity + portfolio.LockedEquity)
+
+	if portfolio.TotalEquity > 0 {
+		for _, asset := range portfolio.Assets {
+			asset.Allocation = utils.RoundFloat(asset.Value / portfolio.TotalEquity * 100)
+		}
+	}
+	sort.Slice(portfolio.Assets, func(i, j int) bool {
+		return portfolio.Assets[i].Value > portfolio.Assets[j].Value
+	})
+
+	return portfolio, nil
+}
+
+func (s *portfolioService) GetEquityHistory(ctx context.Context, userId string, days int) ([]*dto.EquitySnapshot, error) {
+	if days <= 0 || days > equityHistoryMaxDays {
+		return nil, ErrInvalidInput
+	}
+
+	sinceDate := time.Now().UTC().AddDate(0, 0, -days).Format(equitySnapshotDateFmt)
+	return s.equitySnapshotRepo.GetByUserIdSince(ctx, s.db, userId, sinceDate)
+}
+
+// SnapshotAllEquities record all users total equity (USDT) of today (UTC).
+func (s *portfolioService) SnapshotAllEquities(ctx context.Context) error {
+	users, err := s.userRepo.GetAllUsers(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	snapshotDate := time.Now().UTC().Format(equitySnapshotDateFmt)
+	failed := 0
+	for _, user := range users {
+		portfolio, err := s.GetPortfolio(ctx, user.ID, equitySnapshotQuote)
+		if err == nil {
+			err = s.equitySnapshotRepo.Upsert(ctx, s.db, &dto.EquitySnapshot{
+				UserID:       user.ID,
+				SnapshotDate: snapshotDate,
+				Equity:       portfolio.TotalEquity,
+				QuoteAsset:   equitySnapshotQuote,
+			})
+		}
+		if err != nil {
+			failed++
+			log.Errorf("[PortfolioService] snapshot equity failed, userId: %s, error: %v", user.ID, err)
+		}
+	}
+
+	log.Infof("[PortfolioService] snapshot equities done, date: %s, users: %d, failed: %d", snapshotDate, len(users), failed)
+	return nil
+}
+
+// GetUSDTPrices price in USDT of assets, return error if any asset has no price.
+func (s *portfolioService) GetUSDTPrices(ctx context.Context, assets []string) (map[string]float64, error) {
+	cache := make(map[string]usdtPrice, len(assets))
+	prices := make(map[string]float64, len(assets))
+	for _, asset := range assets {
+		price := s.getUSDTPrice(ctx, asset, cache)
+		if price.price <= 0 {
+			return nil, fmt.Errorf("no price available for asset %s", asset)
+		}
+		prices[asset] = price.price
+	}
+	return prices, nil
+}
+
+// getUSDTPrice asset price in USDT, matching engine latest price first and fallback to index price, cached in prices.
+func (s *portfolioService) getUSDTPrice(ctx context.Context, asset string, prices map[string]usdtPrice) usdtPrice {
+	if asset == "USDT" {
+		return usdtPrice{price: 1, source: dto.PRICE_SOURCE_LATEST}
+	}
+	if price, ok := prices[asset]; ok {
+		return price
+	}
+
+	market := fmt.Sprintf("%v-USDT", asset)
+	price := usdtPrice{source: dto.PRICE_SOURCE_NONE}
+	if latestPrice, err := s.orderBookService.GetLatestPrice(ctx, market); err == nil && latestPrice > 0 {
+		price = usdtPrice{price: latestPrice, source: dto.PRICE_SOURCE_LATEST}
+	} else if indexPrice, err := s.priceIndexService.GetIndexPrice(ctx, market); err == nil && indexPrice > 0 {
+		price = usdtPrice{price: indexPrice, source: dto.PRICE_SOURCE_INDEX}
+	} else {
+		log.Warnf("[PortfolioService] no price available for asset %s, error: %v", asset, err)
+	}
+
+	prices[asset] = price
+	return price
+}
+
+// isValuationAsset USDT or base asset of any market.
+func isValuationAsset(asset string) bool {
+	if asset == "USDT" {
+		return true
+	}
+	for _, m := range settings.ALL_MARKETS {
+		if m.BaseAsset == asset {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package serviceImpl
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/external"
+	"github.com/johnny1110/crypto-exchange/service"
+	"sync"
+	"time"
+)
+
+const indexPriceCacheTTL = time.Minute
+
+type cachedIndexPrice struct {
+	price     float64
+	expiredAt time.Time
+}
+
+// priceIndexService external index price with short-lived cache, every external call fetches all markets summary.
+type priceIndexService struct {
+	cache map[string]cachedIndexPrice
+	mu    sync.Mutex
+}
+
+func NewIPriceIndexService() service.IPriceIndexService {
+	return &priceIndexService{
+		cache: make(map[string]cachedIndexPrice),
+	}
+}
+
+func (s *priceIndexService) GetIndexPrice(ctx context.Context, market string) (float64, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[market]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expiredAt) {
+		return cached.price, nil
+	}
+
+	price, err := external.GetIndexPrice(ctx, market)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.cache[market] = cachedIndexPrice{price: price, expiredAt: time.Now().Add(indexPriceCacheTTL)}
+	s.mu.Unlock()
+	return price, nil
+}
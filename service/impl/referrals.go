@@ -0,0 +1,101 @@
+package serviceImpl
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+)
+
+var ErrInvalidReferralCode = errors.New("invalid referral code")
+
+const (
+	referralCodeLength          = 8
+	referralCodeMaxRetry        = 3
+	defaultReferralQueryLimit   = 50
+	referralCommissionsMaxLimit = 500
+)
+
+type referralService struct {
+	db           *sql.DB
+	referralRepo repository.IReferralRepository
+}
+
+func NewIReferralService(db *sql.DB, referralRepo repository.IReferralRepository) service.IReferralService {
+	return &referralService{
+		db:           db,
+		referralRepo: referralRepo,
+	}
+}
+
+// GetReferralInfo return user referral code (create if not exists) and referees count.
+func (s *referralService) GetReferralInfo(ctx context.Context, userId string) (*dto.ReferralInfo, error) {
+	code, err := s.getOrCreateCode(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.referralRepo.CountReferees(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ReferralInfo{
+		Code:           code,
+		RefereeCount:   count,
+		CommissionRate: settings.REFERRAL_COMMISSION_RATE,
+	}, nil
+}
+
+func (s *referralService) GetCommissions(ctx context.Context, userId string, limit int) ([]*dto.ReferralCommission, error) {
+	if limit <= 0 {
+		limit = defaultReferralQueryLimit
+	}
+	limit = min(limit, referralCommissionsMaxLimit)
+	return s.referralRepo.GetCommissionsByReferrerId(ctx, s.db, userId, limit)
+}
+
+func (s *referralService) GetCommissionTotals(ctx context.Context, userId string) ([]*dto.ReferralCommissionTotal, error) {
+	return s.referralRepo.SumCommissionsByReferrerId(ctx, s.db, userId)
+}
+
+func (s *referralService) getOrCreateCode(ctx context.Context, userId string) (string, error) {
+	if code, err := s.referralRepo.GetCodeByUserId(ctx, s.db, userId); err == nil {
+		return code, nil
+	}
+
+	var err error
+	for i := 0; i < referralCodeMaxRetry; i++ {
+		var code string
+		code, err = genReferralCode()
+		if err != nil {
+			return "", err
+		}
+		// code is unique, retry on collision.
+		if err = s.referralRepo.InsertCode(ctx, s.db, userId, code); err == nil {
+			return code, nil
+		}
+		log.Warnf("[ReferralService] insert referral code failed, userId: %s, error: %v", userId, err)
+	}
+
+	return "", errors.New("failed to create referral code")
+}
+
+func genReferralCode() (string, error) {
+	chars := "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	randomBytes := make([]byte, referralCodeLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, referralCodeLength)
+	for i, b := range randomBytes {
+		code[i] = chars[int(b)%len(chars)]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,481 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/ohlcv"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"github.com/johnny1110/crypto-exchange/ws"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var (
+	ErrRfqMarket              = errors.New("rfq is only supported in spot markets")
+	ErrRfqNotProvider         = errors.New("user is not rfq liquidity provider")
+	ErrRfqOwnRequest          = errors.New("can not quote own rfq request")
+	ErrRfqRequestNotFound     = errors.New("rfq request not found")
+	ErrRfqRequestNotOpen      = errors.New("rfq request is not open")
+	ErrRfqRequestExpired      = errors.New("rfq request expired")
+	ErrRfqQuoteNotFound       = errors.New("rfq quote not found")
+	ErrRfqQuoted              = errors.New("rfq request already quoted")
+	ErrRfqInsufficientBalance = errors.New("insufficient balance for rfq")
+)
+
+const (
+	defaultRfqLimit = 50
+	rfqMaxLimit     = 500
+)
+
+type rfqService struct {
+	db               *sql.DB
+	balanceRepo      repository.IBalanceRepository
+	tradeRepo        repository.ITradeRepository
+	feeRevenueRepo   repository.IFeeRevenueRepository
+	pnlRepo          repository.IPnlRepository
+	rfqRepo          repository.IRfqRepository
+	orderBookService service.IOrderBookService
+	klineTradeStream ohlcv.TradeStream
+	auditLogRepo     repository.IAuditLogRepository
+	tradePublisher   ws.TradePublisher
+}
+
+func NewIRfqService(db *sql.DB,
+	balanceRepo repository.IBalanceRepository,
+	tradeRepo repository.ITradeRepository,
+	feeRevenueRepo repository.IFeeRevenueRepository,
+	pnlRepo repository.IPnlRepository,
+	rfqRepo repository.IRfqRepository,
+	orderBookService service.IOrderBookService,
+	klineTradeStream ohlcv.TradeStream,
+	auditLogRepo repository.IAuditLogRepository,
+	tradePublisher ws.TradePublisher) service.IRfqService {
+	return &rfqService{
+		db:               db,
+		balanceRepo:      balanceRepo,
+		tradeRepo:        tradeRepo,
+		feeRevenueRepo:   feeRevenueRepo,
+		pnlRepo:          pnlRepo,
+		rfqRepo:          rfqRepo,
+		orderBookService: orderBookService,
+		klineTradeStream: klineTradeStream,
+		auditLogRepo:     auditLogRepo,
+		tradePublisher:   tradePublisher,
+	}
+}
+
+func (s *rfqService) AddProvider(ctx context.Context, admin *dto.Admin, req *dto.AddRfqProviderReq) (*dto.RfqProvider, error) {
+	provider := &dto.RfqProvider{UserID: req.UserID}
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.rfqRepo.InsertProvider(ctx, tx, provider); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newAdminAuditLog(admin, dto.AUDIT_RFQ_PROVIDER_ADD, req.UserID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("[RfqService] registered rfq provider: %s", req.UserID)
+	return provider, nil
+}
+
+// RemoveProvider open quotes of provider stay firm until their requests are closed.
+func (s *rfqService) RemoveProvider(ctx context.Context, admin *dto.Admin, userId string) error {
+	return WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.rfqRepo.DeleteProvider(ctx, tx, userId); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newAdminAuditLog(admin, dto.AUDIT_RFQ_PROVIDER_REMOVE, userId))
+	})
+}
+
+func (s *rfqService) GetProviders(ctx context.Context) ([]*dto.RfqProvider, error) {
+	return s.rfqRepo.GetProviders(ctx, s.db)
+}
+
+// CreateRequest open request for quotes, internal AMM responds immediately if it can afford the size.
+func (s *rfqService) CreateRequest(ctx context.Context, userId string, req *dto.RfqRequestReq) (*dto.RfqRequest, error) {
+	if _, err := getRfqMarket(req.Market); err != nil {
+		return nil, err
+	}
+	size := utils.RoundFloat(req.Size)
+	if size <= utils.Scale {
+		return nil, errors.New("rfq size must be greater than zero")
+	}
+
+	request := &dto.RfqRequest{
+		ID:        uuid.NewString(),
+		UserID:    userId,
+		Market:    req.Market,
+		Side:      req.Side,
+		Size:      size,
+		Status:    dto.RFQ_REQUEST_STATUS_OPEN,
+		ExpiresAt: time.Now().Add(settings.RFQ_REQUEST_TTL),
+	}
+	if err := s.rfqRepo.InsertRequest(ctx, s.db, request); err != nil {
+		return nil, err
+	}
+
+	if err := s.quoteByAmm(ctx, request); err != nil {
+		log.Warnf("[RfqService] AMM failed to quote rfq request %s, error: %v", request.ID, err)
+	}
+	return s.GetRequest(ctx, userId, request.ID)
+}
+
+// GetRequest request with all quotes, only visible to its taker.
+func (s *rfqService) GetRequest(ctx context.Context, userId, requestId string) (*dto.RfqRequest, error) {
+	request, err := s.getUserRequest(ctx, userId, requestId)
+	if err != nil {
+		return nil, err
+	}
+	request.Quotes, err = s.rfqRepo.GetQuotesByRequestId(ctx, s.db, requestId)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *rfqService) GetRequests(ctx context.Context, userId string, limit int) ([]*dto.RfqRequest, error) {
+	return s.rfqRepo.GetRequestsByUserId(ctx, s.db, userId, normalizeRfqLimit(limit))
+}
+
+func (s *rfqService) CancelRequest(ctx context.Context, userId, requestId string) (*dto.RfqRequest, error) {
+	request, err := s.getUserRequest(ctx, userId, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != dto.RFQ_REQUEST_STATUS_OPEN {
+		return nil, ErrRfqRequestNotOpen
+	}
+	if err := s.closeRequest(ctx, request, dto.RFQ_REQUEST_STATUS_CANCELED); err != nil {
+		return nil, err
+	}
+	return s.GetRequest(ctx, userId, requestId)
+}
+
+// Accept settle request with quote as block trade off order book, other open quotes are rejected.
+func (s *rfqService) Accept(ctx context.Context, user *dto.User, requestId string, req *dto.RfqAcceptReq) (*dto.RfqRequest, error) {
+	if err := checkCanTrade(user); err != nil {
+		return nil, err
+	}
+	request, err := s.getUserRequest(ctx, user.ID, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRfqRequestOpen(request); err != nil {
+		return nil, err
+	}
+	quote, err := s.rfqRepo.GetQuoteById(ctx, s.db, req.QuoteID)
+	if err != nil || quote.RequestID != request.ID {
+		return nil, ErrRfqQuoteNotFound
+	}
+	if quote.Status != dto.RFQ_QUOTE_STATUS_OPEN {
+		return nil, fmt.Errorf("rfq quote is %s", quote.Status)
+	}
+	info, err := getRfqMarket(request.Market)
+	if err != nil {
+		return nil, err
+	}
+
+	// only maker can get rebate.
+	result := serviceHelper.ProcessRfqSettlement(request, quote, info, max(user.TakerFee, 0))
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.rfqRepo.UpdateRequestStatus(ctx, tx, request.ID, dto.RFQ_REQUEST_STATUS_OPEN, dto.RFQ_REQUEST_STATUS_FILLED, quote.ID); err != nil {
+			return ErrRfqRequestNotOpen
+		}
+		if err := s.rfqRepo.UpdateQuoteStatus(ctx, tx, quote.ID, dto.RFQ_QUOTE_STATUS_OPEN, dto.RFQ_QUOTE_STATUS_ACCEPTED); err != nil {
+			return err
+		}
+		if err := s.rejectOpenQuotes(ctx, tx, request, info); err != nil {
+			return err
+		}
+
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, user.ID, result.PaidAsset, false, result.PaidAmount); err != nil {
+			return ErrRfqInsufficientBalance
+		}
+		for _, change := range result.BalanceChanges {
+			if err := s.balanceRepo.UpdateAsset(ctx, tx, change.UserID, change.Asset, change.Available, change.Locked); err != nil {
+				return err
+			}
+		}
+
+		if err := s.tradeRepo.InsertBlockTrade(ctx, tx, result.Trade, request.Side, result.BidFee, result.AskFee, info.BaseAsset, info.QuoteAsset); err != nil {
+			return err
+		}
+		if err := s.settleFeeRevenue(ctx, tx, request.Market, result.FeeAsset, result.Fee); err != nil {
+			return err
+		}
+		return applyPositionChanges(ctx, tx, s.pnlRepo, request.Market, info.BaseAsset, result.PositionChanges)
+	})
+	if err != nil {
+		log.Warnf("[RfqService] accept rfq request %s quote %s failed, error: %v", request.ID, quote.ID, err)
+		return nil, err
+	}
+
+	s.klineTradeStream.SyncTrade(&ohlcv.Trade{
+		Symbol:    result.Trade.Market,
+		Price:     result.Trade.Price,
+		Volume:    result.Trade.Size,
+		Timestamp: result.Trade.Timestamp,
+		Block:     true,
+	})
+	publishMarketTrades(ctx, s.db, s.tradeRepo, s.tradePublisher, request.Market, []book.Trade{result.Trade})
+
+	log.Infof("[RfqService] block trade settled, market: %s, request: %s, price: %v, size: %v", request.Market, request.ID, quote.Price, request.Size)
+	return s.GetRequest(ctx, user.ID, request.ID)
+}
+
+// GetOpenRequests open requests of other users for liquidity providers, quotes are sealed.
+func (s *rfqService) GetOpenRequests(ctx context.Context, userId string) ([]*dto.RfqRequest, error) {
+	if err := s.checkProvider(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.rfqRepo.GetOpenRequests(ctx, s.db, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*dto.RfqRequest, 0, len(requests))
+	for _, request := range requests {
+		if request.UserID != userId {
+			result = append(result, request)
+		}
+	}
+	return result, nil
+}
+
+// SubmitQuote firm quote of liquidity provider, funds to fill the whole size are locked until the request is closed.
+func (s *rfqService) SubmitQuote(ctx context.Context, userId, requestId string, req *dto.RfqQuoteReq) (*dto.RfqQuote, error) {
+	if err := s.checkProvider(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	request, err := s.rfqRepo.GetRequestById(ctx, s.db, requestId)
+	if err != nil {
+		return nil, ErrRfqRequestNotFound
+	}
+	if request.UserID == userId {
+		return nil, ErrRfqOwnRequest
+	}
+	if err := checkRfqRequestOpen(request); err != nil {
+		return nil, err
+	}
+
+	quotes, err := s.rfqRepo.GetQuotesByRequestId(ctx, s.db, requestId)
+	if err != nil {
+		return nil, err
+	}
+	for _, quote := range quotes {
+		if quote.ProviderID == userId && quote.Status == dto.RFQ_QUOTE_STATUS_OPEN {
+			return nil, ErrRfqQuoted
+		}
+	}
+
+	return s.submitQuote(ctx, userId, request, utils.RoundFloat(req.Price))
+}
+
+func (s *rfqService) CancelQuote(ctx context.Context, userId, quoteId string) (*dto.RfqQuote, error) {
+	quote, err := s.rfqRepo.GetQuoteById(ctx, s.db, quoteId)
+	if err != nil || quote.ProviderID != userId {
+		return nil, ErrRfqQuoteNotFound
+	}
+	request, err := s.rfqRepo.GetRequestById(ctx, s.db, quote.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := getRfqMarket(request.Market)
+	if err != nil {
+		return nil, err
+	}
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		return s.releaseQuote(ctx, tx, request, quote, info, dto.RFQ_QUOTE_STATUS_CANCELED)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.rfqRepo.GetQuoteById(ctx, s.db, quoteId)
+}
+
+func (s *rfqService) GetQuotes(ctx context.Context, userId string, limit int) ([]*dto.RfqQuote, error) {
+	return s.rfqRepo.GetQuotesByProviderId(ctx, s.db, userId, normalizeRfqLimit(limit))
+}
+
+// ExpireRequests expire open requests over ttl and release their quotes, failure of one request is logged and skipped.
+func (s *rfqService) ExpireRequests(ctx context.Context) error {
+	requests, err := s.rfqRepo.GetExpiredOpenRequests(ctx, s.db, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, request := range requests {
+		if err := s.closeRequest(ctx, request, dto.RFQ_REQUEST_STATUS_EXPIRED); err != nil {
+			log.Errorf("[RfqService] expire rfq request %s failed, error: %v", request.ID, err)
+		}
+	}
+	return nil
+}
+
+// quoteByAmm internal AMM quotes best price of order book widened by settings.RFQ_AMM_SPREAD.
+func (s *rfqService) quoteByAmm(ctx context.Context, request *dto.RfqRequest) error {
+	if request.UserID == settings.INTERNAL_AMM_ACCOUNT_ID {
+		return nil
+	}
+
+	snapshot, err := s.orderBookService.GetSnapshot(ctx, request.Market)
+	if err != nil {
+		return err
+	}
+	var price float64
+	if request.Side == model.BID {
+		price = snapshot.BestAskPrice * (1 + settings.RFQ_AMM_SPREAD)
+	} else {
+		price = snapshot.BestBidPrice * (1 - settings.RFQ_AMM_SPREAD)
+	}
+	if price <= 0 {
+		return errors.New("no price in order book")
+	}
+
+	_, err = s.submitQuote(ctx, settings.INTERNAL_AMM_ACCOUNT_ID, request, utils.RoundFloat(price))
+	return err
+}
+
+func (s *rfqService) submitQuote(ctx context.Context, providerId string, request *dto.RfqRequest, price float64) (*dto.RfqQuote, error) {
+	info, err := getRfqMarket(request.Market)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &dto.RfqQuote{
+		ID:         uuid.NewString(),
+		RequestID:  request.ID,
+		ProviderID: providerId,
+		Price:      price,
+		Status:     dto.RFQ_QUOTE_STATUS_OPEN,
+	}
+	lockAsset, lockAmount := serviceHelper.RfqProviderLock(request, info, price)
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.balanceRepo.LockedByUserIdAndAsset(ctx, tx, providerId, lockAsset, lockAmount); err != nil {
+			return ErrRfqInsufficientBalance
+		}
+		return s.rfqRepo.InsertQuote(ctx, tx, quote)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// closeRequest move open request to toStatus and reject its open quotes.
+func (s *rfqService) closeRequest(ctx context.Context, request *dto.RfqRequest, toStatus dto.RfqRequestStatus) error {
+	info, err := getRfqMarket(request.Market)
+	if err != nil {
+		return err
+	}
+
+	return WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.rfqRepo.UpdateRequestStatus(ctx, tx, request.ID, dto.RFQ_REQUEST_STATUS_OPEN, toStatus, ""); err != nil {
+			return err
+		}
+		return s.rejectOpenQuotes(ctx, tx, request, info)
+	})
+}
+
+func (s *rfqService) rejectOpenQuotes(ctx context.Context, tx *sql.Tx, request *dto.RfqRequest, info *market.MarketInfo) error {
+	quotes, err := s.rfqRepo.GetQuotesByRequestId(ctx, tx, request.ID)
+	if err != nil {
+		return err
+	}
+	for _, quote := range quotes {
+		if quote.Status != dto.RFQ_QUOTE_STATUS_OPEN {
+			continue
+		}
+		if err := s.releaseQuote(ctx, tx, request, quote, info, dto.RFQ_QUOTE_STATUS_REJECTED); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseQuote move open quote to toStatus and unlock provider's funds.
+func (s *rfqService) releaseQuote(ctx context.Context, tx *sql.Tx, request *dto.RfqRequest, quote *dto.RfqQuote, info *market.MarketInfo, toStatus dto.RfqQuoteStatus) error {
+	if err := s.rfqRepo.UpdateQuoteStatus(ctx, tx, quote.ID, dto.RFQ_QUOTE_STATUS_OPEN, toStatus); err != nil {
+		return err
+	}
+	lockAsset, lockAmount := serviceHelper.RfqProviderLock(request, info, quote.Price)
+	return s.balanceRepo.UnlockedByUserIdAndAsset(ctx, tx, quote.ProviderID, lockAsset, lockAmount)
+}
+
+// settleFeeRevenue taker fees of block trades go to exchange's margin account.
+func (s *rfqService) settleFeeRevenue(ctx context.Context, tx *sql.Tx, market, asset string, fee float64) error {
+	if fee <= 0 {
+		return nil
+	}
+	if err := s.balanceRepo.UpdateAsset(ctx, tx, settings.MARGIN_ACCOUNT_ID, asset, fee, 0); err != nil {
+		return err
+	}
+	return s.feeRevenueRepo.Insert(ctx, tx, &dto.FeeRevenue{
+		Market:    market,
+		Asset:     asset,
+		FeeIncome: fee,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *rfqService) getUserRequest(ctx context.Context, userId, requestId string) (*dto.RfqRequest, error) {
+	request, err := s.rfqRepo.GetRequestById(ctx, s.db, requestId)
+	if err != nil || request.UserID != userId {
+		return nil, ErrRfqRequestNotFound
+	}
+	return request, nil
+}
+
+func (s *rfqService) checkProvider(ctx context.Context, userId string) error {
+	isProvider, err := s.rfqRepo.IsProvider(ctx, s.db, userId)
+	if err != nil {
+		return err
+	}
+	if !isProvider {
+		return ErrRfqNotProvider
+	}
+	return nil
+}
+
+func checkRfqRequestOpen(request *dto.RfqRequest) error {
+	if request.Status != dto.RFQ_REQUEST_STATUS_OPEN {
+		return ErrRfqRequestNotOpen
+	}
+	if time.Now().After(request.ExpiresAt) {
+		return ErrRfqRequestExpired
+	}
+	return nil
+}
+
+// getRfqMarket block trades are spot only, perpetual positions are settled by order flow.
+func getRfqMarket(name string) (*market.MarketInfo, error) {
+	for _, info := range settings.ALL_MARKETS {
+		if info.Name == name && !info.IsPerpetual() {
+			return info, nil
+		}
+	}
+	return nil, ErrRfqMarket
+}
+
+func normalizeRfqLimit(limit int) int {
+	if limit <= 0 {
+		return defaultRfqLimit
+	}
+	return min(limit, rfqMaxLimit)
+}
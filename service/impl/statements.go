@@ -0,0 +1,308 @@
+package serviceImpl
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"io"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidStatementRange = errors.New("invalid statement time range, max range is 366 days")
+
+const (
+	statementPageSize = 500
+	statementMaxRange = 366 * 24 * time.Hour
+)
+
+// stable column schema of each statement type, never reorder or rename, only append.
+var (
+	orderStatementColumns = []string{"order_id", "market", "side", "type", "mode", "status", "price", "original_size",
+		"remaining_size", "quote_amount", "avg_dealt_price", "fee_rate", "fees", "fee_asset", "created_at", "updated_at"}
+	fillStatementColumns = []string{"trade_id", "order_id", "market", "side", "role", "price", "size", "quote_amount",
+		"fee", "fee_asset", "timestamp"}
+	ledgerStatementColumns = []string{"timestamp", "type", "asset", "amount", "ref_id", "market"}
+)
+
+type statementService struct {
+	db           *sql.DB
+	orderRepo    repository.IOrderRepository
+	ledgerRepo   repository.ILedgerRepository
+	orderService service.IOrderService
+}
+
+func NewIStatementService(db *sql.DB, orderRepo repository.IOrderRepository, ledgerRepo repository.ILedgerRepository, orderService service.IOrderService) service.IStatementService {
+	return &statementService{
+		db:           db,
+		orderRepo:    orderRepo,
+		ledgerRepo:   ledgerRepo,
+		orderService: orderService,
+	}
+}
+
+// Validate check statement request before response starts streaming.
+func (s *statementService) Validate(req *dto.StatementReq) error {
+	_, _, _, err := parseStatementReq(req)
+	return err
+}
+
+// parseStatementReq return time range [from, to) and the location to format timestamps.
+// To is default now and never later than now, so paging over the range is stable.
+func parseStatementReq(req *dto.StatementReq) (from, to time.Time, loc *time.Location, err error) {
+	if req == nil {
+		return from, to, nil, ErrInvalidInput
+	}
+	switch req.Type {
+	case dto.STATEMENT_TYPE_ORDERS, dto.STATEMENT_TYPE_FILLS, dto.STATEMENT_TYPE_LEDGER:
+	default:
+		return from, to, nil, ErrInvalidInput
+	}
+	switch req.Format {
+	case dto.STATEMENT_FORMAT_CSV, dto.STATEMENT_FORMAT_JSONL:
+	default:
+		return from, to, nil, ErrInvalidInput
+	}
+
+	loc = time.UTC
+	if req.TimeZone != "" {
+		if loc, err = time.LoadLocation(req.TimeZone); err != nil {
+			return from, to, nil, ErrInvalidInput
+		}
+	}
+
+	now := time.Now()
+	from = time.UnixMilli(req.From)
+	to = now
+	if req.To > 0 && time.UnixMilli(req.To).Before(now) {
+		to = time.UnixMilli(req.To)
+	}
+	if req.From <= 0 || !from.Before(to) || to.Sub(from) > statementMaxRange {
+		return from, to, nil, ErrInvalidStatementRange
+	}
+
+	return from, to, loc, nil
+}
+
+// Export write user statement to w page by page (newest first), flush w after each page if it supports.
+func (s *statementService) Export(ctx context.Context, userId string, req *dto.StatementReq, w io.Writer) error {
+	from, to, loc, err := parseStatementReq(req)
+	if err != nil {
+		return err
+	}
+
+	var writer statementWriter
+	if req.Format == dto.STATEMENT_FORMAT_JSONL {
+		writer = &jsonlStatementWriter{w: w}
+	} else {
+		writer = &csvStatementWriter{w: csv.NewWriter(w)}
+	}
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	switch req.Type {
+	case dto.STATEMENT_TYPE_ORDERS:
+		err = s.exportOrders(ctx, userId, from, to, loc, writer, flush)
+	case dto.STATEMENT_TYPE_FILLS:
+		err = s.exportFills(ctx, userId, from, to, loc, writer, flush)
+	default:
+		err = s.exportLedger(ctx, userId, from, to, loc, writer, flush)
+	}
+	if err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+func (s *statementService) exportOrders(ctx context.Context, userId string, from, to time.Time, loc *time.Location, writer statementWriter, flush func() error) error {
+	if err := writer.WriteHeader(orderStatementColumns); err != nil {
+		return err
+	}
+
+	query := &dto.GetOrdersQueryReq{
+		UserID:      userId,
+		PageSize:    statementPageSize,
+		CurrentPage: 1,
+		CreatedFrom: from,
+		CreatedTo:   to,
+	}
+	statuses := []model.OrderStatus{model.ORDER_STATUS_NEW, model.ORDER_STATUS_PARTIAL, model.ORDER_STATUS_FILLED, model.ORDER_STATUS_CANCELED}
+	for {
+		page, err := s.orderRepo.PaginationQuery(ctx, s.db, query, statuses, time.Time{})
+		if err != nil {
+			return err
+		}
+		for _, order := range page.Result {
+			err = writer.WriteRow(orderStatementColumns, []any{
+				order.ID, order.Market, order.Side.String(), order.Type.String(), order.Mode.String(), string(order.Status),
+				order.Price, order.OriginalSize, order.RemainingSize, order.QuoteAmount, order.AvgDealtPrice,
+				order.FeeRate, order.Fees, order.FeeAsset, formatStatementTime(order.CreatedAt, loc), formatStatementTime(order.UpdatedAt, loc),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err = flush(); err != nil {
+			return err
+		}
+		if !page.HasNext {
+			return nil
+		}
+		query.CurrentPage++
+	}
+}
+
+func (s *statementService) exportFills(ctx context.Context, userId string, from, to time.Time, loc *time.Location, writer statementWriter, flush func() error) error {
+	if err := writer.WriteHeader(fillStatementColumns); err != nil {
+		return err
+	}
+
+	query := &dto.GetFillsQueryReq{
+		StartTime: from.UnixMilli(),
+		EndTime:   to.UnixMilli(),
+		Limit:     statementPageSize,
+	}
+	for {
+		resp, err := s.orderService.GetFills(ctx, userId, query)
+		if err != nil {
+			return err
+		}
+		for _, fill := range resp.Fills {
+			err = writer.WriteRow(fillStatementColumns, []any{
+				fill.TradeID, fill.OrderID, fill.Market, fill.Side.String(), fill.Role.String(), fill.Price, fill.Size,
+				fill.QuoteAmount, fill.Fee, fill.FeeAsset, formatStatementTime(fill.Timestamp, loc),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err = flush(); err != nil {
+			return err
+		}
+		if resp.NextCursor == 0 {
+			return nil
+		}
+		query.Cursor = resp.NextCursor
+	}
+}
+
+func (s *statementService) exportLedger(ctx context.Context, userId string, from, to time.Time, loc *time.Location, writer statementWriter, flush func() error) error {
+	if err := writer.WriteHeader(ledgerStatementColumns); err != nil {
+		return err
+	}
+
+	for offset := 0; ; offset += statementPageSize {
+		entries, err := s.ledgerRepo.GetEntriesByUserId(ctx, s.db, userId, from, to, statementPageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			err = writer.WriteRow(ledgerStatementColumns, []any{
+				formatStatementTime(entry.Timestamp, loc), string(entry.Type), entry.Asset, entry.Amount, entry.RefID, entry.Market,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err = flush(); err != nil {
+			return err
+		}
+		if len(entries) < statementPageSize {
+			return nil
+		}
+	}
+}
+
+// formatStatementTime RFC3339 with offset of user's time zone, e.g. 2025-06-05T18:30:00.123+08:00
+func formatStatementTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+type statementWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(columns []string, values []any) error
+	Flush() error
+}
+
+type csvStatementWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvStatementWriter) WriteHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvStatementWriter) WriteRow(_ []string, values []any) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvStatementWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlStatementWriter one json object per line, keys keep column order.
+type jsonlStatementWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (j *jsonlStatementWriter) WriteHeader(_ []string) error {
+	return nil
+}
+
+func (j *jsonlStatementWriter) WriteRow(columns []string, values []any) error {
+	j.buf.WriteByte('{')
+	for i, column := range columns {
+		if i > 0 {
+			j.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal statement column %s: %w", column, err)
+		}
+		j.buf.Write(key)
+		j.buf.WriteByte(':')
+		j.buf.Write(value)
+	}
+	j.buf.WriteString("}\n")
+	return nil
+}
+
+func (j *jsonlStatementWriter) Flush() error {
+	if j.buf.Len() == 0 {
+		return nil
+	}
+	_, err := j.w.Write(j.buf.Bytes())
+	j.buf.Reset()
+	return err
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/security"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"testing"
+)
+
+var statusOperator = &dto.Admin{ID: "ADM000000000001", Username: "frizo", Role: dto.ADMIN_ROLE_SUPERADMIN}
+
+// updateAccountStatus change account status, return user reloaded with new status.
+func updateAccountStatus(t *testing.T, user *dto.User, req *dto.UpdateAccountStatusReq) (*dto.User, *dto.AccountStatusInfo) {
+	t.Helper()
+	info, err := c.AdminService.UpdateAccountStatus(context.Background(), statusOperator, user.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err = c.UserService.GetUser(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, info
+}
+
+func Test_AccountStatus_TradeAndWithdrawDisabled(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+	whitelistAddress(t, user.ID, "USDT")
+	ctx := context.Background()
+	order := &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 0.01, Size: 1}
+	withdraw := &dto.WithdrawReq{Asset: "USDT", Amount: 10, Address: testAddress}
+
+	user, _ = updateAccountStatus(t, user, &dto.UpdateAccountStatusReq{Status: dto.ACCOUNT_STATUS_ACTIVE, TradeDisabled: true, Reason: "bot abuse"})
+	if _, err := c.OrderService.PlaceOrder(ctx, "HDX-USDT", user, order); !errors.Is(err, serviceImpl.ErrAccountTradeDisabled) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrAccountTradeDisabled, err)
+	}
+	if _, err := c.WithdrawalService.Withdraw(ctx, user, withdraw); err != nil {
+		t.Errorf("Expected withdrawal allowed, got %v", err)
+	}
+
+	user, _ = updateAccountStatus(t, user, &dto.UpdateAccountStatusReq{Status: dto.ACCOUNT_STATUS_ACTIVE, WithdrawDisabled: true, Reason: "address check"})
+	if _, err := c.WithdrawalService.Withdraw(ctx, user, withdraw); !errors.Is(err, serviceImpl.ErrAccountWithdrawDisabled) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrAccountWithdrawDisabled, err)
+	}
+	if _, err := c.OrderService.PlaceOrder(ctx, "HDX-USDT", user, order); err != nil {
+		t.Errorf("Expected trading allowed, got %v", err)
+	}
+
+	auditLogs, err := c.AuditService.GetAuditLogs(ctx, &dto.AuditLogQueryReq{Action: dto.AUDIT_ACCOUNT_STATUS, Target: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(auditLogs), 2)
+	assert(t, auditLogs[0].Before, `{"reason":"bot abuse","status":"ACTIVE","trade_disabled":true,"withdraw_disabled":false}`)
+	assert(t, auditLogs[0].After, `{"cancel_orders":false,"reason":"address check","status":"ACTIVE","trade_disabled":false,"withdraw_disabled":true}`)
+
+	if _, err := c.AdminService.UpdateAccountStatus(ctx, statusOperator, user.ID, &dto.UpdateAccountStatusReq{Status: "BANNED", Reason: "x"}); !errors.Is(err, serviceImpl.ErrInvalidAccountStatus) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidAccountStatus, err)
+	}
+}
+
+func Test_AccountStatus_FreezeAndClose(t *testing.T) {
+	master := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+	sub := createSubAccount(t, master, "")
+	ctx := context.Background()
+	order := placeOrder(t, "ASTR-USDT", master, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 0.01, Size: 100})
+	session, err := c.SessionStore.Create(ctx, master, "10.0.0.1", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// freeze cancels open orders and cascades to sub-accounts.
+	frozen, info := updateAccountStatus(t, master, &dto.UpdateAccountStatusReq{Status: dto.ACCOUNT_STATUS_FROZEN, Reason: "investigation", CancelOrders: true})
+	assert(t, info.CanceledOrderIDs, []string{order.Order.ID})
+	assertFloat(t, balance(t, master.ID, "USDT").Locked, 0)
+	assertFloat(t, balance(t, master.ID, "USDT").Available, 100)
+	if _, err := c.OrderService.PlaceOrder(ctx, "ASTR-USDT", frozen, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 0.01, Size: 1}); !errors.Is(err, serviceImpl.ErrAccountFrozen) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrAccountFrozen, err)
+	}
+	subInfo, err := c.AdminService.GetAccountStatus(ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, subInfo.Status, dto.ACCOUNT_STATUS_FROZEN)
+	assert(t, subInfo.Reason, "master account FROZEN: investigation")
+	// session is kept, AuthMiddleware rejects inactive user.
+	sessionUser, err := c.SessionStore.Get(ctx, session.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, sessionUser.IsActive(), false)
+
+	// unfreeze.
+	active, _ := updateAccountStatus(t, master, &dto.UpdateAccountStatusReq{Status: dto.ACCOUNT_STATUS_ACTIVE, Reason: "cleared"})
+	assert(t, active.IsActive(), true)
+
+	// close deletes sessions and is final.
+	updateAccountStatus(t, master, &dto.UpdateAccountStatusReq{Status: dto.ACCOUNT_STATUS_CLOSED, Reason: "requested by user"})
+	if _, err := c.SessionStore.Get(ctx, session.Token); !errors.Is(err, security.ErrInvalidSession) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidSession, err)
+	}
+	if _, err := c.AdminService.UpdateAccountStatus(ctx, statusOperator, master.ID, &dto.UpdateAccountStatusReq{Status: dto.ACCOUNT_STATUS_ACTIVE, Reason: "reopen"}); !errors.Is(err, serviceImpl.ErrAccountClosed) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrAccountClosed, err)
+	}
+}
@@ -0,0 +1,238 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/security"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// adminTotpCode TOTP code of admin secret now.
+func adminTotpCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := security.TotpCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// withoutAdmins move all admins away during test, restored after test.
+func withoutAdmins(t *testing.T) {
+	t.Helper()
+	exec(t, `CREATE TABLE admins_backup AS SELECT * FROM admins`)
+	exec(t, `DELETE FROM admins`)
+	t.Cleanup(func() {
+		exec(t, `DELETE FROM admins`)
+		exec(t, `INSERT INTO admins SELECT * FROM admins_backup`)
+		exec(t, `DROP TABLE admins_backup`)
+	})
+}
+
+func Test_Admin_BootstrapSuperAdmin(t *testing.T) {
+	withoutAdmins(t)
+	secretFile := filepath.Join(t.TempDir(), "admin_bootstrap.secret")
+	ctx := context.Background()
+
+	if err := c.AdminAccountService.BootstrapSuperAdmin(ctx, secretFile); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(secretFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, info.Mode().Perm(), os.FileMode(0600))
+
+	secrets := make(map[string]string)
+	content, err := os.ReadFile(secretFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		key, value, _ := strings.Cut(line, ": ")
+		secrets[key] = value
+	}
+	assert(t, secrets["username"], settings.ADMIN_BOOTSTRAP_USERNAME)
+	totpURI, err := url.Parse(secrets["totp_uri"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := c.AdminAccountService.Login(ctx, &dto.AdminLoginReq{
+		Username: settings.ADMIN_BOOTSTRAP_USERNAME,
+		Password: secrets["password"],
+		TotpCode: adminTotpCode(t, totpURI.Query().Get("secret")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, session.Admin.Role, dto.ADMIN_ROLE_SUPERADMIN)
+
+	// admin exists, nothing is created or written again.
+	if err := os.Remove(secretFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AdminAccountService.BootstrapSuperAdmin(ctx, secretFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(secretFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no secret file, got %v", err)
+	}
+}
+
+func Test_Admin_BootstrapSuperAdminNotOverwriteSecretFile(t *testing.T) {
+	withoutAdmins(t)
+	secretFile := filepath.Join(t.TempDir(), "admin_bootstrap.secret")
+	if err := os.WriteFile(secretFile, []byte("old secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AdminAccountService.BootstrapSuperAdmin(context.Background(), secretFile); err == nil {
+		t.Fatal("Expected error of existing secret file")
+	}
+	content, err := os.ReadFile(secretFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, string(content), "old secret")
+	admins, err := c.AdminAccountService.GetAdmins(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(admins), 0)
+}
+
+var adminSeq int
+
+// newAdmin admin of role created by superadmin, TotpSecret is kept.
+func newAdmin(t *testing.T, role dto.AdminRole) *dto.Admin {
+	t.Helper()
+	adminSeq++
+	operator := &dto.Admin{ID: "ADM000000000001", Username: "frizo", Role: dto.ADMIN_ROLE_SUPERADMIN}
+	admin, err := c.AdminAccountService.CreateAdmin(context.Background(), operator, &dto.CreateAdminReq{
+		Username: fmt.Sprintf("admin_%d_%d", time.Now().UnixNano(), adminSeq), Password: "password1", Role: role,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return admin
+}
+
+func adminLogin(admin *dto.Admin, password, totpCode string) (*dto.AdminSession, error) {
+	return c.AdminAccountService.Login(context.Background(), &dto.AdminLoginReq{Username: admin.Username, Password: password, TotpCode: totpCode})
+}
+
+func Test_Admin_LoginLockedAfterFailures(t *testing.T) {
+	admin := newAdmin(t, dto.ADMIN_ROLE_SUPPORT)
+
+	// successful login resets failures.
+	for i := 0; i < settings.ADMIN_LOGIN_MAX_FAILURES-1; i++ {
+		if _, err := adminLogin(admin, "wrong", adminTotpCode(t, admin.TotpSecret)); !errors.Is(err, serviceImpl.ErrInvalidAdminCredentials) {
+			t.Fatalf("Expected %v, got %v", serviceImpl.ErrInvalidAdminCredentials, err)
+		}
+	}
+	if _, err := adminLogin(admin, "password1", adminTotpCode(t, admin.TotpSecret)); err != nil {
+		t.Fatal(err)
+	}
+
+	// wrong password and wrong TOTP code both count.
+	for i := 0; i < settings.ADMIN_LOGIN_MAX_FAILURES; i++ {
+		password, totpCode := "wrong", adminTotpCode(t, admin.TotpSecret)
+		if i%2 == 1 {
+			password, totpCode = "password1", "000000"
+		}
+		if _, err := adminLogin(admin, password, totpCode); !errors.Is(err, serviceImpl.ErrInvalidAdminCredentials) {
+			t.Fatalf("Expected %v, got %v", serviceImpl.ErrInvalidAdminCredentials, err)
+		}
+	}
+	if _, err := adminLogin(admin, "password1", adminTotpCode(t, admin.TotpSecret)); !errors.Is(err, serviceImpl.ErrAdminLoginLocked) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrAdminLoginLocked, err)
+	}
+
+	// other admins are not locked.
+	other := newAdmin(t, dto.ADMIN_ROLE_SUPPORT)
+	if _, err := adminLogin(other, "password1", adminTotpCode(t, other.TotpSecret)); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Admin_ChangePassword(t *testing.T) {
+	admin := newAdmin(t, dto.ADMIN_ROLE_FINANCE)
+	current, err := adminLogin(admin, "password1", adminTotpCode(t, admin.TotpSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := adminLogin(admin, "password1", adminTotpCode(t, admin.TotpSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		req  *dto.AdminChangePasswordReq
+		err  error
+	}{
+		{"wrong old password", &dto.AdminChangePasswordReq{OldPassword: "wrong", NewPassword: "password2", TotpCode: adminTotpCode(t, admin.TotpSecret)}, serviceImpl.ErrInvalidAdminCredentials},
+		{"wrong TOTP code", &dto.AdminChangePasswordReq{OldPassword: "password1", NewPassword: "password2", TotpCode: "000000"}, serviceImpl.ErrInvalidAdminCredentials},
+		{"same password", &dto.AdminChangePasswordReq{OldPassword: "password1", NewPassword: "password1", TotpCode: adminTotpCode(t, admin.TotpSecret)}, serviceImpl.ErrSameAdminPassword},
+	}
+	for _, tt := range tests {
+		if err := c.AdminAccountService.ChangePassword(ctx, current.Admin, current.Token, tt.req); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	err = c.AdminAccountService.ChangePassword(ctx, current.Admin, current.Token,
+		&dto.AdminChangePasswordReq{OldPassword: "password1", NewPassword: "password2", TotpCode: adminTotpCode(t, admin.TotpSecret)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// other sessions are logged out, current one is kept.
+	if _, err := c.AdminSessionCache.Get(other.Token); err == nil {
+		t.Error("Expected other session logged out")
+	}
+	if _, err := c.AdminSessionCache.Get(current.Token); err != nil {
+		t.Error(err)
+	}
+	if _, err := adminLogin(admin, "password1", adminTotpCode(t, admin.TotpSecret)); !errors.Is(err, serviceImpl.ErrInvalidAdminCredentials) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidAdminCredentials, err)
+	}
+	if _, err := adminLogin(admin, "password2", adminTotpCode(t, admin.TotpSecret)); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Admin_RotateTotp(t *testing.T) {
+	admin := newAdmin(t, dto.ADMIN_ROLE_OPS)
+	ctx := context.Background()
+
+	if _, err := c.AdminAccountService.RotateTotp(ctx, admin, &dto.AdminRotateTotpReq{Password: "password1", TotpCode: "000000"}); !errors.Is(err, serviceImpl.ErrInvalidAdminCredentials) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidAdminCredentials, err)
+	}
+	rotated, err := c.AdminAccountService.RotateTotp(ctx, admin, &dto.AdminRotateTotpReq{Password: "password1", TotpCode: adminTotpCode(t, admin.TotpSecret)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.TotpSecret == admin.TotpSecret || rotated.TotpURI == "" {
+		t.Fatalf("Expected new TOTP secret, got %q", rotated.TotpSecret)
+	}
+	assert(t, rotated.PasswordHash, "")
+
+	// codes of old secret are invalid right after.
+	if _, err := adminLogin(admin, "password1", adminTotpCode(t, admin.TotpSecret)); !errors.Is(err, serviceImpl.ErrInvalidAdminCredentials) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidAdminCredentials, err)
+	}
+	if _, err := adminLogin(admin, "password1", adminTotpCode(t, rotated.TotpSecret)); err != nil {
+		t.Error(err)
+	}
+}
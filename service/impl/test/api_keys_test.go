@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/security"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// signedAuth api key auth of request signed by secret at timestamp.
+func signedAuth(apiKey *dto.ApiKey, secret string, timestamp time.Time, body, clientIP string) *dto.ApiKeyAuth {
+	ts := strconv.FormatInt(timestamp.UnixMilli(), 10)
+	return &dto.ApiKeyAuth{
+		ApiKey:    apiKey.ApiKey,
+		Timestamp: ts,
+		Signature: security.SignRequest(secret, ts, "POST", "/api/v1/orders/HDX-USDT", body),
+		Method:    "POST",
+		Path:      "/api/v1/orders/HDX-USDT",
+		Body:      body,
+		ClientIP:  clientIP,
+	}
+}
+
+func Test_ApiKey_Create(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, nil)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		req  *dto.CreateApiKeyReq
+		err  error
+	}{
+		{"no scope", &dto.CreateApiKeyReq{Label: "bot"}, serviceImpl.ErrInvalidApiKeyScope},
+		{"unknown scope", &dto.CreateApiKeyReq{Label: "bot", Scopes: []dto.ApiKeyScope{"admin"}}, serviceImpl.ErrInvalidApiKeyScope},
+		{"invalid allowlist", &dto.CreateApiKeyReq{Label: "bot", Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_READ}, IPAllowlist: []string{"10.0.0"}}, serviceImpl.ErrInvalidIPAllowlist},
+		{"expired", &dto.CreateApiKeyReq{Label: "bot", Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_READ}, ExpiresAt: time.Now().Add(-time.Minute).UnixMilli()}, serviceImpl.ErrInvalidApiKeyExpiry},
+	}
+	for _, tt := range tests {
+		if _, err := c.ApiKeyService.CreateApiKey(ctx, user.ID, tt.req); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	apiKey, err := c.ApiKeyService.CreateApiKey(ctx, user.ID, &dto.CreateApiKeyReq{Label: "bot",
+		Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_READ, dto.API_KEY_SCOPE_TRADE}, IPAllowlist: []string{"10.0.0.0/8", "192.168.1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// secret is returned only on creation.
+	assert(t, len(apiKey.Secret), 64)
+	apiKeys, err := c.ApiKeyService.GetApiKeys(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(apiKeys), 1)
+	assert(t, apiKeys[0].Secret, "")
+	assert(t, apiKeys[0].IPAllowlist, []string{"10.0.0.0/8", "192.168.1.1"})
+
+	updated, err := c.ApiKeyService.UpdateLabel(ctx, user.ID, apiKey.ApiKey, &dto.UpdateApiKeyReq{Label: "market maker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, updated.Label, "market maker")
+	assert(t, updated.Secret, "")
+}
+
+func Test_ApiKey_Authenticate(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, nil)
+	ctx := context.Background()
+	apiKey, err := c.ApiKeyService.CreateApiKey(ctx, user.ID, &dto.CreateApiKeyReq{Label: "bot",
+		Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_TRADE}, IPAllowlist: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := apiKey.Secret
+	body := `{"side":0,"price":1,"size":1}`
+
+	auth := signedAuth(apiKey, secret, time.Now(), body, "10.1.2.3")
+	owner, authenticated, err := c.ApiKeyService.Authenticate(ctx, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, owner.ID, user.ID)
+	assert(t, authenticated.Secret, "")
+	assert(t, authenticated.HasScope(dto.API_KEY_SCOPE_TRADE), true)
+	assert(t, authenticated.HasScope(dto.API_KEY_SCOPE_WITHDRAW), false)
+
+	// same signed request is not accepted twice.
+	if _, _, err := c.ApiKeyService.Authenticate(ctx, auth); err == nil {
+		t.Error("Expected error of replayed request")
+	}
+
+	tampered := signedAuth(apiKey, secret, time.Now().Add(time.Millisecond), body, "10.1.2.3")
+	tampered.Body = `{"side":0,"price":1,"size":100}`
+	stale := signedAuth(apiKey, secret, time.Now().Add(-time.Minute), body, "10.1.2.3")
+	tests := []struct {
+		name string
+		auth *dto.ApiKeyAuth
+		err  error
+	}{
+		{"missing signature", &dto.ApiKeyAuth{ApiKey: apiKey.ApiKey, Timestamp: stale.Timestamp}, serviceImpl.ErrApiKeyHeadersRequired},
+		{"unknown key", signedAuth(&dto.ApiKey{ApiKey: "unknown"}, secret, time.Now(), body, "10.1.2.3"), serviceImpl.ErrInvalidApiKey},
+		{"tampered body", tampered, serviceImpl.ErrInvalidApiKeySign},
+		{"wrong secret", signedAuth(apiKey, "secret", time.Now(), body, "10.1.2.3"), serviceImpl.ErrInvalidApiKeySign},
+		{"ip not allowed", signedAuth(apiKey, secret, time.Now().Add(2*time.Millisecond), body, "172.16.0.1"), serviceImpl.ErrApiKeyIPNotAllowed},
+	}
+	for _, tt := range tests {
+		if _, _, err := c.ApiKeyService.Authenticate(ctx, tt.auth); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+	if _, _, err := c.ApiKeyService.Authenticate(ctx, stale); err == nil {
+		t.Error("Expected error of timestamp out of receive window")
+	}
+
+	exec(t, `UPDATE api_keys SET expires_at = ? WHERE api_key = ?`, time.Now().Add(-time.Second), apiKey.ApiKey)
+	if _, _, err := c.ApiKeyService.Authenticate(ctx, signedAuth(apiKey, secret, time.Now().Add(3*time.Millisecond), body, "10.1.2.3")); !errors.Is(err, serviceImpl.ErrApiKeyExpired) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrApiKeyExpired, err)
+	}
+
+	exec(t, `UPDATE api_keys SET expires_at = NULL WHERE api_key = ?`, apiKey.ApiKey)
+	if err := c.ApiKeyService.RevokeApiKey(ctx, user.ID, apiKey.ApiKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ApiKeyService.Authenticate(ctx, signedAuth(apiKey, secret, time.Now().Add(4*time.Millisecond), body, "10.1.2.3")); !errors.Is(err, serviceImpl.ErrApiKeyRevoked) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrApiKeyRevoked, err)
+	}
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"testing"
+)
+
+// keepAuditLogs restore audit logs tampered during test.
+func keepAuditLogs(t *testing.T) {
+	t.Helper()
+	exec(t, `CREATE TABLE audit_logs_backup AS SELECT * FROM audit_logs`)
+	t.Cleanup(func() {
+		exec(t, `DELETE FROM audit_logs`)
+		exec(t, `INSERT INTO audit_logs SELECT * FROM audit_logs_backup`)
+		exec(t, `DROP TABLE audit_logs_backup`)
+	})
+}
+
+func verifyAuditChain(t *testing.T) *dto.AuditVerifyResult {
+	t.Helper()
+	result, err := c.AuditService.VerifyChain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func Test_AuditLog_Chain(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, nil)
+	ctx := context.Background()
+	apiKey, err := c.ApiKeyService.CreateApiKey(ctx, user.ID, &dto.CreateApiKeyReq{Label: "bot", Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_READ}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApiKeyService.RevokeApiKey(ctx, user.ID, apiKey.ApiKey); err != nil {
+		t.Fatal(err)
+	}
+
+	auditLogs, err := c.AuditService.GetAuditLogs(ctx, &dto.AuditLogQueryReq{ActorID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// latest first, revoke is chained to create.
+	assert(t, len(auditLogs), 2)
+	assert(t, auditLogs[0].Action, dto.AUDIT_API_KEY_REVOKE)
+	assert(t, auditLogs[1].Action, dto.AUDIT_API_KEY_CREATE)
+	assert(t, auditLogs[0].PrevHash, auditLogs[1].Hash)
+
+	result := verifyAuditChain(t)
+	assert(t, result.Valid, true)
+	if result.Checked < 2 {
+		t.Errorf("Expected at least 2 rows checked, got %d", result.Checked)
+	}
+}
+
+func Test_AuditLog_TamperDetected(t *testing.T) {
+	keepAuditLogs(t)
+	user := newUser(t, 0.001, 0.002, nil)
+	ctx := context.Background()
+	for _, label := range []string{"bot1", "bot2", "bot3"} {
+		_, err := c.ApiKeyService.CreateApiKey(ctx, user.ID, &dto.CreateApiKeyReq{Label: label, Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_READ}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	auditLogs, err := c.AuditService.GetAuditLogs(ctx, &dto.AuditLogQueryReq{ActorID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(auditLogs), 3)
+	middle := auditLogs[1]
+
+	// modified row.
+	exec(t, `UPDATE audit_logs SET after_value = '{}' WHERE id = ?`, middle.ID)
+	result := verifyAuditChain(t)
+	assert(t, result.Valid, false)
+	assert(t, result.BrokenID, middle.ID)
+	assert(t, result.Reason, "hash does not match row, row modified")
+	exec(t, `UPDATE audit_logs SET after_value = ? WHERE id = ?`, middle.After, middle.ID)
+	assert(t, verifyAuditChain(t).Valid, true)
+
+	// deleted row, next row is broken.
+	exec(t, `DELETE FROM audit_logs WHERE id = ?`, middle.ID)
+	result = verifyAuditChain(t)
+	assert(t, result.Valid, false)
+	assert(t, result.BrokenID, auditLogs[0].ID)
+	assert(t, result.Reason, "prev_hash does not match previous row, rows deleted or inserted")
+}
+
+func Test_AuditLog_ActionFailsWithoutAuditLog(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, nil)
+	ctx := context.Background()
+	exec(t, `ALTER TABLE audit_logs RENAME TO audit_logs_off`)
+	t.Cleanup(func() { exec(t, `ALTER TABLE audit_logs_off RENAME TO audit_logs`) })
+
+	_, err := c.ApiKeyService.CreateApiKey(ctx, user.ID, &dto.CreateApiKeyReq{Label: "bot", Scopes: []dto.ApiKeyScope{dto.API_KEY_SCOPE_READ}})
+	if err == nil {
+		t.Fatal("Expected error of failed audit log")
+	}
+	apiKeys, err := c.ApiKeyService.GetApiKeys(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(apiKeys), 0)
+}
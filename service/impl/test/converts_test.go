@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"testing"
+)
+
+// refreshSnapshot refresh order book snapshot like orderbook snapshot job, convert quotes are priced off snapshots.
+func refreshSnapshot(t *testing.T, market string) {
+	t.Helper()
+	ob, err := c.MatchingEngine.GetOrderBook(market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ob.RefreshSnapshot()
+}
+
+// quoteConvert book a bid of fromAsset and an ask of toAsset, then quote 10 fromAsset to toAsset.
+func quoteConvert(t *testing.T, fromAsset, toAsset string) (user *dto.User, toAssetAsk *dto.PlaceOrderResult, quote *dto.ConvertQuote) {
+	t.Helper()
+	buyer := newUser(t, 0.001, 0.001, map[string]float64{"USDT": 100})
+	seller := newUser(t, 0.001, 0.001, map[string]float64{toAsset: 20})
+	placeOrder(t, fromAsset+"-USDT", buyer, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+	toAssetAsk = placeOrder(t, toAsset+"-USDT", seller, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 5, Size: 20})
+	refreshSnapshot(t, fromAsset+"-USDT")
+	refreshSnapshot(t, toAsset+"-USDT")
+
+	user = newUser(t, 0.001, 0.001, map[string]float64{fromAsset: 10})
+	quote, err := c.ConvertService.Quote(context.Background(), user, &dto.ConvertQuoteReq{FromAsset: fromAsset, ToAsset: toAsset, FromAmount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(quote.Legs), 2)
+	return user, toAssetAsk, quote
+}
+
+func Test_Convert_Completed(t *testing.T) {
+	user, _, quote := quoteConvert(t, "SOL", "ADA")
+
+	convert, err := c.ConvertService.Accept(context.Background(), user, &dto.ConvertAcceptReq{QuoteID: quote.QuoteID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, convert.Status, dto.CONVERT_STATUS_COMPLETED)
+	assert(t, len(convert.OrderIDs), 2)
+	assertFloat(t, convert.FromAmount, 10)
+	if convert.ToAmount < quote.ToAmount {
+		t.Errorf("Expected to amount at least %v, got %v", quote.ToAmount, convert.ToAmount)
+	}
+	assertFloat(t, balance(t, user.ID, "SOL").Available, 0)
+	assertFloat(t, balance(t, user.ID, "ADA").Available, convert.ToAmount)
+}
+
+func Test_Convert_PartialIfSecondLegNotFilled(t *testing.T) {
+	user, toAssetAsk, quote := quoteConvert(t, "DOT", "LINK")
+	// ask is gone after snapshot was taken, re-price still passes and second leg finds no liquidity.
+	if _, err := c.OrderService.CancelOrder(context.Background(), toAssetAsk.Order.UserID, toAssetAsk.Order.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	convert, err := c.ConvertService.Accept(context.Background(), user, &dto.ConvertAcceptReq{QuoteID: quote.QuoteID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, convert.Status, dto.CONVERT_STATUS_PARTIAL)
+	assertFloat(t, convert.FromAmount, 10)
+	assertFloat(t, convert.ToAmount, 0)
+
+	// first leg is not undone, user keeps USDT.
+	assertFloat(t, balance(t, user.ID, "DOT").Available, 0)
+	assertFloat(t, balance(t, user.ID, "USDT").Available, 99.9)
+	assertFloat(t, balance(t, user.ID, "LINK").Available, 0)
+
+	converts, err := c.ConvertService.GetConverts(context.Background(), user.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(converts), 1)
+	assert(t, converts[0].Status, dto.CONVERT_STATUS_PARTIAL)
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"testing"
+	"time"
+)
+
+func Test_FeeTier_GetFeeSchedules(t *testing.T) {
+	schedules, err := c.FeeTierService.GetFeeSchedules(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(schedules), 6)
+
+	tests := []struct {
+		volume   float64
+		vipLevel int
+		next     int
+	}{
+		{0, 1, 2},
+		{49999, 1, 2},
+		{50000, 2, 3},
+		{1000000, 4, 5},
+		{30000000, 6, 0},
+	}
+	for _, tt := range tests {
+		current, next := dto.MatchFeeSchedule(schedules, tt.volume)
+		assert(t, current.VipLevel, tt.vipLevel)
+		if tt.next == 0 {
+			assert(t, next == nil, true)
+		} else {
+			assert(t, next.VipLevel, tt.next)
+		}
+	}
+}
+
+func Test_FeeTier_RecalculateAll(t *testing.T) {
+	now := time.Now()
+	counterparty := newUser(t, 0.001, 0.002, nil)
+	active := newUser(t, 0.001, 0.002, nil)
+	inactive := newUser(t, 0.001, 0.002, nil)
+	marketMaker := newUser(t, 0.001, 0.002, nil)
+	operator := &dto.Admin{ID: "ADM000000000001", Username: "frizo", Role: dto.ADMIN_ROLE_SUPERADMIN}
+	if _, err := c.AdminService.AssignMarketMakerTier(context.Background(), operator, &dto.AssignMarketMakerTierReq{Username: marketMaker.Username, VipLevel: 8}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 60000 within 30 days: level 2.
+	insertTrade(t, active, counterparty, 100, 400, now.Add(-29*24*time.Hour))
+	insertTrade(t, counterparty, active, 100, 200, now.Add(-time.Hour))
+	// only 1000 within 30 days, older volume is out of window.
+	insertTrade(t, inactive, counterparty, 100, 3000, now.Add(-31*24*time.Hour))
+	insertTrade(t, inactive, counterparty, 100, 10, now.Add(-time.Hour))
+	insertTrade(t, marketMaker, counterparty, 100, 3000, now.Add(-time.Hour))
+
+	if err := c.FeeTierService.RecalculateAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := c.UserService.GetUser(context.Background(), active.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, user.VipLevel, 2)
+	assertFloat(t, user.MakerFee, 0.0009)
+	assertFloat(t, user.TakerFee, 0.0018)
+	histories, err := c.FeeTierService.GetVipLevelHistories(context.Background(), active.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(histories), 1)
+	assert(t, histories[0].FromVipLevel, 1)
+	assert(t, histories[0].ToVipLevel, 2)
+	assertFloat(t, histories[0].Volume30D, 60000)
+
+	progress, err := c.FeeTierService.GetTierProgress(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, progress.NextVipLevel, 3)
+	assertFloat(t, progress.Volume30D, 60000)
+	assertFloat(t, progress.VolumeToNextLevel, 190000)
+
+	// unchanged tier is not recorded.
+	user, err = c.UserService.GetUser(context.Background(), inactive.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, user.VipLevel, 1)
+	histories, err = c.FeeTierService.GetVipLevelHistories(context.Background(), inactive.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(histories), 0)
+
+	// market maker tier is kept regardless of volume.
+	user, err = c.UserService.GetUser(context.Background(), marketMaker.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, user.VipLevel, 8)
+	assertFloat(t, user.MakerFee, -0.0001)
+	auditLogs, err := c.AuditService.GetAuditLogs(context.Background(), &dto.AuditLogQueryReq{Action: dto.AUDIT_MARKET_MAKER_TIER, Target: marketMaker.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(auditLogs), 1)
+	assert(t, auditLogs[0].After, `{"maker_fee":-0.0001,"taker_fee":0.0008,"vip_level":8}`)
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"testing"
+	"time"
+)
+
+func getFills(t *testing.T, user *dto.User, query *dto.GetFillsQueryReq) *dto.FillsResp {
+	t.Helper()
+	resp, err := c.OrderService.GetFills(context.Background(), user.ID, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func Test_Fills_FeeAttribution(t *testing.T) {
+	maker := newUser(t, 0.001, 0.002, map[string]float64{"HDX": 10})
+	taker := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+
+	makerOrder := placeOrder(t, "HDX-USDT", maker, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 1})
+	takerOrder := placeOrder(t, "HDX-USDT", taker, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 10})
+
+	fills := getFills(t, maker, &dto.GetFillsQueryReq{Market: "HDX-USDT"}).Fills
+	assert(t, len(fills), 1)
+	assert(t, fills[0].OrderID, makerOrder.Order.ID)
+	assert(t, fills[0].Side, model.ASK)
+	assert(t, fills[0].Role, model.MAKER)
+	assertFloat(t, fills[0].Price, 10)
+	assertFloat(t, fills[0].Size, 1)
+	assertFloat(t, fills[0].Fee, 0.01)
+	assert(t, fills[0].FeeAsset, "USDT")
+
+	fills = getFills(t, taker, &dto.GetFillsQueryReq{}).Fills
+	assert(t, len(fills), 1)
+	assert(t, fills[0].OrderID, takerOrder.Order.ID)
+	assert(t, fills[0].Side, model.BID)
+	assert(t, fills[0].Role, model.TAKER)
+	assertFloat(t, fills[0].Fee, 0.002)
+	assert(t, fills[0].FeeAsset, "HDX")
+
+	// market and time range filters.
+	assert(t, len(getFills(t, taker, &dto.GetFillsQueryReq{Market: "ETH-USDT"}).Fills), 0)
+	assert(t, len(getFills(t, taker, &dto.GetFillsQueryReq{StartTime: time.Now().Add(time.Minute).UnixMilli()}).Fills), 0)
+	assert(t, len(getFills(t, taker, &dto.GetFillsQueryReq{EndTime: time.Now().Add(-time.Minute).UnixMilli()}).Fills), 0)
+}
+
+func Test_Fills_CursorKeepsSelfTradeInOnePage(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, nil)
+	other := newUser(t, 0.001, 0.002, nil)
+	now := time.Now()
+	insertTrade(t, user, other, 10, 1, now.Add(-3*time.Minute))
+	insertTrade(t, user, user, 10, 2, now.Add(-2*time.Minute))
+	insertTrade(t, other, user, 10, 3, now.Add(-time.Minute))
+
+	// latest first, self-trade (both sides) is never split.
+	var sizes [][]float64
+	query := &dto.GetFillsQueryReq{Limit: 2}
+	for {
+		resp := getFills(t, user, query)
+		var page []float64
+		for _, fill := range resp.Fills {
+			page = append(page, fill.Size)
+		}
+		sizes = append(sizes, page)
+		if resp.NextCursor == 0 {
+			break
+		}
+		query.Cursor = resp.NextCursor
+	}
+	assert(t, len(sizes), 3)
+	assert(t, len(sizes[0]), 1)
+	assertFloat(t, sizes[0][0], 3)
+	assert(t, len(sizes[1]), 2)
+	assertFloat(t, sizes[1][0], 2)
+	assertFloat(t, sizes[1][1], 2)
+	assert(t, len(sizes[2]), 1)
+	assertFloat(t, sizes[2][0], 1)
+
+	if _, err := c.OrderService.GetFills(context.Background(), user.ID, &dto.GetFillsQueryReq{StartTime: 2, EndTime: 1}); err == nil {
+		t.Error("Expected error of invalid time range")
+	}
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/container"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/core"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// c services on database with schema and testing data, shared by all tests (metrics can only be registered once).
+var c *container.Container
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.ERROR)
+	dir, err := os.MkdirTemp("", "exg-test")
+	if err != nil {
+		panic(err)
+	}
+	code := func() int {
+		defer os.RemoveAll(dir)
+		db, err := openTestDB(filepath.Join(dir, "exg.db"))
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+
+		engine, err := core.NewMatchingEngine(settings.ALL_MARKETS)
+		if err != nil {
+			panic(err)
+		}
+		c = container.NewContainer(db, engine)
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// openTestDB database like initDB in test mode.
+func openTestDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	for _, file := range []string{"schema.sql", "testing_data.sql"} {
+		content, err := os.ReadFile(filepath.Join("..", "..", "..", "doc", "db_schema", file))
+		if err != nil {
+			return nil, err
+		}
+		for _, statement := range strings.Split(string(content), ";") {
+			if statement = strings.TrimSpace(statement); statement == "" {
+				continue
+			}
+			if _, err := db.Exec(statement); err != nil {
+				return nil, fmt.Errorf("%s: %w\nStatement: %s", file, err, statement)
+			}
+		}
+	}
+	return db, nil
+}
+
+func assert(t *testing.T, a, b any) {
+	t.Helper()
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
+
+func assertFloat(t *testing.T, a, b float64) {
+	t.Helper()
+	if math.Abs(a-b) > 1e-6 {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
+
+func exec(t *testing.T, query string, args ...any) {
+	t.Helper()
+	if _, err := c.DB.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q failed: %v", query, err)
+	}
+}
+
+// setBalance overwrite available and locked of user asset.
+func setBalance(t *testing.T, userId, asset string, available, locked float64) {
+	t.Helper()
+	exec(t, `INSERT INTO balances(user_id, asset, available, locked) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, asset) DO UPDATE SET available = excluded.available, locked = excluded.locked`,
+		userId, asset, available, locked)
+}
+
+func balance(t *testing.T, userId, asset string) *dto.Balance {
+	t.Helper()
+	balances, err := c.BalanceRepo.GetBalancesByUserId(context.Background(), c.DB, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range balances {
+		if b.Asset == asset {
+			return b
+		}
+	}
+	return &dto.Balance{Asset: asset}
+}
+
+var userSeq int
+
+// newUser testing user with fee rates and balances of all assets like registered user.
+func newUser(t *testing.T, makerFee, takerFee float64, balances map[string]float64) *dto.User {
+	t.Helper()
+	userSeq++
+	id := fmt.Sprintf("TUID%08d", userSeq)
+	exec(t, `INSERT INTO users(id, username, password_hash, vip_level, maker_fee, taker_fee) VALUES (?, ?, '!', 1, ?, ?)`,
+		id, id, makerFee, takerFee)
+	for _, asset := range settings.GetAllAssets() {
+		setBalance(t, id, asset, balances[asset], 0)
+	}
+	user, err := c.UserService.GetUser(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// insertTrade trade of bidUser (taker) and askUser (maker) on a market no other test reads prices of.
+func insertTrade(t *testing.T, bidUser, askUser *dto.User, price, size float64, timestamp time.Time) {
+	t.Helper()
+	trade := book.Trade{
+		Market: "TIER-USDT", BidOrderID: "B" + timestamp.String(), AskOrderID: "A" + timestamp.String(),
+		BidUserID: bidUser.ID, AskUserID: askUser.ID, BidFeeRate: bidUser.TakerFee, AskFeeRate: askUser.MakerFee,
+		Price: price, Size: size, Timestamp: timestamp,
+	}
+	if err := c.TradeRepo.BatchInsert(context.Background(), c.DB, []book.Trade{trade}, model.BID, "TIER", "USDT"); err != nil {
+		t.Fatal(err)
+	}
+}
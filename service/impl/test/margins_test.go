@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+	"time"
+)
+
+func marginLoan(t *testing.T, userId, asset string) *dto.MarginLoan {
+	t.Helper()
+	account, err := c.MarginService.GetAccount(context.Background(), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, loan := range account.Loans {
+		if loan.Asset == asset {
+			return loan
+		}
+	}
+	return &dto.MarginLoan{Asset: asset}
+}
+
+// backdateLoan move loan accrued time back, as if interest was not accrued for d.
+func backdateLoan(t *testing.T, userId, asset string, d time.Duration) {
+	t.Helper()
+	exec(t, `UPDATE margin_loans SET accrued_at = ? WHERE user_id = ? AND asset = ?`, time.Now().Add(-d), userId, asset)
+}
+
+func Test_Margin_BorrowAccrueRepay(t *testing.T) {
+	setBalance(t, settings.MARGIN_LENDING_POOL_ACCOUNT_ID, "USDT", 10000, 0)
+	user := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+	ctx := context.Background()
+
+	if _, err := c.MarginService.Borrow(ctx, user.ID, &dto.MarginLoanReq{Asset: "USDT", Amount: 100}); !errors.Is(err, serviceImpl.ErrMarginAccountNotFound) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrMarginAccountNotFound, err)
+	}
+	_, err := c.MarginService.Transfer(ctx, user, &dto.MarginTransferReq{Asset: "USDT", Amount: 100, Direction: dto.MARGIN_TRANSFER_IN, IdempotencyKey: uuid.NewString()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 100 collateral, borrowing 400 keeps margin ratio at initial ratio 0.2 (5x).
+	borrows := []struct {
+		amount float64
+		err    error
+	}{
+		{400.01, serviceImpl.ErrMarginRatioTooLow},
+		{400, nil},
+		{0.01, serviceImpl.ErrMarginRatioTooLow},
+	}
+	for _, tt := range borrows {
+		if _, err := c.MarginService.Borrow(ctx, user.ID, &dto.MarginLoanReq{Asset: "USDT", Amount: tt.amount}); !errors.Is(err, tt.err) {
+			t.Errorf("borrow %v: expected error %v, got %v", tt.amount, tt.err, err)
+		}
+	}
+	assertFloat(t, marginLoan(t, user.ID, "USDT").Principal, 400)
+	assertFloat(t, balance(t, settings.MARGIN_LENDING_POOL_ACCOUNT_ID, "USDT").Available, 9600)
+	// transferring collateral out is checked against initial ratio as well.
+	_, err = c.MarginService.Transfer(ctx, user, &dto.MarginTransferReq{Asset: "USDT", Amount: 1, Direction: dto.MARGIN_TRANSFER_OUT, IdempotencyKey: uuid.NewString()})
+	if !errors.Is(err, serviceImpl.ErrMarginRatioTooLow) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrMarginRatioTooLow, err)
+	}
+
+	// nothing is accrued within first hour, missed hours are caught up once.
+	if err := c.MarginService.AccrueInterest(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, marginLoan(t, user.ID, "USDT").Interest, 0)
+	backdateLoan(t, user.ID, "USDT", 3*time.Hour+time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := c.MarginService.AccrueInterest(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	interest := 3 * 400 * settings.GetMarginHourlyInterestRate("USDT")
+	assertFloat(t, marginLoan(t, user.ID, "USDT").Interest, interest)
+
+	// interest is repaid first, then principal.
+	record, err := c.MarginService.Repay(ctx, user.ID, &dto.MarginLoanReq{Asset: "USDT", Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, record.Interest, interest)
+	assertFloat(t, record.Principal, 100-interest)
+	loan := marginLoan(t, user.ID, "USDT")
+	assertFloat(t, loan.Interest, 0)
+	assertFloat(t, loan.Principal, 300+interest)
+
+	// amount exceeding loan is ignored.
+	record, err = c.MarginService.Repay(ctx, user.ID, &dto.MarginLoanReq{Asset: "USDT", Amount: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, record.Principal, 300+interest)
+	assertFloat(t, marginLoan(t, user.ID, "USDT").Principal, 0)
+	assertFloat(t, balance(t, settings.MARGIN_LENDING_POOL_ACCOUNT_ID, "USDT").Available, 10000+interest)
+}
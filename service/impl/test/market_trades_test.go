@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"github.com/johnny1110/crypto-exchange/ws"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// subscribeTrades connect websocket to container hub and subscribe trades channel of market.
+func subscribeTrades(t *testing.T, market string) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws.HandleWebSocket(c.WSHub, c.WSAuthenticator, w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.WriteJSON(ws.WSReq{Action: ws.SUBSCRIBE, Channel: ws.TRADES, Params: ws.TradesReqParams{Market: market}}); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); !c.WSHub.TradesSubscribed(market); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected trades subscribed")
+		}
+	}
+	return conn
+}
+
+// readTrades read next trades message of websocket.
+func readTrades(t *testing.T, conn *websocket.Conn) []map[string]any {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	var resp struct {
+		Channel ws.WSChannel     `json:"channel"`
+		Data    []map[string]any `json:"data"`
+	}
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, resp.Channel, ws.TRADES)
+	return resp.Data
+}
+
+func Test_Market_RecentTrades(t *testing.T) {
+	ctx := context.Background()
+	maker := newUser(t, 0.001, 0.002, map[string]float64{"BTSE": 10, "USDT": 100})
+	taker := newUser(t, 0.001, 0.002, map[string]float64{"BTSE": 10, "USDT": 100})
+	conn := subscribeTrades(t, "BTSE-USDT")
+
+	// taker buys.
+	placeOrder(t, "BTSE-USDT", maker, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 3, Size: 2})
+	placeOrder(t, "BTSE-USDT", taker, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 6})
+	pushed := readTrades(t, conn)
+	assert(t, len(pushed), 1)
+	assert(t, pushed[0]["taker_side"], float64(model.BID))
+	assert(t, pushed[0]["price"], float64(3))
+	// taker sells.
+	placeOrder(t, "BTSE-USDT", maker, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 2, Size: 1})
+	placeOrder(t, "BTSE-USDT", taker, &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET, Size: 1})
+	pushed = readTrades(t, conn)
+	assert(t, len(pushed), 1)
+	assert(t, pushed[0]["taker_side"], float64(model.ASK))
+
+	trades, err := c.MarketDataService.GetRecentTrades(ctx, "BTSE-USDT", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// newest first, pushed trades carry persisted ids.
+	assert(t, len(trades), 2)
+	assert(t, trades[0].TakerSide, model.ASK)
+	assertFloat(t, trades[0].Price, 2)
+	assertFloat(t, trades[0].QuoteAmount, 2)
+	assert(t, float64(trades[0].ID), pushed[0]["id"])
+	assert(t, trades[1].TakerSide, model.BID)
+	assertFloat(t, trades[1].Size, 2)
+	assertFloat(t, trades[1].QuoteAmount, 6)
+
+	trades, err = c.MarketDataService.GetRecentTrades(ctx, "BTSE-USDT", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(trades), 1)
+	assert(t, trades[0].TakerSide, model.ASK)
+
+	if _, err := c.MarketDataService.GetRecentTrades(ctx, "XYZ-USDT", 10); !errors.Is(err, serviceImpl.ErrMarketNotFound) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrMarketNotFound, err)
+	}
+
+	data, err := json.Marshal(trades[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"timestamp":`+strconv.FormatInt(trades[0].Timestamp.UnixMilli(), 10)) {
+		t.Errorf("Expected timestamp in unix milliseconds, got %s", data)
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+)
+
+// setFeeTokenPrice trade settings.FEE_TOKEN-USDT at price, fee token rate is crossed by its latest price.
+func setFeeTokenPrice(t *testing.T, price float64) {
+	t.Helper()
+	seller := newUser(t, 0, 0, map[string]float64{settings.FEE_TOKEN: 1})
+	buyer := newUser(t, 0, 0, map[string]float64{"USDT": price})
+	market := settings.FEE_TOKEN + "-USDT"
+	placeOrder(t, market, seller, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: price, Size: 1})
+	placeOrder(t, market, buyer, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: price})
+}
+
+func newFeeTokenUser(t *testing.T, balances map[string]float64) *dto.User {
+	t.Helper()
+	user := newUser(t, 0.001, 0.001, balances)
+	exec(t, `UPDATE users SET pay_fee_in_btse = 1 WHERE id = ?`, user.ID)
+	user.PayFeeInBTSE = true
+	return user
+}
+
+// tradeAskFee fee of ask side recorded in trade.
+func tradeAskFee(t *testing.T, askOrderId string) (float64, string) {
+	t.Helper()
+	var fee float64
+	var feeAsset string
+	if err := c.DB.QueryRow(`SELECT ask_fee, ask_fee_asset FROM trades WHERE ask_order_id = ?`, askOrderId).Scan(&fee, &feeAsset); err != nil {
+		t.Fatal(err)
+	}
+	return fee, feeAsset
+}
+
+func Test_Order_FeesPaidInFeeToken(t *testing.T) {
+	setFeeTokenPrice(t, 2)
+	maker := newUser(t, 0.001, 0.001, map[string]float64{"USDT": 100})
+	seller := newFeeTokenUser(t, map[string]float64{"HDX": 10, settings.FEE_TOKEN: 1})
+	marginFeeToken := balance(t, settings.MARGIN_ACCOUNT_ID, settings.FEE_TOKEN).Available
+
+	placeOrder(t, "HDX-USDT", maker, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+	result := placeOrder(t, "HDX-USDT", seller, &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET, Size: 10})
+
+	// 0.1 USDT fee = 0.05 BTSE, 25% discount.
+	assert(t, result.Order.FeeAsset, settings.FEE_TOKEN)
+	assertFloat(t, result.Order.Fees, 0.0375)
+	assertFloat(t, balance(t, seller.ID, "USDT").Available, 100)
+	assertFloat(t, balance(t, seller.ID, settings.FEE_TOKEN).Available, 0.9625)
+	assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, settings.FEE_TOKEN).Available, marginFeeToken+0.0375)
+	fee, feeAsset := tradeAskFee(t, result.Order.ID)
+	assertFloat(t, fee, 0.0375)
+	assert(t, feeAsset, settings.FEE_TOKEN)
+}
+
+func Test_Order_FeeTokenInsufficientFallsBackToNormalAsset(t *testing.T) {
+	setFeeTokenPrice(t, 2)
+	maker := newUser(t, 0.001, 0.001, map[string]float64{"USDT": 100})
+	seller := newFeeTokenUser(t, map[string]float64{"HDX": 10, settings.FEE_TOKEN: 0.01})
+	marginFeeToken := balance(t, settings.MARGIN_ACCOUNT_ID, settings.FEE_TOKEN).Available
+
+	placeOrder(t, "HDX-USDT", maker, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+	result := placeOrder(t, "HDX-USDT", seller, &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET, Size: 10})
+
+	assert(t, result.Order.FeeAsset, "USDT")
+	assertFloat(t, result.Order.Fees, 0.1)
+	assertFloat(t, balance(t, seller.ID, "USDT").Available, 99.9)
+	assertFloat(t, balance(t, seller.ID, settings.FEE_TOKEN).Available, 0.01)
+	assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, settings.FEE_TOKEN).Available, marginFeeToken)
+	fee, feeAsset := tradeAskFee(t, result.Order.ID)
+	assertFloat(t, fee, 0.1)
+	assert(t, feeAsset, "USDT")
+
+	order, err := c.OrderRepo.GetOrderByOrderId(context.Background(), c.DB, result.Order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, order.FeeAsset, "USDT")
+	assertFloat(t, order.Fees, 0.1)
+}
+
+func Test_Order_FeeTokenInsufficientForMakerFills(t *testing.T) {
+	setFeeTokenPrice(t, 2)
+	taker := newUser(t, 0.001, 0.001, map[string]float64{"HDX": 10})
+	maker := newFeeTokenUser(t, map[string]float64{"USDT": 200, settings.FEE_TOKEN: 0.00375})
+
+	placed := placeOrder(t, "HDX-USDT", maker, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+	placeOrder(t, "HDX-USDT", taker, &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET, Size: 5})
+	placeOrder(t, "HDX-USDT", taker, &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET, Size: 5})
+
+	// each fill fee 0.005 HDX = 0.01875 BTSE is more than fee token balance, both fills pay in HDX.
+	order, err := c.OrderRepo.GetOrderByOrderId(context.Background(), c.DB, placed.Order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, order.FeeAsset, "HDX")
+	assertFloat(t, order.Fees, 0.01)
+	assertFloat(t, balance(t, maker.ID, "HDX").Available, 9.99)
+	assertFloat(t, balance(t, maker.ID, settings.FEE_TOKEN).Available, 0.00375)
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+)
+
+func placeOrder(t *testing.T, market string, user *dto.User, req *dto.OrderReq) *dto.PlaceOrderResult {
+	t.Helper()
+	result, err := c.OrderService.PlaceOrder(context.Background(), market, user, req)
+	if err != nil {
+		t.Fatalf("place order failed: %v", err)
+	}
+	return result
+}
+
+func Test_Order_MakerRebatePaidByMarginAccount(t *testing.T) {
+	setBalance(t, settings.MARGIN_ACCOUNT_ID, "USDT", 1, 0)
+	maker := newUser(t, -0.0002, 0.001, map[string]float64{"HDX": 10})
+	taker := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+
+	placeOrder(t, "HDX-USDT", maker, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+	placeOrder(t, "HDX-USDT", taker, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 100})
+
+	assertFloat(t, balance(t, maker.ID, "USDT").Available, 100.02)
+	assertFloat(t, balance(t, maker.ID, "HDX").Locked, 0)
+	assertFloat(t, balance(t, taker.ID, "HDX").Available, 9.98)
+	assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, "USDT").Available, 0.98)
+}
+
+func Test_Order_MakerRebateDisabledIfMarginAccountCanNotAfford(t *testing.T) {
+	setBalance(t, settings.MARGIN_ACCOUNT_ID, "USDT", 0.01, 0)
+	maker := newUser(t, -0.0002, 0.001, map[string]float64{"HDX": 10})
+	taker := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+
+	placeOrder(t, "HDX-USDT", maker, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+	placeOrder(t, "HDX-USDT", taker, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 100})
+
+	assertFloat(t, balance(t, maker.ID, "USDT").Available, 100)
+	assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, "USDT").Available, 0.01)
+}
+
+func Test_Order_MarketOrderOfRebateUserPaysTakerFee(t *testing.T) {
+	setBalance(t, settings.MARGIN_ACCOUNT_ID, "USDT", 1, 0)
+	maker := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+	rebateUser := newUser(t, -0.0002, 0.001, map[string]float64{"HDX": 10})
+
+	placeOrder(t, "HDX-USDT", maker, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+	// mode is omitted like clients placing market orders.
+	result := placeOrder(t, "HDX-USDT", rebateUser, &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET, Size: 10})
+
+	assertFloat(t, result.Order.FeeRate, 0.001)
+	assertFloat(t, balance(t, rebateUser.ID, "USDT").Available, 99.9)
+	assertFloat(t, balance(t, maker.ID, "HDX").Available, 9.99)
+	assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, "USDT").Available, 1.1)
+}
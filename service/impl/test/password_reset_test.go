@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/notifier"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"github.com/johnny1110/crypto-exchange/service/impl/twofa"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var resetTokenPattern = regexp.MustCompile(`reset token is ([0-9a-f]+),`)
+
+// inboxNotifier keep notifications instead of sending them.
+type inboxNotifier struct {
+	notifications []*notifier.Notification
+}
+
+func (n *inboxNotifier) Notify(_ context.Context, notification *notifier.Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+// newResetUserService user service sending reset tokens to inbox.
+func newResetUserService(inbox *inboxNotifier) service.IUserService {
+	return serviceImpl.NewIUserService(c.DB, c.UserRepo, c.BalanceRepo, c.ReferralRepo, c.SessionStore, c.FeeTierService,
+		c.TwoFactorService, c.AuditLogRepo, c.PasswordResetRepo, inbox)
+}
+
+// requestResetToken request password reset of user, return token sent to inbox.
+func requestResetToken(t *testing.T, userService service.IUserService, inbox *inboxNotifier, user *dto.User) string {
+	t.Helper()
+	if err := userService.RequestPasswordReset(context.Background(), &dto.PasswordResetRequestReq{Username: user.Username}); err != nil {
+		t.Fatal(err)
+	}
+	notification := inbox.notifications[len(inbox.notifications)-1]
+	assert(t, notification.UserID, user.ID)
+	match := resetTokenPattern.FindStringSubmatch(notification.Body)
+	if match == nil {
+		t.Fatalf("reset token not found in %q", notification.Body)
+	}
+	return match[1]
+}
+
+func Test_PasswordReset(t *testing.T) {
+	inbox := &inboxNotifier{}
+	userService := newResetUserService(inbox)
+	user := newUser(t, 0.001, 0.002, nil)
+	ctx := context.Background()
+	session, err := c.SessionStore.Create(ctx, user, "10.0.0.1", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := requestResetToken(t, userService, inbox, user)
+	// unknown username and repeated request within interval send nothing.
+	if err := userService.RequestPasswordReset(ctx, &dto.PasswordResetRequestReq{Username: "nobody"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.RequestPasswordReset(ctx, &dto.PasswordResetRequestReq{Username: user.Username}); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(inbox.notifications), 1)
+
+	if err := userService.ResetPassword(ctx, &dto.PasswordResetReq{Token: token, NewPassword: "password2"}); err != nil {
+		t.Fatal(err)
+	}
+	// all sessions are logged out.
+	if _, err := c.SessionStore.Get(ctx, session.Token); !errors.Is(err, security.ErrInvalidSession) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidSession, err)
+	}
+	if _, err := userService.Login(ctx, &dto.LoginReq{Username: user.Username, Password: "password2"}, "10.0.0.1", "phone"); err != nil {
+		t.Errorf("Expected login by new password, got %v", err)
+	}
+
+	// token is single use.
+	if err := userService.ResetPassword(ctx, &dto.PasswordResetReq{Token: token, NewPassword: "password3"}); !errors.Is(err, serviceImpl.ErrInvalidResetToken) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidResetToken, err)
+	}
+
+	auditLogs, err := c.AuditService.GetAuditLogs(ctx, &dto.AuditLogQueryReq{ActorID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := make(map[dto.AuditAction]int)
+	for _, auditLog := range auditLogs {
+		actions[auditLog.Action]++
+	}
+	assert(t, actions[dto.AUDIT_PASSWORD_RESET_REQUEST], 1)
+	assert(t, actions[dto.AUDIT_PASSWORD_RESET], 1)
+}
+
+func Test_PasswordReset_Expired(t *testing.T) {
+	inbox := &inboxNotifier{}
+	userService := newResetUserService(inbox)
+	user := newUser(t, 0.001, 0.002, nil)
+
+	token := requestResetToken(t, userService, inbox, user)
+	exec(t, `UPDATE password_reset_tokens SET expires_at = ? WHERE user_id = ?`, time.Now().Add(-time.Second), user.ID)
+	if err := userService.ResetPassword(context.Background(), &dto.PasswordResetReq{Token: token, NewPassword: "password2"}); !errors.Is(err, serviceImpl.ErrInvalidResetToken) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidResetToken, err)
+	}
+	if err := userService.ResetPassword(context.Background(), &dto.PasswordResetReq{Token: "unknown", NewPassword: "password2"}); !errors.Is(err, serviceImpl.ErrInvalidResetToken) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidResetToken, err)
+	}
+}
+
+func Test_PasswordReset_RequiresTotp(t *testing.T) {
+	inbox := &inboxNotifier{}
+	userService := newResetUserService(inbox)
+	user := newUser(t, 0.001, 0.002, nil)
+	secret := enableTwoFactor(t, user)
+	ctx := context.Background()
+
+	token := requestResetToken(t, userService, inbox, user)
+	if err := userService.ResetPassword(ctx, &dto.PasswordResetReq{Token: token, NewPassword: "password2"}); !errors.Is(err, twofa.ErrTwoFactorRequired) {
+		t.Errorf("Expected %v, got %v", twofa.ErrTwoFactorRequired, err)
+	}
+	if err := userService.ResetPassword(ctx, &dto.PasswordResetReq{Token: token, NewPassword: "password2", TotpCode: "000000"}); !errors.Is(err, twofa.ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected %v, got %v", twofa.ErrInvalidTwoFactorCode, err)
+	}
+	// token is not consumed by failed attempts.
+	if err := userService.ResetPassword(ctx, &dto.PasswordResetReq{Token: token, NewPassword: "password2", TotpCode: totpCode(t, user, secret)}); err != nil {
+		t.Fatal(err)
+	}
+}
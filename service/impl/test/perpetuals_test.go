@@ -0,0 +1,126 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+)
+
+// setInsuranceFund overwrite insurance fund USDT, restored after test.
+func setInsuranceFund(t *testing.T, available float64) {
+	t.Helper()
+	origin := balance(t, settings.INSURANCE_FUND_ACCOUNT_ID, "USDT")
+	setBalance(t, settings.INSURANCE_FUND_ACCOUNT_ID, "USDT", available, 0)
+	t.Cleanup(func() {
+		setBalance(t, settings.INSURANCE_FUND_ACCOUNT_ID, "USDT", origin.Available, origin.Locked)
+	})
+}
+
+func Test_Perpetual_HaltWhenInsuranceFundShort(t *testing.T) {
+	market := "ETH-USDT-PERP"
+	long := newUser(t, 0, 0, map[string]float64{"USDT": 100})
+	short := newUser(t, 0, 0, map[string]float64{"USDT": 100})
+	bidder := newUser(t, 0, 0, map[string]float64{"USDT": 100})
+
+	placeOrder(t, market, short, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 100, Size: 1})
+	placeOrder(t, market, long, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.TAKER, Price: 100, Size: 1})
+	placeOrder(t, market, bidder, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 200, Size: 1})
+
+	// closing long at 200 realizes 100 pnl, insurance fund has only 50.
+	setInsuranceFund(t, 50)
+	_, err := c.OrderService.PlaceOrder(context.Background(), market, long, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.TAKER, Price: 200, Size: 1})
+	if err == nil {
+		t.Fatal("Expected settlement error")
+	}
+
+	// nothing is settled: position is still open and pnl is not paid.
+	positions, err := c.PerpetualService.GetPositions(context.Background(), long.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(positions), 1)
+	assertFloat(t, positions[0].Size, 1)
+	assertFloat(t, balance(t, settings.INSURANCE_FUND_ACCOUNT_ID, "USDT").Available, 50)
+	assertFloat(t, balance(t, long.ID, "USDT").Available+balance(t, long.ID, "USDT").Locked, 100)
+
+	// orders are refused until admin resumes market.
+	_, err = c.OrderService.PlaceOrder(context.Background(), market, bidder, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 90, Size: 1})
+	if !errors.Is(err, serviceImpl.ErrPerpetualMarketHalt) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrPerpetualMarketHalt, err)
+	}
+
+	admin := &dto.Admin{ID: "1", Username: "tester"}
+	if err := c.PerpetualService.ResumeMarket(context.Background(), admin, market); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PerpetualService.ResumeMarket(context.Background(), admin, market); err == nil {
+		t.Error("Expected error resuming market not halted")
+	}
+	placeOrder(t, market, bidder, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 90, Size: 1})
+}
+
+func Test_Perpetual_LiquidateUnderMaintenanceMargin(t *testing.T) {
+	market := "BTC-USDT-PERP"
+	long := newUser(t, 0, 0, map[string]float64{"USDT": 100})
+	short := newUser(t, 0, 0, map[string]float64{"USDT": 100})
+	maker := newUser(t, 0, 0, map[string]float64{"USDT": 100})
+	setInsuranceFund(t, 1000)
+
+	placeOrder(t, market, short, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 100, Size: 1})
+	placeOrder(t, market, long, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.TAKER, Price: 100, Size: 1})
+
+	// mark price median(94, 96, 100) = 96, long margin ratio (10 - 4) / 96 is above maintenance.
+	bid := placeOrder(t, market, maker, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 94, Size: 0.1})
+	ask := placeOrder(t, market, maker, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 96, Size: 0.1})
+	refreshSnapshot(t, market)
+	if err := c.PerpetualService.LiquidatePositions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	positions, err := c.PerpetualService.GetPositions(context.Background(), long.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, positions[0].Size, 1)
+
+	// mark price median(84, 85, 100) = 85, loss 15 exceeds margin 10.
+	for _, order := range []*dto.PlaceOrderResult{bid, ask} {
+		if _, err := c.OrderService.CancelOrder(context.Background(), maker.ID, order.Order.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	placeOrder(t, market, maker, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 84, Size: 0.1})
+	placeOrder(t, market, maker, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 85, Size: 0.1})
+	refreshSnapshot(t, market)
+	if err := c.PerpetualService.LiquidatePositions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	liquidations, err := c.PerpetualService.GetLiquidations(context.Background(), long.ID, &dto.FundingQueryReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(liquidations), 1)
+	assertFloat(t, liquidations[0].Size, 1)
+	assertFloat(t, liquidations[0].MarkPrice, 85)
+	assertFloat(t, liquidations[0].Pnl, -10)
+	assertFloat(t, liquidations[0].BadDebt, 5)
+
+	positions, err = c.PerpetualService.GetPositions(context.Background(), long.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(positions), 0)
+	assertFloat(t, balance(t, long.ID, "USDT").Available, 90)
+	assertFloat(t, balance(t, long.ID, "USDT").Locked, 0)
+	// insurance fund takes the margin, short is not liquidated.
+	assertFloat(t, balance(t, settings.INSURANCE_FUND_ACCOUNT_ID, "USDT").Available, 1010)
+	positions, err = c.PerpetualService.GetPositions(context.Background(), short.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, positions[0].Size, -1)
+}
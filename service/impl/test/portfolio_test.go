@@ -0,0 +1,167 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"testing"
+	"time"
+)
+
+// stubIndexPrices index prices by market, other markets have no index price.
+type stubIndexPrices map[string]float64
+
+func (s stubIndexPrices) GetIndexPrice(_ context.Context, market string) (float64, error) {
+	if price, ok := s[market]; ok {
+		return price, nil
+	}
+	return 0, errors.New("no index price")
+}
+
+// newPortfolioService portfolio service with stub index prices instead of external ones.
+func newPortfolioService(indexPrices stubIndexPrices) service.IPortfolioService {
+	return serviceImpl.NewIPortfolioService(c.DB, c.UserRepo, c.BalanceRepo, c.PnlRepo, c.EquitySnapshotRepo, c.OrderBookService, indexPrices)
+}
+
+// tradeAt make latest price of market by a trade of two new users.
+func tradeAt(t *testing.T, market, baseAsset string, price float64) {
+	t.Helper()
+	seller := newUser(t, 0, 0, map[string]float64{baseAsset: 1})
+	buyer := newUser(t, 0, 0, map[string]float64{"USDT": price})
+	placeOrder(t, market, seller, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: price, Size: 1})
+	placeOrder(t, market, buyer, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: price})
+}
+
+func Test_Portfolio_Pnl(t *testing.T) {
+	seller := newUser(t, 0, 0, map[string]float64{"LINK": 10})
+	trader := newUser(t, 0, 0, map[string]float64{"USDT": 1000})
+	buyer := newUser(t, 0, 0, map[string]float64{"USDT": 1000})
+
+	// trader buys 5 at 10 and 5 at 14, avg cost 12.
+	placeOrder(t, "LINK-USDT", seller, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 5})
+	placeOrder(t, "LINK-USDT", trader, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 50})
+	placeOrder(t, "LINK-USDT", seller, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 14, Size: 5})
+	placeOrder(t, "LINK-USDT", trader, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 70})
+	// then sells 4 at 15.
+	placeOrder(t, "LINK-USDT", trader, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 15, Size: 4})
+	placeOrder(t, "LINK-USDT", buyer, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 60})
+
+	pnl, err := c.PortfolioService.GetPnl(context.Background(), trader.ID, &dto.PnlQueryReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(pnl.Realized), 1)
+	assert(t, pnl.Realized[0].Market, "LINK-USDT")
+	assertFloat(t, pnl.Realized[0].Size, 4)
+	assertFloat(t, pnl.Realized[0].Proceeds, 60)
+	assertFloat(t, pnl.Realized[0].Cost, 48)
+	assertFloat(t, pnl.TotalRealizedPnl, 12)
+
+	// 6 left valued at latest price 15.
+	assert(t, len(pnl.Unrealized), 1)
+	assert(t, pnl.Unrealized[0].Asset, "LINK")
+	assertFloat(t, pnl.Unrealized[0].Quantity, 6)
+	assertFloat(t, pnl.Unrealized[0].AvgCost, 12)
+	assertFloat(t, pnl.Unrealized[0].LatestPrice, 15)
+	assertFloat(t, pnl.Unrealized[0].MarketValue, 90)
+	assertFloat(t, pnl.Unrealized[0].CostValue, 72)
+	assertFloat(t, pnl.TotalUnrealizedPnl, 18)
+
+	// realized pnl out of period is excluded.
+	pnl, err = c.PortfolioService.GetPnl(context.Background(), trader.ID, &dto.PnlQueryReq{To: time.Now().Add(-time.Minute).UnixMilli()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(pnl.Realized), 0)
+	assertFloat(t, pnl.TotalRealizedPnl, 0)
+
+	if _, err := c.PortfolioService.GetPnl(context.Background(), trader.ID, &dto.PnlQueryReq{From: 2, To: 1}); err == nil {
+		t.Error("Expected error of invalid period")
+	}
+}
+
+func Test_Portfolio_Valuation(t *testing.T) {
+	tradeAt(t, "BNB-USDT", "BNB", 5)
+	user := newUser(t, 0, 0, nil)
+	setBalance(t, user.ID, "USDT", 50, 50)
+	setBalance(t, user.ID, "BNB", 8, 2)
+	setBalance(t, user.ID, "DOGE", 100, 0)
+	setBalance(t, user.ID, "AVAX", 1, 0)
+	// BNB by latest price, DOGE falls back to index price, AVAX has no price.
+	portfolioService := newPortfolioService(stubIndexPrices{"DOGE-USDT": 0.5, "BNB-USDT": 100})
+
+	portfolio, err := portfolioService.GetPortfolio(context.Background(), user.ID, "USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, portfolio.TotalEquity, 200)
+	assertFloat(t, portfolio.AvailableEquity, 140)
+	assertFloat(t, portfolio.LockedEquity, 60)
+
+	assets := make(map[string]*dto.PortfolioAsset)
+	for _, asset := range portfolio.Assets {
+		assets[asset.Asset] = asset
+	}
+	// sorted by value desc.
+	assert(t, portfolio.Assets[0].Asset, "USDT")
+	assertFloat(t, assets["USDT"].Allocation, 50)
+	assert(t, assets["BNB"].PriceSource, dto.PRICE_SOURCE_LATEST)
+	assertFloat(t, assets["BNB"].Price, 5)
+	assertFloat(t, assets["BNB"].AvailableValue, 40)
+	assertFloat(t, assets["BNB"].LockedValue, 10)
+	assertFloat(t, assets["BNB"].Allocation, 25)
+	assert(t, assets["DOGE"].PriceSource, dto.PRICE_SOURCE_INDEX)
+	assertFloat(t, assets["DOGE"].Value, 50)
+	assert(t, assets["AVAX"].PriceSource, dto.PRICE_SOURCE_NONE)
+	assertFloat(t, assets["AVAX"].Value, 0)
+
+	// valued in BNB.
+	portfolio, err = portfolioService.GetPortfolio(context.Background(), user.ID, "BNB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, portfolio.TotalEquity, 40)
+
+	if _, err := portfolioService.GetPortfolio(context.Background(), user.ID, "XYZ"); !errors.Is(err, serviceImpl.ErrInvalidInput) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidInput, err)
+	}
+}
+
+func Test_Portfolio_SnapshotAllEquities(t *testing.T) {
+	user := newUser(t, 0, 0, map[string]float64{"USDT": 120})
+	portfolioService := newPortfolioService(stubIndexPrices{})
+	ctx := context.Background()
+
+	if err := portfolioService.SnapshotAllEquities(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// snapshot again on same day overwrites.
+	setBalance(t, user.ID, "USDT", 150, 0)
+	if err := portfolioService.SnapshotAllEquities(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := portfolioService.GetEquityHistory(ctx, user.ID, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(history), 1)
+	assert(t, history[0].SnapshotDate, time.Now().UTC().Format("2006-01-02"))
+	assertFloat(t, history[0].Equity, 150)
+	assert(t, history[0].QuoteAsset, "USDT")
+
+	// snapshots older than requested days are excluded.
+	exec(t, `UPDATE equity_snapshots SET snapshot_date = ? WHERE user_id = ?`, time.Now().UTC().AddDate(0, 0, -10).Format("2006-01-02"), user.ID)
+	history, err = portfolioService.GetEquityHistory(ctx, user.ID, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(history), 0)
+
+	if _, err := portfolioService.GetEquityHistory(ctx, user.ID, 0); !errors.Is(err, serviceImpl.ErrInvalidInput) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrInvalidInput, err)
+	}
+}
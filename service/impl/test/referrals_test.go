@@ -0,0 +1,65 @@
+package test
+
+import (
+	"context"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+	"time"
+)
+
+func refer(t *testing.T, referrer, referee *dto.User) {
+	t.Helper()
+	exec(t, `INSERT INTO referrals(referee_id, referrer_id, created_at) VALUES (?, ?, ?)`, referee.ID, referrer.ID, time.Now())
+}
+
+func Test_Order_ReferralCommission(t *testing.T) {
+	tests := []struct {
+		name        string
+		referMaker  bool
+		referTaker  bool
+		commissions int
+		usdt        float64 // maker pays 0.1 USDT
+		astr        float64 // taker pays 0.02 ASTR
+	}{
+		{"nobody referred", false, false, 0, 0, 0},
+		{"maker referred", true, false, 1, 0.02, 0},
+		{"taker referred", false, true, 1, 0, 0.004},
+		{"both referred", true, true, 2, 0.02, 0.004},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			referrer := newUser(t, 0.001, 0.002, nil)
+			maker := newUser(t, 0.001, 0.002, map[string]float64{"ASTR": 10})
+			taker := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+			if tt.referMaker {
+				refer(t, referrer, maker)
+			}
+			if tt.referTaker {
+				refer(t, referrer, taker)
+			}
+			marginUSDT := balance(t, settings.MARGIN_ACCOUNT_ID, "USDT").Available
+			marginASTR := balance(t, settings.MARGIN_ACCOUNT_ID, "ASTR").Available
+
+			placeOrder(t, "ASTR-USDT", maker, &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.MAKER, Price: 10, Size: 10})
+			placeOrder(t, "ASTR-USDT", taker, &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, QuoteAmount: 100})
+
+			assertFloat(t, balance(t, referrer.ID, "USDT").Available, tt.usdt)
+			assertFloat(t, balance(t, referrer.ID, "ASTR").Available, tt.astr)
+			// margin account keeps fees less commissions.
+			assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, "USDT").Available, marginUSDT+0.1-tt.usdt)
+			assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, "ASTR").Available, marginASTR+0.02-tt.astr)
+
+			commissions, err := c.ReferralService.GetCommissions(context.Background(), referrer.ID, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert(t, len(commissions), tt.commissions)
+			for _, commission := range commissions {
+				assertFloat(t, commission.Commission, commission.Fees*settings.REFERRAL_COMMISSION_RATE)
+			}
+		})
+	}
+}
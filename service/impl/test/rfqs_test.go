@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+	"time"
+)
+
+// quoteRfq request taker buying 2 BTC, quoted at 100 by new provider.
+func quoteRfq(t *testing.T, taker *dto.User) (provider *dto.User, request *dto.RfqRequest, quote *dto.RfqQuote) {
+	t.Helper()
+	provider = newUser(t, 0.001, 0.002, map[string]float64{"BTC": 2})
+	exec(t, `INSERT INTO rfq_providers(user_id, created_at) VALUES (?, ?)`, provider.ID, time.Now())
+
+	request, err := c.RfqService.CreateRequest(context.Background(), taker.ID, &dto.RfqRequestReq{Market: "BTC-USDT", Side: model.BID, Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quote, err = c.RfqService.SubmitQuote(context.Background(), provider.ID, request.ID, &dto.RfqQuoteReq{Price: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, balance(t, provider.ID, "BTC").Locked, 2)
+	return provider, request, quote
+}
+
+func Test_Rfq_AcceptSettlesBlockTrade(t *testing.T) {
+	taker := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 300})
+	provider, request, quote := quoteRfq(t, taker)
+	marginBTC := balance(t, settings.MARGIN_ACCOUNT_ID, "BTC").Available
+
+	accepted, err := c.RfqService.Accept(context.Background(), taker, request.ID, &dto.RfqAcceptReq{QuoteID: quote.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, accepted.Status, dto.RFQ_REQUEST_STATUS_FILLED)
+	assert(t, accepted.AcceptedQuoteID, quote.ID)
+
+	// taker fee 0.004 BTC (rate 0.002) is deducted from received BTC, provider pays no fee.
+	assertFloat(t, balance(t, taker.ID, "USDT").Available, 100)
+	assertFloat(t, balance(t, taker.ID, "BTC").Available, 1.996)
+	assertFloat(t, balance(t, provider.ID, "BTC").Locked, 0)
+	assertFloat(t, balance(t, provider.ID, "USDT").Available, 200)
+	assertFloat(t, balance(t, settings.MARGIN_ACCOUNT_ID, "BTC").Available, marginBTC+0.004)
+}
+
+func Test_Rfq_AcceptInsufficientBalanceRollsBack(t *testing.T) {
+	taker := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 199})
+	provider, request, quote := quoteRfq(t, taker)
+
+	_, err := c.RfqService.Accept(context.Background(), taker, request.ID, &dto.RfqAcceptReq{QuoteID: quote.ID})
+	if !errors.Is(err, serviceImpl.ErrRfqInsufficientBalance) {
+		t.Fatalf("Expected %v, got %v", serviceImpl.ErrRfqInsufficientBalance, err)
+	}
+
+	// request stays open, provider funds stay locked by its quote.
+	request, err = c.RfqService.GetRequest(context.Background(), taker.ID, request.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, request.Status, dto.RFQ_REQUEST_STATUS_OPEN)
+	assertFloat(t, balance(t, taker.ID, "USDT").Available, 199)
+	assertFloat(t, balance(t, taker.ID, "BTC").Available, 0)
+	assertFloat(t, balance(t, provider.ID, "BTC").Locked, 2)
+	assertFloat(t, balance(t, provider.ID, "USDT").Available, 0)
+}
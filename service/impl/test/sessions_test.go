@@ -0,0 +1,157 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_SessionStore(t *testing.T) {
+	stores := map[string]security.SessionStore{
+		"memory": security.NewMemorySessionStore(),
+		"sqlite": security.NewSQLiteSessionStore(c.DB, c.SessionRepo, c.UserRepo),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testSessionStore(t, store)
+		})
+	}
+}
+
+func testSessionStore(t *testing.T, store security.SessionStore) {
+	ctx := context.Background()
+	user := newUser(t, 0.001, 0.002, nil)
+	phone, err := store.Create(ctx, user, "10.0.0.1", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop, err := store.Create(ctx, user, "10.0.0.2", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loggedIn, err := store.Get(ctx, phone.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, loggedIn.ID, user.ID)
+
+	sessions, err := store.GetSessionsByUserId(ctx, user.ID, laptop.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(sessions), 2)
+	for _, session := range sessions {
+		assert(t, session.Token, "")
+		assert(t, session.RefreshToken, "")
+		assert(t, session.Current, session.UserAgent == "laptop")
+	}
+
+	// refresh rotates both tokens.
+	refreshed, err := store.Refresh(ctx, laptop.RefreshToken, "10.0.0.3", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, refreshed.ID, laptop.ID)
+	if _, err := store.Get(ctx, laptop.Token); !errors.Is(err, security.ErrInvalidSession) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidSession, err)
+	}
+	if _, err := store.Refresh(ctx, laptop.RefreshToken, "10.0.0.3", "laptop"); !errors.Is(err, security.ErrInvalidRefreshToken) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidRefreshToken, err)
+	}
+	if _, err := store.Get(ctx, refreshed.Token); err != nil {
+		t.Error(err)
+	}
+
+	// log out other devices keeps current session.
+	if err := store.DeleteOthers(ctx, user.ID, refreshed.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, phone.Token); !errors.Is(err, security.ErrInvalidSession) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidSession, err)
+	}
+	if _, err := store.Get(ctx, refreshed.Token); err != nil {
+		t.Error(err)
+	}
+
+	// concurrent logins.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Create(ctx, user, "10.0.0.4", "bot"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	sessions, err = store.GetSessionsByUserId(ctx, user.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(sessions), 21)
+
+	// log out all devices.
+	if err := store.DeleteByUserId(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, refreshed.Token); !errors.Is(err, security.ErrInvalidSession) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidSession, err)
+	}
+	sessions, err = store.GetSessionsByUserId(ctx, user.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(sessions), 0)
+}
+
+func Test_SessionStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	store := security.NewSQLiteSessionStore(c.DB, c.SessionRepo, c.UserRepo)
+	user := newUser(t, 0.001, 0.002, nil)
+	session, err := store.Create(ctx, user, "10.0.0.1", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// idle access token expires, refresh token renews it.
+	exec(t, `UPDATE sessions SET last_active_at = ? WHERE id = ?`, time.Now().Add(-settings.SESSION_IDLE_TIMEOUT), session.ID)
+	if _, err := store.Get(ctx, session.Token); !errors.Is(err, security.ErrInvalidSession) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidSession, err)
+	}
+	session, err = store.Refresh(ctx, session.RefreshToken, "10.0.0.1", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, session.Token); err != nil {
+		t.Error(err)
+	}
+
+	// access token absolute expiry.
+	exec(t, `UPDATE sessions SET expires_at = ? WHERE id = ?`, time.Now().Add(-time.Second), session.ID)
+	if _, err := store.Get(ctx, session.Token); !errors.Is(err, security.ErrInvalidSession) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidSession, err)
+	}
+
+	// session absolute expiry, can not be refreshed and is cleaned up.
+	exec(t, `UPDATE sessions SET refresh_expires_at = ? WHERE id = ?`, time.Now().Add(-time.Second), session.ID)
+	if _, err := store.Refresh(ctx, session.RefreshToken, "10.0.0.1", "phone"); !errors.Is(err, security.ErrInvalidRefreshToken) {
+		t.Errorf("Expected %v, got %v", security.ErrInvalidRefreshToken, err)
+	}
+	deleted, err := store.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted < 1 {
+		t.Errorf("Expected expired session deleted, got %d", deleted)
+	}
+	sessions, err := store.GetSessionsByUserId(ctx, user.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(sessions), 0)
+}
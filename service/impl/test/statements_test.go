@@ -0,0 +1,125 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportStatement(t *testing.T, user *dto.User, req *dto.StatementReq) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := c.StatementService.Export(context.Background(), user.ID, req, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func Test_Statement_Validate(t *testing.T) {
+	from := time.Now().Add(-time.Hour).UnixMilli()
+	tests := []struct {
+		name string
+		req  *dto.StatementReq
+		err  error
+	}{
+		{"valid", &dto.StatementReq{Type: dto.STATEMENT_TYPE_FILLS, Format: dto.STATEMENT_FORMAT_CSV, From: from}, nil},
+		{"unknown type", &dto.StatementReq{Type: "trades", Format: dto.STATEMENT_FORMAT_CSV, From: from}, serviceImpl.ErrInvalidInput},
+		{"unknown format", &dto.StatementReq{Type: dto.STATEMENT_TYPE_FILLS, Format: "xlsx", From: from}, serviceImpl.ErrInvalidInput},
+		{"unknown time zone", &dto.StatementReq{Type: dto.STATEMENT_TYPE_FILLS, Format: dto.STATEMENT_FORMAT_CSV, From: from, TimeZone: "Mars/Base"}, serviceImpl.ErrInvalidInput},
+		{"from after to", &dto.StatementReq{Type: dto.STATEMENT_TYPE_FILLS, Format: dto.STATEMENT_FORMAT_CSV, From: from, To: from - 1}, serviceImpl.ErrInvalidStatementRange},
+		{"from in future", &dto.StatementReq{Type: dto.STATEMENT_TYPE_FILLS, Format: dto.STATEMENT_FORMAT_CSV, From: time.Now().Add(time.Hour).UnixMilli()}, serviceImpl.ErrInvalidStatementRange},
+		{"range too long", &dto.StatementReq{Type: dto.STATEMENT_TYPE_FILLS, Format: dto.STATEMENT_FORMAT_CSV, From: time.Now().Add(-367 * 24 * time.Hour).UnixMilli()}, serviceImpl.ErrInvalidStatementRange},
+	}
+	for _, tt := range tests {
+		if err := c.StatementService.Validate(tt.req); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func Test_Statement_FillsPeriodBoundary(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, nil)
+	other := newUser(t, 0.001, 0.002, nil)
+	from := time.UnixMilli(time.Now().Add(-3 * time.Hour).UnixMilli())
+	to := from.Add(2 * time.Hour)
+	insertTrade(t, user, other, 10, 1, from.Add(-time.Millisecond))
+	insertTrade(t, user, other, 10, 2, from)
+	insertTrade(t, other, user, 10, 3, to.Add(-time.Millisecond))
+	insertTrade(t, user, other, 10, 4, to)
+
+	// [from, to), newest first.
+	content := exportStatement(t, user, &dto.StatementReq{Type: dto.STATEMENT_TYPE_FILLS, Format: dto.STATEMENT_FORMAT_CSV,
+		From: from.UnixMilli(), To: to.UnixMilli(), TimeZone: "Asia/Taipei"})
+	records, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(records), 3)
+	assert(t, records[0], []string{"trade_id", "order_id", "market", "side", "role", "price", "size", "quote_amount",
+		"fee", "fee_asset", "timestamp"})
+	assert(t, records[1][6], "3")
+	assert(t, records[1][3], "ASK")
+	assert(t, records[2][6], "2")
+	assert(t, records[2][3], "BID")
+	// timestamps in requested time zone.
+	assert(t, records[2][10], from.In(time.FixedZone("", 8*3600)).Format("2006-01-02T15:04:05.000")+"+08:00")
+}
+
+func Test_Statement_LedgerJsonLines(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, nil)
+	other := newUser(t, 0.001, 0.002, nil)
+	from := time.UnixMilli(time.Now().Add(-time.Hour).UnixMilli())
+	insertTrade(t, user, other, 10, 2, from)
+
+	content := exportStatement(t, user, &dto.StatementReq{Type: dto.STATEMENT_TYPE_LEDGER, Format: dto.STATEMENT_FORMAT_JSONL, From: from.UnixMilli()})
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	// bid fill: + base, - quote, - fee in base asset.
+	assert(t, len(lines), 3)
+	amounts := make(map[string]float64)
+	for _, line := range lines {
+		// keys keep column order.
+		if !strings.HasPrefix(line, `{"timestamp":`) {
+			t.Errorf("Expected timestamp first, got %s", line)
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		assert(t, entry["timestamp"], from.UTC().Format("2006-01-02T15:04:05.000Z"))
+		amounts[entry["type"].(string)+" "+entry["asset"].(string)] = entry["amount"].(float64)
+	}
+	if !reflect.DeepEqual(amounts, map[string]float64{"TRADE TIER": 2, "TRADE USDT": -20, "FEE TIER": -0.004}) {
+		t.Errorf("Unexpected ledger amounts %v", amounts)
+	}
+}
+
+func Test_Statement_Orders(t *testing.T) {
+	from := time.Now().Add(-time.Minute).UnixMilli()
+	user := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 100})
+	order := placeOrder(t, "HDX-USDT", user, &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER, Price: 1, Size: 10})
+
+	content := exportStatement(t, user, &dto.StatementReq{Type: dto.STATEMENT_TYPE_ORDERS, Format: dto.STATEMENT_FORMAT_CSV, From: from})
+	records, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(records), 2)
+	assert(t, len(records[0]), 16)
+	assert(t, records[1][0], order.Order.ID)
+	assert(t, records[1][1], "HDX-USDT")
+	assert(t, records[1][5], string(order.Order.Status))
+
+	// orders created after to are excluded.
+	content = exportStatement(t, user, &dto.StatementReq{Type: dto.STATEMENT_TYPE_ORDERS, Format: dto.STATEMENT_FORMAT_CSV,
+		From: from - time.Hour.Milliseconds(), To: from})
+	assert(t, strings.Count(content, "\n"), 1)
+}
@@ -0,0 +1,239 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/security"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"github.com/johnny1110/crypto-exchange/service/impl/twofa"
+	"testing"
+	"time"
+)
+
+func transfer(from *dto.User, to *dto.User, amount float64, totpCode string) (*dto.Transfer, error) {
+	return c.TransferService.Transfer(context.Background(), from.ID, &dto.TransferReq{
+		ToUserID:       to.ID,
+		Asset:          "USDT",
+		Amount:         amount,
+		IdempotencyKey: uuid.NewString(),
+		TotpCode:       totpCode,
+	})
+}
+
+// enableTwoFactor enable 2FA of user, return its secret.
+func enableTwoFactor(t *testing.T, user *dto.User) string {
+	t.Helper()
+	secret, err := security.GenerateTotpSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(t, `UPDATE users SET totp_secret = ?, totp_enabled = 1 WHERE id = ?`, secret, user.ID)
+	return secret
+}
+
+// totpCode current TOTP code of secret, last accepted step of user is reset so code can be used again in same step.
+func totpCode(t *testing.T, user *dto.User, secret string) string {
+	t.Helper()
+	exec(t, `UPDATE users SET totp_last_step = 0 WHERE id = ?`, user.ID)
+	code, err := security.TotpCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// createSubAccount create sub-account of master, return its user.
+func createSubAccount(t *testing.T, master *dto.User, totpCode string) *dto.User {
+	t.Helper()
+	sub, err := c.TransferService.CreateSubAccount(context.Background(), master, &dto.CreateSubAccountReq{
+		Username: master.ID + "_sub", Password: "password1", TotpCode: totpCode,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subUser, err := c.UserRepo.GetUserById(context.Background(), c.DB, sub.SubUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subUser
+}
+
+func Test_Transfer_CountsTowardWithdrawalDailyLimit(t *testing.T) {
+	sender := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 20000}) // vip 1, limit 10000
+	receiver := newUser(t, 0.001, 0.002, nil)
+	whitelistAddress(t, sender.ID, "USDT")
+
+	if _, err := transfer(sender, receiver, 9000, ""); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if _, err := transfer(sender, receiver, 2000, ""); !errors.Is(err, serviceImpl.ErrWithdrawalDailyLimitExceeded) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrWithdrawalDailyLimitExceeded, err)
+	}
+	_, err := c.WithdrawalService.Withdraw(context.Background(), sender, &dto.WithdrawReq{Asset: "USDT", Amount: 2000, Address: testAddress})
+	if !errors.Is(err, serviceImpl.ErrWithdrawalDailyLimitExceeded) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrWithdrawalDailyLimitExceeded, err)
+	}
+
+	assertFloat(t, balance(t, sender.ID, "USDT").Available, 11000)
+	assertFloat(t, balance(t, receiver.ID, "USDT").Available, 9000)
+}
+
+func Test_Transfer_RequiresTwoFactor(t *testing.T) {
+	sender := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 1000})
+	receiver := newUser(t, 0.001, 0.002, nil)
+	secret := enableTwoFactor(t, sender)
+
+	if _, err := transfer(sender, receiver, 100, ""); !errors.Is(err, twofa.ErrTwoFactorRequired) {
+		t.Errorf("Expected %v, got %v", twofa.ErrTwoFactorRequired, err)
+	}
+	assertFloat(t, balance(t, receiver.ID, "USDT").Available, 0)
+
+	code, err := security.TotpCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := transfer(sender, receiver, 100, code)
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	assertFloat(t, result.Valuation, 100)
+	assertFloat(t, balance(t, receiver.ID, "USDT").Available, 100)
+}
+
+func Test_Transfer_SubAccountExempt(t *testing.T) {
+	master := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 50000}) // vip 1, limit 10000
+	secret := enableTwoFactor(t, master)
+	sub := createSubAccount(t, master, totpCode(t, master, secret))
+
+	// internal transfer to own sub-account needs neither 2FA nor daily limit.
+	result, err := transfer(master, sub, 20000, "")
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	assertFloat(t, result.Valuation, 0)
+
+	_, err = c.TransferService.SubAccountTransfer(context.Background(), master.ID, &dto.SubAccountTransferReq{
+		FromUserID: sub.ID, ToUserID: master.ID, Asset: "USDT", Amount: 15000, IdempotencyKey: uuid.NewString(),
+	})
+	if err != nil {
+		t.Fatalf("sub account transfer failed: %v", err)
+	}
+	assertFloat(t, balance(t, master.ID, "USDT").Available, 45000)
+	assertFloat(t, balance(t, sub.ID, "USDT").Available, 5000)
+}
+
+func Test_SubAccount_CreateRequiresTwoFactor(t *testing.T) {
+	master := newUser(t, 0.001, 0.002, nil)
+	secret := enableTwoFactor(t, master)
+
+	_, err := c.TransferService.CreateSubAccount(context.Background(), master, &dto.CreateSubAccountReq{Username: master.ID + "_sub", Password: "password1"})
+	if !errors.Is(err, twofa.ErrTwoFactorRequired) {
+		t.Errorf("Expected %v, got %v", twofa.ErrTwoFactorRequired, err)
+	}
+	subAccounts, err := c.TransferService.GetSubAccounts(context.Background(), master.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(subAccounts), 0)
+
+	createSubAccount(t, master, totpCode(t, master, secret))
+}
+
+func Test_SubAccount_MovesOutUnderMasterGuard(t *testing.T) {
+	master := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 20000}) // vip 1, limit 10000
+	secret := enableTwoFactor(t, master)
+	sub := createSubAccount(t, master, totpCode(t, master, secret))
+	setBalance(t, sub.ID, "USDT", 20000, 0)
+	whitelistAddress(t, sub.ID, "USDT")
+	receiver := newUser(t, 0.001, 0.002, nil)
+
+	// sub-account has no 2FA of its own, master's is required.
+	if _, err := transfer(sub, receiver, 100, ""); !errors.Is(err, twofa.ErrTwoFactorRequired) {
+		t.Errorf("Expected %v, got %v", twofa.ErrTwoFactorRequired, err)
+	}
+	_, err := c.WithdrawalService.Withdraw(context.Background(), sub, &dto.WithdrawReq{Asset: "USDT", Amount: 100, Address: testAddress})
+	if !errors.Is(err, twofa.ErrTwoFactorRequired) {
+		t.Errorf("Expected %v, got %v", twofa.ErrTwoFactorRequired, err)
+	}
+
+	// daily limit is shared by master and sub-account.
+	if _, err := transfer(master, receiver, 6000, totpCode(t, master, secret)); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if _, err := transfer(sub, receiver, 3000, totpCode(t, master, secret)); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if _, err := transfer(sub, receiver, 2000, totpCode(t, master, secret)); !errors.Is(err, serviceImpl.ErrWithdrawalDailyLimitExceeded) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrWithdrawalDailyLimitExceeded, err)
+	}
+	_, err = c.WithdrawalService.Withdraw(context.Background(), sub, &dto.WithdrawReq{Asset: "USDT", Amount: 2000, Address: testAddress, TotpCode: totpCode(t, master, secret)})
+	if !errors.Is(err, serviceImpl.ErrWithdrawalDailyLimitExceeded) {
+		t.Errorf("Expected %v, got %v", serviceImpl.ErrWithdrawalDailyLimitExceeded, err)
+	}
+
+	assertFloat(t, balance(t, sub.ID, "USDT").Available, 17000)
+	assertFloat(t, balance(t, receiver.ID, "USDT").Available, 9000)
+}
+
+func Test_SubAccount_FollowsMasterFreezeAndClose(t *testing.T) {
+	admin := &dto.Admin{ID: "1", Username: "tester"}
+	master := newUser(t, 0.001, 0.002, nil)
+	sub := createSubAccount(t, master, "")
+
+	for _, status := range []dto.AccountStatus{dto.ACCOUNT_STATUS_FROZEN, dto.ACCOUNT_STATUS_ACTIVE, dto.ACCOUNT_STATUS_CLOSED} {
+		_, err := c.AdminService.UpdateAccountStatus(context.Background(), admin, master.ID, &dto.UpdateAccountStatusReq{Status: status, Reason: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		subUser, err := c.UserRepo.GetUserById(context.Background(), c.DB, sub.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// unfreezing master does not unfreeze sub-account.
+		expected := status
+		if status == dto.ACCOUNT_STATUS_ACTIVE {
+			expected = dto.ACCOUNT_STATUS_FROZEN
+		}
+		assert(t, subUser.Status, expected)
+	}
+}
+
+func Test_Transfer_IdempotencyKeyReplay(t *testing.T) {
+	sender := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 1000})
+	receiver := newUser(t, 0.001, 0.002, nil)
+	other := newUser(t, 0.001, 0.002, nil)
+	key := uuid.NewString()
+
+	first, err := c.TransferService.Transfer(context.Background(), sender.ID, &dto.TransferReq{ToUserID: receiver.ID, Asset: "USDT", Amount: 100, IdempotencyKey: key})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *dto.TransferReq
+		err  error
+	}{
+		{"replay returns first transfer", &dto.TransferReq{ToUserID: receiver.ID, Asset: "USDT", Amount: 100, IdempotencyKey: key}, nil},
+		{"other amount conflicts", &dto.TransferReq{ToUserID: receiver.ID, Asset: "USDT", Amount: 200, IdempotencyKey: key}, serviceImpl.ErrIdempotencyKeyConflict},
+		{"other target conflicts", &dto.TransferReq{ToUserID: other.ID, Asset: "USDT", Amount: 100, IdempotencyKey: key}, serviceImpl.ErrIdempotencyKeyConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := c.TransferService.Transfer(context.Background(), sender.ID, tt.req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected %v, got %v", tt.err, err)
+			}
+			if err == nil {
+				assert(t, result.ID, first.ID)
+			}
+		})
+	}
+
+	// funds moved once.
+	assertFloat(t, balance(t, sender.ID, "USDT").Available, 900)
+	assertFloat(t, balance(t, receiver.ID, "USDT").Available, 100)
+	assertFloat(t, balance(t, other.ID, "USDT").Available, 0)
+}
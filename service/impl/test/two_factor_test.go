@@ -0,0 +1,52 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service/impl/twofa"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+)
+
+func Test_TwoFactor_LockedAfterInvalidCodes(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 1000})
+	whitelistAddress(t, user.ID, "USDT")
+	secret := enableTwoFactor(t, user)
+	ctx := context.Background()
+
+	// missing code does not count, successful verification resets failures.
+	if err := c.TwoFactorService.Verify(ctx, user.ID, ""); !errors.Is(err, twofa.ErrTwoFactorRequired) {
+		t.Fatalf("Expected %v, got %v", twofa.ErrTwoFactorRequired, err)
+	}
+	for i := 0; i < settings.TOTP_MAX_FAILURES-1; i++ {
+		if err := c.TwoFactorService.Verify(ctx, user.ID, "000000"); !errors.Is(err, twofa.ErrInvalidTwoFactorCode) {
+			t.Fatalf("Expected %v, got %v", twofa.ErrInvalidTwoFactorCode, err)
+		}
+	}
+	if err := c.TwoFactorService.Verify(ctx, user.ID, totpCode(t, user, secret)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < settings.TOTP_MAX_FAILURES; i++ {
+		if err := c.TwoFactorService.Verify(ctx, user.ID, "000000"); !errors.Is(err, twofa.ErrInvalidTwoFactorCode) {
+			t.Fatalf("Expected %v, got %v", twofa.ErrInvalidTwoFactorCode, err)
+		}
+	}
+	// valid code is refused while locked, withdrawals included.
+	if err := c.TwoFactorService.Verify(ctx, user.ID, totpCode(t, user, secret)); !errors.Is(err, twofa.ErrTwoFactorLocked) {
+		t.Errorf("Expected %v, got %v", twofa.ErrTwoFactorLocked, err)
+	}
+	_, err := c.WithdrawalService.Withdraw(ctx, user, &dto.WithdrawReq{Asset: "USDT", Amount: 10, Address: testAddress, TotpCode: totpCode(t, user, secret)})
+	if !errors.Is(err, twofa.ErrTwoFactorLocked) {
+		t.Errorf("Expected %v, got %v", twofa.ErrTwoFactorLocked, err)
+	}
+	assertFloat(t, balance(t, user.ID, "USDT").Available, 1000)
+
+	// other users are not locked.
+	other := newUser(t, 0.001, 0.002, nil)
+	otherSecret := enableTwoFactor(t, other)
+	if err := c.TwoFactorService.Verify(ctx, other.ID, totpCode(t, other, otherSecret)); err != nil {
+		t.Error(err)
+	}
+}
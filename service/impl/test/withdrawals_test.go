@@ -0,0 +1,43 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
+	"testing"
+	"time"
+)
+
+const testAddress = "0x000000000000000000000000000000000000dEaD"
+
+// whitelistAddress address of user already passed cooling-off.
+func whitelistAddress(t *testing.T, userId, asset string) {
+	t.Helper()
+	now := time.Now()
+	exec(t, `INSERT INTO withdrawal_addresses(user_id, asset, address, created_at, available_at) VALUES (?, ?, ?, ?, ?)`,
+		userId, asset, testAddress, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+}
+
+func Test_Withdraw_DailyLimit(t *testing.T) {
+	user := newUser(t, 0.001, 0.002, map[string]float64{"USDT": 20000}) // vip 1, limit 10000
+	whitelistAddress(t, user.ID, "USDT")
+
+	tests := []struct {
+		amount float64
+		err    error
+	}{
+		{9000, nil},
+		{2000, serviceImpl.ErrWithdrawalDailyLimitExceeded},
+		{1000, nil},
+		{0.01, serviceImpl.ErrWithdrawalDailyLimitExceeded},
+	}
+	for _, tt := range tests {
+		_, err := c.WithdrawalService.Withdraw(context.Background(), user, &dto.WithdrawReq{Asset: "USDT", Amount: tt.amount, Address: testAddress})
+		if !errors.Is(err, tt.err) {
+			t.Errorf("withdraw %v: expected error %v, got %v", tt.amount, tt.err, err)
+		}
+	}
+	assertFloat(t, balance(t, user.ID, "USDT").Locked, 10000)
+	assertFloat(t, balance(t, user.ID, "USDT").Available, 10000)
+}
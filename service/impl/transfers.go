@@ -0,0 +1,356 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	"golang.org/x/crypto/bcrypt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrTransferToSelf          = errors.New("can not transfer to self")
+	ErrTransferTargetNotFound  = errors.New("transfer target user not found")
+	ErrIdempotencyKeyConflict  = errors.New("idempotency key already used by another transfer")
+	ErrSubAccountNotBelongs    = errors.New("account not belongs to master user")
+	ErrSubAccountCanNotBeOwner = errors.New("sub account can not own sub accounts")
+)
+
+type transferService struct {
+	db                *sql.DB
+	userRepo          repository.IUserRepository
+	balanceRepo       repository.IBalanceRepository
+	transferRepo      repository.ITransferRepository
+	subAccountRepo    repository.ISubAccountRepository
+	withdrawalRepo    repository.IWithdrawalRepository
+	marketDataService service.IMarketDataService
+	twoFactorService  service.ITwoFactorService
+}
+
+func NewITransferService(db *sql.DB,
+	userRepo repository.IUserRepository,
+	balanceRepo repository.IBalanceRepository,
+	transferRepo repository.ITransferRepository,
+	subAccountRepo repository.ISubAccountRepository,
+	withdrawalRepo repository.IWithdrawalRepository,
+	marketDataService service.IMarketDataService,
+	twoFactorService service.ITwoFactorService) service.ITransferService {
+	return &transferService{
+		db:                db,
+		userRepo:          userRepo,
+		balanceRepo:       balanceRepo,
+		transferRepo:      transferRepo,
+		subAccountRepo:    subAccountRepo,
+		withdrawalRepo:    withdrawalRepo,
+		marketDataService: marketDataService,
+		twoFactorService:  twoFactorService,
+	}
+}
+
+func (s *transferService) Transfer(ctx context.Context, fromUserId string, req *dto.TransferReq) (*dto.Transfer, error) {
+	if req == nil || (req.ToUserID == "" && req.ToUsername == "") {
+		return nil, ErrInvalidInput
+	}
+
+	var toUser *dto.User
+	var err error
+	if req.ToUserID != "" {
+		toUser, err = s.userRepo.GetUserById(ctx, s.db, req.ToUserID)
+	} else {
+		toUser, err = s.userRepo.GetUserByUsername(ctx, s.db, req.ToUsername)
+	}
+	if err != nil {
+		return nil, ErrTransferTargetNotFound
+	}
+
+	return s.doTransfer(ctx, &dto.Transfer{
+		ID:             uuid.NewString(),
+		IdempotencyKey: req.IdempotencyKey,
+		FromUserID:     fromUserId,
+		ToUserID:       toUser.ID,
+		Asset:          req.Asset,
+		Amount:         req.Amount,
+		Type:           dto.TRANSFER_TYPE_INTERNAL,
+		Remark:         req.Remark,
+		CreatedAt:      time.Now(),
+	}, req.TotpCode)
+}
+
+func (s *transferService) GetTransfers(ctx context.Context, userId string) ([]*dto.Transfer, error) {
+	return s.transferRepo.GetTransfersByUserId(ctx, s.db, userId)
+}
+
+func (s *transferService) CreateSubAccount(ctx context.Context, master *dto.User, req *dto.CreateSubAccountReq) (*dto.SubAccount, error) {
+	if master == nil || req == nil {
+		return nil, ErrInvalidInput
+	}
+	if _, err := s.subAccountRepo.GetSubAccountBySubUserId(ctx, s.db, master.ID); err == nil {
+		return nil, ErrSubAccountCanNotBeOwner
+	}
+	if err := s.twoFactorService.Verify(ctx, master.ID, req.TotpCode); err != nil {
+		return nil, err
+	}
+
+	userID, err := genUIDSecure()
+	if err != nil {
+		log.Errorf("[CreateSubAccount] failed to generate user id: %v", err)
+		return nil, fmt.Errorf("failed to create sub account")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	subAccount := &dto.SubAccount{
+		SubUserID:    userID,
+		MasterUserID: master.ID,
+		Username:     req.Username,
+		Label:        req.Label,
+		CreatedAt:    time.Now(),
+	}
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := s.userRepo.GetUserByUsername(ctx, tx, req.Username); err == nil {
+			return errors.New("username already exists")
+		}
+
+		// sub account shares master's fee level.
+		err := s.userRepo.Insert(ctx, tx, &dto.User{
+			ID:           userID,
+			Username:     req.Username,
+			PasswordHash: string(hash),
+			VipLevel:     master.VipLevel,
+			MakerFee:     master.MakerFee,
+			TakerFee:     master.TakerFee,
+		})
+		if err != nil {
+			return err
+		}
+		if err = s.balanceRepo.BatchCreate(ctx, tx, userID, settings.GetAllAssets()); err != nil {
+			return err
+		}
+		return s.subAccountRepo.Insert(ctx, tx, subAccount)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subAccount, nil
+}
+
+func (s *transferService) GetSubAccounts(ctx context.Context, masterUserId string) ([]*dto.SubAccount, error) {
+	return s.subAccountRepo.GetSubAccountsByMasterUserId(ctx, s.db, masterUserId)
+}
+
+func (s *transferService) SubAccountTransfer(ctx context.Context, masterUserId string, req *dto.SubAccountTransferReq) (*dto.Transfer, error) {
+	if req == nil {
+		return nil, ErrInvalidInput
+	}
+	if err := s.checkOwnership(ctx, masterUserId, req.FromUserID); err != nil {
+		return nil, err
+	}
+	if err := s.checkOwnership(ctx, masterUserId, req.ToUserID); err != nil {
+		return nil, err
+	}
+
+	// idempotency key is scoped by master user, so sender in transfer record is always unique per key.
+	return s.doTransfer(ctx, &dto.Transfer{
+		ID:             uuid.NewString(),
+		IdempotencyKey: fmt.Sprintf("%s:%s", masterUserId, req.IdempotencyKey),
+		FromUserID:     req.FromUserID,
+		ToUserID:       req.ToUserID,
+		Asset:          req.Asset,
+		Amount:         req.Amount,
+		Type:           dto.TRANSFER_TYPE_SUB_ACCOUNT,
+		CreatedAt:      time.Now(),
+	}, "")
+}
+
+func (s *transferService) GetConsolidatedBalances(ctx context.Context, masterUserId string) (*dto.ConsolidatedBalances, error) {
+	master, err := s.userRepo.GetUserById(ctx, s.db, masterUserId)
+	if err != nil {
+		return nil, err
+	}
+	subAccounts, err := s.subAccountRepo.GetSubAccountsByMasterUserId(ctx, s.db, masterUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*dto.AccountBalances, 0, len(subAccounts)+1)
+	accounts = append(accounts, &dto.AccountBalances{UserID: master.ID, Username: master.Username, Label: "master"})
+	for _, sub := range subAccounts {
+		accounts = append(accounts, &dto.AccountBalances{UserID: sub.SubUserID, Username: sub.Username, Label: sub.Label})
+	}
+
+	totalMap := make(map[string]*dto.Balance)
+	for _, account := range accounts {
+		balances, err := s.balanceRepo.GetBalancesByUserId(ctx, s.db, account.UserID)
+		if err != nil {
+			return nil, err
+		}
+		account.Balances = balances
+
+		for _, b := range balances {
+			total, ok := totalMap[b.Asset]
+			if !ok {
+				total = &dto.Balance{Asset: b.Asset}
+				totalMap[b.Asset] = total
+			}
+			total.Available += b.Available
+			total.Locked += b.Locked
+			total.Total += b.Total
+		}
+	}
+
+	totals := make([]*dto.Balance, 0, len(totalMap))
+	for _, b := range totalMap {
+		totals = append(totals, b)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Asset < totals[j].Asset })
+
+	return &dto.ConsolidatedBalances{
+		Total:    totals,
+		Accounts: accounts,
+	}, nil
+}
+
+// checkOwnership userId must be master itself or one of master's sub-accounts.
+func (s *transferService) checkOwnership(ctx context.Context, masterUserId, userId string) error {
+	if userId == masterUserId {
+		return nil
+	}
+	sub, err := s.subAccountRepo.GetSubAccountBySubUserId(ctx, s.db, userId)
+	if err != nil || sub.MasterUserID != masterUserId {
+		return ErrSubAccountNotBelongs
+	}
+	return nil
+}
+
+// doTransfer move available balance and record transfer in one tx, return existing transfer if idempotency key replayed.
+// transfer to other user is guarded like withdrawal, totpCode is required if sender enabled 2FA.
+func (s *transferService) doTransfer(ctx context.Context, transfer *dto.Transfer, totpCode string) (*dto.Transfer, error) {
+	if transfer.FromUserID == transfer.ToUserID {
+		return nil, ErrTransferToSelf
+	}
+	if !isSupportedAsset(transfer.Asset) {
+		return nil, ErrUnsupportedAsset
+	}
+
+	if existing, err := s.transferRepo.GetTransferByIdempotencyKey(ctx, s.db, transfer.FromUserID, transfer.IdempotencyKey); err == nil {
+		return checkReplayedTransfer(existing, transfer)
+	}
+	fromUser, err := s.checkTransferAccounts(ctx, transfer)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.guardExternalTransfer(ctx, fromUser, transfer, totpCode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		// daily limit is checked in same tx so concurrent transfers and withdrawals can not exceed it.
+		if transfer.Valuation > 0 {
+			if err := checkWithdrawalDailyLimit(ctx, tx, s.withdrawalRepo, s.transferRepo, s.subAccountRepo, master, transfer.Valuation); err != nil {
+				return err
+			}
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, transfer.FromUserID, transfer.Asset, false, transfer.Amount); err != nil {
+			log.Warnf("[TransferService] failed to decrease sender balance, %v", err)
+			return ErrInsufficientBalance
+		}
+		if err := s.balanceRepo.ModifyAvailableByUserIdAndAsset(ctx, tx, transfer.ToUserID, transfer.Asset, true, transfer.Amount); err != nil {
+			return err
+		}
+		return s.transferRepo.Insert(ctx, tx, transfer)
+	})
+
+	if err != nil {
+		// concurrent request with same idempotency key may win the race.
+		if existing, qErr := s.transferRepo.GetTransferByIdempotencyKey(ctx, s.db, transfer.FromUserID, transfer.IdempotencyKey); qErr == nil {
+			return checkReplayedTransfer(existing, transfer)
+		}
+		return nil, err
+	}
+
+	log.Infof("[TransferService] transfer %s: %s -> %s, %v %s", transfer.ID, transfer.FromUserID, transfer.ToUserID, transfer.Amount, transfer.Asset)
+	return transfer, nil
+}
+
+// checkTransferAccounts sender must be allowed to withdraw, closed account can not receive, return sender.
+func (s *transferService) checkTransferAccounts(ctx context.Context, transfer *dto.Transfer) (*dto.User, error) {
+	fromUser, err := s.userRepo.GetUserById(ctx, s.db, transfer.FromUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCanWithdraw(fromUser); err != nil {
+		return nil, err
+	}
+	toUser, err := s.userRepo.GetUserById(ctx, s.db, transfer.ToUserID)
+	if err != nil {
+		return nil, ErrTransferTargetNotFound
+	}
+	if toUser.Status == dto.ACCOUNT_STATUS_CLOSED {
+		return nil, ErrAccountClosed
+	}
+	return fromUser, nil
+}
+
+// guardExternalTransfer transfer to other user moves funds out like withdrawal: set its valuation counted by
+// withdrawal daily limit, early check the limit and verify 2FA, both of master if sender is a sub-account.
+// moves between master and sub-accounts are exempt. return master whose daily limit applies, nil if exempt.
+func (s *transferService) guardExternalTransfer(ctx context.Context, fromUser *dto.User, transfer *dto.Transfer, totpCode string) (*dto.User, error) {
+	if transfer.Type == dto.TRANSFER_TYPE_SUB_ACCOUNT {
+		return nil, nil
+	}
+	master, err := getMasterUser(ctx, s.db, s.userRepo, s.subAccountRepo, fromUser)
+	if err != nil {
+		return nil, err
+	}
+	if master.ID == s.masterUserId(ctx, transfer.ToUserID) {
+		return nil, nil
+	}
+	if err := checkCanWithdraw(master); err != nil {
+		return nil, err
+	}
+
+	valuation, err := evaluateUSDT(s.marketDataService, transfer.Asset, transfer.Amount)
+	if err != nil {
+		log.Warnf("[TransferService] transfer evaluate failed, error: %v", err)
+		return nil, ErrWithdrawalValuationNotAllowed
+	}
+	// early check before consuming 2FA code, checked again in transfer tx.
+	if err := checkWithdrawalDailyLimit(ctx, s.db, s.withdrawalRepo, s.transferRepo, s.subAccountRepo, master, valuation); err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorService.Verify(ctx, master.ID, totpCode); err != nil {
+		return nil, err
+	}
+	transfer.Valuation = valuation
+	return master, nil
+}
+
+// masterUserId master of sub-account, user itself if it is not a sub-account.
+func (s *transferService) masterUserId(ctx context.Context, userId string) string {
+	if sub, err := s.subAccountRepo.GetSubAccountBySubUserId(ctx, s.db, userId); err == nil {
+		return sub.MasterUserID
+	}
+	return userId
+}
+
+func checkReplayedTransfer(existing, transfer *dto.Transfer) (*dto.Transfer, error) {
+	if !serviceHelper.IsTransferReplay(existing, transfer) {
+		return nil, ErrIdempotencyKeyConflict
+	}
+	return existing, nil
+}
@@ -0,0 +1,154 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/service/impl/twofa"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+type twoFactorService struct {
+	db            *sql.DB
+	userRepo      repository.IUserRepository
+	sessionStore  security.SessionStore
+	authenticator *twofa.Authenticator
+	failureLock   *security.LoginLock
+	auditLogRepo  repository.IAuditLogRepository
+}
+
+func NewITwoFactorService(db *sql.DB, userRepo repository.IUserRepository, sessionStore security.SessionStore, authenticator *twofa.Authenticator,
+	failureLock *security.LoginLock, auditLogRepo repository.IAuditLogRepository) service.ITwoFactorService {
+	return &twoFactorService{
+		db:            db,
+		userRepo:      userRepo,
+		sessionStore:  sessionStore,
+		authenticator: authenticator,
+		failureLock:   failureLock,
+		auditLogRepo:  auditLogRepo,
+	}
+}
+
+func (s *twoFactorService) Enroll(ctx context.Context, userId string) (*dto.TwoFactorEnrollment, error) {
+	enrollment := &dto.TwoFactorEnrollment{}
+	err := s.update(ctx, userId, "", func(user *dto.User) error {
+		var err error
+		enrollment.Secret, enrollment.URI, err = s.authenticator.Enroll(user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+func (s *twoFactorService) Enable(ctx context.Context, userId string, req *dto.TwoFactorCodeReq) (*dto.TwoFactorBackupCodes, error) {
+	result := &dto.TwoFactorBackupCodes{}
+	err := s.verify(ctx, userId, dto.AUDIT_TWO_FACTOR_ENABLE, func(user *dto.User) error {
+		var err error
+		result.BackupCodes, err = s.authenticator.Enable(user, req.Code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("[TwoFactorService] 2FA enabled, userId: %s", userId)
+	return result, nil
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userId string, req *dto.TwoFactorCodeReq) error {
+	err := s.verify(ctx, userId, dto.AUDIT_TWO_FACTOR_DISABLE, func(user *dto.User) error {
+		return s.authenticator.Disable(user, req.Code)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("[TwoFactorService] 2FA disabled, userId: %s", userId)
+	return nil
+}
+
+func (s *twoFactorService) RegenerateBackupCodes(ctx context.Context, userId string, req *dto.TwoFactorCodeReq) (*dto.TwoFactorBackupCodes, error) {
+	result := &dto.TwoFactorBackupCodes{}
+	err := s.verify(ctx, userId, dto.AUDIT_BACKUP_CODES_RESET, func(user *dto.User) error {
+		var err error
+		result.BackupCodes, err = s.authenticator.RegenerateBackupCodes(user, req.Code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("[TwoFactorService] backup codes regenerated, userId: %s", userId)
+	return result, nil
+}
+
+func (s *twoFactorService) Verify(ctx context.Context, userId, code string) error {
+	user, err := s.userRepo.GetUserById(ctx, s.db, userId)
+	if err != nil {
+		return err
+	}
+	if !user.TotpEnabled {
+		return nil
+	}
+
+	err = s.verify(ctx, userId, "", func(user *dto.User) error {
+		return s.authenticator.Verify(user, code)
+	})
+	if err != nil && err != twofa.ErrTwoFactorRequired {
+		log.Warnf("[TwoFactorService] 2FA verify failed, userId: %s, error: %v", userId, err)
+	}
+	return err
+}
+
+// verify update user by fn verifying a 2FA code, user is locked after settings.TOTP_MAX_FAILURES invalid codes in a row
+// and every verification fails until lock expires.
+func (s *twoFactorService) verify(ctx context.Context, userId string, auditAction dto.AuditAction, fn func(user *dto.User) error) error {
+	now := time.Now()
+	if remaining := s.failureLock.Locked(userId, now); remaining > 0 {
+		return twofa.ErrTwoFactorLocked
+	}
+
+	err := s.update(ctx, userId, auditAction, fn)
+	switch {
+	case err == nil:
+		s.failureLock.Reset(userId)
+	case errors.Is(err, twofa.ErrInvalidTwoFactorCode):
+		if s.failureLock.Fail(userId, now) {
+			log.Warnf("[TwoFactorService] too many invalid 2FA codes, userId %s locked for %v", userId, settings.TOTP_LOCK_DURATION)
+		}
+	}
+	return err
+}
+
+// update load user, apply fn and persist TOTP state in one tx, used TOTP step and backup code are consumed atomically.
+// auditAction is appended to audit log in the same tx if not empty.
+func (s *twoFactorService) update(ctx context.Context, userId string, auditAction dto.AuditAction, fn func(user *dto.User) error) error {
+	var user *dto.User
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		user, err = s.userRepo.GetUserById(ctx, tx, userId)
+		if err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+		if err := s.userRepo.UpdateTotp(ctx, tx, user); err != nil {
+			return err
+		}
+		if auditAction == "" {
+			return nil
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newUserAuditLog(user.ID, user.Username, auditAction, user.ID))
+	})
+	if err != nil {
+		return err
+	}
+	s.sessionStore.RefreshUser(user)
+	return nil
+}
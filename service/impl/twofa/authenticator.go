@@ -0,0 +1,153 @@
+package twofa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTwoFactorRequired    = errors.New("2FA code is required")
+	ErrInvalidTwoFactorCode = errors.New("invalid 2FA code")
+	ErrTwoFactorEnabled     = errors.New("2FA is already enabled")
+	ErrTwoFactorNotEnrolled = errors.New("2FA is not enrolled")
+	ErrTwoFactorNotEnabled  = errors.New("2FA is not enabled")
+	ErrTwoFactorLocked      = errors.New("too many invalid 2FA codes, try again later")
+)
+
+var backupCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Clock current time source, tests inject fixed clock.
+type Clock func() time.Time
+
+// Authenticator TOTP 2FA state machine of user, it only mutates dto.User, callers persist it.
+type Authenticator struct {
+	now Clock
+}
+
+func NewAuthenticator(now Clock) *Authenticator {
+	return &Authenticator{now: now}
+}
+
+// Enroll generate new secret for user, 2FA takes effect after Enable with a code of the secret.
+// re-enroll before enabling replaces previous secret.
+func (a *Authenticator) Enroll(user *dto.User) (secret, uri string, err error) {
+	if user.TotpEnabled {
+		return "", "", ErrTwoFactorEnabled
+	}
+	secret, err = security.GenerateTotpSecret()
+	if err != nil {
+		return "", "", err
+	}
+	user.TotpSecret = secret
+	user.TotpBackupCodes = nil
+	user.TotpLastStep = 0
+	return secret, security.TotpURI(settings.TOTP_ISSUER, user.Username, secret), nil
+}
+
+// Enable confirm enrolled secret by TOTP code and return plain backup codes, they are shown only once.
+func (a *Authenticator) Enable(user *dto.User, code string) ([]string, error) {
+	if user.TotpEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TotpSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err := a.verifyTotp(user, code); err != nil {
+		return nil, err
+	}
+	user.TotpEnabled = true
+	return a.resetBackupCodes(user)
+}
+
+// Verify check TOTP code or backup code of user, user without 2FA enabled always passes.
+// used TOTP step and backup code are consumed, callers must persist user after success.
+func (a *Authenticator) Verify(user *dto.User, code string) error {
+	if !user.TotpEnabled {
+		return nil
+	}
+	code = normalizeCode(code)
+	if code == "" {
+		return ErrTwoFactorRequired
+	}
+	if err := a.verifyTotp(user, code); err == nil {
+		return nil
+	}
+
+	hash := hashBackupCode(code)
+	for i, backupCode := range user.TotpBackupCodes {
+		if backupCode == hash {
+			user.TotpBackupCodes = append(user.TotpBackupCodes[:i:i], user.TotpBackupCodes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// Disable turn off 2FA, code is required.
+func (a *Authenticator) Disable(user *dto.User, code string) error {
+	if !user.TotpEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := a.Verify(user, code); err != nil {
+		return err
+	}
+	user.TotpEnabled = false
+	user.TotpSecret = ""
+	user.TotpBackupCodes = nil
+	user.TotpLastStep = 0
+	return nil
+}
+
+// RegenerateBackupCodes replace all backup codes, code is required.
+func (a *Authenticator) RegenerateBackupCodes(user *dto.User, code string) ([]string, error) {
+	if !user.TotpEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := a.Verify(user, code); err != nil {
+		return nil, err
+	}
+	return a.resetBackupCodes(user)
+}
+
+// verifyTotp code of step not after last accepted step is rejected, so one code can not be used twice.
+func (a *Authenticator) verifyTotp(user *dto.User, code string) error {
+	step, ok := security.MatchTotp(user.TotpSecret, normalizeCode(code), a.now())
+	if !ok || step <= user.TotpLastStep {
+		return ErrInvalidTwoFactorCode
+	}
+	user.TotpLastStep = step
+	return nil
+}
+
+func (a *Authenticator) resetBackupCodes(user *dto.User) ([]string, error) {
+	codes := make([]string, 0, settings.TOTP_BACKUP_CODE_COUNT)
+	hashes := make([]string, 0, settings.TOTP_BACKUP_CODE_COUNT)
+	for i := 0; i < settings.TOTP_BACKUP_CODE_COUNT; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(backupCodeEncoding.EncodeToString(b))
+		codes = append(codes, code)
+		hashes = append(hashes, hashBackupCode(code))
+	}
+	user.TotpBackupCodes = hashes
+	return codes, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+func hashBackupCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/service/impl/twofa"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func assert(t *testing.T, a, b any) {
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
+
+// fakeClock fixed time, advance it to move to next TOTP step.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+func newFixture() (*twofa.Authenticator, *fakeClock, *dto.User) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return twofa.NewAuthenticator(clock.Now), clock, &dto.User{ID: "U1", Username: "frizo"}
+}
+
+func code(t *testing.T, user *dto.User, at time.Time) string {
+	c, err := security.TotpCode(user.TotpSecret, at)
+	if err != nil {
+		t.Fatalf("totp code failed: %v", err)
+	}
+	return c
+}
+
+// enable enroll and enable 2FA of user, returns backup codes.
+func enable(t *testing.T, auth *twofa.Authenticator, clock *fakeClock, user *dto.User) []string {
+	if _, _, err := auth.Enroll(user); err != nil {
+		t.Fatalf("enroll failed: %v", err)
+	}
+	backupCodes, err := auth.Enable(user, code(t, user, clock.t))
+	if err != nil {
+		t.Fatalf("enable failed: %v", err)
+	}
+	return backupCodes
+}
+
+func Test_TwoFA_Enroll(t *testing.T) {
+	auth, _, user := newFixture()
+
+	secret, uri, err := auth.Enroll(user)
+	assert(t, err, nil)
+	assert(t, user.TotpSecret, secret)
+	assert(t, user.TotpEnabled, false)
+	assert(t, strings.HasPrefix(uri, "otpauth://totp/"), true)
+	assert(t, strings.Contains(uri, "secret="+secret), true)
+
+	// 2FA is not enforced before enabling
+	assert(t, auth.Verify(user, ""), nil)
+}
+
+func Test_TwoFA_Enable(t *testing.T) {
+	auth, clock, user := newFixture()
+
+	_, err := auth.Enable(user, "123456")
+	assert(t, err, twofa.ErrTwoFactorNotEnrolled)
+
+	_, _, _ = auth.Enroll(user)
+	_, err = auth.Enable(user, "000000")
+	assert(t, err, twofa.ErrInvalidTwoFactorCode)
+	assert(t, user.TotpEnabled, false)
+
+	backupCodes, err := auth.Enable(user, code(t, user, clock.t))
+	assert(t, err, nil)
+	assert(t, user.TotpEnabled, true)
+	assert(t, len(backupCodes), settings.TOTP_BACKUP_CODE_COUNT)
+	assert(t, len(user.TotpBackupCodes), settings.TOTP_BACKUP_CODE_COUNT)
+	// only hashes are kept
+	assert(t, user.TotpBackupCodes[0] == backupCodes[0], false)
+
+	_, _, err = auth.Enroll(user)
+	assert(t, err, twofa.ErrTwoFactorEnabled)
+}
+
+func Test_TwoFA_Verify_Totp(t *testing.T) {
+	auth, clock, user := newFixture()
+	enable(t, auth, clock, user)
+
+	assert(t, auth.Verify(user, ""), twofa.ErrTwoFactorRequired)
+
+	// code used by Enable can not be used again
+	assert(t, auth.Verify(user, code(t, user, clock.t)), twofa.ErrInvalidTwoFactorCode)
+
+	clock.t = clock.t.Add(30 * time.Second)
+	current := code(t, user, clock.t)
+	assert(t, auth.Verify(user, current), nil)
+	assert(t, auth.Verify(user, current), twofa.ErrInvalidTwoFactorCode)
+
+	// one step of clock drift is tolerated
+	clock.t = clock.t.Add(60 * time.Second)
+	assert(t, auth.Verify(user, code(t, user, clock.t.Add(-30*time.Second))), nil)
+
+	// expired code
+	clock.t = clock.t.Add(5 * time.Minute)
+	assert(t, auth.Verify(user, code(t, user, clock.t.Add(-2*time.Minute))), twofa.ErrInvalidTwoFactorCode)
+}
+
+func Test_TwoFA_Verify_BackupCode(t *testing.T) {
+	auth, clock, user := newFixture()
+	backupCodes := enable(t, auth, clock, user)
+
+	assert(t, auth.Verify(user, strings.ToUpper(backupCodes[0])), nil)
+	assert(t, len(user.TotpBackupCodes), settings.TOTP_BACKUP_CODE_COUNT-1)
+	assert(t, auth.Verify(user, backupCodes[0]), twofa.ErrInvalidTwoFactorCode)
+	assert(t, auth.Verify(user, backupCodes[1]), nil)
+	assert(t, len(user.TotpBackupCodes), settings.TOTP_BACKUP_CODE_COUNT-2)
+}
+
+func Test_TwoFA_RegenerateBackupCodes(t *testing.T) {
+	auth, clock, user := newFixture()
+	oldCodes := enable(t, auth, clock, user)
+
+	_, err := auth.RegenerateBackupCodes(user, "000000")
+	assert(t, err, twofa.ErrInvalidTwoFactorCode)
+
+	newCodes, err := auth.RegenerateBackupCodes(user, oldCodes[0])
+	assert(t, err, nil)
+	assert(t, len(newCodes), settings.TOTP_BACKUP_CODE_COUNT)
+	assert(t, auth.Verify(user, oldCodes[1]), twofa.ErrInvalidTwoFactorCode)
+	assert(t, auth.Verify(user, newCodes[0]), nil)
+}
+
+func Test_TwoFA_Disable(t *testing.T) {
+	auth, clock, user := newFixture()
+
+	assert(t, auth.Disable(user, "123456"), twofa.ErrTwoFactorNotEnabled)
+
+	enable(t, auth, clock, user)
+	assert(t, auth.Disable(user, ""), twofa.ErrTwoFactorRequired)
+	assert(t, user.TotpEnabled, true)
+
+	clock.t = clock.t.Add(30 * time.Second)
+	assert(t, auth.Disable(user, code(t, user, clock.t)), nil)
+	assert(t, user.TotpEnabled, false)
+	assert(t, user.TotpSecret, "")
+	assert(t, len(user.TotpBackupCodes), 0)
+	assert(t, auth.Verify(user, ""), nil)
+}
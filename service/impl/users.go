@@ -6,8 +6,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/google/uuid"
 	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/notifier"
 	"github.com/johnny1110/crypto-exchange/repository"
 	"github.com/johnny1110/crypto-exchange/security"
 	"github.com/johnny1110/crypto-exchange/service"
@@ -17,19 +17,42 @@ import (
 	"time"
 )
 
+var (
+	ErrInvalidAccountStatus    = errors.New("invalid account status")
+	ErrAccountFrozen           = errors.New("account is frozen")
+	ErrAccountClosed           = errors.New("account is closed")
+	ErrAccountTradeDisabled    = errors.New("trading is disabled for account")
+	ErrAccountWithdrawDisabled = errors.New("withdrawal is disabled for account")
+	ErrInvalidOldPassword      = errors.New("invalid old password")
+	ErrSamePassword            = errors.New("new password must be different from old password")
+	ErrInvalidResetToken       = errors.New("invalid or expired reset token")
+)
+
 type userService struct {
-	db              *sql.DB
-	userRepo        repository.IUserRepository
-	balanceRepo     repository.IBalanceRepository
-	credentialCache *security.CredentialCache
+	db                *sql.DB
+	userRepo          repository.IUserRepository
+	balanceRepo       repository.IBalanceRepository
+	sessionStore      security.SessionStore
+	referralRepo      repository.IReferralRepository
+	feeTierService    service.IFeeTierService
+	twoFactorService  service.ITwoFactorService
+	auditLogRepo      repository.IAuditLogRepository
+	passwordResetRepo repository.IPasswordResetRepository
+	notifier          notifier.Notifier
 }
 
-func NewIUserService(db *sql.DB, userRepo repository.IUserRepository, balanceRepo repository.IBalanceRepository, credentialCache *security.CredentialCache) service.IUserService {
+func NewIUserService(db *sql.DB, userRepo repository.IUserRepository, balanceRepo repository.IBalanceRepository, referralRepo repository.IReferralRepository, sessionStore security.SessionStore, feeTierService service.IFeeTierService, twoFactorService service.ITwoFactorService, auditLogRepo repository.IAuditLogRepository, passwordResetRepo repository.IPasswordResetRepository, notifier notifier.Notifier) service.IUserService {
 	return &userService{
-		db:              db,
-		userRepo:        userRepo,
-		balanceRepo:     balanceRepo,
-		credentialCache: credentialCache,
+		db:                db,
+		userRepo:          userRepo,
+		balanceRepo:       balanceRepo,
+		referralRepo:      referralRepo,
+		sessionStore:      sessionStore,
+		feeTierService:    feeTierService,
+		twoFactorService:  twoFactorService,
+		auditLogRepo:      auditLogRepo,
+		passwordResetRepo: passwordResetRepo,
+		notifier:          notifier,
 	}
 }
 
@@ -37,6 +60,21 @@ func (s userService) GetUser(ctx context.Context, userId string) (*dto.User, err
 	return s.userRepo.GetUserById(ctx, s.db, userId)
 }
 
+func (s userService) GetProfile(ctx context.Context, userId string) (*dto.User, error) {
+	user, err := s.userRepo.GetUserById(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := s.feeTierService.GetTierProgress(ctx, user)
+	if err != nil {
+		log.Warnf("[GetProfile] failed to get fee tier progress, userId: %s, error: %v", userId, err)
+		return user, nil
+	}
+	user.FeeTier = progress
+	return user, nil
+}
+
 func (s userService) Register(ctx context.Context, req *dto.RegisterReq) (string, error) {
 	// gen userId
 	userID, err := genUIDSecure()
@@ -69,6 +107,17 @@ func (s userService) Register(ctx context.Context, req *dto.RegisterReq) (string
 			TakerFee:     0.002,
 		})
 
+		if req.ReferralCode != "" {
+			referrerId, err := s.referralRepo.GetUserIdByCode(ctx, tx, req.ReferralCode)
+			if err != nil {
+				log.Warnf("[Register] invalid referral code: %s, error: %v", req.ReferralCode, err)
+				return ErrInvalidReferralCode
+			}
+			if err = s.referralRepo.InsertReferral(ctx, tx, referrerId, userID); err != nil {
+				return err
+			}
+		}
+
 		err = s.balanceRepo.BatchCreate(ctx, tx, userID, settings.GetAllAssets())
 		_ = s.balanceRepo.UpdateAsset(ctx, tx, userID, "USDT", 500, 0)
 		return err
@@ -81,25 +130,258 @@ func (s userService) Register(ctx context.Context, req *dto.RegisterReq) (string
 	return userID, err
 }
 
-func (s userService) Login(ctx context.Context, req *dto.LoginReq) (string, error) {
+func (s userService) Login(ctx context.Context, req *dto.LoginReq, clientIP, userAgent string) (*dto.Session, error) {
 	user, err := s.userRepo.GetUserByUsername(ctx, s.db, req.Username)
 	if err != nil {
-		return "", errors.New("username not exists")
+		return nil, s.loginFailed(ctx, &dto.User{Username: req.Username}, errors.New("username not exists"))
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return "", errors.New("invalid credentials")
+		return nil, s.loginFailed(ctx, user, errors.New("invalid credentials"))
+	}
+	// checked before 2FA, so TOTP code is not consumed.
+	if err := checkAccountActive(user); err != nil {
+		return nil, s.loginFailed(ctx, user, err)
+	}
+	if user.TotpEnabled {
+		if err := s.twoFactorService.Verify(ctx, user.ID, req.TotpCode); err != nil {
+			return nil, s.loginFailed(ctx, user, err)
+		}
+	}
+
+	session, err := s.sessionStore.Create(ctx, user, clientIP, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	// login is refused if it can not be audited.
+	if err := recordAuditLog(ctx, s.db, s.auditLogRepo, newUserAuditLog(user.ID, user.Username, dto.AUDIT_USER_LOGIN, session.ID)); err != nil {
+		if delErr := s.sessionStore.Delete(ctx, session.Token); delErr != nil {
+			log.Errorf("[Login] failed to delete unaudited session, userId: %s, error: %v", user.ID, delErr)
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// loginFailed record failed login with cause as reason, return cause or error of recording.
+func (s userService) loginFailed(ctx context.Context, user *dto.User, cause error) error {
+	auditLog := newUserAuditLog(user.ID, user.Username, dto.AUDIT_USER_LOGIN_FAILED, user.ID)
+	auditLog.After = auditValue(map[string]string{"reason": cause.Error()})
+	if err := recordAuditLog(ctx, s.db, s.auditLogRepo, auditLog); err != nil {
+		return err
+	}
+	return cause
+}
+
+func checkAccountActive(user *dto.User) error {
+	switch user.Status {
+	case dto.ACCOUNT_STATUS_FROZEN:
+		return ErrAccountFrozen
+	case dto.ACCOUNT_STATUS_CLOSED:
+		return ErrAccountClosed
+	}
+	return nil
+}
+
+func checkCanTrade(user *dto.User) error {
+	if err := checkAccountActive(user); err != nil {
+		return err
+	}
+	if user.TradeDisabled {
+		return ErrAccountTradeDisabled
 	}
+	return nil
+}
 
-	token := uuid.NewString()
-	s.credentialCache.Put(token, user)
-	return token, nil
+func checkCanWithdraw(user *dto.User) error {
+	if err := checkAccountActive(user); err != nil {
+		return err
+	}
+	if user.WithdrawDisabled {
+		return ErrAccountWithdrawDisabled
+	}
+	return nil
 }
 
 func (s userService) Logout(ctx context.Context, token string) error {
-	s.credentialCache.Delete(token)
+	return s.sessionStore.Delete(ctx, token)
+}
+
+// ChangePassword sessions other than current one are logged out, TOTP code is required if user enabled 2FA.
+func (s userService) ChangePassword(ctx context.Context, userId, token string, req *dto.ChangePasswordReq) error {
+	user, err := s.userRepo.GetUserById(ctx, s.db, userId)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)); err != nil {
+		return ErrInvalidOldPassword
+	}
+	if req.NewPassword == req.OldPassword {
+		return ErrSamePassword
+	}
+	if err := s.twoFactorService.Verify(ctx, userId, req.TotpCode); err != nil {
+		return err
+	}
+
+	auditLog := newUserAuditLog(user.ID, user.Username, dto.AUDIT_PASSWORD_CHANGE, user.ID)
+	if err := s.updatePassword(ctx, user, req.NewPassword, "", auditLog); err != nil {
+		return err
+	}
+	if err := s.sessionStore.DeleteOthers(ctx, userId, token); err != nil {
+		log.Errorf("[ChangePassword] failed to delete other sessions, userId: %s, error: %v", userId, err)
+	}
+
+	log.Infof("[ChangePassword] password changed, userId: %s", userId)
 	return nil
 }
 
+// RequestPasswordReset send reset token to user by notifier. It does not tell whether username exists,
+// nothing is sent for unknown, frozen or closed accounts, or if last token was sent within PASSWORD_RESET_REQUEST_INTERVAL.
+func (s userService) RequestPasswordReset(ctx context.Context, req *dto.PasswordResetRequestReq) error {
+	user, err := s.userRepo.GetUserByUsername(ctx, s.db, req.Username)
+	if err != nil {
+		log.Infof("[RequestPasswordReset] unknown username: %s", req.Username)
+		return nil
+	}
+	if !user.IsActive() {
+		log.Warnf("[RequestPasswordReset] %s account %s, reset token not sent", user.Status, user.ID)
+		return nil
+	}
+	now := time.Now()
+	if last, err := s.passwordResetRepo.GetByUserId(ctx, s.db, user.ID); err == nil && now.Sub(last.CreatedAt) < settings.PASSWORD_RESET_REQUEST_INTERVAL {
+		log.Infof("[RequestPasswordReset] reset token sent recently, userId: %s", user.ID)
+		return nil
+	}
+
+	token, err := security.GenerateToken()
+	if err != nil {
+		return err
+	}
+	resetToken := &dto.PasswordResetToken{
+		TokenHash: security.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(settings.PASSWORD_RESET_TOKEN_TTL),
+		CreatedAt: now,
+	}
+	// new token replaces unused one.
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.passwordResetRepo.DeleteByUserId(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		if err := s.passwordResetRepo.Insert(ctx, tx, resetToken); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, newUserAuditLog(user.ID, user.Username, dto.AUDIT_PASSWORD_RESET_REQUEST, user.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.notifier.Notify(ctx, &notifier.Notification{
+		UserID:   user.ID,
+		Username: user.Username,
+		Subject:  "Password reset",
+		Body: fmt.Sprintf("Your password reset token is %s, it expires in %s and can be used once. "+
+			"Ignore this message if you did not request it.", token, settings.PASSWORD_RESET_TOKEN_TTL),
+	})
+	if err != nil {
+		log.Errorf("[RequestPasswordReset] failed to notify user %s: %v", user.ID, err)
+		return errors.New("failed to send reset token")
+	}
+	return nil
+}
+
+// ResetPassword by reset token, all sessions of user are logged out. TOTP code is required if user enabled 2FA.
+func (s userService) ResetPassword(ctx context.Context, req *dto.PasswordResetReq) error {
+	tokenHash := security.HashToken(req.Token)
+	resetToken, err := s.passwordResetRepo.GetByTokenHash(ctx, s.db, tokenHash)
+	if err != nil || !time.Now().Before(resetToken.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	user, err := s.userRepo.GetUserById(ctx, s.db, resetToken.UserID)
+	if err != nil {
+		return err
+	}
+	if err := checkAccountActive(user); err != nil {
+		return err
+	}
+	if err := s.twoFactorService.Verify(ctx, user.ID, req.TotpCode); err != nil {
+		return err
+	}
+
+	auditLog := newUserAuditLog(user.ID, user.Username, dto.AUDIT_PASSWORD_RESET, user.ID)
+	if err := s.updatePassword(ctx, user, req.NewPassword, tokenHash, auditLog); err != nil {
+		return err
+	}
+	if err := s.sessionStore.DeleteByUserId(ctx, user.ID); err != nil {
+		log.Errorf("[ResetPassword] failed to delete sessions, userId: %s, error: %v", user.ID, err)
+	}
+
+	log.Infof("[ResetPassword] password reset, userId: %s", user.ID)
+	return nil
+}
+
+// updatePassword save new password, drop reset tokens of user and append auditLog in one tx. resetTokenHash must still exist
+// if not empty, so a reset token can not be used twice concurrently.
+func (s userService) updatePassword(ctx context.Context, user *dto.User, password, resetTokenHash string, auditLog *dto.AuditLog) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+
+	return WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if resetTokenHash != "" {
+			deleted, err := s.passwordResetRepo.DeleteByTokenHash(ctx, tx, resetTokenHash)
+			if err != nil {
+				return err
+			}
+			if deleted == 0 {
+				return ErrInvalidResetToken
+			}
+		}
+		if err := s.userRepo.UpdatePwd(ctx, tx, user); err != nil {
+			return err
+		}
+		if err := s.passwordResetRepo.DeleteByUserId(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, auditLog)
+	})
+}
+
+func (s userService) LogoutAll(ctx context.Context, userId string) error {
+	if err := s.sessionStore.DeleteByUserId(ctx, userId); err != nil {
+		return err
+	}
+	log.Infof("[LogoutAll] all sessions deleted, userId: %s", userId)
+	return nil
+}
+
+func (s userService) RefreshSession(ctx context.Context, req *dto.RefreshTokenReq, clientIP, userAgent string) (*dto.Session, error) {
+	return s.sessionStore.Refresh(ctx, req.RefreshToken, clientIP, userAgent)
+}
+
+func (s userService) GetSessions(ctx context.Context, userId, token string) ([]*dto.Session, error) {
+	return s.sessionStore.GetSessionsByUserId(ctx, userId, token)
+}
+
+// UpdateFeeSettings opt-in/out paying trading fees in settings.FEE_TOKEN with discount, apply to orders placed after.
+func (s userService) UpdateFeeSettings(ctx context.Context, userId string, req *dto.UpdateFeeSettingsReq) (*dto.User, error) {
+	if req == nil || req.PayFeeInBTSE == nil {
+		return nil, ErrInvalidInput
+	}
+	if err := s.userRepo.UpdatePayFeeInBTSE(ctx, s.db, userId, *req.PayFeeInBTSE); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, s.db, userId)
+	if err != nil {
+		return nil, err
+	}
+	s.sessionStore.RefreshUser(user)
+	return user, nil
+}
+
 func genUIDSecure() (string, error) {
 
 	now := time.Now()
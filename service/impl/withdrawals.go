@@ -0,0 +1,345 @@
+package serviceImpl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/repository"
+	"github.com/johnny1110/crypto-exchange/service"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var (
+	ErrUnsupportedAsset              = errors.New("unsupported asset")
+	ErrAddressNotWhitelisted         = errors.New("withdrawal address not whitelisted")
+	ErrAddressInCoolingOff           = errors.New("withdrawal address still in cooling-off period")
+	ErrWithdrawalDailyLimitExceeded  = errors.New("withdrawal daily limit exceeded")
+	ErrInvalidWithdrawalTransition   = errors.New("invalid withdrawal status transition")
+	ErrWithdrawalTxHashRequired      = errors.New("tx hash is required for broadcast")
+	ErrWithdrawalValuationNotAllowed = errors.New("can not evaluate withdrawal value")
+)
+
+type withdrawalService struct {
+	db                *sql.DB
+	userRepo          repository.IUserRepository
+	withdrawalRepo    repository.IWithdrawalRepository
+	addressRepo       repository.IWithdrawalAddressRepository
+	balanceRepo       repository.IBalanceRepository
+	transferRepo      repository.ITransferRepository
+	subAccountRepo    repository.ISubAccountRepository
+	marketDataService service.IMarketDataService
+	twoFactorService  service.ITwoFactorService
+	auditLogRepo      repository.IAuditLogRepository
+}
+
+func NewIWithdrawalService(db *sql.DB,
+	userRepo repository.IUserRepository,
+	withdrawalRepo repository.IWithdrawalRepository,
+	addressRepo repository.IWithdrawalAddressRepository,
+	balanceRepo repository.IBalanceRepository,
+	transferRepo repository.ITransferRepository,
+	subAccountRepo repository.ISubAccountRepository,
+	marketDataService service.IMarketDataService,
+	twoFactorService service.ITwoFactorService,
+	auditLogRepo repository.IAuditLogRepository) service.IWithdrawalService {
+	return &withdrawalService{
+		db:                db,
+		userRepo:          userRepo,
+		withdrawalRepo:    withdrawalRepo,
+		addressRepo:       addressRepo,
+		balanceRepo:       balanceRepo,
+		transferRepo:      transferRepo,
+		subAccountRepo:    subAccountRepo,
+		marketDataService: marketDataService,
+		twoFactorService:  twoFactorService,
+		auditLogRepo:      auditLogRepo,
+	}
+}
+
+func (s *withdrawalService) AddAddress(ctx context.Context, userId string, req *dto.AddWithdrawalAddressReq) (*dto.WithdrawalAddress, error) {
+	if !isSupportedAsset(req.Asset) {
+		return nil, ErrUnsupportedAsset
+	}
+
+	now := time.Now()
+	address := &dto.WithdrawalAddress{
+		UserID:      userId,
+		Asset:       req.Asset,
+		Address:     req.Address,
+		Label:       req.Label,
+		CreatedAt:   now,
+		AvailableAt: now.Add(settings.WITHDRAWAL_ADDRESS_COOLING_OFF),
+	}
+
+	if err := s.addressRepo.Insert(ctx, s.db, address); err != nil {
+		log.Errorf("[WithdrawalService] AddAddress failed, error: %v", err)
+		return nil, errors.New("failed to add withdrawal address")
+	}
+
+	return address, nil
+}
+
+func (s *withdrawalService) GetAddresses(ctx context.Context, userId string) ([]*dto.WithdrawalAddress, error) {
+	return s.addressRepo.GetAddressesByUserId(ctx, s.db, userId)
+}
+
+func (s *withdrawalService) RemoveAddress(ctx context.Context, userId string, addressId int64) error {
+	return s.addressRepo.Delete(ctx, s.db, userId, addressId)
+}
+
+func (s *withdrawalService) Withdraw(ctx context.Context, user *dto.User, req *dto.WithdrawReq) (*dto.Withdrawal, error) {
+	if user == nil || req == nil {
+		return nil, ErrInvalidInput
+	}
+	if !isSupportedAsset(req.Asset) {
+		return nil, ErrUnsupportedAsset
+	}
+	if err := checkCanWithdraw(user); err != nil {
+		return nil, err
+	}
+	// sub-account withdraws under master's 2FA and daily limit.
+	master, err := getMasterUser(ctx, s.db, s.userRepo, s.subAccountRepo, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCanWithdraw(master); err != nil {
+		return nil, err
+	}
+
+	// 1. address whitelist and cooling-off check.
+	address, err := s.addressRepo.GetAddressByUserIdAndAssetAndAddress(ctx, s.db, user.ID, req.Asset, req.Address)
+	if err != nil {
+		return nil, ErrAddressNotWhitelisted
+	}
+	if time.Now().Before(address.AvailableAt) {
+		return nil, ErrAddressInCoolingOff
+	}
+
+	// 2. daily limit check by vip level.
+	valuation, err := evaluateUSDT(s.marketDataService, req.Asset, req.Amount)
+	if err != nil {
+		log.Warnf("[WithdrawalService] Withdraw evaluate failed, error: %v", err)
+		return nil, ErrWithdrawalValuationNotAllowed
+	}
+	// early check before consuming 2FA code, checked again in tx below.
+	if err := checkWithdrawalDailyLimit(ctx, s.db, s.withdrawalRepo, s.transferRepo, s.subAccountRepo, master, valuation); err != nil {
+		return nil, err
+	}
+
+	// 3. 2FA check, last one since it consumes the code.
+	if err := s.twoFactorService.Verify(ctx, master.ID, req.TotpCode); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	withdrawal := &dto.Withdrawal{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Asset:     req.Asset,
+		Amount:    req.Amount,
+		Address:   req.Address,
+		Valuation: valuation,
+		Status:    dto.WITHDRAWAL_STATUS_PENDING,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// 4. lock funds and create PENDING withdrawal, daily limit is checked in same tx so concurrent withdrawals can not exceed it.
+	err = WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := checkWithdrawalDailyLimit(ctx, tx, s.withdrawalRepo, s.transferRepo, s.subAccountRepo, master, valuation); err != nil {
+			return err
+		}
+		if err := s.balanceRepo.LockedByUserIdAndAsset(ctx, tx, user.ID, req.Asset, req.Amount); err != nil {
+			log.Warnf("[WithdrawalService] Withdraw failed to lock user balance, %v", err)
+			return ErrInsufficientBalance
+		}
+		if err := s.withdrawalRepo.Insert(ctx, tx, withdrawal); err != nil {
+			return err
+		}
+		return s.withdrawalRepo.InsertAudit(ctx, tx, &dto.WithdrawalAudit{
+			WithdrawalID: withdrawal.ID,
+			ToStatus:     dto.WITHDRAWAL_STATUS_PENDING,
+			Operator:     user.ID,
+			CreatedAt:    now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. small withdrawal approved by system, others wait for admin review.
+	if valuation < settings.WITHDRAWAL_MANUAL_APPROVAL_THRESHOLD {
+		return s.Transit(ctx, withdrawal.ID, dto.WITHDRAWAL_STATUS_APPROVED, dto.WITHDRAWAL_OPERATOR_SYSTEM,
+			&dto.ReviewWithdrawalReq{Remark: "auto approved under threshold"}, nil)
+	}
+
+	return withdrawal, nil
+}
+
+// checkWithdrawalDailyLimit rolling 24h valuation of withdrawals and transfers to other users of master and its
+// sub-accounts plus valuation must not exceed daily limit of master vip level.
+func checkWithdrawalDailyLimit(ctx context.Context, db repository.DBExecutor,
+	withdrawalRepo repository.IWithdrawalRepository, transferRepo repository.ITransferRepository,
+	subAccountRepo repository.ISubAccountRepository, master *dto.User, valuation float64) error {
+	subAccounts, err := subAccountRepo.GetSubAccountsByMasterUserId(ctx, db, master.ID)
+	if err != nil {
+		return err
+	}
+	userIds := []string{master.ID}
+	for _, sub := range subAccounts {
+		userIds = append(userIds, sub.SubUserID)
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+	var withdrawn, transferred float64
+	for _, userId := range userIds {
+		w, err := withdrawalRepo.SumValuationSince(ctx, db, userId, since)
+		if err != nil {
+			return err
+		}
+		t, err := transferRepo.SumValuationSince(ctx, db, userId, since)
+		if err != nil {
+			return err
+		}
+		withdrawn += w
+		transferred += t
+	}
+	if withdrawn+transferred+valuation > settings.WITHDRAWAL_DAILY_LIMIT_MAP[master.VipLevel] {
+		log.Warnf("[WithdrawalService] daily limit exceeded, userId: %s, withdrawn: %v, transferred: %v, valuation: %v",
+			master.ID, withdrawn, transferred, valuation)
+		return ErrWithdrawalDailyLimitExceeded
+	}
+	return nil
+}
+
+// getMasterUser master of sub-account, user itself if it is not a sub-account.
+func getMasterUser(ctx context.Context, db repository.DBExecutor,
+	userRepo repository.IUserRepository, subAccountRepo repository.ISubAccountRepository, user *dto.User) (*dto.User, error) {
+	sub, err := subAccountRepo.GetSubAccountBySubUserId(ctx, db, user.ID)
+	if err != nil {
+		return user, nil
+	}
+	return userRepo.GetUserById(ctx, db, sub.MasterUserID)
+}
+
+func (s *withdrawalService) GetWithdrawals(ctx context.Context, userId string) ([]*dto.Withdrawal, error) {
+	return s.withdrawalRepo.GetWithdrawalsByUserId(ctx, s.db, userId)
+}
+
+func (s *withdrawalService) GetWithdrawalsByStatus(ctx context.Context, status dto.WithdrawalStatus) ([]*dto.Withdrawal, error) {
+	return s.withdrawalRepo.GetWithdrawalsByStatus(ctx, s.db, status)
+}
+
+func (s *withdrawalService) GetAudits(ctx context.Context, withdrawalId string) ([]*dto.WithdrawalAudit, error) {
+	return s.withdrawalRepo.GetAuditsByWithdrawalId(ctx, s.db, withdrawalId)
+}
+
+func (s *withdrawalService) Transit(ctx context.Context, withdrawalId string, toStatus dto.WithdrawalStatus, operator string, req *dto.ReviewWithdrawalReq, auditLog *dto.AuditLog) (*dto.Withdrawal, error) {
+	if req == nil {
+		req = &dto.ReviewWithdrawalReq{}
+	}
+	if toStatus == dto.WITHDRAWAL_STATUS_BROADCAST && req.TxHash == "" {
+		return nil, ErrWithdrawalTxHashRequired
+	}
+
+	var withdrawal *dto.Withdrawal
+	err := WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		withdrawal, err = s.withdrawalRepo.GetWithdrawalById(ctx, tx, withdrawalId)
+		if err != nil {
+			return err
+		}
+
+		fromStatus := withdrawal.Status
+		if !fromStatus.CanTransitTo(toStatus) {
+			log.Warnf("[WithdrawalService] Transit failed, withdrawalId: %s, %s -> %s", withdrawalId, fromStatus, toStatus)
+			return ErrInvalidWithdrawalTransition
+		}
+		// funds must not leave frozen or withdraw-disabled account, rejecting is always allowed.
+		if toStatus == dto.WITHDRAWAL_STATUS_APPROVED || toStatus == dto.WITHDRAWAL_STATUS_BROADCAST {
+			user, err := s.userRepo.GetUserById(ctx, tx, withdrawal.UserID)
+			if err != nil {
+				return err
+			}
+			if err := checkCanWithdraw(user); err != nil {
+				return err
+			}
+		}
+
+		if err = s.withdrawalRepo.UpdateStatus(ctx, tx, withdrawalId, fromStatus, toStatus, req.TxHash, req.Remark); err != nil {
+			return err
+		}
+
+		switch toStatus {
+		case dto.WITHDRAWAL_STATUS_REJECTED:
+			// give locked funds back.
+			if err = s.balanceRepo.UnlockedByUserIdAndAsset(ctx, tx, withdrawal.UserID, withdrawal.Asset, withdrawal.Amount); err != nil {
+				return fmt.Errorf("failed to unlock withdrawal funds: %w", err)
+			}
+		case dto.WITHDRAWAL_STATUS_CONFIRMED:
+			// funds left exchange.
+			if err = s.balanceRepo.ModifyLockedByUserIdAndAsset(ctx, tx, withdrawal.UserID, withdrawal.Asset, false, withdrawal.Amount); err != nil {
+				return fmt.Errorf("failed to deduct withdrawal funds: %w", err)
+			}
+		}
+
+		err = s.withdrawalRepo.InsertAudit(ctx, tx, &dto.WithdrawalAudit{
+			WithdrawalID: withdrawalId,
+			FromStatus:   fromStatus,
+			ToStatus:     toStatus,
+			Operator:     operator,
+			Remark:       req.Remark,
+			CreatedAt:    time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		withdrawal.Status = toStatus
+		withdrawal.Remark = req.Remark
+		if req.TxHash != "" {
+			withdrawal.TxHash = req.TxHash
+		}
+		withdrawal.UpdatedAt = time.Now()
+		if auditLog == nil {
+			return nil
+		}
+		return appendAuditLog(ctx, tx, s.auditLogRepo, auditLog)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[WithdrawalService] withdrawal: %s transit to %s by %s", withdrawalId, toStatus, operator)
+	return withdrawal, nil
+}
+
+// evaluateUSDT USDT value of asset amount at latest price.
+func evaluateUSDT(marketDataService service.IMarketDataService, asset string, amount float64) (float64, error) {
+	if asset == "USDT" {
+		return amount, nil
+	}
+	data, err := marketDataService.GetMarketData(fmt.Sprintf("%v-USDT", asset))
+	if err != nil {
+		return 0, err
+	}
+	if data.LatestPrice <= 0 {
+		return 0, fmt.Errorf("invalid latest price for asset %s", asset)
+	}
+	return data.LatestPrice * amount, nil
+}
+
+func isSupportedAsset(asset string) bool {
+	for _, a := range settings.GetAllAssets() {
+		if a == asset {
+			return true
+		}
+	}
+	return false
+}
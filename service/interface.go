@@ -7,6 +7,8 @@ import (
 	"github.com/johnny1110/crypto-exchange/engine-v2/market"
 	"github.com/johnny1110/crypto-exchange/engine-v2/model"
 	"github.com/johnny1110/crypto-exchange/ohlcv"
+	"io"
+	"time"
 )
 
 type ICacheService interface {
@@ -16,10 +18,50 @@ type ICacheService interface {
 
 type IUserService interface {
 	GetUser(ctx context.Context, userId string) (*dto.User, error)
+	// GetProfile return user with fee tier progress.
+	GetProfile(ctx context.Context, userId string) (*dto.User, error)
 	Register(ctx context.Context, req *dto.RegisterReq) (string, error)
-	// Login return token
-	Login(ctx context.Context, req *dto.LoginReq) (string, error)
+	// Login create session, return access token and refresh token.
+	Login(ctx context.Context, req *dto.LoginReq, clientIP, userAgent string) (*dto.Session, error)
 	Logout(ctx context.Context, token string) error
+	// LogoutAll delete all sessions of user (all devices).
+	LogoutAll(ctx context.Context, userId string) error
+	// RefreshSession rotate tokens of session, old tokens are invalid after.
+	RefreshSession(ctx context.Context, req *dto.RefreshTokenReq, clientIP, userAgent string) (*dto.Session, error)
+	// GetSessions active sessions of user, session of token is marked current.
+	GetSessions(ctx context.Context, userId, token string) ([]*dto.Session, error)
+	UpdateFeeSettings(ctx context.Context, userId string, req *dto.UpdateFeeSettingsReq) (*dto.User, error)
+	// ChangePassword require old password, log out other sessions of user.
+	ChangePassword(ctx context.Context, userId, token string, req *dto.ChangePasswordReq) error
+	// RequestPasswordReset send single-use, time-limited reset token to user by notifier.
+	RequestPasswordReset(ctx context.Context, req *dto.PasswordResetRequestReq) error
+	// ResetPassword set new password by reset token, log out all sessions of user.
+	ResetPassword(ctx context.Context, req *dto.PasswordResetReq) error
+}
+
+type ITwoFactorService interface {
+	// Enroll generate TOTP secret and provisioning URI, 2FA takes effect after Enable.
+	Enroll(ctx context.Context, userId string) (*dto.TwoFactorEnrollment, error)
+	// Enable confirm enrolled secret by TOTP code, return backup codes.
+	Enable(ctx context.Context, userId string, req *dto.TwoFactorCodeReq) (*dto.TwoFactorBackupCodes, error)
+	Disable(ctx context.Context, userId string, req *dto.TwoFactorCodeReq) error
+	// RegenerateBackupCodes replace all backup codes, previous ones are invalid after.
+	RegenerateBackupCodes(ctx context.Context, userId string, req *dto.TwoFactorCodeReq) (*dto.TwoFactorBackupCodes, error)
+	// Verify check TOTP code or backup code for sensitive operations, pass if user did not enable 2FA.
+	Verify(ctx context.Context, userId, code string) error
+}
+
+type IApiKeyService interface {
+	// CreateApiKey secret is only returned here.
+	CreateApiKey(ctx context.Context, userId string, req *dto.CreateApiKeyReq) (*dto.ApiKey, error)
+	GetApiKeys(ctx context.Context, userId string) ([]*dto.ApiKey, error)
+	UpdateLabel(ctx context.Context, userId, apiKey string, req *dto.UpdateApiKeyReq) (*dto.ApiKey, error)
+	RevokeApiKey(ctx context.Context, userId, apiKey string) error
+	// Authenticate verify signed request (signature, receive window, replay, expiry, revoked, IP allowlist), return key owner.
+	Authenticate(ctx context.Context, auth *dto.ApiKeyAuth) (*dto.User, *dto.ApiKey, error)
+	// GetActiveApiKeyOwner owner of api key that is neither revoked nor expired, signature is not verified,
+	// for re-validating a key authenticated before.
+	GetActiveApiKeyOwner(ctx context.Context, apiKey string) (*dto.User, error)
 }
 
 type IOrderBookService interface {
@@ -36,15 +78,185 @@ type IOrderService interface {
 	PlaceOrder(ctx context.Context, market string, user *dto.User, req *dto.OrderReq) (*dto.PlaceOrderResult, error)
 	QueryOrder(ctx context.Context, userId string, isOpenOrder bool) ([]*dto.Order, error)
 	CancelOrder(ctx context.Context, userID, orderID string) (*dto.Order, error)
+	// CancelAllOrders cancel open orders of user and its margin wallet, return canceled order ids.
+	CancelAllOrders(ctx context.Context, userID string) ([]string, error)
 	QueryOrdersByMarketAndStatuses(ctx context.Context, market string, statuses []model.OrderStatus) ([]*dto.Order, error)
 	PaginationQuery(ctx context.Context, query *dto.GetOrdersQueryReq) (*dto.PaginationResp[*dto.Order], error)
 	QueryOrderByMarket(ctx context.Context, userID string, market string, isOpenOrder bool) ([]*dto.Order, error)
 	CountOpenOrders(ctx context.Context, marketName string) (int64, error)
+	GetFills(ctx context.Context, userId string, query *dto.GetFillsQueryReq) (*dto.FillsResp, error)
 }
 
 type IAdminService interface {
-	Settlement(ctx context.Context, req dto.SettlementReq) error
-	TestAutoMakeMarket(ctx context.Context) error
+	// Settlement credit user balance manually, recorded with the admin performed it.
+	Settlement(ctx context.Context, admin *dto.Admin, req dto.SettlementReq) error
+	// GetManualAdjustments latest manual adjustments, all users if userId is empty.
+	GetManualAdjustments(ctx context.Context, userId string, limit int) ([]*dto.ManualAdjustment, error)
+	TestAutoMakeMarket(ctx context.Context, admin *dto.Admin) error
+	GetWithdrawals(ctx context.Context, status dto.WithdrawalStatus) ([]*dto.Withdrawal, error)
+	GetWithdrawalAudits(ctx context.Context, withdrawalId string) ([]*dto.WithdrawalAudit, error)
+	ApproveWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error)
+	RejectWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error)
+	BroadcastWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error)
+	ConfirmWithdrawal(ctx context.Context, admin *dto.Admin, withdrawalId string, req *dto.ReviewWithdrawalReq) (*dto.Withdrawal, error)
+	AssignMarketMakerTier(ctx context.Context, admin *dto.Admin, req *dto.AssignMarketMakerTierReq) (*dto.User, error)
+	GetAccountStatus(ctx context.Context, userId string) (*dto.AccountStatusInfo, error)
+	// UpdateAccountStatus freeze, close or restrict account, optionally cancel its open orders.
+	UpdateAccountStatus(ctx context.Context, admin *dto.Admin, userId string, req *dto.UpdateAccountStatusReq) (*dto.AccountStatusInfo, error)
+	// GetFeeRevenues report fee income and maker rebates separately by asset.
+	GetFeeRevenues(ctx context.Context, from, to time.Time) ([]*dto.FeeRevenueSummary, error)
+	// GetLiquidations latest liquidations, all users if userId is empty.
+	GetLiquidations(ctx context.Context, userId string, limit int) ([]*dto.Liquidation, error)
+}
+
+type IAuditService interface {
+	GetAuditLogs(ctx context.Context, req *dto.AuditLogQueryReq) ([]*dto.AuditLog, error)
+	// VerifyChain recompute hash chain, result is invalid with first broken row if any row was modified, deleted or inserted.
+	VerifyChain(ctx context.Context) (*dto.AuditVerifyResult, error)
+}
+
+type IAdminAccountService interface {
+	// Login verify password and TOTP code, return admin token.
+	Login(ctx context.Context, req *dto.AdminLoginReq) (*dto.AdminSession, error)
+	Logout(ctx context.Context, token string) error
+	// CreateAdmin TOTP secret of admin is only returned here.
+	CreateAdmin(ctx context.Context, operator *dto.Admin, req *dto.CreateAdminReq) (*dto.Admin, error)
+	GetAdmins(ctx context.Context) ([]*dto.Admin, error)
+	// UpdateAdmin change role or disable admin, sessions of admin are logged out.
+	UpdateAdmin(ctx context.Context, operator *dto.Admin, adminId string, req *dto.UpdateAdminReq) (*dto.Admin, error)
+	// ChangePassword require old password and TOTP code, other sessions of admin are logged out.
+	ChangePassword(ctx context.Context, admin *dto.Admin, token string, req *dto.AdminChangePasswordReq) error
+	// RotateTotp require password and current TOTP code, new TOTP secret is only returned here.
+	RotateTotp(ctx context.Context, admin *dto.Admin, req *dto.AdminRotateTotpReq) (*dto.Admin, error)
+	// BootstrapSuperAdmin create superadmin if there is no admin, its password and TOTP secret are written to secretFile.
+	BootstrapSuperAdmin(ctx context.Context, secretFile string) error
+}
+
+type IWithdrawalService interface {
+	AddAddress(ctx context.Context, userId string, req *dto.AddWithdrawalAddressReq) (*dto.WithdrawalAddress, error)
+	GetAddresses(ctx context.Context, userId string) ([]*dto.WithdrawalAddress, error)
+	RemoveAddress(ctx context.Context, userId string, addressId int64) error
+	// Withdraw lock user funds and create PENDING withdrawal, auto approved if under manual approval threshold.
+	Withdraw(ctx context.Context, user *dto.User, req *dto.WithdrawReq) (*dto.Withdrawal, error)
+	GetWithdrawals(ctx context.Context, userId string) ([]*dto.Withdrawal, error)
+	GetWithdrawalsByStatus(ctx context.Context, status dto.WithdrawalStatus) ([]*dto.Withdrawal, error)
+	GetAudits(ctx context.Context, withdrawalId string) ([]*dto.WithdrawalAudit, error)
+	// Transit move withdrawal to next status and audit it, funds unlocked when REJECTED, deducted when CONFIRMED.
+	// auditLog of reviewing admin is appended in the same tx if not nil.
+	Transit(ctx context.Context, withdrawalId string, toStatus dto.WithdrawalStatus, operator string, req *dto.ReviewWithdrawalReq, auditLog *dto.AuditLog) (*dto.Withdrawal, error)
+}
+
+type ITransferService interface {
+	// Transfer move available balance from user to another user, idempotent by (fromUserId, idempotency_key).
+	Transfer(ctx context.Context, fromUserId string, req *dto.TransferReq) (*dto.Transfer, error)
+	GetTransfers(ctx context.Context, userId string) ([]*dto.Transfer, error)
+	CreateSubAccount(ctx context.Context, master *dto.User, req *dto.CreateSubAccountReq) (*dto.SubAccount, error)
+	GetSubAccounts(ctx context.Context, masterUserId string) ([]*dto.SubAccount, error)
+	// SubAccountTransfer master user move funds between itself and its sub-accounts.
+	SubAccountTransfer(ctx context.Context, masterUserId string, req *dto.SubAccountTransferReq) (*dto.Transfer, error)
+	GetConsolidatedBalances(ctx context.Context, masterUserId string) (*dto.ConsolidatedBalances, error)
+}
+
+type IPortfolioService interface {
+	// GetPnl realized pnl per market in period and unrealized pnl of current balances valued at market latest price.
+	GetPnl(ctx context.Context, userId string, req *dto.PnlQueryReq) (*dto.PnlResp, error)
+	// GetPortfolio value every asset of user in quote asset, latest price first and fallback to index price.
+	GetPortfolio(ctx context.Context, userId string, quote string) (*dto.Portfolio, error)
+	GetEquityHistory(ctx context.Context, userId string, days int) ([]*dto.EquitySnapshot, error)
+	// SnapshotAllEquities record all users total equity (USDT) of today (UTC).
+	SnapshotAllEquities(ctx context.Context) error
+	// GetUSDTPrices price in USDT of assets, return error if any asset has no price.
+	GetUSDTPrices(ctx context.Context, assets []string) (map[string]float64, error)
+}
+
+type IMarginService interface {
+	GetAccount(ctx context.Context, userId string) (*dto.MarginAccountInfo, error)
+	// Transfer move collateral between spot wallet and margin wallet (created at first transfer in).
+	Transfer(ctx context.Context, user *dto.User, req *dto.MarginTransferReq) (*dto.Transfer, error)
+	// Borrow asset from lending pool into margin wallet, rejected if margin ratio would be lower than initial ratio.
+	Borrow(ctx context.Context, userId string, req *dto.MarginLoanReq) (*dto.MarginLoanRecord, error)
+	// Repay loan from margin wallet available balance, interest first then principal.
+	Repay(ctx context.Context, userId string, req *dto.MarginLoanReq) (*dto.MarginLoanRecord, error)
+	GetLoanRecords(ctx context.Context, userId string, limit int) ([]*dto.MarginLoanRecord, error)
+	// AccrueInterest add hourly interest to all outstanding loans.
+	AccrueInterest(ctx context.Context) error
+}
+
+type ILiquidationService interface {
+	// CheckAll issue margin calls and liquidate margin accounts by margin ratio.
+	CheckAll(ctx context.Context) error
+	GetMarginCalls(ctx context.Context, userId string, limit int) ([]*dto.MarginCall, error)
+	// GetLiquidations latest liquidations, all users if userId is empty.
+	GetLiquidations(ctx context.Context, userId string, limit int) ([]*dto.Liquidation, error)
+}
+
+type IPerpetualService interface {
+	GetMarkets(ctx context.Context) ([]*dto.PerpetualMarket, error)
+	// GetPositions user positions valued at mark price.
+	GetPositions(ctx context.Context, userId string) ([]*dto.PerpetualPosition, error)
+	GetFundingRates(ctx context.Context, req *dto.FundingQueryReq) ([]*dto.FundingRate, error)
+	GetFundingPayments(ctx context.Context, userId string, req *dto.FundingQueryReq) ([]*dto.FundingPayment, error)
+	// SettleFunding record funding rates and settle funding payments of all perpetual positions.
+	SettleFunding(ctx context.Context) error
+	GetLiquidations(ctx context.Context, userId string, req *dto.FundingQueryReq) ([]*dto.PerpetualLiquidation, error)
+	// LiquidatePositions close positions under maintenance margin rate at mark price against insurance fund.
+	LiquidatePositions(ctx context.Context) error
+	// ResumeMarket lift halt of perpetual market, market is halted when insurance fund can not cover a payment.
+	ResumeMarket(ctx context.Context, admin *dto.Admin, market string) error
+}
+
+type IConvertService interface {
+	// Quote price convert off current order book depth, quote is held for settings.CONVERT_QUOTE_TTL.
+	Quote(ctx context.Context, user *dto.User, req *dto.ConvertQuoteReq) (*dto.ConvertQuote, error)
+	// Accept execute quote as IOC orders, refused if current order book can not deliver quoted amount.
+	// Route stopped by a leg not fully filled is not an error, it is returned with status PARTIAL.
+	Accept(ctx context.Context, user *dto.User, req *dto.ConvertAcceptReq) (*dto.Convert, error)
+	GetConverts(ctx context.Context, userId string, limit int) ([]*dto.Convert, error)
+}
+
+type IRfqService interface {
+	AddProvider(ctx context.Context, admin *dto.Admin, req *dto.AddRfqProviderReq) (*dto.RfqProvider, error)
+	RemoveProvider(ctx context.Context, admin *dto.Admin, userId string) error
+	GetProviders(ctx context.Context) ([]*dto.RfqProvider, error)
+	// CreateRequest open request for quotes, expires after settings.RFQ_REQUEST_TTL.
+	CreateRequest(ctx context.Context, userId string, req *dto.RfqRequestReq) (*dto.RfqRequest, error)
+	GetRequest(ctx context.Context, userId, requestId string) (*dto.RfqRequest, error)
+	GetRequests(ctx context.Context, userId string, limit int) ([]*dto.RfqRequest, error)
+	CancelRequest(ctx context.Context, userId, requestId string) (*dto.RfqRequest, error)
+	// Accept settle request with quote bilaterally as block trade, order books are not touched.
+	Accept(ctx context.Context, user *dto.User, requestId string, req *dto.RfqAcceptReq) (*dto.RfqRequest, error)
+	// GetOpenRequests open requests for liquidity providers.
+	GetOpenRequests(ctx context.Context, userId string) ([]*dto.RfqRequest, error)
+	SubmitQuote(ctx context.Context, userId, requestId string, req *dto.RfqQuoteReq) (*dto.RfqQuote, error)
+	CancelQuote(ctx context.Context, userId, quoteId string) (*dto.RfqQuote, error)
+	GetQuotes(ctx context.Context, userId string, limit int) ([]*dto.RfqQuote, error)
+	// ExpireRequests expire open requests over ttl and release their quotes.
+	ExpireRequests(ctx context.Context) error
+}
+
+type IStatementService interface {
+	// Validate check statement request before response starts streaming.
+	Validate(req *dto.StatementReq) error
+	// Export write user statement to w page by page (newest first), flush w after each page if it supports.
+	Export(ctx context.Context, userId string, req *dto.StatementReq, w io.Writer) error
+}
+
+type IReferralService interface {
+	// GetReferralInfo return user referral code (create if not exists) and referees count.
+	GetReferralInfo(ctx context.Context, userId string) (*dto.ReferralInfo, error)
+	GetCommissions(ctx context.Context, userId string, limit int) ([]*dto.ReferralCommission, error)
+	GetCommissionTotals(ctx context.Context, userId string) ([]*dto.ReferralCommissionTotal, error)
+}
+
+type IFeeTierService interface {
+	GetFeeSchedules(ctx context.Context) ([]*dto.FeeSchedule, error)
+	// RecalculateAll update all users vip level and fee rates by rolling 30-day quote volume.
+	RecalculateAll(ctx context.Context) error
+	GetTierProgress(ctx context.Context, user *dto.User) (*dto.FeeTierProgress, error)
+	GetVipLevelHistories(ctx context.Context, userId string) ([]*dto.VipLevelHistory, error)
+	// AssignMarketMakerTier set user to designated market maker tier (settings.MARKET_MAKER_FEE_TIERS),
+	// auditLog of assigning admin is appended in the same tx with fee rates before and after.
+	AssignMarketMakerTier(ctx context.Context, username string, vipLevel int, auditLog *dto.AuditLog) (*dto.User, error)
 }
 
 // Auto Market Maker (AMM) etc. >>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>>
@@ -53,6 +265,8 @@ type IAutoMarketMakerService interface {
 }
 
 type IPriceIndexService interface {
+	// GetIndexPrice external index price of market, e.g. "ETH-USDT".
+	GetIndexPrice(ctx context.Context, market string) (float64, error)
 }
 
 // Markets
@@ -61,4 +275,6 @@ type IMarketDataService interface {
 	GetAllMarketData() ([]dto.MarketData, error)
 	GetMarketData(market string) (dto.MarketData, error)
 	GetOHLCVHistory(ctx context.Context, req *ohlcv.GetOhlcvDataReq) (*ohlcv.OHLCV, error)
+	// GetRecentTrades latest trades of market, newest first.
+	GetRecentTrades(ctx context.Context, market string, limit int) ([]*dto.MarketTrade, error)
 }
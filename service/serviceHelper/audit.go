@@ -0,0 +1,29 @@
+package serviceHelper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/johnny1110/crypto-exchange/dto"
+)
+
+// AuditLogHash sha256 of PrevHash and all fields except ID and Hash, fields are JSON encoded so values can not be
+// shifted between fields. created_at is hashed in milliseconds, precision kept by database.
+func AuditLogHash(auditLog *dto.AuditLog) string {
+	payload, _ := json.Marshal([]any{
+		auditLog.PrevHash,
+		auditLog.ActorType,
+		auditLog.ActorID,
+		auditLog.ActorName,
+		auditLog.Action,
+		auditLog.Target,
+		auditLog.ClientIP,
+		auditLog.UserAgent,
+		auditLog.RequestID,
+		auditLog.Before,
+		auditLog.After,
+		auditLog.CreatedAt.UnixMilli(),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,98 @@
+package serviceHelper
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/utils"
+)
+
+// PriceConvertAskLeg sell size into bids (best first), limit price is the worst bid level reached.
+// ok is false if snapshot depth can not fill the size.
+func PriceConvertAskLeg(market, baseAsset, quoteAsset string, bids []*book.PriceVolumePair, size, feeRate float64) (leg *dto.ConvertLeg, ok bool) {
+	remaining, received, worstPrice := size, 0.0, 0.0
+	for _, level := range bids {
+		if remaining <= utils.Scale {
+			break
+		}
+		dealt := min(remaining, level.Volume)
+		received += dealt * level.Price
+		remaining -= dealt
+		worstPrice = level.Price
+	}
+	if remaining > utils.Scale {
+		return nil, false
+	}
+
+	return &dto.ConvertLeg{
+		Market:     market,
+		Side:       model.ASK,
+		FromAsset:  baseAsset,
+		ToAsset:    quoteAsset,
+		Price:      worstPrice,
+		Size:       size,
+		FromAmount: size,
+		ToAmount:   utils.FloorFloat(received * (1 - feeRate)),
+	}, true
+}
+
+// PriceConvertBidLeg spend quoteAmount on asks (best first), limit price is the worst ask level reached and size is
+// quoteAmount / limit price, so locked quote never exceeds quoteAmount. Quote saved by better levels stays unspent.
+// ok is false if snapshot depth can not absorb the quote amount.
+func PriceConvertBidLeg(market, baseAsset, quoteAsset string, asks []*book.PriceVolumePair, quoteAmount, feeRate float64) (leg *dto.ConvertLeg, ok bool) {
+	remaining, worstPrice := quoteAmount, 0.0
+	for _, level := range asks {
+		if remaining <= utils.Scale {
+			break
+		}
+		remaining -= min(remaining, level.Volume*level.Price)
+		worstPrice = level.Price
+	}
+	if remaining > utils.Scale || worstPrice <= 0 {
+		return nil, false
+	}
+
+	size := utils.FloorFloat(quoteAmount / worstPrice)
+	return &dto.ConvertLeg{
+		Market:     market,
+		Side:       model.BID,
+		FromAsset:  quoteAsset,
+		ToAsset:    baseAsset,
+		Price:      worstPrice,
+		Size:       size,
+		FromAmount: walkAsksCost(asks, size),
+		ToAmount:   utils.FloorFloat(size * (1 - feeRate)),
+	}, true
+}
+
+// ConvertLegReceived to asset amount received by IOC order of leg, fees are deducted if paid in the to asset
+// (fee token users pay fees in fee token instead).
+func ConvertLegReceived(leg *dto.ConvertLeg, result *dto.PlaceOrderResult) (spent, received float64) {
+	for _, match := range result.Matches {
+		switch leg.Side {
+		case model.ASK:
+			spent += match.Size
+			received += match.Size * match.Price
+		case model.BID:
+			spent += match.Size * match.Price
+			received += match.Size
+		}
+	}
+	if result.Order.FeeAsset == leg.ToAsset {
+		received *= 1 - result.Order.FeeRate
+	}
+	return utils.RoundFloat(spent), utils.FloorFloat(received)
+}
+
+func walkAsksCost(asks []*book.PriceVolumePair, size float64) float64 {
+	remaining, cost := size, 0.0
+	for _, level := range asks {
+		if remaining <= utils.Scale {
+			break
+		}
+		dealt := min(remaining, level.Volume)
+		cost += dealt * level.Price
+		remaining -= dealt
+	}
+	return utils.RoundFloat(cost)
+}
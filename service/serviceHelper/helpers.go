@@ -5,10 +5,24 @@ import (
 	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/engine-v2/book"
 	"github.com/johnny1110/crypto-exchange/engine-v2/core"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
 	"github.com/johnny1110/crypto-exchange/engine-v2/model"
 	"github.com/johnny1110/crypto-exchange/utils"
 )
 
+// GetMarketInfo market info of order book
+func GetMarketInfo(engine *core.MatchingEngine, marketName string) (*market.MarketInfo, error) {
+	if engine == nil {
+		return nil, fmt.Errorf("engine cannot be nil")
+	}
+
+	orderBook, err := engine.GetOrderBook(marketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order book for market %s: %w", marketName, err)
+	}
+	return orderBook.MarketInfo(), nil
+}
+
 // ParseMarket extracts base and quote assets.html from market
 func ParseMarket(engine *core.MatchingEngine, market string) (string, string, error) {
 	if engine == nil {
@@ -113,10 +127,16 @@ func CalculateRefund(engine *core.MatchingEngine, market string, engineOrder *mo
 		return "", 0, fmt.Errorf("engine and engineOrder cannot be nil")
 	}
 
-	baseAsset, quoteAsset, err := ParseMarket(engine, market)
+	marketInfo, err := GetMarketInfo(engine, market)
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to parse market: %w", err)
 	}
+	baseAsset, quoteAsset := marketInfo.BaseAsset, marketInfo.QuoteAsset
+
+	// perpetual orders lock quote asset of both sides
+	if marketInfo.IsPerpetual() {
+		return quoteAsset, utils.RoundFloat(engineOrder.Price * engineOrder.RemainingSize * marketInfo.ContractSize * PerpetualLockRate(engineOrder.FeeRate)), nil
+	}
 
 	switch engineOrder.Side {
 	case model.BID:
@@ -148,13 +168,20 @@ func WrapPlaceOrderResult(orderDto *dto.Order, trades []book.Trade) *dto.PlaceOr
 	}
 }
 
+// DetermineFeeInfo fee rate by liquidity role of order, only LIMIT/MAKER (post-only) order is maker.
+// mode of MARKET order is ignored, it always takes liquidity and must not get maker rebate.
 func DetermineFeeInfo(req *dto.OrderReq, user *dto.User, baseAsset string, quoteAsset string) (feeAsset string, feeRate float64) {
-	switch req.Mode {
+	mode := req.Mode
+	if req.OrderType == model.MARKET {
+		mode = model.TAKER
+	}
+	switch mode {
 	case model.MAKER:
 		feeRate = user.MakerFee
 		break
 	case model.TAKER:
-		feeRate = user.TakerFee
+		// only maker can get rebate.
+		feeRate = max(user.TakerFee, 0)
 		break
 	default:
 		panic("Unknown order mode")
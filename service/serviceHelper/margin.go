@@ -0,0 +1,55 @@
+package serviceHelper
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"time"
+)
+
+// CalculateMarginRisk value margin wallet balances (available + locked) and loans (principal + interest) by USDT prices.
+func CalculateMarginRisk(balances []*dto.Balance, loans []*dto.MarginLoan, prices map[string]float64) *dto.MarginRisk {
+	risk := &dto.MarginRisk{}
+	for _, balance := range balances {
+		risk.AssetValue += (balance.Available + balance.Locked) * prices[balance.Asset]
+	}
+	for _, loan := range loans {
+		risk.DebtValue += (loan.Principal + loan.Interest) * prices[loan.Asset]
+	}
+	risk.AssetValue = utils.RoundFloat(risk.AssetValue)
+	risk.DebtValue = utils.RoundFloat(risk.DebtValue)
+	risk.Equity = utils.RoundFloat(risk.AssetValue - risk.DebtValue)
+	if risk.AssetValue > 0 {
+		risk.MarginRatio = utils.RoundFloat(risk.Equity / risk.AssetValue)
+	}
+	if risk.Equity > 0 {
+		risk.Leverage = utils.RoundFloat(risk.AssetValue / risk.Equity)
+	}
+	return risk
+}
+
+// MarginRatioAfter margin ratio after asset value and debt value changed (USDT), 0 if no asset left.
+func MarginRatioAfter(risk *dto.MarginRisk, assetValueChanging, debtValueChanging float64) float64 {
+	assetValue := risk.AssetValue + assetValueChanging
+	if assetValue <= 0 {
+		return 0
+	}
+	return (assetValue - risk.DebtValue - debtValueChanging) / assetValue
+}
+
+// AccrueMarginInterest interest of loan principal for full settings.MARGIN_INTEREST_INTERVAL periods elapsed since
+// loan accrued at, periods missed (e.g. server stopped) are caught up. Return interest and new accrued at advanced by
+// the periods, interest is 0 if no full period elapsed.
+func AccrueMarginInterest(loan *dto.MarginLoan, now time.Time) (float64, time.Time) {
+	periods := now.Sub(loan.AccruedAt) / settings.MARGIN_INTEREST_INTERVAL
+	if periods <= 0 {
+		return 0, loan.AccruedAt
+	}
+	interest := loan.Principal * settings.GetMarginHourlyInterestRate(loan.Asset) * float64(periods)
+	return utils.RoundFloat(interest), loan.AccruedAt.Add(periods * settings.MARGIN_INTEREST_INTERVAL)
+}
+
+// MaxBorrowableValue max value (USDT) can be borrowed keeping settings.MARGIN_INITIAL_RATIO.
+func MaxBorrowableValue(risk *dto.MarginRisk) float64 {
+	return utils.RoundFloat(max(risk.Equity/settings.MARGIN_INITIAL_RATIO-risk.AssetValue, 0))
+}
@@ -0,0 +1,359 @@
+package serviceHelper
+
+import (
+	"fmt"
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"math"
+	"sort"
+	"time"
+)
+
+// PerpetualLockRate quote asset locked per notional of perpetual order: initial margin and fee (rebates are not locked).
+func PerpetualLockRate(feeRate float64) float64 {
+	return settings.PERPETUAL_INITIAL_MARGIN_RATE + max(feeRate, 0)
+}
+
+// PerpetualFee fee of a perpetual fill in quote asset, negative is rebate.
+func PerpetualFee(price, size, contractSize, feeRate float64) float64 {
+	return utils.RoundFloat(price * size * contractSize * feeRate)
+}
+
+// DeterminePerpetualFreezeValue perpetual orders always lock quote asset, market ask order is estimated at best bid price
+// (it only fills at or below best bid).
+func DeterminePerpetualFreezeValue(req *dto.OrderReq, marketInfo *market.MarketInfo, feeRate, bestBidPrice float64) (string, float64) {
+	lockRate := PerpetualLockRate(feeRate)
+	switch {
+	case req.OrderType == model.LIMIT:
+		return marketInfo.QuoteAsset, utils.RoundFloat(req.Price * req.Size * marketInfo.ContractSize * lockRate)
+	case req.Side == model.BID:
+		return marketInfo.QuoteAsset, utils.RoundFloat(req.QuoteAmount * marketInfo.ContractSize * lockRate)
+	default:
+		return marketInfo.QuoteAsset, utils.RoundFloat(bestBidPrice * req.Size * marketInfo.ContractSize * lockRate)
+	}
+}
+
+// PerpetualSettlementData quote asset changes of a user in perpetual settlement.
+type PerpetualSettlementData struct {
+	Available float64
+	Locked    float64
+}
+
+// PerpetualSettlementResult perpetual fills never deliver base asset, fills open or close positions and realized pnl
+// is settled in quote asset against the insurance fund.
+type PerpetualSettlementResult struct {
+	QuoteAsset      string
+	OrderUpdates    []*OrderUpdateData
+	UserSettlements map[string]*PerpetualSettlementData
+	Positions       map[string]*dto.PerpetualPosition // positions of users involved, by user id
+	RealizedPnls    []*dto.RealizedPnl
+	TotalDealtAmt   float64
+	TotalDealtSize  float64
+	TotalFees       float64 // fee income, add to settings margin account balances
+	TotalRebates    float64 // maker rebates (negative fee rate), paid by settings margin account
+	NetPnl          float64 // realized pnl paid to users (negative: collected from users) by insurance fund
+	BadDebt         float64 // losses exceeded released position margin, absorbed by insurance fund
+
+	market       string
+	contractSize float64
+	eatenFees    float64
+}
+
+// ProcessPerpetualSettlement settle fills of eaten order, positions are current positions of users involved
+// (missing users have no position yet).
+func ProcessPerpetualSettlement(ctx *dto.PlaceOrderContext, positions map[string]*dto.PerpetualPosition) (*PerpetualSettlementResult, error) {
+	eatenOrder := ctx.OrderDTO
+	if eatenOrder == nil {
+		return nil, fmt.Errorf("eaten order cannot be nil")
+	}
+	if ctx.Perpetual == nil {
+		return nil, fmt.Errorf("market %s is not perpetual", ctx.Market)
+	}
+
+	result := &PerpetualSettlementResult{
+		QuoteAsset:      ctx.Perpetual.QuoteAsset,
+		OrderUpdates:    make([]*OrderUpdateData, 0, len(ctx.Trades)+1),
+		UserSettlements: make(map[string]*PerpetualSettlementData),
+		Positions:       make(map[string]*dto.PerpetualPosition),
+		market:          ctx.Market,
+		contractSize:    ctx.Perpetual.ContractSize,
+	}
+	for userId := range extractUniqueUserIds(ctx.Trades) {
+		result.UserSettlements[userId] = &PerpetualSettlementData{}
+		position, ok := positions[userId]
+		if !ok {
+			position = &dto.PerpetualPosition{UserID: userId, Market: ctx.Market}
+		}
+		result.Positions[userId] = position
+	}
+
+	for _, trade := range ctx.Trades {
+		result.processIndividualTrade(trade, eatenOrder, ctx.FeeRate)
+	}
+	result.NetPnl = utils.RoundFloat(result.NetPnl)
+	result.BadDebt = utils.RoundFloat(result.BadDebt)
+
+	// market order lock is estimated, release all of it after fills settled
+	if eatenOrder.Type == model.MARKET {
+		settlement := result.UserSettlements[eatenOrder.UserID]
+		settlement.Locked = utils.RoundFloat(settlement.Locked - ctx.Assets.FreezeAmt)
+		settlement.Available = utils.RoundFloat(settlement.Available + ctx.Assets.FreezeAmt)
+	}
+
+	result.addEatenOrderUpdate(eatenOrder)
+
+	if result.TotalDealtSize > 0 {
+		eatenOrder.AvgDealtPrice = result.TotalDealtAmt / result.TotalDealtSize
+		eatenOrder.QuoteAmount = result.TotalDealtAmt
+	}
+
+	return result, nil
+}
+
+func (r *PerpetualSettlementResult) processIndividualTrade(trade book.Trade, eatenOrder *dto.Order, eatenFeeRate float64) {
+	tradeQuoteAmount := trade.Price * trade.Size
+	r.TotalDealtAmt += tradeQuoteAmount
+	r.TotalDealtSize += trade.Size
+
+	bidFees := r.settleFill(trade, model.BID, trade.BidUserID, trade.BidFeeRate, eatenOrder, eatenFeeRate)
+	askFees := r.settleFill(trade, model.ASK, trade.AskUserID, trade.AskFeeRate, eatenOrder, eatenFeeRate)
+
+	oppositeOrderId, oppositeUserId, oppositeFees := trade.AskOrderID, trade.AskUserID, askFees
+	if eatenOrder.Side == model.ASK {
+		oppositeOrderId, oppositeUserId, oppositeFees = trade.BidOrderID, trade.BidUserID, bidFees
+	}
+	r.OrderUpdates = append(r.OrderUpdates, &OrderUpdateData{
+		OrderID:                    oppositeOrderId,
+		UserID:                     oppositeUserId,
+		FeeAsset:                   r.QuoteAsset,
+		RemainingSizeDecreasing:    utils.RoundFloat(trade.Size),
+		DealtQuoteAmountIncreasing: utils.RoundFloat(tradeQuoteAmount),
+		FeesIncreasing:             oppositeFees,
+	})
+}
+
+// settleFill apply one side of trade to user position and quote balance, return fees of the side.
+func (r *PerpetualSettlementResult) settleFill(trade book.Trade, side model.Side, userId string, feeRate float64, eatenOrder *dto.Order, eatenFeeRate float64) float64 {
+	settlement := r.UserSettlements[userId]
+	position := r.Positions[userId]
+
+	// lock of maker order is at trade price, lock of eaten limit order is at order price,
+	// lock of eaten market order is released after all fills.
+	var unlock float64
+	lockPrice := trade.Price
+	if side != eatenOrder.Side {
+		unlock = trade.Price * trade.Size * r.contractSize * PerpetualLockRate(feeRate)
+	} else if eatenOrder.Type == model.LIMIT {
+		lockPrice = eatenOrder.Price
+		unlock = eatenOrder.Price * trade.Size * r.contractSize * PerpetualLockRate(eatenFeeRate)
+	}
+
+	size := trade.Size
+	if side == model.ASK {
+		size = -size
+	}
+	fill := ApplyPerpetualFill(position, size, trade.Price, min(trade.Price, lockPrice), r.contractSize)
+
+	// loss exceeding released margin is not charged from user balance.
+	pnl := fill.Pnl
+	if pnl < -fill.ReleasedMargin {
+		r.BadDebt += -fill.ReleasedMargin - pnl
+		pnl = -fill.ReleasedMargin
+	}
+	pnl = utils.RoundFloat(pnl)
+	position.RealizedPnl = utils.RoundFloat(position.RealizedPnl + pnl)
+	r.NetPnl += pnl
+
+	fees := PerpetualFee(trade.Price, trade.Size, r.contractSize, feeRate)
+	if fees >= 0 {
+		r.TotalFees += fees
+	} else {
+		r.TotalRebates -= fees
+	}
+	if side == eatenOrder.Side {
+		r.eatenFees += fees
+	}
+
+	settlement.Available = utils.RoundFloat(settlement.Available + unlock - fill.OpenMargin + fill.ReleasedMargin + pnl - fees)
+	settlement.Locked = utils.RoundFloat(settlement.Locked - unlock + fill.OpenMargin - fill.ReleasedMargin)
+
+	if fill.ClosedSize > 0 {
+		r.RealizedPnls = append(r.RealizedPnls, &dto.RealizedPnl{
+			UserID:   userId,
+			Market:   r.market,
+			Asset:    r.QuoteAsset,
+			Size:     fill.ClosedSize,
+			Proceeds: fill.Proceeds,
+			Cost:     utils.RoundFloat(fill.Proceeds - pnl),
+			Pnl:      pnl,
+		})
+	}
+
+	return fees
+}
+
+func (r *PerpetualSettlementResult) addEatenOrderUpdate(eatenOrder *dto.Order) {
+	update := &OrderUpdateData{
+		OrderID:        eatenOrder.ID,
+		UserID:         eatenOrder.UserID,
+		FeeAsset:       r.QuoteAsset,
+		FeesIncreasing: utils.RoundFloat(r.eatenFees),
+	}
+	// Market bid orders don't need size/amount updates as they're already processed
+	if eatenOrder.Type != model.MARKET || eatenOrder.Side != model.BID {
+		update.RemainingSizeDecreasing = utils.RoundFloat(r.TotalDealtSize)
+		update.DealtQuoteAmountIncreasing = utils.RoundFloat(r.TotalDealtAmt)
+	}
+	eatenOrder.Fees += update.FeesIncreasing
+
+	r.OrderUpdates = append(r.OrderUpdates, update)
+}
+
+// PerpetualFillResult position changes of one fill, amounts in quote asset.
+type PerpetualFillResult struct {
+	ClosedSize     float64 // contracts reducing existing position
+	Proceeds       float64 // notional of closed contracts, long: at fill price, short: at entry price
+	Pnl            float64 // realized pnl of closed contracts
+	ReleasedMargin float64 // position margin released by closed contracts
+	OpenMargin     float64 // margin of contracts opened, moved from order lock into position
+}
+
+// ApplyPerpetualFill apply signed size (long: positive, short: negative) filled at price to position, closing
+// existing position first then opening the rest with new margin at marginPrice.
+func ApplyPerpetualFill(position *dto.PerpetualPosition, size, price, marginPrice, contractSize float64) *PerpetualFillResult {
+	result := &PerpetualFillResult{}
+
+	if position.Size != 0 && (position.Size > 0) != (size > 0) {
+		direction := 1.0
+		if position.Size < 0 {
+			direction = -1.0
+		}
+		closing := min(math.Abs(size), math.Abs(position.Size))
+
+		result.ClosedSize = utils.RoundFloat(closing)
+		result.Pnl = closing * contractSize * (price - position.EntryPrice) * direction
+		result.Proceeds = closing * contractSize * price
+		if direction < 0 {
+			result.Proceeds = closing * contractSize * position.EntryPrice
+		}
+		result.ReleasedMargin = position.Margin * closing / math.Abs(position.Size)
+
+		position.Size = utils.RoundFloat(position.Size - direction*closing)
+		position.Margin = utils.RoundFloat(position.Margin - result.ReleasedMargin)
+		size += direction * closing
+		if position.Size == 0 {
+			result.ReleasedMargin += position.Margin
+			position.Margin = 0
+			position.EntryPrice = 0
+		}
+	}
+
+	if math.Abs(size) > utils.Scale {
+		opening := math.Abs(size)
+		result.OpenMargin = utils.RoundFloat(opening * contractSize * marginPrice * settings.PERPETUAL_INITIAL_MARGIN_RATE)
+		position.EntryPrice = utils.RoundFloat((math.Abs(position.Size)*position.EntryPrice + opening*price) / (math.Abs(position.Size) + opening))
+		position.Size = utils.RoundFloat(position.Size + size)
+		position.Margin = utils.RoundFloat(position.Margin + result.OpenMargin)
+	}
+
+	result.Proceeds = utils.RoundFloat(result.Proceeds)
+	result.ReleasedMargin = utils.RoundFloat(result.ReleasedMargin)
+	return result
+}
+
+// CalculatePerpetualRebates sum maker rebates will be paid for perpetual trades in quote asset.
+func CalculatePerpetualRebates(trades []book.Trade, contractSize float64) (rebates float64) {
+	for _, trade := range trades {
+		if trade.BidFeeRate < 0 {
+			rebates -= trade.Price * trade.Size * contractSize * trade.BidFeeRate
+		}
+		if trade.AskFeeRate < 0 {
+			rebates -= trade.Price * trade.Size * contractSize * trade.AskFeeRate
+		}
+	}
+	return rebates
+}
+
+// TradeUserIds unique users of both sides of trades.
+func TradeUserIds(trades []book.Trade) []string {
+	userIds := make([]string, 0, len(trades)*2)
+	for userId := range extractUniqueUserIds(trades) {
+		userIds = append(userIds, userId)
+	}
+	return userIds
+}
+
+// ValuePerpetualPosition fill query-time fields of position by mark price.
+func ValuePerpetualPosition(position *dto.PerpetualPosition, markPrice, contractSize float64) {
+	position.MarkPrice = markPrice
+	position.Notional = utils.RoundFloat(math.Abs(position.Size) * contractSize * markPrice)
+	position.UnrealizedPnl = utils.RoundFloat(position.Size * contractSize * (markPrice - position.EntryPrice))
+	position.MarginRatio = 0
+	if position.Notional > 0 {
+		position.MarginRatio = utils.RoundFloat((position.Margin + position.UnrealizedPnl) / position.Notional)
+	}
+}
+
+// IsPerpetualPositionAtRisk position valued by ValuePerpetualPosition is under maintenance margin rate.
+func IsPerpetualPositionAtRisk(position *dto.PerpetualPosition) bool {
+	return position.Size != 0 && position.MarginRatio < settings.PERPETUAL_MAINTENANCE_MARGIN_RATE
+}
+
+// LiquidatePerpetualPosition close whole position at mark price, loss exceeding position margin is bad debt.
+// User gets Margin + Pnl into available balance and Margin out of locked balance, insurance fund pays Pnl.
+func LiquidatePerpetualPosition(position *dto.PerpetualPosition, markPrice, contractSize float64) (*dto.PerpetualLiquidation, *PerpetualFillResult) {
+	liquidation := &dto.PerpetualLiquidation{
+		UserID:     position.UserID,
+		Market:     position.Market,
+		Size:       position.Size,
+		EntryPrice: position.EntryPrice,
+		MarkPrice:  markPrice,
+	}
+
+	fill := ApplyPerpetualFill(position, -position.Size, markPrice, markPrice, contractSize)
+	pnl := fill.Pnl
+	if pnl < -fill.ReleasedMargin {
+		liquidation.BadDebt = utils.RoundFloat(-fill.ReleasedMargin - pnl)
+		pnl = -fill.ReleasedMargin
+	}
+	liquidation.Margin = fill.ReleasedMargin
+	liquidation.Pnl = utils.RoundFloat(pnl)
+	position.RealizedPnl = utils.RoundFloat(position.RealizedPnl + liquidation.Pnl)
+	return liquidation, fill
+}
+
+// MarkPrice median of best bid, best ask and latest price, fallback to index price if order book is not two-sided
+// or never traded.
+func MarkPrice(snapshot *book.BookSnapshot, indexPrice float64) float64 {
+	if snapshot == nil || snapshot.BestBidPrice <= 0 || snapshot.BestAskPrice <= 0 || snapshot.LatestPrice <= 0 {
+		return indexPrice
+	}
+	prices := []float64{snapshot.BestBidPrice, snapshot.BestAskPrice, snapshot.LatestPrice}
+	sort.Float64s(prices)
+	return prices[1]
+}
+
+// CalculateFundingRate premium of mark price over index price clamped in ±settings.PERPETUAL_MAX_FUNDING_RATE,
+// positive rate: longs pay shorts.
+func CalculateFundingRate(markPrice, indexPrice float64) float64 {
+	if indexPrice <= 0 {
+		return 0
+	}
+	rate := (markPrice - indexPrice) / indexPrice
+	rate = max(min(rate, settings.PERPETUAL_MAX_FUNDING_RATE), -settings.PERPETUAL_MAX_FUNDING_RATE)
+	return utils.RoundFloat(rate)
+}
+
+// CalculateFundingPayment funding of position in quote asset, positive is received, negative is paid.
+func CalculateFundingPayment(size, contractSize, markPrice, fundingRate float64) float64 {
+	return utils.RoundFloat(-size * contractSize * markPrice * fundingRate)
+}
+
+// NextFundingTime funding is settled every settings.PERPETUAL_FUNDING_INTERVAL aligned to 00:00 UTC.
+func NextFundingTime(now time.Time) time.Time {
+	return now.UTC().Truncate(settings.PERPETUAL_FUNDING_INTERVAL).Add(settings.PERPETUAL_FUNDING_INTERVAL)
+}
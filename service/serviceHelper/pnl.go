@@ -0,0 +1,27 @@
+package serviceHelper
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/utils"
+)
+
+// ApplyPositionChange update average cost basis by position change (buys applied before sells) and return
+// cost of sold size. Sold size exceeding tracked quantity (e.g. asset from transfers) is also costed at average cost.
+func ApplyPositionChange(basis *dto.CostBasis, change *PositionChangeData) (soldCost float64) {
+	if change.BoughtSize > 0 {
+		totalCost := basis.Quantity*basis.AvgCost + change.BoughtCost
+		basis.Quantity += change.BoughtSize
+		basis.AvgCost = utils.RoundFloat(totalCost / basis.Quantity)
+		basis.Quantity = utils.RoundFloat(basis.Quantity)
+	}
+
+	if change.SoldSize > 0 {
+		soldCost = utils.RoundFloat(change.SoldSize * basis.AvgCost)
+		basis.Quantity = utils.RoundFloat(max(basis.Quantity-change.SoldSize, 0))
+		if basis.Quantity == 0 {
+			basis.AvgCost = 0
+		}
+	}
+
+	return soldCost
+}
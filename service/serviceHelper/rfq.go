@@ -0,0 +1,87 @@
+package serviceHelper
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/utils"
+	"time"
+)
+
+// RfqBalanceChange available and locked changing of user asset.
+type RfqBalanceChange struct {
+	UserID    string
+	Asset     string
+	Available float64
+	Locked    float64
+}
+
+// RfqSettlementResult bilateral settlement of accepted RFQ quote. Taker pays from available balance (PaidAsset/PaidAmount),
+// provider pays from funds locked by the quote, taker fee is deducted from the asset taker receives and providers pay no fee.
+type RfqSettlementResult struct {
+	Trade           book.Trade
+	BidFee          float64 // base asset
+	AskFee          float64 // quote asset
+	FeeAsset        string
+	Fee             float64
+	PaidAsset       string
+	PaidAmount      float64
+	BalanceChanges  []*RfqBalanceChange
+	PositionChanges map[string]*PositionChangeData
+}
+
+// RfqProviderLock asset and amount provider locks for firm quote, sells base asset for taker bid, buys with quote asset for taker ask.
+func RfqProviderLock(request *dto.RfqRequest, info *market.MarketInfo, price float64) (asset string, amount float64) {
+	if request.Side == model.BID {
+		return info.BaseAsset, request.Size
+	}
+	return info.QuoteAsset, utils.RoundFloat(price * request.Size)
+}
+
+// ProcessRfqSettlement settle request with accepted quote as a block trade, request id and quote id are used as order ids.
+func ProcessRfqSettlement(request *dto.RfqRequest, quote *dto.RfqQuote, info *market.MarketInfo, takerFeeRate float64) *RfqSettlementResult {
+	size, value := request.Size, utils.RoundFloat(quote.Price*request.Size)
+	result := &RfqSettlementResult{
+		Trade: book.Trade{
+			Market:     request.Market,
+			Price:      quote.Price,
+			Size:       size,
+			TradeValue: value,
+			Timestamp:  time.Now(),
+		},
+		PositionChanges: make(map[string]*PositionChangeData),
+	}
+
+	if request.Side == model.BID {
+		result.Trade.BidOrderID, result.Trade.BidUserID, result.Trade.BidFeeRate = request.ID, request.UserID, takerFeeRate
+		result.Trade.AskOrderID, result.Trade.AskUserID = quote.ID, quote.ProviderID
+		result.BidFee = utils.RoundFloat(size * takerFeeRate)
+		result.FeeAsset, result.Fee = info.BaseAsset, result.BidFee
+		result.PaidAsset, result.PaidAmount = info.QuoteAsset, value
+
+		result.BalanceChanges = []*RfqBalanceChange{
+			{UserID: request.UserID, Asset: info.BaseAsset, Available: utils.RoundFloat(size - result.BidFee)},
+			{UserID: quote.ProviderID, Asset: info.BaseAsset, Locked: -size},
+			{UserID: quote.ProviderID, Asset: info.QuoteAsset, Available: value},
+		}
+		result.PositionChanges[request.UserID] = &PositionChangeData{BoughtSize: size - result.BidFee, BoughtCost: value}
+		result.PositionChanges[quote.ProviderID] = &PositionChangeData{SoldSize: size, SoldProceeds: value}
+		return result
+	}
+
+	result.Trade.AskOrderID, result.Trade.AskUserID, result.Trade.AskFeeRate = request.ID, request.UserID, takerFeeRate
+	result.Trade.BidOrderID, result.Trade.BidUserID = quote.ID, quote.ProviderID
+	result.AskFee = utils.RoundFloat(value * takerFeeRate)
+	result.FeeAsset, result.Fee = info.QuoteAsset, result.AskFee
+	result.PaidAsset, result.PaidAmount = info.BaseAsset, size
+
+	result.BalanceChanges = []*RfqBalanceChange{
+		{UserID: request.UserID, Asset: info.QuoteAsset, Available: utils.RoundFloat(value - result.AskFee)},
+		{UserID: quote.ProviderID, Asset: info.QuoteAsset, Locked: -value},
+		{UserID: quote.ProviderID, Asset: info.BaseAsset, Available: size},
+	}
+	result.PositionChanges[request.UserID] = &PositionChangeData{SoldSize: size, SoldProceeds: value - result.AskFee}
+	result.PositionChanges[quote.ProviderID] = &PositionChangeData{BoughtSize: size, BoughtCost: value}
+	return result
+}
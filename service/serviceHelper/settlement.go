@@ -11,9 +11,16 @@ import (
 // OrderUpdateData represents data needed to update a dealt order
 type OrderUpdateData struct {
 	OrderID                    string
+	UserID                     string
+	FeeAsset                   string // asset of FeesIncreasing
 	RemainingSizeDecreasing    float64
 	DealtQuoteAmountIncreasing float64
 	FeesIncreasing             float64
+	// SwitchFeeAsset not empty if order fee asset switched (fee token fallback), ConvertedFees is previous fees in new asset.
+	SwitchFeeAsset string
+	ConvertedFees  float64
+	// FeeTokenRate fee token per normal asset fee unit, not zero if FeesIncreasing paid in fee token.
+	FeeTokenRate float64
 }
 
 // UserSettlementData represents settlement data for a user's assets.html
@@ -24,16 +31,28 @@ type UserSettlementData struct {
 	QuoteAssetLocked    float64
 }
 
+// PositionChangeData represents base asset bought/sold by a user in one settlement, amounts are in quote asset.
+type PositionChangeData struct {
+	BoughtSize   float64 // base asset received (after fees)
+	BoughtCost   float64 // quote asset paid
+	SoldSize     float64 // base asset sold
+	SoldProceeds float64 // quote asset received (after fees)
+}
+
 // TradeSettlementResult encapsulates the result of trade settlement processing
 type TradeSettlementResult struct {
-	BaseAsset       string
-	QuoteAsset      string
-	OrderUpdates    []*OrderUpdateData
-	UserSettlements map[string]*UserSettlementData
-	TotalDealtAmt   float64
-	TotalDealtSize  float64
-	TotalBaseFees   float64 // add to settings margin account balances
-	TotalQuoteFees  float64 // add to settings margin account balances
+	BaseAsset         string
+	QuoteAsset        string
+	OrderUpdates      []*OrderUpdateData
+	UserSettlements   map[string]*UserSettlementData
+	PositionChanges   map[string]*PositionChangeData // by user id, for cost basis tracking
+	TotalDealtAmt     float64
+	TotalDealtSize    float64
+	TotalBaseFees     float64 // fee income, add to settings margin account balances
+	TotalQuoteFees    float64 // fee income, add to settings margin account balances
+	TotalBaseRebates  float64 // maker rebates (negative fee rate), paid by settings margin account
+	TotalQuoteRebates float64 // maker rebates (negative fee rate), paid by settings margin account
+	TotalFeeTokenFees float64 // fees paid in settings.FEE_TOKEN, add to settings margin account balances
 }
 
 // ProcessTradeSettlement handles the core logic for processing trades and updating balances
@@ -48,6 +67,7 @@ func ProcessTradeSettlement(ctx *dto.PlaceOrderContext) (*TradeSettlementResult,
 	result := &TradeSettlementResult{
 		OrderUpdates:    make([]*OrderUpdateData, 0, len(trades)+1),
 		UserSettlements: initializeUserSettlements(trades),
+		PositionChanges: make(map[string]*PositionChangeData),
 		BaseAsset:       ctx.Assets.BaseAsset,
 		QuoteAsset:      ctx.Assets.QuoteAsset,
 	}
@@ -129,14 +149,22 @@ func (r *TradeSettlementResult) processBidUserBalances(bidSettlement *UserSettle
 		bidSettlement.QuoteAssetLocked = utils.RoundFloat(bidSettlement.QuoteAssetLocked)
 	}
 
-	// Calculate fees and accumulate to sum.
+	// Calculate fees and accumulate to sum, negative fees are rebates.
 	bidFees := trade.Size * trade.BidFeeRate
-	r.TotalBaseFees += bidFees
+	if bidFees >= 0 {
+		r.TotalBaseFees += bidFees
+	} else {
+		r.TotalBaseRebates -= bidFees
+	}
 
 	// Add base asset received (deduct fees)
 	bidSettlement.BaseAssetAvailable += trade.Size - bidFees
 	bidSettlement.BaseAssetAvailable = utils.RoundFloat(bidSettlement.BaseAssetAvailable)
 
+	position := r.positionChange(trade.BidUserID)
+	position.BoughtSize += trade.Size - bidFees
+	position.BoughtCost += tradeQuoteAmount
+
 	return bidFees
 }
 
@@ -146,31 +174,54 @@ func (r *TradeSettlementResult) processAskUserBalances(askSettlement *UserSettle
 	askSettlement.BaseAssetLocked -= trade.Size
 	askSettlement.BaseAssetLocked = utils.RoundFloat(askSettlement.BaseAssetLocked)
 
-	// Calculate fees and accumulate to sum.
+	// Calculate fees and accumulate to sum, negative fees are rebates.
 	askFees := tradeQuoteAmount * trade.AskFeeRate
-	r.TotalQuoteFees += askFees
+	if askFees >= 0 {
+		r.TotalQuoteFees += askFees
+	} else {
+		r.TotalQuoteRebates -= askFees
+	}
 
 	// Add quote asset received
 	askSettlement.QuoteAssetAvailable += tradeQuoteAmount - askFees
 	askSettlement.QuoteAssetAvailable = utils.RoundFloat(askSettlement.QuoteAssetAvailable)
 
+	position := r.positionChange(trade.AskUserID)
+	position.SoldSize += trade.Size
+	position.SoldProceeds += tradeQuoteAmount - askFees
+
 	return askFees
 }
 
+func (r *TradeSettlementResult) positionChange(userId string) *PositionChangeData {
+	position, ok := r.PositionChanges[userId]
+	if !ok {
+		position = &PositionChangeData{}
+		r.PositionChanges[userId] = position
+	}
+	return position
+}
+
 // addOppositeOrderUpdate adds update data for the order opposite to the eaten order
 func (r *TradeSettlementResult) addOppositeOrderUpdate(trade book.Trade, eatenOrder *dto.Order, tradeQuoteAmount, bidFees, askFees float64) {
-	var oppositeOrderId string
+	var oppositeOrderId, oppositeUserId, feeAsset string
 	var feeIncreasing float64
 	if eatenOrder.Side == model.BID {
 		oppositeOrderId = trade.AskOrderID
+		oppositeUserId = trade.AskUserID
 		feeIncreasing = askFees
+		feeAsset = r.QuoteAsset
 	} else {
 		oppositeOrderId = trade.BidOrderID
+		oppositeUserId = trade.BidUserID
 		feeIncreasing = bidFees
+		feeAsset = r.BaseAsset
 	}
 
 	r.OrderUpdates = append(r.OrderUpdates, &OrderUpdateData{
 		OrderID:                    oppositeOrderId,
+		UserID:                     oppositeUserId,
+		FeeAsset:                   feeAsset,
 		RemainingSizeDecreasing:    utils.RoundFloat(trade.Size),
 		DealtQuoteAmountIncreasing: utils.RoundFloat(tradeQuoteAmount),
 		FeesIncreasing:             feeIncreasing,
@@ -185,21 +236,28 @@ func (r *TradeSettlementResult) addEatenOrderUpdate(eatenOrder *dto.Order) {
 		// Market bid orders don't need size/amount updates as they're already processed
 		update = &OrderUpdateData{
 			OrderID:                    eatenOrder.ID,
+			UserID:                     eatenOrder.UserID,
+			FeeAsset:                   r.BaseAsset,
 			RemainingSizeDecreasing:    0.0,
 			DealtQuoteAmountIncreasing: 0.0,
-			FeesIncreasing:             r.TotalBaseFees,
+			FeesIncreasing:             r.NetBaseFees(),
 		}
 	} else {
 		// Limit orders and market sell orders need full updates
 		var fees float64
+		var feeAsset string
 		if eatenOrder.Side == model.BID {
-			fees = r.TotalBaseFees
+			fees = r.NetBaseFees()
+			feeAsset = r.BaseAsset
 		} else {
-			fees = r.TotalQuoteFees
+			fees = r.NetQuoteFees()
+			feeAsset = r.QuoteAsset
 		}
 
 		update = &OrderUpdateData{
 			OrderID:                    eatenOrder.ID,
+			UserID:                     eatenOrder.UserID,
+			FeeAsset:                   feeAsset,
 			RemainingSizeDecreasing:    utils.RoundFloat(r.TotalDealtSize),
 			DealtQuoteAmountIncreasing: utils.RoundFloat(r.TotalDealtAmt),
 			FeesIncreasing:             fees,
@@ -209,3 +267,53 @@ func (r *TradeSettlementResult) addEatenOrderUpdate(eatenOrder *dto.Order) {
 
 	r.OrderUpdates = append(r.OrderUpdates, update)
 }
+
+// NetBaseFees fee income minus rebates in base asset, all bid side fees belong to eaten order when it is BID.
+func (r *TradeSettlementResult) NetBaseFees() float64 {
+	return r.TotalBaseFees - r.TotalBaseRebates
+}
+
+// NetQuoteFees fee income minus rebates in quote asset, all ask side fees belong to eaten order when it is ASK.
+func (r *TradeSettlementResult) NetQuoteFees() float64 {
+	return r.TotalQuoteFees - r.TotalQuoteRebates
+}
+
+// CalculateRebates sum maker rebates will be paid for trades, bid side in base asset, ask side in quote asset.
+func CalculateRebates(trades []book.Trade) (baseRebates float64, quoteRebates float64) {
+	for _, trade := range trades {
+		if trade.BidFeeRate < 0 {
+			baseRebates -= trade.Size * trade.BidFeeRate
+		}
+		if trade.AskFeeRate < 0 {
+			quoteRebates -= trade.Price * trade.Size * trade.AskFeeRate
+		}
+	}
+	return baseRebates, quoteRebates
+}
+
+// DisableRebates reset negative fee rates to 0, used when margin account can not afford rebates.
+func DisableRebates(trades []book.Trade, disableBase bool, disableQuote bool) {
+	for i := range trades {
+		if disableBase && trades[i].BidFeeRate < 0 {
+			trades[i].BidFeeRate = 0
+		}
+		if disableQuote && trades[i].AskFeeRate < 0 {
+			trades[i].AskFeeRate = 0
+		}
+	}
+}
+
+// RefundFees give back fees charged in normal asset (bid: base, ask: quote), used when user paid them in settings.FEE_TOKEN.
+func (r *TradeSettlementResult) RefundFees(userId string, side model.Side, fees float64) {
+	settlement := r.UserSettlements[userId]
+	position := r.positionChange(userId)
+	if side == model.BID {
+		settlement.BaseAssetAvailable = utils.RoundFloat(settlement.BaseAssetAvailable + fees)
+		position.BoughtSize += fees
+		r.TotalBaseFees -= fees
+	} else {
+		settlement.QuoteAssetAvailable = utils.RoundFloat(settlement.QuoteAssetAvailable + fees)
+		position.SoldProceeds += fees
+		r.TotalQuoteFees -= fees
+	}
+}
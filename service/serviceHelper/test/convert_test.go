@@ -0,0 +1,109 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"testing"
+)
+
+func Test_PriceConvertAskLeg(t *testing.T) {
+	bids := []*book.PriceVolumePair{{Price: 10, Volume: 5}, {Price: 9, Volume: 5}}
+	tests := []struct {
+		name     string
+		bids     []*book.PriceVolumePair
+		size     float64
+		ok       bool
+		price    float64
+		toAmount float64
+	}{
+		{"best level", bids, 3, true, 10, 29.97},
+		{"walks to worse level", bids, 8, true, 9, 76.923},
+		{"whole depth", bids, 10, true, 9, 94.905},
+		{"depth not enough", bids, 10.5, false, 0, 0},
+		{"empty book", nil, 1, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leg, ok := serviceHelper.PriceConvertAskLeg("DOT-USDT", "DOT", "USDT", tt.bids, tt.size, 0.001)
+			assert(t, ok, tt.ok)
+			if !ok {
+				return
+			}
+			assert(t, leg.Side, model.ASK)
+			assert(t, leg.FromAsset, "DOT")
+			assert(t, leg.ToAsset, "USDT")
+			assertFloat(t, leg.Price, tt.price)
+			assertFloat(t, leg.Size, tt.size)
+			assertFloat(t, leg.FromAmount, tt.size)
+			assertFloat(t, leg.ToAmount, tt.toAmount)
+		})
+	}
+}
+
+func Test_PriceConvertBidLeg(t *testing.T) {
+	asks := []*book.PriceVolumePair{{Price: 10, Volume: 5}, {Price: 11, Volume: 5}}
+	tests := []struct {
+		name        string
+		asks        []*book.PriceVolumePair
+		quoteAmount float64
+		ok          bool
+		price       float64
+		size        float64
+		fromAmount  float64
+		toAmount    float64
+	}{
+		{"best level", asks, 30, true, 10, 3, 30, 2.997},
+		// size is priced at worst level, quote saved by better level is not spent.
+		{"walks to worse level", asks, 77, true, 11, 7, 72, 6.993},
+		{"whole depth", asks, 105, true, 11, 9.5454545, 100, 9.535909},
+		{"depth not enough", asks, 106, false, 0, 0, 0, 0},
+		{"empty book", nil, 1, false, 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leg, ok := serviceHelper.PriceConvertBidLeg("BTC-USDT", "BTC", "USDT", tt.asks, tt.quoteAmount, 0.001)
+			assert(t, ok, tt.ok)
+			if !ok {
+				return
+			}
+			assert(t, leg.Side, model.BID)
+			assert(t, leg.FromAsset, "USDT")
+			assert(t, leg.ToAsset, "BTC")
+			assertFloat(t, leg.Price, tt.price)
+			assertFloat(t, leg.Size, tt.size)
+			assertFloat(t, leg.FromAmount, tt.fromAmount)
+			assertFloat(t, leg.ToAmount, tt.toAmount)
+		})
+	}
+}
+
+func Test_ConvertLegReceived(t *testing.T) {
+	askLeg := &dto.ConvertLeg{Side: model.ASK, FromAsset: "DOT", ToAsset: "USDT"}
+	bidLeg := &dto.ConvertLeg{Side: model.BID, FromAsset: "USDT", ToAsset: "BTC"}
+	tests := []struct {
+		name     string
+		leg      *dto.ConvertLeg
+		matches  []*dto.Match
+		feeAsset string
+		spent    float64
+		received float64
+	}{
+		{"ask fee in to asset", askLeg, []*dto.Match{{Price: 10, Size: 2}, {Price: 9, Size: 1}}, "USDT", 3, 28.971},
+		{"ask fee in fee token", askLeg, []*dto.Match{{Price: 10, Size: 2}, {Price: 9, Size: 1}}, "BTSE", 3, 29},
+		{"bid fee in to asset", bidLeg, []*dto.Match{{Price: 10, Size: 2}}, "BTC", 20, 1.998},
+		{"not filled", bidLeg, nil, "BTC", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &dto.PlaceOrderResult{Matches: tt.matches, Order: dto.Order{FeeAsset: tt.feeAsset, FeeRate: 0.001}}
+			spent, received := serviceHelper.ConvertLegReceived(tt.leg, result)
+			assertFloat(t, spent, tt.spent)
+			assertFloat(t, received, tt.received)
+		})
+	}
+}
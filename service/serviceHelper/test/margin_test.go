@@ -0,0 +1,62 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"github.com/johnny1110/crypto-exchange/settings"
+	"testing"
+	"time"
+)
+
+func Test_CalculateMarginRisk(t *testing.T) {
+	prices := map[string]float64{"USDT": 1, "BTC": 20000}
+	tests := []struct {
+		name           string
+		balances       []*dto.Balance
+		loans          []*dto.MarginLoan
+		equity         float64
+		marginRatio    float64
+		leverage       float64
+		maxBorrowValue float64
+	}{
+		{"no loan", []*dto.Balance{{Asset: "USDT", Available: 100}}, nil, 100, 1, 1, 400},
+		{"at initial ratio", []*dto.Balance{{Asset: "USDT", Available: 500}}, []*dto.MarginLoan{{Asset: "USDT", Principal: 400}}, 100, 0.2, 5, 0},
+		{"locked and interest are counted", []*dto.Balance{{Asset: "BTC", Available: 0.01, Locked: 0.01}}, []*dto.MarginLoan{{Asset: "USDT", Principal: 100, Interest: 20}}, 280, 0.7, 400.0 / 280, 1000},
+		{"underwater", []*dto.Balance{{Asset: "USDT", Available: 100}}, []*dto.MarginLoan{{Asset: "BTC", Principal: 0.01}}, -100, -1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk := serviceHelper.CalculateMarginRisk(tt.balances, tt.loans, prices)
+			assertFloat(t, risk.Equity, tt.equity)
+			assertFloat(t, risk.MarginRatio, tt.marginRatio)
+			assertFloat(t, risk.Leverage, tt.leverage)
+			assertFloat(t, serviceHelper.MaxBorrowableValue(risk), tt.maxBorrowValue)
+		})
+	}
+}
+
+func Test_AccrueMarginInterest(t *testing.T) {
+	accruedAt := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	loan := &dto.MarginLoan{Asset: "USDT", Principal: 1000, AccruedAt: accruedAt}
+	rate := settings.GetMarginHourlyInterestRate("USDT")
+	tests := []struct {
+		name      string
+		now       time.Time
+		interest  float64
+		accruedAt time.Time
+	}{
+		{"within first hour", accruedAt.Add(59 * time.Minute), 0, accruedAt},
+		{"one hour", accruedAt.Add(time.Hour), 1000 * rate, accruedAt.Add(time.Hour)},
+		{"missed hours caught up", accruedAt.Add(5*time.Hour + 30*time.Minute), 5 * 1000 * rate, accruedAt.Add(5 * time.Hour)},
+		{"clock behind", accruedAt.Add(-time.Hour), 0, accruedAt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interest, newAccruedAt := serviceHelper.AccrueMarginInterest(loan, tt.now)
+			assertFloat(t, interest, tt.interest)
+			assert(t, newAccruedAt, tt.accruedAt)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"math"
+	"testing"
+)
+
+func Test_ApplyPerpetualFill(t *testing.T) {
+	tests := []struct {
+		name     string
+		position dto.PerpetualPosition
+		size     float64
+		price    float64
+		expected dto.PerpetualPosition
+		fill     serviceHelper.PerpetualFillResult
+	}{
+		{"open long", dto.PerpetualPosition{}, 2, 100,
+			dto.PerpetualPosition{Size: 2, EntryPrice: 100, Margin: 20},
+			serviceHelper.PerpetualFillResult{OpenMargin: 20}},
+		{"add to long", dto.PerpetualPosition{Size: 1, EntryPrice: 100, Margin: 10}, 1, 110,
+			dto.PerpetualPosition{Size: 2, EntryPrice: 105, Margin: 21},
+			serviceHelper.PerpetualFillResult{OpenMargin: 11}},
+		{"partial close long", dto.PerpetualPosition{Size: 2, EntryPrice: 100, Margin: 20}, -1, 110,
+			dto.PerpetualPosition{Size: 1, EntryPrice: 100, Margin: 10},
+			serviceHelper.PerpetualFillResult{ClosedSize: 1, Proceeds: 110, Pnl: 10, ReleasedMargin: 10}},
+		{"close short with profit", dto.PerpetualPosition{Size: -2, EntryPrice: 100, Margin: 20}, 2, 90,
+			dto.PerpetualPosition{},
+			serviceHelper.PerpetualFillResult{ClosedSize: 2, Proceeds: 200, Pnl: 20, ReleasedMargin: 20}},
+		{"close long with loss", dto.PerpetualPosition{Size: 1, EntryPrice: 100, Margin: 10}, -1, 95,
+			dto.PerpetualPosition{},
+			serviceHelper.PerpetualFillResult{ClosedSize: 1, Proceeds: 95, Pnl: -5, ReleasedMargin: 10}},
+		{"flip long to short", dto.PerpetualPosition{Size: 1, EntryPrice: 100, Margin: 10}, -3, 120,
+			dto.PerpetualPosition{Size: -2, EntryPrice: 120, Margin: 24},
+			serviceHelper.PerpetualFillResult{ClosedSize: 1, Proceeds: 120, Pnl: 20, ReleasedMargin: 10, OpenMargin: 24}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := tt.position
+			fill := serviceHelper.ApplyPerpetualFill(&position, tt.size, tt.price, tt.price, 1)
+			assertFloat(t, position.Size, tt.expected.Size)
+			assertFloat(t, position.EntryPrice, tt.expected.EntryPrice)
+			assertFloat(t, position.Margin, tt.expected.Margin)
+			assertFloat(t, fill.ClosedSize, tt.fill.ClosedSize)
+			assertFloat(t, fill.Proceeds, tt.fill.Proceeds)
+			assertFloat(t, fill.Pnl, tt.fill.Pnl)
+			assertFloat(t, fill.ReleasedMargin, tt.fill.ReleasedMargin)
+			assertFloat(t, fill.OpenMargin, tt.fill.OpenMargin)
+		})
+	}
+}
+
+func Test_CalculateFundingRate(t *testing.T) {
+	assertFloat(t, serviceHelper.CalculateFundingRate(100.1, 100), 0.001)
+	assertFloat(t, serviceHelper.CalculateFundingRate(99.9, 100), -0.001)
+	assertFloat(t, serviceHelper.CalculateFundingRate(100, 100), 0)
+	// clamped in ±0.75%
+	assertFloat(t, serviceHelper.CalculateFundingRate(110, 100), 0.0075)
+	assertFloat(t, serviceHelper.CalculateFundingRate(90, 100), -0.0075)
+	assertFloat(t, serviceHelper.CalculateFundingRate(100, 0), 0)
+}
+
+func Test_CalculateFundingPayment(t *testing.T) {
+	// positive rate: longs pay shorts.
+	assertFloat(t, serviceHelper.CalculateFundingPayment(2, 1, 100, 0.001), -0.2)
+	assertFloat(t, serviceHelper.CalculateFundingPayment(-2, 1, 100, 0.001), 0.2)
+	// negative rate: shorts pay longs.
+	assertFloat(t, serviceHelper.CalculateFundingPayment(2, 0.5, 100, -0.001), 0.1)
+	assertFloat(t, serviceHelper.CalculateFundingPayment(-2, 0.5, 100, -0.001), -0.1)
+}
+
+// newPerpetualOrderCtx eaten limit order E1 of user U1 on perpetual market, contract size 1.
+func newPerpetualOrderCtx(side model.Side, price float64, feeRate float64, trades []book.Trade) *dto.PlaceOrderContext {
+	return &dto.PlaceOrderContext{
+		Market:    "BTC-USDT-PERP",
+		UserID:    "U1",
+		FeeRate:   feeRate,
+		FeeAsset:  "USDT",
+		OrderDTO:  &dto.Order{ID: "E1", UserID: "U1", Side: side, Type: model.LIMIT, Price: price},
+		Trades:    trades,
+		Perpetual: &market.MarketInfo{BaseAsset: "BTC", QuoteAsset: "USDT", Type: market.PERPETUAL, ContractSize: 1},
+	}
+}
+
+func Test_ProcessPerpetualSettlement_Flip(t *testing.T) {
+	orderCtx := newPerpetualOrderCtx(model.ASK, 110, 0.001, []book.Trade{{
+		BidOrderID: "M1", AskOrderID: "E1", BidUserID: "U2", AskUserID: "U1",
+		BidFeeRate: 0.001, AskFeeRate: 0.001, Price: 110, Size: 3,
+	}})
+	positions := map[string]*dto.PerpetualPosition{
+		"U1": {UserID: "U1", Market: "BTC-USDT-PERP", Size: 1, EntryPrice: 100, Margin: 10},
+	}
+
+	result, err := serviceHelper.ProcessPerpetualSettlement(orderCtx, positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// U1 closes long 1 with 10 pnl and opens short 2 at 110.
+	u1 := result.Positions["U1"]
+	assertFloat(t, u1.Size, -2)
+	assertFloat(t, u1.EntryPrice, 110)
+	assertFloat(t, u1.Margin, 22)
+	assertFloat(t, u1.RealizedPnl, 10)
+	// unlock 33.33 (330 * 0.101), new margin 22, released margin 10, pnl 10, fee 0.33
+	assertFloat(t, result.UserSettlements["U1"].Available, 31)
+	assertFloat(t, result.UserSettlements["U1"].Locked, -21.33)
+
+	// U2 opens long 3 at 110.
+	u2 := result.Positions["U2"]
+	assertFloat(t, u2.Size, 3)
+	assertFloat(t, u2.EntryPrice, 110)
+	assertFloat(t, u2.Margin, 33)
+	assertFloat(t, result.UserSettlements["U2"].Available, 0)
+	assertFloat(t, result.UserSettlements["U2"].Locked, -0.33)
+
+	assertFloat(t, result.NetPnl, 10)
+	assertFloat(t, result.BadDebt, 0)
+	assertFloat(t, result.TotalFees, 0.66)
+	assert(t, len(result.RealizedPnls), 1)
+	assertFloat(t, result.RealizedPnls[0].Pnl, 10)
+	assertFloat(t, result.RealizedPnls[0].Proceeds, 110)
+	assertFloat(t, result.RealizedPnls[0].Cost, 100)
+	assertFloat(t, orderCtx.OrderDTO.Fees, 0.33)
+}
+
+func Test_ProcessPerpetualSettlement_CloseWithBadDebt(t *testing.T) {
+	orderCtx := newPerpetualOrderCtx(model.ASK, 80, 0, []book.Trade{{
+		BidOrderID: "M1", AskOrderID: "E1", BidUserID: "U2", AskUserID: "U1", Price: 80, Size: 1,
+	}})
+	positions := map[string]*dto.PerpetualPosition{
+		"U1": {UserID: "U1", Market: "BTC-USDT-PERP", Size: 1, EntryPrice: 100, Margin: 10},
+	}
+
+	result, err := serviceHelper.ProcessPerpetualSettlement(orderCtx, positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// loss 20 exceeds margin 10, user is only charged margin.
+	u1 := result.Positions["U1"]
+	assertFloat(t, u1.Size, 0)
+	assertFloat(t, u1.Margin, 0)
+	assertFloat(t, u1.RealizedPnl, -10)
+	assertFloat(t, result.UserSettlements["U1"].Available, 8)
+	assertFloat(t, result.UserSettlements["U1"].Locked, -18)
+	assertFloat(t, result.NetPnl, -10)
+	assertFloat(t, result.BadDebt, 10)
+	assert(t, len(result.RealizedPnls), 1)
+	assertFloat(t, result.RealizedPnls[0].Pnl, -10)
+	assertFloat(t, result.RealizedPnls[0].Cost, 90)
+}
+
+func Test_ProcessPerpetualSettlement_NotPerpetual(t *testing.T) {
+	orderCtx := newPerpetualOrderCtx(model.BID, 100, 0, nil)
+	orderCtx.Perpetual = nil
+	if _, err := serviceHelper.ProcessPerpetualSettlement(orderCtx, nil); err == nil {
+		t.Error("Expected error of spot market")
+	}
+}
+
+func Test_LiquidatePerpetualPosition(t *testing.T) {
+	tests := []struct {
+		name      string
+		position  dto.PerpetualPosition
+		markPrice float64
+		atRisk    bool
+		pnl       float64
+		badDebt   float64
+	}{
+		{"long above maintenance", dto.PerpetualPosition{Size: 1, EntryPrice: 100, Margin: 10}, 96, false, -4, 0},
+		{"long under maintenance", dto.PerpetualPosition{Size: 1, EntryPrice: 100, Margin: 10}, 93, true, -7, 0},
+		{"long loss exceeds margin", dto.PerpetualPosition{Size: 1, EntryPrice: 100, Margin: 10}, 85, true, -10, 5},
+		{"short loss exceeds margin", dto.PerpetualPosition{Size: -2, EntryPrice: 100, Margin: 20}, 115, true, -20, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := tt.position
+			serviceHelper.ValuePerpetualPosition(&position, tt.markPrice, 1)
+			assert(t, serviceHelper.IsPerpetualPositionAtRisk(&position), tt.atRisk)
+
+			liquidation, fill := serviceHelper.LiquidatePerpetualPosition(&position, tt.markPrice, 1)
+			assertFloat(t, liquidation.Size, tt.position.Size)
+			assertFloat(t, liquidation.Margin, tt.position.Margin)
+			assertFloat(t, liquidation.Pnl, tt.pnl)
+			assertFloat(t, liquidation.BadDebt, tt.badDebt)
+			assertFloat(t, fill.ClosedSize, math.Abs(tt.position.Size))
+			assertFloat(t, position.Size, 0)
+			assertFloat(t, position.Margin, 0)
+			assertFloat(t, position.RealizedPnl, tt.pnl)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"testing"
+)
+
+func Test_ApplyPositionChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		basis    dto.CostBasis
+		change   serviceHelper.PositionChangeData
+		expected dto.CostBasis
+		soldCost float64
+	}{
+		{"first buy", dto.CostBasis{}, serviceHelper.PositionChangeData{BoughtSize: 2, BoughtCost: 20},
+			dto.CostBasis{Quantity: 2, AvgCost: 10}, 0},
+		{"buy more averages cost", dto.CostBasis{Quantity: 2, AvgCost: 10}, serviceHelper.PositionChangeData{BoughtSize: 2, BoughtCost: 30},
+			dto.CostBasis{Quantity: 4, AvgCost: 12.5}, 0},
+		{"partial sell keeps avg cost", dto.CostBasis{Quantity: 4, AvgCost: 12.5}, serviceHelper.PositionChangeData{SoldSize: 1, SoldProceeds: 20},
+			dto.CostBasis{Quantity: 3, AvgCost: 12.5}, 12.5},
+		{"sell all resets avg cost", dto.CostBasis{Quantity: 3, AvgCost: 12.5}, serviceHelper.PositionChangeData{SoldSize: 3, SoldProceeds: 30},
+			dto.CostBasis{}, 37.5},
+		{"buy applied before sell", dto.CostBasis{Quantity: 1, AvgCost: 10}, serviceHelper.PositionChangeData{BoughtSize: 1, BoughtCost: 20, SoldSize: 1, SoldProceeds: 18},
+			dto.CostBasis{Quantity: 1, AvgCost: 15}, 15},
+		{"oversell costed at avg cost", dto.CostBasis{Quantity: 1, AvgCost: 10}, serviceHelper.PositionChangeData{SoldSize: 3, SoldProceeds: 36},
+			dto.CostBasis{}, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			basis := tt.basis
+			soldCost := serviceHelper.ApplyPositionChange(&basis, &tt.change)
+			assertFloat(t, soldCost, tt.soldCost)
+			assertFloat(t, basis.Quantity, tt.expected.Quantity)
+			assertFloat(t, basis.AvgCost, tt.expected.AvgCost)
+		})
+	}
+}
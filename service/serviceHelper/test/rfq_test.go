@@ -0,0 +1,102 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/market"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"testing"
+)
+
+var btcMarket = market.NewMarketInfo("BTC-USDT", "BTC", "USDT")
+
+func Test_RfqProviderLock(t *testing.T) {
+	tests := []struct {
+		side   model.Side
+		asset  string
+		amount float64
+	}{
+		{model.BID, "BTC", 2},
+		{model.ASK, "USDT", 200.2},
+	}
+
+	for _, tt := range tests {
+		asset, amount := serviceHelper.RfqProviderLock(&dto.RfqRequest{Side: tt.side, Size: 2}, btcMarket, 100.1)
+		assert(t, asset, tt.asset)
+		assertFloat(t, amount, tt.amount)
+	}
+}
+
+func Test_ProcessRfqSettlement(t *testing.T) {
+	quote := &dto.RfqQuote{ID: "Q1", ProviderID: "P1", Price: 100}
+	tests := []struct {
+		name           string
+		side           model.Side
+		feeAsset       string
+		fee            float64
+		paidAsset      string
+		paidAmount     float64
+		balanceChanges []*serviceHelper.RfqBalanceChange
+		takerPosition  *serviceHelper.PositionChangeData
+		providerPos    *serviceHelper.PositionChangeData
+	}{
+		{
+			name: "taker buys", side: model.BID,
+			feeAsset: "BTC", fee: 0.002, paidAsset: "USDT", paidAmount: 200,
+			balanceChanges: []*serviceHelper.RfqBalanceChange{
+				{UserID: "U1", Asset: "BTC", Available: 1.998},
+				{UserID: "P1", Asset: "BTC", Locked: -2},
+				{UserID: "P1", Asset: "USDT", Available: 200},
+			},
+			takerPosition: &serviceHelper.PositionChangeData{BoughtSize: 1.998, BoughtCost: 200},
+			providerPos:   &serviceHelper.PositionChangeData{SoldSize: 2, SoldProceeds: 200},
+		},
+		{
+			name: "taker sells", side: model.ASK,
+			feeAsset: "USDT", fee: 0.2, paidAsset: "BTC", paidAmount: 2,
+			balanceChanges: []*serviceHelper.RfqBalanceChange{
+				{UserID: "U1", Asset: "USDT", Available: 199.8},
+				{UserID: "P1", Asset: "USDT", Locked: -200},
+				{UserID: "P1", Asset: "BTC", Available: 2},
+			},
+			takerPosition: &serviceHelper.PositionChangeData{SoldSize: 2, SoldProceeds: 199.8},
+			providerPos:   &serviceHelper.PositionChangeData{BoughtSize: 2, BoughtCost: 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &dto.RfqRequest{ID: "R1", UserID: "U1", Market: "BTC-USDT", Side: tt.side, Size: 2}
+			result := serviceHelper.ProcessRfqSettlement(request, quote, btcMarket, 0.001)
+
+			assertFloat(t, result.Trade.Price, 100)
+			assertFloat(t, result.Trade.Size, 2)
+			assertFloat(t, result.Trade.TradeValue, 200)
+			if tt.side == model.BID {
+				assert(t, []string{result.Trade.BidOrderID, result.Trade.BidUserID, result.Trade.AskOrderID, result.Trade.AskUserID}, []string{"R1", "U1", "Q1", "P1"})
+				assertFloat(t, result.BidFee, tt.fee)
+			} else {
+				assert(t, []string{result.Trade.AskOrderID, result.Trade.AskUserID, result.Trade.BidOrderID, result.Trade.BidUserID}, []string{"R1", "U1", "Q1", "P1"})
+				assertFloat(t, result.AskFee, tt.fee)
+			}
+			assert(t, result.FeeAsset, tt.feeAsset)
+			assertFloat(t, result.Fee, tt.fee)
+			assert(t, result.PaidAsset, tt.paidAsset)
+			assertFloat(t, result.PaidAmount, tt.paidAmount)
+			assert(t, result.BalanceChanges, tt.balanceChanges)
+			assert(t, result.PositionChanges["U1"], tt.takerPosition)
+			assert(t, result.PositionChanges["P1"], tt.providerPos)
+
+			// nothing is created or lost: balance changes + taker payment + fee sum to 0 per asset.
+			sums := map[string]float64{result.PaidAsset: -result.PaidAmount, result.FeeAsset: result.Fee}
+			for _, change := range result.BalanceChanges {
+				sums[change.Asset] += change.Available + change.Locked
+			}
+			for asset, sum := range sums {
+				if sum < -1e-9 || sum > 1e-9 {
+					t.Errorf("%s not conserved, sum: %v", asset, sum)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/engine-v2/book"
+	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func assert(t *testing.T, a, b any) {
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
+
+func assertFloat(t *testing.T, a, b float64) {
+	if math.Abs(a-b) > 1e-6 {
+		t.Errorf("Expected %v, got %v", b, a)
+	}
+}
+
+// rebateUser designated market maker, negative maker fee.
+var rebateUser = &dto.User{ID: "U1", MakerFee: -0.0002, TakerFee: 0.001}
+
+func Test_DetermineFeeInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *dto.OrderReq
+		user     *dto.User
+		feeAsset string
+		feeRate  float64
+	}{
+		{"limit maker gets rebate", &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.MAKER}, rebateUser, "BTC", -0.0002},
+		{"limit taker", &dto.OrderReq{Side: model.ASK, OrderType: model.LIMIT, Mode: model.TAKER}, rebateUser, "USDT", 0.001},
+		{"market without mode is taker", &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET}, rebateUser, "USDT", 0.001},
+		{"market maker mode is taker", &dto.OrderReq{Side: model.BID, OrderType: model.MARKET, Mode: model.MAKER}, rebateUser, "BTC", 0.001},
+		{"negative taker fee is not paid", &dto.OrderReq{Side: model.BID, OrderType: model.MARKET}, &dto.User{TakerFee: -0.0001}, "BTC", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feeAsset, feeRate := serviceHelper.DetermineFeeInfo(tt.req, tt.user, "BTC", "USDT")
+			assert(t, feeAsset, tt.feeAsset)
+			assertFloat(t, feeRate, tt.feeRate)
+		})
+	}
+}
+
+// newOrderCtx eaten order context of user with fee info determined like order service.
+func newOrderCtx(user *dto.User, req *dto.OrderReq, trades []book.Trade) *dto.PlaceOrderContext {
+	feeAsset, feeRate := serviceHelper.DetermineFeeInfo(req, user, "BTC", "USDT")
+	orderCtx := &dto.PlaceOrderContext{
+		Market:   "BTC-USDT",
+		UserID:   user.ID,
+		Request:  req,
+		FeeRate:  feeRate,
+		FeeAsset: feeAsset,
+		Assets:   &dto.AssetDetails{BaseAsset: "BTC", QuoteAsset: "USDT"},
+		Trades:   trades,
+	}
+	if req.OrderType == model.MARKET {
+		orderCtx.OrderDTO = serviceHelper.NewMarketOrderDtoByOrderReq(orderCtx)
+	} else {
+		orderCtx.OrderDTO = serviceHelper.NewLimitOrderDtoByOrderCtx(orderCtx)
+	}
+	orderCtx.OrderDTO.ID = "E1"
+	return orderCtx
+}
+
+func Test_ProcessTradeSettlement_MarketOrderOfRebateUserPaysTakerFee(t *testing.T) {
+	req := &dto.OrderReq{Side: model.ASK, OrderType: model.MARKET, Size: 1}
+	orderCtx := newOrderCtx(rebateUser, req, nil)
+	orderCtx.Trades = []book.Trade{{
+		BidOrderID: "M1", AskOrderID: "E1", BidUserID: "U2", AskUserID: rebateUser.ID,
+		BidFeeRate: 0.001, AskFeeRate: orderCtx.FeeRate, Price: 100, Size: 1,
+	}}
+
+	result, err := serviceHelper.ProcessTradeSettlement(orderCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, result.TotalQuoteFees, 0.1)
+	assertFloat(t, result.TotalQuoteRebates, 0)
+	assertFloat(t, result.TotalBaseFees, 0.001)
+	assertFloat(t, result.UserSettlements[rebateUser.ID].QuoteAssetAvailable, 99.9)
+	assertFloat(t, result.UserSettlements[rebateUser.ID].BaseAssetLocked, -1)
+	assertFloat(t, result.UserSettlements["U2"].BaseAssetAvailable, 0.999)
+	assertFloat(t, orderCtx.OrderDTO.Fees, 0.1)
+}
+
+func Test_ProcessTradeSettlement_MakerRebate(t *testing.T) {
+	req := &dto.OrderReq{Side: model.BID, OrderType: model.LIMIT, Mode: model.TAKER, Price: 100, Size: 2}
+	taker := &dto.User{ID: "U2", MakerFee: 0.0005, TakerFee: 0.001}
+	orderCtx := newOrderCtx(taker, req, nil)
+	orderCtx.Trades = []book.Trade{{
+		BidOrderID: "E1", AskOrderID: "M1", BidUserID: taker.ID, AskUserID: rebateUser.ID,
+		BidFeeRate: orderCtx.FeeRate, AskFeeRate: rebateUser.MakerFee, Price: 100, Size: 2,
+	}}
+
+	result, err := serviceHelper.ProcessTradeSettlement(orderCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, result.TotalBaseFees, 0.002)
+	assertFloat(t, result.TotalQuoteFees, 0)
+	assertFloat(t, result.TotalQuoteRebates, 0.04)
+	assertFloat(t, result.UserSettlements[rebateUser.ID].QuoteAssetAvailable, 200.04)
+	assertFloat(t, result.UserSettlements[rebateUser.ID].BaseAssetLocked, -2)
+	assertFloat(t, result.UserSettlements[taker.ID].BaseAssetAvailable, 1.998)
+	assertFloat(t, result.UserSettlements[taker.ID].QuoteAssetLocked, -200)
+
+	_, quoteRebates := serviceHelper.CalculateRebates(orderCtx.Trades)
+	assertFloat(t, quoteRebates, result.TotalQuoteRebates)
+	for _, update := range result.OrderUpdates {
+		if update.OrderID == "M1" {
+			assertFloat(t, update.FeesIncreasing, -0.04)
+		}
+	}
+}
+
+func Test_CalculateRebates_DisableRebates(t *testing.T) {
+	trades := []book.Trade{
+		{BidFeeRate: -0.0001, AskFeeRate: 0.001, Price: 100, Size: 2},
+		{BidFeeRate: 0.001, AskFeeRate: -0.0002, Price: 200, Size: 1},
+		{BidFeeRate: -0.0002, AskFeeRate: -0.0002, Price: 100, Size: 1},
+	}
+
+	tests := []struct {
+		name         string
+		disableBase  bool
+		disableQuote bool
+		baseRebates  float64
+		quoteRebates float64
+	}{
+		{"none disabled", false, false, 0.0004, 0.06},
+		{"base disabled", true, false, 0, 0.06},
+		{"quote disabled", false, true, 0.0004, 0},
+		{"both disabled", true, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloned := append([]book.Trade(nil), trades...)
+			serviceHelper.DisableRebates(cloned, tt.disableBase, tt.disableQuote)
+			baseRebates, quoteRebates := serviceHelper.CalculateRebates(cloned)
+			assertFloat(t, baseRebates, tt.baseRebates)
+			assertFloat(t, quoteRebates, tt.quoteRebates)
+			// positive fee rates are kept.
+			assertFloat(t, cloned[0].AskFeeRate, 0.001)
+			assertFloat(t, cloned[1].BidFeeRate, 0.001)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"github.com/johnny1110/crypto-exchange/dto"
+	"github.com/johnny1110/crypto-exchange/service/serviceHelper"
+	"testing"
+)
+
+func Test_IsTransferReplay(t *testing.T) {
+	existing := &dto.Transfer{ID: "T1", FromUserID: "U1", ToUserID: "U2", Asset: "USDT", Amount: 100, IdempotencyKey: "K1"}
+	tests := []struct {
+		name     string
+		transfer *dto.Transfer
+		replay   bool
+	}{
+		{"same transfer", &dto.Transfer{FromUserID: "U1", ToUserID: "U2", Asset: "USDT", Amount: 100, IdempotencyKey: "K1"}, true},
+		{"other target", &dto.Transfer{FromUserID: "U1", ToUserID: "U3", Asset: "USDT", Amount: 100, IdempotencyKey: "K1"}, false},
+		{"other asset", &dto.Transfer{FromUserID: "U1", ToUserID: "U2", Asset: "BTC", Amount: 100, IdempotencyKey: "K1"}, false},
+		{"other amount", &dto.Transfer{FromUserID: "U1", ToUserID: "U2", Asset: "USDT", Amount: 100.5, IdempotencyKey: "K1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert(t, serviceHelper.IsTransferReplay(existing, tt.transfer), tt.replay)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package serviceHelper
+
+import "github.com/johnny1110/crypto-exchange/dto"
+
+// IsTransferReplay transfer reusing idempotency key of existing transfer is a replay only if target, asset and amount
+// are the same, otherwise the key conflicts.
+func IsTransferReplay(existing, transfer *dto.Transfer) bool {
+	return existing.ToUserID == transfer.ToUserID && existing.Asset == transfer.Asset && existing.Amount == transfer.Amount
+}
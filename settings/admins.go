@@ -0,0 +1,19 @@
+package settings
+
+import "time"
+
+// Admin settings
+// ADMIN_SESSION_TTL absolute lifetime of admin login token, admin sessions are kept in memory only.
+const ADMIN_SESSION_TTL = 8 * time.Hour
+
+// ADMIN_LOGIN_MAX_FAILURES failed admin logins (password, TOTP code) in a row before username is locked.
+const ADMIN_LOGIN_MAX_FAILURES = 5
+
+// ADMIN_LOGIN_LOCK_DURATION admin username is locked for it, failures older than it are forgotten.
+const ADMIN_LOGIN_LOCK_DURATION = 15 * time.Minute
+
+// ADMIN_BOOTSTRAP_USERNAME superadmin created on startup if there is no admin.
+const ADMIN_BOOTSTRAP_USERNAME = "superadmin"
+
+// ADMIN_BOOTSTRAP_SECRET_FILE password and TOTP URI of bootstrap superadmin are written to it (mode 0600) once.
+const ADMIN_BOOTSTRAP_SECRET_FILE = "/app/admin_bootstrap.secret"
@@ -0,0 +1,18 @@
+package settings
+
+import "time"
+
+// Proxy settings
+// TRUSTED_PROXIES IPs or CIDRs of reverse proxies, X-Forwarded-For is only honored from them.
+// empty means server is exposed directly and client IP is always peer address.
+var TRUSTED_PROXIES []string
+
+// API key settings
+// API_KEY_RECV_WINDOW signed request timestamp must be within window of server time, signatures are not reusable within it.
+const API_KEY_RECV_WINDOW = 5 * time.Second
+
+// API_KEY_MAX_PER_USER maximum active (not revoked) api keys of one user.
+const API_KEY_MAX_PER_USER = 20
+
+// API_KEY_TOUCH_INTERVAL minimum interval of persisting api key last used time.
+const API_KEY_TOUCH_INTERVAL = time.Minute
@@ -0,0 +1,10 @@
+package settings
+
+import "time"
+
+// Convert settings
+// CONVERT_ROUTE_ASSET convert between two non route assets is routed through two markets (e.g. ETH -> USDT -> BTC).
+const CONVERT_ROUTE_ASSET = "USDT"
+
+// CONVERT_QUOTE_TTL convert quote is held and can be accepted within ttl.
+const CONVERT_QUOTE_TTL = 5 * time.Second
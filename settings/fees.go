@@ -0,0 +1,21 @@
+package settings
+
+// Market maker settings
+type FeeRate struct {
+	MakerFee float64
+	TakerFee float64
+}
+
+// MARKET_MAKER_FEE_TIERS designated market-maker vip levels, assigned by admin and skipped by fee tier recalculation.
+// negative maker fee means rebate paid by margin account.
+var MARKET_MAKER_FEE_TIERS = map[int]FeeRate{
+	8: {MakerFee: -0.0001, TakerFee: 0.0008},
+	9: {MakerFee: -0.0002, TakerFee: 0.0006},
+}
+
+// Fee token settings
+// FEE_TOKEN user opt-in pay trading fees in platform token, converted at latest FEE_TOKEN-USDT price.
+const FEE_TOKEN = "BTSE"
+
+// FEE_TOKEN_DISCOUNT discount rate of fees paid in FEE_TOKEN.
+const FEE_TOKEN_DISCOUNT = 0.25
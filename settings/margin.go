@@ -0,0 +1,50 @@
+package settings
+
+import "time"
+
+// Margin settings
+// MARGIN_LENDING_POOL_ACCOUNT_ID reserved system account of margin lending pool, loans are paid out of and repaid
+// to its balances, kept apart from fee income of MARGIN_ACCOUNT_ID.
+const MARGIN_LENDING_POOL_ACCOUNT_ID = "SYS_MARGIN_LENDING_POOL"
+
+// MARGIN_MAINTENANCE_RATIO minimum equity / asset value of margin wallet, below it the wallet is liquidatable.
+const MARGIN_MAINTENANCE_RATIO = 0.1
+
+// MARGIN_INITIAL_RATIO minimum equity / asset value after borrowing or transferring out collateral.
+const MARGIN_INITIAL_RATIO = MARGIN_MAINTENANCE_RATIO * 2
+
+// MARGIN_MAX_LEVERAGE asset value / equity, derived from MARGIN_INITIAL_RATIO (5x).
+const MARGIN_MAX_LEVERAGE = 1 / MARGIN_INITIAL_RATIO
+
+// MARGIN_INTEREST_INTERVAL interest is accrued for every full interval elapsed since loan last accrued.
+const MARGIN_INTEREST_INTERVAL = time.Hour
+
+// MARGIN_DEFAULT_HOURLY_INTEREST_RATE interest rate accrued on loan principal every hour.
+const MARGIN_DEFAULT_HOURLY_INTEREST_RATE = 0.00001
+
+// MARGIN_HOURLY_INTEREST_RATES hourly interest rate by asset, assets absent use MARGIN_DEFAULT_HOURLY_INTEREST_RATE.
+var MARGIN_HOURLY_INTEREST_RATES = map[string]float64{
+	"USDT": 0.000005,
+	"BTC":  0.000003,
+	"ETH":  0.000004,
+}
+
+func GetMarginHourlyInterestRate(asset string) float64 {
+	if rate, ok := MARGIN_HOURLY_INTEREST_RATES[asset]; ok {
+		return rate
+	}
+	return MARGIN_DEFAULT_HOURLY_INTEREST_RATE
+}
+
+// Liquidation settings
+// MARGIN_CALL_RATIO margin call is issued when margin ratio is lower than it (warning level above maintenance).
+const MARGIN_CALL_RATIO = MARGIN_MAINTENANCE_RATIO * 1.5
+
+// MARGIN_CALL_INTERVAL minimum interval between margin calls of same user.
+const MARGIN_CALL_INTERVAL = time.Hour
+
+// MARGIN_RISK_CHECK_INTERVAL interval of checking all margin accounts risk.
+const MARGIN_RISK_CHECK_INTERVAL = 10 * time.Second
+
+// LIQUIDATION_SLIPPAGE extra quote amount spent on buying loan asset by liquidation market bid order.
+const LIQUIDATION_SLIPPAGE = 0.05
@@ -0,0 +1,16 @@
+package settings
+
+import "time"
+
+// Perpetual settings
+// PERPETUAL_INITIAL_MARGIN_RATE margin locked in quote asset for position notional (10x leverage).
+const PERPETUAL_INITIAL_MARGIN_RATE = 0.1
+
+// PERPETUAL_MAINTENANCE_MARGIN_RATE position with margin ratio (margin + unrealized pnl) / notional lower than it is at risk.
+const PERPETUAL_MAINTENANCE_MARGIN_RATE = 0.05
+
+// PERPETUAL_FUNDING_INTERVAL funding payments are settled every interval (00:00, 08:00, 16:00 UTC).
+const PERPETUAL_FUNDING_INTERVAL = 8 * time.Hour
+
+// PERPETUAL_MAX_FUNDING_RATE funding rate (premium of mark price over index price) is clamped in ±max.
+const PERPETUAL_MAX_FUNDING_RATE = 0.0075
@@ -0,0 +1,55 @@
+package settings
+
+import "time"
+
+// Rate limit settings
+// RATE_LIMIT_WINDOW token buckets refill full capacity in window.
+const RATE_LIMIT_WINDOW = time.Minute
+
+// RATE_LIMIT_IP_CAPACITY request weight per window of one IP on public routes.
+const RATE_LIMIT_IP_CAPACITY = 1200
+
+// RATE_LIMIT_USER_CAPACITY request weight per window of one user (login token) or one API key on private routes.
+const RATE_LIMIT_USER_CAPACITY = 2400
+
+// RATE_LIMIT_DEFAULT_WEIGHT weight of routes not in RATE_LIMIT_WEIGHTS.
+const RATE_LIMIT_DEFAULT_WEIGHT = 1
+
+// RATE_LIMIT_WEIGHTS weight of route by "METHOD path", expensive routes (db writes, bcrypt, large queries) cost more.
+var RATE_LIMIT_WEIGHTS = map[string]int{
+	"POST /api/v1/users/register":                         20,
+	"POST /api/v1/users/login":                            20,
+	"POST /api/v1/users/refresh-token":                    5,
+	"POST /api/v1/users/password-reset/request":           20,
+	"POST /api/v1/users/password-reset/confirm":           20,
+	"PUT /api/v1/users/password":                          20,
+	"GET /api/v1/orderbooks/:market/snapshot":             2,
+	"GET /api/v1/markets/:market/ohlcv-history/:interval": 5,
+	"GET /api/v1/markets/:market/trades":                  5,
+	"POST /api/v1/orders/:market":                         5,
+	"DELETE /api/v1/orders/:orderId":                      2,
+	"GET /api/v1/orders":                                  5,
+	"GET /api/v1/fills":                                   5,
+	"GET /api/v1/statements":                              50,
+	"POST /api/v1/withdrawals":                            10,
+	"POST /api/v1/transfers":                              5,
+	"POST /api/v1/sub-accounts/transfers":                 5,
+	"POST /api/v1/convert/accept":                         5,
+	"POST /api/v1/rfq/requests/:requestId/accept":         5,
+	"POST /api/v1/api-keys":                               10,
+	"GET /api/v1/portfolio/equity-history":                5,
+	"POST /admin/api/v1/login":                            20,
+}
+
+// ORDER_RATE_LIMIT_PER_SECOND orders placed per second of one user in one market, counted apart from request weight.
+const ORDER_RATE_LIMIT_PER_SECOND = 10
+
+// WS_MAX_CONNECTIONS_PER_IP concurrent websocket connections of one IP.
+const WS_MAX_CONNECTIONS_PER_IP = 10
+
+// WS_MAX_SUBSCRIPTIONS_PER_CONNECTION subscriptions of one websocket connection.
+const WS_MAX_SUBSCRIPTIONS_PER_CONNECTION = 50
+
+// WS_LOGIN_CHECK_INTERVAL logged-in websocket connections re-validate their login token or API key at this interval,
+// so logout, password change and frozen account stop private channels.
+var WS_LOGIN_CHECK_INTERVAL = 10 * time.Second
@@ -0,0 +1,5 @@
+package settings
+
+// Referral settings
+// REFERRAL_COMMISSION_RATE share of referee's trading fees paid to referrer by margin account.
+const REFERRAL_COMMISSION_RATE = 0.2
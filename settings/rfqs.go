@@ -0,0 +1,13 @@
+package settings
+
+import "time"
+
+// RFQ settings
+// RFQ_REQUEST_TTL liquidity providers respond and taker accepts within ttl, open quotes are released after it.
+const RFQ_REQUEST_TTL = 30 * time.Second
+
+// RFQ_EXPIRE_CHECK_INTERVAL interval of expiring RFQ requests.
+const RFQ_EXPIRE_CHECK_INTERVAL = 5 * time.Second
+
+// RFQ_AMM_SPREAD internal AMM quotes RFQ at best price of order book widened by spread.
+const RFQ_AMM_SPREAD = 0.002
@@ -0,0 +1,22 @@
+package settings
+
+import "time"
+
+// Session settings
+// SESSION_STORE "sqlite" persists sessions across restarts, "memory" keeps them in process.
+const SESSION_STORE = "sqlite"
+
+// SESSION_IDLE_TIMEOUT access token expires if not used within timeout.
+const SESSION_IDLE_TIMEOUT = 30 * time.Minute
+
+// SESSION_TOKEN_TTL absolute lifetime of access token, renew it by refresh token.
+const SESSION_TOKEN_TTL = 12 * time.Hour
+
+// SESSION_REFRESH_TTL absolute lifetime of session (refresh token) since login, refresh does not extend it.
+const SESSION_REFRESH_TTL = 7 * 24 * time.Hour
+
+// SESSION_TOUCH_INTERVAL minimum interval of persisting session last active time.
+const SESSION_TOUCH_INTERVAL = time.Minute
+
+// SESSION_CLEANUP_INTERVAL interval of deleting expired sessions.
+const SESSION_CLEANUP_INTERVAL = 10 * time.Minute
@@ -21,6 +21,19 @@ var ALL_MARKETS = []*market.MarketInfo{
 	{Name: "BTSE-USDT", BaseAsset: "BTSE", QuoteAsset: "USDT"},
 	{Name: "ASTR-USDT", BaseAsset: "ASTR", QuoteAsset: "USDT"},
 	{Name: "HDX-USDT", BaseAsset: "HDX", QuoteAsset: "USDT"},
+	{Name: "BTC-USDT-PERP", BaseAsset: "BTC", QuoteAsset: "USDT", Type: market.PERPETUAL, ContractSize: 1},
+	{Name: "ETH-USDT-PERP", BaseAsset: "ETH", QuoteAsset: "USDT", Type: market.PERPETUAL, ContractSize: 1},
+}
+
+// GetPerpetualMarkets perpetual markets of ALL_MARKETS.
+func GetPerpetualMarkets() []*market.MarketInfo {
+	markets := make([]*market.MarketInfo, 0)
+	for _, m := range ALL_MARKETS {
+		if m.IsPerpetual() {
+			markets = append(markets, m)
+		}
+	}
+	return markets
 }
 
 // AMM Price Level settings
@@ -41,3 +54,6 @@ var MAX_QUOTE_AMT_PER_LEVEL_MAP = map[string]float64{
 
 const MARGIN_ACCOUNT_ID = "0"
 const INTERNAL_AMM_ACCOUNT_ID = "MID250606CXAZ1199"
+
+// INSURANCE_FUND_ACCOUNT_ID reserved system account, not a generated user id (UID...) and has no usable password.
+const INSURANCE_FUND_ACCOUNT_ID = "SYS_INSURANCE_FUND"
@@ -0,0 +1,29 @@
+package settings
+
+import "time"
+
+// Password settings
+// PASSWORD_MIN_LENGTH new passwords also need at least one letter and one digit, at most 72 bytes (bcrypt limit).
+const PASSWORD_MIN_LENGTH = 8
+
+// PASSWORD_RESET_TOKEN_TTL reset token expires after ttl, it can be used once.
+const PASSWORD_RESET_TOKEN_TTL = 30 * time.Minute
+
+// PASSWORD_RESET_REQUEST_INTERVAL minimum interval of sending reset token to one user, new token replaces old one.
+const PASSWORD_RESET_REQUEST_INTERVAL = time.Minute
+
+// NOTIFIER_LOCAL_FILE notifications (e.g. password reset tokens) are written to file by local notifier in development.
+const NOTIFIER_LOCAL_FILE = "logs/notifications.log"
+
+// 2FA settings
+// TOTP_ISSUER issuer shown in authenticator apps.
+const TOTP_ISSUER = "crypto-exchange"
+
+// TOTP_MAX_FAILURES invalid user 2FA codes in a row (login, withdrawals, transfers...) before user is locked.
+const TOTP_MAX_FAILURES = 5
+
+// TOTP_LOCK_DURATION every 2FA verification of locked user fails for it, failures older than it are forgotten.
+const TOTP_LOCK_DURATION = 15 * time.Minute
+
+// TOTP_BACKUP_CODE_COUNT backup codes generated on enabling user 2FA, each code can be used once.
+const TOTP_BACKUP_CODE_COUNT = 10
@@ -0,0 +1,24 @@
+package settings
+
+import "time"
+
+// Withdrawal settings
+// WITHDRAWAL_DAILY_LIMIT_MAP rolling 24h withdrawal limit (USDT valuation) by vip level
+var WITHDRAWAL_DAILY_LIMIT_MAP = map[int]float64{
+	0: 0,
+	1: 10000,
+	2: 20000,
+	3: 50000,
+	4: 100000,
+	5: 200000,
+	6: 500000,
+	7: 1000000,
+	8: 1000000,
+	9: 1000000,
+}
+
+// WITHDRAWAL_ADDRESS_COOLING_OFF new whitelisted address can not be used until cooling-off period passed.
+const WITHDRAWAL_ADDRESS_COOLING_OFF = 24 * time.Hour
+
+// WITHDRAWAL_MANUAL_APPROVAL_THRESHOLD withdrawal valuation (USDT) >= threshold need admin approval.
+const WITHDRAWAL_MANUAL_APPROVAL_THRESHOLD = 1000.0
@@ -8,6 +8,8 @@ import (
 	"github.com/johnny1110/crypto-exchange/container"
 	"github.com/johnny1110/crypto-exchange/dto"
 	"github.com/johnny1110/crypto-exchange/engine-v2/model"
+	repositoryImpl "github.com/johnny1110/crypto-exchange/repository/impl"
+	serviceImpl "github.com/johnny1110/crypto-exchange/service/impl"
 	"github.com/johnny1110/crypto-exchange/ws"
 	"github.com/labstack/gommon/log"
 	"io"
@@ -154,12 +156,47 @@ func startUpAllScheduler(c *container.Container) {
 	if err != nil {
 		panic(err)
 	}
+
+	err = c.FeeTierScheduler.Start()
+	if err != nil {
+		panic(err)
+	}
+
+	err = c.EquitySnapshotScheduler.Start()
+	if err != nil {
+		panic(err)
+	}
+
+	err = c.MarginInterestScheduler.Start()
+	if err != nil {
+		panic(err)
+	}
+
+	err = c.LiquidationScheduler.Start()
+	if err != nil {
+		panic(err)
+	}
+
+	err = c.FundingScheduler.Start()
+	if err != nil {
+		panic(err)
+	}
+
+	err = c.RfqExpireScheduler.Start()
+	if err != nil {
+		panic(err)
+	}
+
+	err = c.SessionCleanupScheduler.Start()
+	if err != nil {
+		panic(err)
+	}
 }
 
 func setupWebSocket(c *container.Container) {
 	go func() {
-		http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-			ws.HandleWebSocket(c.WSHub, w, r)
+		http.HandleFunc(ws.ENDPOINT, func(w http.ResponseWriter, r *http.Request) {
+			ws.HandleWebSocket(c.WSHub, c.WSAuthenticator, w, r)
 		})
 
 		log.Info("WebSocket listen on :8081")
@@ -170,3 +207,19 @@ func setupWebSocket(c *container.Container) {
 func getEthClient() (*ethclient.Client, error) {
 	return ethclient.Dial("http://localhost:8545")
 }
+
+// verifyAuditLog check audit log hash chain, return exit code 1 if chain is broken.
+func verifyAuditLog(db *sql.DB) int {
+	auditService := serviceImpl.NewIAuditService(db, repositoryImpl.NewAuditLogRepository())
+	result, err := auditService.VerifyChain(context.Background())
+	if err != nil {
+		log.Errorf("failed to verify audit log: %v", err)
+		return 1
+	}
+	if !result.Valid {
+		log.Errorf("audit log hash chain broken at id %d, %s (checked %d rows)", result.BrokenID, result.Reason, result.Checked)
+		return 1
+	}
+	log.Infof("audit log hash chain valid, checked %d rows", result.Checked)
+	return 0
+}
@@ -15,3 +15,8 @@ func RoundFloats(fs []float64) []float64 {
 	}
 	return result
 }
+
+// FloorFloat round down to Scale.
+func FloorFloat(f float64) float64 {
+	return math.Floor(f*1e7+1e-6) / 1e7
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/websocket"
+	"github.com/johnny1110/crypto-exchange/security"
+	"github.com/johnny1110/crypto-exchange/settings"
 	"github.com/labstack/gommon/log"
 	"net/http"
 	"sync"
@@ -13,23 +15,43 @@ import (
 
 type Client struct {
 	ID            string
+	IP            string
 	Conn          *websocket.Conn
 	Send          chan []byte
 	Subscriptions map[SubscriptionKey]bool
 	Hub           *Hub
+	auth          Authenticator
+	userID        string // logged-in user, empty before login
+	done          chan struct{}
 	mu            sync.RWMutex
 }
 
-func NewClient(id string, conn *websocket.Conn, hub *Hub) *Client {
+// Authenticator resolve user id of login request, frozen and closed accounts are refused.
+// Revalidate check login is still valid: token not logged out, API key not revoked or expired, account active.
+type Authenticator interface {
+	Authenticate(ctx context.Context, params *LoginReqParams, clientIP string) (string, error)
+	Revalidate(ctx context.Context, params *LoginReqParams) error
+}
+
+func NewClient(id, ip string, conn *websocket.Conn, hub *Hub, auth Authenticator) *Client {
 	return &Client{
 		ID:            id,
+		IP:            ip,
 		Conn:          conn,
 		Send:          make(chan []byte, 256),
 		Subscriptions: make(map[SubscriptionKey]bool),
 		Hub:           hub,
+		auth:          auth,
+		done:          make(chan struct{}),
 	}
 }
 
+func (c *Client) UserID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.userID
+}
+
 // ReadPump read client req data
 func (c *Client) ReadPump() {
 	defer func() {
@@ -71,11 +93,79 @@ func (c *Client) handleRequest(req WSReq) {
 		c.handleSubscribe(req)
 	case UNSUBSCRIBE:
 		c.handleUnsubscribe(req)
+	case LOGIN:
+		c.handleLogin(req)
 	default:
 		log.Warnf("UNKNOWN Action: %s", req.Action)
 	}
 }
 
+// handleLogin authenticate client once, it stays logged in until disconnected or its login is revoked.
+func (c *Client) handleLogin(req WSReq) {
+	if c.UserID() != "" {
+		c.Hub.sendError(c, ErrAlreadyLoggedIn)
+		return
+	}
+
+	var params LoginReqParams
+	paramsBytes, _ := json.Marshal(req.Params)
+	if err := json.Unmarshal(paramsBytes, &params); err != nil {
+		c.Hub.sendError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userId, err := c.auth.Authenticate(ctx, &params, c.IP)
+	if err != nil {
+		log.Warnf("[WS] client %v login failed: %v", c.ID, err)
+		c.Hub.sendError(c, err)
+		return
+	}
+
+	c.mu.Lock()
+	c.userID = userId
+	c.mu.Unlock()
+	log.Infof("[WS] client %v logged in, userId: %s", c.ID, userId)
+	c.Hub.send(c, AUTH, map[string]string{"user_id": userId})
+	go c.checkLogin(&params)
+}
+
+// checkLogin re-validate login every settings.WS_LOGIN_CHECK_INTERVAL until client is unregistered,
+// revoked login is logged out and its private subscriptions are dropped.
+func (c *Client) checkLogin(params *LoginReqParams) {
+	ticker := time.NewTicker(settings.WS_LOGIN_CHECK_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := c.auth.Revalidate(ctx, params)
+			cancel()
+			if err == nil {
+				continue
+			}
+			log.Infof("[WS] client %v login revoked, userId: %s, reason: %v", c.ID, c.UserID(), err)
+			c.Hub.logout(c)
+			c.Hub.sendError(c, ErrLoginRevoked)
+			return
+		}
+	}
+}
+
+// privateKey bind private channel key to logged-in user.
+func (c *Client) privateKey(key SubscriptionKey) (SubscriptionKey, error) {
+	userId := c.UserID()
+	if userId == "" {
+		return key, ErrLoginRequired
+	}
+	key.Params = PrivateParams{UserID: userId}
+	return key, nil
+}
+
 // handleSubscribe
 func (c *Client) handleSubscribe(req WSReq) {
 	key, err := BuildSubscriptionKey(req)
@@ -83,8 +173,17 @@ func (c *Client) handleSubscribe(req WSReq) {
 		log.Errorf("Failed to create subscribtion key: %v", err)
 		return
 	}
+	if IsPrivateChannel(key.Channel) {
+		if key, err = c.privateKey(key); err != nil {
+			c.Hub.sendError(c, err)
+			return
+		}
+	}
 
-	c.Hub.Subscribe(c, key)
+	if err := c.Hub.Subscribe(c, key); err != nil {
+		log.Warnf("[WS] client %v subscribe failed: %v", c.ID, err)
+		c.Hub.sendError(c, err)
+	}
 }
 
 // handleUnsubscribe
@@ -94,6 +193,11 @@ func (c *Client) handleUnsubscribe(req WSReq) {
 		log.Errorf("Failed to create subscribtion key: %v", err)
 		return
 	}
+	if IsPrivateChannel(key.Channel) {
+		if key, err = c.privateKey(key); err != nil {
+			return
+		}
+	}
 
 	c.Hub.Unsubscribe(c, key)
 }
@@ -129,16 +233,27 @@ func (c *Client) WritePump() {
 	}
 }
 
-// HandleWebSocket WebSocket handler
-func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
+// HandleWebSocket WebSocket handler, auth is used by login action for private channels.
+func HandleWebSocket(hub *Hub, auth Authenticator, w http.ResponseWriter, r *http.Request) {
+	// same client IP as http rate limits.
+	ip := security.ClientIP(r)
+	if !hub.acquireConn(ip) {
+		log.Warnf("[WS] connections limit exceeded, ip: %s", ip)
+		http.Error(w, "too many websocket connections", http.StatusTooManyRequests)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("[WS] failed to upgrade: %v", err)
+		hub.mu.Lock()
+		hub.releaseConn(ip)
+		hub.mu.Unlock()
 		return
 	}
 
 	clientID := fmt.Sprintf("client_%d", time.Now().UnixNano())
-	client := NewClient(clientID, conn, hub)
+	client := NewClient(clientID, ip, conn, hub, auth)
 
 	hub.register <- client
 
@@ -3,7 +3,9 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/gorilla/websocket"
+	"github.com/johnny1110/crypto-exchange/settings"
 	"github.com/labstack/gommon/log"
 	"net/http"
 	"sync"
@@ -17,6 +19,7 @@ type Hub struct {
 	unregister    chan *Client
 	broadcast     chan []byte
 	subscriptions map[SubscriptionKey]map[*Client]bool
+	connsByIP     map[string]int
 	mu            sync.RWMutex
 }
 
@@ -35,6 +38,7 @@ func NewHub() *Hub {
 		unregister:    make(chan *Client),
 		broadcast:     make(chan []byte),
 		subscriptions: make(map[SubscriptionKey]map[*Client]bool),
+		connsByIP:     make(map[string]int),
 	}
 }
 
@@ -66,6 +70,7 @@ func (h *Hub) Run(ctx context.Context) {
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
 				close(client.Send)
+				close(client.done)
 
 				// clean subscription
 				for key := range client.Subscriptions {
@@ -77,6 +82,7 @@ func (h *Hub) Run(ctx context.Context) {
 					}
 				}
 				client.Conn.Close()
+				h.releaseConn(client.IP)
 			}
 
 			h.mu.Unlock()
@@ -92,10 +98,24 @@ func (h *Hub) Run(ctx context.Context) {
 	}
 }
 
-func (h *Hub) Subscribe(client *Client, key SubscriptionKey) {
+// Subscribe at most settings.WS_MAX_SUBSCRIPTIONS_PER_CONNECTION keys per client.
+func (h *Hub) Subscribe(client *Client, key SubscriptionKey) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	client.mu.RLock()
+	subscribed := client.Subscriptions[key]
+	count := len(client.Subscriptions)
+	userId := client.userID
+	client.mu.RUnlock()
+	// login may be revoked after private key was bound to user.
+	if params, ok := key.Params.(PrivateParams); ok && IsPrivateChannel(key.Channel) && params.UserID != userId {
+		return ErrLoginRequired
+	}
+	if !subscribed && count >= settings.WS_MAX_SUBSCRIPTIONS_PER_CONNECTION {
+		return fmt.Errorf("subscriptions limit %d exceeded", settings.WS_MAX_SUBSCRIPTIONS_PER_CONNECTION)
+	}
+
 	// create key in hub.subscriptions
 	if h.subscriptions[key] == nil {
 		h.subscriptions[key] = make(map[*Client]bool)
@@ -106,6 +126,7 @@ func (h *Hub) Subscribe(client *Client, key SubscriptionKey) {
 	client.Subscriptions[key] = true
 	client.mu.Unlock()
 	log.Infof("[WS] subscribe client %v, key:%v", client.ID, key)
+	return nil
 }
 
 func (h *Hub) Unsubscribe(client *Client, subKey SubscriptionKey) {
@@ -126,6 +147,71 @@ func (h *Hub) Unsubscribe(client *Client, subKey SubscriptionKey) {
 	log.Infof("[WS] unsubscribe client %v, key:%v", client.ID, subKey)
 }
 
+// logout drop private subscriptions of client and reset its login, public subscriptions are kept.
+func (h *Hub) logout(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	for key := range client.Subscriptions {
+		if !IsPrivateChannel(key.Channel) {
+			continue
+		}
+		if clients, ok := h.subscriptions[key]; ok {
+			delete(clients, client)
+			if len(clients) == 0 {
+				delete(h.subscriptions, key)
+			}
+		}
+		delete(client.Subscriptions, key)
+	}
+	client.userID = ""
+}
+
+// acquireConn count connection of ip, false if ip reached settings.WS_MAX_CONNECTIONS_PER_IP.
+func (h *Hub) acquireConn(ip string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.connsByIP[ip] >= settings.WS_MAX_CONNECTIONS_PER_IP {
+		return false
+	}
+	h.connsByIP[ip]++
+	return true
+}
+
+// releaseConn caller must hold h.mu, or use it before client registered.
+func (h *Hub) releaseConn(ip string) {
+	h.connsByIP[ip]--
+	if h.connsByIP[ip] <= 0 {
+		delete(h.connsByIP, ip)
+	}
+}
+
+// sendError notify client error of its request, dropped if client is unregistered or its send buffer is full.
+func (h *Hub) sendError(client *Client, err error) {
+	h.send(client, ERROR, err.Error())
+}
+
+// send reply to one client, dropped if client is unregistered or its send buffer is full.
+func (h *Hub) send(client *Client, channel WSChannel, data interface{}) {
+	message, _ := json.Marshal(WSResp{
+		Channel:   channel,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	select {
+	case client.Send <- message:
+	default:
+	}
+}
+
 // BroadcastToSubscribers broadcast to all sub users.
 func (h *Hub) BroadcastToSubscribers(key SubscriptionKey, data interface{}) {
 	resp := WSResp{
@@ -142,14 +228,14 @@ func (h *Hub) BroadcastToSubscribers(key SubscriptionKey, data interface{}) {
 
 	h.mu.RLock()
 	log.Debugf("[BroadcastToSubscribers] inputKey:%v", key)
-	clients, ok := h.subscriptions[key]
-	h.mu.RUnlock()
-
-	if !ok {
-		return
+	// copy clients, subscriptions may change while sending.
+	clients := make([]*Client, 0, len(h.subscriptions[key]))
+	for client := range h.subscriptions[key] {
+		clients = append(clients, client)
 	}
+	h.mu.RUnlock()
 
-	for client := range clients {
+	for _, client := range clients {
 		select {
 		case client.Send <- message:
 		default:
@@ -157,3 +243,38 @@ func (h *Hub) BroadcastToSubscribers(key SubscriptionKey, data interface{}) {
 		}
 	}
 }
+
+// UserDataPublisher push private channel data to logged-in users, implemented by Hub.
+type UserDataPublisher interface {
+	Subscribed(channel WSChannel, userId string) bool
+	PublishToUser(channel WSChannel, userId string, data interface{})
+}
+
+// Subscribed true if any connection of user subscribed private channel, so data nobody receives is not queried.
+func (h *Hub) Subscribed(channel WSChannel, userId string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscriptions[SubscriptionKey{Channel: channel, Params: PrivateParams{UserID: userId}}]) > 0
+}
+
+// PublishToUser push data to connections of user subscribed private channel.
+func (h *Hub) PublishToUser(channel WSChannel, userId string, data interface{}) {
+	h.BroadcastToSubscribers(SubscriptionKey{Channel: channel, Params: PrivateParams{UserID: userId}}, data)
+}
+
+// TradePublisher push trades of market to trades channel subscribers, implemented by Hub.
+type TradePublisher interface {
+	TradesSubscribed(market string) bool
+	PublishTrades(market string, trades interface{})
+}
+
+// TradesSubscribed true if any connection subscribed trades of market, so trades nobody receives are not queried.
+func (h *Hub) TradesSubscribed(market string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscriptions[SubscriptionKey{Channel: TRADES, Params: TradesReqParams{Market: market}}]) > 0
+}
+
+func (h *Hub) PublishTrades(market string, trades interface{}) {
+	h.BroadcastToSubscribers(SubscriptionKey{Channel: TRADES, Params: TradesReqParams{Market: market}}, trades)
+}
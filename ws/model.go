@@ -2,25 +2,50 @@ package ws
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
+// ENDPOINT path of websocket, API key login signs "GET" + ENDPOINT.
+const ENDPOINT = "/ws"
+
 type WSChannel string
 
 const OHLCV = WSChannel("ohlcv")
 const ORDERBOOK = WSChannel("orderbook")
 const MARKETS = WSChannel("markets")
 
+// TRADES channel pushes trades of market as soon as they are persisted, not by WSDataFeederJob.
+const TRADES = WSChannel("trades")
+
+// private channels of logged-in user, data of user's margin wallet is included.
+const ORDERS = WSChannel("orders")
+const FILLS = WSChannel("fills")
+const BALANCES = WSChannel("balances")
+
+// AUTH channel of login result.
+const AUTH = WSChannel("auth")
+
+// ERROR channel of errors of client requests, e.g. subscriptions limit exceeded.
+const ERROR = WSChannel("error")
+
 type WSAction string
 
 const (
 	SUBSCRIBE   WSAction = "subscribe"
 	UNSUBSCRIBE WSAction = "unsubscribe"
+	LOGIN       WSAction = "login"
+)
+
+var (
+	ErrLoginRequired   = errors.New("login required")
+	ErrAlreadyLoggedIn = errors.New("already logged in")
+	ErrLoginRevoked    = errors.New("login revoked, login required")
 )
 
 // WSReq WebSocket request
 type WSReq struct {
-	Action  WSAction    `json:"action"` // subscribe/unsubscribe
+	Action  WSAction    `json:"action"` // subscribe/unsubscribe/login
 	Channel WSChannel   `json:"channel"`
 	Params  interface{} `json:"params"`
 }
@@ -36,6 +61,24 @@ type OrderBookReqParams struct {
 	Market string `json:"market"`
 }
 
+// Trades params
+type TradesReqParams struct {
+	Market string `json:"market"`
+}
+
+// LoginReqParams login by session token, or by API key with Signature of Timestamp + "GET" + ENDPOINT (empty body).
+type LoginReqParams struct {
+	Token     string `json:"token"`
+	ApiKey    string `json:"api_key"`
+	Timestamp string `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
+// PrivateParams of private channel subscription key, filled by logged-in user id, not by client.
+type PrivateParams struct {
+	UserID string
+}
+
 // WSResp WebSocket response
 type WSResp struct {
 	Channel   WSChannel   `json:"channel"`
@@ -55,7 +98,7 @@ func BuildSubscriptionKey(req WSReq) (SubscriptionKey, error) {
 	}
 
 	switch req.Channel {
-	case MARKETS:
+	case MARKETS, ORDERS, FILLS, BALANCES:
 		return SubscriptionKey{
 			Channel: req.Channel,
 		}, nil
@@ -79,11 +122,25 @@ func BuildSubscriptionKey(req WSReq) (SubscriptionKey, error) {
 			Params:  params,
 		}, nil
 
+	case TRADES:
+		var params TradesReqParams
+		if err := json.Unmarshal(paramsBytes, &params); err != nil {
+			return SubscriptionKey{}, err
+		}
+		return SubscriptionKey{
+			Channel: req.Channel,
+			Params:  params,
+		}, nil
+
 	default:
 		return SubscriptionKey{}, fmt.Errorf("Unsupport Channel: %s", req.Channel)
 	}
 }
 
+func IsPrivateChannel(channel WSChannel) bool {
+	return channel == ORDERS || channel == FILLS || channel == BALANCES
+}
+
 type WSFeedPackage struct {
 	Key  SubscriptionKey
 	Data interface{}